@@ -0,0 +1,193 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/config"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/diagnose"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
+	mountpkg "github.com/googlecloudplatform/gcsfuse/v2/internal/mount"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage"
+	"github.com/urfave/cli"
+)
+
+// Files being written are staged whole in temp-dir, and so are files read
+// through an unlimited file cache in cache-dir: warn below this free space.
+const minFreeDirBytes = 1 << 30
+
+func newDiagnoseCommand(flags []cli.Flag) cli.Command {
+	return cli.Command{
+		Name:      "diagnose",
+		Usage:     "Check the environment of a mount, or of a bucket to be mounted",
+		ArgsUsage: "<mount point|bucket>",
+		Description: "Checks the credentials, the access to the bucket, the temporary and cache " +
+			"directories, the FUSE device and the kernel mount options, then prints which checks " +
+			"pass. Pass the flags and config file used to mount, as they tell what to check.",
+		Flags:  flags,
+		Action: runDiagnose,
+	}
+}
+
+// diagnoseChecks returns the checks of the mount at the given mount point, or
+// of the given bucket.
+func diagnoseChecks(ctx context.Context, arg string, flags *flagStorage, mountConfig *config.MountConfig) (checks []diagnose.Check) {
+	scheme := "gs"
+	if flags.Backend == mountpkg.S3 || storage.IsS3Endpoint(flags.CustomEndpoint) {
+		scheme = "s3"
+		checks = append(checks, diagnose.Outcome("credentials", diagnose.Skip, "the S3 credentials are checked by accessing the bucket"))
+	} else if flags.Backend == mountpkg.Local || storage.IsLocalEndpoint(flags.CustomEndpoint) {
+		scheme = "file"
+		checks = append(checks, diagnose.Outcome("credentials", diagnose.Skip, "local buckets need no credentials"))
+	} else {
+		checks = append(checks, diagnose.CredentialsCheck(flags.KeyFile, flags.TokenUrl, flags.ImpersonateServiceAccount,
+			flags.ReuseTokenFromUrl, flags.AllowCredentialExecutables, bool(mountConfig.AuthConfig.AnonymousAccess)))
+	}
+
+	bucketName := arg
+	if isMountPointArg(ctx, arg) {
+		mountPoint, err := resolveMountPoint(arg)
+		if err != nil {
+			return append(checks, diagnose.Outcome("mount", diagnose.Fail, err.Error()))
+		}
+
+		mi, mountChecks := diagnose.MountPointChecks(mountPoint)
+		checks = append(checks, mountChecks...)
+
+		// The file system is named gcsfuse when mounting all the buckets.
+		bucketName = ""
+		if mi != nil && mi.Source != "gcsfuse" {
+			bucketName = mi.Source
+		}
+	} else {
+		checks = append(checks, diagnose.MountOptionsCheck(flags.MountOptions))
+	}
+	checks = append(checks, diagnose.FuseDeviceCheck())
+
+	tempDir := flags.TempDir
+	if tempDir == "" {
+		tempDir = os.TempDir()
+	}
+	checks = append(checks, diagnose.DirectoryCheck("temp-dir", tempDir, minFreeDirBytes))
+	if config.IsFileCacheEnabled(mountConfig) {
+		var minFreeBytes uint64 = minFreeDirBytes
+		if mountConfig.FileCacheConfig.MaxSizeMB > 0 {
+			minFreeBytes = uint64(mountConfig.FileCacheConfig.MaxSizeMB) << 20
+		}
+		checks = append(checks, diagnose.DirectoryCheck("cache-dir", string(mountConfig.CacheDir), minFreeBytes))
+	}
+
+	if bucketName == "" {
+		return append(checks, diagnose.Outcome("bucket", diagnose.Skip, "pass a bucket name to check the access to it"))
+	}
+
+	// The storage control client, created with HNS enabled, gets the bucket
+	// type.
+	hnsConfig := *mountConfig
+	hnsConfig.EnableHNS = true
+	userAgent := getUserAgent(flags.AppName, getConfigForUserAgent(mountConfig))
+	storageHandle, err := createStorageHandle(flags, &hnsConfig, userAgent)
+	if err != nil {
+		return append(checks, diagnose.Outcome("bucket", diagnose.Fail, fmt.Sprintf("can't create the storage client: %v", err)))
+	}
+
+	var prefix string
+	if flags.OnlyDir != "" {
+		prefix = path.Clean(flags.OnlyDir) + "/"
+	}
+	return append(checks, diagnose.BucketChecks(storageHandle.BucketHandle(bucketName, flags.BillingProject), scheme, prefix)...)
+}
+
+// isMountPointArg tells whether the argument of diagnose is a mount point
+// rather than a bucket name. Bucket names can't contain slashes, so only bare
+// names are stat'ed, in case they name a directory. Stat'ing the mount point
+// of a crashed gcsfuse fails with ENOTCONN, and that of a hung one blocks.
+func isMountPointArg(ctx context.Context, arg string) bool {
+	if strings.Contains(arg, "/") {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, diagnose.CheckTimeout)
+	defer cancel()
+	fi, err := diagnose.Stat(ctx, arg)
+	return (err == nil && fi.IsDir()) || errors.Is(err, syscall.ENOTCONN) || ctx.Err() != nil
+}
+
+// resolveMountPoint makes the mount point absolute, like /proc/self/mountinfo
+// lists it. Symlinks are resolved in its parent only, as resolving the mount
+// point itself fails if gcsfuse crashed.
+func resolveMountPoint(mountPoint string) (string, error) {
+	mountPoint, err := filepath.Abs(mountPoint)
+	if err != nil {
+		return "", err
+	}
+	parent, err := filepath.EvalSymlinks(filepath.Dir(mountPoint))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(parent, filepath.Base(mountPoint)), nil
+}
+
+func runDiagnose(c *cli.Context) (err error) {
+	if len(c.Args()) != 1 {
+		return fmt.Errorf(
+			"%s diagnose takes one argument. Run `%s diagnose --help` for more info.",
+			path.Base(os.Args[0]),
+			path.Base(os.Args[0]))
+	}
+
+	err = resolvePathForTheFlagsInContext(c)
+	if err != nil {
+		return fmt.Errorf("Resolving path: %w", err)
+	}
+
+	flags, err := populateFlags(c)
+	if err != nil {
+		return fmt.Errorf("parsing flags failed: %w", err)
+	}
+
+	mountConfig, err := config.ParseConfigFile(flags.ConfigFile)
+	if err != nil {
+		return fmt.Errorf("parsing config file failed: %w", err)
+	}
+	config.OverrideWithAnonymousAccessFlag(c, mountConfig, flags.AnonymousAccess)
+
+	err = resolveConfigFilePaths(mountConfig)
+	if err != nil {
+		return fmt.Errorf("Resolving path: %w", err)
+	}
+
+	// Keep the report readable.
+	logger.SetLogSeverity(config.ERROR)
+
+	ctx := context.Background()
+	report := diagnose.Run(ctx, diagnoseChecks(ctx, c.Args()[0], flags, mountConfig))
+	if _, err = report.WriteTo(os.Stdout); err != nil {
+		return
+	}
+
+	if report.Failed() > 0 {
+		return errors.New("diagnose: some checks failed")
+	}
+	return
+}
@@ -0,0 +1,116 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/config"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/diagnose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/urfave/cli"
+)
+
+func TestDiagnose(t *testing.T) { suite.Run(t, new(DiagnoseTest)) }
+
+type DiagnoseTest struct {
+	suite.Suite
+}
+
+func (t *DiagnoseTest) TestDiagnoseCommandTakesTheMountFlags() {
+	app := newApp()
+	app.Action = func(*cli.Context) { t.T().Fatal("mounting instead of diagnosing") }
+	var flags *flagStorage
+	app.Commands[0].Action = func(c *cli.Context) {
+		var err error
+		flags, err = populateFlags(c)
+		require.NoError(t.T(), err)
+		assert.Equal(t.T(), []string{"b"}, []string(c.Args()))
+	}
+
+	err := app.Run([]string{"gcsfuse", "diagnose", "--only-dir", "d", "--temp-dir", "/tmp", "b"})
+
+	require.NoError(t.T(), err)
+	require.NotNil(t.T(), flags)
+	assert.Equal(t.T(), "d", flags.OnlyDir)
+	assert.Equal(t.T(), "/tmp", flags.TempDir)
+}
+
+func (t *DiagnoseTest) TestResolveMountPoint() {
+	dir := t.T().TempDir()
+	require.NoError(t.T(), os.Symlink(dir, filepath.Join(dir, "link")))
+	realDir, err := filepath.EvalSymlinks(dir)
+	require.NoError(t.T(), err)
+
+	mountPoint, err := resolveMountPoint(filepath.Join(dir, "link", "mnt"))
+
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), filepath.Join(realDir, "mnt"), mountPoint)
+}
+
+func (t *DiagnoseTest) TestDiagnoseChecksOfDirectoryNotMounted() {
+	mountPoint := t.T().TempDir()
+	flags := &flagStorage{TempDir: t.T().TempDir()}
+	mountConfig := config.NewMountConfig()
+	mountConfig.AuthConfig.AnonymousAccess = true
+
+	report := diagnose.Run(context.Background(), diagnoseChecks(context.Background(), mountPoint, flags, mountConfig))
+
+	var checks []string
+	for _, result := range report.Results {
+		checks = append(checks, result.Check)
+	}
+	assert.Equal(t.T(), []string{"credentials", "mount", "fuse device", "temp-dir", "bucket"}, checks)
+	assert.Equal(t.T(), diagnose.Fail, report.Results[1].Status)
+	assert.Equal(t.T(), diagnose.Skip, report.Results[4].Status)
+}
+
+func (t *DiagnoseTest) TestDiagnoseChecksOfBucket() {
+	flags := &flagStorage{TempDir: t.T().TempDir(), MountOptions: map[string]string{"ro": ""}}
+	mountConfig := config.NewMountConfig()
+	mountConfig.AuthConfig.AnonymousAccess = true
+	mountConfig.CacheDir = config.CacheDir(t.T().TempDir())
+	mountConfig.FileCacheConfig.MaxSizeMB = 1
+
+	checks := diagnoseChecks(context.Background(), "some-bucket-that-is-not-a-file", flags, mountConfig)
+
+	var names []string
+	for _, check := range checks[:4] {
+		names = append(names, check.Name)
+	}
+	assert.Equal(t.T(), []string{"credentials", "mount options", "fuse device", "temp-dir"}, names)
+	assert.Equal(t.T(), "cache-dir", checks[4].Name)
+}
+
+func (t *DiagnoseTest) TestIsMountPointArg() {
+	dir := t.T().TempDir()
+	require.NoError(t.T(), os.Mkdir(filepath.Join(dir, "mnt"), 0755))
+	require.NoError(t.T(), os.WriteFile(filepath.Join(dir, "file"), nil, 0644))
+	wd, err := os.Getwd()
+	require.NoError(t.T(), err)
+	require.NoError(t.T(), os.Chdir(dir))
+	defer func() { require.NoError(t.T(), os.Chdir(wd)) }()
+	ctx := context.Background()
+
+	assert.True(t.T(), isMountPointArg(ctx, "mnt"))
+	assert.True(t.T(), isMountPointArg(ctx, "./missing"))
+	assert.False(t.T(), isMountPointArg(ctx, "file"))
+	assert.False(t.T(), isMountPointArg(ctx, "some-bucket"))
+}
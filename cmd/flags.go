@@ -22,8 +22,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/canned"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/config"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/mount"
 	mountpkg "github.com/googlecloudplatform/gcsfuse/v2/internal/mount"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/util"
@@ -47,6 +49,7 @@ func init() {
 
 USAGE:
    {{.Name}} {{if .Flags}}[global options]{{end}} [bucket] mountpoint
+   {{.Name}} diagnose {{if .Flags}}[global options]{{end}} <mount point|bucket>
    {{if .Version}}
 VERSION:
    {{.Version}}
@@ -179,6 +182,12 @@ func newApp() (app *cli.App) {
 				Usage: "Absolute path to JSON key file for use with GCS. (default: none, Google application default credentials used)",
 			},
 
+			cli.StringFlag{
+				Name:  "aws-credentials-file",
+				Value: "",
+				Usage: "Path to the AWS shared credentials file used with --backend s3. (default: none, the AWS or MinIO environment variables, or else ~/.aws/credentials, are used)",
+			},
+
 			cli.StringFlag{
 				Name:  "token-url",
 				Value: "",
@@ -190,6 +199,25 @@ func newApp() (app *cli.App) {
 				Usage: "If false, the token acquired from token-url is not reused.",
 			},
 
+			cli.StringFlag{
+				Name:  "impersonate-service-account",
+				Value: "",
+				Usage: "The service account to impersonate with the credentials, or a comma-separated delegation chain ending with it, each account being allowed to impersonate the next.",
+			},
+
+			cli.BoolFlag{
+				Name:  "allow-credential-executables",
+				Usage: "Allow the external account credentials of the key-file to run the executable they are sourced from.",
+			},
+
+			cli.StringFlag{
+				Name:  "bucket-credentials-file",
+				Value: "",
+				Usage: "The YAML or JSON file mapping the names or patterns of buckets to the key-file, token-url or impersonate-service-account, " +
+					"billing-project and client-protocol to access them with, e.g. to mount the buckets of different projects with _. " +
+					"The default value \"\" accesses all the buckets with those of the mount.",
+			},
+
 			cli.Float64Flag{
 				Name:  "limit-bytes-per-sec",
 				Value: -1,
@@ -270,6 +298,16 @@ func newApp() (app *cli.App) {
 					" upload to Cloud Storage. (default: system default, likely /tmp)",
 			},
 
+			cli.StringFlag{
+				Name:  "backend",
+				Value: string(mountpkg.GCS),
+				Usage: "The API of the object store: 'gcs', 's3' for S3-compatible stores like MinIO, " +
+					"at --custom-endpoint or else at AWS, or 'local' for the subdirectories of the " +
+					"directory at a --custom-endpoint like file:///path/to/buckets. A --custom-endpoint " +
+					"like s3://host:port, or s3+http://host:port without TLS, selects 's3' too, and a " +
+					"file:// one selects 'local'.",
+			},
+
 			cli.StringFlag{
 				Name:  "client-protocol",
 				Value: string(mountpkg.HTTP1),
@@ -312,7 +350,55 @@ func newApp() (app *cli.App) {
 			cli.StringFlag{
 				Name:  "experimental-opentelemetry-collector-address",
 				Value: "",
-				Usage: "Experimental: Export metrics to the OpenTelemetry collector at this address.",
+				Usage: "Experimental: Export metrics to the OpenTelemetry collector at this address (host:port), with OTLP.",
+			},
+
+			cli.StringFlag{
+				Name:  "experimental-opentelemetry-collector-protocol",
+				Value: monitor.OTLPProtocolGRPC,
+				Usage: "Experimental: The OTLP transport used to export metrics to the OpenTelemetry collector: \"grpc\" or \"http\".",
+			},
+
+			cli.IntFlag{
+				Name:  "prometheus-port",
+				Value: 0,
+				Usage: "Serve metrics in the Prometheus exposition format at /metrics on this port. The default value 0 indicates no serving.",
+			},
+
+			cli.StringFlag{
+				Name:  "prometheus-address",
+				Value: "127.0.0.1",
+				Usage: "The address on which --prometheus-port is opened. Set it to 0.0.0.0 to serve metrics on all interfaces.",
+			},
+
+			cli.StringFlag{
+				Name:  "experimental-tracing-exporter",
+				Value: "",
+				Usage: "Experimental: Export a trace per file system operation, down to the GCS requests it makes: \"otlp\" to the OpenTelemetry collector at --experimental-opentelemetry-collector-address, or \"file\" to --experimental-tracing-file. The default value \"\" indicates no tracing.",
+			},
+
+			cli.StringFlag{
+				Name:  "experimental-tracing-file",
+				Value: "",
+				Usage: "Experimental: The file to which the \"file\" tracing exporter appends spans, as JSON.",
+			},
+
+			cli.Float64Flag{
+				Name:  "experimental-tracing-sample-ratio",
+				Value: 1,
+				Usage: "Experimental: The fraction of file system operations to trace, between 0 and 1.",
+			},
+
+			cli.StringFlag{
+				Name:  "experimental-access-stats-file",
+				Value: "",
+				Usage: "Experimental: Account the bytes read and written, ops, file cache hits and GCS requests per file and directory, and write them as JSON to this file when gcsfuse receives SIGUSR1, or when this file followed by \".request\" is created. The default value \"\" indicates no accounting.",
+			},
+
+			cli.StringFlag{
+				Name:  "experimental-admin-socket",
+				Value: "",
+				Usage: "Experimental: Serve an HTTP API to inspect the mount and invalidate its caches on this unix socket, which only the owner of the mount can use. The default value \"\" indicates no API.",
 			},
 
 			cli.StringFlag{
@@ -374,6 +460,21 @@ func newApp() (app *cli.App) {
 				Usage: "Print debug messages when a mutex is held too long.",
 			},
 
+			cli.StringFlag{
+				Name:  "fake-bucket-manifest",
+				Value: "",
+				Usage: "When mounting the in-memory fake bucket named " + canned.FakeBucketName + ", create its objects from this YAML or JSON manifest, " +
+					"which can also inject latency and errors in the calls to the bucket, or from the tree of this directory. " +
+					"The default value \"\" indicates canned objects.",
+			},
+
+			cli.StringFlag{
+				Name:  "fault-injection-file",
+				Value: "",
+				Usage: "Inject latency, errors and truncated reads in the calls to the bucket, following the rules of this YAML or JSON file, " +
+					"to test the recovery from a flaky network. The default value \"\" indicates no faults.",
+			},
+
 			/////////////////////////
 			// Post-mount actions
 			/////////////////////////
@@ -381,10 +482,11 @@ func newApp() (app *cli.App) {
 			cli.StringFlag{
 				Name:  ExperimentalMetadataPrefetchOnMountFlag,
 				Value: config.DefaultExperimentalMetadataPrefetchOnMount,
-				Usage: "Experimental: This indicates whether or not to prefetch the metadata (prefilling of metadata caches and creation of inodes) of the mounted bucket at the time of mounting the bucket. Supported values: \"disabled\", \"sync\" and \"async\". Any other values will return error on mounting. This is applicable only to static mounting, and not to dynamic mounting.",
+				Usage: "Experimental: This indicates whether or not to prefetch the metadata (prefilling of the stat and type caches, by listing the bucket in parallel) of the mounted bucket at the time of mounting the bucket. Prefetching stops once the fetched metadata fills metadata-cache:stat-cache-max-size-mb. Supported values: \"disabled\", \"sync\" and \"async\". Any other values will return error on mounting. This is applicable only to static mounting, and not to dynamic mounting.",
 			},
 		},
 	}
+	app.Commands = []cli.Command{newDiagnoseCommand(app.Flags)}
 
 	return
 }
@@ -409,8 +511,12 @@ type flagStorage struct {
 	CustomEndpoint                     *url.URL
 	BillingProject                     string
 	KeyFile                            string
+	AwsCredentialsFile                 string
 	TokenUrl                           string
 	ReuseTokenFromUrl                  bool
+	ImpersonateServiceAccount          string
+	AllowCredentialExecutables         bool
+	BucketCredentialsFile              string
 	EgressBandwidthLimitBytesPerSecond float64
 	OpRateLimitHz                      float64
 	SequentialReadSizeMb               int32
@@ -427,6 +533,7 @@ type flagStorage struct {
 	RetryMultiplier            float64
 	LocalFileCache             bool
 	TempDir                    string
+	Backend                    mountpkg.Backend
 	ClientProtocol             mountpkg.ClientProtocol
 	MaxConnsPerHost            int
 	MaxIdleConnsPerHost        int
@@ -435,6 +542,14 @@ type flagStorage struct {
 	// Monitoring & Logging
 	StackdriverExportInterval  time.Duration
 	OtelCollectorAddress       string
+	OtelCollectorProtocol      string
+	PrometheusPort             int
+	PrometheusAddress          string
+	TracingExporter            string
+	TracingFile                string
+	TracingSampleRatio         float64
+	AccessStatsFile            string
+	AdminSocket                string
 	LogFile                    string
 	LogFormat                  string
 	ExperimentalEnableJsonRead bool
@@ -448,6 +563,9 @@ type flagStorage struct {
 	DebugInvariants bool
 	DebugMutex      bool
 
+	FakeBucketManifest string
+	FaultInjectionFile string
+
 	// Post-mount actions
 
 	// ExperimentalMetadataPrefetchOnMount indicates whether or not to prefetch the metadata of the mounted bucket at the time of mounting the bucket.
@@ -495,11 +613,46 @@ func resolvePathForTheFlagsInContext(c *cli.Context) (err error) {
 		return fmt.Errorf("resolving for key-file: %w", err)
 	}
 
+	err = resolvePathForTheFlagInContext("aws-credentials-file", c)
+	if err != nil {
+		return fmt.Errorf("resolving for aws-credentials-file: %w", err)
+	}
+
+	err = resolvePathForTheFlagInContext("bucket-credentials-file", c)
+	if err != nil {
+		return fmt.Errorf("resolving for bucket-credentials-file: %w", err)
+	}
+
 	err = resolvePathForTheFlagInContext("config-file", c)
 	if err != nil {
 		return fmt.Errorf("resolving for config-file: %w", err)
 	}
 
+	err = resolvePathForTheFlagInContext("experimental-tracing-file", c)
+	if err != nil {
+		return fmt.Errorf("resolving for experimental-tracing-file: %w", err)
+	}
+
+	err = resolvePathForTheFlagInContext("experimental-access-stats-file", c)
+	if err != nil {
+		return fmt.Errorf("resolving for experimental-access-stats-file: %w", err)
+	}
+
+	err = resolvePathForTheFlagInContext("experimental-admin-socket", c)
+	if err != nil {
+		return fmt.Errorf("resolving for experimental-admin-socket: %w", err)
+	}
+
+	err = resolvePathForTheFlagInContext("fake-bucket-manifest", c)
+	if err != nil {
+		return fmt.Errorf("resolving for fake-bucket-manifest: %w", err)
+	}
+
+	err = resolvePathForTheFlagInContext("fault-injection-file", c)
+	if err != nil {
+		return fmt.Errorf("resolving for fault-injection-file: %w", err)
+	}
+
 	return
 }
 
@@ -510,6 +663,11 @@ func resolveConfigFilePaths(mountConfig *config.MountConfig) (err error) {
 		return
 	}
 
+	mountConfig.LogConfig.AuditFilePath, err = resolveFilePath(mountConfig.LogConfig.AuditFilePath, "logging: audit-file-path")
+	if err != nil {
+		return
+	}
+
 	// Resolve cache-dir path
 	resolvedPath, err := resolveFilePath(string(mountConfig.CacheDir), "cache-dir")
 	mountConfig.CacheDir = config.CacheDir(resolvedPath)
@@ -517,6 +675,16 @@ func resolveConfigFilePaths(mountConfig *config.MountConfig) (err error) {
 		return
 	}
 
+	mountConfig.MetadataCacheConfig.SharedCacheSocket, err = resolveFilePath(mountConfig.MetadataCacheConfig.SharedCacheSocket, "metadata-cache: shared-cache-socket")
+	if err != nil {
+		return
+	}
+
+	mountConfig.CacheInvalidationConfig.NotificationFile, err = resolveFilePath(mountConfig.CacheInvalidationConfig.NotificationFile, "cache-invalidation: notification-file")
+	if err != nil {
+		return
+	}
+
 	return
 }
 
@@ -559,8 +727,12 @@ func populateFlags(c *cli.Context) (flags *flagStorage, err error) {
 		AnonymousAccess:                    c.Bool("anonymous-access"),
 		BillingProject:                     c.String("billing-project"),
 		KeyFile:                            c.String("key-file"),
+		AwsCredentialsFile:                 c.String("aws-credentials-file"),
 		TokenUrl:                           c.String("token-url"),
 		ReuseTokenFromUrl:                  c.BoolT("reuse-token-from-url"),
+		ImpersonateServiceAccount:          c.String("impersonate-service-account"),
+		AllowCredentialExecutables:         c.Bool("allow-credential-executables"),
+		BucketCredentialsFile:              c.String("bucket-credentials-file"),
 		EgressBandwidthLimitBytesPerSecond: c.Float64("limit-bytes-per-sec"),
 		OpRateLimitHz:                      c.Float64("limit-ops-per-sec"),
 		SequentialReadSizeMb:               int32(c.Int("sequential-read-size-mb")),
@@ -577,6 +749,7 @@ func populateFlags(c *cli.Context) (flags *flagStorage, err error) {
 		// This flag is deprecated and we have plans to remove the implementation related to this flag in next release.
 		LocalFileCache:             false,
 		TempDir:                    c.String("temp-dir"),
+		Backend:                    mountpkg.Backend(strings.ToLower(c.String("backend"))),
 		ClientProtocol:             clientProtocol,
 		MaxConnsPerHost:            c.Int("max-conns-per-host"),
 		MaxIdleConnsPerHost:        c.Int("max-idle-conns-per-host"),
@@ -585,6 +758,14 @@ func populateFlags(c *cli.Context) (flags *flagStorage, err error) {
 		// Monitoring & Logging
 		StackdriverExportInterval:  c.Duration("stackdriver-export-interval"),
 		OtelCollectorAddress:       c.String("experimental-opentelemetry-collector-address"),
+		OtelCollectorProtocol:      c.String("experimental-opentelemetry-collector-protocol"),
+		PrometheusPort:             c.Int("prometheus-port"),
+		PrometheusAddress:          c.String("prometheus-address"),
+		TracingExporter:            c.String("experimental-tracing-exporter"),
+		TracingFile:                c.String("experimental-tracing-file"),
+		TracingSampleRatio:         c.Float64("experimental-tracing-sample-ratio"),
+		AccessStatsFile:            c.String("experimental-access-stats-file"),
+		AdminSocket:                c.String("experimental-admin-socket"),
 		LogFile:                    c.String("log-file"),
 		LogFormat:                  c.String("log-format"),
 		ExperimentalEnableJsonRead: c.Bool("experimental-enable-json-read"),
@@ -598,6 +779,9 @@ func populateFlags(c *cli.Context) (flags *flagStorage, err error) {
 		DebugInvariants: c.Bool("debug_invariants"),
 		DebugMutex:      c.Bool("debug_mutex"),
 
+		FakeBucketManifest: c.String("fake-bucket-manifest"),
+		FaultInjectionFile: c.String("fault-injection-file"),
+
 		// Post-mount actions
 		ExperimentalMetadataPrefetchOnMount: c.String(ExperimentalMetadataPrefetchOnMountFlag),
 	}
@@ -634,6 +818,10 @@ func validateFlags(flags *flagStorage) (err error) {
 		return fmt.Errorf("client protocol: %s is not valid", flags.ClientProtocol)
 	}
 
+	if flags.Backend != "" && !flags.Backend.IsValid() {
+		return fmt.Errorf("backend: %s is not valid", flags.Backend)
+	}
+
 	if err = validateExperimentalMetadataPrefetchOnMount(flags.ExperimentalMetadataPrefetchOnMount); err != nil {
 		return fmt.Errorf("%s: is not valid; error = %w", ExperimentalMetadataPrefetchOnMountFlag, err)
 	}
@@ -642,6 +830,32 @@ func validateFlags(flags *flagStorage) (err error) {
 		return fmt.Errorf("kernelListCacheTtlSeconds: %w", err)
 	}
 
+	if flags.OtelCollectorProtocol != "" && flags.OtelCollectorProtocol != monitor.OTLPProtocolGRPC && flags.OtelCollectorProtocol != monitor.OTLPProtocolHTTP {
+		return fmt.Errorf("experimental-opentelemetry-collector-protocol: %s is not valid", flags.OtelCollectorProtocol)
+	}
+
+	if flags.PrometheusPort < 0 || flags.PrometheusPort > 65535 {
+		return fmt.Errorf("prometheus-port: %d is not a valid port", flags.PrometheusPort)
+	}
+
+	switch flags.TracingExporter {
+	case "":
+	case monitor.TracingExporterOTLP:
+		if flags.OtelCollectorAddress == "" {
+			return fmt.Errorf("experimental-tracing-exporter: %s requires experimental-opentelemetry-collector-address", flags.TracingExporter)
+		}
+	case monitor.TracingExporterFile:
+		if flags.TracingFile == "" {
+			return fmt.Errorf("experimental-tracing-exporter: %s requires experimental-tracing-file", flags.TracingExporter)
+		}
+	default:
+		return fmt.Errorf("experimental-tracing-exporter: %s is not valid", flags.TracingExporter)
+	}
+
+	if flags.TracingSampleRatio < 0 || flags.TracingSampleRatio > 1 {
+		return fmt.Errorf("experimental-tracing-sample-ratio: %v is not between 0 and 1", flags.TracingSampleRatio)
+	}
+
 	return
 }
 
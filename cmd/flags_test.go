@@ -79,6 +79,7 @@ func (t *FlagsTest) Defaults() {
 
 	// GCS
 	assert.Equal(t.T(), "", f.KeyFile)
+	assert.Equal(t.T(), "", f.AwsCredentialsFile)
 	assert.Equal(t.T(), -1, f.EgressBandwidthLimitBytesPerSecond)
 	assert.Equal(t.T(), -1, f.OpRateLimitHz)
 	assert.True(t.T(), f.ReuseTokenFromUrl)
@@ -91,12 +92,21 @@ func (t *FlagsTest) Defaults() {
 	assert.Equal(t.T(), mount.DefaultStatOrTypeCacheTTL, f.TypeCacheTTL)
 	assert.Equal(t.T(), 0, f.HttpClientTimeout)
 	assert.Equal(t.T(), "", f.TempDir)
+	assert.Equal(t.T(), mountpkg.GCS, f.Backend)
 	assert.Equal(t.T(), 2, f.RetryMultiplier)
 	assert.False(t.T(), f.EnableNonexistentTypeCache)
 	assert.Equal(t.T(), 0, f.MaxConnsPerHost)
 
 	// Logging
 	assert.True(t.T(), f.DebugFuseErrors)
+	assert.Equal(t.T(), 0, f.PrometheusPort)
+	assert.Equal(t.T(), "127.0.0.1", f.PrometheusAddress)
+	assert.Equal(t.T(), "grpc", f.OtelCollectorProtocol)
+	assert.Equal(t.T(), "", f.TracingExporter)
+	assert.Equal(t.T(), "", f.TracingFile)
+	assert.Equal(t.T(), 1.0, f.TracingSampleRatio)
+	assert.Equal(t.T(), "", f.AccessStatsFile)
+	assert.Equal(t.T(), "", f.AdminSocket)
 
 	// Debugging
 	assert.False(t.T(), f.DebugFuse)
@@ -121,6 +131,7 @@ func (t *FlagsTest) Bools() {
 		"experimental-enable-json-read",
 		"ignore-interrupts",
 		"anonymous-access",
+		"allow-credential-executables",
 	}
 
 	var args []string
@@ -144,6 +155,7 @@ func (t *FlagsTest) Bools() {
 	assert.True(t.T(), f.ExperimentalEnableJsonRead)
 	assert.True(t.T(), f.IgnoreInterrupts)
 	assert.True(t.T(), f.AnonymousAccess)
+	assert.True(t.T(), f.AllowCredentialExecutables)
 
 	// --foo=false form
 	args = nil
@@ -160,6 +172,7 @@ func (t *FlagsTest) Bools() {
 	assert.False(t.T(), f.DebugHTTP)
 	assert.False(t.T(), f.DebugInvariants)
 	assert.False(t.T(), f.EnableNonexistentTypeCache)
+	assert.False(t.T(), f.AllowCredentialExecutables)
 
 	// --foo=true form
 	args = nil
@@ -188,6 +201,8 @@ func (t *FlagsTest) DecimalNumbers() {
 		"--max-idle-conns-per-host=100",
 		"--max-conns-per-host=100",
 		"--kernel-list-cache-ttl-secs=234",
+		"--prometheus-port=9100",
+		"--experimental-tracing-sample-ratio=0.25",
 	}
 
 	f := parseArgs(t, args)
@@ -199,6 +214,8 @@ func (t *FlagsTest) DecimalNumbers() {
 	assert.Equal(t.T(), 100, f.MaxIdleConnsPerHost)
 	assert.Equal(t.T(), 100, f.MaxConnsPerHost)
 	assert.Equal(t.T(), 234, f.KernelListCacheTtlSeconds)
+	assert.Equal(t.T(), 9100, f.PrometheusPort)
+	assert.Equal(t.T(), 0.25, f.TracingSampleRatio)
 }
 
 func (t *FlagsTest) OctalNumbers() {
@@ -215,18 +232,38 @@ func (t *FlagsTest) OctalNumbers() {
 func (t *FlagsTest) Strings() {
 	args := []string{
 		"--key-file", "-asdf",
+		"--aws-credentials-file=/tmp/aws-credentials",
 		"--temp-dir=foobar",
 		"--only-dir=baz",
 		"--client-protocol=HTTP2",
+		"--backend=S3",
 		"--experimental-metadata-prefetch-on-mount=async",
+		"--experimental-opentelemetry-collector-protocol=http",
+		"--prometheus-address=0.0.0.0",
+		"--experimental-tracing-exporter=file",
+		"--experimental-tracing-file=/tmp/traces.json",
+		"--fake-bucket-manifest=/tmp/manifest.yaml",
+		"--fault-injection-file=/tmp/faults.yaml",
+		"--impersonate-service-account=a@p.iam.gserviceaccount.com,b@p.iam.gserviceaccount.com",
+		"--bucket-credentials-file=/tmp/buckets.yaml",
 	}
 
 	f := parseArgs(t, args)
 	assert.Equal(t.T(), "-asdf", f.KeyFile)
+	assert.Equal(t.T(), "/tmp/aws-credentials", f.AwsCredentialsFile)
 	assert.Equal(t.T(), "foobar", f.TempDir)
 	assert.Equal(t.T(), "baz", f.OnlyDir)
 	assert.Equal(t.T(), mountpkg.HTTP2, f.ClientProtocol)
+	assert.Equal(t.T(), mountpkg.S3, f.Backend)
 	assert.Equal(t.T(), config.ExperimentalMetadataPrefetchOnMountAsynchronous, f.ExperimentalMetadataPrefetchOnMount)
+	assert.Equal(t.T(), "http", f.OtelCollectorProtocol)
+	assert.Equal(t.T(), "0.0.0.0", f.PrometheusAddress)
+	assert.Equal(t.T(), "file", f.TracingExporter)
+	assert.Equal(t.T(), "/tmp/traces.json", f.TracingFile)
+	assert.Equal(t.T(), "/tmp/manifest.yaml", f.FakeBucketManifest)
+	assert.Equal(t.T(), "/tmp/faults.yaml", f.FaultInjectionFile)
+	assert.Equal(t.T(), "a@p.iam.gserviceaccount.com,b@p.iam.gserviceaccount.com", f.ImpersonateServiceAccount)
+	assert.Equal(t.T(), "/tmp/buckets.yaml", f.BucketCredentialsFile)
 }
 
 func (t *FlagsTest) Durations() {
@@ -310,10 +347,19 @@ func (t *FlagsTest) TestResolvePathForTheFlagsInContext() {
 			appCtx.String("key-file"))
 		assert.Equal(t.T(), filepath.Join(currentWorkingDir, "config.yaml"),
 			appCtx.String("config-file"))
+		assert.Equal(t.T(), filepath.Join(currentWorkingDir, "traces.json"),
+			appCtx.String("experimental-tracing-file"))
+		assert.Equal(t.T(), filepath.Join(currentWorkingDir, "stats.json"),
+			appCtx.String("experimental-access-stats-file"))
+		assert.Equal(t.T(), filepath.Join(currentWorkingDir, "admin.sock"),
+			appCtx.String("experimental-admin-socket"))
 	}
 	// Simulate argv.
 	fullArgs := []string{"some_app", "--log-file=test.txt",
-		"--key-file=test.txt", "--config-file=config.yaml"}
+		"--key-file=test.txt", "--config-file=config.yaml",
+		"--experimental-tracing-file=traces.json",
+		"--experimental-access-stats-file=stats.json",
+		"--experimental-admin-socket=admin.sock"}
 
 	err = app.Run(fullArgs)
 
@@ -370,6 +416,19 @@ func (t *FlagsTest) TestValidateFlagsForValidSequentialReadSizeAndInValidClientP
 	assert.Equal(t.T(), "client protocol: http4 is not valid", err.Error())
 }
 
+func (t *FlagsTest) TestValidateFlagsForInvalidBackend() {
+	flags := &flagStorage{
+		SequentialReadSizeMb:                10,
+		ClientProtocol:                      mountpkg.HTTP1,
+		Backend:                             mountpkg.Backend("azure"),
+		ExperimentalMetadataPrefetchOnMount: config.DefaultExperimentalMetadataPrefetchOnMount,
+	}
+
+	err := validateFlags(flags)
+
+	assert.Equal(t.T(), "backend: azure is not valid", err.Error())
+}
+
 func (t *FlagsTest) TestValidateFlagsForValidSequentialReadSizeAndHTTP2ClientProtocol() {
 	flags := &flagStorage{
 		SequentialReadSizeMb:                10,
@@ -382,6 +441,85 @@ func (t *FlagsTest) TestValidateFlagsForValidSequentialReadSizeAndHTTP2ClientPro
 	assert.Equal(t.T(), nil, err)
 }
 
+func (t *FlagsTest) TestValidateFlagsForInvalidPrometheusPort() {
+	flags := &flagStorage{
+		SequentialReadSizeMb:                10,
+		ClientProtocol:                      mountpkg.ClientProtocol("http2"),
+		ExperimentalMetadataPrefetchOnMount: config.DefaultExperimentalMetadataPrefetchOnMount,
+		PrometheusPort:                      65536,
+	}
+
+	err := validateFlags(flags)
+
+	assert.Equal(t.T(), "prometheus-port: 65536 is not a valid port", err.Error())
+}
+
+func (t *FlagsTest) TestValidateFlagsForInvalidOtelCollectorProtocol() {
+	flags := &flagStorage{
+		SequentialReadSizeMb:                10,
+		ClientProtocol:                      mountpkg.ClientProtocol("http2"),
+		ExperimentalMetadataPrefetchOnMount: config.DefaultExperimentalMetadataPrefetchOnMount,
+		OtelCollectorProtocol:               "udp",
+	}
+
+	err := validateFlags(flags)
+
+	assert.Equal(t.T(), "experimental-opentelemetry-collector-protocol: udp is not valid", err.Error())
+}
+
+func (t *FlagsTest) TestValidateFlagsForTracingExporters() {
+	testCases := []struct {
+		name          string
+		flags         flagStorage
+		expectedError string
+	}{
+		{
+			name:  "otlp",
+			flags: flagStorage{TracingExporter: "otlp", OtelCollectorAddress: "localhost:4317"},
+		},
+		{
+			name:  "file",
+			flags: flagStorage{TracingExporter: "file", TracingFile: "/tmp/traces.json"},
+		},
+		{
+			name:          "otlp without collector",
+			flags:         flagStorage{TracingExporter: "otlp"},
+			expectedError: "experimental-tracing-exporter: otlp requires experimental-opentelemetry-collector-address",
+		},
+		{
+			name:          "file without path",
+			flags:         flagStorage{TracingExporter: "file"},
+			expectedError: "experimental-tracing-exporter: file requires experimental-tracing-file",
+		},
+		{
+			name:          "unsupported exporter",
+			flags:         flagStorage{TracingExporter: "zipkin"},
+			expectedError: "experimental-tracing-exporter: zipkin is not valid",
+		},
+		{
+			name:          "sample ratio above 1",
+			flags:         flagStorage{TracingSampleRatio: 1.5},
+			expectedError: "experimental-tracing-sample-ratio: 1.5 is not between 0 and 1",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func() {
+			flags := tc.flags
+			flags.SequentialReadSizeMb = 10
+			flags.ClientProtocol = mountpkg.ClientProtocol("http2")
+			flags.ExperimentalMetadataPrefetchOnMount = config.DefaultExperimentalMetadataPrefetchOnMount
+
+			err := validateFlags(&flags)
+
+			if tc.expectedError == "" {
+				assert.NoError(t.T(), err)
+			} else {
+				assert.EqualError(t.T(), err, tc.expectedError)
+			}
+		})
+	}
+}
+
 func (t *FlagsTest) TestValidateFlagsForSupportedExperimentalMetadataPrefetchOnMount() {
 	for _, input := range []string{
 		"disabled", "sync", "async",
@@ -422,7 +560,8 @@ func (t *FlagsTest) TestValidateFlagsForUnsupportedExperimentalMetadataPrefetchO
 func (t *FlagsTest) Test_resolveConfigFilePaths() {
 	mountConfig := &config.MountConfig{}
 	mountConfig.LogConfig = config.LogConfig{
-		FilePath: "~/test.txt",
+		FilePath:      "~/test.txt",
+		AuditFilePath: "~/audit.log",
 	}
 	mountConfig.CacheDir = "~/cache-dir"
 
@@ -432,6 +571,7 @@ func (t *FlagsTest) Test_resolveConfigFilePaths() {
 	homeDir, err := os.UserHomeDir()
 	assert.Equal(t.T(), nil, err)
 	assert.Equal(t.T(), filepath.Join(homeDir, "test.txt"), mountConfig.LogConfig.FilePath)
+	assert.Equal(t.T(), filepath.Join(homeDir, "audit.log"), mountConfig.LogConfig.AuditFilePath)
 	assert.EqualValues(t.T(), filepath.Join(homeDir, "cache-dir"), mountConfig.CacheDir)
 }
 
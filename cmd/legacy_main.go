@@ -21,13 +21,13 @@ package cmd
 
 import (
 	"fmt"
-	"io/fs"
 	"os"
 	"os/signal"
 	"path"
-	"path/filepath"
 	"strings"
+	"syscall"
 
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/admin"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/canned"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/config"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/locker"
@@ -76,6 +76,57 @@ func registerSIGINTHandler(mountPoint string) {
 	}()
 }
 
+// setDebugSources enables or disables the TRACE messages of the FUSE ops and
+// GCS requests whatever the log severity, as requested in the log config.
+func setDebugSources(logConfig config.LogConfig) {
+	logger.SetDebugSource(logger.DebugFuse, logConfig.DebugFuse)
+	logger.SetDebugSource(logger.DebugGCS, logConfig.DebugGCS)
+}
+
+// reloadLogConfig applies the log severity and the debug sources of the
+// config file again. As at startup, --debug_fuse, --debug_gcs and
+// --debug_mutex keep the severity at TRACE.
+func reloadLogConfig(flags *flagStorage) (logConfig config.LogConfig, err error) {
+	mountConfig, err := config.ParseConfigFile(flags.ConfigFile)
+	if err != nil {
+		return
+	}
+	config.OverrideWithLoggingFlags(mountConfig, flags.LogFile, flags.LogFormat,
+		flags.DebugFuse, flags.DebugGCS, flags.DebugMutex)
+
+	logger.SetLogSeverity(mountConfig.LogConfig.Severity)
+	setDebugSources(mountConfig.LogConfig)
+	logConfig = mountConfig.LogConfig
+	return
+}
+
+// registerSIGHUPHandler reloads the log config whenever gcsfuse receives
+// SIGHUP, so that traces can be captured without remounting. The rest of the
+// config file is not reloaded. As SIGHUP is handled, it no longer terminates
+// gcsfuse, e.g. a foreground one whose terminal is closed.
+func registerSIGHUPHandler(flags *flagStorage) {
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, syscall.SIGHUP)
+
+	go func() {
+		for range signalChan {
+			if flags.ConfigFile == "" {
+				logger.Infof("Received SIGHUP, but there is no config file to reload the log config from.")
+				continue
+			}
+			logger.Infof("Received SIGHUP, reloading the log config from %q...", flags.ConfigFile)
+
+			logConfig, err := reloadLogConfig(flags)
+			if err != nil {
+				logger.Errorf("Failed to reload the log config: %v", err)
+				continue
+			}
+			logger.Infof("Reloaded the log config: severity %s, debug-fuse %t, debug-gcs %t.",
+				logConfig.Severity, logConfig.DebugFuse, logConfig.DebugGCS)
+		}
+	}()
+}
+
 func getUserAgent(appName string, config string) string {
 	gcsfuseMetadataImageType := os.Getenv("GCSFUSE_METADATA_IMAGE_TYPE")
 	if len(gcsfuseMetadataImageType) > 0 {
@@ -100,8 +151,11 @@ func getConfigForUserAgent(mountConfig *config.MountConfig) string {
 	}
 	return fmt.Sprintf("%s:%s", isFileCacheEnabled, isFileCacheForRangeReadEnabled)
 }
-func createStorageHandle(flags *flagStorage, mountConfig *config.MountConfig, userAgent string) (storageHandle storage.StorageHandle, err error) {
-	storageClientConfig := storageutil.StorageClientConfig{
+
+// Return the config of the storage client of the mount.
+func newStorageClientConfig(flags *flagStorage, mountConfig *config.MountConfig, userAgent string) storageutil.StorageClientConfig {
+	return storageutil.StorageClientConfig{
+		Backend:                    flags.Backend,
 		ClientProtocol:             flags.ClientProtocol,
 		MaxConnsPerHost:            flags.MaxConnsPerHost,
 		MaxIdleConnsPerHost:        flags.MaxIdleConnsPerHost,
@@ -111,13 +165,21 @@ func createStorageHandle(flags *flagStorage, mountConfig *config.MountConfig, us
 		UserAgent:                  userAgent,
 		CustomEndpoint:             flags.CustomEndpoint,
 		KeyFile:                    flags.KeyFile,
+		AwsCredentialsFile:         flags.AwsCredentialsFile,
 		AnonymousAccess:            mountConfig.AuthConfig.AnonymousAccess,
 		TokenUrl:                   flags.TokenUrl,
 		ReuseTokenFromUrl:          flags.ReuseTokenFromUrl,
+		ImpersonateServiceAccount:  flags.ImpersonateServiceAccount,
+		AllowCredentialExecutables: flags.AllowCredentialExecutables,
 		ExperimentalEnableJsonRead: flags.ExperimentalEnableJsonRead,
 		GrpcConnPoolSize:           mountConfig.GrpcClientConfig.ConnPoolSize,
 		EnableHNS:                  mountConfig.EnableHNS,
+		EnableTracing:              flags.TracingExporter != "",
 	}
+}
+
+func createStorageHandle(flags *flagStorage, mountConfig *config.MountConfig, userAgent string) (storageHandle storage.StorageHandle, err error) {
+	storageClientConfig := newStorageClientConfig(flags, mountConfig, userAgent)
 	logger.Infof("UserAgent = %s\n", storageClientConfig.UserAgent)
 	storageHandle, err = storage.NewStorageHandle(context.Background(), storageClientConfig)
 	return
@@ -132,7 +194,8 @@ func mountWithArgs(
 	bucketName string,
 	mountPoint string,
 	flags *flagStorage,
-	mountConfig *config.MountConfig) (mfs *fuse.MountedFileSystem, err error) {
+	mountConfig *config.MountConfig,
+	adminServer *admin.Server) (mfs *fuse.MountedFileSystem, err error) {
 	// Enable invariant checking if requested.
 	if flags.DebugInvariants {
 		locker.EnableInvariantsCheck()
@@ -164,7 +227,8 @@ func mountWithArgs(
 		mountPoint,
 		flags,
 		mountConfig,
-		storageHandle)
+		storageHandle,
+		adminServer)
 
 	if err != nil {
 		err = fmt.Errorf("mountWithStorageHandle: %w", err)
@@ -208,29 +272,6 @@ func populateArgs(c *cli.Context) (
 	return
 }
 
-func callListRecursive(mountPoint string) (err error) {
-	logger.Debugf("Started recursive metadata-prefetch of directory: \"%s\" ...", mountPoint)
-	numItems := 0
-	err = filepath.WalkDir(mountPoint, func(path string, d fs.DirEntry, err error) error {
-		if err == nil {
-			numItems++
-			return err
-		}
-		if d == nil {
-			return fmt.Errorf("got error walking: path=\"%s\" does not exist, error = %w", path, err)
-		}
-		return fmt.Errorf("got error walking: path=\"%s\", dentry=\"%s\", isDir=%v, error = %w", path, d.Name(), d.IsDir(), err)
-	})
-
-	if err != nil {
-		return fmt.Errorf("failed in recursive metadata-prefetch of directory: \"%s\"; error = %w", mountPoint, err)
-	}
-
-	logger.Debugf("... Completed recursive metadata-prefetch of directory: \"%s\". Number of items discovered: %v", mountPoint, numItems)
-
-	return nil
-}
-
 func isDynamicMount(bucketName string) bool {
 	return bucketName == "" || bucketName == "_"
 }
@@ -262,6 +303,7 @@ func runCLIApp(c *cli.Context) (err error) {
 	// that means the logs generated by resolveConfigFilePaths below don't honour
 	// the user-provided log-format.
 	logger.SetLogFormat(mountConfig.LogConfig.Format)
+	setDebugSources(mountConfig.LogConfig)
 
 	err = resolveConfigFilePaths(mountConfig)
 	if err != nil {
@@ -396,15 +438,46 @@ func runCLIApp(c *cli.Context) (err error) {
 		return err
 	}
 
-	// The returned error is ignored as we do not enforce monitoring exporters
-	_ = monitor.EnableStackdriverExporter(flags.StackdriverExportInterval)
-	_ = monitor.EnableOpenTelemetryCollectorExporter(flags.OtelCollectorAddress)
+	// The mount doesn't fail if an exporter fails to start, as we do not
+	// enforce monitoring exporters.
+	if err := monitor.EnableMetricsExporters(monitor.MetricsConfig{
+		StackdriverExportInterval: flags.StackdriverExportInterval,
+		OtelCollectorAddress:      flags.OtelCollectorAddress,
+		OtelCollectorProtocol:     flags.OtelCollectorProtocol,
+		PrometheusPort:            flags.PrometheusPort,
+		PrometheusAddress:         flags.PrometheusAddress,
+	}); err != nil {
+		logger.Errorf("Failed to start metrics exporters: %v", err)
+	}
+
+	if err := monitor.EnableTracing(monitor.TracingConfig{
+		Exporter:              flags.TracingExporter,
+		OtelCollectorAddress:  flags.OtelCollectorAddress,
+		OtelCollectorProtocol: flags.OtelCollectorProtocol,
+		FilePath:              flags.TracingFile,
+		SampleRatio:           flags.TracingSampleRatio,
+	}); err != nil {
+		logger.Errorf("Failed to start tracing: %v", err)
+	}
+
+	// Set up the admin API, served once mounted.
+	var adminServer *admin.Server
+	if flags.AdminSocket != "" {
+		adminServer = admin.NewServer(admin.Config{
+			SocketPath: flags.AdminSocket,
+			MountPoint: mountPoint,
+			EffectiveConfig: struct {
+				Flags       *flagStorage        `json:"flags"`
+				MountConfig *config.MountConfig `json:"mount_config"`
+			}{flags, mountConfig},
+		})
+	}
 
 	// Mount, writing information about our progress to the writer that package
 	// daemonize gives us and telling it about the outcome.
 	var mfs *fuse.MountedFileSystem
 	{
-		mfs, err = mountWithArgs(bucketName, mountPoint, flags, mountConfig)
+		mfs, err = mountWithArgs(bucketName, mountPoint, flags, mountConfig, adminServer)
 
 		// This utility is to absorb the error
 		// returned by daemonize.SignalOutcome calls by simply
@@ -434,32 +507,28 @@ func runCLIApp(c *cli.Context) (err error) {
 			markMountFailure(err)
 			return err
 		}
-		if !isDynamicMount(bucketName) {
-			switch flags.ExperimentalMetadataPrefetchOnMount {
-			case config.ExperimentalMetadataPrefetchOnMountSynchronous:
-				if err = callListRecursive(mountPoint); err != nil {
-					markMountFailure(err)
-					return err
-				}
-			case config.ExperimentalMetadataPrefetchOnMountAsynchronous:
-				go func() {
-					if err := callListRecursive(mountPoint); err != nil {
-						logger.Errorf("Metadata-prefetch failed: %v", err)
-					}
-				}()
-			}
-		}
 		markSuccessfulMount()
 	}
 
 	// Let the user unmount with Ctrl-C (SIGINT).
 	registerSIGINTHandler(mfs.Dir())
+	registerSIGHUPHandler(flags)
+
+	// Let ops inspect and repair the mount through the admin API. The mount
+	// doesn't fail if the API fails to start, as it isn't needed to serve files.
+	if adminServer != nil {
+		if startErr := adminServer.Start(); startErr != nil {
+			logger.Errorf("Failed to start the admin API: %v", startErr)
+		} else {
+			defer adminServer.Stop()
+		}
+	}
 
 	// Wait for the file system to be unmounted.
 	err = mfs.Join(context.Background())
 
-	monitor.CloseStackdriverExporter()
-	monitor.CloseOpenTelemetryCollectorExporter()
+	monitor.CloseMetricsExporters()
+	monitor.CloseTracing()
 
 	if err != nil {
 		err = fmt.Errorf("MountedFileSystem.Join: %w", err)
@@ -16,11 +16,15 @@ package cmd
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"path"
 	"strings"
 	"testing"
 
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/config"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/util"
 
 	mountpkg "github.com/googlecloudplatform/gcsfuse/v2/internal/mount"
@@ -82,6 +86,21 @@ func (t *MainTest) TestCreateStorageHandle_WithClientProtocolAsGRPC() {
 	assert.NotEqual(t.T(), nil, storageHandle)
 }
 
+func (t *MainTest) TestCreateStorageHandle_WithS3Backend() {
+	flags := &flagStorage{
+		Backend:        mountpkg.S3,
+		ClientProtocol: mountpkg.HTTP1,
+		CustomEndpoint: &url.URL{Scheme: "http", Host: "localhost:9000"},
+	}
+	mountConfig := &config.MountConfig{}
+
+	storageHandle, err := createStorageHandle(flags, mountConfig, "AppName")
+
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), "b", storageHandle.BucketHandle("b", "").Name())
+	assert.Equal(t.T(), gcs.NonHierarchical, storageHandle.BucketHandle("b", "").BucketType())
+}
+
 func (t *MainTest) TestGetUserAgentWhenMetadataImageTypeEnvVarIsSet() {
 	os.Setenv("GCSFUSE_METADATA_IMAGE_TYPE", "DLVM")
 	defer os.Unsetenv("GCSFUSE_METADATA_IMAGE_TYPE")
@@ -192,7 +211,7 @@ func (t *MainTest) TestStringifyShouldReturnAllFlagsPassedInMountConfigAsMarshal
 	actual, err := util.Stringify(mountConfig)
 	assert.Equal(t.T(), nil, err)
 
-	expected := "{\"CreateEmptyFile\":false,\"Severity\":\"TRACE\",\"Format\":\"\",\"FilePath\":\"\\\"path\\\"to\\\"file\\\"\",\"LogRotateConfig\":{\"MaxFileSizeMB\":2,\"BackupFileCount\":2,\"Compress\":true},\"MaxSizeMB\":0,\"CacheFileForRangeRead\":false,\"EnableCrcCheck\":false,\"CacheDir\":\"\",\"TtlInSeconds\":0,\"TypeCacheMaxSizeMB\":0,\"StatCacheMaxSizeMB\":0,\"EnableEmptyManagedFolders\":false,\"KernelListCacheTtlSeconds\":0,\"ConnPoolSize\":0,\"AnonymousAccess\":false,\"EnableHNS\":true,\"IgnoreInterrupts\":false,\"DisableParallelDirops\":false}"
+	expected := "{\"CreateEmptyFile\":false,\"Severity\":\"TRACE\",\"Format\":\"\",\"FilePath\":\"\\\"path\\\"to\\\"file\\\"\",\"LogRotateConfig\":{\"MaxFileSizeMB\":2,\"BackupFileCount\":2,\"Compress\":true},\"AuditFilePath\":\"\",\"DebugFuse\":false,\"DebugGCS\":false,\"MaxSizeMB\":0,\"CacheFileForRangeRead\":false,\"EnableCrcCheck\":false,\"MinFreeDiskPercent\":0,\"FreeDiskCheckIntervalSecs\":0,\"CacheDir\":\"\",\"TtlInSeconds\":0,\"TypeCacheMaxSizeMB\":0,\"StatCacheMaxSizeMB\":0,\"SharedCacheSocket\":\"\",\"EnableEmptyManagedFolders\":false,\"KernelListCacheTtlSeconds\":0,\"ListingCacheTtlSeconds\":0,\"ConnPoolSize\":0,\"AnonymousAccess\":false,\"EnableHNS\":true,\"IgnoreInterrupts\":false,\"DisableParallelDirops\":false,\"NotificationListenAddress\":\"\",\"NotificationFile\":\"\",\"Reads\":{\"MaxAttempts\":0,\"InitialBackoffMs\":0,\"MaxBackoffMs\":0,\"Multiplier\":0,\"AttemptTimeoutMs\":0},\"Metadata\":{\"MaxAttempts\":0,\"InitialBackoffMs\":0,\"MaxBackoffMs\":0,\"Multiplier\":0,\"AttemptTimeoutMs\":0},\"Uploads\":{\"MaxAttempts\":0,\"InitialBackoffMs\":0,\"MaxBackoffMs\":0,\"Multiplier\":0,\"AttemptTimeoutMs\":0},\"BudgetRatio\":0,\"BudgetMinRetriesPerSec\":0,\"Enabled\":false,\"Percentile\":0,\"MinDelayMs\":0,\"MaxReadSizeKB\":0,\"StallTimeoutSecs\":0,\"StallMinThroughputKBPerSec\":0,\"StallMaxReopens\":0,\"ListBucketsProject\":\"\",\"BucketAllowlist\":null,\"BucketRegex\":\"\",\"BucketListTtlSecs\":0}"
 	assert.Equal(t.T(), expected, actual)
 }
 
@@ -204,7 +223,7 @@ func (t *MainTest) TestEnableHNSFlagFalse() {
 	actual, err := util.Stringify(mountConfig)
 	assert.Equal(t.T(), nil, err)
 
-	expected := "{\"CreateEmptyFile\":false,\"Severity\":\"\",\"Format\":\"\",\"FilePath\":\"\",\"LogRotateConfig\":{\"MaxFileSizeMB\":0,\"BackupFileCount\":0,\"Compress\":false},\"MaxSizeMB\":0,\"CacheFileForRangeRead\":false,\"EnableCrcCheck\":false,\"CacheDir\":\"\",\"TtlInSeconds\":0,\"TypeCacheMaxSizeMB\":0,\"StatCacheMaxSizeMB\":0,\"EnableEmptyManagedFolders\":false,\"KernelListCacheTtlSeconds\":0,\"ConnPoolSize\":0,\"AnonymousAccess\":false,\"EnableHNS\":false,\"IgnoreInterrupts\":false,\"DisableParallelDirops\":false}"
+	expected := "{\"CreateEmptyFile\":false,\"Severity\":\"\",\"Format\":\"\",\"FilePath\":\"\",\"LogRotateConfig\":{\"MaxFileSizeMB\":0,\"BackupFileCount\":0,\"Compress\":false},\"AuditFilePath\":\"\",\"DebugFuse\":false,\"DebugGCS\":false,\"MaxSizeMB\":0,\"CacheFileForRangeRead\":false,\"EnableCrcCheck\":false,\"MinFreeDiskPercent\":0,\"FreeDiskCheckIntervalSecs\":0,\"CacheDir\":\"\",\"TtlInSeconds\":0,\"TypeCacheMaxSizeMB\":0,\"StatCacheMaxSizeMB\":0,\"SharedCacheSocket\":\"\",\"EnableEmptyManagedFolders\":false,\"KernelListCacheTtlSeconds\":0,\"ListingCacheTtlSeconds\":0,\"ConnPoolSize\":0,\"AnonymousAccess\":false,\"EnableHNS\":false,\"IgnoreInterrupts\":false,\"DisableParallelDirops\":false,\"NotificationListenAddress\":\"\",\"NotificationFile\":\"\",\"Reads\":{\"MaxAttempts\":0,\"InitialBackoffMs\":0,\"MaxBackoffMs\":0,\"Multiplier\":0,\"AttemptTimeoutMs\":0},\"Metadata\":{\"MaxAttempts\":0,\"InitialBackoffMs\":0,\"MaxBackoffMs\":0,\"Multiplier\":0,\"AttemptTimeoutMs\":0},\"Uploads\":{\"MaxAttempts\":0,\"InitialBackoffMs\":0,\"MaxBackoffMs\":0,\"Multiplier\":0,\"AttemptTimeoutMs\":0},\"BudgetRatio\":0,\"BudgetMinRetriesPerSec\":0,\"Enabled\":false,\"Percentile\":0,\"MinDelayMs\":0,\"MaxReadSizeKB\":0,\"StallTimeoutSecs\":0,\"StallMinThroughputKBPerSec\":0,\"StallMaxReopens\":0,\"ListBucketsProject\":\"\",\"BucketAllowlist\":null,\"BucketRegex\":\"\",\"BucketListTtlSecs\":0}"
 	assert.Equal(t.T(), expected, actual)
 }
 
@@ -224,36 +243,10 @@ func (t *MainTest) TestStringifyShouldReturnAllFlagsPassedInFlagStorageAsMarshal
 	actual, err := util.Stringify(flags)
 	assert.Equal(t.T(), nil, err)
 
-	expected := "{\"AppName\":\"\",\"Foreground\":false,\"ConfigFile\":\"\",\"MountOptions\":{\"1\":\"one\",\"2\":\"two\",\"3\":\"three\"},\"DirMode\":0,\"FileMode\":0,\"Uid\":0,\"Gid\":0,\"ImplicitDirs\":false,\"OnlyDir\":\"\",\"RenameDirLimit\":0,\"IgnoreInterrupts\":false,\"CustomEndpoint\":null,\"BillingProject\":\"\",\"KeyFile\":\"\",\"TokenUrl\":\"\",\"ReuseTokenFromUrl\":false,\"EgressBandwidthLimitBytesPerSecond\":0,\"OpRateLimitHz\":0,\"SequentialReadSizeMb\":10,\"AnonymousAccess\":false,\"MaxRetrySleep\":0,\"StatCacheCapacity\":0,\"StatCacheTTL\":0,\"TypeCacheTTL\":0,\"KernelListCacheTtlSeconds\":-1,\"HttpClientTimeout\":0,\"MaxRetryDuration\":0,\"RetryMultiplier\":0,\"LocalFileCache\":false,\"TempDir\":\"\",\"ClientProtocol\":\"http4\",\"MaxConnsPerHost\":0,\"MaxIdleConnsPerHost\":0,\"EnableNonexistentTypeCache\":false,\"StackdriverExportInterval\":0,\"OtelCollectorAddress\":\"\",\"LogFile\":\"\",\"LogFormat\":\"\",\"ExperimentalEnableJsonRead\":false,\"DebugFuseErrors\":false,\"DebugFuse\":false,\"DebugFS\":false,\"DebugGCS\":false,\"DebugHTTP\":false,\"DebugInvariants\":false,\"DebugMutex\":false,\"ExperimentalMetadataPrefetchOnMount\":\"\"}"
+	expected := "{\"AppName\":\"\",\"Foreground\":false,\"ConfigFile\":\"\",\"MountOptions\":{\"1\":\"one\",\"2\":\"two\",\"3\":\"three\"},\"DirMode\":0,\"FileMode\":0,\"Uid\":0,\"Gid\":0,\"ImplicitDirs\":false,\"OnlyDir\":\"\",\"RenameDirLimit\":0,\"IgnoreInterrupts\":false,\"CustomEndpoint\":null,\"BillingProject\":\"\",\"KeyFile\":\"\",\"AwsCredentialsFile\":\"\",\"TokenUrl\":\"\",\"ReuseTokenFromUrl\":false,\"ImpersonateServiceAccount\":\"\",\"AllowCredentialExecutables\":false,\"BucketCredentialsFile\":\"\",\"EgressBandwidthLimitBytesPerSecond\":0,\"OpRateLimitHz\":0,\"SequentialReadSizeMb\":10,\"AnonymousAccess\":false,\"MaxRetrySleep\":0,\"StatCacheCapacity\":0,\"StatCacheTTL\":0,\"TypeCacheTTL\":0,\"KernelListCacheTtlSeconds\":-1,\"HttpClientTimeout\":0,\"MaxRetryDuration\":0,\"RetryMultiplier\":0,\"LocalFileCache\":false,\"TempDir\":\"\",\"Backend\":\"\",\"ClientProtocol\":\"http4\",\"MaxConnsPerHost\":0,\"MaxIdleConnsPerHost\":0,\"EnableNonexistentTypeCache\":false,\"StackdriverExportInterval\":0,\"OtelCollectorAddress\":\"\",\"OtelCollectorProtocol\":\"\",\"PrometheusPort\":0,\"PrometheusAddress\":\"\",\"TracingExporter\":\"\",\"TracingFile\":\"\",\"TracingSampleRatio\":0,\"AccessStatsFile\":\"\",\"AdminSocket\":\"\",\"LogFile\":\"\",\"LogFormat\":\"\",\"ExperimentalEnableJsonRead\":false,\"DebugFuseErrors\":false,\"DebugFuse\":false,\"DebugFS\":false,\"DebugGCS\":false,\"DebugHTTP\":false,\"DebugInvariants\":false,\"DebugMutex\":false,\"FakeBucketManifest\":\"\",\"FaultInjectionFile\":\"\",\"ExperimentalMetadataPrefetchOnMount\":\"\"}"
 	assert.Equal(t.T(), expected, actual)
 }
 
-func (t *MainTest) TestCallListRecursiveOnExistingDirectory() {
-	// Set up a mini file-system to test on.
-	rootdir, err := os.MkdirTemp("/tmp", "TestCallListRecursive-*")
-	if err != nil {
-		t.T().Fatalf("Failed to set up test. error = %v", err)
-	}
-	defer os.RemoveAll(rootdir)
-	_, err = os.CreateTemp(rootdir, "abc-*.txt")
-	if err != nil {
-		t.T().Fatalf("Failed to set up test. error = %v", err)
-	}
-
-	err = callListRecursive(rootdir)
-
-	assert.Nil(t.T(), err)
-}
-
-func (t *MainTest) TestCallListRecursiveOnNonExistingDirectory() {
-	// Set up a mini file-system to test on, which must fail.
-	rootdir := "/path/to/non/existing/directory"
-
-	err := callListRecursive(rootdir)
-
-	assert.ErrorContains(t.T(), err, "does not exist")
-}
-
 func (t *MainTest) TestIsDynamicMount() {
 	for _, input := range []struct {
 		bucketName string
@@ -273,3 +266,28 @@ func (t *MainTest) TestIsDynamicMount() {
 		assert.Equal(t.T(), input.isDynamic, isDynamicMount(input.bucketName))
 	}
 }
+
+func (t *MainTest) TestReloadLogConfigKeepsTraceForDebugFlags() {
+	configFile := path.Join(t.T().TempDir(), "config.yaml")
+	assert.NoError(t.T(), os.WriteFile(configFile, []byte("logging:\n  severity: error\n  debug-gcs: true\n"), 0600))
+	defer logger.SetLogSeverity(config.INFO)
+	defer logger.SetDebugSource(logger.DebugGCS, false)
+
+	logConfig, err := reloadLogConfig(&flagStorage{ConfigFile: configFile})
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), config.ERROR, logConfig.Severity)
+	assert.True(t.T(), logger.DebugSourceEnabled(logger.DebugGCS))
+
+	logConfig, err = reloadLogConfig(&flagStorage{ConfigFile: configFile, DebugMutex: true})
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), config.TRACE, logConfig.Severity)
+}
+
+func (t *MainTest) TestReloadLogConfigWithInvalidConfigFile() {
+	configFile := path.Join(t.T().TempDir(), "config.yaml")
+	assert.NoError(t.T(), os.WriteFile(configFile, []byte("logging:\n  severity: loud\n"), 0600))
+
+	_, err := reloadLogConfig(&flagStorage{ConfigFile: configFile})
+
+	assert.Error(t.T(), err)
+}
@@ -18,9 +18,14 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/accounting"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/admin"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/audit"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/metadata/shared"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/config"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/mount"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/faults"
 	"golang.org/x/net/context"
 
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/fs"
@@ -40,7 +45,8 @@ func mountWithStorageHandle(
 	mountPoint string,
 	flags *flagStorage,
 	mountConfig *config.MountConfig,
-	storageHandle storage.StorageHandle) (mfs *fuse.MountedFileSystem, err error) {
+	storageHandle storage.StorageHandle,
+	adminServer *admin.Server) (mfs *fuse.MountedFileSystem, err error) {
 	// Sanity check: make sure the temporary directory exists and is writable
 	// currently. This gives a better user experience than harder to debug EIO
 	// errors when reading files in the future.
@@ -90,6 +96,48 @@ be interacting with the file system.`)
 		return nil, fmt.Errorf("failed to calculate StatCacheMaxSizeMB from stat-cache-ttl=%v, metadata-cache:stat-cache-max-size-mb=%v: %w", flags.StatCacheCapacity, mountConfig.StatCacheMaxSizeMB, err)
 	}
 
+	var sharedMetadataCache *shared.Client
+	if mountConfig.MetadataCacheConfig.SharedCacheSocket != "" {
+		logger.Infof("Using shared metadata cache at %s\n", mountConfig.MetadataCacheConfig.SharedCacheSocket)
+		sharedMetadataCache = shared.NewClient(mountConfig.MetadataCacheConfig.SharedCacheSocket, flags.OnlyDir)
+	}
+
+	var accountant *accounting.Accountant
+	if flags.AccessStatsFile != "" {
+		logger.Infof("Accounting accesses, written to %s on SIGUSR1 or on creation of %s\n",
+			flags.AccessStatsFile, flags.AccessStatsFile+accounting.ControlFileSuffix)
+		accountant = accounting.NewAccountant(accounting.DefaultMaxPaths)
+		go accounting.HandleReportSignals(accountant, flags.AccessStatsFile)
+		go accounting.HandleReportRequests(accountant, flags.AccessStatsFile)
+	}
+
+	var auditLogger *audit.Logger
+	if mountConfig.LogConfig.AuditFilePath != "" {
+		logger.Infof("Recording file system changes to %s\n", mountConfig.LogConfig.AuditFilePath)
+		auditLogger, err = audit.NewLogger(mountConfig.LogConfig.AuditFilePath, mountConfig.LogConfig.LogRotateConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open the audit log: %w", err)
+		}
+	}
+
+	var faultsConfig *faults.Config
+	if flags.FaultInjectionFile != "" {
+		logger.Infof("Injecting faults following the rules of %s\n", flags.FaultInjectionFile)
+		faultsConfig, err = faults.LoadConfig(flags.FaultInjectionFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the fault injection rules: %w", err)
+		}
+	}
+
+	var bucketCredentials []storage.BucketCredentials
+	if flags.BucketCredentialsFile != "" {
+		logger.Infof("Accessing the buckets with the credentials of %s\n", flags.BucketCredentialsFile)
+		bucketCredentials, err = storage.LoadBucketCredentials(flags.BucketCredentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the bucket credentials: %w", err)
+		}
+	}
+
 	bucketCfg := gcsx.BucketConfig{
 		BillingProject:                     flags.BillingProject,
 		OnlyDir:                            flags.OnlyDir,
@@ -97,10 +145,21 @@ be interacting with the file system.`)
 		OpRateLimitHz:                      flags.OpRateLimitHz,
 		StatCacheMaxSizeMB:                 statCacheMaxSizeMB,
 		StatCacheTTL:                       metadataCacheTTL,
-		EnableMonitoring:                   flags.StackdriverExportInterval > 0,
+		EnableMonitoring:                   flags.StackdriverExportInterval > 0 || flags.OtelCollectorAddress != "" || flags.PrometheusPort > 0,
+		EnableTracing:                      flags.TracingExporter != "",
 		AppendThreshold:                    1 << 21, // 2 MiB, a total guess.
 		TmpObjectPrefix:                    ".gcsfuse_tmp/",
 		DebugGCS:                           flags.DebugGCS,
+		SharedMetadataCache:                sharedMetadataCache,
+		Accountant:                         accountant,
+		FakeBucketManifest:                 flags.FakeBucketManifest,
+		Faults:                             faultsConfig,
+		Retry:                              mountConfig.RetryConfig,
+		Hedging:                            mountConfig.HedgingConfig,
+		ReadStall:                          mountConfig.ReadStallConfig,
+		BucketCredentials:                  bucketCredentials,
+		DynamicMount:                       mountConfig.DynamicMountConfig,
+		StorageClientConfig:                newStorageClientConfig(flags, mountConfig, getUserAgent(flags.AppName, getConfigForUserAgent(mountConfig))),
 	}
 	bm := gcsx.NewBucketManager(bucketCfg, storageHandle)
 
@@ -115,6 +174,7 @@ be interacting with the file system.`)
 		ImplicitDirectories:        flags.ImplicitDirs,
 		InodeAttributeCacheTTL:     metadataCacheTTL,
 		DirTypeCacheTTL:            metadataCacheTTL,
+		SharedMetadataCache:        sharedMetadataCache,
 		Uid:                        uid,
 		Gid:                        gid,
 		FilePerms:                  os.FileMode(flags.FileMode),
@@ -122,6 +182,11 @@ be interacting with the file system.`)
 		RenameDirLimit:             flags.RenameDirLimit,
 		SequentialReadSizeMb:       flags.SequentialReadSizeMb,
 		EnableNonexistentTypeCache: flags.EnableNonexistentTypeCache,
+		MetadataPrefetchOnMount:    flags.ExperimentalMetadataPrefetchOnMount,
+		StatCacheMaxSizeMB:         statCacheMaxSizeMB,
+		Accountant:                 accountant,
+		AuditLogger:                auditLogger,
+		AdminServer:                adminServer,
 		MountConfig:                mountConfig,
 	}
 
@@ -157,7 +222,7 @@ be interacting with the file system.`)
 	}
 
 	mountCfg.ErrorLogger = logger.NewLegacyLogger(logger.LevelError, "fuse: ")
-	mountCfg.DebugLogger = logger.NewLegacyLogger(logger.LevelTrace, "fuse_debug: ")
+	mountCfg.DebugLogger = logger.NewDebugLegacyLogger(logger.DebugFuse, "fuse_debug: ")
 
 	mfs, err = fuse.Mount(mountPoint, server, mountCfg)
 	if err != nil {
@@ -0,0 +1,270 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package accounting aggregates statistics of the accesses to each file and
+// directory of the file system, to find the hot files and those missing the
+// cache.
+package accounting
+
+import (
+	"container/list"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stats are the statistics of the accesses to a file or directory.
+type Stats struct {
+	// The number of file system ops, by op, e.g. ReadFile.
+	Ops map[string]uint64 `json:"ops,omitempty"`
+
+	BytesRead    uint64 `json:"bytes_read"`
+	BytesWritten uint64 `json:"bytes_written"`
+
+	// The number of reads served from the file cache, and of those that
+	// weren't, when the file cache is enabled.
+	CacheHits   uint64 `json:"cache_hits"`
+	CacheMisses uint64 `json:"cache_misses"`
+
+	// The number of requests sent to GCS, by method, e.g. StatObject.
+	GCSRequests map[string]uint64 `json:"gcs_requests,omitempty"`
+}
+
+// CacheHitRatio returns the fraction of the reads served from the file cache,
+// or 0 if there were none through it.
+func (s *Stats) CacheHitRatio() float64 {
+	total := s.CacheHits + s.CacheMisses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.CacheHits) / float64(total)
+}
+
+func (s *Stats) add(other *Stats) {
+	for op, n := range other.Ops {
+		increment(&s.Ops, op, n)
+	}
+	s.BytesRead += other.BytesRead
+	s.BytesWritten += other.BytesWritten
+	s.CacheHits += other.CacheHits
+	s.CacheMisses += other.CacheMisses
+	for method, n := range other.GCSRequests {
+		increment(&s.GCSRequests, method, n)
+	}
+}
+
+func increment(m *map[string]uint64, key string, n uint64) {
+	if *m == nil {
+		*m = make(map[string]uint64)
+	}
+	(*m)[key] += n
+}
+
+// MarshalJSON adds the cache hit ratio to the fields of the stats.
+func (s *Stats) MarshalJSON() ([]byte, error) {
+	type stats Stats
+	return json.Marshal(struct {
+		*stats
+		CacheHitRatio float64 `json:"cache_hit_ratio"`
+	}{(*stats)(s), s.CacheHitRatio()})
+}
+
+// DefaultMaxPaths is the number of paths whose statistics are kept by
+// default.
+const DefaultMaxPaths = 100000
+
+// Report is a snapshot of the statistics.
+type Report struct {
+	Time time.Time `json:"time"`
+
+	// The number of paths whose statistics were dropped, as the least recently
+	// accessed ones, to keep those of at most the maximum number of paths.
+	// Their statistics are missing from those of their directories too.
+	DroppedPaths uint64 `json:"dropped_paths"`
+
+	// The stats of each file accessed, by path relative to the mount point.
+	Files map[string]*Stats `json:"files"`
+
+	// The stats of each directory accessed, by path relative to the mount point
+	// with a trailing slash, the root being "". They include the stats of the
+	// files and directories within.
+	Directories map[string]*Stats `json:"directories"`
+}
+
+// An Accountant aggregates the statistics of the accesses by path. A nil
+// Accountant records nothing, so that callers needn't check whether
+// accounting is enabled.
+//
+// Paths are those of the inodes, relative to the mount point, with a trailing
+// slash for directories. The statistics of a bounded number of paths are
+// kept, dropping those of the least recently accessed ones.
+//
+// All methods are safe for concurrent access.
+type Accountant struct {
+	clock    func() time.Time
+	maxPaths int
+
+	mu sync.Mutex
+
+	// The stats of each path, in an element of recent.
+	//
+	// GUARDED_BY(mu)
+	paths map[string]*list.Element
+
+	// The pathStats of the paths, from the most to the least recently
+	// accessed.
+	//
+	// GUARDED_BY(mu)
+	recent list.List
+
+	// GUARDED_BY(mu)
+	dropped uint64
+}
+
+type pathStats struct {
+	path  string
+	stats *Stats
+}
+
+// NewAccountant returns an Accountant with no statistics, keeping those of at
+// most maxPaths paths.
+func NewAccountant(maxPaths int) *Accountant {
+	return &Accountant{
+		clock:    time.Now,
+		maxPaths: maxPaths,
+		paths:    make(map[string]*list.Element),
+	}
+}
+
+// stats returns the stats of the given path, dropping those of the least
+// recently accessed path if there are too many.
+//
+// LOCKS_REQUIRED(a.mu)
+func (a *Accountant) stats(path string) *Stats {
+	if e, ok := a.paths[path]; ok {
+		a.recent.MoveToFront(e)
+		return e.Value.(*pathStats).stats
+	}
+
+	s := &Stats{}
+	a.paths[path] = a.recent.PushFront(&pathStats{path: path, stats: s})
+	if a.recent.Len() > a.maxPaths {
+		oldest := a.recent.Remove(a.recent.Back()).(*pathStats)
+		delete(a.paths, oldest.path)
+		a.dropped++
+	}
+	return s
+}
+
+// RecordOp counts a file system op on the given path.
+func (a *Accountant) RecordOp(path string, op string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	increment(&a.stats(path).Ops, op, 1)
+}
+
+// RecordRead counts n bytes read from the given path. cacheHit says whether
+// they were served from the file cache, if cached is true.
+func (a *Accountant) RecordRead(path string, n int, cached bool, cacheHit bool) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	s := a.stats(path)
+	s.BytesRead += uint64(n)
+	if !cached {
+		return
+	}
+	if cacheHit {
+		s.CacheHits++
+	} else {
+		s.CacheMisses++
+	}
+}
+
+// RecordWrite counts n bytes written to the given path.
+func (a *Accountant) RecordWrite(path string, n int) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stats(path).BytesWritten += uint64(n)
+}
+
+// RecordGCSRequest counts a request sent to GCS about the given path.
+func (a *Accountant) RecordGCSRequest(path string, method string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	increment(&a.stats(path).GCSRequests, method, 1)
+}
+
+// Report returns a snapshot of the statistics.
+func (a *Accountant) Report() *Report {
+	r := &Report{
+		Time:        a.clock(),
+		Files:       make(map[string]*Stats),
+		Directories: map[string]*Stats{"": {}},
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	r.DroppedPaths = a.dropped
+	for e := a.recent.Front(); e != nil; e = e.Next() {
+		path, s := e.Value.(*pathStats).path, e.Value.(*pathStats).stats
+		own := &Stats{}
+		own.add(s)
+		if path == "" || strings.HasSuffix(path, "/") {
+			r.dir(path).add(own)
+		} else {
+			r.Files[path] = own
+		}
+
+		// Add the stats to those of the ancestors.
+		for i := len(strings.TrimSuffix(path, "/")) - 1; i >= 0; i-- {
+			if path[i] == '/' {
+				r.dir(path[:i+1]).add(s)
+			}
+		}
+		if path != "" {
+			r.dir("").add(s)
+		}
+	}
+	return r
+}
+
+func (r *Report) dir(path string) *Stats {
+	s, ok := r.Directories[path]
+	if !ok {
+		s = &Stats{}
+		r.Directories[path] = s
+	}
+	return s
+}
+
+// WriteJSON writes a report of the statistics to w.
+func (a *Accountant) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(a.Report())
+}
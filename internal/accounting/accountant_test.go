@@ -0,0 +1,214 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accounting
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/fake"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"github.com/jacobsa/timeutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type AccountantTest struct {
+	suite.Suite
+	now        time.Time
+	accountant *Accountant
+}
+
+func TestAccountantSuite(t *testing.T) {
+	suite.Run(t, new(AccountantTest))
+}
+
+func (t *AccountantTest) SetupTest() {
+	t.now = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	t.accountant = NewAccountant(DefaultMaxPaths)
+	t.accountant.clock = func() time.Time { return t.now }
+}
+
+func (t *AccountantTest) TestReportAggregatesDirectories() {
+	t.accountant.RecordOp("a/b/c", "ReadFile")
+	t.accountant.RecordRead("a/b/c", 10, true, true)
+	t.accountant.RecordRead("a/b/c", 30, true, false)
+	t.accountant.RecordOp("a/d", "WriteFile")
+	t.accountant.RecordWrite("a/d", 5)
+	t.accountant.RecordOp("a/", "ReadDir")
+	t.accountant.RecordGCSRequest("a/", "ListObjects")
+	t.accountant.RecordGCSRequest("e", "StatObject")
+
+	r := t.accountant.Report()
+
+	assert.Equal(t.T(), t.now, r.Time)
+	assert.Equal(t.T(), map[string]*Stats{
+		"a/b/c": {
+			Ops:         map[string]uint64{"ReadFile": 1},
+			BytesRead:   40,
+			CacheHits:   1,
+			CacheMisses: 1,
+		},
+		"a/d": {
+			Ops:          map[string]uint64{"WriteFile": 1},
+			BytesWritten: 5,
+		},
+		"e": {
+			GCSRequests: map[string]uint64{"StatObject": 1},
+		},
+	}, r.Files)
+	assert.Equal(t.T(), map[string]*Stats{
+		"": {
+			Ops:          map[string]uint64{"ReadFile": 1, "WriteFile": 1, "ReadDir": 1},
+			BytesRead:    40,
+			BytesWritten: 5,
+			CacheHits:    1,
+			CacheMisses:  1,
+			GCSRequests:  map[string]uint64{"ListObjects": 1, "StatObject": 1},
+		},
+		"a/": {
+			Ops:          map[string]uint64{"ReadFile": 1, "WriteFile": 1, "ReadDir": 1},
+			BytesRead:    40,
+			BytesWritten: 5,
+			CacheHits:    1,
+			CacheMisses:  1,
+			GCSRequests:  map[string]uint64{"ListObjects": 1},
+		},
+		"a/b/": {
+			Ops:         map[string]uint64{"ReadFile": 1},
+			BytesRead:   40,
+			CacheHits:   1,
+			CacheMisses: 1,
+		},
+	}, r.Directories)
+	assert.Equal(t.T(), 0.5, r.Files["a/b/c"].CacheHitRatio())
+}
+
+func (t *AccountantTest) TestReadsNotThroughTheCache() {
+	t.accountant.RecordRead("a", 10, false, false)
+
+	r := t.accountant.Report()
+
+	assert.Equal(t.T(), uint64(10), r.Files["a"].BytesRead)
+	assert.Equal(t.T(), uint64(0), r.Files["a"].CacheMisses)
+	assert.Equal(t.T(), 0.0, r.Files["a"].CacheHitRatio())
+}
+
+func (t *AccountantTest) TestReportIsASnapshot() {
+	t.accountant.RecordWrite("a", 1)
+	r := t.accountant.Report()
+
+	t.accountant.RecordWrite("a", 1)
+
+	assert.Equal(t.T(), uint64(1), r.Files["a"].BytesWritten)
+	assert.Equal(t.T(), uint64(1), r.Directories[""].BytesWritten)
+}
+
+func (t *AccountantTest) TestLeastRecentlyAccessedPathsAreDropped() {
+	t.accountant = NewAccountant(2)
+	t.accountant.RecordWrite("a/b", 1)
+	t.accountant.RecordWrite("a/c", 1)
+	t.accountant.RecordWrite("a/b", 1)
+
+	t.accountant.RecordWrite("d", 1)
+
+	r := t.accountant.Report()
+	assert.Equal(t.T(), uint64(1), r.DroppedPaths)
+	assert.Len(t.T(), r.Files, 2)
+	assert.Contains(t.T(), r.Files, "a/b")
+	assert.Contains(t.T(), r.Files, "d")
+	assert.Equal(t.T(), uint64(2), r.Directories["a/"].BytesWritten)
+	assert.Equal(t.T(), uint64(3), r.Directories[""].BytesWritten)
+}
+
+func (t *AccountantTest) TestNilAccountantRecordsNothing() {
+	var a *Accountant
+
+	assert.NotPanics(t.T(), func() {
+		a.RecordOp("a", "ReadFile")
+		a.RecordRead("a", 1, true, true)
+		a.RecordWrite("a", 1)
+		a.RecordGCSRequest("a", "StatObject")
+	})
+}
+
+func (t *AccountantTest) TestWriteReport() {
+	t.accountant.RecordRead("a", 10, true, true)
+	path := filepath.Join(t.T().TempDir(), "stats.json")
+	require.NoError(t.T(), os.WriteFile(path, []byte("stale"), 0644))
+
+	err := t.accountant.WriteReport(path)
+
+	require.NoError(t.T(), err)
+	contents, err := os.ReadFile(path)
+	require.NoError(t.T(), err)
+	var r struct {
+		Files map[string]map[string]any
+	}
+	require.NoError(t.T(), json.Unmarshal(contents, &r))
+	assert.Equal(t.T(), 10.0, r.Files["a"]["bytes_read"])
+	assert.Equal(t.T(), 1.0, r.Files["a"]["cache_hit_ratio"])
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t.T(), err)
+	assert.Len(t.T(), entries, 1)
+}
+
+func (t *AccountantTest) TestReportRequestedByControlFile() {
+	t.accountant.RecordRead("a", 10, true, true)
+	path := filepath.Join(t.T().TempDir(), "stats.json")
+
+	// Nothing is written until the control file is created.
+	handleReportRequest(t.accountant, path)
+	assert.NoFileExists(t.T(), path)
+	require.NoError(t.T(), os.WriteFile(path+ControlFileSuffix, nil, 0644))
+	handleReportRequest(t.accountant, path)
+
+	assert.FileExists(t.T(), path)
+	assert.NoFileExists(t.T(), path+ControlFileSuffix)
+}
+
+func (t *AccountantTest) TestWriteReportToMissingDirectory() {
+	path := filepath.Join(t.T().TempDir(), "missing", "stats.json")
+
+	err := t.accountant.WriteReport(path)
+
+	assert.ErrorContains(t.T(), err, "CreateTemp")
+}
+
+func (t *AccountantTest) TestAccountingBucket() {
+	clock := &timeutil.SimulatedClock{}
+	bucket := NewAccountingBucket(t.accountant, "some_bucket/", fake.NewFakeBucket(clock, "some_bucket"))
+	ctx := context.Background()
+
+	_, err := bucket.CreateObject(ctx, &gcs.CreateObjectRequest{
+		Name:     "a/b",
+		Contents: strings.NewReader("taco"),
+	})
+	require.NoError(t.T(), err)
+	_, _, err = bucket.StatObject(ctx, &gcs.StatObjectRequest{Name: "a/b"})
+	require.NoError(t.T(), err)
+	_, err = bucket.ListObjects(ctx, &gcs.ListObjectsRequest{Prefix: "a/", Delimiter: "/"})
+	require.NoError(t.T(), err)
+
+	r := t.accountant.Report()
+	assert.Equal(t.T(), map[string]uint64{"CreateObject": 1, "StatObject": 1}, r.Files["some_bucket/a/b"].GCSRequests)
+	assert.Equal(t.T(), map[string]uint64{"CreateObject": 1, "StatObject": 1, "ListObjects": 1}, r.Directories["some_bucket/a/"].GCSRequests)
+}
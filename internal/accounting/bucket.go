@@ -0,0 +1,107 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accounting
+
+import (
+	"io"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"golang.org/x/net/context"
+)
+
+// NewAccountingBucket returns a bucket counting the requests to the wrapped
+// bucket in a, by the path of the object they are about: pathPrefix followed
+// by the object name. Listings are counted for the directory listed.
+func NewAccountingBucket(a *Accountant, pathPrefix string, b gcs.Bucket) gcs.Bucket {
+	return &accountingBucket{
+		accountant: a,
+		pathPrefix: pathPrefix,
+		wrapped:    b,
+	}
+}
+
+type accountingBucket struct {
+	accountant *Accountant
+	pathPrefix string
+	wrapped    gcs.Bucket
+}
+
+func (ab *accountingBucket) record(name string, method string) {
+	ab.accountant.RecordGCSRequest(ab.pathPrefix+name, method)
+}
+
+func (ab *accountingBucket) Name() string {
+	return ab.wrapped.Name()
+}
+
+func (ab *accountingBucket) BucketType() gcs.BucketType {
+	return ab.wrapped.BucketType()
+}
+
+func (ab *accountingBucket) NewReader(
+	ctx context.Context,
+	req *gcs.ReadObjectRequest) (io.ReadCloser, error) {
+	ab.record(req.Name, "NewReader")
+	return ab.wrapped.NewReader(ctx, req)
+}
+
+func (ab *accountingBucket) CreateObject(
+	ctx context.Context,
+	req *gcs.CreateObjectRequest) (*gcs.Object, error) {
+	ab.record(req.Name, "CreateObject")
+	return ab.wrapped.CreateObject(ctx, req)
+}
+
+func (ab *accountingBucket) CopyObject(
+	ctx context.Context,
+	req *gcs.CopyObjectRequest) (*gcs.Object, error) {
+	ab.record(req.DstName, "CopyObject")
+	return ab.wrapped.CopyObject(ctx, req)
+}
+
+func (ab *accountingBucket) ComposeObjects(
+	ctx context.Context,
+	req *gcs.ComposeObjectsRequest) (*gcs.Object, error) {
+	ab.record(req.DstName, "ComposeObjects")
+	return ab.wrapped.ComposeObjects(ctx, req)
+}
+
+func (ab *accountingBucket) StatObject(
+	ctx context.Context,
+	req *gcs.StatObjectRequest) (*gcs.MinObject, *gcs.ExtendedObjectAttributes, error) {
+	ab.record(req.Name, "StatObject")
+	return ab.wrapped.StatObject(ctx, req)
+}
+
+func (ab *accountingBucket) ListObjects(
+	ctx context.Context,
+	req *gcs.ListObjectsRequest) (*gcs.Listing, error) {
+	ab.record(req.Prefix, "ListObjects")
+	return ab.wrapped.ListObjects(ctx, req)
+}
+
+func (ab *accountingBucket) UpdateObject(
+	ctx context.Context,
+	req *gcs.UpdateObjectRequest) (*gcs.Object, error) {
+	ab.record(req.Name, "UpdateObject")
+	return ab.wrapped.UpdateObject(ctx, req)
+}
+
+func (ab *accountingBucket) DeleteObject(
+	ctx context.Context,
+	req *gcs.DeleteObjectRequest) error {
+	ab.record(req.Name, "DeleteObject")
+	return ab.wrapped.DeleteObject(ctx, req)
+}
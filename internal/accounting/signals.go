@@ -0,0 +1,109 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accounting
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
+)
+
+// WriteReport replaces the file at path with a JSON report of the statistics.
+// Readers of the file never see a partial report.
+func (a *Accountant) WriteReport(path string) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		err = fmt.Errorf("CreateTemp: %w", err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}()
+
+	err = a.WriteJSON(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		err = fmt.Errorf("WriteJSON: %w", err)
+		return
+	}
+
+	if err = os.Rename(f.Name(), path); err != nil {
+		err = fmt.Errorf("Rename: %w", err)
+		return
+	}
+	return
+}
+
+// ControlFileSuffix is appended to the path of the report to get the path of
+// the control file, whose creation requests a report.
+const ControlFileSuffix = ".request"
+
+// The interval at which the existence of the control file is checked.
+const controlFilePollInterval = time.Second
+
+func writeReportAndLog(a *Accountant, path string) {
+	logger.Infof("Writing access statistics to %s...", path)
+
+	err := a.WriteReport(path)
+	if err == nil {
+		logger.Infof("Wrote access statistics to %s.", path)
+	} else {
+		logger.Errorf("Error writing access statistics: %v", err)
+	}
+}
+
+// HandleReportSignals writes a report of the statistics to the file at path
+// whenever the process receives SIGUSR1.
+func HandleReportSignals(a *Accountant, path string) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGUSR1)
+	for range c {
+		writeReportAndLog(a, path)
+	}
+}
+
+// HandleReportRequests writes a report of the statistics to the file at path
+// whenever the control file, at path followed by ControlFileSuffix, is
+// created, e.g. with touch, and then removes the control file. This is for
+// where signals can't be sent to gcsfuse, e.g. from another container.
+func HandleReportRequests(a *Accountant, path string) {
+	ticker := time.NewTicker(controlFilePollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		handleReportRequest(a, path)
+	}
+}
+
+// handleReportRequest writes a report if the control file exists.
+func handleReportRequest(a *Accountant, path string) {
+	controlFile := path + ControlFileSuffix
+	if _, err := os.Stat(controlFile); err != nil {
+		return
+	}
+
+	writeReportAndLog(a, path)
+	if err := os.Remove(controlFile); err != nil {
+		logger.Errorf("Error removing the access statistics control file: %v", err)
+	}
+}
@@ -0,0 +1,279 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package admin serves an HTTP API on a unix socket to inspect and repair a
+// running mount: its effective config, its caches, the objects being
+// downloaded into the file cache and the files with changes not yet uploaded
+// to GCS.
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/util"
+)
+
+// Names of the caches, as accepted by FileSystem.CacheEntries.
+const (
+	StatCache = "stat"
+	TypeCache = "type"
+	FileCache = "file"
+)
+
+// HealthCheckTimeout bounds the time taken by the mount point to be stat'ed
+// for the health check.
+const HealthCheckTimeout = 5 * time.Second
+
+// ErrUnknownCache is returned by FileSystem.CacheEntries for a cache name
+// other than StatCache, TypeCache and FileCache.
+var ErrUnknownCache = errors.New("unknown cache")
+
+// FileSystem is the state of the mounted file system exposed by the server.
+type FileSystem interface {
+	// Caches returns the statistics of the enabled caches.
+	Caches() []CacheStats
+
+	// CacheEntries returns the entries of the given cache, which is empty if
+	// the cache is disabled.
+	CacheEntries(cache string) ([]CacheEntry, error)
+
+	// InvalidateObject drops the cached stat, type and content of the object
+	// with the given full name in the given bucket.
+	InvalidateObject(bucketName, objectName string)
+
+	// DownloadJobs returns the objects being downloaded into the file cache.
+	DownloadJobs() []DownloadJob
+
+	// DirtyFiles returns the files with changes not yet uploaded to GCS.
+	DirtyFiles() []DirtyFile
+}
+
+// CacheStats describes the usage of a cache.
+type CacheStats struct {
+	Name    string `json:"name"`
+	Entries int    `json:"entries"`
+
+	// Size and MaxSize are in bytes. They are zero for the type caches, as
+	// each directory has its own.
+	Size    uint64 `json:"size,omitempty"`
+	MaxSize uint64 `json:"max_size,omitempty"`
+}
+
+// CacheEntry describes an entry of a cache.
+type CacheEntry struct {
+	// Name is the object name for the stat and file caches, prefixed with the
+	// bucket name when mounting all the buckets, and the path of the child
+	// for the type caches.
+	Name       string     `json:"name"`
+	Value      string     `json:"value"`
+	Expiration *time.Time `json:"expiration,omitempty"`
+}
+
+// DownloadJob describes the download of an object into the file cache.
+type DownloadJob struct {
+	Bucket     string `json:"bucket"`
+	Object     string `json:"object"`
+	Generation int64  `json:"generation"`
+	Size       uint64 `json:"size"`
+	Status     string `json:"status"`
+
+	// Offset is the size of the object downloaded so far.
+	Offset int64  `json:"offset"`
+	Error  string `json:"error,omitempty"`
+}
+
+// DirtyFile describes a file with changes not yet uploaded to GCS.
+type DirtyFile struct {
+	Inode uint64 `json:"inode"`
+	Path  string `json:"path"`
+
+	// Local is true for a file created locally, not yet in GCS; Generation is
+	// otherwise the generation of the object changed.
+	Local        bool  `json:"local,omitempty"`
+	Generation   int64 `json:"generation,omitempty"`
+	PendingBytes int64 `json:"pending_bytes"`
+}
+
+// Config specifies what a Server serves.
+type Config struct {
+	// SocketPath is the path of the unix socket the server listens on. A
+	// socket left behind at this path is replaced, but not one still in use.
+	SocketPath string
+
+	// MountPoint is stat'ed for the health check.
+	MountPoint string
+
+	// EffectiveConfig is served as JSON by /config.
+	EffectiveConfig any
+}
+
+// Server serves the admin API of a mount:
+//
+//	GET  /healthz                                 the mount point can be stat'ed
+//	GET  /config                                  the effective config
+//	GET  /caches                                  the statistics of the caches
+//	GET  /caches/{stat,type,file}                 the entries of a cache
+//	POST /caches/invalidate?bucket=...&object=... drop the cached object
+//	GET  /downloads                               the file cache download jobs
+//	GET  /dirty                                   the files pending an upload
+type Server struct {
+	config Config
+
+	mu sync.Mutex
+	// GUARDED_BY(mu)
+	fs FileSystem
+
+	listener net.Listener
+	server   *http.Server
+	wg       sync.WaitGroup
+}
+
+// NewServer returns a Server serving the given config. Call SetFileSystem
+// once the file system is created, then Start.
+func NewServer(config Config) *Server {
+	return &Server{config: config}
+}
+
+// SetFileSystem sets the file system whose state is served. Until then, the
+// endpoints other than /healthz and /config fail.
+func (s *Server) SetFileSystem(fs FileSystem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fs = fs
+}
+
+func (s *Server) fileSystem() FileSystem {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fs
+}
+
+// Start listens on the socket and serves the API in the background.
+func (s *Server) Start() (err error) {
+	// The API can change the state of the mount: only its owner may use it.
+	s.listener, err = util.ListenUnixSocket(s.config.SocketPath, 0600)
+	if err != nil {
+		return fmt.Errorf("while listening: %w", err)
+	}
+
+	s.server = &http.Server{Handler: s.handler()}
+	logger.Infof("Serving the admin API on %s", s.config.SocketPath)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.server.Serve(s.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Errorf("Admin API failed: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Stop stops serving the API and removes the socket.
+func (s *Server) Stop() {
+	if s.server == nil {
+		return
+	}
+	_ = s.server.Close()
+	s.wg.Wait()
+	os.Remove(s.config.SocketPath)
+}
+
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.healthz)
+	mux.HandleFunc("GET /config", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, s.config.EffectiveConfig)
+	})
+	mux.HandleFunc("GET /caches", s.withFileSystem(func(fs FileSystem, w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, fs.Caches())
+	}))
+	mux.HandleFunc("GET /caches/{cache}", s.withFileSystem(func(fs FileSystem, w http.ResponseWriter, r *http.Request) {
+		entries, err := fs.CacheEntries(r.PathValue("cache"))
+		if errors.Is(err, ErrUnknownCache) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, entries)
+	}))
+	mux.HandleFunc("POST /caches/invalidate", s.withFileSystem(func(fs FileSystem, w http.ResponseWriter, r *http.Request) {
+		bucket, object := r.URL.Query().Get("bucket"), r.URL.Query().Get("object")
+		if bucket == "" || object == "" {
+			http.Error(w, "bucket and object are required", http.StatusBadRequest)
+			return
+		}
+		logger.Infof("Invalidating gs://%s/%s through the admin API", bucket, object)
+		fs.InvalidateObject(bucket, object)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	mux.HandleFunc("GET /downloads", s.withFileSystem(func(fs FileSystem, w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, fs.DownloadJobs())
+	}))
+	mux.HandleFunc("GET /dirty", s.withFileSystem(func(fs FileSystem, w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, fs.DirtyFiles())
+	}))
+	return mux
+}
+
+func (s *Server) withFileSystem(f func(FileSystem, http.ResponseWriter, *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fs := s.fileSystem()
+		if fs == nil {
+			http.Error(w, "the file system is not mounted yet", http.StatusServiceUnavailable)
+			return
+		}
+		f(fs, w, r)
+	}
+}
+
+// healthz stats the mount point, which goes through the kernel down to the
+// file system. A stat still hanging after HealthCheckTimeout is left running.
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	errs := make(chan error, 1)
+	go func() {
+		_, err := os.Stat(s.config.MountPoint)
+		errs <- err
+	}()
+
+	select {
+	case err := <-errs:
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	case <-time.After(HealthCheckTimeout):
+		http.Error(w, fmt.Sprintf("stat of %s timed out after %v", s.config.MountPoint, HealthCheckTimeout), http.StatusServiceUnavailable)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		logger.Warnf("Admin API: while writing the response: %v", err)
+	}
+}
@@ -0,0 +1,269 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type fakeFileSystem struct {
+	invalidated []string
+}
+
+func (f *fakeFileSystem) Caches() []CacheStats {
+	return []CacheStats{{Name: StatCache, Entries: 1, Size: 10, MaxSize: 100}}
+}
+
+func (f *fakeFileSystem) CacheEntries(cache string) ([]CacheEntry, error) {
+	if cache != FileCache {
+		return nil, fmt.Errorf("%w %q", ErrUnknownCache, cache)
+	}
+	return []CacheEntry{{Name: "bucket/a", Value: "generation 1, 3 of 3 bytes"}}, nil
+}
+
+func (f *fakeFileSystem) InvalidateObject(bucketName, objectName string) {
+	f.invalidated = append(f.invalidated, bucketName+"/"+objectName)
+}
+
+func (f *fakeFileSystem) DownloadJobs() []DownloadJob {
+	return []DownloadJob{{Bucket: "bucket", Object: "a", Generation: 1, Size: 3, Status: "Downloading", Offset: 1}}
+}
+
+func (f *fakeFileSystem) DirtyFiles() []DirtyFile {
+	return []DirtyFile{{Inode: 2, Path: "a", Local: true, PendingBytes: 3}}
+}
+
+type ServerTest struct {
+	suite.Suite
+	dir    string
+	fs     *fakeFileSystem
+	server *Server
+	client *http.Client
+}
+
+func TestServerSuite(t *testing.T) {
+	suite.Run(t, new(ServerTest))
+}
+
+func (t *ServerTest) SetupTest() {
+	t.dir = t.T().TempDir()
+	t.fs = &fakeFileSystem{}
+	socketPath := filepath.Join(t.dir, "admin.sock")
+	t.server = NewServer(Config{
+		SocketPath:      socketPath,
+		MountPoint:      t.dir,
+		EffectiveConfig: map[string]string{"bucket": "b"},
+	})
+	t.client = &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+		},
+	}}
+}
+
+func (t *ServerTest) TearDownTest() {
+	t.server.Stop()
+}
+
+func (t *ServerTest) do(method, path string) (int, string) {
+	req, err := http.NewRequest(method, "http://admin"+path, nil)
+	require.NoError(t.T(), err)
+	resp, err := t.client.Do(req)
+	require.NoError(t.T(), err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t.T(), err)
+	return resp.StatusCode, string(body)
+}
+
+func (t *ServerTest) start() {
+	t.server.SetFileSystem(t.fs)
+	require.NoError(t.T(), t.server.Start())
+}
+
+func (t *ServerTest) TestSocketPermissions() {
+	t.start()
+
+	fi, err := os.Stat(t.server.config.SocketPath)
+
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), os.FileMode(0600), fi.Mode().Perm())
+}
+
+func (t *ServerTest) TestStaleSocketIsReplaced() {
+	l, err := net.Listen("unix", t.server.config.SocketPath)
+	require.NoError(t.T(), err)
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+
+	t.start()
+
+	code, _ := t.do(http.MethodGet, "/healthz")
+	assert.Equal(t.T(), http.StatusOK, code)
+}
+
+func (t *ServerTest) TestSocketInUseIsNotReplaced() {
+	l, err := net.Listen("unix", t.server.config.SocketPath)
+	require.NoError(t.T(), err)
+	defer l.Close()
+
+	err = t.server.Start()
+
+	assert.ErrorContains(t.T(), err, "in use")
+	// The other process's socket is left in place.
+	fi, err := os.Stat(t.server.config.SocketPath)
+	require.NoError(t.T(), err)
+	assert.NotZero(t.T(), fi.Mode()&os.ModeSocket)
+}
+
+func (t *ServerTest) TestStopRemovesSocket() {
+	t.start()
+
+	t.server.Stop()
+
+	_, err := os.Stat(t.server.config.SocketPath)
+	assert.True(t.T(), os.IsNotExist(err))
+}
+
+func (t *ServerTest) TestHealthz() {
+	t.start()
+
+	code, body := t.do(http.MethodGet, "/healthz")
+
+	assert.Equal(t.T(), http.StatusOK, code)
+	assert.Equal(t.T(), "ok\n", body)
+}
+
+func (t *ServerTest) TestHealthzFailsForMissingMountPoint() {
+	t.server.config.MountPoint = filepath.Join(t.dir, "missing")
+	t.start()
+
+	code, body := t.do(http.MethodGet, "/healthz")
+
+	assert.Equal(t.T(), http.StatusServiceUnavailable, code)
+	assert.Contains(t.T(), body, "no such file or directory")
+}
+
+func (t *ServerTest) TestConfig() {
+	t.start()
+
+	code, body := t.do(http.MethodGet, "/config")
+
+	assert.Equal(t.T(), http.StatusOK, code)
+	assert.JSONEq(t.T(), `{"bucket": "b"}`, body)
+}
+
+func (t *ServerTest) TestCaches() {
+	t.start()
+
+	code, body := t.do(http.MethodGet, "/caches")
+
+	assert.Equal(t.T(), http.StatusOK, code)
+	assert.JSONEq(t.T(), `[{"name": "stat", "entries": 1, "size": 10, "max_size": 100}]`, body)
+}
+
+func (t *ServerTest) TestCacheEntries() {
+	t.start()
+
+	code, body := t.do(http.MethodGet, "/caches/file")
+
+	assert.Equal(t.T(), http.StatusOK, code)
+	assert.JSONEq(t.T(), `[{"name": "bucket/a", "value": "generation 1, 3 of 3 bytes"}]`, body)
+}
+
+func (t *ServerTest) TestCacheEntriesOfUnknownCache() {
+	t.start()
+
+	code, body := t.do(http.MethodGet, "/caches/foo")
+
+	assert.Equal(t.T(), http.StatusNotFound, code)
+	assert.Contains(t.T(), body, `unknown cache "foo"`)
+}
+
+func (t *ServerTest) TestCacheEntryExpiration() {
+	expiration := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	b, err := json.Marshal(CacheEntry{Name: "a", Value: "file", Expiration: &expiration})
+
+	require.NoError(t.T(), err)
+	assert.JSONEq(t.T(), `{"name": "a", "value": "file", "expiration": "2024-05-01T00:00:00Z"}`, string(b))
+}
+
+func (t *ServerTest) TestInvalidate() {
+	t.start()
+
+	code, _ := t.do(http.MethodPost, "/caches/invalidate?bucket=b&object=a/c")
+
+	assert.Equal(t.T(), http.StatusNoContent, code)
+	assert.Equal(t.T(), []string{"b/a/c"}, t.fs.invalidated)
+}
+
+func (t *ServerTest) TestInvalidateRequiresObject() {
+	t.start()
+
+	code, _ := t.do(http.MethodPost, "/caches/invalidate?bucket=b")
+
+	assert.Equal(t.T(), http.StatusBadRequest, code)
+	assert.Empty(t.T(), t.fs.invalidated)
+}
+
+func (t *ServerTest) TestInvalidateRequiresPost() {
+	t.start()
+
+	code, _ := t.do(http.MethodGet, "/caches/invalidate?bucket=b&object=a")
+
+	// GET /caches/{cache} matches, for a cache named "invalidate".
+	assert.Equal(t.T(), http.StatusNotFound, code)
+	assert.Empty(t.T(), t.fs.invalidated)
+}
+
+func (t *ServerTest) TestDownloads() {
+	t.start()
+
+	code, body := t.do(http.MethodGet, "/downloads")
+
+	assert.Equal(t.T(), http.StatusOK, code)
+	assert.JSONEq(t.T(), `[{"bucket": "bucket", "object": "a", "generation": 1, "size": 3, "status": "Downloading", "offset": 1}]`, body)
+}
+
+func (t *ServerTest) TestDirty() {
+	t.start()
+
+	code, body := t.do(http.MethodGet, "/dirty")
+
+	assert.Equal(t.T(), http.StatusOK, code)
+	assert.JSONEq(t.T(), `[{"inode": 2, "path": "a", "local": true, "pending_bytes": 3}]`, body)
+}
+
+func (t *ServerTest) TestFileSystemNotSet() {
+	require.NoError(t.T(), t.server.Start())
+
+	code, _ := t.do(http.MethodGet, "/dirty")
+
+	assert.Equal(t.T(), http.StatusServiceUnavailable, code)
+}
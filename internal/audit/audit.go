@@ -0,0 +1,171 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audit records the file system operations changing the contents of
+// the mount, along with the process making them, separately from the logs.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/config"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
+	"github.com/jacobsa/fuse/fuseops"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// The operations recorded in the audit log.
+const (
+	OpCreate     = "create"
+	OpWriteClose = "write-close"
+	OpRename     = "rename"
+	OpUnlink     = "unlink"
+	OpMkDir      = "mkdir"
+	OpRmDir      = "rmdir"
+	OpSetAttr    = "setattr"
+)
+
+// The outcomes of the recorded operations.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Entry is a line of the audit log.
+type Entry struct {
+	Time time.Time `json:"time"`
+	Op   string    `json:"op"`
+
+	// Pid and Uid identify the process making the operation. They are zero
+	// when the kernel does not tell them.
+	Pid uint32 `json:"pid"`
+	Uid uint32 `json:"uid"`
+
+	// Path is the path of the file or directory in the mount; NewPath is the
+	// path it is renamed to.
+	Path    string `json:"path"`
+	NewPath string `json:"new_path,omitempty"`
+
+	// Generation is the generation of the object resulting from the operation,
+	// if any.
+	Generation int64 `json:"generation,omitempty"`
+
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Logger writes the audit log, one JSON encoded Entry per line. A nil *Logger
+// records nothing, so that it can be used unconditionally.
+type Logger struct {
+	clock func() time.Time
+
+	// GUARDED_BY(mu)
+	w  io.WriteCloser
+	mu sync.Mutex
+}
+
+// NewLogger returns a Logger appending to the file at the supplied path,
+// rotated according to rotateConfig like the log file.
+func NewLogger(path string, rotateConfig config.LogRotateConfig) (*Logger, error) {
+	// Fail now rather than on the first operation if the file can't be written.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("OpenFile: %w", err)
+	}
+	f.Close()
+
+	return newLogger(&lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    rotateConfig.MaxFileSizeMB,
+		MaxBackups: rotateConfig.BackupFileCount,
+		Compress:   rotateConfig.Compress,
+	}), nil
+}
+
+func newLogger(w io.WriteCloser) *Logger {
+	return &Logger{
+		clock: time.Now,
+		w:     w,
+	}
+}
+
+// Record records the outcome of an operation on the supplied path, made on
+// behalf of the process described by opCtx.
+func (l *Logger) Record(opCtx fuseops.OpContext, op string, path string, generation int64, err error) {
+	if l == nil {
+		return
+	}
+
+	l.write(Entry{
+		Op:         op,
+		Pid:        opCtx.Pid,
+		Uid:        opCtx.Uid,
+		Path:       path,
+		Generation: generation,
+	}, err)
+}
+
+// RecordRename records the outcome of renaming path to newPath.
+func (l *Logger) RecordRename(opCtx fuseops.OpContext, path string, newPath string, generation int64, err error) {
+	if l == nil {
+		return
+	}
+
+	l.write(Entry{
+		Op:         OpRename,
+		Pid:        opCtx.Pid,
+		Uid:        opCtx.Uid,
+		Path:       path,
+		NewPath:    newPath,
+		Generation: generation,
+	}, err)
+}
+
+func (l *Logger) write(e Entry, err error) {
+	e.Time = l.clock()
+	e.Outcome = OutcomeSuccess
+	if err != nil {
+		e.Outcome = OutcomeFailure
+		e.Error = err.Error()
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		logger.Errorf("Audit log: Marshal: %v", err)
+		return
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.w.Write(line); err != nil {
+		logger.Errorf("Audit log: failed to record %s of %q: %v", e.Op, e.Path, err)
+	}
+}
+
+// Close closes the audit log file.
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Close()
+}
@@ -0,0 +1,161 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/config"
+	"github.com/jacobsa/fuse/fuseops"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+type AuditTest struct {
+	suite.Suite
+	now    time.Time
+	buf    bytes.Buffer
+	logger *Logger
+}
+
+func TestAuditSuite(t *testing.T) {
+	suite.Run(t, new(AuditTest))
+}
+
+func (t *AuditTest) SetupTest() {
+	t.now = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	t.buf.Reset()
+	t.logger = newLogger(nopWriteCloser{&t.buf})
+	t.logger.clock = func() time.Time { return t.now }
+}
+
+func (t *AuditTest) entries() (entries []Entry) {
+	for _, line := range strings.Split(strings.TrimSuffix(t.buf.String(), "\n"), "\n") {
+		var e Entry
+		require.NoError(t.T(), json.Unmarshal([]byte(line), &e))
+		entries = append(entries, e)
+	}
+	return
+}
+
+func (t *AuditTest) TestRecord() {
+	opCtx := fuseops.OpContext{Pid: 12, Uid: 1000}
+
+	t.logger.Record(opCtx, OpCreate, "a/b", 5, nil)
+	t.logger.Record(opCtx, OpUnlink, "a/c", 0, errors.New("DeleteChildFile: not found"))
+
+	assert.Equal(t.T(), []Entry{
+		{
+			Time:       t.now,
+			Op:         OpCreate,
+			Pid:        12,
+			Uid:        1000,
+			Path:       "a/b",
+			Generation: 5,
+			Outcome:    OutcomeSuccess,
+		},
+		{
+			Time:    t.now,
+			Op:      OpUnlink,
+			Pid:     12,
+			Uid:     1000,
+			Path:    "a/c",
+			Outcome: OutcomeFailure,
+			Error:   "DeleteChildFile: not found",
+		},
+	}, t.entries())
+}
+
+func (t *AuditTest) TestRecordRename() {
+	t.logger.RecordRename(fuseops.OpContext{Pid: 1}, "a/", "b/", 0, nil)
+
+	assert.Equal(t.T(), []Entry{{
+		Time:    t.now,
+		Op:      OpRename,
+		Pid:     1,
+		Path:    "a/",
+		NewPath: "b/",
+		Outcome: OutcomeSuccess,
+	}}, t.entries())
+}
+
+func (t *AuditTest) TestEntryOmitsEmptyFields() {
+	t.logger.Record(fuseops.OpContext{}, OpRmDir, "a/", 0, nil)
+
+	assert.Equal(t.T(),
+		`{"time":"2024-05-01T00:00:00Z","op":"rmdir","pid":0,"uid":0,"path":"a/","outcome":"success"}`+"\n",
+		t.buf.String())
+}
+
+func (t *AuditTest) TestNilLoggerRecordsNothing() {
+	var l *Logger
+
+	assert.NotPanics(t.T(), func() {
+		l.Record(fuseops.OpContext{}, OpMkDir, "a/", 1, nil)
+		l.RecordRename(fuseops.OpContext{}, "a", "b", 1, nil)
+		assert.NoError(t.T(), l.Close())
+	})
+}
+
+func (t *AuditTest) TestNewLogger() {
+	path := filepath.Join(t.T().TempDir(), "audit.json")
+	require.NoError(t.T(), os.WriteFile(path, []byte(`{"op":"create"}`+"\n"), 0644))
+	l, err := NewLogger(path, config.DefaultLogRotateConfig())
+	require.NoError(t.T(), err)
+
+	l.Record(fuseops.OpContext{}, OpSetAttr, "a", 1, nil)
+	require.NoError(t.T(), l.Close())
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t.T(), err)
+	lines := strings.Split(strings.TrimSuffix(string(contents), "\n"), "\n")
+	require.Len(t.T(), lines, 2)
+	assert.Contains(t.T(), lines[1], `"op":"setattr"`)
+}
+
+func (t *AuditTest) TestNewLoggerCreatesPrivateFile() {
+	path := filepath.Join(t.T().TempDir(), "audit.json")
+
+	l, err := NewLogger(path, config.DefaultLogRotateConfig())
+	require.NoError(t.T(), err)
+	require.NoError(t.T(), l.Close())
+
+	fi, err := os.Stat(path)
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), os.FileMode(0600), fi.Mode().Perm())
+}
+
+func (t *AuditTest) TestNewLoggerInMissingDirectory() {
+	path := filepath.Join(t.T().TempDir(), "missing", "audit.json")
+
+	_, err := NewLogger(path, config.DefaultLogRotateConfig())
+
+	assert.ErrorContains(t.T(), err, "OpenFile")
+}
@@ -16,6 +16,8 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 
@@ -26,6 +28,24 @@ import (
 
 const universeDomainDefault = "googleapis.com"
 
+// The types of credentials files handled apart.
+const (
+	serviceAccountKey  = "service_account"
+	externalAccountKey = "external_account"
+)
+
+// The environment variable allowing the external account credentials to run
+// the executables they are sourced from.
+const allowExecutablesEnvVar = "GOOGLE_EXTERNAL_ACCOUNT_ALLOW_EXECUTABLES"
+
+// The fields of a credentials file looked at before loading it.
+type credentialsFile struct {
+	Type             string `json:"type"`
+	CredentialSource struct {
+		Executable *json.RawMessage `json:"executable"`
+	} `json:"credential_source"`
+}
+
 func getUniverseDomain(ctx context.Context, contents []byte, scope string) (string, error) {
 	creds, err := google.CredentialsFromJSON(ctx, contents, scope)
 	if err != nil {
@@ -42,11 +62,46 @@ func getUniverseDomain(ctx context.Context, contents []byte, scope string) (stri
 	return domain, nil
 }
 
+// Create the token source of the credentials of an external account, i.e.
+// of workload identity federation, or of another type of credentials file but
+// a service account key, whose tokens are obtained as described in the file.
+func newTokenSourceFromCredentials(
+	ctx context.Context,
+	file *credentialsFile,
+	contents []byte,
+	scope string,
+	allowExecutables bool,
+) (ts oauth2.TokenSource, err error) {
+	if file.Type == externalAccountKey && file.CredentialSource.Executable != nil {
+		if !allowExecutables {
+			err = errors.New("the credentials are sourced from an executable, which must be allowed with --allow-credential-executables")
+			return
+		}
+
+		// The client library only runs the executables if allowed by the
+		// environment.
+		if err = os.Setenv(allowExecutablesEnvVar, "1"); err != nil {
+			err = fmt.Errorf("Setenv: %w", err)
+			return
+		}
+	}
+
+	creds, err := google.CredentialsFromJSON(ctx, contents, scope)
+	if err != nil {
+		err = fmt.Errorf("CredentialsFromJSON: %w", err)
+		return
+	}
+
+	ts = creds.TokenSource
+	return
+}
+
 // Create token source from the JSON file at the supplide path.
 func newTokenSourceFromPath(
 	ctx context.Context,
 	path string,
 	scope string,
+	allowExecutables bool,
 ) (ts oauth2.TokenSource, err error) {
 	// Read the file.
 	contents, err := os.ReadFile(path)
@@ -55,11 +110,22 @@ func newTokenSourceFromPath(
 		return
 	}
 
+	var file credentialsFile
+	if err = json.Unmarshal(contents, &file); err != nil {
+		err = fmt.Errorf("Unmarshal(%q): %w", path, err)
+		return
+	}
+
+	if file.Type != serviceAccountKey {
+		return newTokenSourceFromCredentials(ctx, &file, contents, scope, allowExecutables)
+	}
+
 	// By default, a standard OAuth 2.0 token source is created
 	// Create a config struct based on its contents.
 	jwtConfig, err := google.JWTConfigFromJSON(contents, scope)
 	if err != nil {
 		err = fmt.Errorf("JWTConfigFromJSON: %w", err)
+		return
 	}
 	// Create the token source.
 	ts = jwtConfig.TokenSource(ctx)
@@ -87,30 +153,55 @@ func newTokenSourceFromPath(
 // for key-file and default-credential flow.
 // It also supports generating the self-signed JWT tokenSource for key-file authentication which can be
 // used by custom-endpoint(e.g. TPC).
+// The key file can also hold the credentials of an external account, for workload identity federation,
+// which are sourced from an executable only if allowCredentialExecutables is set.
+// If impersonateServiceAccount is set, the tokens are those of the service account it names, or of the last
+// of its comma-separated delegation chain, generated with the credentials, which are then scoped for the
+// IAM Service Account Credentials API rather than for GCS.
 func GetTokenSource(
 	ctx context.Context,
 	keyFile string,
 	tokenUrl string,
 	reuseTokenFromUrl bool,
+	impersonateServiceAccount string,
+	allowCredentialExecutables bool,
 ) (tokenSrc oauth2.TokenSource, err error) {
 	// Create the oauth2 token source.
 	const scope = storagev1.DevstorageFullControlScope
-	var method string
+	var method, source string
+
+	// The credentials used to impersonate a service account must be allowed
+	// to call the IAM Service Account Credentials API, which the storage scope
+	// doesn't cover.
+	baseScope := scope
+	if impersonateServiceAccount != "" {
+		baseScope = storagev1.CloudPlatformScope
+	}
 
 	if keyFile != "" {
-		tokenSrc, err = newTokenSourceFromPath(ctx, keyFile, scope)
-		method = "newTokenSourceFromPath"
+		tokenSrc, err = newTokenSourceFromPath(ctx, keyFile, baseScope, allowCredentialExecutables)
+		method, source = "newTokenSourceFromPath", sourceKeyFile
 	} else if tokenUrl != "" {
 		tokenSrc, err = newProxyTokenSource(ctx, tokenUrl, reuseTokenFromUrl)
-		method = "newProxyTokenSource"
+		method, source = "newProxyTokenSource", sourceTokenUrl
 	} else {
-		tokenSrc, err = google.DefaultTokenSource(ctx, scope)
-		method = "DefaultTokenSource"
+		tokenSrc, err = google.DefaultTokenSource(ctx, baseScope)
+		method, source = "DefaultTokenSource", sourceDefault
 	}
 
 	if err != nil {
 		err = fmt.Errorf("%s: %w", method, err)
 		return
 	}
+
+	if impersonateServiceAccount != "" {
+		tokenSrc, err = newImpersonatedTokenSource(ctx, tokenSrc, impersonateServiceAccount, scope)
+		if err != nil {
+			return
+		}
+		source = sourceImpersonation
+	}
+
+	tokenSrc = newMonitoredTokenSource(ctx, source, tokenSrc)
 	return
 }
@@ -16,10 +16,13 @@ package auth
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	storagev1 "google.golang.org/api/storage/v1"
 )
@@ -71,3 +74,108 @@ func (t *AuthTest) TestGetUniverseDomainForEmptyCreds() {
 	assert.Error(t.T(), err)
 	assert.Equal(t.T(), "CredentialsFromJSON(): unexpected end of JSON input", err.Error())
 }
+
+// Write the credentials of an external account exchanging the subject token
+// of the given credential source at the token server.
+func writeExternalAccountCredentials(t *AuthTest, server *tokenServer, credentialSource string) string {
+	p := filepath.Join(t.T().TempDir(), "creds.json")
+	contents := fmt.Sprintf(`{
+  "type": "external_account",
+  "audience": "//iam.googleapis.com/projects/123/locations/global/workloadIdentityPools/pool/providers/oidc",
+  "subject_token_type": "urn:ietf:params:oauth:token-type:jwt",
+  "token_url": "%s/sts",
+  "credential_source": %s
+}`, server.URL, credentialSource)
+	require.NoError(t.T(), os.WriteFile(p, []byte(contents), 0600))
+	return p
+}
+
+func writeSubjectTokenFile(t *AuthTest) string {
+	p := filepath.Join(t.T().TempDir(), "token")
+	require.NoError(t.T(), os.WriteFile(p, []byte(subjectToken), 0600))
+	return p
+}
+
+func (t *AuthTest) TestExternalAccountFromFile() {
+	server := newTokenServer()
+	defer server.Close()
+	keyFile := writeExternalAccountCredentials(t, server, fmt.Sprintf(`{"file": %q}`, writeSubjectTokenFile(t)))
+
+	ts, err := GetTokenSource(context.Background(), keyFile, "", false, "", false)
+	require.NoError(t.T(), err)
+	token, err := ts.Token()
+
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), federatedToken, token.AccessToken)
+}
+
+func writeExecutableCredentials(t *AuthTest, server *tokenServer) string {
+	script := filepath.Join(t.T().TempDir(), "token.sh")
+	response := fmt.Sprintf(`{"version": 1, "success": true, "token_type": "urn:ietf:params:oauth:token-type:jwt", "id_token": %q, "expiration_time": 4102444800}`, subjectToken)
+	require.NoError(t.T(), os.WriteFile(script, []byte("#!/bin/sh\necho '"+response+"'\n"), 0700))
+
+	return writeExternalAccountCredentials(t, server, fmt.Sprintf(`{"executable": {"command": %q, "timeout_millis": 5000}}`, script))
+}
+
+func (t *AuthTest) TestExternalAccountFromExecutableNotAllowed() {
+	server := newTokenServer()
+	defer server.Close()
+	keyFile := writeExecutableCredentials(t, server)
+
+	_, err := GetTokenSource(context.Background(), keyFile, "", false, "", false)
+
+	assert.ErrorContains(t.T(), err, "--allow-credential-executables")
+}
+
+func (t *AuthTest) TestExternalAccountFromExecutable() {
+	server := newTokenServer()
+	defer server.Close()
+	keyFile := writeExecutableCredentials(t, server)
+	// Restore the environment after the test.
+	t.T().Setenv(allowExecutablesEnvVar, "")
+
+	ts, err := GetTokenSource(context.Background(), keyFile, "", false, "", true)
+	require.NoError(t.T(), err)
+	token, err := ts.Token()
+
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), federatedToken, token.AccessToken)
+}
+
+func (t *AuthTest) TestImpersonationChain() {
+	server := newTokenServer()
+	defer server.Close()
+	defer func(endpoint string) { iamCredentialsEndpoint = endpoint }(iamCredentialsEndpoint)
+	iamCredentialsEndpoint = server.URL
+	keyFile := writeExternalAccountCredentials(t, server, fmt.Sprintf(`{"file": %q}`, writeSubjectTokenFile(t)))
+
+	ts, err := GetTokenSource(context.Background(), keyFile, "", false, "a@p.iam.gserviceaccount.com, b@p.iam.gserviceaccount.com", false)
+	require.NoError(t.T(), err)
+	token, err := ts.Token()
+
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), "impersonated-b@p.iam.gserviceaccount.com", token.AccessToken)
+	assert.Equal(t.T(), "Bearer "+federatedToken, server.authorization)
+	assert.Equal(t.T(), []string{"projects/-/serviceAccounts/a@p.iam.gserviceaccount.com"}, server.delegates)
+	assert.Equal(t.T(), storagev1.CloudPlatformScope, server.exchangeScope)
+	assert.Equal(t.T(), []string{storagev1.DevstorageFullControlScope}, server.scopes)
+}
+
+func (t *AuthTest) TestImpersonationInvalidChain() {
+	server := newTokenServer()
+	defer server.Close()
+	keyFile := writeExternalAccountCredentials(t, server, fmt.Sprintf(`{"file": %q}`, writeSubjectTokenFile(t)))
+
+	_, err := GetTokenSource(context.Background(), keyFile, "", false, "a@p.iam.gserviceaccount.com,,b@p.iam.gserviceaccount.com", false)
+
+	assert.ErrorContains(t.T(), err, "invalid service account list")
+}
+
+func (t *AuthTest) TestInvalidKeyFile() {
+	keyFile := filepath.Join(t.T().TempDir(), "creds.json")
+	require.NoError(t.T(), os.WriteFile(keyFile, []byte("{"), 0600))
+
+	_, err := GetTokenSource(context.Background(), keyFile, "", false, "", false)
+
+	assert.ErrorContains(t.T(), err, "newTokenSourceFromPath: Unmarshal")
+}
@@ -0,0 +1,136 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// The endpoint of the IAM Service Account Credentials API, replaced by tests.
+var iamCredentialsEndpoint = "https://iamcredentials.googleapis.com"
+
+// The lifetime of the impersonated access tokens, the maximum by default.
+const impersonatedTokenLifetime = time.Hour
+
+// newImpersonatedTokenSource returns a TokenSource of access tokens of the
+// last service account of the given comma-separated list, generated with the
+// tokens of the base source through the IAM Service Account Credentials API.
+// The service accounts before the last are the delegation chain: each must be
+// granted the Service Account Token Creator role on the next.
+func newImpersonatedTokenSource(
+	ctx context.Context,
+	base oauth2.TokenSource,
+	serviceAccounts string,
+	scope string,
+) (ts oauth2.TokenSource, err error) {
+	chain := strings.Split(serviceAccounts, ",")
+	for i := range chain {
+		chain[i] = strings.TrimSpace(chain[i])
+		if chain[i] == "" {
+			err = fmt.Errorf("newImpersonatedTokenSource: invalid service account list %q", serviceAccounts)
+			return
+		}
+	}
+
+	ts = oauth2.ReuseTokenSource(nil, &impersonatedTokenSource{
+		client:    oauth2.NewClient(ctx, base),
+		target:    chain[len(chain)-1],
+		delegates: chain[:len(chain)-1],
+		scope:     scope,
+	})
+	return
+}
+
+type impersonatedTokenSource struct {
+	client    *http.Client
+	target    string
+	delegates []string
+	scope     string
+}
+
+type generateAccessTokenRequest struct {
+	Delegates []string `json:"delegates,omitempty"`
+	Scope     []string `json:"scope"`
+	Lifetime  string   `json:"lifetime"`
+}
+
+type generateAccessTokenResponse struct {
+	AccessToken string    `json:"accessToken"`
+	ExpireTime  time.Time `json:"expireTime"`
+}
+
+func serviceAccountName(email string) string {
+	return "projects/-/serviceAccounts/" + email
+}
+
+func (ts *impersonatedTokenSource) Token() (token *oauth2.Token, err error) {
+	req := generateAccessTokenRequest{
+		Scope:    []string{ts.scope},
+		Lifetime: fmt.Sprintf("%ds", int(impersonatedTokenLifetime.Seconds())),
+	}
+	for _, d := range ts.delegates {
+		req.Delegates = append(req.Delegates, serviceAccountName(d))
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		err = fmt.Errorf("impersonatedTokenSource cannot encode request: %w", err)
+		return
+	}
+
+	url := fmt.Sprintf("%s/v1/%s:generateAccessToken", iamCredentialsEndpoint, serviceAccountName(ts.target))
+	resp, err := ts.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		err = fmt.Errorf("impersonatedTokenSource cannot generate token for %s: %w", ts.target, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err = io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		err = fmt.Errorf("impersonatedTokenSource cannot load body: %w", err)
+		return
+	}
+
+	if c := resp.StatusCode; c < 200 || c >= 300 {
+		err = &oauth2.RetrieveError{
+			Response: resp,
+			Body:     body,
+		}
+		return
+	}
+
+	var r generateAccessTokenResponse
+	if err = json.Unmarshal(body, &r); err != nil {
+		err = fmt.Errorf("impersonatedTokenSource cannot decode body: %w", err)
+		return
+	}
+
+	token = &oauth2.Token{
+		AccessToken: r.AccessToken,
+		TokenType:   "Bearer",
+		Expiry:      r.ExpireTime,
+	}
+	return
+}
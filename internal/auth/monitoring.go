@@ -0,0 +1,76 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor"
+	"golang.org/x/oauth2"
+)
+
+// The sources of tokens, as annotated in the metrics.
+const (
+	sourceKeyFile       = "key_file"
+	sourceTokenUrl      = "token_url"
+	sourceDefault       = "default"
+	sourceImpersonation = "impersonation"
+)
+
+// monitoredTokenSource records the fetches of tokens by the wrapped source,
+// which reuses a token until it expires: a fetch is a call returning a new
+// token, or failing.
+type monitoredTokenSource struct {
+	ctx     context.Context
+	source  string
+	wrapped oauth2.TokenSource
+
+	mu sync.Mutex
+
+	// The last token returned.
+	last string // GUARDED_BY(mu)
+}
+
+func newMonitoredTokenSource(ctx context.Context, source string, wrapped oauth2.TokenSource) oauth2.TokenSource {
+	return &monitoredTokenSource{
+		ctx:     ctx,
+		source:  source,
+		wrapped: wrapped,
+	}
+}
+
+func (ts *monitoredTokenSource) Token() (token *oauth2.Token, err error) {
+	start := time.Now()
+	token, err = ts.wrapped.Token()
+	latency := time.Since(start)
+
+	if err != nil {
+		monitor.CaptureTokenRefreshMetrics(ts.ctx, ts.source, false, latency)
+		return
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if token.AccessToken != ts.last {
+		ts.last = token.AccessToken
+		logger.Debugf("Fetched an access token from the %s source, expiring at %v", ts.source, token.Expiry)
+		monitor.CaptureTokenRefreshMetrics(ts.ctx, ts.source, true, latency)
+	}
+
+	return
+}
@@ -0,0 +1,95 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	subjectToken   = "subject-token"
+	federatedToken = "federated-token"
+)
+
+// tokenServer is a local Security Token Service, exchanging subjectToken for
+// federatedToken, and IAM Service Account Credentials API, generating the
+// tokens of any service account.
+type tokenServer struct {
+	*httptest.Server
+
+	mu sync.Mutex
+
+	// The scope of the last token exchange.
+	exchangeScope string
+
+	// The authorization, the delegates and the scopes of the last generated
+	// token.
+	authorization string
+	delegates     []string
+	scopes        []string
+}
+
+func newTokenServer() *tokenServer {
+	s := &tokenServer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sts", s.exchange)
+	mux.HandleFunc("/v1/", s.generateAccessToken)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+func (s *tokenServer) exchange(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:token-exchange" || r.FormValue("subject_token") != subjectToken {
+		http.Error(w, "invalid token exchange", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.exchangeScope = r.FormValue("scope")
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"access_token":      federatedToken,
+		"issued_token_type": "urn:ietf:params:oauth:token-type:access_token",
+		"token_type":        "Bearer",
+		"expires_in":        3600,
+	})
+}
+
+func (s *tokenServer) generateAccessToken(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/v1/projects/-/serviceAccounts/"), ":generateAccessToken")
+	var req generateAccessTokenRequest
+	if !ok || r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&req) != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.authorization = r.Header.Get("Authorization")
+	s.delegates = req.Delegates
+	s.scopes = req.Scope
+	s.mu.Unlock()
+
+	_ = json.NewEncoder(w).Encode(generateAccessTokenResponse{
+		AccessToken: "impersonated-" + name,
+		ExpireTime:  time.Now().Add(time.Hour),
+	})
+}
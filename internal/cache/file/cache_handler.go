@@ -17,6 +17,7 @@ package file
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/data"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/file/downloader"
@@ -51,6 +52,28 @@ type CacheHandler struct {
 
 	// mu guards the handling of insertion into and eviction from file cache.
 	mu locker.Locker
+
+	// diskUsage samples the usage of the filesystem holding cacheDir. It is a
+	// field so that tests can fake the filesystem.
+	diskUsage func(path string) (util.DiskUsage, error)
+
+	// minFreeDiskPercent is the percentage of the filesystem holding cacheDir
+	// to be kept free. Zero means the free-disk watermark is disabled.
+	//
+	// GUARDED_BY(mu)
+	minFreeDiskPercent float64
+
+	// maxSize is the upper bound on the fileInfoCache size when the free-disk
+	// watermark is enabled.
+	//
+	// GUARDED_BY(mu)
+	maxSize uint64
+
+	// stopWatermarkMonitor is closed to stop the goroutine sampling the free
+	// space of the filesystem holding cacheDir.
+	//
+	// GUARDED_BY(mu)
+	stopWatermarkMonitor chan struct{}
 }
 
 func NewCacheHandler(fileInfoCache *lru.Cache, jobManager *downloader.JobManager, cacheDir string, filePerm os.FileMode, dirPerm os.FileMode) *CacheHandler {
@@ -61,6 +84,7 @@ func NewCacheHandler(fileInfoCache *lru.Cache, jobManager *downloader.JobManager
 		filePerm:      filePerm,
 		dirPerm:       dirPerm,
 		mu:            locker.New("FileCacheHandler", func() {}),
+		diskUsage:     util.GetDiskUsage,
 	}
 }
 
@@ -273,7 +297,131 @@ func (chr *CacheHandler) InvalidateCache(objectName string, bucketName string) e
 	return nil
 }
 
-// Destroy destroys the job manager (i.e. invalidate all the jobs).
+// EnableFreeDiskWatermark makes the size of the file cache track the free
+// space of the filesystem holding cacheDir, so that at least minFreeDiskPercent
+// of it is kept free. The cache never grows beyond maxSize. The filesystem is
+// sampled once before returning and then every interval, and entries are
+// evicted as soon as the watermark is crossed.
+//
+// Acquires and releases LOCK(CacheHandler.mu)
+func (chr *CacheHandler) EnableFreeDiskWatermark(minFreeDiskPercent float64, maxSize uint64, interval time.Duration) error {
+	if minFreeDiskPercent <= 0 || minFreeDiskPercent >= 100 {
+		return fmt.Errorf("EnableFreeDiskWatermark: invalid minFreeDiskPercent: %v", minFreeDiskPercent)
+	}
+	if interval <= 0 {
+		return fmt.Errorf("EnableFreeDiskWatermark: invalid interval: %v", interval)
+	}
+
+	chr.mu.Lock()
+	chr.minFreeDiskPercent = minFreeDiskPercent
+	chr.maxSize = maxSize
+	chr.mu.Unlock()
+
+	if err := chr.ApplyFreeDiskWatermark(); err != nil {
+		return fmt.Errorf("EnableFreeDiskWatermark: %w", err)
+	}
+
+	stop := make(chan struct{})
+	chr.mu.Lock()
+	chr.stopWatermarkMonitor = stop
+	chr.mu.Unlock()
+
+	go chr.monitorFreeDisk(interval, stop)
+	return nil
+}
+
+func (chr *CacheHandler) monitorFreeDisk(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := chr.ApplyFreeDiskWatermark(); err != nil {
+				logger.Warnf("monitorFreeDisk: %v", err)
+			}
+		}
+	}
+}
+
+// ApplyFreeDiskWatermark samples the filesystem holding cacheDir and resizes
+// the fileInfoCache so that, once fully downloaded, the cache leaves at least
+// minFreeDiskPercent of the filesystem free. Entries evicted by the resize are
+// cleaned up. It is a no-op if the free-disk watermark is not enabled.
+//
+// Note: the size of an entry counts towards the cache as soon as its download
+// job is created, so the cache may temporarily be sized slightly above what
+// the disk allows until the next sample.
+//
+// Acquires and releases LOCK(CacheHandler.mu)
+func (chr *CacheHandler) ApplyFreeDiskWatermark() error {
+	chr.mu.Lock()
+	defer chr.mu.Unlock()
+
+	if chr.minFreeDiskPercent == 0 {
+		return nil
+	}
+
+	usage, err := chr.diskUsage(chr.cacheDir)
+	if err != nil {
+		return fmt.Errorf("ApplyFreeDiskWatermark: while sampling disk usage: %w", err)
+	}
+
+	watermark := uint64(float64(usage.TotalBytes) * chr.minFreeDiskPercent / 100)
+	currentSize := chr.fileInfoCache.CurrentSize()
+	var newMaxSize uint64
+	if usage.AvailableBytes >= watermark {
+		newMaxSize = currentSize + (usage.AvailableBytes - watermark)
+	} else if deficit := watermark - usage.AvailableBytes; deficit < currentSize {
+		newMaxSize = currentSize - deficit
+	}
+	newMaxSize = min(newMaxSize, chr.maxSize)
+	// The LRU cache requires a non-zero size. A cache of one byte can't hold
+	// any object, so all reads fall back to GCS.
+	newMaxSize = max(newMaxSize, 1)
+
+	evictedValues, err := chr.fileInfoCache.UpdateMaxSize(newMaxSize)
+	if err != nil {
+		return fmt.Errorf("ApplyFreeDiskWatermark: while resizing the cache: %w", err)
+	}
+	if len(evictedValues) > 0 {
+		logger.Infof("Free disk watermark crossed for %s (available: %d bytes, total: %d bytes), evicting %d file(s) from cache", chr.cacheDir, usage.AvailableBytes, usage.TotalBytes, len(evictedValues))
+	}
+	for _, val := range evictedValues {
+		fileInfo := val.(data.FileInfo)
+		err := chr.cleanUpEvictedFile(&fileInfo)
+		if err != nil {
+			return fmt.Errorf("ApplyFreeDiskWatermark: while performing post eviction of %s object error: %w", fileInfo.Key.ObjectName, err)
+		}
+	}
+
+	return nil
+}
+
+// Entries returns the file info of the objects in the cache, from the most to
+// the least recently used.
+func (chr *CacheHandler) Entries() (entries []data.FileInfo) {
+	chr.fileInfoCache.ForEach(func(key string, value lru.ValueType) {
+		entries = append(entries, value.(data.FileInfo))
+	})
+	return
+}
+
+// Size returns the size of the objects in the cache and the maximum size of
+// the cache, in bytes.
+func (chr *CacheHandler) Size() (size uint64, maxSize uint64) {
+	return chr.fileInfoCache.CurrentSize(), chr.fileInfoCache.MaxSize()
+}
+
+// DownloadJobs returns the jobs downloading objects into the cache.
+func (chr *CacheHandler) DownloadJobs() []*downloader.Job {
+	return chr.jobManager.Jobs()
+}
+
+// Destroy destroys the job manager (i.e. invalidate all the jobs) and stops
+// sampling the free disk space, if it was enabled.
 // Note: This method is expected to be called at the time of unmounting and
 // because file info cache is in-memory, it is not required to destroy it.
 //
@@ -282,6 +430,10 @@ func (chr *CacheHandler) Destroy() (err error) {
 	chr.mu.Lock()
 	defer chr.mu.Unlock()
 
+	if chr.stopWatermarkMonitor != nil {
+		close(chr.stopWatermarkMonitor)
+		chr.stopWatermarkMonitor = nil
+	}
 	chr.jobManager.Destroy()
 	return
 }
@@ -19,6 +19,7 @@ import (
 	"crypto/rand"
 	"errors"
 	"io"
+	"math"
 	"os"
 	"path"
 	"strconv"
@@ -707,3 +708,105 @@ func (chrT *cacheHandlerTest) Test_Destroy() {
 	AssertEq(nil, chrT.jobManager.GetJob(minObject1.Name, chrT.bucket.Name()))
 	AssertEq(nil, chrT.jobManager.GetJob(minObject2.Name, chrT.bucket.Name()))
 }
+
+func (chrT *cacheHandlerTest) fakeDiskUsage(totalBytes, availableBytes uint64) {
+	chrT.cacheHandler.diskUsage = func(string) (util.DiskUsage, error) {
+		return util.DiskUsage{TotalBytes: totalBytes, AvailableBytes: availableBytes}, nil
+	}
+}
+
+func (chrT *cacheHandlerTest) Test_ApplyFreeDiskWatermark_WhenDisabled() {
+	chrT.fakeDiskUsage(100*util.MiB, 0)
+
+	err := chrT.cacheHandler.ApplyFreeDiskWatermark()
+
+	AssertEq(nil, err)
+	ExpectEq(HandlerCacheMaxSize, chrT.cache.MaxSize())
+	ExpectTrue(chrT.isEntryInFileInfoCache(chrT.object.Name, chrT.bucket.Name()))
+}
+
+func (chrT *cacheHandlerTest) Test_EnableFreeDiskWatermark_WhenWatermarkCrossed() {
+	// 10% of 100 MiB is to be kept free, but only 5 MiB is available, so the
+	// cache has to shrink by 5 MiB and the test object no longer fits.
+	chrT.fakeDiskUsage(100*util.MiB, 5*util.MiB)
+
+	err := chrT.cacheHandler.EnableFreeDiskWatermark(10, HandlerCacheMaxSize, time.Hour)
+
+	AssertEq(nil, err)
+	ExpectEq(11*util.MiB, chrT.cache.MaxSize())
+	ExpectFalse(chrT.isEntryInFileInfoCache(chrT.object.Name, chrT.bucket.Name()))
+	ExpectFalse(doesFileExist(chrT.downloadPath))
+	ExpectEq(nil, chrT.jobManager.GetJob(chrT.object.Name, chrT.bucket.Name()))
+}
+
+func (chrT *cacheHandlerTest) Test_EnableFreeDiskWatermark_WhenDiskIsFull() {
+	chrT.fakeDiskUsage(100*util.MiB, 0)
+
+	err := chrT.cacheHandler.EnableFreeDiskWatermark(50, HandlerCacheMaxSize, time.Hour)
+
+	AssertEq(nil, err)
+	ExpectEq(1, chrT.cache.MaxSize())
+	ExpectEq(0, chrT.cache.CurrentSize())
+}
+
+func (chrT *cacheHandlerTest) Test_EnableFreeDiskWatermark_CappedByMaxSize() {
+	chrT.fakeDiskUsage(100*util.MiB, 90*util.MiB)
+
+	err := chrT.cacheHandler.EnableFreeDiskWatermark(10, HandlerCacheMaxSize, time.Hour)
+
+	AssertEq(nil, err)
+	ExpectEq(HandlerCacheMaxSize, chrT.cache.MaxSize())
+	ExpectTrue(chrT.isEntryInFileInfoCache(chrT.object.Name, chrT.bucket.Name()))
+}
+
+func (chrT *cacheHandlerTest) Test_EnableFreeDiskWatermark_GrowsWithFreeSpace() {
+	chrT.fakeDiskUsage(100*util.MiB, 90*util.MiB)
+
+	err := chrT.cacheHandler.EnableFreeDiskWatermark(10, math.MaxUint64, time.Hour)
+
+	AssertEq(nil, err)
+	// The 16 MiB already cached plus 80 MiB that can still be used.
+	ExpectEq(96*util.MiB, chrT.cache.MaxSize())
+	ExpectTrue(chrT.isEntryInFileInfoCache(chrT.object.Name, chrT.bucket.Name()))
+}
+
+func (chrT *cacheHandlerTest) Test_EnableFreeDiskWatermark_InvalidArguments() {
+	err := chrT.cacheHandler.EnableFreeDiskWatermark(0, HandlerCacheMaxSize, time.Hour)
+	ExpectNe(nil, err)
+
+	err = chrT.cacheHandler.EnableFreeDiskWatermark(100, HandlerCacheMaxSize, time.Hour)
+	ExpectNe(nil, err)
+
+	err = chrT.cacheHandler.EnableFreeDiskWatermark(10, HandlerCacheMaxSize, 0)
+	ExpectNe(nil, err)
+}
+
+func (chrT *cacheHandlerTest) Test_EnableFreeDiskWatermark_WhenStatfsFails() {
+	chrT.cacheHandler.diskUsage = func(string) (util.DiskUsage, error) {
+		return util.DiskUsage{}, errors.New("statfs failed")
+	}
+
+	err := chrT.cacheHandler.EnableFreeDiskWatermark(10, HandlerCacheMaxSize, time.Hour)
+
+	AssertNe(nil, err)
+	ExpectTrue(strings.Contains(err.Error(), "statfs failed"))
+}
+
+func (chrT *cacheHandlerTest) Test_EnableFreeDiskWatermark_EvictsPeriodically() {
+	chrT.fakeDiskUsage(100*util.MiB, 90*util.MiB)
+	err := chrT.cacheHandler.EnableFreeDiskWatermark(10, HandlerCacheMaxSize, time.Millisecond)
+	AssertEq(nil, err)
+	AssertTrue(chrT.isEntryInFileInfoCache(chrT.object.Name, chrT.bucket.Name()))
+
+	// Other jobs fill the disk.
+	chrT.cacheHandler.mu.Lock()
+	chrT.fakeDiskUsage(100*util.MiB, 0)
+	chrT.cacheHandler.mu.Unlock()
+
+	for i := 0; i < 1000 && chrT.isEntryInFileInfoCache(chrT.object.Name, chrT.bucket.Name()); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	ExpectFalse(chrT.isEntryInFileInfoCache(chrT.object.Name, chrT.bucket.Name()))
+	AssertEq(nil, chrT.cacheHandler.Destroy())
+	ExpectEq(nil, chrT.cacheHandler.stopWatermarkMonitor)
+}
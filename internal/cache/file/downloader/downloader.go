@@ -16,6 +16,7 @@ package downloader
 
 import (
 	"os"
+	"sort"
 
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/data"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/lru"
@@ -116,6 +117,24 @@ func (jm *JobManager) GetJob(objectName string, bucketName string) *Job {
 	return job
 }
 
+// Jobs returns the jobs for which the download hasn't completed, failed or
+// been invalidated yet, sorted by object path.
+//
+// Acquires and releases Lock(jm.mu)
+func (jm *JobManager) Jobs() (jobs []*Job) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	paths := make([]string, 0, len(jm.jobs))
+	for objectPath := range jm.jobs {
+		paths = append(paths, objectPath)
+	}
+	sort.Strings(paths)
+	for _, objectPath := range paths {
+		jobs = append(jobs, jm.jobs[objectPath])
+	}
+	return
+}
+
 // InvalidateAndRemoveJob invalidates downloader.Job for given object and bucket.
 // If there is no existing job present then this method does nothing.
 // Note: Invalidating a job also removes job from jm.jobs map.
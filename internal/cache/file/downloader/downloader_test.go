@@ -190,6 +190,21 @@ func (dt *downloaderTest) Test_GetJob_Concurrent() {
 	}
 }
 
+func (dt *downloaderTest) Test_Jobs() {
+	AssertEq(0, len(dt.jm.Jobs()))
+	other := gcs.MinObject{Name: "a/" + dt.object.Name, Generation: dt.object.Generation}
+	job1 := dt.jm.CreateJobIfNotExists(&dt.object, dt.bucket)
+	job2 := dt.jm.CreateJobIfNotExists(&other, dt.bucket)
+
+	jobs := dt.jm.Jobs()
+
+	AssertEq(2, len(jobs))
+	ExpectEq(job2, jobs[0])
+	ExpectEq(job1, jobs[1])
+	ExpectEq(&other, jobs[0].Object())
+	ExpectEq(dt.bucket.Name(), jobs[0].BucketName())
+}
+
 func (dt *downloaderTest) Test_InvalidateAndRemoveJob_NotExisting() {
 	expectedJob := dt.jm.GetJob(dt.object.Name, dt.bucket.Name())
 	AssertEq(nil, expectedJob)
@@ -29,8 +29,10 @@ import (
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/locker"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor/tags"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/util"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/context"
 )
 
@@ -137,10 +139,36 @@ func (job *Job) checkInvariants() {
 // state.
 func (job *Job) init() {
 	job.status = JobStatus{NotStarted, nil, 0}
+	monitor.CaptureActiveDownloadJobMetrics(context.Background(), string(NotStarted), 1)
 	job.subscribers = list.List{}
 	job.doneCh = make(chan struct{})
 }
 
+// isActive tells whether a job in the given state is yet to finish.
+func (name jobStatusName) isActive() bool {
+	return name == NotStarted || name == Downloading
+}
+
+// setStatusName changes the state of the job and records the transition in
+// the download job metrics.
+//
+// Not concurrency safe and requires LOCK(job.mu)
+func (job *Job) setStatusName(name jobStatusName) {
+	prev := job.status.Name
+	job.status.Name = name
+	if prev == name || !prev.isActive() {
+		return
+	}
+
+	ctx := context.Background()
+	monitor.CaptureActiveDownloadJobMetrics(ctx, string(prev), -1)
+	if name.isActive() {
+		monitor.CaptureActiveDownloadJobMetrics(ctx, string(name), 1)
+	} else {
+		monitor.CaptureFinishedDownloadJobMetrics(ctx, string(name))
+	}
+}
+
 // cancel is helper function to cancel the in-progress job.downloadAsync goroutine.
 // This call is blocking until the job.downloadAsync terminates. Also, it should
 // only be called when job.downloadAsync goroutine is running.
@@ -176,14 +204,14 @@ func (job *Job) cancel() {
 func (job *Job) Invalidate() {
 	job.mu.Lock()
 	if job.status.Name == Downloading {
-		job.status.Name = Invalid
+		job.setStatusName(Invalid)
 		job.cancel()
 
 		// Lock again to execute common notification logic.
 		job.mu.Lock()
 	}
 	defer job.mu.Unlock()
-	job.status.Name = Invalid
+	job.setStatusName(Invalid)
 	logger.Tracef("Job:%p (%s:/%s) is no longer valid.", job, job.bucket.Name(), job.object.Name)
 	if job.removeJobCallback != nil {
 		job.removeJobCallback()
@@ -228,7 +256,7 @@ func (job *Job) failWhileDownloading(downloadErr error) {
 	logger.Errorf("Job:%p (%s:/%s) failed with: %v", job, job.bucket.Name(), job.object.Name, downloadErr)
 	job.mu.Lock()
 	job.status.Err = downloadErr
-	job.status.Name = Failed
+	job.setStatusName(Failed)
 	job.notifySubscribers()
 	job.mu.Unlock()
 }
@@ -280,6 +308,9 @@ func (job *Job) downloadObjectAsync() {
 			job.removeJobCallback()
 			job.removeJobCallback = nil
 		}
+		span := trace.SpanFromContext(job.cancelCtx)
+		span.SetAttributes(tags.Size.Int64(job.status.Offset))
+		monitor.EndSpan(span, job.status.Err)
 		job.cancelCtx, job.cancelFunc = nil, nil
 		job.mu.Unlock()
 	}()
@@ -301,7 +332,7 @@ func (job *Job) downloadObjectAsync() {
 
 	notifyInvalid := func() {
 		job.mu.Lock()
-		job.status.Name = Invalid
+		job.setStatusName(Invalid)
 		job.notifySubscribers()
 		job.mu.Unlock()
 	}
@@ -385,7 +416,7 @@ func (job *Job) downloadObjectAsync() {
 					// downloading. If the entry is deleted in between which is expected
 					// to happen at the time of eviction, then the job should be
 					// marked Invalid instead of Failed.
-					job.status.Name = Invalid
+					job.setStatusName(Invalid)
 					job.notifySubscribers()
 					logger.Tracef("Job:%p (%s:/%s) is no longer valid due to absense of entry in file info cache.", job, job.bucket.Name(), job.object.Name)
 					job.mu.Unlock()
@@ -399,7 +430,7 @@ func (job *Job) downloadObjectAsync() {
 				}
 			} else {
 				job.mu.Lock()
-				job.status.Name = Completed
+				job.setStatusName(Completed)
 				job.notifySubscribers()
 				job.mu.Unlock()
 				return
@@ -427,8 +458,14 @@ func (job *Job) Download(ctx context.Context, offset int64, waitForDownload bool
 		return job.status, nil
 	} else if job.status.Name == NotStarted {
 		// Start the async download
-		job.status.Name = Downloading
-		job.cancelCtx, job.cancelFunc = context.WithCancel(context.Background())
+		job.setStatusName(Downloading)
+		// The download outlives the request starting it, so it is not cancelled
+		// along with it, but its span is a child of the request's.
+		downloadCtx, _ := monitor.StartSpan(trace.ContextWithSpan(context.Background(), trace.SpanFromContext(ctx)),
+			"file_cache/download",
+			tags.GCSBucket.String(job.bucket.Name()),
+			tags.GCSObject.String(job.object.Name))
+		job.cancelCtx, job.cancelFunc = context.WithCancel(downloadCtx)
 		go job.downloadObjectAsync()
 	} else if job.status.Name == Failed || job.status.Name == Invalid || job.status.Offset >= offset {
 		defer job.mu.Unlock()
@@ -455,6 +492,17 @@ func (job *Job) Download(ctx context.Context, offset int64, waitForDownload bool
 	return
 }
 
+// Object returns the object downloaded by the job.
+func (job *Job) Object() *gcs.MinObject {
+	return job.object
+}
+
+// BucketName returns the name of the bucket of the object downloaded by the
+// job.
+func (job *Job) BucketName() string {
+	return job.bucket.Name()
+}
+
 // GetStatus returns the status of download job.
 //
 // Acquires and releases LOCK(job.mu)
@@ -25,16 +25,21 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"testing"
 	"time"
 
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/data"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/lru"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/util"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor/monitortest"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor/tags"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/faults"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/storageutil"
 	testutil "github.com/googlecloudplatform/gcsfuse/v2/internal/util"
 	. "github.com/jacobsa/ogletest"
+	"github.com/stretchr/testify/assert"
 )
 
 ////////////////////////////////////////////////////////////////////////
@@ -477,6 +482,41 @@ func (dt *downloaderTest) Test_Download_WhenAsyncFails() {
 	AssertTrue(callbackExecuted.Load())
 }
 
+func (dt *downloaderTest) Test_Download_WhenReaderTruncated() {
+	objectName := "path/in/gcs/foo.txt"
+	objectSize := util.MiB
+	objectContent := testutil.GenerateRandomBytes(objectSize)
+	var callbackExecuted atomic.Bool
+	removeCallback := func() { callbackExecuted.Store(true) }
+	// Drop the connection of the first download halfway through.
+	truncateAfter := int64(objectSize / 2)
+	dt.bucket = faults.NewBucket(
+		[]faults.Rule{{Method: "NewReader", Object: "^" + objectName + "$", Count: 1, TruncateAfter: &truncateAfter}},
+		1,
+		dt.bucket)
+	dt.initJobTest(objectName, objectContent, DefaultSequentialReadSizeMb, uint64(objectSize), removeCallback)
+
+	jobStatus, err := dt.job.Download(context.Background(), int64(objectSize), true)
+
+	AssertEq(nil, err)
+	// Verify that jobStatus is failed
+	AssertEq(Failed, jobStatus.Name)
+	AssertTrue(strings.Contains(jobStatus.Err.Error(), "stream truncated"))
+	AssertTrue(callbackExecuted.Load())
+
+	// A new job downloads the whole object.
+	dt.initJobTest(objectName, objectContent, DefaultSequentialReadSizeMb, uint64(objectSize), func() {})
+
+	jobStatus, err = dt.job.Download(context.Background(), int64(objectSize), true)
+
+	AssertEq(nil, err)
+	AssertNe(Failed, jobStatus.Name)
+	AssertEq(nil, jobStatus.Err)
+	AssertEq(objectSize, jobStatus.Offset)
+	dt.verifyFile(objectContent)
+	dt.verifyFileInfoEntry(uint64(objectSize))
+}
+
 func (dt *downloaderTest) Test_Download_AlreadyFailed() {
 	objectName := "path/in/gcs/foo.txt"
 	objectSize := util.MiB
@@ -780,3 +820,32 @@ func (dt *downloaderTest) Test_Invalidate_Download_Concurrent() {
 	defer dt.job.mu.Unlock()
 	AssertEq(nil, dt.job.removeJobCallback)
 }
+
+func TestSetStatusNameRecordsTransitions(t *testing.T) {
+	activeJobs := func(name jobStatusName) int64 {
+		return monitortest.Int64(t, "file_cache/active_download_jobs", tags.JobState.String(string(name)))
+	}
+	finishedJobs := func(name jobStatusName) int64 {
+		return monitortest.Int64(t, "file_cache/download_job_count", tags.JobState.String(string(name)))
+	}
+	notStarted, downloading := activeJobs(NotStarted), activeJobs(Downloading)
+	completed, invalid := finishedJobs(Completed), finishedJobs(Invalid)
+	job := NewJob(&gcs.MinObject{Name: DefaultObjectName}, nil, nil, DefaultSequentialReadSizeMb, data.FileSpec{}, nil, false)
+	assert.Equal(t, notStarted+1, activeJobs(NotStarted))
+
+	job.mu.Lock()
+	job.setStatusName(Downloading)
+	job.setStatusName(Downloading)
+	job.mu.Unlock()
+	assert.Equal(t, notStarted, activeJobs(NotStarted))
+	assert.Equal(t, downloading+1, activeJobs(Downloading))
+
+	job.mu.Lock()
+	job.setStatusName(Completed)
+	// Leaving a finished state isn't recorded, as the job was already counted.
+	job.setStatusName(Invalid)
+	job.mu.Unlock()
+	assert.Equal(t, downloading, activeJobs(Downloading))
+	assert.Equal(t, completed+1, finishedJobs(Completed))
+	assert.Equal(t, invalid, finishedJobs(Invalid))
+}
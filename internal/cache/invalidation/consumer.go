@@ -0,0 +1,243 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package invalidation
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
+)
+
+// maxNotificationSize bounds the size of a single notification accepted from
+// either source.
+const maxNotificationSize = 1 << 20
+
+// DefaultPollInterval is the interval at which the notification file is
+// checked for new notifications.
+const DefaultPollInterval = time.Second
+
+// Config specifies the sources a Consumer reads notifications from.
+type Config struct {
+	// ListenAddress is the local address (host:port) on which Pub/Sub push
+	// deliveries are accepted. Empty disables the endpoint.
+	ListenAddress string
+
+	// FilePath is a file to which notifications are appended, one per line.
+	// Only notifications appended after the consumer starts are processed.
+	// Empty disables the file source.
+	FilePath string
+
+	// PollInterval is the interval at which FilePath is checked for new
+	// notifications.
+	PollInterval time.Duration
+}
+
+// Consumer reads notifications from the configured sources and invalidates
+// the affected objects.
+type Consumer struct {
+	config      Config
+	invalidator Invalidator
+
+	listener net.Listener
+	server   *http.Server
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewConsumer returns a Consumer that invalidates objects through invalidator.
+// Call Start to begin consuming.
+func NewConsumer(config Config, invalidator Invalidator) *Consumer {
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultPollInterval
+	}
+	return &Consumer{
+		config:      config,
+		invalidator: invalidator,
+	}
+}
+
+// Start binds the listen address and opens the notification file, if
+// configured, and starts consuming notifications in the background.
+func (c *Consumer) Start() (err error) {
+	var f *os.File
+	if c.config.FilePath != "" {
+		f, err = os.Open(c.config.FilePath)
+		if err != nil {
+			return fmt.Errorf("while opening notification file: %w", err)
+		}
+		// Older notifications are about state that isn't cached yet.
+		if _, err = f.Seek(0, io.SeekEnd); err != nil {
+			f.Close()
+			return fmt.Errorf("while seeking notification file: %w", err)
+		}
+	}
+
+	if c.config.ListenAddress != "" {
+		c.listener, err = net.Listen("tcp", c.config.ListenAddress)
+		if err != nil {
+			if f != nil {
+				f.Close()
+			}
+			return fmt.Errorf("while listening for notifications: %w", err)
+		}
+		c.server = &http.Server{Handler: NewPushHandler(c.invalidator)}
+		logger.Infof("Listening for object change notifications on %s", c.listener.Addr())
+
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			if err := c.server.Serve(c.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Errorf("Notification endpoint failed: %v", err)
+			}
+		}()
+	}
+
+	var ctx context.Context
+	ctx, c.cancel = context.WithCancel(context.Background())
+	if f != nil {
+		logger.Infof("Reading object change notifications from %s", c.config.FilePath)
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			defer f.Close()
+			tailFile(ctx, f, c.config.PollInterval, c.invalidator)
+		}()
+	}
+
+	return nil
+}
+
+// Addr returns the address the push endpoint is listening on, or nil if it
+// is disabled.
+func (c *Consumer) Addr() net.Addr {
+	if c.listener == nil {
+		return nil
+	}
+	return c.listener.Addr()
+}
+
+// Stop stops consuming notifications and waits for the sources to be closed.
+func (c *Consumer) Stop() {
+	if c.server != nil {
+		_ = c.server.Close()
+	}
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.wg.Wait()
+}
+
+func apply(n *Notification, invalidator Invalidator) {
+	logger.Tracef("Invalidating gs://%s/%s on %s notification (generation: %d)", n.BucketName, n.ObjectName, n.EventType, n.Generation)
+	invalidator.InvalidateObject(n.BucketName, n.ObjectName)
+}
+
+// NewPushHandler returns an http.Handler that accepts Pub/Sub push deliveries
+// of GCS notifications and invalidates the objects they are about.
+func NewPushHandler(invalidator Invalidator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxNotificationSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		n, err := ParseNotification(body)
+		if err != nil {
+			// Acknowledge anyway, as Pub/Sub would redeliver a malformed message
+			// forever.
+			logger.Warnf("Ignoring notification: %v", err)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		apply(n, invalidator)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// tailFile applies the notifications appended to f, one per line, until ctx
+// is cancelled. If f is truncated, it is read again from the start.
+func tailFile(ctx context.Context, f *os.File, pollInterval time.Duration, invalidator Invalidator) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	r := bufio.NewReaderSize(f, 64*1024)
+	var partial []byte
+	for {
+		for {
+			line, err := r.ReadBytes('\n')
+			partial = append(partial, line...)
+			if err != nil {
+				if err != io.EOF {
+					logger.Errorf("While reading notification file: %v", err)
+				}
+				break
+			}
+			applyLine(partial, invalidator)
+			partial = partial[:0]
+		}
+		if len(partial) > maxNotificationSize {
+			logger.Warnf("Ignoring notification larger than %d bytes", maxNotificationSize)
+			partial = partial[:0]
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Start over if the file has been truncated, e.g. by log rotation.
+		if offset, err := f.Seek(0, io.SeekCurrent); err == nil {
+			if fi, err := f.Stat(); err == nil && fi.Size() < offset {
+				if _, err := f.Seek(0, io.SeekStart); err == nil {
+					r.Reset(f)
+					partial = partial[:0]
+				}
+			}
+		}
+	}
+}
+
+func applyLine(line []byte, invalidator Invalidator) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return
+	}
+
+	n, err := ParseNotification(line)
+	if err != nil {
+		logger.Warnf("Ignoring notification: %v", err)
+		return
+	}
+	apply(n, invalidator)
+}
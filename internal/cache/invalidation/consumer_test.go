@@ -0,0 +1,144 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package invalidation
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeInvalidator struct {
+	mu      sync.Mutex
+	objects []string
+}
+
+func (f *fakeInvalidator) InvalidateObject(bucketName string, objectName string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects = append(f.objects, bucketName+"/"+objectName)
+}
+
+func (f *fakeInvalidator) invalidated() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.objects...)
+}
+
+func (f *fakeInvalidator) waitFor(t *testing.T, count int) []string {
+	t.Helper()
+	assert.Eventually(t, func() bool { return len(f.invalidated()) >= count }, 5*time.Second, time.Millisecond)
+	return f.invalidated()
+}
+
+func finalizeMessage(bucket, object string) string {
+	return fmt.Sprintf(`{"attributes":{"eventType":"OBJECT_FINALIZE","bucketId":%q,"objectId":%q}}`, bucket, object)
+}
+
+func TestPushHandler(t *testing.T) {
+	inv := &fakeInvalidator{}
+	h := NewPushHandler(inv)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"message":`+finalizeMessage("b", "o")+`}`)))
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, []string{"b/o"}, inv.invalidated())
+}
+
+func TestPushHandler_MalformedNotificationIsAcknowledged(t *testing.T) {
+	inv := &fakeInvalidator{}
+	h := NewPushHandler(inv)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`garbage`)))
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, inv.invalidated())
+}
+
+func TestPushHandler_RejectsGet(t *testing.T) {
+	h := NewPushHandler(&fakeInvalidator{})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestConsumer_ListenAddress(t *testing.T) {
+	inv := &fakeInvalidator{}
+	c := NewConsumer(Config{ListenAddress: "127.0.0.1:0"}, inv)
+	require.NoError(t, c.Start())
+	defer c.Stop()
+
+	resp, err := http.Post("http://"+c.Addr().String(), "application/json", strings.NewReader(finalizeMessage("b", "o")))
+
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, []string{"b/o"}, inv.invalidated())
+}
+
+func TestConsumer_File(t *testing.T) {
+	filePath := path.Join(t.TempDir(), "notifications")
+	// Notifications written before the consumer starts are ignored.
+	require.NoError(t, os.WriteFile(filePath, []byte(finalizeMessage("b", "old")+"\n"), 0600))
+	inv := &fakeInvalidator{}
+	c := NewConsumer(Config{FilePath: filePath, PollInterval: time.Millisecond}, inv)
+	require.NoError(t, c.Start())
+	defer c.Stop()
+	f, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	defer f.Close()
+
+	_, err = f.WriteString(finalizeMessage("b", "o1") + "\n" + "garbage\n\n" + objectResourceJSON[:10])
+	require.NoError(t, err)
+	// The partially written line is applied only once complete.
+	_, err = f.WriteString(objectResourceJSON[10:] + "\n")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"b/o1", "some-bucket/a/b/c.txt"}, inv.waitFor(t, 2))
+}
+
+func TestConsumer_FileTruncated(t *testing.T) {
+	filePath := path.Join(t.TempDir(), "notifications")
+	require.NoError(t, os.WriteFile(filePath, []byte(strings.Repeat(" ", 1024)), 0600))
+	inv := &fakeInvalidator{}
+	c := NewConsumer(Config{FilePath: filePath, PollInterval: time.Millisecond}, inv)
+	require.NoError(t, c.Start())
+	defer c.Stop()
+
+	require.NoError(t, os.WriteFile(filePath, []byte(finalizeMessage("b", "o")+"\n"), 0600))
+
+	assert.Equal(t, []string{"b/o"}, inv.waitFor(t, 1))
+}
+
+func TestConsumer_FileNotExist(t *testing.T) {
+	c := NewConsumer(Config{FilePath: path.Join(t.TempDir(), "missing")}, &fakeInvalidator{})
+
+	err := c.Start()
+
+	assert.ErrorContains(t, err, "while opening notification file")
+}
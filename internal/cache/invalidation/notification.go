@@ -0,0 +1,164 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package invalidation erases metadata and file cache entries of objects that
+// are changed outside the mount, as reported by a feed of GCS object change
+// notifications (https://cloud.google.com/storage/docs/pubsub-notifications).
+package invalidation
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Event types of GCS Pub/Sub notifications.
+const (
+	ObjectFinalize       = "OBJECT_FINALIZE"
+	ObjectMetadataUpdate = "OBJECT_METADATA_UPDATE"
+	ObjectDelete         = "OBJECT_DELETE"
+	ObjectArchive        = "OBJECT_ARCHIVE"
+)
+
+// Notification describes a change to a single GCS object.
+type Notification struct {
+	EventType  string
+	BucketName string
+	ObjectName string
+	// Generation of the object the notification is about, or zero if unknown.
+	Generation int64
+}
+
+// Invalidator erases the cached state of objects.
+type Invalidator interface {
+	// InvalidateObject erases every cache entry of the object with the given
+	// full name in the given bucket.
+	InvalidateObject(bucketName string, objectName string)
+}
+
+// pubSubMessage is a Pub/Sub message as delivered by a pull subscriber, or
+// wrapped in a push delivery.
+type pubSubMessage struct {
+	Attributes map[string]string `json:"attributes"`
+	Data       string            `json:"data"`
+}
+
+// pushRequest is the body of a Pub/Sub push delivery.
+type pushRequest struct {
+	Message *pubSubMessage `json:"message"`
+}
+
+// objectResource is the subset of the JSON API object resource, which is the
+// payload of notifications with the JSON_API_V1 payload format.
+type objectResource struct {
+	Kind       string `json:"kind"`
+	Bucket     string `json:"bucket"`
+	Name       string `json:"name"`
+	Generation string `json:"generation"`
+}
+
+// ParseNotification parses a notification from any of:
+//   - the body of a Pub/Sub push delivery,
+//   - a Pub/Sub message, e.g. as written by a pull subscriber, or
+//   - a bare JSON API object resource, which is treated as a metadata update.
+func ParseNotification(b []byte) (*Notification, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, fmt.Errorf("while parsing notification: %w", err)
+	}
+
+	if _, ok := fields["message"]; ok {
+		var req pushRequest
+		if err := json.Unmarshal(b, &req); err != nil {
+			return nil, fmt.Errorf("while parsing push request: %w", err)
+		}
+		if req.Message == nil {
+			return nil, errors.New("push request has no message")
+		}
+		return req.Message.notification()
+	}
+
+	if _, ok := fields["attributes"]; ok {
+		var msg pubSubMessage
+		if err := json.Unmarshal(b, &msg); err != nil {
+			return nil, fmt.Errorf("while parsing Pub/Sub message: %w", err)
+		}
+		return msg.notification()
+	}
+
+	var o objectResource
+	if err := json.Unmarshal(b, &o); err != nil {
+		return nil, fmt.Errorf("while parsing object resource: %w", err)
+	}
+	return o.notification(ObjectMetadataUpdate)
+}
+
+func (m *pubSubMessage) notification() (*Notification, error) {
+	attrs := m.Attributes
+	n := &Notification{
+		EventType:  attrs["eventType"],
+		BucketName: attrs["bucketId"],
+		ObjectName: attrs["objectId"],
+	}
+	if g := attrs["objectGeneration"]; g != "" {
+		var err error
+		if n.Generation, err = strconv.ParseInt(g, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid objectGeneration %q: %w", g, err)
+		}
+	}
+
+	// Fall back to the payload if the attributes don't name the object, e.g.
+	// when the message was re-published without them.
+	if (n.BucketName == "" || n.ObjectName == "") && m.Data != "" {
+		data, err := base64.StdEncoding.DecodeString(m.Data)
+		if err != nil {
+			return nil, fmt.Errorf("while decoding message data: %w", err)
+		}
+		var o objectResource
+		if err := json.Unmarshal(data, &o); err != nil {
+			return nil, fmt.Errorf("while parsing message data: %w", err)
+		}
+		eventType := n.EventType
+		if eventType == "" {
+			eventType = ObjectMetadataUpdate
+		}
+		return o.notification(eventType)
+	}
+
+	if n.BucketName == "" || n.ObjectName == "" {
+		return nil, errors.New("notification doesn't name a bucket and an object")
+	}
+	return n, nil
+}
+
+func (o *objectResource) notification(eventType string) (*Notification, error) {
+	if o.Bucket == "" || o.Name == "" {
+		return nil, errors.New("notification doesn't name a bucket and an object")
+	}
+
+	n := &Notification{
+		EventType:  eventType,
+		BucketName: o.Bucket,
+		ObjectName: o.Name,
+	}
+	if o.Generation != "" {
+		var err error
+		if n.Generation, err = strconv.ParseInt(o.Generation, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid generation %q: %w", o.Generation, err)
+		}
+	}
+	return n, nil
+}
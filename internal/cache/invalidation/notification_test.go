@@ -0,0 +1,95 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package invalidation
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const objectResourceJSON = `{"kind":"storage#object","bucket":"some-bucket","name":"a/b/c.txt","generation":"1234"}`
+
+func TestParseNotification_PushRequest(t *testing.T) {
+	body := `{
+		"message": {
+			"attributes": {
+				"eventType": "OBJECT_DELETE",
+				"bucketId": "some-bucket",
+				"objectId": "a/b/c.txt",
+				"objectGeneration": "1234"
+			},
+			"data": "",
+			"messageId": "1"
+		},
+		"subscription": "projects/p/subscriptions/s"
+	}`
+
+	n, err := ParseNotification([]byte(body))
+
+	require.NoError(t, err)
+	assert.Equal(t, &Notification{EventType: ObjectDelete, BucketName: "some-bucket", ObjectName: "a/b/c.txt", Generation: 1234}, n)
+}
+
+func TestParseNotification_PubSubMessage(t *testing.T) {
+	body := `{"attributes":{"eventType":"OBJECT_FINALIZE","bucketId":"some-bucket","objectId":"a/b/c.txt"}}`
+
+	n, err := ParseNotification([]byte(body))
+
+	require.NoError(t, err)
+	assert.Equal(t, &Notification{EventType: ObjectFinalize, BucketName: "some-bucket", ObjectName: "a/b/c.txt"}, n)
+}
+
+func TestParseNotification_PubSubMessageWithOnlyData(t *testing.T) {
+	data := base64.StdEncoding.EncodeToString([]byte(objectResourceJSON))
+	body := fmt.Sprintf(`{"attributes":{"eventType":"OBJECT_ARCHIVE"},"data":%q}`, data)
+
+	n, err := ParseNotification([]byte(body))
+
+	require.NoError(t, err)
+	assert.Equal(t, &Notification{EventType: ObjectArchive, BucketName: "some-bucket", ObjectName: "a/b/c.txt", Generation: 1234}, n)
+}
+
+func TestParseNotification_ObjectResource(t *testing.T) {
+	n, err := ParseNotification([]byte(objectResourceJSON))
+
+	require.NoError(t, err)
+	assert.Equal(t, &Notification{EventType: ObjectMetadataUpdate, BucketName: "some-bucket", ObjectName: "a/b/c.txt", Generation: 1234}, n)
+}
+
+func TestParseNotification_Invalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		body string
+	}{
+		{"NotJSON", `not json`},
+		{"PushRequestWithoutMessage", `{"message":null}`},
+		{"MissingObject", `{"attributes":{"eventType":"OBJECT_DELETE","bucketId":"some-bucket"}}`},
+		{"InvalidGeneration", `{"attributes":{"bucketId":"b","objectId":"o","objectGeneration":"x"}}`},
+		{"InvalidData", `{"attributes":{},"data":"!!!"}`},
+		{"EmptyObjectResource", `{}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseNotification([]byte(tc.body))
+
+			assert.Error(t, err)
+		})
+	}
+}
@@ -16,11 +16,13 @@ package lru
 
 import (
 	"container/list"
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
 
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/locker"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor"
 )
 
 // Predefined error messages returned by the Cache.
@@ -29,6 +31,7 @@ const (
 	InvalidEntryErrorMsg           = "nil values are not supported"
 	InvalidUpdateEntrySizeErrorMsg = "size of entry to be updated is not same as existing size"
 	EntryNotExistErrMsg            = "entry with given key does not exist"
+	InvalidMaxSizeErrorMsg         = "maxSize of the cache should be greater than zero"
 )
 
 // Cache is a LRU cache for any lru.ValueType indexed by string keys.
@@ -38,13 +41,17 @@ type Cache struct {
 	// Constant data
 	/////////////////////////
 
-	// INVARIANT: maxSize > 0
-	maxSize uint64
+	// Name under which the size and evictions of the cache are reported in the
+	// metrics, e.g. stat_cache. Nothing is reported for an empty name.
+	name string
 
 	/////////////////////////
 	// Mutable state
 	/////////////////////////
 
+	// INVARIANT: maxSize > 0
+	maxSize uint64
+
 	// Sum of entry.Value.Size() of all the entries in the cache.
 	currentSize uint64
 
@@ -77,7 +84,14 @@ type entry struct {
 // NewCache returns the reference of cache object by initialising the cache with
 // the supplied maxSize, which must be greater than zero.
 func NewCache(maxSize uint64) *Cache {
+	return NewNamedCache("", maxSize)
+}
+
+// NewNamedCache is like NewCache, but reports the size of the cache and the
+// number of evicted entries in the metrics under the supplied name.
+func NewNamedCache(name string, maxSize uint64) *Cache {
 	c := &Cache{
+		name:    name,
 		maxSize: maxSize,
 		index:   make(map[string]*list.Element),
 	}
@@ -137,6 +151,17 @@ func (c *Cache) evictOne() ValueType {
 	return evictedEntry
 }
 
+// captureMetrics reports the change of the size of the cache since it was
+// prevSize, along with the number of entries evicted meanwhile.
+//
+// Requires LOCK(c.mu)
+func (c *Cache) captureMetrics(prevSize uint64, evictions int) {
+	if c.name == "" {
+		return
+	}
+	monitor.CaptureLRUCacheMetrics(context.Background(), c.name, int64(c.currentSize)-int64(prevSize), int64(evictions))
+}
+
 ////////////////////////////////////////////////////////////////////////
 // Cache interface
 ////////////////////////////////////////////////////////////////////////
@@ -158,6 +183,7 @@ func (c *Cache) Insert(
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	prevSize := c.currentSize
 
 	e, ok := c.index[key]
 	if ok {
@@ -178,6 +204,7 @@ func (c *Cache) Insert(
 	for c.currentSize > c.maxSize {
 		evictedValues = append(evictedValues, c.evictOne())
 	}
+	c.captureMetrics(prevSize, len(evictedValues))
 
 	return evictedValues, nil
 }
@@ -193,7 +220,9 @@ func (c *Cache) Erase(key string) (value ValueType) {
 	}
 
 	deletedEntry := e.Value.(entry).Value
+	prevSize := c.currentSize
 	c.currentSize -= deletedEntry.Size()
+	c.captureMetrics(prevSize, 0)
 
 	delete(c.index, key)
 	c.entries.Remove(e)
@@ -267,3 +296,63 @@ func (c *Cache) UpdateWithoutChangingOrder(
 
 	return nil
 }
+
+// UpdateMaxSize changes the maximum size of the cache to the supplied value,
+// which must be greater than zero. If the cache currently holds more than the
+// new maxSize, least recently used entries are evicted until it fits, and the
+// evicted values are returned.
+func (c *Cache) UpdateMaxSize(maxSize uint64) ([]ValueType, error) {
+	if maxSize == 0 {
+		return nil, errors.New(InvalidMaxSizeErrorMsg)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxSize = maxSize
+	prevSize := c.currentSize
+
+	var evictedValues []ValueType
+	for c.currentSize > c.maxSize {
+		evictedValues = append(evictedValues, c.evictOne())
+	}
+	c.captureMetrics(prevSize, len(evictedValues))
+
+	return evictedValues, nil
+}
+
+// MaxSize returns the current maximum size of the cache.
+func (c *Cache) MaxSize() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.maxSize
+}
+
+// CurrentSize returns the sum of sizes of all the entries in the cache.
+func (c *Cache) CurrentSize() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.currentSize
+}
+
+// Len returns the number of entries in the cache.
+func (c *Cache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return len(c.index)
+}
+
+// ForEach calls f with the key and value of each entry, from the most to the
+// least recently used, without changing the order of entries in the cache.
+// f must not call the methods of the cache.
+func (c *Cache) ForEach(f func(key string, value ValueType)) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for e := c.entries.Front(); e != nil; e = e.Next() {
+		f(e.Value.(entry).Key, e.Value.(entry).Value)
+	}
+}
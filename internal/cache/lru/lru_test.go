@@ -23,7 +23,12 @@ import (
 
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/lru"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/locker"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor/monitortest"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor/tags"
+	. "github.com/jacobsa/oglematchers"
 	. "github.com/jacobsa/ogletest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCache(t *testing.T) { RunTests(t) }
@@ -231,6 +236,22 @@ func (t *CacheTest) TestUpdateNotChangeOrder() {
 	t.insertAndAssert(key3, data3, []int64{7}, nil)
 }
 
+func (t *CacheTest) TestForEach() {
+	t.insertAndAssert("burrito1", testData{Value: 1, DataSize: 10}, []int64{}, nil)
+	t.insertAndAssert("burrito2", testData{Value: 2, DataSize: 10}, []int64{}, nil)
+	t.cache.LookUp("burrito1")
+
+	var keys []string
+	var values []int64
+	t.cache.ForEach(func(key string, value lru.ValueType) {
+		keys = append(keys, key)
+		values = append(values, value.(testData).Value)
+	})
+
+	ExpectThat(keys, ElementsAre("burrito1", "burrito2"))
+	ExpectThat(values, ElementsAre(1, 2))
+}
+
 func (t *CacheTest) TestLookUpWithoutChangingOrder_WhenKeyPresent() {
 	key := "burrito"
 	data := testData{Value: 23, DataSize: 4}
@@ -267,6 +288,46 @@ func (t *CacheTest) TestLookUpWithoutChangingOrder_NotChangeOrder() {
 	t.insertAndAssert(key3, data3, []int64{23}, nil)
 }
 
+func (t *CacheTest) TestUpdateMaxSize_Shrink() {
+	t.insertAndAssert("burrito1", testData{Value: 23, DataSize: 10}, []int64{}, nil)
+	t.insertAndAssert("burrito2", testData{Value: 2, DataSize: 20}, []int64{}, nil)
+	t.insertAndAssert("burrito3", testData{Value: 3, DataSize: 15}, []int64{}, nil)
+
+	evicted, err := t.cache.UpdateMaxSize(20)
+
+	AssertEq(nil, err)
+	AssertEq(2, len(evicted))
+	ExpectEq(23, evicted[0].(testData).Value)
+	ExpectEq(2, evicted[1].(testData).Value)
+	ExpectEq(20, t.cache.MaxSize())
+	ExpectEq(15, t.cache.CurrentSize())
+	ExpectEq(3, t.cache.LookUp("burrito3").(testData).Value)
+}
+
+func (t *CacheTest) TestUpdateMaxSize_Grow() {
+	t.insertAndAssert("burrito1", testData{Value: 23, DataSize: 40}, []int64{}, nil)
+
+	evicted, err := t.cache.UpdateMaxSize(100)
+
+	AssertEq(nil, err)
+	ExpectEq(0, len(evicted))
+	ExpectEq(100, t.cache.MaxSize())
+	// An entry bigger than the old maxSize can be inserted now.
+	t.insertAndAssert("burrito2", testData{Value: 2, DataSize: 60}, []int64{}, nil)
+	ExpectEq(100, t.cache.CurrentSize())
+}
+
+func (t *CacheTest) TestUpdateMaxSize_Zero() {
+	t.insertAndAssert("burrito1", testData{Value: 23, DataSize: 40}, []int64{}, nil)
+
+	evicted, err := t.cache.UpdateMaxSize(0)
+
+	AssertNe(nil, err)
+	ExpectTrue(strings.Contains(err.Error(), lru.InvalidMaxSizeErrorMsg))
+	ExpectEq(0, len(evicted))
+	ExpectEq(MaxSize, t.cache.MaxSize())
+}
+
 // This will detect race if we run the test with `-race` flag.
 // We get the race condition failure if we remove lock from Insert or Erase method.
 func (t *CacheTest) TestRaceCondition() {
@@ -318,3 +379,22 @@ func (t *CacheTest) TestRaceCondition() {
 
 	wg.Wait()
 }
+
+func TestNamedCacheMetrics(t *testing.T) {
+	attr := tags.CacheName.String("lru_test_cache")
+	size := monitortest.Int64(t, "lru_cache/size", attr)
+	evictions := monitortest.Int64(t, "lru_cache/eviction_count", attr)
+	cache := lru.NewNamedCache("lru_test_cache", 10)
+
+	_, err := cache.Insert("a", testData{Value: 1, DataSize: 4})
+	require.NoError(t, err)
+	_, err = cache.Insert("b", testData{Value: 2, DataSize: 4})
+	require.NoError(t, err)
+	// Inserting c evicts a.
+	_, err = cache.Insert("c", testData{Value: 3, DataSize: 4})
+	require.NoError(t, err)
+	cache.Erase("b")
+
+	assert.Equal(t, size+4, monitortest.Int64(t, "lru_cache/size", attr))
+	assert.Equal(t, evictions+1, monitortest.Int64(t, "lru_cache/eviction_count", attr))
+}
@@ -0,0 +1,58 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/lru"
+)
+
+// EntryInfo describes an entry of a stat or type cache, for troubleshooting.
+type EntryInfo struct {
+	// Name is the key of the entry: the object name, prefixed with the bucket
+	// name in a stat cache shared by several buckets, or the child name in a
+	// type cache.
+	Name string
+
+	// Value describes the cached value, e.g. "generation 12" or "directory".
+	Value string
+
+	Expiration time.Time
+}
+
+var typeNames = map[Type]string{
+	UnknownType:     "unknown",
+	SymlinkType:     "symlink",
+	RegularFileType: "file",
+	ExplicitDirType: "directory",
+	ImplicitDirType: "implicit directory",
+	NonexistentType: "nonexistent",
+}
+
+// StatCacheEntries returns the entries of the LRU cache backing the stat
+// caches, from the most to the least recently used.
+func StatCacheEntries(sc *lru.Cache) (entries []EntryInfo) {
+	sc.ForEach(func(key string, value lru.ValueType) {
+		e := value.(entry)
+		info := EntryInfo{Name: key, Value: "negative", Expiration: e.expiration}
+		if e.m != nil {
+			info.Value = fmt.Sprintf("generation %d, metageneration %d", e.m.Generation, e.m.MetaGeneration)
+		}
+		entries = append(entries, info)
+	})
+	return
+}
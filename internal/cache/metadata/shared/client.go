@@ -0,0 +1,534 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"maps"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/lru"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/metadata"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/util"
+)
+
+const (
+	// DefaultCallTimeout bounds the time spent on a single cache operation,
+	// including connecting to the server.
+	DefaultCallTimeout = 500 * time.Millisecond
+
+	// maxIdleConns is the number of connections kept open for reuse.
+	maxIdleConns = 16
+
+	// DefaultRetryInterval is how long the server isn't called after a call
+	// failed or timed out, so that an unavailable or slow server doesn't delay
+	// every lookup.
+	DefaultRetryInterval = time.Second
+
+	// The sizes of the caches of the entries of the mount kept in process, in
+	// front of the server.
+	localStatCacheMaxSizeMB = 32
+	localTypeCacheMaxSizeMB = 8
+
+	// How long the entries fetched from the server are kept in process. The
+	// server doesn't return their expiration, so this bounds how much longer
+	// than on the server they may be used.
+	localFillTTL = 5 * time.Second
+
+	// The number of updates waiting to be sent to the server, beyond which
+	// insertions are dropped, and erasures are sent synchronously.
+	maxQueuedUpdates = 1024
+)
+
+var errUnavailable = errors.New("not retrying yet after a failure")
+
+// Client talks to a Server listening on a unix socket, on behalf of one
+// mount. It is safe for concurrent use.
+//
+// The caches handed out by a Client never fail: if the server can't be
+// reached, lookups miss and updates are dropped, so that the mount falls back
+// to going to GCS.
+//
+// The entries of the mount are also cached in process, where lookups are
+// served first, without waiting for the server. Updates are applied in
+// process at once, and sent to the server in the background, in order.
+type Client struct {
+	socketPath    string
+	onlyDir       string
+	callTimeout   time.Duration
+	retryInterval time.Duration
+
+	localStats *lru.Cache
+	localTypes *lru.Cache
+
+	// The updates waiting to be sent to the server, and the number of those
+	// not sent yet.
+	updates  chan *request
+	inFlight sync.WaitGroup
+	stop     chan struct{}
+
+	mu sync.Mutex
+
+	// Whether Close was called, after which updates are dropped.
+	//
+	// GUARDED_BY(mu)
+	closed bool
+
+	// GUARDED_BY(mu)
+	idle []*clientConn
+
+	// Whether the last call failed. Used to log only the first of a series of
+	// failures.
+	//
+	// GUARDED_BY(mu)
+	failing bool
+
+	// While failing, the server isn't called until then.
+	//
+	// GUARDED_BY(mu)
+	retryAt time.Time
+
+	// The number of updates of each entry waiting to be sent to the server,
+	// which isn't asked about the entry meanwhile, as its answer may be stale.
+	//
+	// GUARDED_BY(mu)
+	pending map[string]int
+}
+
+type clientConn struct {
+	net.Conn
+	enc *gob.Encoder
+	dec *gob.Decoder
+}
+
+// NewClient creates a client for the server listening on socketPath. onlyDir
+// is the directory of the bucket being mounted, if any; entries are only
+// shared between mounts of the same directory.
+func NewClient(socketPath string, onlyDir string) *Client {
+	c := &Client{
+		socketPath:    socketPath,
+		onlyDir:       strings.Trim(onlyDir, "/"),
+		callTimeout:   DefaultCallTimeout,
+		retryInterval: DefaultRetryInterval,
+		localStats:    lru.NewCache(util.MiBsToBytes(localStatCacheMaxSizeMB)),
+		localTypes:    lru.NewCache(util.MiBsToBytes(localTypeCacheMaxSizeMB)),
+		updates:       make(chan *request, maxQueuedUpdates),
+		stop:          make(chan struct{}),
+		pending:       make(map[string]int),
+	}
+	go c.sendUpdates()
+	return c
+}
+
+// NewStatCache returns a stat cache for the given bucket, backed by the
+// server.
+func (c *Client) NewStatCache(bucketName string) metadata.StatCache {
+	namespace := c.namespace(bucketName)
+	return &statCache{
+		client:    c,
+		namespace: namespace,
+		local:     metadata.NewStatCacheBucketView(c.localStats, namespace),
+	}
+}
+
+// NewTypeCache returns a type cache for the children of the given directory
+// (e.g. "" or "a/b/") in the given bucket, backed by the server.
+func (c *Client) NewTypeCache(bucketName string, dirName string, ttl time.Duration) metadata.TypeCache {
+	namespace := c.namespace(bucketName) + "/" + dirName
+	return &typeCache{
+		client:    c,
+		namespace: namespace,
+		ttl:       ttl,
+		local:     metadata.NewTypeCacheView(c.localTypes, ttl, namespace),
+		localFill: metadata.NewTypeCacheView(c.localTypes, min(ttl, localFillTTL), namespace),
+	}
+}
+
+// Close sends the updates waiting to be sent to the server, stops sending
+// those made afterwards, and closes the idle connections to the server.
+//
+// LOCKS_EXCLUDED(c.mu)
+func (c *Client) Close() {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	c.mu.Unlock()
+
+	// No update is added once closed, so this doesn't race with update. The
+	// calls of an unavailable server fail at once, so this doesn't wait long.
+	c.flush()
+	close(c.stop)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, conn := range c.idle {
+		conn.Close()
+	}
+	c.idle = nil
+}
+
+// The keys of the entries a request is about, in the pending map.
+func pendingKeys(req *request) []string {
+	switch req.Op {
+	case opTypeInsertMultiple:
+		keys := make([]string, 0, len(req.Types))
+		for name := range req.Types {
+			keys = append(keys, "t"+req.Namespace+"\x00"+name)
+		}
+		return keys
+	case opTypeInsert, opTypeErase, opTypeGet:
+		return []string{"t" + req.Namespace + "\x00" + req.Name}
+	}
+	return []string{"s" + req.Namespace + "\x00" + req.Name}
+}
+
+// LOCKS_EXCLUDED(c.mu)
+func (c *Client) isPending(req *request) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pending[pendingKeys(req)[0]] > 0
+}
+
+// LOCKS_EXCLUDED(c.mu)
+func (c *Client) donePending(req *request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range pendingKeys(req) {
+		if c.pending[key]--; c.pending[key] <= 0 {
+			delete(c.pending, key)
+		}
+	}
+}
+
+// update sends an update to the server in the background. If too many are
+// waiting, an insertion is dropped, and an erasure is sent right away, so that
+// the server doesn't keep serving the erased entry. Once closed, the updates
+// are dropped.
+//
+// LOCKS_EXCLUDED(c.mu)
+func (c *Client) update(req *request) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	for _, key := range pendingKeys(req) {
+		c.pending[key]++
+	}
+	c.inFlight.Add(1)
+	c.mu.Unlock()
+
+	select {
+	case c.updates <- req:
+		return
+	default:
+	}
+
+	if req.Op == opStatErase || req.Op == opTypeErase {
+		c.callIgnoringResponse(req)
+	}
+	c.donePending(req)
+	c.inFlight.Done()
+}
+
+func (c *Client) sendUpdates() {
+	for {
+		select {
+		case <-c.stop:
+			return
+		case req := <-c.updates:
+			c.callIgnoringResponse(req)
+			c.donePending(req)
+			c.inFlight.Done()
+		}
+	}
+}
+
+// flush waits for the updates sent so far to be done.
+func (c *Client) flush() {
+	c.inFlight.Wait()
+}
+
+// namespace returns the namespace of the entries of the given bucket. As '#'
+// isn't allowed in bucket names, different (bucket, onlyDir) pairs never map
+// to the same namespace.
+func (c *Client) namespace(bucketName string) string {
+	return bucketName + "#" + c.onlyDir
+}
+
+func (c *Client) getConn() (*clientConn, error) {
+	c.mu.Lock()
+	if n := len(c.idle); n > 0 {
+		conn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return conn, nil
+	}
+	c.mu.Unlock()
+
+	nc, err := net.DialTimeout("unix", c.socketPath, c.callTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &clientConn{
+		Conn: nc,
+		enc:  gob.NewEncoder(nc),
+		dec:  gob.NewDecoder(nc),
+	}, nil
+}
+
+func (c *Client) putConn(conn *clientConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.idle) >= maxIdleConns {
+		conn.Close()
+		return
+	}
+	c.idle = append(c.idle, conn)
+}
+
+func (c *Client) call(req *request) (resp *response, err error) {
+	c.mu.Lock()
+	if c.failing && time.Now().Before(c.retryAt) {
+		c.mu.Unlock()
+		return nil, errUnavailable
+	}
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if err != nil && !c.failing {
+			logger.Warnf("Shared metadata cache at %s is unavailable, falling back to GCS: %v", c.socketPath, err)
+		} else if err == nil && c.failing {
+			logger.Infof("Shared metadata cache at %s is available again", c.socketPath)
+		}
+		c.failing = err != nil
+		if c.failing {
+			c.retryAt = time.Now().Add(c.retryInterval)
+		}
+	}()
+
+	conn, err := c.getConn()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = conn.SetDeadline(time.Now().Add(c.callTimeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	resp = &response{}
+	if err = conn.enc.Encode(req); err == nil {
+		err = conn.dec.Decode(resp)
+	}
+	if err != nil {
+		// The stream may be out of sync, so the connection can't be reused.
+		conn.Close()
+		return nil, err
+	}
+	c.putConn(conn)
+
+	if resp.Err != "" {
+		return nil, errors.New(resp.Err)
+	}
+	return resp, nil
+}
+
+// callIgnoringResponse makes a call whose only effect is on the server.
+func (c *Client) callIgnoringResponse(req *request) {
+	if _, err := c.call(req); err != nil {
+		logger.Tracef("Shared metadata cache: op %d on %q failed: %v", req.Op, req.Name, err)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////
+// Stat cache
+////////////////////////////////////////////////////////////////////////
+
+type statCache struct {
+	client    *Client
+	namespace string
+	local     metadata.StatCache
+}
+
+func (sc *statCache) Insert(m *gcs.MinObject, expiration time.Time) {
+	sc.local.Insert(m, expiration)
+	sc.client.update(&request{
+		Op:         opStatInsert,
+		Namespace:  sc.namespace,
+		Name:       m.Name,
+		Object:     m,
+		Expiration: expiration,
+	})
+}
+
+func (sc *statCache) AddNegativeEntry(name string, expiration time.Time) {
+	sc.local.AddNegativeEntry(name, expiration)
+	sc.client.update(&request{
+		Op:         opStatAddNegativeEntry,
+		Namespace:  sc.namespace,
+		Name:       name,
+		Expiration: expiration,
+	})
+}
+
+func (sc *statCache) Erase(name string) {
+	sc.local.Erase(name)
+	sc.client.update(&request{
+		Op:        opStatErase,
+		Namespace: sc.namespace,
+		Name:      name,
+	})
+}
+
+func (sc *statCache) LookUp(name string, now time.Time) (hit bool, m *gcs.MinObject) {
+	if hit, m = sc.local.LookUp(name, now); hit {
+		return
+	}
+
+	req := &request{
+		Op:        opStatLookUp,
+		Namespace: sc.namespace,
+		Name:      name,
+		Now:       now,
+	}
+	if sc.client.isPending(req) {
+		return
+	}
+
+	resp, err := sc.client.call(req)
+	if err != nil {
+		logger.Tracef("Shared metadata cache: stat lookup of %q failed: %v", name, err)
+		return
+	}
+
+	if resp.Hit {
+		if resp.Object != nil {
+			sc.local.Insert(resp.Object, now.Add(localFillTTL))
+		} else {
+			sc.local.AddNegativeEntry(name, now.Add(localFillTTL))
+		}
+	}
+	return resp.Hit, resp.Object
+}
+
+////////////////////////////////////////////////////////////////////////
+// Type cache
+////////////////////////////////////////////////////////////////////////
+
+type typeCache struct {
+	client    *Client
+	namespace string
+	ttl       time.Duration
+
+	// Views of the in-process cache, for the entries of the mount and for
+	// those fetched from the server.
+	local     metadata.TypeCache
+	localFill metadata.TypeCache
+}
+
+func (tc *typeCache) Insert(now time.Time, name string, it metadata.Type) {
+	tc.local.Insert(now, name, it)
+	tc.client.update(&request{
+		Op:        opTypeInsert,
+		Namespace: tc.namespace,
+		Name:      name,
+		Now:       now,
+		TTL:       tc.ttl,
+		Type:      it,
+	})
+}
+
+// InsertMultiple sends all the entries to the server in a single request.
+func (tc *typeCache) InsertMultiple(now time.Time, types map[string]metadata.Type) {
+	if len(types) == 0 {
+		return
+	}
+
+	tc.local.InsertMultiple(now, types)
+	tc.client.update(&request{
+		Op:        opTypeInsertMultiple,
+		Namespace: tc.namespace,
+		Now:       now,
+		TTL:       tc.ttl,
+		Types:     maps.Clone(types),
+	})
+}
+
+func (tc *typeCache) Erase(name string) {
+	tc.local.Erase(name)
+	tc.client.update(&request{
+		Op:        opTypeErase,
+		Namespace: tc.namespace,
+		Name:      name,
+	})
+}
+
+// Entries returns nothing, as the entries are kept by the shared metadata
+// cache daemon.
+func (tc *typeCache) Entries() []metadata.EntryInfo {
+	return nil
+}
+
+// Len returns zero, as the entries are kept by the shared metadata cache
+// daemon.
+func (tc *typeCache) Len() int {
+	return 0
+}
+
+func (tc *typeCache) Get(now time.Time, name string) metadata.Type {
+	if it := tc.local.Get(now, name); it != metadata.UnknownType {
+		monitor.CaptureTypeCacheLookupMetrics(context.Background(), true)
+		return it
+	}
+
+	req := &request{
+		Op:        opTypeGet,
+		Namespace: tc.namespace,
+		Name:      name,
+		Now:       now,
+	}
+	if tc.client.isPending(req) {
+		return metadata.UnknownType
+	}
+
+	resp, err := tc.client.call(req)
+	if err != nil {
+		logger.Tracef("Shared metadata cache: type lookup of %q failed: %v", name, err)
+		return metadata.UnknownType
+	}
+
+	if resp.Type != metadata.UnknownType {
+		tc.localFill.Insert(now, name, resp.Type)
+	}
+	monitor.CaptureTypeCacheLookupMetrics(context.Background(), resp.Type != metadata.UnknownType)
+	return resp.Type
+}
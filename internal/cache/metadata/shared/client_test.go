@@ -0,0 +1,295 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"net"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/metadata"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+const ttl = time.Minute
+
+type ClientTest struct {
+	suite.Suite
+	socketPath string
+	server     *Server
+	serveErr   chan error
+	now        time.Time
+}
+
+func TestClientSuite(t *testing.T) {
+	suite.Run(t, new(ClientTest))
+}
+
+func (t *ClientTest) SetupTest() {
+	// Unix socket paths are limited in length, so don't use t.T().TempDir().
+	dir, err := os.MkdirTemp("", "shared_cache")
+	require.NoError(t.T(), err)
+	t.T().Cleanup(func() { os.RemoveAll(dir) })
+	t.socketPath = path.Join(dir, "sock")
+	t.startServer()
+	t.now = time.Now()
+}
+
+func (t *ClientTest) startServer() {
+	var err error
+	t.server, err = NewServer(1, 1)
+	require.NoError(t.T(), err)
+	l, err := net.Listen("unix", t.socketPath)
+	require.NoError(t.T(), err)
+	t.serveErr = make(chan error, 1)
+	go func() { t.serveErr <- t.server.Serve(l) }()
+}
+
+func (t *ClientTest) TearDownTest() {
+	t.server.Close()
+	assert.ErrorIs(t.T(), <-t.serveErr, net.ErrClosed)
+}
+
+func (t *ClientTest) TestStatCacheIsSharedBetweenClients() {
+	client1 := NewClient(t.socketPath, "")
+	defer client1.Close()
+	client2 := NewClient(t.socketPath, "")
+	defer client2.Close()
+	o := &gcs.MinObject{
+		Name:       "a/b",
+		Size:       10,
+		Generation: 7,
+		Metadata:   map[string]string{"k": "v"},
+	}
+
+	client1.NewStatCache("bucket").Insert(o, t.now.Add(ttl))
+	client1.flush()
+	hit, m := client2.NewStatCache("bucket").LookUp("a/b", t.now)
+
+	assert.True(t.T(), hit)
+	assert.Equal(t.T(), o, m)
+}
+
+func (t *ClientTest) TestCloseSendsQueuedUpdates() {
+	client1 := NewClient(t.socketPath, "")
+	client2 := NewClient(t.socketPath, "")
+	defer client2.Close()
+	o := &gcs.MinObject{Name: "a", Generation: 7}
+
+	client1.NewStatCache("bucket").Insert(o, t.now.Add(ttl))
+	client1.Close()
+	client1.NewStatCache("bucket").Insert(&gcs.MinObject{Name: "b"}, t.now.Add(ttl))
+	hit, m := client2.NewStatCache("bucket").LookUp("a", t.now)
+	hitAfterClose, _ := client2.NewStatCache("bucket").LookUp("b", t.now)
+
+	assert.True(t.T(), hit)
+	assert.Equal(t.T(), o, m)
+	assert.False(t.T(), hitAfterClose)
+}
+
+func (t *ClientTest) TestStatCacheNegativeEntry() {
+	client := NewClient(t.socketPath, "")
+	defer client.Close()
+	sc := client.NewStatCache("bucket")
+
+	sc.AddNegativeEntry("a", t.now.Add(ttl))
+	hit, m := sc.LookUp("a", t.now)
+
+	assert.True(t.T(), hit)
+	assert.Nil(t.T(), m)
+}
+
+func (t *ClientTest) TestStatCacheEraseAndExpiry() {
+	client := NewClient(t.socketPath, "")
+	defer client.Close()
+	sc := client.NewStatCache("bucket")
+	sc.Insert(&gcs.MinObject{Name: "a", Generation: 1}, t.now.Add(ttl))
+	sc.Insert(&gcs.MinObject{Name: "b", Generation: 1}, t.now.Add(ttl))
+
+	sc.Erase("a")
+
+	hit, _ := sc.LookUp("a", t.now)
+	assert.False(t.T(), hit)
+	hit, _ = sc.LookUp("b", t.now)
+	assert.True(t.T(), hit)
+	hit, _ = sc.LookUp("b", t.now.Add(ttl+time.Second))
+	assert.False(t.T(), hit)
+}
+
+func (t *ClientTest) TestStatCacheNamespaces() {
+	client := NewClient(t.socketPath, "")
+	defer client.Close()
+	onlyDirClient := NewClient(t.socketPath, "dir/")
+	defer onlyDirClient.Close()
+	client.NewStatCache("bucket").Insert(&gcs.MinObject{Name: "a", Generation: 1}, t.now.Add(ttl))
+
+	hit, _ := client.NewStatCache("other").LookUp("a", t.now)
+	assert.False(t.T(), hit)
+	hit, _ = onlyDirClient.NewStatCache("bucket").LookUp("a", t.now)
+	assert.False(t.T(), hit)
+}
+
+func (t *ClientTest) TestTypeCacheIsSharedBetweenClients() {
+	client1 := NewClient(t.socketPath, "")
+	defer client1.Close()
+	client2 := NewClient(t.socketPath, "")
+	defer client2.Close()
+
+	client1.NewTypeCache("bucket", "a/", ttl).Insert(t.now, "b", metadata.ExplicitDirType)
+	client1.flush()
+
+	assert.Equal(t.T(), metadata.ExplicitDirType, client2.NewTypeCache("bucket", "a/", ttl).Get(t.now, "b"))
+	assert.Equal(t.T(), metadata.UnknownType, client2.NewTypeCache("bucket", "", ttl).Get(t.now, "b"))
+	assert.Equal(t.T(), metadata.UnknownType, client2.NewTypeCache("bucket", "a/", ttl).Get(t.now.Add(ttl+time.Second), "b"))
+}
+
+func (t *ClientTest) TestTypeCacheInsertMultipleIsSharedBetweenClients() {
+	client1 := NewClient(t.socketPath, "")
+	defer client1.Close()
+	client2 := NewClient(t.socketPath, "")
+	defer client2.Close()
+
+	client1.NewTypeCache("bucket", "a/", ttl).InsertMultiple(t.now, map[string]metadata.Type{
+		"b": metadata.ExplicitDirType,
+		"c": metadata.RegularFileType,
+	})
+	client1.flush()
+
+	tc := client2.NewTypeCache("bucket", "a/", ttl)
+	assert.Equal(t.T(), metadata.ExplicitDirType, tc.Get(t.now, "b"))
+	assert.Equal(t.T(), metadata.RegularFileType, tc.Get(t.now, "c"))
+	assert.Empty(t.T(), client1.pending)
+}
+
+func (t *ClientTest) TestTypeCacheErase() {
+	client := NewClient(t.socketPath, "")
+	defer client.Close()
+	tc := client.NewTypeCache("bucket", "", ttl)
+	tc.Insert(t.now, "a", metadata.RegularFileType)
+
+	tc.Erase("a")
+
+	assert.Equal(t.T(), metadata.UnknownType, tc.Get(t.now, "a"))
+}
+
+func (t *ClientTest) TestServerUnavailable() {
+	client := NewClient(path.Join(path.Dir(t.socketPath), "missing"), "")
+	defer client.Close()
+	sc := client.NewStatCache("bucket")
+	tc := client.NewTypeCache("bucket", "", ttl)
+
+	sc.Insert(&gcs.MinObject{Name: "a"}, t.now.Add(ttl))
+	tc.Insert(t.now, "a", metadata.RegularFileType)
+	client.flush()
+
+	// The entries of the mount are still served in process.
+	hit, _ := sc.LookUp("a", t.now)
+	assert.True(t.T(), hit)
+	assert.Equal(t.T(), metadata.RegularFileType, tc.Get(t.now, "a"))
+	hit, _ = sc.LookUp("b", t.now)
+	assert.False(t.T(), hit)
+	assert.Equal(t.T(), metadata.UnknownType, tc.Get(t.now, "b"))
+}
+
+func (t *ClientTest) TestEntriesFromServerAreKeptInProcess() {
+	client1 := NewClient(t.socketPath, "")
+	defer client1.Close()
+	client2 := NewClient(t.socketPath, "")
+	defer client2.Close()
+	client1.NewStatCache("bucket").Insert(&gcs.MinObject{Name: "a", Generation: 1}, t.now.Add(ttl))
+	client1.NewTypeCache("bucket", "", ttl).Insert(t.now, "a", metadata.RegularFileType)
+	client1.flush()
+	sc := client2.NewStatCache("bucket")
+	tc := client2.NewTypeCache("bucket", "", ttl)
+	hit, _ := sc.LookUp("a", t.now)
+	require.True(t.T(), hit)
+	require.Equal(t.T(), metadata.RegularFileType, tc.Get(t.now, "a"))
+
+	t.server.Close()
+	assert.ErrorIs(t.T(), <-t.serveErr, net.ErrClosed)
+	t.startServer()
+
+	hit, _ = sc.LookUp("a", t.now)
+	assert.True(t.T(), hit)
+	assert.Equal(t.T(), metadata.RegularFileType, tc.Get(t.now, "a"))
+	// But not for longer than localFillTTL.
+	hit, _ = sc.LookUp("a", t.now.Add(localFillTTL+time.Second))
+	assert.False(t.T(), hit)
+}
+
+func (t *ClientTest) TestSlowServerIsNotCalledUntilRetryInterval() {
+	// A server accepting connections, but never answering.
+	l, err := net.Listen("unix", t.socketPath+".slow")
+	require.NoError(t.T(), err)
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer c.Close()
+		}
+	}()
+	client := NewClient(t.socketPath+".slow", "")
+	defer client.Close()
+	client.callTimeout = 50 * time.Millisecond
+	sc := client.NewStatCache("bucket")
+
+	start := time.Now()
+	hit, _ := sc.LookUp("a", t.now)
+	assert.False(t.T(), hit)
+	assert.GreaterOrEqual(t.T(), time.Since(start), client.callTimeout)
+
+	start = time.Now()
+	hit, _ = sc.LookUp("b", t.now)
+	assert.False(t.T(), hit)
+	assert.Less(t.T(), time.Since(start), client.callTimeout)
+}
+
+func (t *ClientTest) TestClientRecoversAfterServerRestart() {
+	client := NewClient(t.socketPath, "")
+	defer client.Close()
+	client.retryInterval = 0
+	other := NewClient(t.socketPath, "")
+	defer other.Close()
+	sc := client.NewStatCache("bucket")
+	// Leave an idle connection to the server.
+	hit, _ := sc.LookUp("a", t.now)
+	require.False(t.T(), hit)
+	// Restart the server, which drops the idle connection of the client.
+	t.server.Close()
+	assert.ErrorIs(t.T(), <-t.serveErr, net.ErrClosed)
+	t.startServer()
+	other.NewStatCache("bucket").Insert(&gcs.MinObject{Name: "a", Generation: 1}, t.now.Add(ttl))
+	other.flush()
+
+	// The first call fails on the stale connection.
+	hit, _ = sc.LookUp("a", t.now)
+	assert.False(t.T(), hit)
+	hit, _ = sc.LookUp("a", t.now)
+	assert.True(t.T(), hit)
+}
+
+func TestNewServer_ZeroSize(t *testing.T) {
+	_, err := NewServer(0, 1)
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,81 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package shared provides a host-local metadata cache, which lets several
+// gcsfuse mounts of the same bucket on a host share stat and type cache
+// entries.
+//
+// The cache lives in a daemon (see Server) listening on a unix socket. Mounts
+// talk to it through a Client, which hands out implementations of
+// metadata.StatCache and metadata.TypeCache. Requests and responses are gob
+// encoded, one after the other, on long-lived connections.
+package shared
+
+import (
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/metadata"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+)
+
+type op uint8
+
+const (
+	opStatInsert op = iota + 1
+	opStatAddNegativeEntry
+	opStatErase
+	opStatLookUp
+	opTypeInsert
+	opTypeErase
+	opTypeGet
+	opTypeInsertMultiple
+)
+
+// request is sent by the client for every cache operation. Only the fields
+// relevant to Op are set.
+type request struct {
+	Op op
+
+	// Namespace keeps the entries of different buckets (and, for type caches,
+	// directories) apart.
+	Namespace string
+	Name      string
+
+	// For stat-cache operations.
+	Object     *gcs.MinObject
+	Expiration time.Time
+
+	// Now is the time of the operation, according to the client's cache clock.
+	Now time.Time
+
+	// For type-cache operations.
+	TTL  time.Duration
+	Type metadata.Type
+
+	// For opTypeInsertMultiple, the types by name.
+	Types map[string]metadata.Type
+}
+
+// response is sent by the server for every request.
+type response struct {
+	// For opStatLookUp.
+	Hit    bool
+	Object *gcs.MinObject
+
+	// For opTypeGet.
+	Type metadata.Type
+
+	// Err is set if the request couldn't be served.
+	Err string
+}
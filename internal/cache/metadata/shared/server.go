@@ -0,0 +1,186 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/lru"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/metadata"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/util"
+)
+
+// Server serves a stat cache and a type cache to the clients connecting to
+// it. Must be created with NewServer.
+type Server struct {
+	mu sync.Mutex
+
+	// GUARDED_BY(mu)
+	statCache *lru.Cache
+
+	// GUARDED_BY(mu)
+	typeCache *lru.Cache
+
+	// GUARDED_BY(mu)
+	listeners map[net.Listener]struct{}
+
+	// GUARDED_BY(mu)
+	conns map[net.Conn]struct{}
+
+	// GUARDED_BY(mu)
+	closed bool
+
+	wg sync.WaitGroup
+}
+
+// NewServer creates a server whose stat and type caches are limited to the
+// given sizes. Both sizes must be non-zero.
+func NewServer(statCacheMaxSizeMB, typeCacheMaxSizeMB uint64) (*Server, error) {
+	if statCacheMaxSizeMB == 0 || typeCacheMaxSizeMB == 0 {
+		return nil, fmt.Errorf("cache sizes must be non-zero, got stat-cache: %d MiB, type-cache: %d MiB", statCacheMaxSizeMB, typeCacheMaxSizeMB)
+	}
+
+	return &Server{
+		statCache: lru.NewNamedCache("shared_stat_cache", util.MiBsToBytes(statCacheMaxSizeMB)),
+		typeCache: lru.NewNamedCache("shared_type_cache", util.MiBsToBytes(typeCacheMaxSizeMB)),
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]struct{}),
+	}, nil
+}
+
+// Serve accepts connections on l and serves them until the server is closed.
+// It always returns a non-nil error; after Close, the error is net.ErrClosed.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return net.ErrClosed
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return net.ErrClosed
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			c.Close()
+			return net.ErrClosed
+		}
+		s.conns[c] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.serveConn(c)
+	}
+}
+
+// Close stops the listeners, closes all connections and waits for them to be
+// done.
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *Server) serveConn(c net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		c.Close()
+		s.wg.Done()
+	}()
+
+	dec := gob.NewDecoder(c)
+	enc := gob.NewEncoder(c)
+	for {
+		// Decode into a fresh value every time, as gob leaves the fields missing
+		// from the stream untouched.
+		var req request
+		if err := dec.Decode(&req); err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				logger.Warnf("Shared metadata cache: while reading request: %v", err)
+			}
+			return
+		}
+
+		resp := s.handle(&req)
+		if err := enc.Encode(resp); err != nil {
+			logger.Warnf("Shared metadata cache: while writing response: %v", err)
+			return
+		}
+	}
+}
+
+func (s *Server) handle(req *request) (resp *response) {
+	resp = &response{}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch req.Op {
+	case opStatInsert:
+		if req.Object == nil {
+			resp.Err = "missing object"
+			return
+		}
+		metadata.NewStatCacheBucketView(s.statCache, req.Namespace).Insert(req.Object, req.Expiration)
+	case opStatAddNegativeEntry:
+		metadata.NewStatCacheBucketView(s.statCache, req.Namespace).AddNegativeEntry(req.Name, req.Expiration)
+	case opStatErase:
+		metadata.NewStatCacheBucketView(s.statCache, req.Namespace).Erase(req.Name)
+	case opStatLookUp:
+		resp.Hit, resp.Object = metadata.NewStatCacheBucketView(s.statCache, req.Namespace).LookUp(req.Name, req.Now)
+	case opTypeInsert:
+		metadata.NewTypeCacheView(s.typeCache, req.TTL, req.Namespace).Insert(req.Now, req.Name, req.Type)
+	case opTypeInsertMultiple:
+		metadata.NewTypeCacheView(s.typeCache, req.TTL, req.Namespace).InsertMultiple(req.Now, req.Types)
+	case opTypeErase:
+		// The TTL doesn't matter for erasing, but must be non-zero for the view
+		// to be enabled.
+		metadata.NewTypeCacheView(s.typeCache, 1, req.Namespace).Erase(req.Name)
+	case opTypeGet:
+		resp.Type = metadata.NewTypeCacheView(s.typeCache, 1, req.Namespace).Get(req.Now, req.Name)
+	default:
+		resp.Err = fmt.Sprintf("unknown operation %d", req.Op)
+	}
+
+	return
+}
@@ -106,6 +106,11 @@ func (e entry) Size() (size uint64) {
 	return
 }
 
+// Return the size (rss) of a positive stat-cache entry for the given object.
+func SizeOfStatCacheEntry(m *gcs.MinObject) uint64 {
+	return entry{m: m, key: m.Name}.Size()
+}
+
 // Should the supplied object for a new positive entry replace the given
 // existing entry?
 func shouldReplace(m *gcs.MinObject, existing entry) bool {
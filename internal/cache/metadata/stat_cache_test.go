@@ -22,6 +22,7 @@ import (
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/metadata"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/mount"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	. "github.com/jacobsa/oglematchers"
 	. "github.com/jacobsa/ogletest"
 )
 
@@ -99,6 +100,7 @@ type StatCacheTest struct {
 }
 
 type MultiBucketStatCacheTest struct {
+	sharedCache      *lru.Cache
 	multiBucketCache testMultiBucketCacheHelper
 }
 
@@ -116,6 +118,7 @@ func (t *StatCacheTest) SetUp(ti *TestInfo) {
 
 func (t *MultiBucketStatCacheTest) SetUp(ti *TestInfo) {
 	sharedCache := lru.NewCache(uint64((mount.AverageSizeOfPositiveStatCacheEntry + mount.AverageSizeOfNegativeStatCacheEntry) * capacity))
+	t.sharedCache = sharedCache
 	t.multiBucketCache.fruits = testHelperCache{wrapped: metadata.NewStatCacheBucketView(sharedCache, "fruits")}
 	t.multiBucketCache.spices = testHelperCache{wrapped: metadata.NewStatCacheBucketView(sharedCache, "spices")}
 }
@@ -407,3 +410,14 @@ func (t *MultiBucketStatCacheTest) ExpiresLeastRecentlyUsed() {
 	ExpectEq(cardamom, spices.LookUpOrNil("cardamom", someTime))
 	ExpectEq(saffron, spices.LookUpOrNil("saffron", someTime))
 }
+
+func (t *MultiBucketStatCacheTest) Entries() {
+	cache := &t.multiBucketCache
+	cache.fruits.Insert(&gcs.MinObject{Name: "apple", Generation: 3, MetaGeneration: 1}, expiration)
+	cache.spices.AddNegativeEntry("saffron", expiration)
+
+	ExpectThat(metadata.StatCacheEntries(t.sharedCache), DeepEquals([]metadata.EntryInfo{
+		{Name: "spices/saffron", Value: "negative", Expiration: expiration},
+		{Name: "fruits/apple", Value: "generation 3, metageneration 1", Expiration: expiration},
+	}))
+}
@@ -15,11 +15,14 @@
 package metadata
 
 import (
+	"context"
 	"fmt"
 	"math"
+	"strings"
 	"time"
 
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/lru"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/util"
 )
 
@@ -51,6 +54,9 @@ type TypeCache interface {
 	// Insert inserts the given entry (name -> type)
 	// with the entry-expiration at now+ttl.
 	Insert(now time.Time, name string, it Type)
+	// InsertMultiple inserts the given entries (name -> type) at once, as
+	// Insert does.
+	InsertMultiple(now time.Time, types map[string]Type)
 	// Erase removes the entry with the given name.
 	Erase(name string)
 	// Get returns the entry with given name, and also
@@ -60,6 +66,11 @@ type TypeCache interface {
 	// If entry doesn't exist in the cache, then
 	// UnknownType is returned.
 	Get(now time.Time, name string) Type
+	// Entries returns the entries of the cache, from the most to the least
+	// recently used, without changing their order.
+	Entries() []EntryInfo
+	// Len returns the number of entries of the cache.
+	Len() int
 }
 
 type cacheEntry struct {
@@ -111,7 +122,8 @@ func SizeOfTypeCacheEntry(key string) uint64 {
 //   - We have recorded that N is both a file and a directory.
 //
 // Must be created with NewTypeCache. May be contained in a larger struct.
-// External synchronization is required.
+// External synchronization is required, except for Entries and Len, as the
+// entries are kept in an lru.Cache.
 type typeCache struct {
 	/////////////////////////
 	// Constant data
@@ -127,6 +139,10 @@ type typeCache struct {
 	// INVARIANT: entries.CheckInvariants() does not panic
 	// INVARIANT: Each value is of type cacheEntry
 	entries *lru.Cache
+
+	// keyPrefix is prepended to the names to make them unique among all the
+	// typeCache objects sharing entries. It is empty for a private cache.
+	keyPrefix string
 }
 
 // NewTypeCache creates an LRU-policy-based cache with given parameters.
@@ -142,18 +158,37 @@ func NewTypeCache(maxSizeMB int, ttl time.Duration) TypeCache {
 		}
 		return &typeCache{
 			ttl:     ttl,
-			entries: lru.NewCache(lruSizeInBytesToUse),
+			entries: lru.NewNamedCache("type_cache", lruSizeInBytesToUse),
+		}
+	}
+	return &typeCache{}
+}
+
+// NewTypeCacheView creates a TypeCache which shares the given LRU cache with
+// other views. Names are prefixed with keyPrefix, which must be unique among
+// the views of the same LRU cache. If TTL is zero, nothing is ever cached.
+func NewTypeCacheView(sharedCache *lru.Cache, ttl time.Duration, keyPrefix string) TypeCache {
+	if ttl > 0 && sharedCache != nil {
+		return &typeCache{
+			ttl:       ttl,
+			entries:   sharedCache,
+			keyPrefix: keyPrefix,
 		}
 	}
 	return &typeCache{}
 }
 
+func (tc *typeCache) key(name string) string {
+	return tc.keyPrefix + name
+}
+
 func (tc *typeCache) Insert(now time.Time, name string, it Type) {
 	if tc.entries != nil { // only if caching is enabled
-		_, err := tc.entries.Insert(name, cacheEntry{
+		key := tc.key(name)
+		_, err := tc.entries.Insert(key, cacheEntry{
 			expiry:    now.Add(tc.ttl),
 			inodeType: it,
-			key:       name,
+			key:       key,
 		})
 		if err != nil {
 			panic(fmt.Errorf("failed to insert entry in typeCache: %v", err))
@@ -161,9 +196,15 @@ func (tc *typeCache) Insert(now time.Time, name string, it Type) {
 	}
 }
 
+func (tc *typeCache) InsertMultiple(now time.Time, types map[string]Type) {
+	for name, it := range types {
+		tc.Insert(now, name, it)
+	}
+}
+
 func (tc *typeCache) Erase(name string) {
 	if tc.entries != nil { // only if caching is enabled
-		tc.entries.Erase(name)
+		tc.entries.Erase(tc.key(name))
 	}
 }
 
@@ -172,16 +213,56 @@ func (tc *typeCache) Get(now time.Time, name string) Type {
 		return UnknownType
 	}
 
-	val := tc.entries.LookUp(name)
+	key := tc.key(name)
+	val := tc.entries.LookUp(key)
 	if val == nil {
+		monitor.CaptureTypeCacheLookupMetrics(context.Background(), false)
 		return UnknownType
 	}
 
 	entry := val.(cacheEntry)
 	// Has the entry expired?
 	if entry.expiry.Before(now) {
-		tc.entries.Erase(name)
+		tc.entries.Erase(key)
+		monitor.CaptureTypeCacheLookupMetrics(context.Background(), false)
 		return UnknownType
 	}
+	monitor.CaptureTypeCacheLookupMetrics(context.Background(), true)
 	return entry.inodeType
 }
+
+func (tc *typeCache) Entries() (entries []EntryInfo) {
+	if tc.entries == nil {
+		return
+	}
+
+	tc.entries.ForEach(func(key string, value lru.ValueType) {
+		if !strings.HasPrefix(key, tc.keyPrefix) {
+			return
+		}
+		ce := value.(cacheEntry)
+		entries = append(entries, EntryInfo{
+			Name:       strings.TrimPrefix(key, tc.keyPrefix),
+			Value:      typeNames[ce.inodeType],
+			Expiration: ce.expiry,
+		})
+	})
+	return
+}
+
+func (tc *typeCache) Len() (n int) {
+	if tc.entries == nil {
+		return
+	}
+	if tc.keyPrefix == "" {
+		return tc.entries.Len()
+	}
+
+	// The entries are shared with other views.
+	tc.entries.ForEach(func(key string, _ lru.ValueType) {
+		if strings.HasPrefix(key, tc.keyPrefix) {
+			n++
+		}
+	})
+	return
+}
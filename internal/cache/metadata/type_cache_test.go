@@ -19,7 +19,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/lru"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/util"
+	. "github.com/jacobsa/oglematchers"
 	. "github.com/jacobsa/ogletest"
 )
 
@@ -144,6 +146,14 @@ func (t *TypeCacheTest) TestGetOverwrittenEntry() {
 	ExpectEq(ExplicitDirType, t.cache.Get(beforeExpiration, "abcd"))
 }
 
+func (t *TypeCacheTest) TestGetMultipleInsertedEntries() {
+	t.cache.InsertMultiple(now, map[string]Type{"abcd": RegularFileType, "efgh": ExplicitDirType})
+
+	ExpectEq(RegularFileType, t.cache.Get(beforeExpiration, "abcd"))
+	ExpectEq(ExplicitDirType, t.cache.Get(beforeExpiration, "efgh"))
+	ExpectEq(UnknownType, t.cache.Get(afterExpiration, "efgh"))
+}
+
 func (t *TypeCacheTest) TestGetBeforeTtlExpiration() {
 	t.cache.Insert(now, "abcd", RegularFileType)
 
@@ -193,6 +203,25 @@ func (t *TypeCacheTest) TestGetReinsertedEntry() {
 	ExpectEq(ExplicitDirType, t.cache.Get(beforeExpiration2, "abcd"))
 }
 
+func (t *TypeCacheTest) TestEntries() {
+	t.cache.Insert(now, "abcd", RegularFileType)
+	t.cache.Insert(now2, "efgh", ExplicitDirType)
+
+	ExpectThat(t.cache.Entries(), DeepEquals([]EntryInfo{
+		{Name: "efgh", Value: "directory", Expiration: expiration2},
+		{Name: "abcd", Value: "file", Expiration: expiration},
+	}))
+}
+
+func (t *TypeCacheTest) TestLen() {
+	ExpectEq(0, t.cache.Len())
+
+	t.cache.Insert(now, "abcd", RegularFileType)
+	t.cache.Insert(now2, "efgh", ExplicitDirType)
+
+	ExpectEq(2, t.cache.Len())
+}
+
 ////////////////////////////////////////////////////////////////////////
 // Tests for TypeCache created with size=0 - ZeroSizeTypeCacheTest
 ////////////////////////////////////////////////////////////////////////
@@ -201,6 +230,12 @@ func (t *ZeroSizeTypeCacheTest) TestGetFromEmptyTypeCache() {
 	ExpectEq(UnknownType, t.cache.Get(now, "abc"))
 }
 
+func (t *ZeroSizeTypeCacheTest) TestEntries() {
+	t.cache.Insert(now, "abcd", RegularFileType)
+
+	ExpectEq(0, len(t.cache.Entries()))
+}
+
 func (t *ZeroSizeTypeCacheTest) TestGetInsertedEntry() {
 	t.cache.Insert(now, "abcd", RegularFileType)
 
@@ -220,3 +255,66 @@ func (t *ZeroTtlTypeCacheTest) TestGetInsertedEntry() {
 
 	ExpectEq(UnknownType, t.cache.Get(beforeExpiration, "abcd"))
 }
+
+////////////////////////////////////////////////////////////////////////
+// Tests for TypeCache views sharing an LRU cache
+////////////////////////////////////////////////////////////////////////
+
+type TypeCacheViewTest struct {
+}
+
+func init() { RegisterTestSuite(&TypeCacheViewTest{}) }
+
+func (t *TypeCacheViewTest) TestViewsAreIsolated() {
+	sharedCache := lru.NewCache(util.MiBsToBytes(TypeCacheMaxSizeMB))
+	view1 := NewTypeCacheView(sharedCache, TTL, "bucket/a/")
+	view2 := NewTypeCacheView(sharedCache, TTL, "bucket/b/")
+
+	view1.Insert(now, "abcd", RegularFileType)
+	view2.Insert(now, "abcd", ExplicitDirType)
+
+	ExpectEq(RegularFileType, view1.Get(beforeExpiration, "abcd"))
+	ExpectEq(ExplicitDirType, view2.Get(beforeExpiration, "abcd"))
+	ExpectEq(RegularFileType, NewTypeCacheView(sharedCache, TTL, "bucket/a/").Get(beforeExpiration, "abcd"))
+
+	view1.Erase("abcd")
+
+	ExpectEq(UnknownType, view1.Get(beforeExpiration, "abcd"))
+	ExpectEq(ExplicitDirType, view2.Get(beforeExpiration, "abcd"))
+	ExpectEq(UnknownType, view2.Get(afterExpiration, "abcd"))
+}
+
+func (t *TypeCacheViewTest) TestEntries() {
+	sharedCache := lru.NewCache(util.MiBsToBytes(TypeCacheMaxSizeMB))
+	view1 := NewTypeCacheView(sharedCache, TTL, "bucket/a/")
+	view2 := NewTypeCacheView(sharedCache, TTL, "bucket/b/")
+
+	view1.Insert(now, "abcd", RegularFileType)
+	view2.Insert(now, "efgh", ImplicitDirType)
+
+	ExpectThat(view1.Entries(), DeepEquals([]EntryInfo{{Name: "abcd", Value: "file", Expiration: expiration}}))
+	ExpectThat(view2.Entries(), DeepEquals([]EntryInfo{{Name: "efgh", Value: "implicit directory", Expiration: expiration}}))
+}
+
+func (t *TypeCacheViewTest) TestLen() {
+	sharedCache := lru.NewCache(util.MiBsToBytes(TypeCacheMaxSizeMB))
+	view1 := NewTypeCacheView(sharedCache, TTL, "bucket/a/")
+	view2 := NewTypeCacheView(sharedCache, TTL, "bucket/b/")
+
+	view1.Insert(now, "abcd", RegularFileType)
+	view1.Insert(now, "efgh", ExplicitDirType)
+	view2.Insert(now, "ijkl", ImplicitDirType)
+
+	ExpectEq(2, view1.Len())
+	ExpectEq(1, view2.Len())
+	ExpectEq(3, NewTypeCacheView(sharedCache, TTL, "").Len())
+}
+
+func (t *TypeCacheViewTest) TestZeroTtl() {
+	sharedCache := lru.NewCache(util.MiBsToBytes(TypeCacheMaxSizeMB))
+	view := NewTypeCacheView(sharedCache, 0, "")
+
+	view.Insert(now, "abcd", RegularFileType)
+
+	ExpectEq(UnknownType, view.Get(beforeExpiration, "abcd"))
+}
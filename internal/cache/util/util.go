@@ -20,6 +20,7 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"syscall"
 
 	"github.com/jacobsa/fuse/fsutil"
 
@@ -128,3 +129,26 @@ func CreateCacheDirectoryIfNotPresentAt(dirPath string, dirPerm os.FileMode) err
 
 	return nil
 }
+
+// DiskUsage describes the capacity of the filesystem holding a path.
+type DiskUsage struct {
+	// TotalBytes is the size of the filesystem.
+	TotalBytes uint64
+
+	// AvailableBytes is the space available to unprivileged users.
+	AvailableBytes uint64
+}
+
+// GetDiskUsage samples, using statfs, the capacity and available space of the
+// filesystem holding the given path.
+func GetDiskUsage(path string) (DiskUsage, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return DiskUsage{}, fmt.Errorf("error in statfs of %s: %w", path, err)
+	}
+
+	return DiskUsage{
+		TotalBytes:     uint64(stat.Blocks) * uint64(stat.Bsize),
+		AvailableBytes: uint64(stat.Bavail) * uint64(stat.Bsize),
+	}, nil
+}
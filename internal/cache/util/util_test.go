@@ -298,3 +298,20 @@ func Test_CreateCacheDirectoryIfNotPresentAt_ShouldReturnErrorWhenDirectoryDoesN
 	AssertNe(nil, err)
 	AssertTrue(strings.Contains(err.Error(), "error creating file at directory ("+dirPath+")"))
 }
+
+func Test_GetDiskUsage(t *testing.T) {
+	usage, err := GetDiskUsage(os.TempDir())
+
+	AssertEq(nil, err)
+	AssertLt(0, usage.TotalBytes)
+	AssertLe(usage.AvailableBytes, usage.TotalBytes)
+}
+
+func Test_GetDiskUsage_PathNotExist(t *testing.T) {
+	dirPath := path.Join(os.TempDir(), string(testutil.GenerateRandomBytes(4)), "not-exist")
+
+	_, err := GetDiskUsage(dirPath)
+
+	AssertNe(nil, err)
+	AssertTrue(strings.Contains(err.Error(), "error in statfs of "+dirPath))
+}
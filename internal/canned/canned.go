@@ -18,12 +18,19 @@
 package canned
 
 import (
+	"bytes"
+	"fmt"
+	"io/fs"
 	"log"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/fake"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/faults"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
 	"golang.org/x/net/context"
+	"gopkg.in/yaml.v3"
 
 	"github.com/jacobsa/timeutil"
 )
@@ -82,3 +89,135 @@ func MakeFakeBucket(ctx context.Context) (b gcs.Bucket) {
 
 	return
 }
+
+// Manifest describes the contents of a fake bucket, and the faults injected in
+// the calls to it. It is read from YAML or JSON.
+type Manifest struct {
+	// A directory whose tree is copied to the bucket: its files as objects,
+	// and its subdirectories as objects whose name ends with a slash. A relative
+	// path is relative to the directory of the manifest.
+	Dir string `yaml:"dir"`
+
+	// Objects created in the bucket, after the ones of Dir.
+	Objects []ManifestObject `yaml:"objects"`
+
+	// Latency and errors injected in the calls to the bucket.
+	Faults []faults.Rule `yaml:"faults"`
+
+	// The seed of the random choice of the calls to fail, to reproduce a run.
+	// Zero means a random seed.
+	RandomSeed int64 `yaml:"random-seed"`
+}
+
+// ManifestObject is an object of a Manifest.
+type ManifestObject struct {
+	Name        string            `yaml:"name"`
+	Contents    string            `yaml:"contents"`
+	ContentType string            `yaml:"content-type"`
+	Metadata    map[string]string `yaml:"metadata"`
+}
+
+// LoadFakeBucket creates a fake bucket named FakeBucketName with the contents
+// described by the manifest at the given path, or with the tree of the
+// directory at the given path.
+func LoadFakeBucket(ctx context.Context, p string) (b gcs.Bucket, err error) {
+	fi, err := os.Stat(p)
+	if err != nil {
+		return
+	}
+
+	var m Manifest
+	if fi.IsDir() {
+		m.Dir = p
+	} else {
+		var buf []byte
+		buf, err = os.ReadFile(p)
+		if err != nil {
+			return
+		}
+
+		decoder := yaml.NewDecoder(bytes.NewReader(buf))
+		decoder.KnownFields(true)
+		if err = decoder.Decode(&m); err != nil {
+			err = fmt.Errorf("invalid manifest %s: %w", p, err)
+			return
+		}
+
+		if m.Dir != "" && !filepath.IsAbs(m.Dir) {
+			m.Dir = filepath.Join(filepath.Dir(p), m.Dir)
+		}
+	}
+
+	if err = faults.ValidateRules(m.Faults); err != nil {
+		err = fmt.Errorf("invalid manifest %s: %w", p, err)
+		return
+	}
+
+	b = fake.NewFakeBucket(timeutil.RealClock(), FakeBucketName)
+	if m.Dir != "" {
+		if err = copyTree(ctx, b, m.Dir); err != nil {
+			return
+		}
+	}
+
+	for _, o := range m.Objects {
+		_, err = b.CreateObject(
+			ctx,
+			&gcs.CreateObjectRequest{
+				Name:        o.Name,
+				Contents:    strings.NewReader(o.Contents),
+				ContentType: o.ContentType,
+				Metadata:    o.Metadata,
+			})
+
+		if err != nil {
+			err = fmt.Errorf("CreateObject %q: %w", o.Name, err)
+			return
+		}
+	}
+
+	if len(m.Faults) > 0 {
+		b = faults.NewBucket(m.Faults, m.RandomSeed, b)
+	}
+
+	return
+}
+
+// Create an object in the bucket for each file and subdirectory of the given
+// directory.
+func copyTree(ctx context.Context, b gcs.Bucket, dir string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == dir {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		req := &gcs.CreateObjectRequest{Name: filepath.ToSlash(rel)}
+
+		switch {
+		case d.IsDir():
+			req.Name += "/"
+			req.Contents = strings.NewReader("")
+
+		case d.Type().IsRegular():
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			req.Contents = f
+
+		default:
+			return nil
+		}
+
+		if _, err := b.CreateObject(ctx, req); err != nil {
+			return fmt.Errorf("CreateObject %q: %w", req.Name, err)
+		}
+
+		return nil
+	})
+}
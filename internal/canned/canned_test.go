@@ -0,0 +1,116 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package canned
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/api/googleapi"
+)
+
+func names(t *testing.T, b gcs.Bucket) (names []string) {
+	listing, err := b.ListObjects(context.Background(), &gcs.ListObjectsRequest{})
+	require.NoError(t, err)
+	for _, o := range listing.Objects {
+		names = append(names, o.Name)
+	}
+	return
+}
+
+func read(t *testing.T, b gcs.Bucket, name string) string {
+	rc, err := b.NewReader(context.Background(), &gcs.ReadObjectRequest{Name: name})
+	require.NoError(t, err)
+	defer rc.Close()
+	buf, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	return string(buf)
+}
+
+func writeFile(t *testing.T, p string, contents string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+	require.NoError(t, os.WriteFile(p, []byte(contents), 0644))
+}
+
+func TestLoadFakeBucketFromDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "foo"), "taco")
+	writeFile(t, filepath.Join(dir, "bar", "baz"), "burrito")
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "empty"), 0755))
+
+	b, err := LoadFakeBucket(context.Background(), dir)
+
+	require.NoError(t, err)
+	assert.Equal(t, FakeBucketName, b.Name())
+	assert.Equal(t, []string{"bar/", "bar/baz", "empty/", "foo"}, names(t, b))
+	assert.Equal(t, "burrito", read(t, b, "bar/baz"))
+}
+
+func TestLoadFakeBucketFromManifest(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tree", "foo"), "taco")
+	manifest := filepath.Join(dir, "manifest.yaml")
+	writeFile(t, manifest, `
+dir: tree
+objects:
+  - name: bar/qux
+    contents: enchilada
+    content-type: text/plain
+    metadata:
+      k: v
+faults:
+  - method: StatObject
+    error-code: 500
+random-seed: 1
+`)
+
+	b, err := LoadFakeBucket(context.Background(), manifest)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bar/qux", "foo"}, names(t, b))
+	assert.Equal(t, "enchilada", read(t, b, "bar/qux"))
+	_, _, err = b.StatObject(context.Background(), &gcs.StatObjectRequest{Name: "foo"})
+	var apiErr *googleapi.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, 500, apiErr.Code)
+}
+
+func TestLoadFakeBucketFromJSONManifest(t *testing.T) {
+	manifest := filepath.Join(t.TempDir(), "manifest.json")
+	writeFile(t, manifest, `{"objects": [{"name": "foo", "contents": "taco"}], "faults": [{"method": "NewReader", "latency": "1ms"}]}`)
+
+	b, err := LoadFakeBucket(context.Background(), manifest)
+
+	require.NoError(t, err)
+	assert.Equal(t, "taco", read(t, b, "foo"))
+}
+
+func TestLoadFakeBucketWithInvalidManifest(t *testing.T) {
+	manifest := filepath.Join(t.TempDir(), "manifest.yaml")
+
+	writeFile(t, manifest, "object: []\n")
+	_, err := LoadFakeBucket(context.Background(), manifest)
+	assert.ErrorContains(t, err, "field object not found")
+
+	writeFile(t, manifest, "faults: [{method: Stat, error-code: 500}]\n")
+	_, err = LoadFakeBucket(context.Background(), manifest)
+	assert.ErrorContains(t, err, "unknown method \"Stat\"")
+}
@@ -58,7 +58,36 @@ const (
 
 	DefaultKernelListCacheTtlSeconds int64 = 0
 
+	DefaultListingCacheTtlSeconds int64 = 0
+
 	DefaultEnableCrcCheck = true
+
+	// DefaultFreeDiskCheckIntervalSecs is the default interval at which the
+	// free space of the filesystem holding cache-dir is sampled, when
+	// file-cache:min-free-disk-percent is set.
+	DefaultFreeDiskCheckIntervalSecs int64 = 5
+
+	// Default retry policy values, used for the fields not set in the retry
+	// config of a class of requests.
+	DefaultRetryInitialBackoffMs int64   = 100
+	DefaultRetryMaxBackoffMs     int64   = 30000
+	DefaultRetryMultiplier       float64 = 2
+
+	// Default retry budget values.
+	DefaultRetryBudgetRatio            float64 = 0.1
+	DefaultRetryBudgetMinRetriesPerSec int64   = 10
+
+	// Default hedging values.
+	DefaultHedgingPercentile    float64 = 95
+	DefaultHedgingMinDelayMs    int64   = 10
+	DefaultHedgingMaxReadSizeKB int64   = 1024
+
+	// Default read stall detection values.
+	DefaultStallMinThroughputKBPerSec int64 = 64
+	DefaultStallMaxReopens            int   = 5
+
+	// Default dynamic mount values.
+	DefaultBucketListTtlSecs int64 = 60
 )
 
 type WriteConfig struct {
@@ -70,6 +99,18 @@ type LogConfig struct {
 	Format          string          `yaml:"format"`
 	FilePath        string          `yaml:"file-path"`
 	LogRotateConfig LogRotateConfig `yaml:"log-rotate"`
+
+	// AuditFilePath is the path of the file to which the operations changing
+	// files and directories are recorded, one JSON document per line. It is
+	// rotated according to LogRotateConfig. Nothing is recorded if empty.
+	AuditFilePath string `yaml:"audit-file-path"`
+
+	// DebugFuse and DebugGCS write the TRACE messages about the FUSE ops and
+	// the GCS requests respectively, whatever the severity. Unlike the rest of
+	// the config, they and Severity are applied again when gcsfuse receives
+	// SIGHUP.
+	DebugFuse bool `yaml:"debug-fuse"`
+	DebugGCS  bool `yaml:"debug-gcs"`
 }
 
 type ListConfig struct {
@@ -83,6 +124,12 @@ type ListConfig struct {
 	EnableEmptyManagedFolders bool `yaml:"enable-empty-managed-folders"`
 
 	KernelListCacheTtlSeconds int64 `yaml:"kernel-list-cache-ttl-secs"`
+
+	// ListingCacheTtlSeconds is the time for which gcsfuse serves the listing
+	// of a directory from memory instead of listing GCS again. Listings older
+	// than half of it are refreshed in the background when they are served.
+	// It can be set to -1 for no-ttl and 0 to disable the cache.
+	ListingCacheTtlSeconds int64 `yaml:"listing-cache-ttl-secs"`
 }
 
 type GrpcClientConfig struct {
@@ -109,6 +156,16 @@ type FileCacheConfig struct {
 	MaxSizeMB             int64 `yaml:"max-size-mb"`
 	CacheFileForRangeRead bool  `yaml:"cache-file-for-range-read"`
 	EnableCrcCheck        bool  `yaml:"enable-crc-check"`
+
+	// MinFreeDiskPercent, when non-zero, sizes the file cache dynamically so
+	// that at least this percentage of the filesystem holding cache-dir is kept
+	// free. MaxSizeMB still acts as the upper bound on the cache size.
+	MinFreeDiskPercent float64 `yaml:"min-free-disk-percent"`
+
+	// FreeDiskCheckIntervalSecs is the interval at which the free space of the
+	// filesystem holding cache-dir is sampled. It is used only when
+	// MinFreeDiskPercent is non-zero.
+	FreeDiskCheckIntervalSecs int64 `yaml:"free-disk-check-interval-secs"`
 }
 
 type MetadataCacheConfig struct {
@@ -129,19 +186,142 @@ type MetadataCacheConfig struct {
 	// It can also be set to -1 for no-size-limit, 0 for
 	// no cache. Values below -1 are not supported.
 	StatCacheMaxSizeMB int64 `yaml:"stat-cache-max-size-mb,omitempty"`
+
+	// SharedCacheSocket is the path of the unix socket of a host-local
+	// metadata cache daemon. If set, stat-cache and type-cache entries are
+	// kept in the daemon, and shared with the other mounts using it, instead
+	// of in this process.
+	SharedCacheSocket string `yaml:"shared-cache-socket,omitempty"`
+}
+
+// CacheInvalidationConfig configures the sources of GCS object change
+// notifications, which are used to erase stat, type and file cache entries
+// of objects changed outside the mount, so that long metadata-cache TTLs can
+// be used for buckets that are also written by other systems.
+type CacheInvalidationConfig struct {
+	// NotificationListenAddress is a loopback address (host:port) on which
+	// Pub/Sub push deliveries of notifications are accepted. They aren't
+	// authenticated, so they must not be accepted from other hosts.
+	NotificationListenAddress string `yaml:"notification-listen-address"`
+
+	// NotificationFile is the path of a file to which notifications are
+	// appended, one JSON document per line, e.g. by a local Pub/Sub subscriber.
+	NotificationFile string `yaml:"notification-file"`
+}
+
+// RetryPolicyConfig configures how gcsfuse retries one class of GCS requests
+// that failed with a transient error, or didn't complete in time.
+type RetryPolicyConfig struct {
+	// MaxAttempts is the maximum number of attempts of a request, including the
+	// first one. The requests of a class with 0 are retried by the GCS client
+	// library instead, until they succeed or their context is cancelled.
+	MaxAttempts int `yaml:"max-attempts"`
+
+	// The wait before the n-th retry is drawn uniformly at random between 0 and
+	// InitialBackoffMs * Multiplier^(n-1), capped to MaxBackoffMs.
+	InitialBackoffMs int64   `yaml:"initial-backoff-ms"`
+	MaxBackoffMs     int64   `yaml:"max-backoff-ms"`
+	Multiplier       float64 `yaml:"multiplier"`
+
+	// AttemptTimeoutMs, if non-zero, is the deadline of each attempt: an
+	// attempt that didn't complete in time is cancelled and retried. For
+	// reads, it's the deadline to open the object.
+	AttemptTimeoutMs int64 `yaml:"attempt-timeout-ms"`
+}
+
+// RetryConfig configures the retries of the GCS requests, per class of
+// requests.
+type RetryConfig struct {
+	// Reads are the requests opening objects to read them.
+	Reads RetryPolicyConfig `yaml:"reads"`
+
+	// Metadata are the requests reading, listing, updating and deleting
+	// objects. Updates and deletions are retried only if they have a
+	// precondition, making them idempotent.
+	Metadata RetryPolicyConfig `yaml:"metadata"`
+
+	// Uploads are the requests creating objects, by writing, copying or
+	// composing them. They are retried only if they have a precondition on the
+	// generation of the object they create.
+	Uploads RetryPolicyConfig `yaml:"uploads"`
+
+	// The retries of the last 10 seconds can't exceed BudgetRatio times the
+	// requests of the last 10 seconds, plus BudgetMinRetriesPerSec retries per
+	// second, so that an outage doesn't turn into a retry storm.
+	BudgetRatio            float64 `yaml:"budget-ratio"`
+	BudgetMinRetriesPerSec int64   `yaml:"budget-min-retries-per-sec"`
+}
+
+// Enabled returns whether gcsfuse retries any class of requests itself.
+func (c *RetryConfig) Enabled() bool {
+	return c.Reads.MaxAttempts > 0 || c.Metadata.MaxAttempts > 0 || c.Uploads.MaxAttempts > 0
+}
+
+// HedgingConfig configures the hedging of the small reads and of the stats of
+// GCS objects: when a request hasn't answered after a delay, a duplicate is
+// sent, and the first to answer is used.
+type HedgingConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Percentile is the percentile of the latencies of the recent requests of
+	// the same kind after which a request is hedged, e.g. 95 to hedge about 5%
+	// of them.
+	Percentile float64 `yaml:"percentile"`
+
+	// MinDelayMs is the minimum delay before a request is hedged.
+	MinDelayMs int64 `yaml:"min-delay-ms"`
+
+	// MaxReadSizeKB is the size of the largest ranges of objects whose reads
+	// are hedged.
+	MaxReadSizeKB int64 `yaml:"max-read-size-kb"`
+}
+
+// ReadStallConfig configures the detection of the streams of GCS objects that
+// stall, which are reopened from where they stalled.
+type ReadStallConfig struct {
+	// StallTimeoutSecs is the time spent waiting for a stream after which it is
+	// stalled if it delivered less than StallMinThroughputKBPerSec per second.
+	// 0 disables the detection.
+	StallTimeoutSecs int64 `yaml:"timeout-secs"`
+
+	StallMinThroughputKBPerSec int64 `yaml:"min-throughput-kb-per-sec"`
+
+	// StallMaxReopens is the number of times a stream is reopened at most.
+	StallMaxReopens int `yaml:"max-reopens"`
+}
+
+// DynamicMountConfig configures the root of a mount of all the buckets, i.e.
+// with _ as the bucket name.
+type DynamicMountConfig struct {
+	// ListBucketsProject is the project whose buckets are listed at the root.
+	// Empty disables the listing.
+	ListBucketsProject string `yaml:"list-buckets-project"`
+
+	// BucketAllowlist and BucketRegex, if set, restrict the buckets listed and
+	// mounted to those named in the list and matching the regular expression.
+	BucketAllowlist []string `yaml:"bucket-allowlist"`
+	BucketRegex     string   `yaml:"bucket-regex"`
+
+	// BucketListTtlSecs is how long the listing of the buckets is cached for.
+	BucketListTtlSecs int64 `yaml:"bucket-list-ttl-secs"`
 }
 
 type MountConfig struct {
-	WriteConfig         `yaml:"write"`
-	LogConfig           `yaml:"logging"`
-	FileCacheConfig     `yaml:"file-cache"`
-	CacheDir            `yaml:"cache-dir"`
-	MetadataCacheConfig `yaml:"metadata-cache"`
-	ListConfig          `yaml:"list"`
-	GrpcClientConfig    `yaml:"grpc"`
-	AuthConfig          `yaml:"auth-config"`
-	EnableHNS           `yaml:"enable-hns"`
-	FileSystemConfig    `yaml:"file-system"`
+	WriteConfig             `yaml:"write"`
+	LogConfig               `yaml:"logging"`
+	FileCacheConfig         `yaml:"file-cache"`
+	CacheDir                `yaml:"cache-dir"`
+	MetadataCacheConfig     `yaml:"metadata-cache"`
+	ListConfig              `yaml:"list"`
+	GrpcClientConfig        `yaml:"grpc"`
+	AuthConfig              `yaml:"auth-config"`
+	EnableHNS               `yaml:"enable-hns"`
+	FileSystemConfig        `yaml:"file-system"`
+	CacheInvalidationConfig `yaml:"cache-invalidation"`
+	RetryConfig             `yaml:"retry"`
+	HedgingConfig           `yaml:"hedging"`
+	ReadStallConfig         `yaml:"read-stall"`
+	DynamicMountConfig      `yaml:"dynamic-mount"`
 }
 
 // LogRotateConfig defines the parameters for log rotation. It consists of three
@@ -167,6 +347,14 @@ func DefaultLogRotateConfig() LogRotateConfig {
 	}
 }
 
+func DefaultRetryPolicyConfig() RetryPolicyConfig {
+	return RetryPolicyConfig{
+		InitialBackoffMs: DefaultRetryInitialBackoffMs,
+		MaxBackoffMs:     DefaultRetryMaxBackoffMs,
+		Multiplier:       DefaultRetryMultiplier,
+	}
+}
+
 func NewMountConfig() *MountConfig {
 	mountConfig := &MountConfig{}
 	mountConfig.LogConfig = LogConfig{
@@ -176,8 +364,9 @@ func NewMountConfig() *MountConfig {
 		LogRotateConfig: DefaultLogRotateConfig(),
 	}
 	mountConfig.FileCacheConfig = FileCacheConfig{
-		MaxSizeMB:      DefaultFileCacheMaxSizeMB,
-		EnableCrcCheck: DefaultEnableCrcCheck,
+		MaxSizeMB:                 DefaultFileCacheMaxSizeMB,
+		EnableCrcCheck:            DefaultEnableCrcCheck,
+		FreeDiskCheckIntervalSecs: DefaultFreeDiskCheckIntervalSecs,
 	}
 	mountConfig.MetadataCacheConfig = MetadataCacheConfig{
 		TtlInSeconds:       TtlInSecsUnsetSentinel,
@@ -197,6 +386,26 @@ func NewMountConfig() *MountConfig {
 
 	mountConfig.ListConfig = ListConfig{
 		KernelListCacheTtlSeconds: DefaultKernelListCacheTtlSeconds,
+		ListingCacheTtlSeconds:    DefaultListingCacheTtlSeconds,
+	}
+	mountConfig.RetryConfig = RetryConfig{
+		Reads:                  DefaultRetryPolicyConfig(),
+		Metadata:               DefaultRetryPolicyConfig(),
+		Uploads:                DefaultRetryPolicyConfig(),
+		BudgetRatio:            DefaultRetryBudgetRatio,
+		BudgetMinRetriesPerSec: DefaultRetryBudgetMinRetriesPerSec,
+	}
+	mountConfig.HedgingConfig = HedgingConfig{
+		Percentile:    DefaultHedgingPercentile,
+		MinDelayMs:    DefaultHedgingMinDelayMs,
+		MaxReadSizeKB: DefaultHedgingMaxReadSizeKB,
+	}
+	mountConfig.ReadStallConfig = ReadStallConfig{
+		StallMinThroughputKBPerSec: DefaultStallMinThroughputKBPerSec,
+		StallMaxReopens:            DefaultStallMaxReopens,
+	}
+	mountConfig.DynamicMountConfig = DynamicMountConfig{
+		BucketListTtlSecs: DefaultBucketListTtlSecs,
 	}
 	return mountConfig
 }
@@ -18,7 +18,9 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"net"
 	"os"
+	"regexp"
 	"strings"
 
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/util"
@@ -37,13 +39,22 @@ const (
 
 	parseConfigFileErrMsgFormat = "error parsing config file: %v"
 
-	MetadataCacheTtlSecsInvalidValueError = "the value of ttl-secs for metadata-cache can't be less than -1"
-	MetadataCacheTtlSecsTooHighError      = "the value of ttl-secs in metadata-cache is too high to be supported. Max is 9223372036"
-	TypeCacheMaxSizeMBInvalidValueError   = "the value of type-cache-max-size-mb for metadata-cache can't be less than -1"
-	StatCacheMaxSizeMBInvalidValueError   = "the value of stat-cache-max-size-mb for metadata-cache can't be less than -1"
-	StatCacheMaxSizeMBTooHighError        = "the value of stat-cache-max-size-mb for metadata-cache is too high! Max supported: 17592186044415"
-	MaxSupportedStatCacheMaxSizeMB        = util.MaxMiBsInUint64
-	UnsupportedMetadataPrefixModeError    = "unsupported metadata-prefix-mode: \"%s\"; supported values: disabled, sync, async"
+	MetadataCacheTtlSecsInvalidValueError      = "the value of ttl-secs for metadata-cache can't be less than -1"
+	MetadataCacheTtlSecsTooHighError           = "the value of ttl-secs in metadata-cache is too high to be supported. Max is 9223372036"
+	TypeCacheMaxSizeMBInvalidValueError        = "the value of type-cache-max-size-mb for metadata-cache can't be less than -1"
+	StatCacheMaxSizeMBInvalidValueError        = "the value of stat-cache-max-size-mb for metadata-cache can't be less than -1"
+	StatCacheMaxSizeMBTooHighError             = "the value of stat-cache-max-size-mb for metadata-cache is too high! Max supported: 17592186044415"
+	MaxSupportedStatCacheMaxSizeMB             = util.MaxMiBsInUint64
+	UnsupportedMetadataPrefixModeError         = "unsupported metadata-prefix-mode: \"%s\"; supported values: disabled, sync, async"
+	MinFreeDiskPercentInvalidValueError        = "the value of min-free-disk-percent for file-cache should be in the range [0, 100)"
+	FreeDiskCheckIntervalSecsInvalidValueError = "the value of free-disk-check-interval-secs for file-cache can't be less than 1"
+	RetryBudgetInvalidValueError               = "the values of budget-ratio and budget-min-retries-per-sec can't be negative"
+	HedgingPercentileInvalidValueError         = "the value of percentile for hedging should be in the range (0, 100)"
+	HedgingNegativeValueError                  = "the values of min-delay-ms and max-read-size-kb for hedging can't be negative"
+	ReadStallInvalidValueError                 = "the values of timeout-secs and max-reopens for read-stall can't be negative, and min-throughput-kb-per-sec should be positive"
+	BucketRegexInvalidValueError               = "the value of bucket-regex for dynamic-mount should be a valid regular expression"
+	BucketListTtlSecsInvalidValueError         = "the value of bucket-list-ttl-secs for dynamic-mount can't be negative"
+	NotificationListenAddressInvalidValueError = "the value of notification-listen-address for cache-invalidation should be a loopback host:port"
 )
 
 func IsValidLogSeverity(severity LogSeverity) bool {
@@ -74,6 +85,12 @@ func (fileCacheConfig *FileCacheConfig) validate() error {
 	if fileCacheConfig.MaxSizeMB < -1 {
 		return fmt.Errorf("the value of max-size-mb for file-cache can't be less than -1")
 	}
+	if fileCacheConfig.MinFreeDiskPercent < 0 || fileCacheConfig.MinFreeDiskPercent >= 100 {
+		return fmt.Errorf(MinFreeDiskPercentInvalidValueError)
+	}
+	if fileCacheConfig.MinFreeDiskPercent > 0 && fileCacheConfig.FreeDiskCheckIntervalSecs < 1 {
+		return fmt.Errorf(FreeDiskCheckIntervalSecsInvalidValueError)
+	}
 	return nil
 }
 
@@ -113,6 +130,85 @@ func (listConfig *ListConfig) validate() error {
 	if err != nil {
 		return fmt.Errorf("invalid kernelListCacheTtlSecs: %w", err)
 	}
+	err = IsTtlInSecsValid(listConfig.ListingCacheTtlSeconds)
+	if err != nil {
+		return fmt.Errorf("invalid listingCacheTtlSecs: %w", err)
+	}
+	return nil
+}
+
+func (policy *RetryPolicyConfig) validate() error {
+	if policy.MaxAttempts < 0 {
+		return fmt.Errorf("the value of max-attempts can't be negative")
+	}
+	if policy.InitialBackoffMs < 0 || policy.MaxBackoffMs < policy.InitialBackoffMs {
+		return fmt.Errorf("the value of initial-backoff-ms can't be negative, nor more than max-backoff-ms")
+	}
+	if policy.Multiplier < 1 {
+		return fmt.Errorf("the value of multiplier can't be less than 1")
+	}
+	if policy.AttemptTimeoutMs < 0 {
+		return fmt.Errorf("the value of attempt-timeout-ms can't be negative")
+	}
+	return nil
+}
+
+func (retryConfig *RetryConfig) validate() error {
+	if err := retryConfig.Reads.validate(); err != nil {
+		return fmt.Errorf("reads: %w", err)
+	}
+	if err := retryConfig.Metadata.validate(); err != nil {
+		return fmt.Errorf("metadata: %w", err)
+	}
+	if err := retryConfig.Uploads.validate(); err != nil {
+		return fmt.Errorf("uploads: %w", err)
+	}
+	if retryConfig.BudgetRatio < 0 || retryConfig.BudgetMinRetriesPerSec < 0 {
+		return fmt.Errorf(RetryBudgetInvalidValueError)
+	}
+	return nil
+}
+
+func (hedgingConfig *HedgingConfig) validate() error {
+	if hedgingConfig.Percentile <= 0 || hedgingConfig.Percentile >= 100 {
+		return fmt.Errorf(HedgingPercentileInvalidValueError)
+	}
+	if hedgingConfig.MinDelayMs < 0 || hedgingConfig.MaxReadSizeKB < 0 {
+		return fmt.Errorf(HedgingNegativeValueError)
+	}
+	return nil
+}
+
+func (readStallConfig *ReadStallConfig) validate() error {
+	if readStallConfig.StallTimeoutSecs < 0 || readStallConfig.StallMaxReopens < 0 || readStallConfig.StallMinThroughputKBPerSec <= 0 {
+		return fmt.Errorf(ReadStallInvalidValueError)
+	}
+	return nil
+}
+
+func (dynamicMountConfig *DynamicMountConfig) validate() error {
+	if _, err := regexp.Compile(dynamicMountConfig.BucketRegex); err != nil {
+		return fmt.Errorf("%s: %w", BucketRegexInvalidValueError, err)
+	}
+	if dynamicMountConfig.BucketListTtlSecs < 0 {
+		return fmt.Errorf(BucketListTtlSecsInvalidValueError)
+	}
+	return nil
+}
+
+// The push endpoint doesn't authenticate the notifications, so it only
+// accepts them from the host.
+func (cacheInvalidationConfig *CacheInvalidationConfig) validate() error {
+	if cacheInvalidationConfig.NotificationListenAddress == "" {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(cacheInvalidationConfig.NotificationListenAddress)
+	if err != nil {
+		return fmt.Errorf("%s: %w", NotificationListenAddressInvalidValueError, err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("%s: %q isn't a loopback host", NotificationListenAddressInvalidValueError, host)
+	}
 	return nil
 }
 
@@ -169,5 +265,25 @@ func ParseConfigFile(fileName string) (mountConfig *MountConfig, err error) {
 		return mountConfig, fmt.Errorf("error parsing list config: %w", err)
 	}
 
+	if err = mountConfig.RetryConfig.validate(); err != nil {
+		return mountConfig, fmt.Errorf("error parsing retry config: %w", err)
+	}
+
+	if err = mountConfig.HedgingConfig.validate(); err != nil {
+		return mountConfig, fmt.Errorf("error parsing hedging config: %w", err)
+	}
+
+	if err = mountConfig.ReadStallConfig.validate(); err != nil {
+		return mountConfig, fmt.Errorf("error parsing read-stall config: %w", err)
+	}
+
+	if err = mountConfig.DynamicMountConfig.validate(); err != nil {
+		return mountConfig, fmt.Errorf("error parsing dynamic-mount config: %w", err)
+	}
+
+	if err = mountConfig.CacheInvalidationConfig.validate(); err != nil {
+		return mountConfig, fmt.Errorf("error parsing cache-invalidation config: %w", err)
+	}
+
 	return
 }
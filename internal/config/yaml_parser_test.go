@@ -43,12 +43,15 @@ func validateDefaultConfig(t *testing.T, mountConfig *MountConfig) {
 	assert.Equal(t, int64(-1), mountConfig.FileCacheConfig.MaxSizeMB)
 	assert.False(t, mountConfig.FileCacheConfig.CacheFileForRangeRead)
 	assert.True(t, mountConfig.FileCacheConfig.EnableCrcCheck)
+	assert.Equal(t, float64(0), mountConfig.FileCacheConfig.MinFreeDiskPercent)
+	assert.Equal(t, DefaultFreeDiskCheckIntervalSecs, mountConfig.FileCacheConfig.FreeDiskCheckIntervalSecs)
 	assert.Equal(t, 1, mountConfig.GrpcClientConfig.ConnPoolSize)
 	assert.False(t, mountConfig.AuthConfig.AnonymousAccess)
 	assert.False(t, bool(mountConfig.EnableHNS))
 	assert.False(t, mountConfig.FileSystemConfig.IgnoreInterrupts)
 	assert.False(t, mountConfig.FileSystemConfig.DisableParallelDirops)
 	assert.Equal(t, DefaultKernelListCacheTtlSeconds, mountConfig.KernelListCacheTtlSeconds)
+	assert.Equal(t, DefaultListingCacheTtlSeconds, mountConfig.ListingCacheTtlSeconds)
 }
 
 func (t *YamlParserTest) TestReadConfigFile_EmptyFileName() {
@@ -106,6 +109,9 @@ func (t *YamlParserTest) TestReadConfigFile_ValidConfig() {
 	assert.Equal(t.T(), ERROR, mountConfig.LogConfig.Severity)
 	assert.Equal(t.T(), "/tmp/logfile.json", mountConfig.LogConfig.FilePath)
 	assert.Equal(t.T(), "text", mountConfig.LogConfig.Format)
+	assert.Equal(t.T(), "/tmp/audit.json", mountConfig.LogConfig.AuditFilePath)
+	assert.False(t.T(), mountConfig.LogConfig.DebugFuse)
+	assert.True(t.T(), mountConfig.LogConfig.DebugGCS)
 
 	// log-rotate config
 	assert.Equal(t.T(), 100, mountConfig.LogConfig.LogRotateConfig.MaxFileSizeMB)
@@ -116,6 +122,7 @@ func (t *YamlParserTest) TestReadConfigFile_ValidConfig() {
 	assert.Equal(t.T(), int64(5), mountConfig.MetadataCacheConfig.TtlInSeconds)
 	assert.Equal(t.T(), 1, mountConfig.MetadataCacheConfig.TypeCacheMaxSizeMB)
 	assert.Equal(t.T(), int64(3), mountConfig.MetadataCacheConfig.StatCacheMaxSizeMB)
+	assert.Equal(t.T(), "/tmp/gcsfuse-metadata.sock", mountConfig.MetadataCacheConfig.SharedCacheSocket)
 
 	// list config
 	assert.True(t.T(), mountConfig.ListConfig.EnableEmptyManagedFolders)
@@ -130,10 +137,16 @@ func (t *YamlParserTest) TestReadConfigFile_ValidConfig() {
 	assert.True(t.T(), mountConfig.FileSystemConfig.IgnoreInterrupts)
 	assert.True(t.T(), mountConfig.FileSystemConfig.DisableParallelDirops)
 
+	// cache-invalidation
+	assert.Equal(t.T(), "localhost:8090", mountConfig.CacheInvalidationConfig.NotificationListenAddress)
+	assert.Equal(t.T(), "/tmp/notifications.json", mountConfig.CacheInvalidationConfig.NotificationFile)
+
 	// file-cache config
 	assert.Equal(t.T(), int64(100), mountConfig.FileCacheConfig.MaxSizeMB)
 	assert.True(t.T(), mountConfig.FileCacheConfig.CacheFileForRangeRead)
 	assert.False(t.T(), mountConfig.FileCacheConfig.EnableCrcCheck)
+	assert.Equal(t.T(), 10.5, mountConfig.FileCacheConfig.MinFreeDiskPercent)
+	assert.Equal(t.T(), int64(30), mountConfig.FileCacheConfig.FreeDiskCheckIntervalSecs)
 }
 
 func (t *YamlParserTest) TestReadConfigFile_InvalidLogConfig() {
@@ -160,6 +173,18 @@ func (t *YamlParserTest) TestReadConfigFile_InvalidFileCacheMaxSizeConfig() {
 	assert.ErrorContains(t.T(), err, "error parsing file-cache configs: the value of max-size-mb for file-cache can't be less than -1")
 }
 
+func (t *YamlParserTest) TestReadConfigFile_InvalidFileCacheMinFreeDiskPercentConfig() {
+	_, err := ParseConfigFile("testdata/invalid_filecache_min_free_disk_percent_config.yaml")
+
+	assert.ErrorContains(t.T(), err, MinFreeDiskPercentInvalidValueError)
+}
+
+func (t *YamlParserTest) TestReadConfigFile_InvalidFileCacheFreeDiskCheckIntervalConfig() {
+	_, err := ParseConfigFile("testdata/invalid_filecache_free_disk_check_interval_config.yaml")
+
+	assert.ErrorContains(t.T(), err, FreeDiskCheckIntervalSecsInvalidValueError)
+}
+
 func (t *YamlParserTest) TestReadConfigFile_MetatadaCacheConfig_InvalidTTL() {
 	_, err := ParseConfigFile("testdata/metadata_cache_config_invalid_ttl.yaml")
 
@@ -297,3 +322,154 @@ func (t *YamlParserTest) TestReadConfigFile_ListConfig_ValidKernelListCacheTtl()
 	assert.NotNil(t.T(), mountConfig)
 	assert.Equal(t.T(), int64(10), mountConfig.ListConfig.KernelListCacheTtlSeconds)
 }
+
+func (t *YamlParserTest) TestReadConfigFile_ListConfig_InvalidListingCacheTtl() {
+	_, err := ParseConfigFile("testdata/list_config/invalid_listing_cache_ttl.yaml")
+
+	assert.ErrorContains(t.T(), err, fmt.Sprintf("invalid listingCacheTtlSecs: %s", TtlInSecsInvalidValueError))
+}
+
+func (t *YamlParserTest) TestReadConfigFile_ListConfig_ValidListingCacheTtl() {
+	mountConfig, err := ParseConfigFile("testdata/list_config/valid_listing_cache_ttl.yaml")
+
+	assert.NoError(t.T(), err)
+	assert.NotNil(t.T(), mountConfig)
+	assert.Equal(t.T(), int64(60), mountConfig.ListConfig.ListingCacheTtlSeconds)
+}
+
+func (t *YamlParserTest) TestReadConfigFile_RetryConfig_Unset() {
+	mountConfig, err := ParseConfigFile("testdata/empty_file.yaml")
+
+	assert.NoError(t.T(), err)
+	assert.False(t.T(), mountConfig.RetryConfig.Enabled())
+	assert.Equal(t.T(), DefaultRetryPolicyConfig(), mountConfig.RetryConfig.Reads)
+	assert.Equal(t.T(), DefaultRetryBudgetRatio, mountConfig.RetryConfig.BudgetRatio)
+	assert.Equal(t.T(), DefaultRetryBudgetMinRetriesPerSec, mountConfig.RetryConfig.BudgetMinRetriesPerSec)
+}
+
+func (t *YamlParserTest) TestReadConfigFile_RetryConfig_Valid() {
+	mountConfig, err := ParseConfigFile("testdata/retry_config/valid_retry_config.yaml")
+
+	assert.NoError(t.T(), err)
+	assert.True(t.T(), mountConfig.RetryConfig.Enabled())
+	assert.Equal(t.T(), RetryPolicyConfig{
+		MaxAttempts:      4,
+		InitialBackoffMs: DefaultRetryInitialBackoffMs,
+		MaxBackoffMs:     DefaultRetryMaxBackoffMs,
+		Multiplier:       DefaultRetryMultiplier,
+		AttemptTimeoutMs: 5000,
+	}, mountConfig.RetryConfig.Reads)
+	assert.Equal(t.T(), DefaultRetryPolicyConfig(), mountConfig.RetryConfig.Metadata)
+	assert.Equal(t.T(), RetryPolicyConfig{
+		MaxAttempts:      3,
+		InitialBackoffMs: 500,
+		MaxBackoffMs:     10000,
+		Multiplier:       3,
+	}, mountConfig.RetryConfig.Uploads)
+	assert.Equal(t.T(), 0.2, mountConfig.RetryConfig.BudgetRatio)
+	assert.Equal(t.T(), DefaultRetryBudgetMinRetriesPerSec, mountConfig.RetryConfig.BudgetMinRetriesPerSec)
+}
+
+func (t *YamlParserTest) TestReadConfigFile_RetryConfig_InvalidBackoff() {
+	_, err := ParseConfigFile("testdata/retry_config/invalid_retry_backoff.yaml")
+
+	assert.ErrorContains(t.T(), err, "error parsing retry config: metadata: the value of initial-backoff-ms can't be negative, nor more than max-backoff-ms")
+}
+
+func (t *YamlParserTest) TestReadConfigFile_RetryConfig_InvalidBudget() {
+	_, err := ParseConfigFile("testdata/retry_config/invalid_retry_budget.yaml")
+
+	assert.ErrorContains(t.T(), err, RetryBudgetInvalidValueError)
+}
+
+func (t *YamlParserTest) TestReadConfigFile_HedgingConfig_Unset() {
+	mountConfig, err := ParseConfigFile("testdata/empty_file.yaml")
+
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), HedgingConfig{
+		Enabled:       false,
+		Percentile:    DefaultHedgingPercentile,
+		MinDelayMs:    DefaultHedgingMinDelayMs,
+		MaxReadSizeKB: DefaultHedgingMaxReadSizeKB,
+	}, mountConfig.HedgingConfig)
+}
+
+func (t *YamlParserTest) TestReadConfigFile_HedgingConfig_Valid() {
+	mountConfig, err := ParseConfigFile("testdata/hedging_config/valid_hedging_config.yaml")
+
+	assert.NoError(t.T(), err)
+	assert.True(t.T(), mountConfig.HedgingConfig.Enabled)
+	assert.Equal(t.T(), float64(99), mountConfig.HedgingConfig.Percentile)
+	assert.Equal(t.T(), DefaultHedgingMinDelayMs, mountConfig.HedgingConfig.MinDelayMs)
+}
+
+func (t *YamlParserTest) TestReadConfigFile_HedgingConfig_InvalidPercentile() {
+	_, err := ParseConfigFile("testdata/hedging_config/invalid_hedging_percentile.yaml")
+
+	assert.ErrorContains(t.T(), err, HedgingPercentileInvalidValueError)
+}
+
+func (t *YamlParserTest) TestReadConfigFile_ReadStallConfig_Unset() {
+	mountConfig, err := ParseConfigFile("testdata/empty_file.yaml")
+
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), ReadStallConfig{
+		StallTimeoutSecs:           0,
+		StallMinThroughputKBPerSec: DefaultStallMinThroughputKBPerSec,
+		StallMaxReopens:            DefaultStallMaxReopens,
+	}, mountConfig.ReadStallConfig)
+}
+
+func (t *YamlParserTest) TestReadConfigFile_ReadStallConfig_Valid() {
+	mountConfig, err := ParseConfigFile("testdata/read_stall_config/valid_read_stall_config.yaml")
+
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), int64(30), mountConfig.ReadStallConfig.StallTimeoutSecs)
+	assert.Equal(t.T(), DefaultStallMinThroughputKBPerSec, mountConfig.ReadStallConfig.StallMinThroughputKBPerSec)
+	assert.Equal(t.T(), 3, mountConfig.ReadStallConfig.StallMaxReopens)
+}
+
+func (t *YamlParserTest) TestReadConfigFile_ReadStallConfig_InvalidMinThroughput() {
+	_, err := ParseConfigFile("testdata/read_stall_config/invalid_read_stall_min_throughput.yaml")
+
+	assert.ErrorContains(t.T(), err, ReadStallInvalidValueError)
+}
+
+func (t *YamlParserTest) TestReadConfigFile_DynamicMountConfig_Unset() {
+	mountConfig, err := ParseConfigFile("testdata/empty_file.yaml")
+
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), DynamicMountConfig{
+		BucketListTtlSecs: DefaultBucketListTtlSecs,
+	}, mountConfig.DynamicMountConfig)
+}
+
+func (t *YamlParserTest) TestReadConfigFile_DynamicMountConfig_Valid() {
+	mountConfig, err := ParseConfigFile("testdata/dynamic_mount_config/valid_dynamic_mount_config.yaml")
+
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), DynamicMountConfig{
+		ListBucketsProject: "my-project",
+		BucketAllowlist:    []string{"datasets", "models"},
+		BucketRegex:        "^team-a-",
+		BucketListTtlSecs:  300,
+	}, mountConfig.DynamicMountConfig)
+}
+
+func (t *YamlParserTest) TestReadConfigFile_DynamicMountConfig_InvalidBucketRegex() {
+	_, err := ParseConfigFile("testdata/dynamic_mount_config/invalid_bucket_regex.yaml")
+
+	assert.ErrorContains(t.T(), err, BucketRegexInvalidValueError)
+}
+
+func (t *YamlParserTest) TestReadConfigFile_DynamicMountConfig_InvalidBucketListTtlSecs() {
+	_, err := ParseConfigFile("testdata/dynamic_mount_config/invalid_bucket_list_ttl_secs.yaml")
+
+	assert.ErrorContains(t.T(), err, BucketListTtlSecsInvalidValueError)
+}
+
+func (t *YamlParserTest) TestReadConfigFile_CacheInvalidationConfig_NonLoopbackListenAddress() {
+	_, err := ParseConfigFile("testdata/cache_invalidation_config/non_loopback_listen_address.yaml")
+
+	assert.ErrorContains(t.T(), err, NotificationListenAddressInvalidValueError)
+}
@@ -0,0 +1,340 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diagnose
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"cloud.google.com/go/storage/control/apiv2/controlpb"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/auth"
+	cacheutil "github.com/googlecloudplatform/gcsfuse/v2/internal/cache/util"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"github.com/jacobsa/fuse/fsutil"
+	"golang.org/x/sys/unix"
+)
+
+// Overridden by tests.
+var (
+	fuseDevicePath = "/dev/fuse"
+	fuseConfPath   = "/etc/fuse.conf"
+)
+
+// ProbeObjectPrefix is the prefix of the object created and deleted to check
+// the permission to write to the bucket.
+const ProbeObjectPrefix = ".gcsfuse_tmp/diagnose-"
+
+// storageLayoutBucket is implemented by the GCS buckets returned by
+// storage.StorageHandle, but not by those of other backends.
+type storageLayoutBucket interface {
+	StorageLayout(ctx context.Context) (*controlpb.StorageLayout, error)
+}
+
+// Outcome returns a check with a known outcome, e.g. failing because what it
+// checks couldn't be set up.
+func Outcome(name string, status Status, detail string) Check {
+	return Check{Name: name, Run: func(context.Context) (Status, string) { return status, detail }}
+}
+
+// CredentialsCheck checks that a token can be obtained, like gcsfuse does to
+// access GCS, from the key file, the token URL or else the application default
+// credentials, impersonating the given service account if any.
+func CredentialsCheck(keyFile, tokenUrl, impersonateServiceAccount string, reuseTokenFromUrl, allowCredentialExecutables, anonymousAccess bool) Check {
+	return Check{Name: "credentials", Run: func(ctx context.Context) (Status, string) {
+		if anonymousAccess {
+			return Skip, "the bucket is accessed anonymously"
+		}
+
+		source := "the application default credentials"
+		if keyFile != "" {
+			source = "the key file " + keyFile
+		} else if tokenUrl != "" {
+			source = "the token URL " + tokenUrl
+		}
+		if impersonateServiceAccount != "" {
+			source += " impersonating " + impersonateServiceAccount
+		}
+
+		ts, err := auth.GetTokenSource(ctx, keyFile, tokenUrl, reuseTokenFromUrl, impersonateServiceAccount, allowCredentialExecutables)
+		if err != nil {
+			return Fail, fmt.Sprintf("can't use %s: %v", source, err)
+		}
+		if _, err = ts.Token(); err != nil {
+			return Fail, fmt.Sprintf("can't get a token from %s: %v", source, err)
+		}
+		return Pass, "got a token from " + source
+	}}
+}
+
+// BucketChecks check that the bucket exists and its type, that its objects
+// under the given prefix can be listed, and that an object can be created and
+// deleted there. The scheme, e.g. gs or s3, is that of the URIs in the details.
+func BucketChecks(bucket gcs.Bucket, scheme, prefix string) []Check {
+	bucketURI := scheme + "://" + bucket.Name()
+	uri := scheme + "://" + path.Join(bucket.Name(), prefix)
+
+	return []Check{
+		{Name: "bucket", Run: func(ctx context.Context) (Status, string) {
+			b, ok := bucket.(storageLayoutBucket)
+			if !ok {
+				return Skip, "the bucket type is known only for GCS"
+			}
+			storageLayout, err := b.StorageLayout(ctx)
+			if err != nil {
+				return Fail, fmt.Sprintf("can't get the storage layout of %s: %v", bucketURI, err)
+			}
+			if hns := storageLayout.GetHierarchicalNamespace(); hns != nil && hns.Enabled {
+				return Pass, fmt.Sprintf("%s exists, with a hierarchical namespace", bucketURI)
+			}
+			return Pass, fmt.Sprintf("%s exists, with a flat namespace", bucketURI)
+		}},
+
+		{Name: "list", Run: func(ctx context.Context) (Status, string) {
+			_, err := bucket.ListObjects(ctx, &gcs.ListObjectsRequest{Prefix: prefix, Delimiter: "/", MaxResults: 1})
+			if err != nil {
+				return Fail, fmt.Sprintf("can't list %s: %v", uri, err)
+			}
+			return Pass, "can list " + uri
+		}},
+
+		{Name: "write", Run: func(ctx context.Context) (Status, string) {
+			name := prefix + ProbeObjectPrefix + strconv.FormatInt(time.Now().UnixNano(), 10)
+			var preconditionDoesNotExist int64 = 0
+			o, err := bucket.CreateObject(ctx, &gcs.CreateObjectRequest{
+				Name:                   name,
+				Contents:               strings.NewReader("gcsfuse diagnose"),
+				GenerationPrecondition: &preconditionDoesNotExist,
+			})
+			if err != nil {
+				return Fail, fmt.Sprintf("can't create %s/%s: %v", bucketURI, name, err)
+			}
+
+			err = bucket.DeleteObject(ctx, &gcs.DeleteObjectRequest{Name: name, Generation: o.Generation})
+			if err != nil {
+				return Fail, fmt.Sprintf("created %s/%s, but can't delete it: %v", bucketURI, name, err)
+			}
+			return Pass, fmt.Sprintf("created and deleted %s/%s", bucketURI, name)
+		}},
+	}
+}
+
+// DirectoryCheck checks that files can be created in the given directory, or
+// in its closest existing ancestor when gcsfuse is to create it, and that at
+// least minFreeBytes are available on its filesystem.
+func DirectoryCheck(name, dir string, minFreeBytes uint64) Check {
+	return Check{Name: name, Run: func(context.Context) (Status, string) {
+		existing := dir
+		for {
+			fi, err := os.Stat(existing)
+			if err == nil && fi.IsDir() {
+				break
+			} else if err == nil {
+				return Fail, fmt.Sprintf("%s is not a directory", existing)
+			} else if !os.IsNotExist(err) || filepath.Dir(existing) == existing {
+				return Fail, err.Error()
+			}
+			existing = filepath.Dir(existing)
+		}
+
+		detail := dir + " is writable"
+		if existing == dir {
+			f, err := fsutil.AnonymousFile(dir)
+			if err != nil {
+				return Fail, fmt.Sprintf("can't create a file in %s: %v", dir, err)
+			}
+			f.Close()
+		} else {
+			if err := unix.Access(existing, unix.W_OK|unix.X_OK); err != nil {
+				return Fail, fmt.Sprintf("%s doesn't exist, and can't be created in %s: %v", dir, existing, err)
+			}
+			detail = fmt.Sprintf("%s doesn't exist, and can be created in %s", dir, existing)
+		}
+
+		usage, err := cacheutil.GetDiskUsage(existing)
+		if err != nil {
+			return Fail, err.Error()
+		}
+		if usage.AvailableBytes < minFreeBytes {
+			return Warn, fmt.Sprintf("%s, but only %s of %s are available, below %s", detail,
+				formatBytes(usage.AvailableBytes), formatBytes(usage.TotalBytes), formatBytes(minFreeBytes))
+		}
+		return Pass, fmt.Sprintf("%s, %s of %s available", detail, formatBytes(usage.AvailableBytes), formatBytes(usage.TotalBytes))
+	}}
+}
+
+func formatBytes(b uint64) string {
+	if b >= 1<<30 {
+		return fmt.Sprintf("%.1f GiB", float64(b)/(1<<30))
+	}
+	return fmt.Sprintf("%.1f MiB", float64(b)/(1<<20))
+}
+
+// FuseDeviceCheck checks that the FUSE device exists and that it can be
+// opened by root, or else that fusermount, used to mount without root, is
+// installed.
+func FuseDeviceCheck() Check {
+	return Check{Name: "fuse device", Run: func(context.Context) (Status, string) {
+		fi, err := os.Stat(fuseDevicePath)
+		if err != nil {
+			return Fail, fmt.Sprintf("%v: load the fuse kernel module, or give the container access to %s", err, fuseDevicePath)
+		}
+		if fi.Mode()&os.ModeCharDevice == 0 {
+			return Fail, fmt.Sprintf("%s is not a character device", fuseDevicePath)
+		}
+
+		if os.Geteuid() == 0 {
+			f, err := os.OpenFile(fuseDevicePath, os.O_RDWR, 0)
+			if err != nil {
+				return Fail, fmt.Sprintf("can't open %s: %v", fuseDevicePath, err)
+			}
+			f.Close()
+			return Pass, fuseDevicePath + " can be opened"
+		}
+
+		fusermount, err := exec.LookPath("fusermount3")
+		if err != nil {
+			fusermount, err = exec.LookPath("fusermount")
+		}
+		if err != nil {
+			return Fail, "neither fusermount3 nor fusermount, needed to mount without root, is in PATH: install fuse3"
+		}
+		return Pass, fmt.Sprintf("%s exists, and %s mounts it", fuseDevicePath, fusermount)
+	}}
+}
+
+// MountOptionsCheck checks that the given kernel mount options, passed with
+// -o, can be used by the current user.
+func MountOptionsCheck(options map[string]string) Check {
+	return Check{Name: "mount options", Run: func(context.Context) (Status, string) {
+		if _, ok := options["allow_other"]; ok && os.Geteuid() != 0 {
+			allowed, err := userAllowOther()
+			if err != nil {
+				return Fail, fmt.Sprintf("allow_other requires user_allow_other in %s when not mounting as root: %v", fuseConfPath, err)
+			} else if !allowed {
+				return Fail, fmt.Sprintf("allow_other requires user_allow_other in %s when not mounting as root", fuseConfPath)
+			}
+		}
+		return Pass, formatOptions(options)
+	}}
+}
+
+// userAllowOther returns true if the fuse config lets users other than root
+// mount with allow_other.
+func userAllowOther() (bool, error) {
+	f, err := os.Open(fuseConfPath)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "user_allow_other" {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+func formatOptions(options map[string]string) string {
+	if len(options) == 0 {
+		return "no options"
+	}
+
+	var s []string
+	for name, value := range options {
+		if value != "" {
+			name += "=" + value
+		}
+		s = append(s, name)
+	}
+	sort.Strings(s)
+	return strings.Join(s, ",")
+}
+
+// MountPointChecks check that a gcsfuse mount is at the given mount point,
+// that it can be accessed by the current user and that it responds. It also
+// returns the mount, which is nil when there is none.
+func MountPointChecks(mountPoint string) (*MountInfo, []Check) {
+	mi, err := FindMount(mountPoint)
+	if err != nil {
+		return nil, []Check{Outcome("mount", Fail, err.Error())}
+	} else if mi == nil {
+		return nil, []Check{Outcome("mount", Fail, "nothing is mounted at "+mountPoint)}
+	} else if mi.FSType != FSType {
+		return nil, []Check{Outcome("mount", Fail, fmt.Sprintf("%s is a %s mount, not a gcsfuse one", mountPoint, mi.FSType))}
+	}
+
+	return mi, []Check{
+		{Name: "mount", Run: func(context.Context) (Status, string) {
+			return Pass, fmt.Sprintf("%s is mounted at %s", mi.Source, mountPoint)
+		}},
+
+		{Name: "mount options", Run: func(context.Context) (Status, string) {
+			options := formatOptions(mi.Options) + " " + formatOptions(mi.SuperOptions)
+			if _, ok := mi.SuperOptions["allow_other"]; ok {
+				return Pass, options
+			}
+			if uid := mi.SuperOptions["user_id"]; uid != "" && uid != strconv.Itoa(os.Geteuid()) {
+				return Warn, fmt.Sprintf("%s: only the user %s can access the mount, unless mounted with -o allow_other", options, uid)
+			}
+			return Pass, options
+		}},
+
+		{Name: "responsive", Run: func(ctx context.Context) (Status, string) {
+			_, err := Stat(ctx, mountPoint)
+			if errors.Is(err, syscall.ENOTCONN) {
+				return Fail, fmt.Sprintf("%v: the gcsfuse process is gone, unmount with fusermount -u %s", err, mountPoint)
+			} else if ctx.Err() != nil {
+				return Fail, fmt.Sprintf("stat of %s hangs", mountPoint)
+			} else if err != nil {
+				return Fail, err.Error()
+			}
+			return Pass, mountPoint + " can be stat'ed"
+		}},
+	}
+}
+
+// Stat is os.Stat, except that it returns ctx.Err() when ctx is done first, as
+// stat'ing a hung mount blocks.
+func Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	type result struct {
+		fi  os.FileInfo
+		err error
+	}
+	results := make(chan result, 1)
+	go func() {
+		fi, err := os.Stat(name)
+		results <- result{fi, err}
+	}()
+
+	select {
+	case r := <-results:
+		return r.fi, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,261 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diagnose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cloud.google.com/go/storage/control/apiv2/controlpb"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/fake"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"github.com/jacobsa/timeutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeBucket struct {
+	gcs.Bucket
+	storageLayout *controlpb.StorageLayout
+	err           error
+}
+
+func (b *fakeBucket) StorageLayout(ctx context.Context) (*controlpb.StorageLayout, error) {
+	return b.storageLayout, b.err
+}
+
+// failingBucket fails to create objects.
+type failingBucket struct {
+	fakeBucket
+}
+
+func (b *failingBucket) CreateObject(ctx context.Context, req *gcs.CreateObjectRequest) (*gcs.Object, error) {
+	return nil, errors.New("permission denied")
+}
+
+func run(c Check) (Status, string) {
+	return c.Run(context.Background())
+}
+
+func TestCredentialsCheckAnonymous(t *testing.T) {
+	status, _ := run(CredentialsCheck("", "", "", false, false, true))
+
+	assert.Equal(t, Skip, status)
+}
+
+func TestCredentialsCheckMissingKeyFile(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key.json")
+
+	status, detail := run(CredentialsCheck(keyFile, "", "", false, false, false))
+
+	assert.Equal(t, Fail, status)
+	assert.Contains(t, detail, "can't use the key file "+keyFile)
+}
+
+func TestBucketChecks(t *testing.T) {
+	bucket := &fakeBucket{
+		Bucket:        fake.NewFakeBucket(timeutil.RealClock(), "b"),
+		storageLayout: &controlpb.StorageLayout{HierarchicalNamespace: &controlpb.StorageLayout_HierarchicalNamespace{Enabled: true}},
+	}
+
+	report := Run(context.Background(), BucketChecks(bucket, "gs", "dir/"))
+
+	require.Len(t, report.Results, 3)
+	assert.Equal(t, Result{Check: "bucket", Status: Pass, Detail: "gs://b exists, with a hierarchical namespace"}, report.Results[0])
+	assert.Equal(t, Result{Check: "list", Status: Pass, Detail: "can list gs://b/dir"}, report.Results[1])
+	assert.Equal(t, Pass, report.Results[2].Status)
+	assert.Contains(t, report.Results[2].Detail, "created and deleted gs://b/dir/"+ProbeObjectPrefix)
+	// The probe object is deleted.
+	listing, err := bucket.ListObjects(context.Background(), &gcs.ListObjectsRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, listing.Objects)
+}
+
+func TestBucketChecksFlatNamespace(t *testing.T) {
+	bucket := &fakeBucket{Bucket: fake.NewFakeBucket(timeutil.RealClock(), "b"), storageLayout: &controlpb.StorageLayout{}}
+
+	status, detail := run(BucketChecks(bucket, "gs", "")[0])
+
+	assert.Equal(t, Pass, status)
+	assert.Equal(t, "gs://b exists, with a flat namespace", detail)
+}
+
+func TestBucketChecksWithoutStorageLayout(t *testing.T) {
+	bucket := fake.NewFakeBucket(timeutil.RealClock(), "b")
+
+	report := Run(context.Background(), BucketChecks(bucket, "s3", ""))
+
+	require.Len(t, report.Results, 3)
+	assert.Equal(t, Result{Check: "bucket", Status: Skip, Detail: "the bucket type is known only for GCS"}, report.Results[0])
+	assert.Equal(t, Result{Check: "list", Status: Pass, Detail: "can list s3://b"}, report.Results[1])
+	assert.Equal(t, Pass, report.Results[2].Status)
+	assert.Contains(t, report.Results[2].Detail, "created and deleted s3://b/"+ProbeObjectPrefix)
+}
+
+func TestBucketChecksMissingBucket(t *testing.T) {
+	bucket := &fakeBucket{Bucket: fake.NewFakeBucket(timeutil.RealClock(), "b"), err: errors.New("not found")}
+
+	status, detail := run(BucketChecks(bucket, "gs", "")[0])
+
+	assert.Equal(t, Fail, status)
+	assert.Equal(t, "can't get the storage layout of gs://b: not found", detail)
+}
+
+func TestBucketChecksReadOnly(t *testing.T) {
+	bucket := &failingBucket{fakeBucket{Bucket: fake.NewFakeBucket(timeutil.RealClock(), "b")}}
+
+	status, detail := run(BucketChecks(bucket, "gs", "")[2])
+
+	assert.Equal(t, Fail, status)
+	assert.Contains(t, detail, "permission denied")
+}
+
+func TestDirectoryCheck(t *testing.T) {
+	dir := t.TempDir()
+
+	status, detail := run(DirectoryCheck("cache-dir", dir, 0))
+
+	assert.Equal(t, Pass, status)
+	assert.Contains(t, detail, dir+" is writable")
+}
+
+func TestDirectoryCheckMissingDirectory(t *testing.T) {
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "a", "b")
+
+	status, detail := run(DirectoryCheck("cache-dir", dir, 0))
+
+	assert.Equal(t, Pass, status)
+	assert.Contains(t, detail, fmt.Sprintf("%s doesn't exist, and can be created in %s", dir, parent))
+	assert.NoDirExists(t, dir)
+}
+
+func TestDirectoryCheckNotADirectory(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "f")
+	require.NoError(t, os.WriteFile(f, nil, 0644))
+
+	status, detail := run(DirectoryCheck("temp-dir", f, 0))
+
+	assert.Equal(t, Fail, status)
+	assert.Equal(t, f+" is not a directory", detail)
+}
+
+func TestDirectoryCheckLowFreeSpace(t *testing.T) {
+	status, detail := run(DirectoryCheck("temp-dir", t.TempDir(), math.MaxUint64))
+
+	assert.Equal(t, Warn, status)
+	assert.Contains(t, detail, "are available, below")
+}
+
+func TestFuseDeviceCheckMissingDevice(t *testing.T) {
+	old := fuseDevicePath
+	fuseDevicePath = filepath.Join(t.TempDir(), "fuse")
+	defer func() { fuseDevicePath = old }()
+
+	status, detail := run(FuseDeviceCheck())
+
+	assert.Equal(t, Fail, status)
+	assert.Contains(t, detail, "load the fuse kernel module")
+}
+
+func TestFuseDeviceCheckNotADevice(t *testing.T) {
+	old := fuseDevicePath
+	fuseDevicePath = t.TempDir()
+	defer func() { fuseDevicePath = old }()
+
+	status, detail := run(FuseDeviceCheck())
+
+	assert.Equal(t, Fail, status)
+	assert.Contains(t, detail, "is not a character device")
+}
+
+func TestUserAllowOther(t *testing.T) {
+	old := fuseConfPath
+	fuseConfPath = filepath.Join(t.TempDir(), "fuse.conf")
+	defer func() { fuseConfPath = old }()
+
+	require.NoError(t, os.WriteFile(fuseConfPath, []byte("# user_allow_other\nmount_max = 1000\n"), 0644))
+	allowed, err := userAllowOther()
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	require.NoError(t, os.WriteFile(fuseConfPath, []byte("mount_max = 1000\n user_allow_other\n"), 0644))
+	allowed, err = userAllowOther()
+	require.NoError(t, err)
+	assert.True(t, allowed)
+}
+
+func TestMountOptionsCheck(t *testing.T) {
+	status, detail := run(MountOptionsCheck(map[string]string{"ro": "", "max_read": "4096"}))
+
+	assert.Equal(t, Pass, status)
+	assert.Equal(t, "max_read=4096,ro", detail)
+}
+
+func TestMountPointChecksNotMounted(t *testing.T) {
+	setMountInfo(t, "22 1 8:1 / / rw - ext4 /dev/sda1 rw\n")
+
+	mi, checks := MountPointChecks("/mnt")
+
+	assert.Nil(t, mi)
+	require.Len(t, checks, 1)
+	status, detail := run(checks[0])
+	assert.Equal(t, Fail, status)
+	assert.Equal(t, "nothing is mounted at /mnt", detail)
+}
+
+func TestMountPointChecksNotGcsfuse(t *testing.T) {
+	setMountInfo(t, "22 1 8:1 / /mnt rw - ext4 /dev/sda1 rw\n")
+
+	mi, checks := MountPointChecks("/mnt")
+
+	assert.Nil(t, mi)
+	require.Len(t, checks, 1)
+	status, detail := run(checks[0])
+	assert.Equal(t, Fail, status)
+	assert.Equal(t, "/mnt is a ext4 mount, not a gcsfuse one", detail)
+}
+
+func TestMountPointChecks(t *testing.T) {
+	mountPoint := t.TempDir()
+	setMountInfo(t, fmt.Sprintf("37 22 0:46 / %s rw,nosuid - fuse.gcsfuse b rw,user_id=%d\n", mountPoint, os.Geteuid()))
+
+	mi, checks := MountPointChecks(mountPoint)
+
+	require.NotNil(t, mi)
+	assert.Equal(t, "b", mi.Source)
+	report := Run(context.Background(), checks)
+	assert.Equal(t, []Result{
+		{Check: "mount", Status: Pass, Detail: "b is mounted at " + mountPoint},
+		{Check: "mount options", Status: Pass, Detail: fmt.Sprintf("nosuid,rw rw,user_id=%d", os.Geteuid())},
+		{Check: "responsive", Status: Pass, Detail: mountPoint + " can be stat'ed"},
+	}, report.Results)
+}
+
+func TestMountPointChecksOtherUser(t *testing.T) {
+	mountPoint := t.TempDir()
+	setMountInfo(t, fmt.Sprintf("37 22 0:46 / %s rw - fuse.gcsfuse b rw,user_id=%d\n", mountPoint, os.Geteuid()+1))
+
+	_, checks := MountPointChecks(mountPoint)
+
+	status, detail := run(checks[1])
+	assert.Equal(t, Warn, status)
+	assert.Contains(t, detail, "unless mounted with -o allow_other")
+}
@@ -0,0 +1,113 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package diagnose checks the environment a bucket is mounted in, or is to be
+// mounted in: the credentials, the access to the bucket, the local directories
+// used by gcsfuse, the FUSE device and the kernel mount options. It reports
+// which checks pass and, for the others, what is wrong.
+package diagnose
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// CheckTimeout bounds the time taken by each check.
+const CheckTimeout = 30 * time.Second
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	Pass Status = "PASS"
+	// Warn is for a problem which doesn't prevent mounting, but may cause
+	// errors later, e.g. low free space.
+	Warn Status = "WARN"
+	Fail Status = "FAIL"
+	// Skip is for a check which doesn't apply, e.g. to credentials when
+	// accessing the bucket anonymously.
+	Skip Status = "SKIP"
+)
+
+// Check is a check of the environment.
+type Check struct {
+	Name string
+
+	// Run returns the outcome of the check, with a detail saying what was
+	// checked or, on failure, what is wrong.
+	Run func(ctx context.Context) (status Status, detail string)
+}
+
+// Result is the outcome of a check.
+type Result struct {
+	Check  string
+	Status Status
+	Detail string
+}
+
+// Report is the outcome of all the checks, in order.
+type Report struct {
+	Results []Result
+}
+
+// Failed returns the number of failed checks.
+func (r *Report) Failed() (n int) {
+	for _, result := range r.Results {
+		if result.Status == Fail {
+			n++
+		}
+	}
+	return
+}
+
+// WriteTo writes the report as a table, one line per check.
+func (r *Report) WriteTo(w io.Writer) (n int64, err error) {
+	cw := &countingWriter{w: w}
+	tw := tabwriter.NewWriter(cw, 0, 0, 2, ' ', 0)
+	for _, result := range r.Results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", result.Status, result.Check, result.Detail)
+	}
+	if err = tw.Flush(); err != nil {
+		return cw.n, err
+	}
+
+	_, err = fmt.Fprintf(cw, "\n%d checks, %d failed\n", len(r.Results), r.Failed())
+	return cw.n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (n int, err error) {
+	n, err = cw.w.Write(p)
+	cw.n += int64(n)
+	return
+}
+
+// Run runs the checks one after the other, each within CheckTimeout.
+func Run(ctx context.Context, checks []Check) (report Report) {
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, CheckTimeout)
+		status, detail := check.Run(checkCtx)
+		cancel()
+
+		report.Results = append(report.Results, Result{Check: check.Name, Status: status, Detail: detail})
+	}
+	return
+}
@@ -0,0 +1,62 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diagnose
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	var deadlineSet bool
+	checks := []Check{
+		Outcome("a", Pass, "fine"),
+		{Name: "b", Run: func(ctx context.Context) (Status, string) {
+			_, deadlineSet = ctx.Deadline()
+			return Fail, "broken"
+		}},
+		Outcome("c", Warn, "low"),
+	}
+
+	report := Run(context.Background(), checks)
+
+	assert.True(t, deadlineSet)
+	assert.Equal(t, []Result{
+		{Check: "a", Status: Pass, Detail: "fine"},
+		{Check: "b", Status: Fail, Detail: "broken"},
+		{Check: "c", Status: Warn, Detail: "low"},
+	}, report.Results)
+	assert.Equal(t, 1, report.Failed())
+}
+
+func TestReportWriteTo(t *testing.T) {
+	report := Report{Results: []Result{
+		{Check: "credentials", Status: Pass, Detail: "got a token"},
+		{Check: "fuse device", Status: Fail, Detail: "missing"},
+	}}
+	var b bytes.Buffer
+
+	n, err := report.WriteTo(&b)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(b.Len()), n)
+	assert.Equal(t, "PASS  credentials  got a token\n"+
+		"FAIL  fuse device  missing\n"+
+		"\n2 checks, 1 failed\n", b.String())
+}
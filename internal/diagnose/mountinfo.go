@@ -0,0 +1,121 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diagnose
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	mountpkg "github.com/googlecloudplatform/gcsfuse/v2/internal/mount"
+)
+
+// FSType is the type of the file systems mounted by gcsfuse.
+const FSType = "fuse.gcsfuse"
+
+// Overridden by tests.
+var mountInfoPath = "/proc/self/mountinfo"
+
+// MountInfo describes a mount, as listed in /proc/self/mountinfo.
+type MountInfo struct {
+	MountPoint string
+	FSType     string
+
+	// Source is the bucket name for gcsfuse, or "gcsfuse" when mounting all
+	// the buckets.
+	Source string
+
+	// Options are the options of the mount point, e.g. ro and nosuid, and
+	// SuperOptions those of the file system, e.g. allow_other and user_id.
+	Options      map[string]string
+	SuperOptions map[string]string
+}
+
+// FindMount returns the topmost mount at the given mount point, or nil if
+// nothing is mounted there.
+func FindMount(mountPoint string) (mi *MountInfo, err error) {
+	f, err := os.Open(mountInfoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Mounts on top of others are listed after them.
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		m, parseErr := parseMountInfo(scanner.Text())
+		if parseErr != nil {
+			return nil, fmt.Errorf("while parsing %s: %w", mountInfoPath, parseErr)
+		}
+		if m.MountPoint == mountPoint {
+			mi = m
+		}
+	}
+	err = scanner.Err()
+	return
+}
+
+// parseMountInfo parses a line of /proc/self/mountinfo, e.g.
+//
+//	36 35 0:45 / /mnt/b rw,nosuid,nodev,relatime shared:1 - fuse.gcsfuse b rw,user_id=0,group_id=0
+//
+// The optional fields, like shared:1, end with a dash.
+func parseMountInfo(line string) (*MountInfo, error) {
+	fields := strings.Fields(line)
+	sep := -1
+	for i := 6; i < len(fields); i++ {
+		if fields[i] == "-" {
+			sep = i
+			break
+		}
+	}
+	if sep == -1 || len(fields) < sep+4 {
+		return nil, fmt.Errorf("malformed line %q", line)
+	}
+
+	m := &MountInfo{
+		MountPoint:   unescapeMountInfo(fields[4]),
+		FSType:       fields[sep+1],
+		Source:       unescapeMountInfo(fields[sep+2]),
+		Options:      make(map[string]string),
+		SuperOptions: make(map[string]string),
+	}
+	mountpkg.ParseOptions(m.Options, fields[5])
+	mountpkg.ParseOptions(m.SuperOptions, fields[sep+3])
+	return m, nil
+}
+
+// unescapeMountInfo replaces the octal escapes of the kernel, e.g. \040 for a
+// space.
+func unescapeMountInfo(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,89 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diagnose
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setMountInfo makes FindMount read the given lines.
+func setMountInfo(t *testing.T, content string) {
+	p := filepath.Join(t.TempDir(), "mountinfo")
+	require.NoError(t, os.WriteFile(p, []byte(content), 0644))
+	old := mountInfoPath
+	mountInfoPath = p
+	t.Cleanup(func() { mountInfoPath = old })
+}
+
+func TestParseMountInfo(t *testing.T) {
+	m, err := parseMountInfo(`36 35 0:45 / /mnt/my\040bucket rw,nosuid,relatime shared:1 master:2 - fuse.gcsfuse my-bucket rw,user_id=1000,group_id=1000,allow_other`)
+
+	require.NoError(t, err)
+	assert.Equal(t, &MountInfo{
+		MountPoint:   "/mnt/my bucket",
+		FSType:       FSType,
+		Source:       "my-bucket",
+		Options:      map[string]string{"rw": "", "nosuid": "", "relatime": ""},
+		SuperOptions: map[string]string{"rw": "", "user_id": "1000", "group_id": "1000", "allow_other": ""},
+	}, m)
+}
+
+func TestParseMountInfoWithoutOptionalFields(t *testing.T) {
+	m, err := parseMountInfo("22 1 8:1 / / rw - ext4 /dev/sda1 rw")
+
+	require.NoError(t, err)
+	assert.Equal(t, "/", m.MountPoint)
+	assert.Equal(t, "ext4", m.FSType)
+	assert.Equal(t, "/dev/sda1", m.Source)
+}
+
+func TestParseMountInfoMalformed(t *testing.T) {
+	_, err := parseMountInfo("22 1 8:1 / / rw ext4 /dev/sda1 rw")
+
+	assert.ErrorContains(t, err, "malformed line")
+}
+
+func TestUnescapeMountInfo(t *testing.T) {
+	assert.Equal(t, "a b\tc\\d", unescapeMountInfo(`a\040b\011c\134d`))
+	assert.Equal(t, `a\04`, unescapeMountInfo(`a\04`))
+	assert.Equal(t, `a\xyzb`, unescapeMountInfo(`a\xyzb`))
+}
+
+func TestFindMount(t *testing.T) {
+	setMountInfo(t, "22 1 8:1 / / rw - ext4 /dev/sda1 rw\n"+
+		"36 22 0:45 / /mnt rw - tmpfs tmpfs rw\n"+
+		"37 36 0:46 / /mnt rw - fuse.gcsfuse b rw,user_id=0\n")
+
+	m, err := FindMount("/mnt")
+
+	require.NoError(t, err)
+	require.NotNil(t, m)
+	assert.Equal(t, FSType, m.FSType)
+	assert.Equal(t, "b", m.Source)
+}
+
+func TestFindMountNotMounted(t *testing.T) {
+	setMountInfo(t, "22 1 8:1 / / rw - ext4 /dev/sda1 rw\n")
+
+	m, err := FindMount("/mnt")
+
+	require.NoError(t, err)
+	assert.Nil(t, m)
+}
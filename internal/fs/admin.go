@@ -0,0 +1,173 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fs
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/admin"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/lru"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/metadata"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/fs/inode"
+)
+
+// This file implements admin.FileSystem, for the admin server to inspect the
+// state of the file system. It doesn't take the inode locks, so that a mount
+// with an operation stuck while holding one can still be inspected.
+
+// dirInodes returns the directory inodes, sorted by name.
+//
+// LOCKS_EXCLUDED(fs.mu)
+func (fs *fileSystem) dirInodes() (dirs []inode.DirInode) {
+	fs.mu.Lock()
+	for _, in := range fs.inodes {
+		if d, ok := in.(inode.DirInode); ok {
+			dirs = append(dirs, d)
+		}
+	}
+	fs.mu.Unlock()
+
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].Name().LocalName() < dirs[j].Name().LocalName() })
+	return
+}
+
+// typeCacheEntries returns the entries of the type caches of all directories.
+//
+// LOCKS_EXCLUDED(fs.mu)
+func (fs *fileSystem) typeCacheEntries() (entries []admin.CacheEntry) {
+	for _, d := range fs.dirInodes() {
+		for _, info := range d.TypeCacheEntries() {
+			info.Name = d.Name().LocalName() + info.Name
+			entries = append(entries, cacheEntry(info))
+		}
+	}
+	return
+}
+
+func cacheEntry(info metadata.EntryInfo) admin.CacheEntry {
+	expiration := info.Expiration
+	return admin.CacheEntry{Name: info.Name, Value: info.Value, Expiration: &expiration}
+}
+
+// LOCKS_EXCLUDED(fs.mu)
+func (fs *fileSystem) Caches() (caches []admin.CacheStats) {
+	if sc := fs.bucketManager.StatCache(); sc != nil {
+		stats := admin.CacheStats{Name: admin.StatCache, Size: sc.CurrentSize(), MaxSize: sc.MaxSize()}
+		sc.ForEach(func(string, lru.ValueType) { stats.Entries++ })
+		caches = append(caches, stats)
+	}
+
+	if fs.dirTypeCacheTTL > 0 && fs.sharedMetadataCache == nil {
+		stats := admin.CacheStats{Name: admin.TypeCache}
+		for _, d := range fs.dirInodes() {
+			stats.Entries += d.TypeCacheLen()
+		}
+		caches = append(caches, stats)
+	}
+
+	if fs.fileCacheHandler != nil {
+		size, maxSize := fs.fileCacheHandler.Size()
+		caches = append(caches, admin.CacheStats{
+			Name:    admin.FileCache,
+			Entries: len(fs.fileCacheHandler.Entries()),
+			Size:    size,
+			MaxSize: maxSize,
+		})
+	}
+	return
+}
+
+// LOCKS_EXCLUDED(fs.mu)
+func (fs *fileSystem) CacheEntries(cache string) (entries []admin.CacheEntry, err error) {
+	switch cache {
+	case admin.StatCache:
+		if sc := fs.bucketManager.StatCache(); sc != nil {
+			for _, info := range metadata.StatCacheEntries(sc) {
+				entries = append(entries, cacheEntry(info))
+			}
+		}
+
+	case admin.TypeCache:
+		entries = fs.typeCacheEntries()
+
+	case admin.FileCache:
+		if fs.fileCacheHandler != nil {
+			for _, fi := range fs.fileCacheHandler.Entries() {
+				entries = append(entries, admin.CacheEntry{
+					Name:  fi.Key.BucketName + "/" + fi.Key.ObjectName,
+					Value: fmt.Sprintf("generation %d, %d of %d bytes", fi.ObjectGeneration, fi.Offset, fi.FileSize),
+				})
+			}
+		}
+
+	default:
+		err = fmt.Errorf("%w %q", admin.ErrUnknownCache, cache)
+	}
+	return
+}
+
+// LOCKS_EXCLUDED(fs.mu)
+func (fs *fileSystem) DownloadJobs() (jobs []admin.DownloadJob) {
+	if fs.fileCacheHandler == nil {
+		return
+	}
+
+	for _, job := range fs.fileCacheHandler.DownloadJobs() {
+		status := job.GetStatus()
+		j := admin.DownloadJob{
+			Bucket:     job.BucketName(),
+			Object:     job.Object().Name,
+			Generation: job.Object().Generation,
+			Size:       job.Object().Size,
+			Status:     string(status.Name),
+			Offset:     status.Offset,
+		}
+		if status.Err != nil {
+			j.Error = status.Err.Error()
+		}
+		jobs = append(jobs, j)
+	}
+	return
+}
+
+// LOCKS_EXCLUDED(fs.mu)
+func (fs *fileSystem) DirtyFiles() (files []admin.DirtyFile) {
+	var fileInodes []*inode.FileInode
+	fs.mu.Lock()
+	for _, in := range fs.inodes {
+		if f, ok := in.(*inode.FileInode); ok {
+			fileInodes = append(fileInodes, f)
+		}
+	}
+	fs.mu.Unlock()
+
+	for _, f := range fileInodes {
+		if status := f.Status(); status.Dirty {
+			files = append(files, admin.DirtyFile{
+				Inode:        uint64(f.ID()),
+				Path:         f.Name().LocalName(),
+				Local:        status.Local,
+				Generation:   status.Generation,
+				PendingBytes: status.PendingBytes,
+			})
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return
+}
+
+var _ admin.FileSystem = &fileSystem{}
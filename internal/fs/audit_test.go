@@ -0,0 +1,100 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fs_test
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/audit"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/config"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/storageutil"
+	. "github.com/jacobsa/ogletest"
+)
+
+////////////////////////////////////////////////////////////////////////
+// Boilerplate
+////////////////////////////////////////////////////////////////////////
+
+type AuditTest struct {
+	auditPath string
+	fsTest
+}
+
+func init() {
+	RegisterTestSuite(&AuditTest{})
+}
+
+func (t *AuditTest) SetUpTestSuite() {
+	dir, err := os.MkdirTemp("", "audit_test")
+	AssertEq(nil, err)
+	t.auditPath = path.Join(dir, "audit.json")
+	t.serverCfg.AuditLogger, err = audit.NewLogger(t.auditPath, config.DefaultLogRotateConfig())
+	AssertEq(nil, err)
+
+	t.fsTest.SetUpTestSuite()
+}
+
+func (t *AuditTest) TearDownTestSuite() {
+	t.fsTest.TearDownTestSuite()
+	os.RemoveAll(path.Dir(t.auditPath))
+}
+
+// entries returns the entries of the audit log recorded since the last call.
+func (t *AuditTest) entries() (entries []audit.Entry) {
+	contents, err := os.ReadFile(t.auditPath)
+	AssertEq(nil, err)
+	AssertEq(nil, os.Truncate(t.auditPath, 0))
+
+	for _, line := range strings.Split(strings.TrimSuffix(string(contents), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		var e audit.Entry
+		AssertEq(nil, json.Unmarshal([]byte(line), &e))
+		entries = append(entries, e)
+	}
+	return
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *AuditTest) WriteSyncClose() {
+	// Create an object in the bucket.
+	_, err := storageutil.CreateObject(ctx, bucket, "foo", []byte("taco"))
+	AssertEq(nil, err)
+	t.entries()
+
+	// Write to it, sync and close it.
+	f, err := os.OpenFile(path.Join(mntDir, "foo"), os.O_WRONLY, 0)
+	AssertEq(nil, err)
+	_, err = f.Write([]byte("burrito"))
+	AssertEq(nil, err)
+	AssertEq(nil, f.Sync())
+	AssertEq(nil, f.Close())
+
+	// The write back on sync is recorded, but not the flush of the already
+	// synced file on close.
+	entries := t.entries()
+	AssertEq(1, len(entries))
+	ExpectEq(audit.OpWriteClose, entries[0].Op)
+	ExpectEq("foo", entries[0].Path)
+	ExpectEq(audit.OutcomeSuccess, entries[0].Outcome)
+	ExpectNe(0, entries[0].Generation)
+}
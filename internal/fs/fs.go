@@ -28,14 +28,21 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/accounting"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/admin"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/audit"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/file"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/file/downloader"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/invalidation"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/lru"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/metadata"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/metadata/shared"
 	cacheutil "github.com/googlecloudplatform/gcsfuse/v2/internal/cache/util"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/config"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/contentcache"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/fs/handle"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/fs/inode"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/fs/prefetch"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/gcsx"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/locker"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
@@ -110,6 +117,11 @@ type ServerConfig struct {
 	// before the expiration, we may fail to find it.
 	DirTypeCacheTTL time.Duration
 
+	// If non-nil, the directory type caches are kept in this shared metadata
+	// cache, instead of in this process. The file system closes it when
+	// destroyed.
+	SharedMetadataCache *shared.Client
+
 	// The UID and GID that owns all inodes in the file system.
 	Uid uint32
 	Gid uint32
@@ -125,6 +137,27 @@ type ServerConfig struct {
 	// File chunk size to read from GCS in one call. Specified in MB.
 	SequentialReadSizeMb int32
 
+	// Whether and how to prefetch the metadata of the mounted bucket: one of
+	// the config.ExperimentalMetadataPrefetchOnMount* modes. With "sync",
+	// NewFileSystem returns once prefetching is complete. Ignored when mounting
+	// all accessible buckets.
+	MetadataPrefetchOnMount string
+
+	// The size of the stat cache, which bounds the metadata fetched by
+	// metadata prefetching.
+	StatCacheMaxSizeMB uint64
+
+	// If non-nil, the accesses to each file and directory are accounted in it.
+	Accountant *accounting.Accountant
+
+	// If non-nil, the operations changing files and directories are recorded
+	// in it. It is closed when the file system is destroyed.
+	AuditLogger *audit.Logger
+
+	// If non-nil, the file system is registered with it, so that its caches
+	// and the changes pending an upload can be inspected while it is mounted.
+	AdminServer *admin.Server
+
 	// MountConfig has all the config specified by the user using configFile flag.
 	MountConfig *config.MountConfig
 }
@@ -192,16 +225,28 @@ func NewFileSystem(
 		mountConfig:                cfg.MountConfig,
 		fileCacheHandler:           fileCacheHandler,
 		cacheFileForRangeRead:      cfg.MountConfig.FileCacheConfig.CacheFileForRangeRead,
+		sharedMetadataCache:        cfg.SharedMetadataCache,
+		accountant:                 cfg.Accountant,
+		auditLogger:                cfg.AuditLogger,
+	}
+
+	// Set up the listing caches of directories, if enabled.
+	if ttl := config.ListCacheTtlSecsToDuration(cfg.MountConfig.ListingCacheTtlSeconds); ttl > 0 {
+		var listingCtx context.Context
+		listingCtx, fs.cancelListingCache = context.WithCancel(context.Background())
+		fs.listingCache = inode.NewListingCache(listingCtx, ttl)
 	}
 
 	// Set up root bucket
 	var root inode.DirInode
+	var syncerBucket gcsx.SyncerBucket
 	if cfg.BucketName == "" || cfg.BucketName == "_" {
 		logger.Info("Set up root directory for all accessible buckets")
 		root = makeRootForAllBuckets(fs)
 	} else {
 		logger.Info("Set up root directory for bucket " + cfg.BucketName)
-		syncerBucket, err := fs.bucketManager.SetUpBucket(ctx, cfg.BucketName, false)
+		var err error
+		syncerBucket, err = fs.bucketManager.SetUpBucket(ctx, cfg.BucketName, false)
 		if err != nil {
 			return nil, fmt.Errorf("SetUpBucket: %w", err)
 		}
@@ -215,6 +260,43 @@ func NewFileSystem(
 
 	// Set up invariant checking.
 	fs.mu = locker.New("FS", fs.checkInvariants)
+
+	// Start consuming object change notifications, if configured.
+	invalidationConfig := cfg.MountConfig.CacheInvalidationConfig
+	if invalidationConfig.NotificationListenAddress != "" || invalidationConfig.NotificationFile != "" {
+		fs.invalidationConsumer = invalidation.NewConsumer(invalidation.Config{
+			ListenAddress: invalidationConfig.NotificationListenAddress,
+			FilePath:      invalidationConfig.NotificationFile,
+		}, fs)
+		if err := fs.invalidationConsumer.Start(); err != nil {
+			fs.Destroy()
+			return nil, fmt.Errorf("starting cache invalidation: %w", err)
+		}
+	}
+
+	if cfg.AdminServer != nil {
+		cfg.AdminServer.SetFileSystem(fs)
+	}
+
+	// Prefetch the metadata of the bucket, if asked to.
+	if syncerBucket.Bucket != nil {
+		switch cfg.MetadataPrefetchOnMount {
+		case config.ExperimentalMetadataPrefetchOnMountSynchronous:
+			if err := fs.prefetchMetadata(ctx, syncerBucket, cfg.StatCacheMaxSizeMB); err != nil {
+				fs.Destroy()
+				return nil, err
+			}
+		case config.ExperimentalMetadataPrefetchOnMountAsynchronous:
+			var prefetchCtx context.Context
+			prefetchCtx, fs.cancelPrefetch = context.WithCancel(context.Background())
+			go func() {
+				if err := fs.prefetchMetadata(prefetchCtx, syncerBucket, cfg.StatCacheMaxSizeMB); err != nil {
+					logger.Errorf("Metadata-prefetch failed: %v", err)
+				}
+			}()
+		}
+	}
+
 	return fs, nil
 }
 
@@ -227,7 +309,7 @@ func createFileCacheHandler(cfg *ServerConfig) (fileCacheHandler *file.CacheHand
 	} else {
 		sizeInBytes = uint64(cfg.MountConfig.FileCacheConfig.MaxSizeMB) * cacheutil.MiB
 	}
-	fileInfoCache := lru.NewCache(sizeInBytes)
+	fileInfoCache := lru.NewNamedCache("file_info_cache", sizeInBytes)
 
 	cacheDir := string(cfg.MountConfig.CacheDir)
 	// Adding a new directory inside cacheDir to keep file-cache separate from
@@ -247,6 +329,16 @@ func createFileCacheHandler(cfg *ServerConfig) (fileCacheHandler *file.CacheHand
 		cfg.SequentialReadSizeMb, cfg.MountConfig.EnableCrcCheck)
 	fileCacheHandler = file.NewCacheHandler(fileInfoCache, jobManager,
 		cacheDir, filePerm, dirPerm)
+
+	// Size the cache dynamically from the free space of the filesystem holding
+	// cache-dir, if asked to.
+	if minFreeDiskPercent := cfg.MountConfig.FileCacheConfig.MinFreeDiskPercent; minFreeDiskPercent > 0 {
+		interval := time.Duration(cfg.MountConfig.FileCacheConfig.FreeDiskCheckIntervalSecs) * time.Second
+		err = fileCacheHandler.EnableFreeDiskWatermark(minFreeDiskPercent, sizeInBytes, interval)
+		if err != nil {
+			return nil, fmt.Errorf("createFileCacheHandler: while enabling free disk watermark: %w", err)
+		}
+	}
 	return
 }
 
@@ -275,6 +367,8 @@ func makeRootForBucket(
 		fs.mtimeClock,
 		fs.cacheClock,
 		fs.mountConfig.MetadataCacheConfig.TypeCacheMaxSizeMB,
+		fs.listingCache,
+		fs.sharedMetadataCache,
 	)
 }
 
@@ -349,6 +443,14 @@ type fileSystem struct {
 	// of next list call) from user, asks the kernel to evict the old cache entries.
 	kernelListCacheTTL time.Duration
 
+	// listingCache is the state shared by the listing caches of directory
+	// inodes, which serve their listings from memory. Nil disables them.
+	listingCache *inode.ListingCache
+
+	// cancelListingCache stops the background refreshes of listings. It is nil
+	// unless listingCache is set.
+	cancelListingCache context.CancelFunc
+
 	renameDirLimit       int64
 	sequentialReadSizeMb int32
 
@@ -469,6 +571,34 @@ type fileSystem struct {
 	// cacheFileForRangeRead when true downloads file into cache even for
 	// random file access.
 	cacheFileForRangeRead bool
+
+	// sharedMetadataCache, if non-nil, holds the type caches of the directory
+	// inodes.
+	sharedMetadataCache *shared.Client
+
+	// accountant, if non-nil, accounts the accesses to each file and directory
+	// by the local name of its inode.
+	accountant *accounting.Accountant
+
+	// auditLogger, if non-nil, records the operations changing files and
+	// directories by the local name of their inode.
+	auditLogger *audit.Logger
+
+	// invalidationConsumer invalidates cached state of objects that changed in
+	// GCS, as reported by object change notifications. It is nil unless a
+	// notification source is configured.
+	invalidationConsumer *invalidation.Consumer
+
+	// cancelPrefetch stops asynchronous metadata prefetching. It is nil unless
+	// prefetching runs asynchronously.
+	cancelPrefetch context.CancelFunc
+
+	// The types found by metadata prefetching for the children of directories
+	// that had no inode yet, to be inserted in their type caches when their
+	// inodes are minted. Nil if there are none left.
+	//
+	// GUARDED_BY(mu)
+	prefetchedTypes *prefetch.Result
 }
 
 ////////////////////////////////////////////////////////////////////////
@@ -704,7 +834,9 @@ func (fs *fileSystem) mintInode(ic inode.Core) (in inode.Inode) {
 			ic.Bucket,
 			fs.mtimeClock,
 			fs.cacheClock,
-			fs.mountConfig.MetadataCacheConfig.TypeCacheMaxSizeMB)
+			fs.mountConfig.MetadataCacheConfig.TypeCacheMaxSizeMB,
+			fs.listingCache,
+			fs.sharedMetadataCache)
 
 		// Implicit directories
 	case ic.FullName.IsDir():
@@ -728,7 +860,9 @@ func (fs *fileSystem) mintInode(ic inode.Core) (in inode.Inode) {
 			ic.Bucket,
 			fs.mtimeClock,
 			fs.cacheClock,
-			fs.mountConfig.MetadataCacheConfig.TypeCacheMaxSizeMB)
+			fs.mountConfig.MetadataCacheConfig.TypeCacheMaxSizeMB,
+			fs.listingCache,
+			fs.sharedMetadataCache)
 
 	case inode.IsSymlink(ic.MinObject):
 		in = inode.NewSymlinkInode(
@@ -758,6 +892,14 @@ func (fs *fileSystem) mintInode(ic inode.Core) (in inode.Inode) {
 			ic.Local)
 	}
 
+	// Hand it the types prefetched for its children, if any. As the inode isn't
+	// reachable by anyone else yet, there's no need to lock it.
+	if d, ok := in.(inode.DirInode); ok {
+		if types, listingTime := fs.takePrefetchedTypes(d.Name()); types != nil {
+			d.InsertIntoTypeCache(listingTime, types)
+		}
+	}
+
 	// Place it in our map of IDs to inodes.
 	fs.inodes[in.ID()] = in
 
@@ -1058,12 +1200,14 @@ func (fs *fileSystem) lookUpOrCreateChildDirInode(
 }
 
 // Synchronize the supplied file inode to GCS, updating the index as
-// appropriate.
+// appropriate. Writing the file back is audited on behalf of the process
+// described by opCtx.
 //
 // LOCKS_EXCLUDED(fs.mu)
 // LOCKS_REQUIRED(f)
 func (fs *fileSystem) syncFile(
 	ctx context.Context,
+	opCtx fuseops.OpContext,
 	f *inode.FileInode) (err error) {
 	// SyncFile can be triggered for unlinked files if the fileHandle is open by
 	// same or another user. Silently ignore the syncFile call.
@@ -1072,7 +1216,12 @@ func (fs *fileSystem) syncFile(
 		return
 	}
 
-	// Sync the inode.
+	// Sync the inode. Only the syncs writing the file back to GCS are audited.
+	if !f.SourceGenerationIsAuthoritative() {
+		defer func() {
+			fs.auditLogger.Record(opCtx, audit.OpWriteClose, f.Name().LocalName(), f.SourceGeneration().Object, err)
+		}()
+	}
 	err = f.Sync(ctx)
 	if err != nil {
 		err = fmt.Errorf("FileInode.Sync: %w", err)
@@ -1086,6 +1235,15 @@ func (fs *fileSystem) syncFile(
 	// Once the inode is synced to GCS, it is no longer an localFileInode.
 	// Delete the entry from localFileInodes map and add it to generationBackedInodes.
 	fs.mu.Lock()
+	if fs.listingCache != nil {
+		// The generation of the file changed in the cached descendants of its
+		// ancestors.
+		fs.listingCache.Invalidate()
+	}
+	if _, ok := fs.localFileInodes[f.Name()]; ok {
+		// The file now shows up in the listing of its parent.
+		fs.invalidateParentListingCache(f.Name())
+	}
 	delete(fs.localFileInodes, f.Name())
 	_, ok := fs.generationBackedInodes[f.Name()]
 	if !ok {
@@ -1267,6 +1425,32 @@ func (fs *fileSystem) symlinkInodeOrDie(
 	return
 }
 
+// invalidateParentListingCache drops the cached listing, if any, of the
+// parent directory of the given inode.
+//
+// LOCKS_REQUIRED(fs.mu)
+func (fs *fileSystem) invalidateParentListingCache(name inode.Name) {
+	objectName := strings.TrimSuffix(name.GcsObjectName(), "/")
+	parentName := inode.NewDescendantName(name, objectName[:strings.LastIndex(objectName, "/")+1])
+	if in, ok := fs.implicitDirInodes[parentName]; ok {
+		in.InvalidateListingCache()
+	} else if in, ok := fs.generationBackedInodes[parentName].(inode.DirInode); ok {
+		in.InvalidateListingCache()
+	}
+}
+
+// invalidateDescendantListingCaches drops the cached listings of the
+// directories below the given directory.
+//
+// LOCKS_REQUIRED(fs.mu)
+func (fs *fileSystem) invalidateDescendantListingCaches(name inode.Name) {
+	for _, in := range fs.inodes {
+		if d, ok := in.(inode.DirInode); ok && d.Name().IsDescendantOf(name) {
+			d.InvalidateListingCache()
+		}
+	}
+}
+
 // invalidateChildFileCacheIfExist invalidates the file in read cache. This is used to
 // invalidate the file in read cache after deletion of original file.
 //
@@ -1291,15 +1475,165 @@ func (fs *fileSystem) invalidateChildFileCacheIfExist(parentInode inode.DirInode
 	return nil
 }
 
+// InvalidateObject drops the cached stat, type and content of the given
+// object, so that the next access to it goes to GCS. It is called for object
+// change notifications.
+//
+// LOCKS_EXCLUDED(fs.mu)
+func (fs *fileSystem) InvalidateObject(bucketName, objectName string) {
+	name, ok := fs.bucketManager.InvalidateObject(bucketName, objectName)
+	if !ok || name == "" {
+		return
+	}
+
+	// Find the directories whose type cache may hold an entry for the object
+	// or for one of its implicit parent directories.
+	var dirs []inode.DirInode
+	var children []string
+	fs.mu.Lock()
+	inodeBucketName := ""
+	if _, ok := fs.inodes[fuseops.RootInodeID].(inode.BucketOwnedDirInode); !ok {
+		// In a mount of all accessible buckets, inode names carry the bucket name.
+		inodeBucketName = bucketName
+	}
+	root := inode.NewRootName(inodeBucketName)
+	parent := ""
+	for _, child := range strings.Split(strings.TrimSuffix(name, "/"), "/") {
+		dirName := inode.NewDescendantName(root, parent)
+		if in, ok := fs.implicitDirInodes[dirName]; ok {
+			dirs = append(dirs, in)
+			children = append(children, child)
+		} else if in, ok := fs.generationBackedInodes[dirName].(inode.DirInode); ok {
+			dirs = append(dirs, in)
+			children = append(children, child)
+		}
+		if fs.prefetchedTypes != nil {
+			delete(fs.prefetchedTypes.Types[parent], child)
+		}
+		parent += child + "/"
+	}
+	fs.mu.Unlock()
+
+	for i, d := range dirs {
+		d.Lock()
+		d.EraseFromTypeCache(children[i])
+		d.Unlock()
+		d.InvalidateListingCache()
+	}
+
+	if fs.fileCacheHandler != nil {
+		if err := fs.fileCacheHandler.InvalidateCache(name, bucketName); err != nil {
+			logger.Warnf("InvalidateObject: while invalidating the file cache for %s: %v", name, err)
+		}
+	}
+}
+
+// prefetchMetadata lists the whole bucket, so that the stat cache and the
+// type caches of the directory inodes are populated without going through the
+// kernel. It stops early, without failing, when the metadata fetched would no
+// longer fit in the stat cache.
+//
+// LOCKS_EXCLUDED(fs.mu)
+func (fs *fileSystem) prefetchMetadata(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	statCacheMaxSizeMB uint64) error {
+	if statCacheMaxSizeMB == 0 {
+		logger.Warnf("Skipping metadata-prefetch, as the stat cache is disabled")
+		return nil
+	}
+
+	logger.Infof("Started metadata-prefetch of bucket %q ...", bucket.Name())
+	start := time.Now()
+	result, err := prefetch.Run(ctx, prefetch.Config{
+		Bucket:       bucket,
+		ImplicitDirs: fs.implicitDirs,
+		MaxSizeBytes: util.MiBsToBytes(statCacheMaxSizeMB),
+		Clock:        fs.cacheClock,
+	})
+	if err != nil {
+		return fmt.Errorf("metadata-prefetch of bucket %q: %w", bucket.Name(), err)
+	}
+
+	if result.Truncated {
+		logger.Warnf("Metadata-prefetch stopped after %d objects, as the stat cache is full", result.Objects)
+	}
+	logger.Infof("... Completed metadata-prefetch of bucket %q in %v. Number of objects discovered: %d",
+		bucket.Name(), time.Since(start), result.Objects)
+
+	// Directories minted from now on pick up their types when minted, while
+	// the existing ones get them below.
+	var dirs []inode.DirInode
+	fs.mu.Lock()
+	fs.prefetchedTypes = result
+	for _, in := range fs.inodes {
+		if d, ok := in.(inode.DirInode); ok {
+			dirs = append(dirs, d)
+		}
+	}
+	fs.mu.Unlock()
+
+	for _, d := range dirs {
+		d.Lock()
+		fs.mu.Lock()
+		types, listingTime := fs.takePrefetchedTypes(d.Name())
+		fs.mu.Unlock()
+		d.InsertIntoTypeCache(listingTime, types)
+		d.Unlock()
+	}
+
+	return nil
+}
+
+// takePrefetchedTypes removes and returns the prefetched types of the children
+// of the given directory, along with the time they were listed at, if any.
+//
+// LOCKS_REQUIRED(fs.mu)
+func (fs *fileSystem) takePrefetchedTypes(dirName inode.Name) (types map[string]metadata.Type, listingTime time.Time) {
+	if fs.prefetchedTypes == nil {
+		return
+	}
+
+	// Drop all of them once they have expired anyway.
+	listingTime = fs.prefetchedTypes.Time
+	if fs.cacheClock.Now().After(listingTime.Add(fs.dirTypeCacheTTL)) {
+		fs.prefetchedTypes = nil
+		return
+	}
+
+	types = fs.prefetchedTypes.Types[dirName.GcsObjectName()]
+	delete(fs.prefetchedTypes.Types, dirName.GcsObjectName())
+	if len(fs.prefetchedTypes.Types) == 0 {
+		fs.prefetchedTypes = nil
+	}
+
+	return
+}
+
 ////////////////////////////////////////////////////////////////////////
 // fuse.FileSystem methods
 ////////////////////////////////////////////////////////////////////////
 
 func (fs *fileSystem) Destroy() {
+	if fs.cancelPrefetch != nil {
+		fs.cancelPrefetch()
+	}
+	if fs.cancelListingCache != nil {
+		fs.cancelListingCache()
+	}
+	if fs.invalidationConsumer != nil {
+		fs.invalidationConsumer.Stop()
+	}
 	fs.bucketManager.ShutDown()
+	if fs.sharedMetadataCache != nil {
+		fs.sharedMetadataCache.Close()
+	}
 	if fs.fileCacheHandler != nil {
 		_ = fs.fileCacheHandler.Destroy()
 	}
+	if err := fs.auditLogger.Close(); err != nil {
+		logger.Errorf("Failed to close the audit log: %v", err)
+	}
 }
 
 func (fs *fileSystem) StatFS(
@@ -1347,6 +1681,7 @@ func (fs *fileSystem) LookUpInode(
 	}
 
 	defer fs.unlockAndMaybeDisposeOfInode(child, &err)
+	fs.accountant.RecordOp(child.Name().LocalName(), "LookUpInode")
 
 	// Fill out the response.
 	e := &op.Entry
@@ -1407,6 +1742,13 @@ func (fs *fileSystem) SetInodeAttributes(
 	in.Lock()
 	defer in.Unlock()
 	file, isFile := in.(*inode.FileInode)
+	defer func() {
+		var generation int64
+		if isFile {
+			generation = file.SourceGeneration().Object
+		}
+		fs.auditLogger.Record(op.OpContext, audit.OpSetAttr, in.Name().LocalName(), generation, err)
+	}()
 
 	// Set file mtimes.
 	if isFile && op.Mtime != nil {
@@ -1470,6 +1812,11 @@ func (fs *fileSystem) MkDir(
 	parent := fs.dirInodeOrDie(op.Parent)
 	fs.mu.Unlock()
 
+	var generation int64
+	defer func() {
+		fs.auditLogger.Record(op.OpContext, audit.OpMkDir, inode.NewDirName(parent.Name(), op.Name).LocalName(), generation, err)
+	}()
+
 	// Create an empty backing object for the child, failing if it already
 	// exists.
 	parent.Lock()
@@ -1488,6 +1835,7 @@ func (fs *fileSystem) MkDir(
 		err = fmt.Errorf("CreateChildDir: %w", err)
 		return err
 	}
+	generation = result.MinObject.Generation
 
 	// Attempt to create a child inode using the object we created. If we fail to
 	// do so, it means someone beat us to the punch with a newer generation
@@ -1663,10 +2011,18 @@ func (fs *fileSystem) CreateFile(
 	}
 
 	if err != nil {
+		if fs.auditLogger != nil {
+			fs.mu.Lock()
+			name := inode.NewFileName(fs.dirInodeOrDie(op.Parent).Name(), op.Name)
+			fs.mu.Unlock()
+			fs.auditLogger.Record(op.OpContext, audit.OpCreate, name.LocalName(), 0, err)
+		}
 		return err
 	}
 
 	defer fs.unlockAndMaybeDisposeOfInode(child, &err)
+	fs.accountant.RecordOp(child.Name().LocalName(), "CreateFile")
+	fs.auditLogger.Record(op.OpContext, audit.OpCreate, child.Name().LocalName(), child.(*inode.FileInode).SourceGeneration().Object, nil)
 
 	// Allocate a handle.
 	fs.mu.Lock()
@@ -1778,6 +2134,10 @@ func (fs *fileSystem) RmDir(
 	parent := fs.dirInodeOrDie(op.Parent)
 	fs.mu.Unlock()
 
+	defer func() {
+		fs.auditLogger.Record(op.OpContext, audit.OpRmDir, inode.NewDirName(parent.Name(), op.Name).LocalName(), 0, err)
+	}()
+
 	// Find or create the child inode, locked.
 	child, err := fs.lookUpOrCreateChildInode(ctx, parent, op.Name)
 	if err != nil {
@@ -1881,6 +2241,13 @@ func (fs *fileSystem) Rename(
 	newParent := fs.dirInodeOrDie(op.NewParent)
 	fs.mu.Unlock()
 
+	oldName := inode.NewFileName(oldParent.Name(), op.OldName)
+	newName := inode.NewFileName(newParent.Name(), op.NewName)
+	var generation int64
+	defer func() {
+		fs.auditLogger.RecordRename(op.OpContext, oldName.LocalName(), newName.LocalName(), generation, err)
+	}()
+
 	if oldInode, ok := oldParent.(inode.BucketOwnedInode); !ok {
 		// The old parent is not owned by any bucket, which means it's the base
 		// directory that holds all the buckets' root directories. So, this op
@@ -1917,11 +2284,16 @@ func (fs *fileSystem) Rename(
 	}
 
 	if child.FullName.IsDir() {
+		oldName = inode.NewDirName(oldParent.Name(), op.OldName)
+		newName = inode.NewDirName(newParent.Name(), op.NewName)
 		return fs.renameDir(ctx, oldParent, op.OldName, newParent, op.NewName)
 	}
-	return fs.renameFile(ctx, oldParent, op.OldName, child.MinObject, newParent, op.NewName)
+	generation, err = fs.renameFile(ctx, oldParent, op.OldName, child.MinObject, newParent, op.NewName)
+	return err
 }
 
+// Rename a file, returning the generation of the object at its new location.
+//
 // LOCKS_EXCLUDED(fs.mu)
 // LOCKS_EXCLUDED(oldParent)
 // LOCKS_EXCLUDED(newParent)
@@ -1931,16 +2303,17 @@ func (fs *fileSystem) renameFile(
 	oldName string,
 	oldObject *gcs.MinObject,
 	newParent inode.DirInode,
-	newFileName string) error {
+	newFileName string) (int64, error) {
 	// Clone into the new location.
 	newParent.Lock()
-	_, err := newParent.CloneToChildFile(ctx, newFileName, oldObject)
+	newFile, err := newParent.CloneToChildFile(ctx, newFileName, oldObject)
 	newParent.Unlock()
 
 	if err != nil {
 		err = fmt.Errorf("CloneToChildFile: %w", err)
-		return err
+		return 0, err
 	}
+	generation := newFile.MinObject.Generation
 
 	// Delete behind. Make sure to delete exactly the generation we cloned, in
 	// case the referent of the name has changed in the meantime.
@@ -1952,17 +2325,17 @@ func (fs *fileSystem) renameFile(
 		&oldObject.MetaGeneration)
 
 	if err := fs.invalidateChildFileCacheIfExist(oldParent, oldObject.Name); err != nil {
-		return fmt.Errorf("renameFile: while invalidating cache for delete file: %w", err)
+		return generation, fmt.Errorf("renameFile: while invalidating cache for delete file: %w", err)
 	}
 
 	oldParent.Unlock()
 
 	if err != nil {
 		err = fmt.Errorf("DeleteChildFile: %w", err)
-		return err
+		return generation, err
 	}
 
-	return nil
+	return generation, nil
 }
 
 // Rename an old directory to a new directory. If the new directory already
@@ -2066,6 +2439,12 @@ func (fs *fileSystem) renameDir(
 		}
 	}
 
+	// The subdirectories of both directories changed too.
+	fs.mu.Lock()
+	fs.invalidateDescendantListingCaches(oldDir.Name())
+	fs.invalidateDescendantListingCaches(newDir.Name())
+	fs.mu.Unlock()
+
 	// We are done with both directories.
 	releaseInodes()
 
@@ -2101,6 +2480,12 @@ func (fs *fileSystem) Unlink(
 
 	// if inode is a local file, mark it unlinked.
 	fileName := inode.NewFileName(parent.Name(), op.Name)
+	defer func() {
+		if err == nil {
+			fs.accountant.RecordOp(fileName.LocalName(), "Unlink")
+		}
+		fs.auditLogger.Record(op.OpContext, audit.OpUnlink, fileName.LocalName(), 0, err)
+	}()
 	fs.mu.Lock()
 	fileInode, ok := fs.localFileInodes[fileName]
 	if ok {
@@ -2154,6 +2539,7 @@ func (fs *fileSystem) OpenDir(
 
 	fs.handles[handleID] = handle.NewDirHandle(in, fs.implicitDirs)
 	op.Handle = handleID
+	fs.accountant.RecordOp(in.Name().LocalName(), "OpenDir")
 
 	// Enables kernel list-cache in case of non-zero kernelListCacheTTL.
 	if fs.kernelListCacheTTL > 0 {
@@ -2190,6 +2576,7 @@ func (fs *fileSystem) ReadDir(
 	// we need fs lock to fetch local file entries.
 	localFileEntries := in.LocalFileEntries(fs.localFileInodes)
 	fs.mu.Unlock()
+	fs.accountant.RecordOp(in.Name().LocalName(), "ReadDir")
 
 	dh.Mu.Lock()
 	defer dh.Mu.Unlock()
@@ -2233,6 +2620,7 @@ func (fs *fileSystem) OpenFile(
 
 	fs.handles[handleID] = handle.NewFileHandle(in, fs.fileCacheHandler, fs.cacheFileForRangeRead)
 	op.Handle = handleID
+	fs.accountant.RecordOp(in.Name().LocalName(), "OpenFile")
 
 	// When we observe object generations that we didn't create, we assign them
 	// new inode IDs. So for a given inode, all modifications go through the
@@ -2266,7 +2654,13 @@ func (fs *fileSystem) ReadFile(
 	defer fh.Unlock()
 
 	// Serve the read.
-	op.BytesRead, err = fh.Read(ctx, op.Dst, op.Offset, fs.sequentialReadSizeMb)
+	var cacheHit bool
+	op.BytesRead, cacheHit, err = fh.Read(ctx, op.Dst, op.Offset, fs.sequentialReadSizeMb)
+	if fs.accountant != nil {
+		path := fh.Inode().Name().LocalName()
+		fs.accountant.RecordOp(path, "ReadFile")
+		fs.accountant.RecordRead(path, op.BytesRead, fs.fileCacheHandler != nil, cacheHit)
+	}
 
 	// As required by fuse, we don't treat EOF as an error.
 	if err == io.EOF {
@@ -2314,9 +2708,11 @@ func (fs *fileSystem) WriteFile(
 	defer in.Unlock()
 
 	// Serve the request.
+	fs.accountant.RecordOp(in.Name().LocalName(), "WriteFile")
 	if err := in.Write(ctx, op.Data, op.Offset); err != nil {
 		return err
 	}
+	fs.accountant.RecordWrite(in.Name().LocalName(), len(op.Data))
 
 	return
 }
@@ -2345,9 +2741,10 @@ func (fs *fileSystem) SyncFile(
 
 	file.Lock()
 	defer file.Unlock()
+	fs.accountant.RecordOp(file.Name().LocalName(), "SyncFile")
 
 	// Sync it.
-	if err := fs.syncFile(ctx, file); err != nil {
+	if err := fs.syncFile(ctx, op.OpContext, file); err != nil {
 		return err
 	}
 
@@ -2372,9 +2769,10 @@ func (fs *fileSystem) FlushFile(
 
 	in.Lock()
 	defer in.Unlock()
+	fs.accountant.RecordOp(in.Name().LocalName(), "FlushFile")
 
 	// Sync it.
-	if err := fs.syncFile(ctx, in); err != nil {
+	if err := fs.syncFile(ctx, op.OpContext, in); err != nil {
 		return err
 	}
 
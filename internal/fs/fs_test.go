@@ -26,9 +26,11 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/lru"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/config"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/fs"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/gcsx"
@@ -326,6 +328,20 @@ type fakeBucketManager struct {
 
 func (bm *fakeBucketManager) ShutDown() {}
 
+func (bm *fakeBucketManager) StatCache() *lru.Cache {
+	return nil
+}
+
+// The buckets aren't listed, as without dynamic-mount: list-buckets-project.
+func (bm *fakeBucketManager) ListBuckets(ctx context.Context) ([]string, error) {
+	return nil, syscall.ENOTSUP
+}
+
+func (bm *fakeBucketManager) InvalidateObject(bucketName, objectName string) (string, bool) {
+	_, ok := bm.buckets[bucketName]
+	return objectName, ok
+}
+
 func (bm *fakeBucketManager) SetUpBucket(
 	ctx context.Context,
 	name string, isMultibucketMount bool) (sb gcsx.SyncerBucket, err error) {
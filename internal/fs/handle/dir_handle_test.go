@@ -79,7 +79,9 @@ func (t *DirHandleTest) resetDirHandle() {
 		&t.bucket,
 		&t.clock,
 		&t.clock,
-		0)
+		0,
+		nil,
+		nil)
 
 	t.dh = NewDirHandle(
 		dirInode,
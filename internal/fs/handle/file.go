@@ -81,11 +81,12 @@ func (fh *FileHandle) Unlock() {
 }
 
 // Equivalent to locking fh.Inode() and calling fh.Inode().Read, but may be
-// more efficient.
+// more efficient. cacheHit says whether the contents were read from the file
+// cache.
 //
 // LOCKS_REQUIRED(fh)
 // LOCKS_EXCLUDED(fh.inode)
-func (fh *FileHandle) Read(ctx context.Context, dst []byte, offset int64, sequentialReadSizeMb int32) (n int, err error) {
+func (fh *FileHandle) Read(ctx context.Context, dst []byte, offset int64, sequentialReadSizeMb int32) (n int, cacheHit bool, err error) {
 	// Lock the inode and attempt to ensure that we have a reader for its current
 	// state, or clear fh.reader if it's not possible to create one (probably
 	// because the inode is dirty).
@@ -104,7 +105,7 @@ func (fh *FileHandle) Read(ctx context.Context, dst []byte, offset int64, sequen
 	if fh.reader != nil {
 		fh.inode.Unlock()
 
-		n, _, err = fh.reader.ReadAt(ctx, dst, offset)
+		n, cacheHit, err = fh.reader.ReadAt(ctx, dst, offset)
 		switch {
 		case err == io.EOF:
 			return
@@ -15,9 +15,10 @@
 package inode
 
 import (
-	"syscall"
+	"fmt"
 	"time"
 
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/metadata"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/locker"
 
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
@@ -178,12 +179,23 @@ func (d *baseDirInode) ReadDescendants(ctx context.Context, limit int) (map[Name
 func (d *baseDirInode) ReadEntries(
 	ctx context.Context,
 	tok string) (entries []fuseutil.Dirent, newTok string, err error) {
+	// The subdirectories of the base directory are the buckets of the project
+	// configured to be listed, if any, and otherwise aren't listed: the user
+	// can still visit each bucket by name.
+	names, err := d.bucketManager.ListBuckets(ctx)
+	if err != nil {
+		err = fmt.Errorf("ListBuckets: %w", err)
+		return
+	}
+
+	for _, name := range names {
+		entries = append(entries, fuseutil.Dirent{
+			Name: name,
+			Type: fuseutil.DT_Directory,
+		})
+	}
 
-	// The subdirectories of the base directory should be all the accessible
-	// buckets. Although the user is allowed to visit each individual
-	// subdirectory, listing all the subdirectories (i.e. the buckets) can be
-	// very expensive and currently not supported.
-	return nil, "", syscall.ENOTSUP
+	return
 }
 
 ////////////////////////////////////////////////////////////////////////
@@ -237,8 +249,26 @@ func (d *baseDirInode) LocalFileEntries(localFileInodes map[Name]Inode) (localEn
 	return nil
 }
 
+func (d *baseDirInode) EraseFromTypeCache(name string) {
+	// baseDirInode has no type cache; buckets are looked up through the bucket
+	// manager.
+}
+
+func (d *baseDirInode) TypeCacheEntries() []metadata.EntryInfo {
+	return nil
+}
+
+func (d *baseDirInode) TypeCacheLen() int {
+	return 0
+}
+
+func (d *baseDirInode) InsertIntoTypeCache(now time.Time, types map[string]metadata.Type) {
+}
+
+func (d *baseDirInode) InvalidateListingCache() {
+}
+
 func (d *baseDirInode) ShouldInvalidateKernelListCache(ttl time.Duration) bool {
-	// Keeping the default behavior although list operation is not supported
-	// for baseDirInode.
+	// The listing of the buckets is cached by the bucket manager instead.
 	return true
 }
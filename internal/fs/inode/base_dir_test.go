@@ -15,17 +15,21 @@
 package inode
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/lru"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/metadata"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/fake"
 	"golang.org/x/net/context"
 
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/gcsx"
 	"github.com/jacobsa/fuse/fuseops"
+	"github.com/jacobsa/fuse/fuseutil"
 	. "github.com/jacobsa/ogletest"
 	"github.com/jacobsa/timeutil"
 )
@@ -82,6 +86,10 @@ func (t *BaseDirTest) TearDown() {
 type fakeBucketManager struct {
 	buckets    map[string]gcsx.SyncerBucket
 	setupTimes int
+
+	// The result of ListBuckets.
+	bucketNames []string
+	listErr     error
 }
 
 func (bm *fakeBucketManager) SetUpBucket(
@@ -98,8 +106,20 @@ func (bm *fakeBucketManager) SetUpBucket(
 	return
 }
 
+func (bm *fakeBucketManager) InvalidateObject(bucketName string, objectName string) (string, bool) {
+	return objectName, true
+}
+
 func (bm *fakeBucketManager) ShutDown() {}
 
+func (bm *fakeBucketManager) StatCache() *lru.Cache {
+	return nil
+}
+
+func (bm *fakeBucketManager) ListBuckets(ctx context.Context) ([]string, error) {
+	return bm.bucketNames, bm.listErr
+}
+
 func (bm *fakeBucketManager) SetUpTimes() int {
 	return bm.setupTimes
 }
@@ -200,6 +220,30 @@ func (t *BaseDirTest) LookUpChild_BucketCached() {
 	ExpectEq(3, t.bm.SetUpTimes())
 }
 
+func (t *BaseDirTest) ReadEntries() {
+	t.bm.bucketNames = []string{"bucketA", "bucketB"}
+
+	entries, tok, err := t.in.ReadEntries(t.ctx, "")
+
+	AssertEq(nil, err)
+	ExpectEq("", tok)
+	AssertEq(2, len(entries))
+	ExpectEq("bucketA", entries[0].Name)
+	ExpectEq(fuseutil.DT_Directory, entries[0].Type)
+	ExpectEq("bucketB", entries[1].Name)
+	ExpectEq(fuseutil.DT_Directory, entries[1].Type)
+	// Listing doesn't set up the buckets.
+	ExpectEq(0, t.bm.SetUpTimes())
+}
+
+func (t *BaseDirTest) ReadEntries_NotSupported() {
+	t.bm.listErr = syscall.ENOTSUP
+
+	_, _, err := t.in.ReadEntries(t.ctx, "")
+
+	ExpectTrue(errors.Is(err, syscall.ENOTSUP))
+}
+
 func (t *BaseDirTest) Test_ShouldInvalidateKernelListCache() {
 	ttl := time.Second
 	AssertEq(true, t.in.ShouldInvalidateKernelListCache(ttl))
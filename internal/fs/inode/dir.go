@@ -17,13 +17,17 @@ package inode
 import (
 	"errors"
 	"fmt"
+	"maps"
 	"path"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/metadata"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/metadata/shared"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/gcsx"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/locker"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/storageutil"
 	"github.com/jacobsa/fuse/fuseops"
@@ -144,6 +148,27 @@ type DirInode interface {
 	// should be invalidated or not.
 	ShouldInvalidateKernelListCache(ttl time.Duration) bool
 
+	// EraseFromTypeCache erases the type-cache entry, if any, for the direct
+	// child with the given (relative) name.
+	EraseFromTypeCache(name string)
+
+	// TypeCacheEntries returns the entries of the type cache of the directory.
+	// Unlike most methods, it may be called without holding the inode lock.
+	TypeCacheEntries() []metadata.EntryInfo
+
+	// TypeCacheLen returns the number of entries of the type cache of the
+	// directory. It may be called without holding the inode lock.
+	TypeCacheLen() int
+
+	// InsertIntoTypeCache records the types of the given direct children, as
+	// learned at the given time, e.g. by metadata prefetching.
+	InsertIntoTypeCache(now time.Time, types map[string]metadata.Type)
+
+	// InvalidateListingCache drops the cached listing of the directory, if
+	// any, so that it is listed from GCS on the next ReadEntries. Unlike most
+	// methods, it may be called without holding the inode lock.
+	InvalidateListingCache()
+
 	// RLock readonly lock.
 	RLock()
 
@@ -194,7 +219,7 @@ type dirInode struct {
 
 	// cache.CheckInvariants() does not panic.
 	//
-	// GUARDED_BY(mu)
+	// GUARDED_BY(mu), except for cache.Entries and cache.Len.
 	cache metadata.TypeCache
 
 	// prevDirListingTimeStamp is the time stamp of previous listing when user asked
@@ -202,6 +227,86 @@ type dirInode struct {
 	// Specially used when kernelListCacheTTL > 0 that means kernel list-cache is
 	// enabled.
 	prevDirListingTimeStamp *time.Time
+
+	// listingCache holds the state shared with the listing caches of the
+	// other directories of the file system. Nil disables the listing cache.
+	listingCache *ListingCache
+
+	// listing is the last full listing of the directory, if any. It is only
+	// valid while its generation matches listingGeneration.
+	//
+	// GUARDED_BY(mu)
+	listing *cachedListing
+
+	// listingGeneration is incremented whenever the directory is known to have
+	// changed, invalidating the cached listing.
+	listingGeneration atomic.Uint64
+
+	// refreshingListing is true while a background refresh of listing is in
+	// flight.
+	//
+	// GUARDED_BY(mu)
+	refreshingListing bool
+
+	// descendants is the last result of ReadDescendants, if any. It is only
+	// valid while its generation matches the generation of listingCache.
+	//
+	// GUARDED_BY(mu)
+	descendants *cachedDescendants
+}
+
+// ListingCache is the state shared by the listing caches of the directories
+// of a file system.
+type ListingCache struct {
+	// The time for which listings are served from the cache.
+	ttl time.Duration
+
+	// ctx bounds the background refreshes of listings. Cancelling it, e.g. when
+	// the file system is unmounted, stops them.
+	ctx context.Context
+
+	// generation is incremented whenever anything is changed through the file
+	// system. A change to any descendant of a directory changes its cached
+	// descendants, so they are invalidated by any change.
+	generation atomic.Uint64
+}
+
+// NewListingCache returns the state of listing caches serving listings for
+// the given TTL, refreshed in the background until ctx is cancelled.
+func NewListingCache(ctx context.Context, ttl time.Duration) *ListingCache {
+	return &ListingCache{ttl: ttl, ctx: ctx}
+}
+
+// Invalidate drops the cached descendants of all the directories.
+func (c *ListingCache) Invalidate() {
+	c.generation.Add(1)
+}
+
+// cachedListing is a full listing of a directory.
+type cachedListing struct {
+	entries []fuseutil.Dirent
+
+	// The time at which the listing started.
+	time time.Time
+
+	// The value of listingGeneration when the listing started.
+	generation uint64
+}
+
+// cachedDescendants is a result of ReadDescendants.
+type cachedDescendants struct {
+	descendants map[Name]*Core
+
+	// The limit the descendants were read with. The result is complete if it
+	// has fewer descendants than that.
+	limit int
+
+	// The time at which the listing started.
+	time time.Time
+
+	// The value of the generation of the ListingCache when the listing
+	// started.
+	generation uint64
 }
 
 var _ DirInode = &dirInode{}
@@ -222,6 +327,17 @@ var _ DirInode = &dirInode{}
 // child is removed and recreated with a different type before the expiration,
 // we may fail to find it.
 //
+// If sharedMetadataCache is non-nil, that cache is kept in the shared metadata
+// cache daemon instead of in this inode, so that it outlives the inode and is
+// shared with the other mounts of the bucket on the host.
+//
+// If listingCache is non-nil, ReadEntries returns the full listing at once
+// and caches it for the TTL of listingCache, and so does ReadDescendants.
+// Listings older than half the TTL are refreshed in the background when they
+// are served. Changes made through this inode invalidate the cached listing,
+// and changes made through the file system invalidate the cached descendants,
+// but changes made by other clients are not seen until they expire.
+//
 // The initial lookup count is zero.
 //
 // REQUIRES: name.IsDir()
@@ -236,12 +352,21 @@ func NewDirInode(
 	bucket *gcsx.SyncerBucket,
 	mtimeClock timeutil.Clock,
 	cacheClock timeutil.Clock,
-	typeCacheMaxSizeMB int) (d DirInode) {
+	typeCacheMaxSizeMB int,
+	listingCache *ListingCache,
+	sharedMetadataCache *shared.Client) (d DirInode) {
 
 	if !name.IsDir() {
 		panic(fmt.Sprintf("Unexpected name: %s", name))
 	}
 
+	var typeCache metadata.TypeCache
+	if sharedMetadataCache != nil && typeCacheTTL > 0 && typeCacheMaxSizeMB != 0 {
+		typeCache = sharedMetadataCache.NewTypeCache(bucket.Name(), name.GcsObjectName(), typeCacheTTL)
+	} else {
+		typeCache = metadata.NewTypeCache(typeCacheMaxSizeMB, typeCacheTTL)
+	}
+
 	typed := &dirInode{
 		bucket:                      bucket,
 		mtimeClock:                  mtimeClock,
@@ -252,7 +377,8 @@ func NewDirInode(
 		enableNonexistentTypeCache:  enableNonexistentTypeCache,
 		name:                        name,
 		attrs:                       attrs,
-		cache:                       metadata.NewTypeCache(typeCacheMaxSizeMB, typeCacheTTL),
+		cache:                       typeCache,
+		listingCache:                listingCache,
 	}
 
 	typed.lc.Init(id)
@@ -546,6 +672,43 @@ func (d *dirInode) LookUpChild(ctx context.Context, name string) (*Core, error)
 
 // LOCKS_REQUIRED(d)
 func (d *dirInode) ReadDescendants(ctx context.Context, limit int) (map[Name]*Core, error) {
+	if d.listingCache == nil {
+		return d.listDescendants(ctx, limit)
+	}
+
+	now := d.cacheClock.Now()
+	generation := d.listingCache.generation.Load()
+	if l := d.descendants; l != nil && l.generation == generation && now.Sub(l.time) < d.listingCache.ttl {
+		if len(l.descendants) < l.limit || limit <= len(l.descendants) {
+			descendants := make(map[Name]*Core, limit)
+			for name, c := range l.descendants {
+				if len(descendants) >= limit {
+					break
+				}
+				descendants[name] = c
+			}
+			return descendants, nil
+		}
+	}
+
+	descendants, err := d.listDescendants(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	d.descendants = &cachedDescendants{
+		descendants: descendants,
+		limit:       limit,
+		time:        now,
+		generation:  generation,
+	}
+	// The caller may modify the result.
+	return maps.Clone(descendants), nil
+}
+
+// listDescendants lists up to limit objects below the directory.
+//
+// LOCKS_REQUIRED(d)
+func (d *dirInode) listDescendants(ctx context.Context, limit int) (map[Name]*Core, error) {
 	var tok string
 	descendants := make(map[Name]*Core)
 	for {
@@ -585,6 +748,31 @@ func (d *dirInode) ReadDescendants(ctx context.Context, limit int) (map[Name]*Co
 
 // LOCKS_REQUIRED(d)
 func (d *dirInode) readObjects(
+	ctx context.Context,
+	tok string) (cores map[Name]*Core, newTok string, err error) {
+	cores, newTok, err = d.listObjects(ctx, tok)
+	if err != nil {
+		return
+	}
+
+	d.insertTypes(cores)
+	return
+}
+
+// insertTypes records the types of the given children in the type cache.
+//
+// LOCKS_REQUIRED(d)
+func (d *dirInode) insertTypes(cores map[Name]*Core) {
+	types := make(map[string]metadata.Type, len(cores))
+	for fullName, c := range cores {
+		types[path.Base(fullName.LocalName())] = c.Type()
+	}
+	d.cache.InsertMultiple(d.cacheClock.Now(), types)
+}
+
+// listObjects lists one page of the children of the directory. It only reads
+// constant data of the inode, so it doesn't require the inode lock.
+func (d *dirInode) listObjects(
 	ctx context.Context,
 	tok string) (cores map[Name]*Core, newTok string, err error) {
 	// Ask the bucket to list some objects.
@@ -607,13 +795,6 @@ func (d *dirInode) readObjects(
 	}
 
 	cores = make(map[Name]*Core)
-	defer func() {
-		now := d.cacheClock.Now()
-		for fullName, c := range cores {
-			d.cache.Insert(now, path.Base(fullName.LocalName()), c.Type())
-		}
-	}()
-
 	for _, o := range listing.Objects {
 		// Skip empty results or the directory object backing this inode.
 		if o.Name == d.Name().GcsObjectName() || o.Name == "" {
@@ -669,9 +850,15 @@ func (d *dirInode) readObjects(
 	return
 }
 
+// LOCKS_REQUIRED(d)
 func (d *dirInode) ReadEntries(
 	ctx context.Context,
 	tok string) (entries []fuseutil.Dirent, newTok string, err error) {
+	if d.listingCache != nil && tok == "" {
+		entries, err = d.readEntriesFromListingCache(ctx)
+		return
+	}
+
 	var cores map[Name]*Core
 	cores, newTok, err = d.readObjects(ctx, tok)
 	if err != nil {
@@ -679,6 +866,14 @@ func (d *dirInode) ReadEntries(
 		return
 	}
 
+	entries = coresToEntries(cores)
+
+	nowTime := d.cacheClock.Now()
+	d.prevDirListingTimeStamp = &nowTime
+	return
+}
+
+func coresToEntries(cores map[Name]*Core) (entries []fuseutil.Dirent) {
 	for fullName, core := range cores {
 		entry := fuseutil.Dirent{
 			Name: path.Base(fullName.LocalName()),
@@ -694,14 +889,107 @@ func (d *dirInode) ReadEntries(
 		}
 		entries = append(entries, entry)
 	}
+	return
+}
 
-	nowTime := d.cacheClock.Now()
-	d.prevDirListingTimeStamp = &nowTime
+// listAllObjects lists all the children of the directory. Like listObjects,
+// it doesn't require the inode lock.
+func (d *dirInode) listAllObjects(ctx context.Context) (cores map[Name]*Core, err error) {
+	cores = make(map[Name]*Core)
+	var tok string
+	for {
+		var page map[Name]*Core
+		page, tok, err = d.listObjects(ctx, tok)
+		if err != nil {
+			return nil, err
+		}
+
+		for name, c := range page {
+			// As in a single page, a directory takes precedence over a file of
+			// the same name.
+			if existing, ok := cores[name]; ok && existing.Type() == metadata.ExplicitDirType {
+				continue
+			}
+			cores[name] = c
+		}
+
+		if tok == "" {
+			return cores, nil
+		}
+	}
+}
+
+// readEntriesFromListingCache returns the full listing of the directory,
+// from the listing cache if it is fresh enough, and from GCS otherwise.
+//
+// LOCKS_REQUIRED(d)
+func (d *dirInode) readEntriesFromListingCache(ctx context.Context) (entries []fuseutil.Dirent, err error) {
+	now := d.cacheClock.Now()
+	generation := d.listingGeneration.Load()
+
+	if l := d.listing; l != nil && l.generation == generation {
+		age := now.Sub(l.time)
+		if age < d.listingCache.ttl {
+			if age >= d.listingCache.ttl/2 && !d.refreshingListing {
+				d.refreshingListing = true
+				go d.refreshListing(generation)
+			}
+
+			listingTime := l.time
+			d.prevDirListingTimeStamp = &listingTime
+			// The caller may modify the entries, e.g. to fix conflicting names.
+			return append([]fuseutil.Dirent(nil), l.entries...), nil
+		}
+	}
+
+	cores, err := d.listAllObjects(ctx)
+	if err != nil {
+		err = fmt.Errorf("list objects: %w", err)
+		return
+	}
+	d.insertTypes(cores)
+
+	entries = coresToEntries(cores)
+	d.listing = &cachedListing{
+		entries:    append([]fuseutil.Dirent(nil), entries...),
+		time:       now,
+		generation: generation,
+	}
+	d.prevDirListingTimeStamp = &now
 	return
 }
 
+// refreshListing lists the directory and replaces the cached listing, unless
+// the directory has changed since the refresh was started.
+//
+// LOCKS_EXCLUDED(d)
+func (d *dirInode) refreshListing(generation uint64) {
+	start := d.cacheClock.Now()
+	cores, err := d.listAllObjects(d.listingCache.ctx)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.refreshingListing = false
+	if err != nil {
+		logger.Warnf("Refreshing the listing of %q: %v", d.name.GcsObjectName(), err)
+		return
+	}
+	if d.listingGeneration.Load() != generation {
+		return
+	}
+
+	d.insertTypes(cores)
+	d.listing = &cachedListing{
+		entries:    coresToEntries(cores),
+		time:       start,
+		generation: generation,
+	}
+}
+
 // LOCKS_REQUIRED(d)
 func (d *dirInode) CreateChildFile(ctx context.Context, name string) (*Core, error) {
+	d.InvalidateListingCache()
 	childMetadata := map[string]string{
 		FileMtimeMetadataKey: d.mtimeClock.Now().UTC().Format(time.RFC3339Nano),
 	}
@@ -736,6 +1024,7 @@ func (d *dirInode) CreateLocalChildFile(name string) (*Core, error) {
 func (d *dirInode) CloneToChildFile(ctx context.Context, name string, src *gcs.MinObject) (*Core, error) {
 	// Erase any existing type information for this name.
 	d.cache.Erase(name)
+	d.InvalidateListingCache()
 	fullName := NewFileName(d.Name(), name)
 
 	// Clone over anything that might already exist for the name.
@@ -763,6 +1052,7 @@ func (d *dirInode) CloneToChildFile(ctx context.Context, name string, src *gcs.M
 
 // LOCKS_REQUIRED(d)
 func (d *dirInode) CreateChildSymlink(ctx context.Context, name string, target string) (*Core, error) {
+	d.InvalidateListingCache()
 	fullName := NewFileName(d.Name(), name)
 	childMetadata := map[string]string{
 		SymlinkMetadataKey: target,
@@ -785,6 +1075,7 @@ func (d *dirInode) CreateChildSymlink(ctx context.Context, name string, target s
 
 // LOCKS_REQUIRED(d)
 func (d *dirInode) CreateChildDir(ctx context.Context, name string) (*Core, error) {
+	d.InvalidateListingCache()
 	fullName := NewDirName(d.Name(), name)
 	o, err := d.createNewObject(ctx, fullName, nil)
 	if err != nil {
@@ -808,6 +1099,7 @@ func (d *dirInode) DeleteChildFile(
 	generation int64,
 	metaGeneration *int64) (err error) {
 	d.cache.Erase(name)
+	d.InvalidateListingCache()
 	childName := NewFileName(d.Name(), name)
 
 	err = d.bucket.DeleteObject(
@@ -833,6 +1125,7 @@ func (d *dirInode) DeleteChildDir(
 	name string,
 	isImplicitDir bool) (err error) {
 	d.cache.Erase(name)
+	d.InvalidateListingCache()
 
 	// if the directory is an implicit directory, then no backing object
 	// exists in the gcs bucket, so returning from here.
@@ -890,3 +1183,27 @@ func (d *dirInode) ShouldInvalidateKernelListCache(ttl time.Duration) bool {
 	cachedDuration := d.cacheClock.Now().Sub(*d.prevDirListingTimeStamp)
 	return cachedDuration >= ttl
 }
+
+// LOCKS_REQUIRED(d)
+func (d *dirInode) EraseFromTypeCache(name string) {
+	d.cache.Erase(name)
+}
+
+func (d *dirInode) TypeCacheEntries() []metadata.EntryInfo {
+	return d.cache.Entries()
+}
+
+func (d *dirInode) TypeCacheLen() int {
+	return d.cache.Len()
+}
+
+func (d *dirInode) InsertIntoTypeCache(now time.Time, types map[string]metadata.Type) {
+	d.cache.InsertMultiple(now, types)
+}
+
+func (d *dirInode) InvalidateListingCache() {
+	d.listingGeneration.Add(1)
+	if d.listingCache != nil {
+		d.listingCache.Invalidate()
+	}
+}
@@ -118,7 +118,9 @@ func (t *DirTest) resetInodeWithTypeCacheConfigs(implicitDirs, enableNonexistent
 		&t.bucket,
 		&t.clock,
 		&t.clock,
-		typeCacheMaxSizeMB)
+		typeCacheMaxSizeMB,
+		nil,
+		nil)
 
 	d := t.in.(*dirInode)
 	AssertNe(nil, d)
@@ -1526,3 +1528,171 @@ func (t *DirTest) Test_ShouldInvalidateKernelListCache_ZeroTtl() {
 
 	AssertEq(true, shouldInvalidate)
 }
+
+func (t *DirTest) Test_EraseFromTypeCache() {
+	t.resetInodeWithTypeCacheConfigs(true, true, true, config.DefaultTypeCacheMaxSizeMB, time.Minute)
+	const name = "qux"
+	// Cache the name as nonexistent.
+	result, err := t.in.LookUpChild(t.ctx, name)
+	AssertEq(nil, err)
+	AssertEq(nil, result)
+	AssertEq(metadata.NonexistentType, t.getTypeFromCache(name))
+	// Create the object behind the inode's back.
+	_, err = storageutil.CreateObject(t.ctx, t.bucket, path.Join(dirInodeName, name), []byte("taco"))
+	AssertEq(nil, err)
+
+	t.in.EraseFromTypeCache(name)
+
+	ExpectEq(metadata.UnknownType, t.getTypeFromCache(name))
+	result, err = t.in.LookUpChild(t.ctx, name)
+	AssertEq(nil, err)
+	ExpectNe(nil, result)
+}
+
+func (t *DirTest) readEntryNames() (names []string) {
+	entries, err := t.readAllEntries()
+	AssertEq(nil, err)
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	return
+}
+
+func (t *DirTest) Test_ReadEntries_ListingCacheServedWithinTTL() {
+	t.in.(*dirInode).listingCache = NewListingCache(t.ctx, time.Minute)
+	_, err := storageutil.CreateObject(t.ctx, t.bucket, dirInodeName+"a", []byte("taco"))
+	AssertEq(nil, err)
+	AssertThat(t.readEntryNames(), ElementsAre("a"))
+	// Create an object behind the inode's back.
+	_, err = storageutil.CreateObject(t.ctx, t.bucket, dirInodeName+"b", []byte("taco"))
+	AssertEq(nil, err)
+
+	// It isn't seen until the listing expires.
+	t.clock.AdvanceTime(29 * time.Second)
+	ExpectThat(t.readEntryNames(), ElementsAre("a"))
+	t.in.Unlock()
+	t.waitForListingRefresh()
+	t.in.Lock()
+	t.clock.AdvanceTime(time.Minute)
+	ExpectThat(t.readEntryNames(), ElementsAre("a", "b"))
+}
+
+func (t *DirTest) Test_ReadEntries_ListingCacheRefreshedInBackground() {
+	d := t.in.(*dirInode)
+	d.listingCache = NewListingCache(t.ctx, time.Minute)
+	_, err := storageutil.CreateObject(t.ctx, t.bucket, dirInodeName+"a", []byte("taco"))
+	AssertEq(nil, err)
+	AssertThat(t.readEntryNames(), ElementsAre("a"))
+	_, err = storageutil.CreateObject(t.ctx, t.bucket, dirInodeName+"b", []byte("taco"))
+	AssertEq(nil, err)
+
+	// Past half the TTL, the cached listing is served, and refreshed in the
+	// background.
+	t.clock.AdvanceTime(31 * time.Second)
+	ExpectThat(t.readEntryNames(), ElementsAre("a"))
+	t.in.Unlock()
+	t.waitForListingRefresh()
+	t.in.Lock()
+
+	ExpectThat(t.readEntryNames(), ElementsAre("a", "b"))
+	ExpectEq(metadata.RegularFileType, t.getTypeFromCache("b"))
+}
+
+func (t *DirTest) Test_ReadEntries_ListingCacheInvalidatedByLocalChanges() {
+	t.in.(*dirInode).listingCache = NewListingCache(t.ctx, time.Minute)
+	AssertThat(t.readEntryNames(), ElementsAre())
+
+	_, err := t.in.CreateChildFile(t.ctx, "a")
+	AssertEq(nil, err)
+	ExpectThat(t.readEntryNames(), ElementsAre("a"))
+	_, err = t.in.CreateChildDir(t.ctx, "b")
+	AssertEq(nil, err)
+	ExpectThat(t.readEntryNames(), ElementsAre("a", "b"))
+	err = t.in.DeleteChildFile(t.ctx, "a", 0, nil)
+	AssertEq(nil, err)
+	ExpectThat(t.readEntryNames(), ElementsAre("b"))
+}
+
+func (t *DirTest) Test_InvalidateListingCache() {
+	t.in.(*dirInode).listingCache = NewListingCache(t.ctx, time.Minute)
+	AssertThat(t.readEntryNames(), ElementsAre())
+	_, err := storageutil.CreateObject(t.ctx, t.bucket, dirInodeName+"a", []byte("taco"))
+	AssertEq(nil, err)
+	AssertThat(t.readEntryNames(), ElementsAre())
+
+	t.in.InvalidateListingCache()
+
+	ExpectThat(t.readEntryNames(), ElementsAre("a"))
+}
+
+func (t *DirTest) Test_ReadDescendants_ListingCacheServedWithinTTL() {
+	t.in.(*dirInode).listingCache = NewListingCache(t.ctx, time.Minute)
+	err := storageutil.CreateEmptyObjects(t.ctx, t.bucket, []string{dirInodeName + "a", dirInodeName + "b/c"})
+	AssertEq(nil, err)
+	descendants, err := t.in.ReadDescendants(t.ctx, 10)
+	AssertEq(nil, err)
+	AssertEq(2, len(descendants))
+	// Create an object behind the inode's back.
+	_, err = storageutil.CreateObject(t.ctx, t.bucket, dirInodeName+"b/d", []byte("taco"))
+	AssertEq(nil, err)
+
+	// It isn't seen until the listing expires, also with a smaller limit.
+	descendants, err = t.in.ReadDescendants(t.ctx, 10)
+	AssertEq(nil, err)
+	ExpectEq(2, len(descendants))
+	descendants, err = t.in.ReadDescendants(t.ctx, 1)
+	AssertEq(nil, err)
+	ExpectEq(1, len(descendants))
+	t.clock.AdvanceTime(time.Minute)
+	descendants, err = t.in.ReadDescendants(t.ctx, 10)
+	AssertEq(nil, err)
+	ExpectEq(3, len(descendants))
+}
+
+func (t *DirTest) Test_ReadDescendants_ListingCacheNotServedForLargerLimit() {
+	t.in.(*dirInode).listingCache = NewListingCache(t.ctx, time.Minute)
+	err := storageutil.CreateEmptyObjects(t.ctx, t.bucket, []string{dirInodeName + "a", dirInodeName + "b"})
+	AssertEq(nil, err)
+	descendants, err := t.in.ReadDescendants(t.ctx, 1)
+	AssertEq(nil, err)
+	AssertEq(1, len(descendants))
+
+	descendants, err = t.in.ReadDescendants(t.ctx, 10)
+
+	AssertEq(nil, err)
+	ExpectEq(2, len(descendants))
+}
+
+func (t *DirTest) Test_ReadDescendants_ListingCacheInvalidatedByAnyLocalChange() {
+	listingCache := NewListingCache(t.ctx, time.Minute)
+	t.in.(*dirInode).listingCache = listingCache
+	descendants, err := t.in.ReadDescendants(t.ctx, 10)
+	AssertEq(nil, err)
+	AssertEq(0, len(descendants))
+	_, err = storageutil.CreateObject(t.ctx, t.bucket, dirInodeName+"b/c", []byte("taco"))
+	AssertEq(nil, err)
+
+	// A change made through another directory of the file system.
+	listingCache.Invalidate()
+
+	descendants, err = t.in.ReadDescendants(t.ctx, 10)
+	AssertEq(nil, err)
+	ExpectEq(1, len(descendants))
+}
+
+// waitForListingRefresh waits until no background refresh of the listing is
+// in flight.
+//
+// LOCKS_EXCLUDED(t.in)
+func (t *DirTest) waitForListingRefresh() {
+	d := t.in.(*dirInode)
+	for {
+		d.mu.Lock()
+		refreshing := d.refreshingListing
+		d.mu.Unlock()
+		if !refreshing {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -17,6 +17,7 @@ package inode
 import (
 	"time"
 
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/metadata/shared"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/gcsx"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
 	"github.com/jacobsa/fuse/fuseops"
@@ -44,7 +45,9 @@ func NewExplicitDirInode(
 	bucket *gcsx.SyncerBucket,
 	mtimeClock timeutil.Clock,
 	cacheClock timeutil.Clock,
-	typeCacheMaxSizeMB int) (d ExplicitDirInode) {
+	typeCacheMaxSizeMB int,
+	listingCache *ListingCache,
+	sharedMetadataCache *shared.Client) (d ExplicitDirInode) {
 	wrapped := NewDirInode(
 		id,
 		name,
@@ -56,7 +59,9 @@ func NewExplicitDirInode(
 		bucket,
 		mtimeClock,
 		cacheClock,
-		typeCacheMaxSizeMB)
+		typeCacheMaxSizeMB,
+		listingCache,
+		sharedMetadataCache)
 
 	d = &explicitDirInode{
 		dirInode: wrapped.(*dirInode),
@@ -20,10 +20,12 @@ import (
 	"io"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/contentcache"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/gcsx"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/storageutil"
 	"github.com/jacobsa/fuse/fuseops"
@@ -78,6 +80,11 @@ type FileInode struct {
 	// authoritative.
 	content gcsx.TempFile
 
+	// The size of content last reported as pending upload in the metrics.
+	//
+	// GUARDED_BY(mu)
+	pendingUploadBytes int64
+
 	// Has Destroy been called?
 	//
 	// GUARDED_BY(mu)
@@ -88,6 +95,29 @@ type FileInode struct {
 
 	// Represents if local file has been unlinked.
 	unlinked bool
+
+	// A snapshot of the changes not yet uploaded to GCS, taken whenever mu is
+	// released, so that it can be read while mu is held for long, e.g. by a
+	// stuck upload.
+	//
+	// GUARDED_BY(statusMu)
+	status   DirtyStatus
+	statusMu sync.Mutex
+}
+
+// DirtyStatus describes the changes of a file not yet uploaded to GCS.
+type DirtyStatus struct {
+	// Dirty is false if the content of the file is the one of its source
+	// object, in which case the other fields are not set.
+	Dirty bool
+
+	// Local is true for a file created locally, not yet in GCS; Generation is
+	// otherwise the generation of the source object.
+	Local      bool
+	Generation int64
+
+	// PendingBytes is the size of the content written locally.
+	PendingBytes int64
 }
 
 var _ Inode = &FileInode{}
@@ -132,6 +162,7 @@ func NewFileInode(
 
 	// Set up invariant checking.
 	f.mu = syncutil.NewInvariantMutex(f.checkInvariants)
+	f.updateStatus()
 
 	return
 }
@@ -272,11 +303,44 @@ func (f *FileInode) ensureContent(ctx context.Context) (err error) {
 		}
 		// Update state.
 		f.content = tf
+		f.reportPendingUploadBytes(ctx)
 	}
 
 	return
 }
 
+// Report the growth of the content not yet uploaded to GCS by a write ending
+// at the given offset, without statting the content. Requires
+// pendingUploadBytes to be up to date before the write.
+//
+// LOCKS_REQUIRED(f.mu)
+func (f *FileInode) reportWrittenPendingUploadBytes(ctx context.Context, end int64) {
+	if f.content == nil || f.localFileCache || f.destroyed || end <= f.pendingUploadBytes {
+		return
+	}
+
+	monitor.CapturePendingUploadBytesMetrics(ctx, end-f.pendingUploadBytes)
+	f.pendingUploadBytes = end
+}
+
+// Report the change of the size of the content not yet uploaded to GCS in the
+// metrics. Contents kept by the local file cache are not pending an upload.
+//
+// LOCKS_REQUIRED(f.mu)
+func (f *FileInode) reportPendingUploadBytes(ctx context.Context) {
+	var size int64
+	if f.content != nil && !f.localFileCache && !f.destroyed {
+		sr, err := f.content.Stat()
+		if err != nil {
+			return
+		}
+		size = sr.Size
+	}
+
+	monitor.CapturePendingUploadBytesMetrics(ctx, size-f.pendingUploadBytes)
+	f.pendingUploadBytes = size
+}
+
 ////////////////////////////////////////////////////////////////////////
 // Public interface
 ////////////////////////////////////////////////////////////////////////
@@ -286,9 +350,42 @@ func (f *FileInode) Lock() {
 }
 
 func (f *FileInode) Unlock() {
+	f.updateStatus()
 	f.mu.Unlock()
 }
 
+// Take a snapshot of the changes not yet uploaded, for Status.
+//
+// LOCKS_REQUIRED(f.mu)
+func (f *FileInode) updateStatus() {
+	var status DirtyStatus
+	if f.content != nil && !f.destroyed {
+		status = DirtyStatus{
+			Dirty:        true,
+			Local:        f.local,
+			PendingBytes: f.pendingUploadBytes,
+		}
+		if !f.local {
+			status.Generation = f.src.Generation
+		}
+	}
+
+	f.statusMu.Lock()
+	f.status = status
+	f.statusMu.Unlock()
+}
+
+// Status returns the changes of the file not yet uploaded to GCS, as of the
+// last time the inode was unlocked. Unlike most methods, it doesn't require
+// the inode lock, and so doesn't wait for the operations holding it.
+//
+// LOCKS_EXCLUDED(f.mu)
+func (f *FileInode) Status() DirtyStatus {
+	f.statusMu.Lock()
+	defer f.statusMu.Unlock()
+	return f.status
+}
+
 func (f *FileInode) ID() fuseops.InodeID {
 	return f.id
 }
@@ -358,6 +455,7 @@ func (f *FileInode) Destroy() (err error) {
 	} else if f.content != nil {
 		f.content.Destroy()
 	}
+	f.reportPendingUploadBytes(context.Background())
 	return
 }
 
@@ -476,7 +574,9 @@ func (f *FileInode) Write(
 
 	// Write to the mutable content. Note that io.WriterAt guarantees it returns
 	// an error for short writes.
-	_, err = f.content.WriteAt(data, offset)
+	var n int
+	n, err = f.content.WriteAt(data, offset)
+	f.reportWrittenPendingUploadBytes(ctx, offset+int64(n))
 
 	return
 }
@@ -619,6 +719,7 @@ func (f *FileInode) Sync(ctx context.Context) (err error) {
 		}
 		f.content.Destroy()
 		f.content = nil
+		f.reportPendingUploadBytes(ctx)
 	}
 
 	return
@@ -639,6 +740,7 @@ func (f *FileInode) Truncate(
 
 	// Call through.
 	err = f.content.Truncate(size)
+	f.reportPendingUploadBytes(ctx)
 
 	return
 }
@@ -22,15 +22,20 @@ import (
 	"testing"
 	"time"
 
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor/monitortest"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/fake"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/faults"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/storageutil"
 	"github.com/jacobsa/syncutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/net/context"
 
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/contentcache"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/gcsx"
 	"github.com/jacobsa/fuse/fuseops"
+	. "github.com/jacobsa/oglematchers"
 	. "github.com/jacobsa/ogletest"
 	"github.com/jacobsa/timeutil"
 )
@@ -693,6 +698,60 @@ func (t *FileTest) Sync_Clobbered() {
 	ExpectEq(newObj.Size, m.Size)
 }
 
+func (t *FileTest) Sync_TransientFailure() {
+	var err error
+
+	// Fail the first upload of the object.
+	t.bucket = faults.NewBucket(
+		[]faults.Rule{{Method: "CreateObject", Object: "^" + fileName + "$", Count: 1, ErrorCode: 503}},
+		1,
+		t.bucket)
+	t.createInode()
+
+	err = t.in.Write(t.ctx, []byte("burrito"), 0)
+	AssertEq(nil, err)
+
+	// The first sync should fail, and keep the contents dirty.
+	err = t.in.Sync(t.ctx)
+
+	ExpectThat(err, Error(HasSubstr("fault injected in CreateObject")))
+	ExpectEq(t.backingObj.Generation, t.in.SourceGeneration().Object)
+
+	// The second one should upload them.
+	err = t.in.Sync(t.ctx)
+
+	AssertEq(nil, err)
+	ExpectNe(t.backingObj.Generation, t.in.SourceGeneration().Object)
+
+	contents, err := storageutil.ReadObject(t.ctx, t.bucket, t.in.Name().GcsObjectName())
+	AssertEq(nil, err)
+	ExpectEq("burrito", string(contents))
+}
+
+func (t *FileTest) Sync_InjectedPreconditionError() {
+	var err error
+
+	// The object looks changed meanwhile when uploading it.
+	t.bucket = faults.NewBucket(
+		[]faults.Rule{{Method: "CreateObject", ErrorCode: 412}},
+		1,
+		t.bucket)
+	t.createInode()
+
+	err = t.in.Write(t.ctx, []byte("burrito"), 0)
+	AssertEq(nil, err)
+
+	// Sync. The call should succeed, but nothing should change.
+	err = t.in.Sync(t.ctx)
+
+	AssertEq(nil, err)
+	ExpectEq(t.backingObj.Generation, t.in.SourceGeneration().Object)
+
+	contents, err := storageutil.ReadObject(t.ctx, t.bucket, t.in.Name().GcsObjectName())
+	AssertEq(nil, err)
+	ExpectEq(t.initialContents, string(contents))
+}
+
 func (t *FileTest) SetMtime_ContentNotFaultedIn() {
 	var err error
 	var attrs fuseops.InodeAttributes
@@ -942,3 +1001,50 @@ func (t *FileTest) UnlinkLocalFile() {
 	AssertNe(nil, err)
 	AssertEq("gcs.NotFoundError: Object test not found", err.Error())
 }
+
+func TestPendingUploadBytesMetric(t *testing.T) {
+	pending := func() int64 { return monitortest.Int64(t, "fs/pending_upload_bytes") }
+	initial := pending()
+	ft := &FileTest{ctx: context.Background()}
+	ft.clock.SetTime(time.Date(2012, 8, 15, 22, 56, 0, 0, time.Local))
+	ft.bucket = fake.NewFakeBucket(&ft.clock, "some_bucket")
+	object, err := storageutil.CreateObject(ft.ctx, ft.bucket, fileName, []byte("taco"))
+	require.NoError(t, err)
+	ft.backingObj = storageutil.ConvertObjToMinObject(object)
+	ft.createInode()
+	defer ft.in.Unlock()
+
+	// The first write makes the whole content pending an upload.
+	require.NoError(t, ft.in.Write(ft.ctx, []byte("burrito"), 2))
+	assert.Equal(t, initial+9, pending())
+	// Overwriting doesn't grow the content.
+	require.NoError(t, ft.in.Write(ft.ctx, []byte("en"), 0))
+	assert.Equal(t, initial+9, pending())
+	require.NoError(t, ft.in.Truncate(ft.ctx, 4))
+	assert.Equal(t, initial+4, pending())
+	require.NoError(t, ft.in.Sync(ft.ctx))
+	assert.Equal(t, initial, pending())
+}
+
+func TestStatusIsPublishedOnUnlock(t *testing.T) {
+	ft := &FileTest{ctx: context.Background()}
+	ft.clock.SetTime(time.Date(2012, 8, 15, 22, 56, 0, 0, time.Local))
+	ft.bucket = fake.NewFakeBucket(&ft.clock, "some_bucket")
+	object, err := storageutil.CreateObject(ft.ctx, ft.bucket, fileName, []byte("taco"))
+	require.NoError(t, err)
+	ft.backingObj = storageutil.ConvertObjToMinObject(object)
+	ft.createInode()
+	assert.Equal(t, DirtyStatus{}, ft.in.Status())
+
+	require.NoError(t, ft.in.Write(ft.ctx, []byte("burrito"), 2))
+	// Status doesn't take the inode lock, so it only sees the write once the
+	// lock is released.
+	assert.False(t, ft.in.Status().Dirty)
+	ft.in.Unlock()
+	assert.Equal(t, DirtyStatus{Dirty: true, Generation: object.Generation, PendingBytes: 9}, ft.in.Status())
+
+	ft.in.Lock()
+	require.NoError(t, ft.in.Sync(ft.ctx))
+	ft.in.Unlock()
+	assert.Equal(t, DirtyStatus{}, ft.in.Status())
+}
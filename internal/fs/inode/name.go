@@ -123,3 +123,13 @@ func (name Name) IsDirectChildOf(parent Name) bool {
 	cleanDiff := strings.TrimSuffix(diff, "/")
 	return !strings.Contains(cleanDiff, "/")
 }
+
+// IsDescendantOf returns true if the name is a file or directory anywhere
+// below another directory.
+func (name Name) IsDescendantOf(ancestor Name) bool {
+	if !ancestor.IsDir() || name.bucketName != ancestor.bucketName {
+		return false
+	}
+	return name.objectName != ancestor.objectName &&
+		strings.HasPrefix(name.objectName, ancestor.objectName)
+}
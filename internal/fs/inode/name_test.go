@@ -80,6 +80,13 @@ func TestName(t *testing.T) {
 		ExpectFalse(qux.IsDirectChildOf(root))
 		ExpectFalse(baz.IsDirectChildOf(baz))
 		ExpectTrue(qux.IsDirectChildOf(bar))
+		ExpectTrue(qux.IsDescendantOf(bar))
+		ExpectTrue(qux.IsDescendantOf(foo))
+		ExpectTrue(qux.IsDescendantOf(root))
+		ExpectFalse(qux.IsDescendantOf(anotherRoot))
+		ExpectFalse(bar.IsDescendantOf(bar))
+		ExpectFalse(foo.IsDescendantOf(bar))
+		ExpectFalse(qux.IsDescendantOf(baz))
 
 		qux = inode.NewDescendantName(foo, "foo/bar/qux") // "foo/bar/qux"
 		ExpectFalse(qux.IsBucketRoot())
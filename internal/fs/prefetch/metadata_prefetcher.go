@@ -0,0 +1,383 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package prefetch warms the metadata caches of a mount by listing its bucket
+// directly, rather than by walking the mounted file system.
+package prefetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/metadata"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/fs/inode"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/storageutil"
+	"github.com/jacobsa/timeutil"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// DefaultParallelism is the number of shards listed concurrently when
+	// Config.Parallelism is not set.
+	DefaultParallelism = 16
+
+	// DefaultProgressInterval is the interval between progress logs when
+	// Config.ProgressInterval is not set.
+	DefaultProgressInterval = 10 * time.Second
+
+	maxResultsForListObjectsCall = 5000
+)
+
+var errSizeLimitReached = errors.New("size limit reached")
+
+type Config struct {
+	// The bucket to list. Objects listed through it are expected to land in its
+	// stat cache, as they do with the caching bucket.
+	Bucket gcs.Bucket
+
+	// Whether directories only implied by the names of their descendants are
+	// recorded as implicit directories.
+	ImplicitDirs bool
+
+	// The number of prefix shards listed concurrently.
+	Parallelism int
+
+	// Prefetching stops, without failing, once the estimated memory of the
+	// fetched metadata reaches this many bytes. Zero means no limit.
+	MaxSizeBytes uint64
+
+	// The interval between progress logs.
+	ProgressInterval time.Duration
+
+	Clock timeutil.Clock
+}
+
+// Result holds the types of the entries found by Run, to be inserted in the
+// type caches of the directory inodes.
+type Result struct {
+	// The time at which listing started. Entries are as old as that.
+	Time time.Time
+
+	// The types of the children of each listed directory, keyed by the GCS name
+	// of the directory (e.g. "" or "a/b/") and then by child name (e.g. "c").
+	Types map[string]map[string]metadata.Type
+
+	// The number of objects listed.
+	Objects int
+
+	// Whether prefetching stopped early because of Config.MaxSizeBytes.
+	Truncated bool
+}
+
+type prefetcher struct {
+	cfg Config
+
+	// The group listing the shards.
+	group *errgroup.Group
+
+	mu sync.Mutex
+
+	// GUARDED_BY(mu)
+	result *Result
+
+	// The estimated memory used by the listed objects in the stat cache and by
+	// result.Types.
+	//
+	// GUARDED_BY(mu)
+	size uint64
+}
+
+// Run lists all objects of the bucket with flat ListObjects calls, one shard
+// per top-level prefix, up to cfg.Parallelism at a time. Shards that don't fit
+// in a single page are split by their subdirectories, so that deep trees are
+// listed in parallel too. Listed objects are inserted in the stat cache by the
+// bucket, while their types are returned.
+func Run(ctx context.Context, cfg Config) (*Result, error) {
+	if cfg.Parallelism <= 0 {
+		cfg.Parallelism = DefaultParallelism
+	}
+	if cfg.ProgressInterval <= 0 {
+		cfg.ProgressInterval = DefaultProgressInterval
+	}
+
+	p := &prefetcher{
+		cfg: cfg,
+		result: &Result{
+			Time:  cfg.Clock.Now(),
+			Types: make(map[string]map[string]metadata.Type),
+		},
+	}
+
+	stopProgress := p.logProgress()
+	defer stopProgress()
+
+	err := p.run(ctx)
+	if errors.Is(err, errSizeLimitReached) {
+		p.mu.Lock()
+		p.result.Truncated = true
+		p.mu.Unlock()
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.result, nil
+}
+
+func (p *prefetcher) run(ctx context.Context) error {
+	shards, err := p.listTopLevel(ctx)
+	if err != nil {
+		return err
+	}
+
+	p.group, ctx = errgroup.WithContext(ctx)
+	p.group.SetLimit(p.cfg.Parallelism)
+	for _, shard := range shards {
+		shard := shard
+		p.group.Go(func() error {
+			return p.listShard(ctx, shard, "")
+		})
+	}
+
+	return p.group.Wait()
+}
+
+// spawn lists the given shard in another goroutine of the group, or in this
+// one if the group is busy.
+func (p *prefetcher) spawn(ctx context.Context, prefix string, after string) error {
+	f := func() error {
+		return p.listShard(ctx, prefix, after)
+	}
+	if p.group.TryGo(f) {
+		return nil
+	}
+	return f()
+}
+
+// listTopLevel lists the top level of the bucket, recording the objects found
+// there and returning the prefixes to be listed as shards.
+func (p *prefetcher) listTopLevel(ctx context.Context) (shards []string, err error) {
+	req := &gcs.ListObjectsRequest{
+		Delimiter:                "/",
+		IncludeTrailingDelimiter: true,
+		MaxResults:               maxResultsForListObjectsCall,
+		ProjectionVal:            gcs.NoAcl,
+	}
+
+	for {
+		var listing *gcs.Listing
+		listing, err = p.cfg.Bucket.ListObjects(ctx, req)
+		if err != nil {
+			err = fmt.Errorf("ListObjects: %w", err)
+			return
+		}
+
+		if err = p.record(listing.Objects, "", ""); err != nil {
+			return
+		}
+		shards = append(shards, listing.CollapsedRuns...)
+
+		if listing.ContinuationToken == "" {
+			return
+		}
+		req.ContinuationToken = listing.ContinuationToken
+	}
+}
+
+// listShard lists all objects under the given prefix without a delimiter,
+// skipping those named up to after. If they don't fit in one page, the rest
+// of them are listed by split.
+func (p *prefetcher) listShard(ctx context.Context, prefix string, after string) error {
+	req := &gcs.ListObjectsRequest{
+		Prefix:        prefix,
+		MaxResults:    maxResultsForListObjectsCall,
+		ProjectionVal: gcs.NoAcl,
+	}
+
+	listing, err := p.cfg.Bucket.ListObjects(ctx, req)
+	if err != nil {
+		return fmt.Errorf("ListObjects(%q): %w", prefix, err)
+	}
+
+	// The object backing the prefix, if any, was recorded along with its
+	// parent.
+	if err = p.record(listing.Objects, prefix, after); err != nil {
+		return err
+	}
+
+	if listing.ContinuationToken == "" || len(listing.Objects) == 0 {
+		return nil
+	}
+	if last := listing.Objects[len(listing.Objects)-1].Name; last > after {
+		after = last
+	}
+	return p.split(ctx, prefix, after)
+}
+
+// split lists the objects under the given prefix named after the given name,
+// one level at a time: objects directly under the prefix are recorded, while
+// subdirectories are listed as shards of their own.
+func (p *prefetcher) split(ctx context.Context, prefix string, after string) error {
+	req := &gcs.ListObjectsRequest{
+		Prefix:                   prefix,
+		Delimiter:                "/",
+		IncludeTrailingDelimiter: true,
+		MaxResults:               maxResultsForListObjectsCall,
+		ProjectionVal:            gcs.NoAcl,
+	}
+
+	for {
+		listing, err := p.cfg.Bucket.ListObjects(ctx, req)
+		if err != nil {
+			return fmt.Errorf("ListObjects(%q): %w", prefix, err)
+		}
+
+		if err = p.record(listing.Objects, prefix, after); err != nil {
+			return err
+		}
+
+		// Names are listed in order, so the objects of a subdirectory sorting
+		// before after have all been recorded, and those of the subdirectory
+		// of after, if any, up to after.
+		for _, sub := range listing.CollapsedRuns {
+			switch {
+			case strings.HasPrefix(after, sub):
+				err = p.spawn(ctx, sub, after)
+			case sub > after:
+				err = p.spawn(ctx, sub, "")
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if listing.ContinuationToken == "" {
+			return nil
+		}
+		req.ContinuationToken = listing.ContinuationToken
+	}
+}
+
+// record adds the types of the supplied objects and of their ancestors to the
+// result, skipping the object named skip and those named up to after.
+//
+// LOCKS_EXCLUDED(p.mu)
+func (p *prefetcher) record(objects []*gcs.Object, skip string, after string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, o := range objects {
+		if o.Name == "" || o.Name == skip || o.Name <= after {
+			continue
+		}
+
+		m := storageutil.ConvertObjToMinObject(o)
+		p.size += metadata.SizeOfStatCacheEntry(m)
+		p.result.Objects++
+
+		t := metadata.RegularFileType
+		switch {
+		case strings.HasSuffix(o.Name, "/"):
+			t = metadata.ExplicitDirType
+		case inode.IsSymlink(m):
+			t = metadata.SymlinkType
+		}
+
+		name := strings.TrimSuffix(o.Name, "/")
+		p.addType(name, t)
+		if p.cfg.ImplicitDirs {
+			for i := strings.LastIndex(name, "/"); i > 0; i = strings.LastIndex(name, "/") {
+				name = name[:i]
+				if !p.addType(name, metadata.ImplicitDirType) {
+					// The ancestors of name have been recorded along with it.
+					break
+				}
+			}
+		}
+
+		if p.cfg.MaxSizeBytes > 0 && p.size >= p.cfg.MaxSizeBytes {
+			return errSizeLimitReached
+		}
+	}
+
+	return nil
+}
+
+// addType records the type of the entry with the given name (e.g. "a/b" for
+// object "a/b/"), returning whether it was not already known as a directory.
+// As in directory listings, a directory shadows a file with the same name.
+//
+// LOCKS_REQUIRED(p.mu)
+func (p *prefetcher) addType(name string, t metadata.Type) bool {
+	dir, child := "", name
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		dir, child = name[:i+1], name[i+1:]
+	}
+
+	children := p.result.Types[dir]
+	if children == nil {
+		children = make(map[string]metadata.Type)
+		p.result.Types[dir] = children
+	}
+
+	existing, ok := children[child]
+	if !ok {
+		p.size += metadata.SizeOfTypeCacheEntry(child)
+	}
+
+	switch existing {
+	case metadata.ExplicitDirType:
+		return false
+	case metadata.ImplicitDirType:
+		if t == metadata.ExplicitDirType {
+			children[child] = t
+		}
+		return false
+	}
+
+	children[child] = t
+	return true
+}
+
+// logProgress logs the progress of prefetching periodically, until the
+// returned function is called.
+func (p *prefetcher) logProgress() (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(p.cfg.ProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				p.mu.Lock()
+				objects, dirs, size := p.result.Objects, len(p.result.Types), p.size
+				p.mu.Unlock()
+				logger.Infof("Metadata prefetch in progress: %d objects in %d directories listed (%d KiB)", objects, dirs, size>>10)
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
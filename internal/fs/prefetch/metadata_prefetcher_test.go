@@ -0,0 +1,202 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prefetch
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/metadata"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/fs/inode"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/fake"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/storageutil"
+	"github.com/jacobsa/timeutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type MetadataPrefetcherTest struct {
+	suite.Suite
+	ctx    context.Context
+	clock  timeutil.SimulatedClock
+	bucket gcs.Bucket
+}
+
+func TestMetadataPrefetcherSuite(t *testing.T) {
+	suite.Run(t, new(MetadataPrefetcherTest))
+}
+
+func (t *MetadataPrefetcherTest) SetupTest() {
+	t.ctx = context.Background()
+	t.clock.SetTime(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+	t.bucket = fake.NewFakeBucket(&t.clock, "some_bucket")
+	err := storageutil.CreateObjects(t.ctx, t.bucket, map[string][]byte{
+		"foo":           []byte("taco"),
+		"a/":            nil,
+		"a/b":           []byte("taco"),
+		"a/c/d":         []byte("taco"),
+		"a/c/e/":        nil,
+		"f/g":           []byte("taco"),
+		"f/h/i/j":       []byte("taco"),
+		"shadowed":      []byte("taco"),
+		"shadowed/file": []byte("taco"),
+	})
+	require.NoError(t.T(), err)
+	_, err = t.bucket.CreateObject(t.ctx, &gcs.CreateObjectRequest{
+		Name:     "a/link",
+		Contents: strings.NewReader(""),
+		Metadata: map[string]string{inode.SymlinkMetadataKey: "b"},
+	})
+	require.NoError(t.T(), err)
+}
+
+func (t *MetadataPrefetcherTest) config() Config {
+	return Config{
+		Bucket:       t.bucket,
+		ImplicitDirs: true,
+		Parallelism:  2,
+		Clock:        &t.clock,
+	}
+}
+
+func (t *MetadataPrefetcherTest) TestRunWithImplicitDirs() {
+	result, err := Run(t.ctx, t.config())
+
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), t.clock.Now(), result.Time)
+	assert.Equal(t.T(), 10, result.Objects)
+	assert.False(t.T(), result.Truncated)
+	assert.Equal(t.T(), map[string]map[string]metadata.Type{
+		"": {
+			"foo":      metadata.RegularFileType,
+			"a":        metadata.ExplicitDirType,
+			"f":        metadata.ImplicitDirType,
+			"shadowed": metadata.ImplicitDirType,
+		},
+		"a/": {
+			"b":    metadata.RegularFileType,
+			"c":    metadata.ImplicitDirType,
+			"link": metadata.SymlinkType,
+		},
+		"a/c/": {
+			"d": metadata.RegularFileType,
+			"e": metadata.ExplicitDirType,
+		},
+		"f/": {
+			"g": metadata.RegularFileType,
+			"h": metadata.ImplicitDirType,
+		},
+		"f/h/": {
+			"i": metadata.ImplicitDirType,
+		},
+		"f/h/i/": {
+			"j": metadata.RegularFileType,
+		},
+		"shadowed/": {
+			"file": metadata.RegularFileType,
+		},
+	}, result.Types)
+}
+
+func (t *MetadataPrefetcherTest) TestRunSplitsShardsNotFittingInOnePage() {
+	bucket := &pagingBucket{Bucket: t.bucket}
+	cfg := t.config()
+	cfg.Bucket = bucket
+	expected, err := Run(t.ctx, t.config())
+	require.NoError(t.T(), err)
+
+	result, err := Run(t.ctx, cfg)
+
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), expected.Objects, result.Objects)
+	assert.Equal(t.T(), expected.Types, result.Types)
+	assert.Contains(t.T(), bucket.prefixes, "a/c/")
+}
+
+func (t *MetadataPrefetcherTest) TestRunWithoutImplicitDirs() {
+	cfg := t.config()
+	cfg.ImplicitDirs = false
+
+	result, err := Run(t.ctx, cfg)
+
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), map[string]metadata.Type{
+		"foo":      metadata.RegularFileType,
+		"a":        metadata.ExplicitDirType,
+		"shadowed": metadata.RegularFileType,
+	}, result.Types[""])
+	assert.Equal(t.T(), map[string]metadata.Type{
+		"d": metadata.RegularFileType,
+		"e": metadata.ExplicitDirType,
+	}, result.Types["a/c/"])
+	assert.NotContains(t.T(), result.Types["a/"], "c")
+}
+
+func (t *MetadataPrefetcherTest) TestRunStopsAtMaxSize() {
+	cfg := t.config()
+	cfg.MaxSizeBytes = 1
+
+	result, err := Run(t.ctx, cfg)
+
+	require.NoError(t.T(), err)
+	assert.True(t.T(), result.Truncated)
+	assert.Equal(t.T(), 1, result.Objects)
+}
+
+func (t *MetadataPrefetcherTest) TestRunFailsOnListError() {
+	cfg := t.config()
+	cfg.Bucket = &failingBucket{Bucket: t.bucket}
+
+	_, err := Run(t.ctx, cfg)
+
+	assert.ErrorContains(t.T(), err, "injected")
+}
+
+// failingBucket fails the listings of shards.
+type failingBucket struct {
+	gcs.Bucket
+}
+
+func (b *failingBucket) ListObjects(ctx context.Context, req *gcs.ListObjectsRequest) (*gcs.Listing, error) {
+	if req.Prefix != "" {
+		return nil, errors.New("injected")
+	}
+	return b.Bucket.ListObjects(ctx, req)
+}
+
+// pagingBucket lists at most two objects at a time, recording the prefixes
+// listed.
+type pagingBucket struct {
+	gcs.Bucket
+
+	mu       sync.Mutex
+	prefixes []string
+}
+
+func (b *pagingBucket) ListObjects(ctx context.Context, req *gcs.ListObjectsRequest) (*gcs.Listing, error) {
+	b.mu.Lock()
+	b.prefixes = append(b.prefixes, req.Prefix)
+	b.mu.Unlock()
+
+	paged := *req
+	paged.MaxResults = 2
+	return b.Bucket.ListObjects(ctx, &paged)
+}
@@ -21,49 +21,36 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor/tags"
 	"github.com/jacobsa/fuse/fuseops"
 	"github.com/jacobsa/fuse/fuseutil"
-	"go.opencensus.io/plugin/ochttp"
-	"go.opencensus.io/stats"
-	"go.opencensus.io/stats/view"
-	"go.opencensus.io/tag"
+	"go.opentelemetry.io/otel/metric"
 )
 
 var (
-	opsCount      = stats.Int64("fs/ops_count", "The number of ops processed by the file system.", stats.UnitDimensionless)
-	opsLatency    = stats.Float64("fs/ops_latency", "The latency of a file system operation.", stats.UnitMilliseconds)
-	opsErrorCount = stats.Int64("fs/ops_error_count", "The number of errors generated by file system operation.", stats.UnitDimensionless)
+	opsCount      metric.Int64Counter
+	opsLatency    metric.Float64Histogram
+	opsErrorCount metric.Int64Counter
 )
 
 // Initialize the metrics.
 func init() {
-
-	// Register the view.
-	if err := view.Register(
-		&view.View{
-			Name:        "fs/ops_count",
-			Measure:     opsCount,
-			Description: "The cumulative number of ops processed by the file system.",
-			Aggregation: view.Sum(),
-			TagKeys:     []tag.Key{tags.FSOp},
-		},
-		&view.View{
-			Name:        "fs/ops_error_count",
-			Measure:     opsErrorCount,
-			Description: "The cumulative number of errors generated by file system operations",
-			Aggregation: view.Sum(),
-			TagKeys:     []tag.Key{tags.FSOp, tags.FSError},
-		},
-		&view.View{
-			Name:        "fs/ops_latency",
-			Measure:     opsLatency,
-			Description: "The cumulative distribution of file system operation latencies",
-			Aggregation: ochttp.DefaultLatencyDistribution,
-			TagKeys:     []tag.Key{tags.FSOp},
-		}); err != nil {
-		fmt.Printf("Failed to register metrics for the file system: %v\n", err)
+	meter := monitor.Meter()
+	var err, errs error
+	opsCount, err = meter.Int64Counter("fs/ops_count",
+		metric.WithDescription("The cumulative number of ops processed by the file system."))
+	errs = errors.Join(errs, err)
+	opsErrorCount, err = meter.Int64Counter("fs/ops_error_count",
+		metric.WithDescription("The cumulative number of errors generated by file system operations"))
+	errs = errors.Join(errs, err)
+	opsLatency, err = meter.Float64Histogram("fs/ops_latency",
+		metric.WithDescription("The cumulative distribution of file system operation latencies"),
+		metric.WithUnit("ms"),
+		monitor.DefaultLatencyDistribution)
+	errs = errors.Join(errs, err)
+	if errs != nil {
+		fmt.Printf("Failed to register metrics for the file system: %v\n", errs)
 	}
 }
 
@@ -82,50 +69,24 @@ func fsErrStr(err error) string {
 
 // Records file system operation count, failed operation count and the operation latency.
 func recordOp(ctx context.Context, method string, start time.Time, fsErr error) {
-
 	// Recording opCount.
-	if err := stats.RecordWithTags(
-		ctx,
-		[]tag.Mutator{
-			tag.Upsert(tags.FSOp, method),
-		},
-		opsCount.M(1),
-	); err != nil {
-		// Error in recording opCount.
-		logger.Errorf("Cannot record file system op: %v", err)
-	}
+	opsCount.Add(ctx, 1, metric.WithAttributes(tags.FSOp.String(method)))
 
 	// Recording opErrorCount.
 	if fsErr != nil {
-		if err := stats.RecordWithTags(
-			ctx,
-			[]tag.Mutator{
-				tag.Upsert(tags.FSOp, method),
-				tag.Upsert(tags.FSError, fsErrStr(fsErr)),
-			},
-			opsErrorCount.M(1),
-		); err != nil {
-			// Error in recording opErrorCount.
-			logger.Errorf("Cannot record error count of the file system failed operations: %v", err)
-		}
+		opsErrorCount.Add(ctx, 1, metric.WithAttributes(
+			tags.FSOp.String(method),
+			tags.FSError.String(fsErrStr(fsErr))))
 	}
 
 	// Recording opLatency.
 	latencyMs := float64(time.Since(start).Microseconds()) / 1000.0
-	if err := stats.RecordWithTags(
-		ctx,
-		[]tag.Mutator{
-			tag.Upsert(tags.FSOp, method),
-		},
-		opsLatency.M(latencyMs),
-	); err != nil {
-		// Error in opLatency.
-		logger.Errorf("Cannot record file system operation latency: %v", err)
-	}
+	opsLatency.Record(ctx, latencyMs, metric.WithAttributes(tags.FSOp.String(method)))
 }
 
 // WithMonitoring takes a FileSystem, returns a FileSystem with monitoring
-// on the counts of requests per API.
+// on the counts of requests per API, and a trace span per request, named
+// after the op, e.g. fs/ReadFile.
 func WithMonitoring(fs fuseutil.FileSystem) fuseutil.FileSystem {
 	return &monitoring{
 		wrapped: fs,
@@ -143,260 +104,322 @@ func (fs *monitoring) Destroy() {
 func (fs *monitoring) StatFS(
 	ctx context.Context,
 	op *fuseops.StatFSOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/StatFS")
 	startTime := time.Now()
 	err := fs.wrapped.StatFS(ctx, op)
 	recordOp(ctx, "StatFS", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) LookUpInode(
 	ctx context.Context,
 	op *fuseops.LookUpInodeOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/LookUpInode")
 	startTime := time.Now()
 	err := fs.wrapped.LookUpInode(ctx, op)
 	recordOp(ctx, "LookUpInode", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) GetInodeAttributes(
 	ctx context.Context,
 	op *fuseops.GetInodeAttributesOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/GetInodeAttributes")
 	startTime := time.Now()
 	err := fs.wrapped.GetInodeAttributes(ctx, op)
 	recordOp(ctx, "GetInodeAttributes", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) SetInodeAttributes(
 	ctx context.Context,
 	op *fuseops.SetInodeAttributesOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/SetInodeAttributes")
 	startTime := time.Now()
 	err := fs.wrapped.SetInodeAttributes(ctx, op)
 	recordOp(ctx, "SetInodeAttributes", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) ForgetInode(
 	ctx context.Context,
 	op *fuseops.ForgetInodeOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/ForgetInode")
 	startTime := time.Now()
 	err := fs.wrapped.ForgetInode(ctx, op)
 	recordOp(ctx, "ForgetInode", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) BatchForget(
 	ctx context.Context,
 	op *fuseops.BatchForgetOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/BatchForget")
 	startTime := time.Now()
 	err := fs.wrapped.BatchForget(ctx, op)
 	recordOp(ctx, "BatchForget", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) MkDir(
 	ctx context.Context,
 	op *fuseops.MkDirOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/MkDir")
 	startTime := time.Now()
 	err := fs.wrapped.MkDir(ctx, op)
 	recordOp(ctx, "MkDir", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) MkNode(
 	ctx context.Context,
 	op *fuseops.MkNodeOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/MkNode")
 	startTime := time.Now()
 	err := fs.wrapped.MkNode(ctx, op)
 	recordOp(ctx, "MkNode", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) CreateFile(
 	ctx context.Context,
 	op *fuseops.CreateFileOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/CreateFile")
 	startTime := time.Now()
 	err := fs.wrapped.CreateFile(ctx, op)
 	recordOp(ctx, "CreateFile", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) CreateLink(
 	ctx context.Context,
 	op *fuseops.CreateLinkOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/CreateLink")
 	startTime := time.Now()
 	err := fs.wrapped.CreateLink(ctx, op)
 	recordOp(ctx, "CreateLink", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) CreateSymlink(
 	ctx context.Context,
 	op *fuseops.CreateSymlinkOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/CreateSymlink")
 	startTime := time.Now()
 	err := fs.wrapped.CreateSymlink(ctx, op)
 	recordOp(ctx, "CreateSymlink", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) Rename(
 	ctx context.Context,
 	op *fuseops.RenameOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/Rename")
 	startTime := time.Now()
 	err := fs.wrapped.Rename(ctx, op)
 	recordOp(ctx, "Rename", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) RmDir(
 	ctx context.Context,
 	op *fuseops.RmDirOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/RmDir")
 	startTime := time.Now()
 	err := fs.wrapped.RmDir(ctx, op)
 	recordOp(ctx, "RmDir", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) Unlink(
 	ctx context.Context,
 	op *fuseops.UnlinkOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/Unlink")
 	startTime := time.Now()
 	err := fs.wrapped.Unlink(ctx, op)
 	recordOp(ctx, "Unlink", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) OpenDir(
 	ctx context.Context,
 	op *fuseops.OpenDirOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/OpenDir")
 	startTime := time.Now()
 	err := fs.wrapped.OpenDir(ctx, op)
 	recordOp(ctx, "OpenDir", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) ReadDir(
 	ctx context.Context,
 	op *fuseops.ReadDirOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/ReadDir")
 	startTime := time.Now()
 	err := fs.wrapped.ReadDir(ctx, op)
 	recordOp(ctx, "ReadDir", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) ReleaseDirHandle(
 	ctx context.Context,
 	op *fuseops.ReleaseDirHandleOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/ReleaseDirHandle")
 	startTime := time.Now()
 	err := fs.wrapped.ReleaseDirHandle(ctx, op)
 	recordOp(ctx, "ReleaseDirHandle", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) OpenFile(
 	ctx context.Context,
 	op *fuseops.OpenFileOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/OpenFile")
 	startTime := time.Now()
 	err := fs.wrapped.OpenFile(ctx, op)
 	recordOp(ctx, "OpenFile", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) ReadFile(
 	ctx context.Context,
 	op *fuseops.ReadFileOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/ReadFile",
+		tags.Offset.Int64(op.Offset),
+		tags.Size.Int64(op.Size))
 	startTime := time.Now()
 	err := fs.wrapped.ReadFile(ctx, op)
 	recordOp(ctx, "ReadFile", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) WriteFile(
 	ctx context.Context,
 	op *fuseops.WriteFileOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/WriteFile",
+		tags.Offset.Int64(op.Offset),
+		tags.Size.Int(len(op.Data)))
 	startTime := time.Now()
 	err := fs.wrapped.WriteFile(ctx, op)
 	recordOp(ctx, "WriteFile", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) SyncFile(
 	ctx context.Context,
 	op *fuseops.SyncFileOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/SyncFile")
 	startTime := time.Now()
 	err := fs.wrapped.SyncFile(ctx, op)
 	recordOp(ctx, "SyncFile", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) FlushFile(
 	ctx context.Context,
 	op *fuseops.FlushFileOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/FlushFile")
 	startTime := time.Now()
 	err := fs.wrapped.FlushFile(ctx, op)
 	recordOp(ctx, "FlushFile", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) ReleaseFileHandle(
 	ctx context.Context,
 	op *fuseops.ReleaseFileHandleOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/ReleaseFileHandle")
 	startTime := time.Now()
 	err := fs.wrapped.ReleaseFileHandle(ctx, op)
 	recordOp(ctx, "ReleaseFileHandle", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) ReadSymlink(
 	ctx context.Context,
 	op *fuseops.ReadSymlinkOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/ReadSymlink")
 	startTime := time.Now()
 	err := fs.wrapped.ReadSymlink(ctx, op)
 	recordOp(ctx, "ReadSymlink", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) RemoveXattr(
 	ctx context.Context,
 	op *fuseops.RemoveXattrOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/RemoveXattr")
 	startTime := time.Now()
 	err := fs.wrapped.RemoveXattr(ctx, op)
 	recordOp(ctx, "RemoveXattr", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) GetXattr(
 	ctx context.Context,
 	op *fuseops.GetXattrOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/GetXattr")
 	startTime := time.Now()
 	err := fs.wrapped.GetXattr(ctx, op)
 	recordOp(ctx, "GetXattr", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) ListXattr(
 	ctx context.Context,
 	op *fuseops.ListXattrOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/ListXattr")
 	startTime := time.Now()
 	err := fs.wrapped.ListXattr(ctx, op)
 	recordOp(ctx, "ListXattr", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) SetXattr(
 	ctx context.Context,
 	op *fuseops.SetXattrOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/SetXattr")
 	startTime := time.Now()
 	err := fs.wrapped.SetXattr(ctx, op)
 	recordOp(ctx, "SetXattr", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
 
 func (fs *monitoring) Fallocate(
 	ctx context.Context,
 	op *fuseops.FallocateOp) error {
+	ctx, span := monitor.StartSpan(ctx, "fs/Fallocate")
 	startTime := time.Now()
 	err := fs.wrapped.Fallocate(ctx, op)
 	recordOp(ctx, "Fallocate", startTime, err)
+	monitor.EndSpan(span, err)
 	return err
 }
@@ -19,16 +19,29 @@ import (
 	"errors"
 	"fmt"
 	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/accounting"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/lru"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/metadata"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/metadata/shared"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/canned"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/config"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/ratelimit"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/caching"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/faults"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/hedging"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/retry"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/stall"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/storageutil"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/util"
 	"github.com/jacobsa/timeutil"
 )
@@ -41,8 +54,17 @@ type BucketConfig struct {
 	StatCacheMaxSizeMB                 uint64
 	StatCacheTTL                       time.Duration
 	EnableMonitoring                   bool
+	EnableTracing                      bool
 	DebugGCS                           bool
 
+	// SharedMetadataCache, if set, holds the stat-cache entries instead of a
+	// cache in this process, in which case StatCacheMaxSizeMB is ignored.
+	SharedMetadataCache *shared.Client
+
+	// Accountant, if set, counts the requests sent to GCS by the local name of
+	// the file or directory they are about.
+	Accountant *accounting.Accountant
+
 	// Files backed by on object of length at least AppendThreshold that have
 	// only been appended to (i.e. none of the object's contents have been
 	// dirtied) will be written out by "appending" to the object in GCS with this
@@ -61,6 +83,35 @@ type BucketConfig struct {
 	// periodically garbage collected.
 	AppendThreshold int64
 	TmpObjectPrefix string
+
+	// FakeBucketManifest, if set, is the manifest or the directory the contents
+	// of the fake bucket named canned.FakeBucketName are loaded from.
+	FakeBucketManifest string
+
+	// Faults, if set, are injected in the calls to the bucket.
+	Faults *faults.Config
+
+	// Retry configures the retries of the requests to the bucket by gcsfuse,
+	// instead of the client library, per class of requests.
+	Retry config.RetryConfig
+
+	// Hedging configures the duplication of the slow stats and small reads.
+	Hedging config.HedgingConfig
+
+	// ReadStall configures the reopening of the streams of objects that stall.
+	ReadStall config.ReadStallConfig
+
+	// BucketCredentials, if set, are the credentials, billing project and
+	// client protocol of the buckets matching them, each accessed through a
+	// storage handle of its own, configured as StorageClientConfig but for the
+	// fields they set. The other buckets are accessed through the storage
+	// handle of the manager.
+	BucketCredentials   []storage.BucketCredentials
+	StorageClientConfig storageutil.StorageClientConfig
+
+	// DynamicMount configures the listing of the buckets of a mount of all the
+	// buckets, and the buckets it can mount.
+	DynamicMount config.DynamicMountConfig
 }
 
 // BucketManager manages the lifecycle of buckets.
@@ -69,6 +120,21 @@ type BucketManager interface {
 		ctx context.Context,
 		name string, isMultibucketMount bool) (b SyncerBucket, err error)
 
+	// InvalidateObject erases the stat-cache entry, if any, for the object
+	// with the given full name in the given bucket. It returns the name of the
+	// object as seen through the buckets set up by the manager (i.e. relative
+	// to OnlyDir), and false if the object is not visible through them.
+	InvalidateObject(bucketName string, objectName string) (name string, ok bool)
+
+	// StatCache returns the LRU cache backing the stat caches of all the
+	// buckets, or nil if the stat cache is disabled or shared with other
+	// processes.
+	StatCache() *lru.Cache
+
+	// ListBuckets returns the names of the buckets of the project of the
+	// DynamicMount config allowed by it, or ENOTSUP if it has no project.
+	ListBuckets(ctx context.Context) (names []string, err error)
+
 	// Shuts down the bucket manager and its buckets
 	ShutDown()
 }
@@ -78,6 +144,33 @@ type bucketManager struct {
 	storageHandle   storage.StorageHandle
 	sharedStatCache *lru.Cache
 
+	mu sync.Mutex
+
+	// The stat-cache views of the buckets set up so far, keyed by bucket name.
+	//
+	// GUARDED_BY(mu)
+	statCaches map[string]metadata.StatCache
+
+	// The storage handles of the entries of BucketCredentials created so far,
+	// keyed by index.
+	//
+	// GUARDED_BY(mu)
+	credentialsHandles map[int]storage.StorageHandle
+
+	// The compiled BucketRegex of the DynamicMount config, if any.
+	bucketRegex *regexp.Regexp
+
+	clock timeutil.Clock
+
+	// The last listing of the buckets, and when it expires.
+	//
+	// GUARDED_BY(mu)
+	bucketNames           []string
+	bucketNamesExpiration time.Time
+
+	// Creates the storage handle of an entry of BucketCredentials.
+	newStorageHandle func(context.Context, storageutil.StorageClientConfig) (storage.StorageHandle, error)
+
 	// Garbage collector
 	gcCtx                 context.Context
 	stopGarbageCollecting func()
@@ -86,13 +179,18 @@ type bucketManager struct {
 func NewBucketManager(config BucketConfig, storageHandle storage.StorageHandle) BucketManager {
 	var c *lru.Cache
 	if config.StatCacheMaxSizeMB > 0 {
-		c = lru.NewCache(util.MiBsToBytes(config.StatCacheMaxSizeMB))
+		c = lru.NewNamedCache("stat_cache", util.MiBsToBytes(config.StatCacheMaxSizeMB))
 	}
 
 	bm := &bucketManager{
-		config:          config,
-		storageHandle:   storageHandle,
-		sharedStatCache: c,
+		config:           config,
+		storageHandle:    storageHandle,
+		sharedStatCache:  c,
+		newStorageHandle: storage.NewStorageHandle,
+		clock:            timeutil.RealClock(),
+	}
+	if config.DynamicMount.BucketRegex != "" {
+		bm.bucketRegex = regexp.MustCompile(config.DynamicMount.BucketRegex)
 	}
 	bm.gcCtx, bm.stopGarbageCollecting = context.WithCancel(context.Background())
 	return bm
@@ -152,17 +250,87 @@ func setUpRateLimiting(
 	return
 }
 
+// Return the handle of the named bucket, through the storage handle of the
+// first entry of BucketCredentials matching it, if any.
+//
+// LOCKS_EXCLUDED(bm.mu)
+func (bm *bucketManager) bucketHandle(ctx context.Context, name string) (b gcs.Bucket, err error) {
+	i := storage.MatchBucketCredentials(bm.config.BucketCredentials, name)
+	if i < 0 {
+		b = bm.storageHandle.BucketHandle(name, bm.config.BillingProject)
+		return
+	}
+
+	creds := &bm.config.BucketCredentials[i]
+	billingProject := bm.config.BillingProject
+	if creds.BillingProject != "" {
+		billingProject = creds.BillingProject
+	}
+
+	bm.mu.Lock()
+	sh, ok := bm.credentialsHandles[i]
+	bm.mu.Unlock()
+
+	if !ok {
+		// Create the handle without holding the lock, as it may fetch tokens,
+		// and keep the first one installed if another was created meanwhile.
+		sh, err = bm.newStorageHandle(ctx, creds.ClientConfig(bm.config.StorageClientConfig))
+		if err != nil {
+			err = fmt.Errorf("creating the storage handle of the buckets matching %q: %w", creds.Bucket, err)
+			return
+		}
+
+		bm.mu.Lock()
+		if installed, ok := bm.credentialsHandles[i]; ok {
+			sh = installed
+		} else {
+			if bm.credentialsHandles == nil {
+				bm.credentialsHandles = make(map[int]storage.StorageHandle)
+			}
+			bm.credentialsHandles[i] = sh
+		}
+		bm.mu.Unlock()
+	}
+
+	b = sh.BucketHandle(name, billingProject)
+	return
+}
+
 func (bm *bucketManager) SetUpBucket(
 	ctx context.Context,
 	name string,
 	isMultibucketMount bool,
 ) (sb SyncerBucket, err error) {
+	if isMultibucketMount && !bm.bucketAllowed(name) {
+		err = fmt.Errorf("bucket %q isn't allowed by the dynamic-mount config: %w", name, syscall.ENOENT)
+		return
+	}
+
 	var b gcs.Bucket
 	// Set up the appropriate backing bucket.
-	if name == canned.FakeBucketName {
+	if name == canned.FakeBucketName && bm.config.FakeBucketManifest != "" {
+		b, err = canned.LoadFakeBucket(ctx, bm.config.FakeBucketManifest)
+		if err != nil {
+			err = fmt.Errorf("LoadFakeBucket: %w", err)
+			return
+		}
+	} else if name == canned.FakeBucketName {
 		b = canned.MakeFakeBucket(ctx)
 	} else {
-		b = bm.storageHandle.BucketHandle(name, bm.config.BillingProject)
+		b, err = bm.bucketHandle(ctx, name)
+		if err != nil {
+			return
+		}
+	}
+
+	// Inject faults, as close to the backing bucket as possible.
+	if bm.config.Faults != nil {
+		b = faults.NewBucket(bm.config.Faults.Rules, bm.config.Faults.RandomSeed, b)
+	}
+
+	// Record a span per GCS request.
+	if bm.config.EnableTracing {
+		b = monitor.NewTracingBucket(b)
 	}
 
 	// Enable monitoring.
@@ -173,6 +341,21 @@ func (bm *bucketManager) SetUpBucket(
 	// Enable gcs logs.
 	b = storage.NewDebugBucket(b)
 
+	// Hedge the slow requests, within each attempt of the retries.
+	if bm.config.Hedging.Enabled {
+		b = hedging.NewBucket(bm.config.Hedging, b)
+	}
+
+	// Retry the failed requests, logging and recording every attempt.
+	if bm.config.Retry.Enabled() {
+		b = retry.NewBucket(bm.config.Retry, timeutil.RealClock(), b)
+	}
+
+	// Reopen the streams that stall, with the retries of the reads.
+	if bm.config.ReadStall.StallTimeoutSecs > 0 {
+		b = stall.NewBucket(bm.config.ReadStall, b)
+	}
+
 	// Limit to a requested prefix of the bucket, if any.
 	if bm.config.OnlyDir != "" {
 		b, err = NewPrefixBucket(path.Clean(bm.config.OnlyDir)+"/", b)
@@ -193,10 +376,21 @@ func (bm *bucketManager) SetUpBucket(
 		return
 	}
 
+	// Count the requests that aren't served by the stat cache, if requested.
+	if bm.config.Accountant != nil {
+		pathPrefix := ""
+		if isMultibucketMount {
+			pathPrefix = name + "/"
+		}
+		b = accounting.NewAccountingBucket(bm.config.Accountant, pathPrefix, b)
+	}
+
 	// Enable cached StatObject results, if appropriate.
-	if bm.config.StatCacheTTL != 0 && bm.sharedStatCache != nil {
+	if bm.config.StatCacheTTL != 0 && (bm.sharedStatCache != nil || bm.config.SharedMetadataCache != nil) {
 		var statCache metadata.StatCache
-		if isMultibucketMount {
+		if bm.config.SharedMetadataCache != nil {
+			statCache = bm.config.SharedMetadataCache.NewStatCache(name)
+		} else if isMultibucketMount {
 			statCache = metadata.NewStatCacheBucketView(bm.sharedStatCache, name)
 		} else {
 			statCache = metadata.NewStatCacheBucketView(bm.sharedStatCache, "")
@@ -207,6 +401,13 @@ func (bm *bucketManager) SetUpBucket(
 			statCache,
 			timeutil.RealClock(),
 			b)
+
+		bm.mu.Lock()
+		if bm.statCaches == nil {
+			bm.statCaches = make(map[string]metadata.StatCache)
+		}
+		bm.statCaches[name] = statCache
+		bm.mu.Unlock()
 	}
 
 	// Enable content type awareness
@@ -240,6 +441,80 @@ func (bm *bucketManager) SetUpBucket(
 	return
 }
 
+// Return whether the named bucket is allowed by the DynamicMount config.
+func (bm *bucketManager) bucketAllowed(name string) bool {
+	allowlist := bm.config.DynamicMount.BucketAllowlist
+	if len(allowlist) > 0 && !slices.Contains(allowlist, name) {
+		return false
+	}
+
+	return bm.bucketRegex == nil || bm.bucketRegex.MatchString(name)
+}
+
+// LOCKS_EXCLUDED(bm.mu)
+func (bm *bucketManager) ListBuckets(ctx context.Context) (names []string, err error) {
+	project := bm.config.DynamicMount.ListBucketsProject
+	if project == "" {
+		err = fmt.Errorf("listing the buckets requires dynamic-mount: list-buckets-project: %w", syscall.ENOTSUP)
+		return
+	}
+
+	bm.mu.Lock()
+	if bm.clock.Now().Before(bm.bucketNamesExpiration) {
+		names = bm.bucketNames
+		bm.mu.Unlock()
+		return
+	}
+	bm.mu.Unlock()
+
+	// List without holding the lock, which guards the set up of the buckets.
+	listed, err := bm.storageHandle.ListBuckets(ctx, project)
+	if err != nil {
+		return
+	}
+
+	for _, name := range listed {
+		if bm.bucketAllowed(name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	bm.mu.Lock()
+	bm.bucketNames = names
+	bm.bucketNamesExpiration = bm.clock.Now().Add(time.Duration(bm.config.DynamicMount.BucketListTtlSecs) * time.Second)
+	bm.mu.Unlock()
+
+	return
+}
+
+func (bm *bucketManager) InvalidateObject(bucketName string, objectName string) (name string, ok bool) {
+	name = objectName
+	if bm.config.OnlyDir != "" {
+		prefix := path.Clean(bm.config.OnlyDir) + "/"
+		if !strings.HasPrefix(objectName, prefix) {
+			return "", false
+		}
+		name = strings.TrimPrefix(objectName, prefix)
+	}
+
+	bm.mu.Lock()
+	statCache := bm.statCaches[bucketName]
+	bm.mu.Unlock()
+
+	if statCache != nil {
+		statCache.Erase(name)
+	}
+	return name, true
+}
+
+func (bm *bucketManager) StatCache() *lru.Cache {
+	if bm.config.StatCacheTTL == 0 || bm.config.SharedMetadataCache != nil {
+		return nil
+	}
+	return bm.sharedStatCache
+}
+
 func (bm *bucketManager) ShutDown() {
 	bm.stopGarbageCollecting()
 }
@@ -2,12 +2,25 @@ package gcsx
 
 import (
 	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/metadata/shared"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/canned"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/config"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/faults"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/storageutil"
+	. "github.com/jacobsa/oglematchers"
 	. "github.com/jacobsa/ogletest"
+	"github.com/jacobsa/timeutil"
 )
 
 func TestBucketManager(t *testing.T) { RunTests(t) }
@@ -111,6 +124,237 @@ func (t *BucketManagerTest) TestSetUpBucketMethod_IsMultiBucketMountTrue() {
 	ExpectEq(nil, err)
 }
 
+func (t *BucketManagerTest) TestSetUpBucketMethod_FakeBucketManifest() {
+	manifest := path.Join(os.TempDir(), "gcsfuse-fake-bucket-manifest.yaml")
+	AssertEq(nil, os.WriteFile(manifest, []byte("objects: [{name: foo, contents: taco}]\n"), 0644))
+	defer os.Remove(manifest)
+	var bm bucketManager
+	bm.config = BucketConfig{FakeBucketManifest: manifest, TmpObjectPrefix: "TmpObjectPrefix"}
+	bm.gcCtx = context.Background()
+
+	bucket, err := bm.SetUpBucket(context.Background(), canned.FakeBucketName, false)
+
+	AssertEq(nil, err)
+	_, _, err = bucket.StatObject(context.Background(), &gcs.StatObjectRequest{Name: "foo"})
+	ExpectEq(nil, err)
+}
+
+func (t *BucketManagerTest) TestSetUpBucketMethod_InvalidFakeBucketManifest() {
+	var bm bucketManager
+	bm.config = BucketConfig{FakeBucketManifest: path.Join(os.TempDir(), "gcsfuse-missing-manifest.yaml")}
+	bm.gcCtx = context.Background()
+
+	_, err := bm.SetUpBucket(context.Background(), canned.FakeBucketName, false)
+
+	ExpectNe(nil, err)
+}
+
+func (t *BucketManagerTest) TestSetUpBucketMethod_Faults() {
+	var bm bucketManager
+	bm.config = BucketConfig{
+		TmpObjectPrefix: "TmpObjectPrefix",
+		Faults: &faults.Config{
+			Rules: []faults.Rule{{Method: "StatObject", Object: "^foo$", ErrorCode: 503}},
+		},
+	}
+	bm.gcCtx = context.Background()
+
+	bucket, err := bm.SetUpBucket(context.Background(), canned.FakeBucketName, false)
+
+	AssertEq(nil, err)
+	_, _, err = bucket.StatObject(context.Background(), &gcs.StatObjectRequest{Name: "foo"})
+	ExpectThat(err, Error(HasSubstr("fault injected in StatObject")))
+}
+
+func (t *BucketManagerTest) TestSetUpBucketMethod_Retry() {
+	var bm bucketManager
+	bm.config = BucketConfig{
+		TmpObjectPrefix: "TmpObjectPrefix",
+		Faults: &faults.Config{
+			Rules: []faults.Rule{{Method: "StatObject", Count: 1, ErrorCode: 503}},
+		},
+		Retry: config.NewMountConfig().RetryConfig,
+	}
+	bm.config.Retry.Metadata.MaxAttempts = 2
+	bm.config.Retry.Metadata.InitialBackoffMs = 1
+	bm.gcCtx = context.Background()
+
+	bucket, err := bm.SetUpBucket(context.Background(), canned.FakeBucketName, false)
+
+	AssertEq(nil, err)
+	_, _, err = bucket.StatObject(context.Background(), &gcs.StatObjectRequest{Name: "foo"})
+	ExpectEq(nil, err)
+}
+
+func (t *BucketManagerTest) TestSetUpBucketMethod_ReadStall() {
+	var bm bucketManager
+	bm.config = BucketConfig{
+		TmpObjectPrefix: "TmpObjectPrefix",
+		ReadStall:       config.NewMountConfig().ReadStallConfig,
+	}
+	bm.config.ReadStall.StallTimeoutSecs = 1
+	bm.gcCtx = context.Background()
+
+	bucket, err := bm.SetUpBucket(context.Background(), canned.FakeBucketName, false)
+
+	AssertEq(nil, err)
+	m, _, err := bucket.StatObject(context.Background(), &gcs.StatObjectRequest{Name: "foo"})
+	AssertEq(nil, err)
+	rc, err := bucket.NewReader(context.Background(), &gcs.ReadObjectRequest{
+		Name:       "foo",
+		Generation: m.Generation,
+		Range:      &gcs.ByteRange{Start: 0, Limit: m.Size},
+	})
+	AssertEq(nil, err)
+	contents, err := io.ReadAll(rc)
+	AssertEq(nil, err)
+	ExpectEq(m.Size, len(contents))
+	ExpectEq(nil, rc.Close())
+}
+
+// recordingStorageHandle records the billing projects of the buckets it
+// returns.
+type recordingStorageHandle struct {
+	storage.StorageHandle
+	billingProjects []string
+}
+
+func (sh *recordingStorageHandle) BucketHandle(bucketName string, billingProject string) gcs.Bucket {
+	sh.billingProjects = append(sh.billingProjects, billingProject)
+	return sh.StorageHandle.BucketHandle(bucketName, billingProject)
+}
+
+func (t *BucketManagerTest) TestSetUpBucketMethod_BucketCredentials() {
+	var configs []storageutil.StorageClientConfig
+	var handles []*recordingStorageHandle
+	bm := NewBucketManager(BucketConfig{
+		BillingProject:  "mount-project",
+		TmpObjectPrefix: "TmpObjectPrefix",
+		BucketCredentials: []storage.BucketCredentials{
+			{Bucket: "gcsfuse-*", KeyFile: "/etc/key.json", BillingProject: "bucket-project"},
+		},
+		StorageClientConfig: storageutil.StorageClientConfig{TokenUrl: "http://token"},
+	}, t.storageHandle).(*bucketManager)
+	bm.newStorageHandle = func(_ context.Context, c storageutil.StorageClientConfig) (storage.StorageHandle, error) {
+		configs = append(configs, c)
+		handles = append(handles, &recordingStorageHandle{StorageHandle: t.storageHandle})
+		return handles[len(handles)-1], nil
+	}
+	defer bm.ShutDown()
+
+	_, err := bm.SetUpBucket(context.Background(), TestBucketName, true)
+	AssertEq(nil, err)
+	_, err = bm.SetUpBucket(context.Background(), TestBucketName, true)
+	AssertEq(nil, err)
+
+	// A single handle is created for the matching buckets.
+	AssertEq(1, len(handles))
+	ExpectEq("/etc/key.json", configs[0].KeyFile)
+	ExpectEq("", configs[0].TokenUrl)
+	ExpectThat(handles[0].billingProjects, ElementsAre("bucket-project", "bucket-project"))
+}
+
+func (t *BucketManagerTest) TestSetUpBucketMethod_BucketCredentialsNotMatching() {
+	bm := NewBucketManager(BucketConfig{
+		TmpObjectPrefix:   "TmpObjectPrefix",
+		BucketCredentials: []storage.BucketCredentials{{Bucket: "other-*", KeyFile: "/etc/key.json"}},
+	}, t.storageHandle).(*bucketManager)
+	bm.newStorageHandle = func(context.Context, storageutil.StorageClientConfig) (storage.StorageHandle, error) {
+		return nil, errors.New("unexpected storage handle")
+	}
+	defer bm.ShutDown()
+
+	_, err := bm.SetUpBucket(context.Background(), TestBucketName, true)
+
+	ExpectEq(nil, err)
+}
+
+func (t *BucketManagerTest) TestSetUpBucketMethod_BucketCredentialsError() {
+	bm := NewBucketManager(BucketConfig{
+		TmpObjectPrefix:   "TmpObjectPrefix",
+		BucketCredentials: []storage.BucketCredentials{{Bucket: "*", KeyFile: "/etc/key.json"}},
+	}, t.storageHandle).(*bucketManager)
+	bm.newStorageHandle = func(context.Context, storageutil.StorageClientConfig) (storage.StorageHandle, error) {
+		return nil, errors.New("taco")
+	}
+	defer bm.ShutDown()
+
+	_, err := bm.SetUpBucket(context.Background(), TestBucketName, true)
+
+	ExpectThat(err, Error(HasSubstr(`buckets matching "*": taco`)))
+}
+
+// listingStorageHandle lists the given buckets, counting the listings.
+type listingStorageHandle struct {
+	storage.StorageHandle
+	names    []string
+	listings int
+}
+
+func (sh *listingStorageHandle) ListBuckets(ctx context.Context, projectID string) ([]string, error) {
+	sh.listings++
+	return sh.names, nil
+}
+
+func (t *BucketManagerTest) TestListBucketsMethod() {
+	sh := &listingStorageHandle{
+		StorageHandle: t.storageHandle,
+		names:         []string{"team-b-logs", "team-a-models", "team-a-datasets", "team-a-tmp", "other"},
+	}
+	var clock timeutil.SimulatedClock
+	clock.SetTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	bm := NewBucketManager(BucketConfig{
+		DynamicMount: config.DynamicMountConfig{
+			ListBucketsProject: "project",
+			BucketAllowlist:    []string{"team-a-datasets", "team-a-models", "team-b-logs"},
+			BucketRegex:        "^team-a-",
+			BucketListTtlSecs:  60,
+		},
+	}, sh).(*bucketManager)
+	bm.clock = &clock
+	defer bm.ShutDown()
+
+	names, err := bm.ListBuckets(context.Background())
+
+	AssertEq(nil, err)
+	ExpectThat(names, ElementsAre("team-a-datasets", "team-a-models"))
+
+	// The listing is cached until it expires.
+	clock.AdvanceTime(59 * time.Second)
+	_, err = bm.ListBuckets(context.Background())
+	AssertEq(nil, err)
+	ExpectEq(1, sh.listings)
+
+	clock.AdvanceTime(time.Second)
+	_, err = bm.ListBuckets(context.Background())
+	AssertEq(nil, err)
+	ExpectEq(2, sh.listings)
+}
+
+func (t *BucketManagerTest) TestListBucketsMethod_NoProject() {
+	bm := NewBucketManager(BucketConfig{}, t.storageHandle)
+	defer bm.ShutDown()
+
+	_, err := bm.ListBuckets(context.Background())
+
+	ExpectTrue(errors.Is(err, syscall.ENOTSUP))
+}
+
+func (t *BucketManagerTest) TestSetUpBucketMethod_NotAllowed() {
+	bm := NewBucketManager(BucketConfig{
+		TmpObjectPrefix: "TmpObjectPrefix",
+		DynamicMount:    config.DynamicMountConfig{BucketRegex: "^team-a-"},
+	}, t.storageHandle)
+	defer bm.ShutDown()
+
+	_, err := bm.SetUpBucket(context.Background(), TestBucketName, true)
+	ExpectTrue(errors.Is(err, syscall.ENOENT))
+
+	// The config only applies to the mounts of all the buckets.
+	_, err = bm.SetUpBucket(context.Background(), TestBucketName, false)
+	ExpectEq(nil, err)
+}
+
 func (t *BucketManagerTest) TestSetUpBucketMethodWhenBucketDoesNotExist() {
 	var bm bucketManager
 	bucketConfig := BucketConfig{
@@ -160,3 +404,88 @@ func (t *BucketManagerTest) TestSetUpBucketMethodWhenBucketDoesNotExist_IsMultiB
 	ExpectEq("Error in iterating through objects: storage: bucket doesn't exist", err.Error())
 	ExpectNe(nil, bucket.Syncer)
 }
+
+func (t *BucketManagerTest) TestSetUpBucketMethod_WithSharedMetadataCache() {
+	dir, err := os.MkdirTemp("", "bucket_manager_test")
+	AssertEq(nil, err)
+	defer os.RemoveAll(dir)
+	socketPath := path.Join(dir, "sock")
+	server, err := shared.NewServer(1, 1)
+	AssertEq(nil, err)
+	l, err := net.Listen("unix", socketPath)
+	AssertEq(nil, err)
+	go func() { _ = server.Serve(l) }()
+	defer server.Close()
+	client := shared.NewClient(socketPath, "")
+	defer client.Close()
+	bucketConfig := BucketConfig{
+		StatCacheTTL:        time.Hour,
+		TmpObjectPrefix:     "TmpObjectPrefix",
+		SharedMetadataCache: client,
+	}
+	bm := NewBucketManager(bucketConfig, t.storageHandle)
+	defer bm.ShutDown()
+	ctx := context.Background()
+	_, err = storageutil.CreateObject(ctx, t.bucket, "foo", []byte("taco"))
+	AssertEq(nil, err)
+	sb, err := bm.SetUpBucket(ctx, TestBucketName, false)
+	AssertEq(nil, err)
+	_, _, err = sb.StatObject(ctx, &gcs.StatObjectRequest{Name: "foo"})
+	AssertEq(nil, err)
+	err = t.bucket.DeleteObject(ctx, &gcs.DeleteObjectRequest{Name: "foo"})
+	AssertEq(nil, err)
+
+	// The object is still served from the shared cache.
+	m, _, err := sb.StatObject(ctx, &gcs.StatObjectRequest{Name: "foo"})
+
+	AssertEq(nil, err)
+	ExpectEq(len("taco"), m.Size)
+}
+
+func (t *BucketManagerTest) TestInvalidateObject() {
+	bucketConfig := BucketConfig{
+		OnlyDir:            "OnlyDir",
+		StatCacheMaxSizeMB: 1,
+		StatCacheTTL:       time.Hour,
+		TmpObjectPrefix:    "TmpObjectPrefix",
+	}
+	bm := NewBucketManager(bucketConfig, t.storageHandle)
+	defer bm.ShutDown()
+	ctx := context.Background()
+	_, err := storageutil.CreateObject(ctx, t.bucket, "OnlyDir/foo", []byte("taco"))
+	AssertEq(nil, err)
+	sb, err := bm.SetUpBucket(ctx, TestBucketName, false)
+	AssertEq(nil, err)
+	// Cache the object, then delete it behind the back of the mount.
+	_, _, err = sb.StatObject(ctx, &gcs.StatObjectRequest{Name: "foo"})
+	AssertEq(nil, err)
+	err = t.bucket.DeleteObject(ctx, &gcs.DeleteObjectRequest{Name: "OnlyDir/foo"})
+	AssertEq(nil, err)
+	_, _, err = sb.StatObject(ctx, &gcs.StatObjectRequest{Name: "foo"})
+	AssertEq(nil, err)
+
+	name, ok := bm.InvalidateObject(TestBucketName, "OnlyDir/foo")
+
+	ExpectTrue(ok)
+	ExpectEq("foo", name)
+	_, _, err = sb.StatObject(ctx, &gcs.StatObjectRequest{Name: "foo"})
+	var notFoundErr *gcs.NotFoundError
+	ExpectTrue(errors.As(err, &notFoundErr))
+}
+
+func (t *BucketManagerTest) TestInvalidateObject_OutsideOnlyDir() {
+	bm := NewBucketManager(BucketConfig{OnlyDir: "OnlyDir/"}, t.storageHandle)
+
+	_, ok := bm.InvalidateObject(TestBucketName, "OnlyDirectory/foo")
+
+	ExpectFalse(ok)
+}
+
+func (t *BucketManagerTest) TestInvalidateObject_BucketNotSetUp() {
+	bm := NewBucketManager(BucketConfig{}, t.storageHandle)
+
+	name, ok := bm.InvalidateObject(TestBucketName, "foo")
+
+	ExpectTrue(ok)
+	ExpectEq("foo", name)
+}
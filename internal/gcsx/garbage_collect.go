@@ -19,6 +19,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/storageutil"
 	"golang.org/x/net/context"
@@ -116,6 +117,7 @@ func garbageCollect(
 
 		startTime := time.Now()
 		objectsDeleted, err := garbageCollectOnce(ctx, tmpObjectPrefix, bucket)
+		monitor.CaptureGarbageCollectionMetrics(ctx, objectsDeleted)
 
 		if err != nil {
 			logger.Infof(
@@ -26,9 +26,11 @@ import (
 	cacheutil "github.com/googlecloudplatform/gcsfuse/v2/internal/cache/util"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor/tags"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/util"
 	"github.com/jacobsa/fuse/fuseops"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/context"
 )
 
@@ -179,6 +181,9 @@ func (rr *randomReader) tryReadingFromFileCache(ctx context.Context,
 	requestId := uuid.New()
 	readOp := ctx.Value(ReadOp).(*fuseops.ReadFileOp)
 	logger.Tracef("%.13v <- FileCache(%s:/%s, offset: %d, size: %d handle: %d)", requestId, rr.bucket.Name(), rr.object.Name, offset, len(p), readOp.Handle)
+	ctx, span := monitor.StartSpan(ctx, "file_cache/read",
+		tags.Offset.Int64(offset),
+		tags.Size.Int(len(p)))
 	startTime := time.Now()
 
 	// Response log
@@ -203,6 +208,8 @@ func (rr *randomReader) tryReadingFromFileCache(ctx context.Context,
 		}
 		// Capture file cache metrics to be exported via stackdriver
 		monitor.CaptureFileCacheMetrics(ctx, readType, n, cacheHit, executionTime.Nanoseconds())
+		span.SetAttributes(tags.ReadType.String(readType), tags.CacheHit.Bool(cacheHit))
+		monitor.EndSpan(span, err)
 	}()
 
 	// Create fileCacheHandle if not already.
@@ -350,6 +357,7 @@ func (rr *randomReader) ReadAt(
 			// have hit the limit above.
 			if rr.reader != nil {
 				err = fmt.Errorf("Reader returned %d too few bytes", rr.limit-rr.start)
+				rr.discardReader()
 				return
 			}
 
@@ -358,6 +366,7 @@ func (rr *randomReader) ReadAt(
 		case err != nil:
 			// Propagate other errors.
 			err = fmt.Errorf("readFull: %w", err)
+			rr.discardReader()
 			return
 		}
 	}
@@ -365,6 +374,18 @@ func (rr *randomReader) ReadAt(
 	return
 }
 
+// Don't attempt to reuse a reader once it failed, e.g. after the connection
+// dropped: the next read starts a new one.
+func (rr *randomReader) discardReader() {
+	if rr.reader == nil {
+		return
+	}
+
+	rr.reader.Close()
+	rr.reader = nil
+	rr.cancel = nil
+}
+
 func (rr *randomReader) Object() (o *gcs.MinObject) {
 	o = rr.object
 	return
@@ -481,8 +502,9 @@ func (rr *randomReader) startRead(
 		end = start + maxSizeToReadFromGCS
 	}
 
-	// Begin the read.
-	ctx, cancel := context.WithCancel(context.Background())
+	// Begin the read. The reader outlives this request, so it is not cancelled
+	// along with it, but the request's span is kept as the parent of the read.
+	ctx, cancel := context.WithCancel(trace.ContextWithSpan(context.Background(), trace.SpanFromContext(ctx)))
 	rc, err := rr.bucket.NewReader(
 		ctx,
 		&gcs.ReadObjectRequest{
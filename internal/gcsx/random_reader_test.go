@@ -32,12 +32,15 @@ import (
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/lru"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/util"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/fake"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/faults"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
 	testutil "github.com/googlecloudplatform/gcsfuse/v2/internal/util"
 	"github.com/jacobsa/fuse/fuseops"
 	. "github.com/jacobsa/oglematchers"
 	. "github.com/jacobsa/oglemock"
 	. "github.com/jacobsa/ogletest"
+	"github.com/jacobsa/timeutil"
 	"golang.org/x/net/context"
 )
 
@@ -308,6 +311,37 @@ func (t *RandomReaderTest) ReaderFails() {
 	ExpectThat(err, Error(HasSubstr(iotest.ErrTimeout.Error())))
 }
 
+func (t *RandomReaderTest) ReaderFails_RetrySucceeds() {
+	// A bucket whose first reader drops the connection after 5 bytes.
+	contents := "abcdefghijklmnopq"
+	truncateAfter := int64(5)
+	bucket := fake.NewFakeBucket(timeutil.RealClock(), "bucket")
+	o, err := bucket.CreateObject(t.rr.ctx, &gcs.CreateObjectRequest{
+		Name:     t.object.Name,
+		Contents: strings.NewReader(contents),
+	})
+	AssertEq(nil, err)
+	t.object.Generation = o.Generation
+	t.rr.wrapped.bucket = faults.NewBucket(
+		[]faults.Rule{{Method: "NewReader", Count: 1, TruncateAfter: &truncateAfter}},
+		1,
+		bucket)
+
+	// The first read fails after 5 bytes.
+	buf := make([]byte, len(contents))
+	n, _, err := t.rr.ReadAt(buf, 0)
+
+	ExpectThat(err, Error(HasSubstr("truncated after 5 bytes")))
+	AssertEq(5, n)
+	ExpectEq(nil, t.rr.wrapped.reader)
+
+	// Reading the rest starts a new reader.
+	m, _, err := t.rr.ReadAt(buf[n:], int64(n))
+
+	AssertEq(nil, err)
+	ExpectEq(contents, string(buf[:n+m]))
+}
+
 func (t *RandomReaderTest) ReaderOvershootsRange() {
 	// Simulate a reader that is supposed to return two more bytes, but actually
 	// returns three when asked to.
@@ -19,6 +19,7 @@ import (
 	"io"
 	"time"
 
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
 	"golang.org/x/net/context"
 )
@@ -200,7 +201,11 @@ func (os *syncer) SyncObject(
 			err = fmt.Errorf("error in seeking: %w", err)
 			return
 		}
-		return os.fullCreator.Create(ctx, objectName, srcObject, sr.Mtime, content)
+		o, err = os.fullCreator.Create(ctx, objectName, srcObject, sr.Mtime, content)
+		if err == nil {
+			monitor.CaptureSyncMetrics(ctx, monitor.SyncMethodFull)
+		}
+		return
 	}
 
 	// Make sure the dirty threshold makes sense.
@@ -231,6 +236,7 @@ func (os *syncer) SyncObject(
 	// Otherwise, we need to create a new generation. If the source object is
 	// long enough, hasn't been dirtied, and has a low enough component count,
 	// then we can make the optimization of not rewriting its contents.
+	syncMethod := monitor.SyncMethodFull
 	if srcSize >= os.appendThreshold &&
 		sr.DirtyThreshold == srcSize &&
 		srcObject.ComponentCount < gcs.MaxComponentCount {
@@ -240,6 +246,7 @@ func (os *syncer) SyncObject(
 			return
 		}
 
+		syncMethod = monitor.SyncMethodCompose
 		o, err = os.appendCreator.Create(ctx, objectName, srcObject, sr.Mtime, content)
 	} else {
 		_, err = content.Seek(0, 0)
@@ -256,6 +263,7 @@ func (os *syncer) SyncObject(
 		err = fmt.Errorf("Create: %w", err)
 		return
 	}
+	monitor.CaptureSyncMetrics(ctx, syncMethod)
 
 	return
 }
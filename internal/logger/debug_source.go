@@ -0,0 +1,73 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// DebugSource is a source of TRACE messages which can be enabled at runtime on
+// its own, without lowering the severity of all the logs to TRACE.
+type DebugSource string
+
+const (
+	// DebugFuse is the source of the FUSE ops received from the kernel.
+	DebugFuse DebugSource = "fuse"
+	// DebugGCS is the source of the requests sent to GCS.
+	DebugGCS DebugSource = "gcs"
+)
+
+var debugSources = map[DebugSource]*atomic.Bool{
+	DebugFuse: new(atomic.Bool),
+	DebugGCS:  new(atomic.Bool),
+}
+
+// SetDebugSource enables or disables the messages of the source. They are
+// written regardless of the severity while the source is enabled.
+func SetDebugSource(source DebugSource, enabled bool) {
+	debugSources[source].Store(enabled)
+}
+
+// DebugSourceEnabled returns whether the messages of the source are written
+// regardless of the severity.
+func DebugSourceEnabled(source DebugSource) bool {
+	return debugSources[source].Load()
+}
+
+// debugSourceHandler writes the messages which the wrapped handler discards
+// because of their level while the source is enabled.
+type debugSourceHandler struct {
+	slog.Handler
+	source DebugSource
+}
+
+func (h *debugSourceHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return DebugSourceEnabled(h.source) || h.Handler.Enabled(ctx, level)
+}
+
+// DebugTracef prints the message of the source with TRACE severity in the
+// specified format, if either the severity is TRACE or the source is enabled.
+func DebugTracef(source DebugSource, format string, v ...interface{}) {
+	ctx := context.Background()
+	h := &debugSourceHandler{Handler: defaultLogger.Handler(), source: source}
+	if !h.Enabled(ctx, LevelTrace) {
+		return
+	}
+	h.Handle(ctx, slog.NewRecord(time.Now(), LevelTrace, fmt.Sprintf(format, v...), 0))
+}
@@ -28,8 +28,16 @@ import (
 // This method is created to support jacobsa/fuse loggers and will be removed
 // after slog support is added.
 func NewLegacyLogger(level slog.Level, prefix string) *log.Logger {
-	var programLevel = new(slog.LevelVar)
-	logger := slog.NewLogLogger(defaultLoggerFactory.handler(programLevel, prefix), level)
-	setLoggingLevel(defaultLoggerFactory.level, programLevel)
-	return logger
+	return slog.NewLogLogger(defaultLoggerFactory.handler(programLevel, prefix), level)
+}
+
+// NewDebugLegacyLogger creates a new legacy logger writing the TRACE messages
+// of the given debug source, which are written either when the severity is
+// TRACE or the source is enabled with SetDebugSource.
+func NewDebugLegacyLogger(source DebugSource, prefix string) *log.Logger {
+	h := &debugSourceHandler{
+		Handler: defaultLoggerFactory.handler(programLevel, prefix),
+		source:  source,
+	}
+	return slog.NewLogLogger(h, LevelTrace)
 }
@@ -42,6 +42,10 @@ const (
 var (
 	defaultLoggerFactory *loggerFactory
 	defaultLogger        *slog.Logger
+
+	// programLevel is the level of all the loggers, so that their severity can
+	// be changed at runtime. It is the only place the level is kept.
+	programLevel = new(slog.LevelVar)
 )
 
 // InitLogFile initializes the logger factory to create loggers that print to
@@ -91,10 +95,10 @@ func InitLogFile(logConfig config.LogConfig) error {
 		sysWriter:       sysWriter,
 		fileWriter:      fileWriter,
 		format:          logConfig.Format,
-		level:           logConfig.Severity,
 		logRotateConfig: logConfig.LogRotateConfig,
 	}
-	defaultLogger = defaultLoggerFactory.newLogger(logConfig.Severity)
+	defaultLogger = defaultLoggerFactory.newLogger()
+	setLoggingLevel(logConfig.Severity, programLevel)
 
 	return nil
 }
@@ -104,10 +108,11 @@ func init() {
 	defaultLoggerFactory = &loggerFactory{
 		file:            nil,
 		format:          defaultFormat,
-		level:           config.INFO, // setting log level to INFO by default
 		logRotateConfig: config.DefaultLogRotateConfig(),
 	}
-	defaultLogger = defaultLoggerFactory.newLogger(config.INFO)
+	defaultLogger = defaultLoggerFactory.newLogger()
+	// setting log level to INFO by default
+	setLoggingLevel(config.INFO, programLevel)
 }
 
 // SetLogFormat updates the log format of default logger.
@@ -116,7 +121,14 @@ func SetLogFormat(format string) {
 		return
 	}
 	defaultLoggerFactory.format = format
-	defaultLogger = defaultLoggerFactory.newLogger(defaultLoggerFactory.level)
+	defaultLogger = defaultLoggerFactory.newLogger()
+}
+
+// SetLogSeverity changes the severity of the logs written from now on by all
+// the loggers, including the ones already created. It may be called while
+// logging.
+func SetLogSeverity(level config.LogSeverity) {
+	setLoggingLevel(level, programLevel)
 }
 
 // Close closes the log file when necessary.
@@ -169,17 +181,15 @@ type loggerFactory struct {
 	file            *os.File
 	sysWriter       *syslog.Writer
 	format          string
-	level           config.LogSeverity
 	logRotateConfig config.LogRotateConfig
 	fileWriter      *lumberjack.Logger
 }
 
-func (f *loggerFactory) newLogger(level config.LogSeverity) *slog.Logger {
+// newLogger creates a new logger at the level of programLevel.
+func (f *loggerFactory) newLogger() *slog.Logger {
 	// create a new logger
-	var programLevel = new(slog.LevelVar)
 	logger := slog.New(f.handler(programLevel, ""))
 	slog.SetDefault(logger)
-	setLoggingLevel(level, programLevel)
 	return logger
 }
 
@@ -18,12 +18,15 @@ import (
 	"bytes"
 	"log/slog"
 	"os"
+	"path"
 	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 const (
@@ -291,7 +294,7 @@ func (t *LoggerTest) TestInitLogFile() {
 	assert.Equal(t.T(), filePath, defaultLoggerFactory.file.Name())
 	assert.Nil(t.T(), defaultLoggerFactory.sysWriter)
 	assert.Equal(t.T(), format, defaultLoggerFactory.format)
-	assert.Equal(t.T(), config.DEBUG, defaultLoggerFactory.level)
+	assert.Equal(t.T(), LevelDebug, programLevel.Level())
 	assert.Equal(t.T(), fileSize, defaultLoggerFactory.logRotateConfig.MaxFileSizeMB)
 	assert.Equal(t.T(), backupFileCount, defaultLoggerFactory.logRotateConfig.BackupFileCount)
 	assert.True(t.T(), defaultLoggerFactory.logRotateConfig.Compress)
@@ -300,7 +303,6 @@ func (t *LoggerTest) TestInitLogFile() {
 func (t *LoggerTest) TestSetLogFormatToText() {
 	defaultLoggerFactory = &loggerFactory{
 		file:            nil,
-		level:           config.INFO, // setting log level to INFO by default
 		logRotateConfig: config.DefaultLogRotateConfig(),
 	}
 
@@ -330,7 +332,7 @@ func (t *LoggerTest) TestSetLogFormatToText() {
 		assert.Equal(t.T(), defaultLoggerFactory.format, test.format)
 		// Create a logger using defaultLoggerFactory that writes to buffer.
 		var buf bytes.Buffer
-		redirectLogsToGivenBuffer(&buf, defaultLoggerFactory.level)
+		redirectLogsToGivenBuffer(&buf, config.INFO)
 		Infof("www.infoExample.com")
 		output := buf.String()
 		// Compare expected and actual log.
@@ -338,3 +340,77 @@ func (t *LoggerTest) TestSetLogFormatToText() {
 		assert.True(t.T(), expectedRegexp.MatchString(output))
 	}
 }
+
+func (t *LoggerTest) TestSetLogSeverity() {
+	var buf bytes.Buffer
+	defaultLogger = slog.New(defaultLoggerFactory.createJsonOrTextHandler(&buf, programLevel, "TestLogs: "))
+	defer SetLogSeverity(config.INFO)
+
+	SetLogSeverity(config.ERROR)
+	Infof("www.infoExample.com")
+	assert.Empty(t.T(), buf.String())
+
+	SetLogSeverity(config.TRACE)
+	Tracef("www.traceExample.com")
+	assert.Regexp(t.T(), jsonTraceString, buf.String())
+	assert.Equal(t.T(), LevelTrace, programLevel.Level())
+}
+
+func (t *LoggerTest) TestSetLogSeverityWhileLogging() {
+	var buf bytes.Buffer
+	defaultLogger = slog.New(defaultLoggerFactory.createJsonOrTextHandler(&buf, programLevel, "TestLogs: "))
+	defer SetLogSeverity(config.INFO)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			Tracef("www.traceExample.com")
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		SetLogSeverity(config.TRACE)
+		SetLogSeverity(config.ERROR)
+	}
+	<-done
+}
+
+func (t *LoggerTest) TestDebugTracef() {
+	var buf bytes.Buffer
+	defaultLogger = slog.New(defaultLoggerFactory.createJsonOrTextHandler(&buf, programLevel, "TestLogs: "))
+	SetLogSeverity(config.INFO)
+	defer SetDebugSource(DebugGCS, false)
+
+	DebugTracef(DebugGCS, "www.%s.com", "traceExample")
+	assert.Empty(t.T(), buf.String())
+
+	SetDebugSource(DebugGCS, true)
+	DebugTracef(DebugFuse, "www.%s.com", "traceExample")
+	assert.Empty(t.T(), buf.String())
+	DebugTracef(DebugGCS, "www.%s.com", "traceExample")
+	assert.Regexp(t.T(), jsonTraceString, buf.String())
+}
+
+func (t *LoggerTest) TestNewDebugLegacyLogger() {
+	filePath := path.Join(t.T().TempDir(), "log.txt")
+	defaultLoggerFactory = &loggerFactory{
+		format:          "json",
+		logRotateConfig: config.DefaultLogRotateConfig(),
+		fileWriter:      &lumberjack.Logger{Filename: filePath},
+	}
+	SetLogSeverity(config.INFO)
+	defer SetDebugSource(DebugFuse, false)
+	l := NewDebugLegacyLogger(DebugFuse, "TestLogs: ")
+
+	l.Printf("www.traceExample.com")
+	SetDebugSource(DebugFuse, true)
+	l.Printf("www.traceExample.com")
+	SetDebugSource(DebugFuse, false)
+	l.Printf("www.traceExample.com")
+
+	content, err := os.ReadFile(filePath)
+	assert.NoError(t.T(), err)
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	assert.Len(t.T(), lines, 1)
+	assert.Regexp(t.T(), jsonTraceString, lines[0])
+}
@@ -0,0 +1,381 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package logparser reconstructs the requests served by gcsfuse from its logs
+// written with --log-format json at trace severity: the FUSE ops logged with
+// --debug_fuse, the GCS requests logged with --debug_gcs and the reads served
+// via the file cache.
+package logparser
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jacobsa/fuse/fuseops"
+)
+
+// Kinds of requests found in the logs.
+const (
+	KindFuse      = "fuse"
+	KindGCS       = "gcs"
+	KindFileCache = "file_cache"
+)
+
+// maxLineSize bounds the size of a log line; longer lines fail the parsing.
+const maxLineSize = 1 << 20
+
+// Request is a request found in the logs, from the line logging its start to
+// the one logging its end.
+type Request struct {
+	// Kind is one of KindFuse, KindGCS and KindFileCache.
+	Kind string `json:"kind"`
+
+	// ID identifies the request among the ones of the same kind in flight.
+	ID string `json:"id"`
+
+	// Op is the FUSE op (e.g. ReadFile) or the GCS method (e.g. StatObject).
+	// It is "Read" for reads via the file cache.
+	Op string `json:"op"`
+
+	// Desc is the description of the request in the log.
+	Desc string `json:"desc"`
+
+	// Path is the path of the file or directory the request is about, in the
+	// mount for FUSE ops and in the bucket otherwise. It is empty if unknown,
+	// e.g. for ops on inodes looked up before the logs start.
+	Path string `json:"path,omitempty"`
+
+	// Inode, PID and Handle describe FUSE ops.
+	Inode  uint64 `json:"inode,omitempty"`
+	PID    uint32 `json:"pid,omitempty"`
+	Handle uint64 `json:"handle,omitempty"`
+
+	// Offset and Size are the range of bytes read or written, if any.
+	Offset int64 `json:"offset,omitempty"`
+	Size   int64 `json:"size,omitempty"`
+
+	// Bucket is the bucket read via the file cache.
+	Bucket string `json:"bucket,omitempty"`
+
+	// CacheHit tells whether a read via the file cache was served from it, and
+	// Sequential whether the read was found sequential.
+	CacheHit   bool `json:"cache_hit,omitempty"`
+	Sequential bool `json:"sequential,omitempty"`
+
+	Start time.Time `json:"start"`
+	// End is zero if the end of the request is not in the logs.
+	End time.Time `json:"end"`
+	// Err is the error the request failed with, if any.
+	Err string `json:"error,omitempty"`
+}
+
+// Finished tells whether the end of the request is in the logs.
+func (r *Request) Finished() bool {
+	return !r.End.IsZero()
+}
+
+// Duration returns the latency of a finished request.
+func (r *Request) Duration() time.Duration {
+	if !r.Finished() {
+		return 0
+	}
+	return r.End.Sub(r.Start)
+}
+
+// Name returns the kind and op of the request, e.g. gcs/StatObject.
+func (r *Request) Name() string {
+	return r.Kind + "/" + r.Op
+}
+
+var (
+	fuseLineRegex = regexp.MustCompile(`^fuse_debug: Op 0x([0-9a-f]+)\s+\S+\] (<-|->) (.*)$`)
+	fuseOpRegex   = regexp.MustCompile(`^(\w+)(?: \((.*)\))?$`)
+	fuseOKRegex   = regexp.MustCompile(`^OK \((.*)\)$`)
+	fuseErrRegex  = regexp.MustCompile(`^Error: (".*")$`)
+
+	gcsLineRegex     = regexp.MustCompile(`^gcs: Req\s+0x([0-9a-f]+): (<-|->) (.*)$`)
+	gcsRequestRegex  = regexp.MustCompile(`^(\w+)\((.*)\)$`)
+	gcsResponseRegex = regexp.MustCompile(`^(.*) \(([^()]+)\): (.*)$`)
+	gcsRangeRegex    = regexp.MustCompile(`\[(\d+), (\d+)\)$`)
+
+	cacheRequestRegex  = regexp.MustCompile(`^(\S+) <- FileCache\((.*?):/(.*), offset: (\d+), size: (\d+) handle: (\d+)\)$`)
+	cacheResponseRegex = regexp.MustCompile(`^(\S+) -> (?:OK \(isSeq: (true|false), hit: (true|false)\)|err: (.*)) \(([^()]+)\)$`)
+
+	quotedRegex = `"((?:[^"\\]|\\.)*)"`
+	fieldRegex  = map[string]*regexp.Regexp{
+		"inode":      regexp.MustCompile(`(?:^|, )inode (\d+)`),
+		"parent":     regexp.MustCompile(`(?:^|, )parent (\d+)`),
+		"name":       regexp.MustCompile(`(?:^|, )name ` + quotedRegex),
+		"pid":        regexp.MustCompile(`(?:^|, )PID (\d+)`),
+		"handle":     regexp.MustCompile(`(?:^|, )handle (\d+)`),
+		"offset":     regexp.MustCompile(`(?:^|, )offset (\d+)`),
+		"bytes":      regexp.MustCompile(`(?:^|, )(\d+) bytes`),
+		"old_parent": regexp.MustCompile(`(?:^|, )old_parent (\d+)`),
+		"old_name":   regexp.MustCompile(`(?:^|, )old_name ` + quotedRegex),
+	}
+	firstQuotedRegex = regexp.MustCompile(quotedRegex)
+)
+
+// jsonLogLine is a line of the logs written with --log-format json.
+type jsonLogLine struct {
+	Timestamp struct {
+		Seconds int64 `json:"seconds"`
+		Nanos   int64 `json:"nanos"`
+	} `json:"timestamp"`
+	Message string `json:"message"`
+}
+
+// Parser reconstructs the requests from the lines of logs fed to it in order.
+type Parser struct {
+	// The requests in the order of their start.
+	requests []*Request
+
+	// The requests in flight, by kind and ID.
+	inFlight map[string]*Request
+
+	// The paths of the inodes in the mount, learnt from the ops creating or
+	// looking them up.
+	inodePaths map[uint64]string
+}
+
+// NewParser returns a Parser which has not seen any line yet.
+func NewParser() *Parser {
+	return &Parser{
+		inFlight:   make(map[string]*Request),
+		inodePaths: map[uint64]string{uint64(fuseops.RootInodeID): ""},
+	}
+}
+
+// Parse reconstructs the requests from the logs read from r.
+func Parse(r io.Reader) ([]*Request, error) {
+	p := NewParser()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		p.ParseLine(scanner.Bytes())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading logs: %w", err)
+	}
+
+	return p.Requests(), nil
+}
+
+// Requests returns the requests seen so far in the order of their start,
+// including the ones not finished yet.
+func (p *Parser) Requests() []*Request {
+	return p.requests
+}
+
+// ParseLine parses a line of the logs. Lines which are not JSON or do not log
+// the start or end of a request are ignored.
+func (p *Parser) ParseLine(line []byte) {
+	var l jsonLogLine
+	if err := json.Unmarshal(line, &l); err != nil {
+		return
+	}
+	t := time.Unix(l.Timestamp.Seconds, l.Timestamp.Nanos).UTC()
+
+	switch msg := l.Message; {
+	case strings.HasPrefix(msg, "fuse_debug: "):
+		p.parseFuseLine(t, msg)
+	case strings.HasPrefix(msg, "gcs: "):
+		p.parseGCSLine(t, msg)
+	case strings.Contains(msg, "FileCache("):
+		p.parseCacheRequestLine(t, msg)
+	default:
+		p.parseCacheResponseLine(t, msg)
+	}
+}
+
+func (p *Parser) start(r *Request) {
+	p.requests = append(p.requests, r)
+	p.inFlight[r.Kind+"/"+r.ID] = r
+}
+
+// finish returns the request in flight of the given kind and ID, if any, and
+// forgets about it.
+func (p *Parser) finish(kind string, id string) *Request {
+	key := kind + "/" + id
+	r := p.inFlight[key]
+	delete(p.inFlight, key)
+	return r
+}
+
+func (p *Parser) childPath(parent uint64, name string) string {
+	parentPath, ok := p.inodePaths[parent]
+	if !ok {
+		return ""
+	}
+	return path.Join(parentPath, name)
+}
+
+func uintField(field string, s string) (v uint64) {
+	if m := fieldRegex[field].FindStringSubmatch(s); m != nil {
+		v, _ = strconv.ParseUint(m[1], 10, 64)
+	}
+	return
+}
+
+func quotedField(field string, s string) (v string, ok bool) {
+	m := fieldRegex[field].FindStringSubmatch(s)
+	if m == nil {
+		return
+	}
+	v, err := strconv.Unquote(`"` + m[1] + `"`)
+	return v, err == nil
+}
+
+func (p *Parser) parseFuseLine(t time.Time, msg string) {
+	m := fuseLineRegex.FindStringSubmatch(msg)
+	if m == nil {
+		return
+	}
+	id, body := m[1], m[3]
+
+	if m[2] == "->" {
+		r := p.finish(KindFuse, id)
+		if r == nil {
+			return
+		}
+		r.End = t
+		if ok := fuseOKRegex.FindStringSubmatch(body); ok != nil {
+			// Learn the path of the inodes the op resolved.
+			if child := uintField("inode", ok[1]); child != 0 && r.Path != "" {
+				p.inodePaths[child] = r.Path
+			}
+		} else if e := fuseErrRegex.FindStringSubmatch(body); e != nil {
+			r.Err, _ = strconv.Unquote(e[1])
+		} else {
+			r.Err = body
+		}
+		return
+	}
+
+	op := fuseOpRegex.FindStringSubmatch(body)
+	if op == nil {
+		return
+	}
+	args := op[2]
+	r := &Request{
+		Kind:   KindFuse,
+		ID:     id,
+		Op:     op[1],
+		Desc:   body,
+		Inode:  uintField("inode", args),
+		PID:    uint32(uintField("pid", args)),
+		Handle: uintField("handle", args),
+		Offset: int64(uintField("offset", args)),
+		Size:   int64(uintField("bytes", args)),
+		Start:  t,
+	}
+	if name, ok := quotedField("name", args); ok {
+		r.Path = p.childPath(uintField("parent", args), name)
+	} else if name, ok := quotedField("old_name", args); ok {
+		r.Path = p.childPath(uintField("old_parent", args), name)
+	} else if r.Inode != 0 {
+		r.Path = p.inodePaths[r.Inode]
+	}
+	p.start(r)
+}
+
+func (p *Parser) parseGCSLine(t time.Time, msg string) {
+	m := gcsLineRegex.FindStringSubmatch(msg)
+	if m == nil {
+		return
+	}
+	id, body := m[1], m[3]
+
+	if m[2] == "->" {
+		resp := gcsResponseRegex.FindStringSubmatch(body)
+		if resp == nil {
+			// E.g. read errors, which are followed by the end of the request.
+			return
+		}
+		r := p.finish(KindGCS, id)
+		if r == nil {
+			return
+		}
+		r.End = t
+		if resp[3] != "OK" {
+			r.Err = resp[3]
+		}
+		return
+	}
+
+	req := gcsRequestRegex.FindStringSubmatch(body)
+	if req == nil {
+		return
+	}
+	r := &Request{
+		Kind:  KindGCS,
+		ID:    id,
+		Op:    req[1],
+		Desc:  body,
+		Start: t,
+	}
+	if q := firstQuotedRegex.FindStringSubmatch(req[2]); q != nil {
+		r.Path, _ = strconv.Unquote(`"` + q[1] + `"`)
+	}
+	if rng := gcsRangeRegex.FindStringSubmatch(req[2]); rng != nil {
+		start, _ := strconv.ParseInt(rng[1], 10, 64)
+		limit, _ := strconv.ParseInt(rng[2], 10, 64)
+		r.Offset, r.Size = start, limit-start
+	}
+	p.start(r)
+}
+
+func (p *Parser) parseCacheRequestLine(t time.Time, msg string) {
+	m := cacheRequestRegex.FindStringSubmatch(msg)
+	if m == nil {
+		return
+	}
+	offset, _ := strconv.ParseInt(m[4], 10, 64)
+	size, _ := strconv.ParseInt(m[5], 10, 64)
+	handle, _ := strconv.ParseUint(m[6], 10, 64)
+	p.start(&Request{
+		Kind:   KindFileCache,
+		ID:     m[1],
+		Op:     "Read",
+		Desc:   strings.TrimPrefix(msg, m[1]+" <- "),
+		Path:   m[3],
+		Bucket: m[2],
+		Handle: handle,
+		Offset: offset,
+		Size:   size,
+		Start:  t,
+	})
+}
+
+func (p *Parser) parseCacheResponseLine(t time.Time, msg string) {
+	m := cacheResponseRegex.FindStringSubmatch(msg)
+	if m == nil {
+		return
+	}
+	r := p.finish(KindFileCache, m[1])
+	if r == nil {
+		return
+	}
+	r.End = t
+	r.Sequential = m[2] == "true"
+	r.CacheHit = m[3] == "true"
+	r.Err = m[4]
+}
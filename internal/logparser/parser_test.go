@@ -0,0 +1,162 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logparser
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const logs = `{"timestamp":{"seconds":1704458059,"nanos":0},"severity":"INFO","message":"Start gcsfuse/2.1.0 for app \"\" using mount point: /mnt"}
+not a json line
+{"timestamp":{"seconds":1704458059,"nanos":100000000},"severity":"TRACE","message":"fuse_debug: Op 0x00000002        connection.go:415] <- LookUpInode (parent 1, name \"dir\", PID 42)"}
+{"timestamp":{"seconds":1704458059,"nanos":101000000},"severity":"TRACE","message":"gcs: Req              0x0: <- StatObject(\"dir\")"}
+{"timestamp":{"seconds":1704458059,"nanos":104000000},"severity":"TRACE","message":"gcs: Req              0x0: -> StatObject(\"dir\") (3ms): gcs.NotFoundError: object dir not found"}
+{"timestamp":{"seconds":1704458059,"nanos":110000000},"severity":"TRACE","message":"fuse_debug: Op 0x00000002        connection.go:497] -> OK (inode 2)"}
+{"timestamp":{"seconds":1704458059,"nanos":200000000},"severity":"TRACE","message":"fuse_debug: Op 0x00000004        connection.go:415] <- LookUpInode (parent 2, name \"a, \\\"b\\\"\", PID 42)"}
+{"timestamp":{"seconds":1704458059,"nanos":201000000},"severity":"TRACE","message":"fuse_debug: Op 0x00000004        connection.go:497] -> OK (inode 3)"}
+{"timestamp":{"seconds":1704458059,"nanos":300000000},"severity":"TRACE","message":"fuse_debug: Op 0x00000006        connection.go:415] <- ReadFile (inode 3, PID 42, handle 7, offset 0, 4096 bytes)"}
+{"timestamp":{"seconds":1704458059,"nanos":301000000},"severity":"TRACE","message":"f41c82a2-c891 <- FileCache(bucket:/dir/a, \"b\", offset: 0, size: 4096 handle: 7)"}
+{"timestamp":{"seconds":1704458059,"nanos":302000000},"severity":"TRACE","message":"gcs: Req              0x1: <- Read(\"dir/a, \\\"b\\\"\", [0, 4096))"}
+{"timestamp":{"seconds":1704458059,"nanos":402000000},"severity":"TRACE","message":"gcs: Req              0x1: -> Read error: unexpected EOF"}
+{"timestamp":{"seconds":1704458059,"nanos":502000000},"severity":"TRACE","message":"gcs: Req              0x1: -> Read(\"dir/a, \\\"b\\\"\", [0, 4096)) (200ms): OK"}
+{"timestamp":{"seconds":1704458059,"nanos":503000000},"severity":"TRACE","message":"f41c82a2-c891 -> OK (isSeq: true, hit: false) (202ms)"}
+{"timestamp":{"seconds":1704458059,"nanos":504000000},"severity":"TRACE","message":"fuse_debug: Op 0x00000006        connection.go:497] -> OK ()"}
+{"timestamp":{"seconds":1704458059,"nanos":600000000},"severity":"TRACE","message":"fuse_debug: Op 0x00000008        connection.go:415] <- Rename (old_parent 2, old_name \"x\", new_parent 1, new_name \"y\", PID 43)"}
+{"timestamp":{"seconds":1704458059,"nanos":650000000},"severity":"TRACE","message":"fuse_debug: Op 0x00000008        connection.go:515] -> Error: \"CloneToChildFile: permission denied\""}
+{"timestamp":{"seconds":1704458059,"nanos":700000000},"severity":"TRACE","message":"fuse_debug: Op 0x0000000a        connection.go:415] <- FlushFile (inode 9, PID 42)"}
+`
+
+func parseLogs(t *testing.T) []*Request {
+	t.Helper()
+	requests, err := Parse(strings.NewReader(logs))
+	require.NoError(t, err)
+	return requests
+}
+
+func at(nanos int) time.Time {
+	return time.Unix(1704458059, int64(nanos)).UTC()
+}
+
+func TestParse(t *testing.T) {
+	requests := parseLogs(t)
+
+	assert.Equal(t, []*Request{
+		{Kind: KindFuse, ID: "00000002", Op: "LookUpInode", Desc: `LookUpInode (parent 1, name "dir", PID 42)`, Path: "dir", PID: 42, Start: at(100000000), End: at(110000000)},
+		{Kind: KindGCS, ID: "0", Op: "StatObject", Desc: `StatObject("dir")`, Path: "dir", Start: at(101000000), End: at(104000000), Err: "gcs.NotFoundError: object dir not found"},
+		{Kind: KindFuse, ID: "00000004", Op: "LookUpInode", Desc: `LookUpInode (parent 2, name "a, \"b\"", PID 42)`, Path: `dir/a, "b"`, PID: 42, Start: at(200000000), End: at(201000000)},
+		{Kind: KindFuse, ID: "00000006", Op: "ReadFile", Desc: "ReadFile (inode 3, PID 42, handle 7, offset 0, 4096 bytes)", Path: `dir/a, "b"`, Inode: 3, PID: 42, Handle: 7, Size: 4096, Start: at(300000000), End: at(504000000)},
+		{Kind: KindFileCache, ID: "f41c82a2-c891", Op: "Read", Desc: `FileCache(bucket:/dir/a, "b", offset: 0, size: 4096 handle: 7)`, Path: `dir/a, "b"`, Bucket: "bucket", Handle: 7, Size: 4096, Sequential: true, Start: at(301000000), End: at(503000000)},
+		{Kind: KindGCS, ID: "1", Op: "Read", Desc: `Read("dir/a, \"b\"", [0, 4096))`, Path: `dir/a, "b"`, Size: 4096, Start: at(302000000), End: at(502000000)},
+		{Kind: KindFuse, ID: "00000008", Op: "Rename", Desc: `Rename (old_parent 2, old_name "x", new_parent 1, new_name "y", PID 43)`, Path: "dir/x", PID: 43, Start: at(600000000), End: at(650000000), Err: "CloneToChildFile: permission denied"},
+		{Kind: KindFuse, ID: "0000000a", Op: "FlushFile", Desc: "FlushFile (inode 9, PID 42)", Inode: 9, PID: 42, Start: at(700000000)},
+	}, requests)
+}
+
+func TestParseCacheHitAndError(t *testing.T) {
+	requests, err := Parse(strings.NewReader(`{"timestamp":{"seconds":1704458059,"nanos":0},"message":"a1 <- FileCache(bucket:/foo, offset: 10, size: 5 handle: 1)"}
+{"timestamp":{"seconds":1704458059,"nanos":0},"message":"b2 <- FileCache(bucket:/foo, offset: 20, size: 5 handle: 1)"}
+{"timestamp":{"seconds":1704458059,"nanos":1000},"message":"a1 -> OK (isSeq: false, hit: true) (1µs)"}
+{"timestamp":{"seconds":1704458059,"nanos":2000},"message":"b2 -> err: read failed (2µs)"}
+`))
+
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+	assert.True(t, requests[0].CacheHit)
+	assert.False(t, requests[0].Sequential)
+	assert.Empty(t, requests[0].Err)
+	assert.Equal(t, int64(10), requests[0].Offset)
+	assert.False(t, requests[1].CacheHit)
+	assert.Equal(t, "read failed", requests[1].Err)
+	assert.Equal(t, 2*time.Microsecond, requests[1].Duration())
+}
+
+func TestParseLineTooLong(t *testing.T) {
+	_, err := Parse(strings.NewReader(strings.Repeat("a", maxLineSize+1)))
+
+	assert.ErrorContains(t, err, "reading logs")
+}
+
+func TestLatencyHistograms(t *testing.T) {
+	histograms := LatencyHistograms(parseLogs(t))
+
+	require.Len(t, histograms, 6)
+	assert.Equal(t, []string{"file_cache/Read", "fuse/LookUpInode", "fuse/ReadFile", "fuse/Rename", "gcs/Read", "gcs/StatObject"},
+		[]string{histograms[0].Name, histograms[1].Name, histograms[2].Name, histograms[3].Name, histograms[4].Name, histograms[5].Name})
+	lookUp := histograms[1]
+	assert.Equal(t, 2, lookUp.Count)
+	assert.Equal(t, 0, lookUp.Errors)
+	assert.Equal(t, time.Millisecond, lookUp.P50)
+	assert.Equal(t, 10*time.Millisecond, lookUp.P99)
+	assert.Equal(t, 10*time.Millisecond, lookUp.Max)
+	// 1ms falls in the first bucket and 10ms in the fourth one.
+	assert.Equal(t, []int{1, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, lookUp.Counts)
+	assert.Equal(t, 1, histograms[5].Errors)
+}
+
+func TestSlowest(t *testing.T) {
+	slowest := Slowest(parseLogs(t), 2)
+
+	require.Len(t, slowest, 2)
+	assert.Equal(t, "fuse/ReadFile", slowest[0].Name())
+	assert.Equal(t, 204*time.Millisecond, slowest[0].Duration())
+	assert.Equal(t, "file_cache/Read", slowest[1].Name())
+}
+
+func TestTimelines(t *testing.T) {
+	timelines := Timelines(parseLogs(t))
+
+	assert.Len(t, timelines, 3)
+	var names []string
+	for _, r := range timelines[`dir/a, "b"`] {
+		names = append(names, r.Name())
+	}
+	assert.Equal(t, []string{"fuse/LookUpInode", "fuse/ReadFile", "file_cache/Read", "gcs/Read"}, names)
+	assert.Len(t, timelines["dir"], 2)
+	assert.Len(t, timelines["dir/x"], 1)
+}
+
+func TestReadsByHandle(t *testing.T) {
+	requests, err := Parse(strings.NewReader(`{"timestamp":{"seconds":1704458059,"nanos":0},"message":"a1 <- FileCache(bucket:/orphan, offset: 0, size: 5 handle: 3)"}
+{"timestamp":{"seconds":1704458059,"nanos":100},"message":"fuse_debug: Op 0x00000002        connection.go:415] <- ReadFile (inode 6, PID 42, handle 29, offset 0, 4096 bytes)"}
+{"timestamp":{"seconds":1704458059,"nanos":200},"message":"b2 <- FileCache(bucket:/dir/foo, offset: 0, size: 4096 handle: 29)"}
+{"timestamp":{"seconds":1704458059,"nanos":300},"message":"b2 -> OK (isSeq: true, hit: false) (100ns)"}
+{"timestamp":{"seconds":1704458059,"nanos":400},"message":"fuse_debug: Op 0x00000004        connection.go:415] <- ReadFile (inode 6, PID 42, handle 29, offset 4096, 4096 bytes)"}
+{"timestamp":{"seconds":1704458059,"nanos":500},"message":"c3 <- FileCache(bucket:/dir/foo, offset: 4096, size: 4096 handle: 29)"}
+{"timestamp":{"seconds":1704458059,"nanos":600},"message":"c3 -> OK (isSeq: true, hit: true) (100ns)"}
+{"timestamp":{"seconds":1704458059,"nanos":700},"message":"fuse_debug: Op 0x00000006        connection.go:415] <- ReadFile (inode 7, PID 43, handle 30, offset 0, 4096 bytes)"}
+`))
+	require.NoError(t, err)
+
+	reads := ReadsByHandle(requests)
+
+	require.Len(t, reads, 2)
+	assert.Equal(t, uint64(29), reads[0].Handle)
+	assert.Equal(t, uint32(42), reads[0].PID)
+	assert.Equal(t, uint64(6), reads[0].Inode)
+	assert.Equal(t, at(100), reads[0].Start)
+	assert.Equal(t, "bucket", reads[0].Bucket)
+	assert.Equal(t, "dir/foo", reads[0].Object)
+	require.Len(t, reads[0].Reads, 2)
+	assert.False(t, reads[0].Reads[0].CacheHit)
+	assert.True(t, reads[0].Reads[1].CacheHit)
+	assert.Equal(t, int64(4096), reads[0].Reads[1].Offset)
+	assert.Equal(t, uint64(30), reads[1].Handle)
+	assert.Empty(t, reads[1].Reads)
+}
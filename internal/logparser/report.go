@@ -0,0 +1,178 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logparser
+
+import (
+	"sort"
+	"time"
+)
+
+// HistogramBounds are the upper bounds of the buckets of the latency
+// histograms; the last bucket holds the latencies above the last bound.
+var HistogramBounds = []time.Duration{
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	20 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2 * time.Second,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Histogram is the distribution of the latencies of the finished requests of
+// an op.
+type Histogram struct {
+	Name   string `json:"name"`
+	Count  int    `json:"count"`
+	Errors int    `json:"errors"`
+
+	// Counts holds the number of requests per bucket: Counts[i] is the number
+	// of latencies at most HistogramBounds[i] and above the previous bound.
+	Counts []int `json:"counts"`
+
+	P50 time.Duration `json:"p50"`
+	P90 time.Duration `json:"p90"`
+	P99 time.Duration `json:"p99"`
+	Max time.Duration `json:"max"`
+}
+
+// percentile returns the p-th percentile of the sorted latencies.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (len(sorted)*p+99)/100 - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// LatencyHistograms returns the latency histograms of the finished requests
+// per op, sorted by name.
+func LatencyHistograms(requests []*Request) []*Histogram {
+	latencies := make(map[string][]time.Duration)
+	errors := make(map[string]int)
+	for _, r := range requests {
+		if !r.Finished() {
+			continue
+		}
+		latencies[r.Name()] = append(latencies[r.Name()], r.Duration())
+		if r.Err != "" {
+			errors[r.Name()]++
+		}
+	}
+
+	var histograms []*Histogram
+	for name, l := range latencies {
+		sort.Slice(l, func(i, j int) bool { return l[i] < l[j] })
+		h := &Histogram{
+			Name:   name,
+			Count:  len(l),
+			Errors: errors[name],
+			Counts: make([]int, len(HistogramBounds)+1),
+			P50:    percentile(l, 50),
+			P90:    percentile(l, 90),
+			P99:    percentile(l, 99),
+			Max:    l[len(l)-1],
+		}
+		for _, d := range l {
+			h.Counts[sort.Search(len(HistogramBounds), func(i int) bool { return d <= HistogramBounds[i] })]++
+		}
+		histograms = append(histograms, h)
+	}
+	sort.Slice(histograms, func(i, j int) bool { return histograms[i].Name < histograms[j].Name })
+	return histograms
+}
+
+// Slowest returns the n finished requests which took the longest, slowest
+// first.
+func Slowest(requests []*Request, n int) []*Request {
+	var finished []*Request
+	for _, r := range requests {
+		if r.Finished() {
+			finished = append(finished, r)
+		}
+	}
+	sort.SliceStable(finished, func(i, j int) bool { return finished[i].Duration() > finished[j].Duration() })
+	if len(finished) > n {
+		finished = finished[:n]
+	}
+	return finished
+}
+
+// Timelines returns the requests about each file or directory, in the order
+// of their start, by path. Requests of unknown path are left out.
+func Timelines(requests []*Request) map[string][]*Request {
+	timelines := make(map[string][]*Request)
+	for _, r := range requests {
+		if r.Path != "" {
+			timelines[r.Path] = append(timelines[r.Path], r)
+		}
+	}
+	return timelines
+}
+
+// FileReads are the reads of a file through a handle, as served via the file
+// cache.
+type FileReads struct {
+	// Handle, PID and Inode are the ones of the first ReadFile op on the
+	// handle, and Start its start.
+	Handle uint64    `json:"handle"`
+	PID    uint32    `json:"pid"`
+	Inode  uint64    `json:"inode"`
+	Start  time.Time `json:"start"`
+
+	// Bucket and Object are the object read, known from the first read via
+	// the file cache.
+	Bucket string `json:"bucket,omitempty"`
+	Object string `json:"object,omitempty"`
+
+	// Reads are the reads via the file cache through the handle, in the order
+	// of their start.
+	Reads []*Request `json:"reads"`
+}
+
+// ReadsByHandle returns the reads via the file cache grouped by the handle
+// they are made through, in the order of the first ReadFile op on each
+// handle. Reads through handles with no ReadFile op in the logs are left out.
+func ReadsByHandle(requests []*Request) []*FileReads {
+	var all []*FileReads
+	byHandle := make(map[uint64]*FileReads)
+	for _, r := range requests {
+		switch {
+		case r.Kind == KindFuse && r.Op == "ReadFile":
+			if _, ok := byHandle[r.Handle]; !ok {
+				fr := &FileReads{Handle: r.Handle, PID: r.PID, Inode: r.Inode, Start: r.Start}
+				byHandle[r.Handle] = fr
+				all = append(all, fr)
+			}
+
+		case r.Kind == KindFileCache:
+			fr, ok := byHandle[r.Handle]
+			if !ok {
+				continue
+			}
+			if len(fr.Reads) == 0 {
+				fr.Bucket, fr.Object = r.Bucket, r.Path
+			}
+			fr.Reads = append(fr.Reads, r)
+		}
+	}
+	return all
+}
@@ -0,0 +1,58 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor/tags"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var (
+	tokenRefreshCount     metric.Int64Counter
+	tokenRefreshLatencies metric.Float64Histogram
+)
+
+func init() {
+	if err := registerAuthMetrics(Meter()); err != nil {
+		logger.Errorf("Failed to register the auth metrics: %v", err)
+	}
+}
+
+// registerAuthMetrics creates the instruments of the auth metrics with the
+// supplied meter.
+func registerAuthMetrics(meter metric.Meter) (errs error) {
+	var err error
+	tokenRefreshCount, err = meter.Int64Counter("auth/token_refresh_count",
+		metric.WithDescription("The cumulative number of access tokens fetched along with the token source - key_file/token_url/default/impersonation, and whether the fetch succeeded - true/false"))
+	errs = errors.Join(errs, err)
+	tokenRefreshLatencies, err = meter.Float64Histogram("auth/token_refresh_latencies",
+		metric.WithDescription("The cumulative distribution of the latencies of the access token fetches along with the token source"),
+		metric.WithUnit("ms"),
+		DefaultLatencyDistribution)
+	errs = errors.Join(errs, err)
+	return
+}
+
+// CaptureTokenRefreshMetrics records a fetch of an access token from the
+// given source, and how long it took.
+func CaptureTokenRefreshMetrics(ctx context.Context, source string, succeeded bool, latency time.Duration) {
+	tokenRefreshCount.Add(ctx, 1, metric.WithAttributes(tags.TokenSource.String(source), tags.Succeeded.Bool(succeeded)))
+	tokenRefreshLatencies.Record(ctx, float64(latency.Microseconds())/1000, metric.WithAttributes(tags.TokenSource.String(source)))
+}
@@ -16,87 +16,93 @@ package monitor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"time"
 
-	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor/tags"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
-	"go.opencensus.io/plugin/ochttp"
-	"go.opencensus.io/stats"
-	"go.opencensus.io/stats/view"
-	"go.opencensus.io/tag"
+	"go.opentelemetry.io/otel/metric"
 )
 
 var (
-	// OpenCensus measures
-	readBytesCount = stats.Int64("gcs/read_bytes_count", "The number of bytes read from GCS objects.", stats.UnitBytes)
-	readerCount    = stats.Int64("gcs/reader_count", "The number of GCS object readers opened or closed.", stats.UnitDimensionless)
-	requestCount   = stats.Int64("gcs/request_count", "The number of GCS requests processed.", stats.UnitDimensionless)
-	requestLatency = stats.Float64("gcs/request_latency", "The latency of a GCS request.", stats.UnitMilliseconds)
+	// OpenTelemetry instruments
+	readBytesCount metric.Int64Counter
+	readerCount    metric.Int64Counter
+	requestCount   metric.Int64Counter
+	requestLatency metric.Float64Histogram
+	retryCount     metric.Int64Counter
+	hedgeCount     metric.Int64Counter
+	readStallCount metric.Int64Counter
+)
+
+// Reasons a GCS request is retried, or not.
+const (
+	RetryReasonError          = "error"
+	RetryReasonDeadline       = "deadline"
+	RetryReasonBudgetExceeded = "budget_exceeded"
 )
 
 // Initialize the metrics.
 func init() {
-	// OpenCensus views (aggregated measures)
-	if err := view.Register(
-		&view.View{
-			Name:        "gcs/read_bytes_count",
-			Measure:     readBytesCount,
-			Description: "The cumulative number of bytes read from GCS objects.",
-			Aggregation: view.Sum(),
-		},
-		&view.View{
-			Name:        "gcs/reader_count",
-			Measure:     readerCount,
-			Description: "The cumulative number of GCS object readers opened or closed.",
-			Aggregation: view.Sum(),
-			TagKeys:     []tag.Key{tags.IOMethod},
-		},
-		&view.View{
-			Name:        "gcs/request_count",
-			Measure:     requestCount,
-			Description: "The cumulative number of GCS requests processed.",
-			Aggregation: view.Sum(),
-			TagKeys:     []tag.Key{tags.GCSMethod},
-		},
-		&view.View{
-			Name:        "gcs/request_latencies",
-			Measure:     requestLatency,
-			Description: "The cumulative distribution of the GCS request latencies.",
-			Aggregation: ochttp.DefaultLatencyDistribution,
-			TagKeys:     []tag.Key{tags.GCSMethod},
-		}); err != nil {
-		fmt.Printf("Failed to register OpenCensus metrics for GCS client library: %v", err)
+	meter := Meter()
+	var err, errs error
+	readBytesCount, err = meter.Int64Counter("gcs/read_bytes_count",
+		metric.WithDescription("The cumulative number of bytes read from GCS objects."),
+		metric.WithUnit("By"))
+	errs = errors.Join(errs, err)
+	readerCount, err = meter.Int64Counter("gcs/reader_count",
+		metric.WithDescription("The cumulative number of GCS object readers opened or closed."))
+	errs = errors.Join(errs, err)
+	requestCount, err = meter.Int64Counter("gcs/request_count",
+		metric.WithDescription("The cumulative number of GCS requests processed."))
+	errs = errors.Join(errs, err)
+	requestLatency, err = meter.Float64Histogram("gcs/request_latencies",
+		metric.WithDescription("The cumulative distribution of the GCS request latencies."),
+		metric.WithUnit("ms"),
+		DefaultLatencyDistribution)
+	errs = errors.Join(errs, err)
+	retryCount, err = meter.Int64Counter("gcs/retry_count",
+		metric.WithDescription("The cumulative number of GCS requests retried after an error or a missed deadline, or not retried because the retry budget was exceeded, along with the reason - error/deadline/budget_exceeded"))
+	errs = errors.Join(errs, err)
+	hedgeCount, err = meter.Int64Counter("gcs/hedge_count",
+		metric.WithDescription("The cumulative number of duplicate GCS requests sent for slow requests, along with whether the duplicate answered first - true/false"))
+	errs = errors.Join(errs, err)
+	readStallCount, err = meter.Int64Counter("gcs/read_stall_count",
+		metric.WithDescription("The cumulative number of streams of GCS objects reopened after they stalled."))
+	errs = errors.Join(errs, err)
+	if errs != nil {
+		fmt.Printf("Failed to register OpenTelemetry metrics for GCS client library: %v", errs)
 	}
 }
 
 // recordRequest records a request and its latency.
 func recordRequest(ctx context.Context, method string, start time.Time) {
-	if err := stats.RecordWithTags(
-		ctx,
-		[]tag.Mutator{
-			tag.Upsert(tags.GCSMethod, method),
-		},
-		requestCount.M(1),
-	); err != nil {
-		// The error should be caused by a bad tag
-		logger.Errorf("Cannot record request count: %v", err)
-	}
+	attrs := metric.WithAttributes(tags.GCSMethod.String(method))
+	requestCount.Add(ctx, 1, attrs)
 
 	latencyUs := time.Since(start).Microseconds()
 	latencyMs := float64(latencyUs) / 1000.0
-	if err := stats.RecordWithTags(
-		ctx,
-		[]tag.Mutator{
-			tag.Upsert(tags.GCSMethod, method),
-		},
-		requestLatency.M(latencyMs),
-	); err != nil {
-		// The error should be caused by a bad tag
-		logger.Errorf("Cannot record request latency: %v", err)
-	}
+	requestLatency.Record(ctx, latencyMs, attrs)
+}
+
+// CaptureRetryMetrics counts a retry of a GCS request, or a retry denied by the
+// retry budget.
+func CaptureRetryMetrics(ctx context.Context, method string, reason string) {
+	retryCount.Add(ctx, 1, metric.WithAttributes(tags.GCSMethod.String(method), tags.RetryReason.String(reason)))
+}
+
+// CaptureHedgeMetrics counts a duplicate of a slow GCS request, and whether it
+// answered first.
+func CaptureHedgeMetrics(ctx context.Context, method string, won bool) {
+	hedgeCount.Add(ctx, 1, metric.WithAttributes(tags.GCSMethod.String(method), tags.HedgeWon.Bool(won)))
+}
+
+// CaptureReadStallMetrics counts a stream of a GCS object reopened after it
+// stalled.
+func CaptureReadStallMetrics(ctx context.Context) {
+	readStallCount.Add(ctx, 1)
 }
 
 // NewMonitoringBucket returns a gcs.Bucket that exports metrics for monitoring
@@ -197,15 +203,7 @@ func (mb *monitoringBucket) DeleteObject(
 
 // recordReader increments the reader count when it's opened or closed.
 func recordReader(ctx context.Context, ioMethod string) {
-	if err := stats.RecordWithTags(
-		ctx,
-		[]tag.Mutator{
-			tag.Upsert(tags.IOMethod, ioMethod),
-		},
-		readerCount.M(1),
-	); err != nil {
-		logger.Errorf("Cannot record a reader %v: %v", ioMethod, err)
-	}
+	readerCount.Add(ctx, 1, metric.WithAttributes(tags.IOMethod.String(ioMethod)))
 }
 
 // Monitoring on the object reader
@@ -227,7 +225,7 @@ type monitoringReadCloser struct {
 func (mrc *monitoringReadCloser) Read(p []byte) (n int, err error) {
 	n, err = mrc.wrapped.Read(p)
 	if err == nil {
-		stats.Record(mrc.ctx, readBytesCount.M(int64(n)))
+		readBytesCount.Add(mrc.ctx, int64(n))
 	}
 	return
 }
@@ -0,0 +1,133 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor/tags"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Ways a file is written back to GCS on sync.
+const (
+	SyncMethodCompose = "compose"
+	SyncMethodFull    = "full"
+)
+
+var (
+	statCacheLookupCount   metric.Int64Counter
+	typeCacheLookupCount   metric.Int64Counter
+	lruCacheSize           metric.Int64UpDownCounter
+	lruCacheEvictionCount  metric.Int64Counter
+	activeDownloadJobs     metric.Int64UpDownCounter
+	downloadJobCount       metric.Int64Counter
+	pendingUploadBytes     metric.Int64UpDownCounter
+	syncCount              metric.Int64Counter
+	garbageCollectionCount metric.Int64Counter
+)
+
+func init() {
+	if err := registerCacheMetrics(Meter()); err != nil {
+		logger.Errorf("Failed to register the cache metrics: %v", err)
+	}
+}
+
+// registerCacheMetrics creates the instruments of the cache metrics with the
+// supplied meter.
+func registerCacheMetrics(meter metric.Meter) (errs error) {
+	var err error
+	// Metadata cache related metrics
+	statCacheLookupCount, err = meter.Int64Counter("stat_cache/lookup_count",
+		metric.WithDescription("The cumulative number of stat cache lookups along with cache hit - true/false"))
+	errs = errors.Join(errs, err)
+	typeCacheLookupCount, err = meter.Int64Counter("type_cache/lookup_count",
+		metric.WithDescription("The cumulative number of type cache lookups along with cache hit - true/false"))
+	errs = errors.Join(errs, err)
+	lruCacheSize, err = meter.Int64UpDownCounter("lru_cache/size",
+		metric.WithDescription("The current size of the entries of an in-memory LRU cache along with the cache name"))
+	errs = errors.Join(errs, err)
+	lruCacheEvictionCount, err = meter.Int64Counter("lru_cache/eviction_count",
+		metric.WithDescription("The cumulative number of entries evicted from an in-memory LRU cache along with the cache name"))
+	errs = errors.Join(errs, err)
+	// File cache download job related metrics
+	activeDownloadJobs, err = meter.Int64UpDownCounter("file_cache/active_download_jobs",
+		metric.WithDescription("The number of download jobs not yet finished along with their state - NotStarted/Downloading"))
+	errs = errors.Join(errs, err)
+	downloadJobCount, err = meter.Int64Counter("file_cache/download_job_count",
+		metric.WithDescription("The cumulative number of finished download jobs along with their state - Completed/Failed/Invalid"))
+	errs = errors.Join(errs, err)
+	// Upload related metrics
+	pendingUploadBytes, err = meter.Int64UpDownCounter("fs/pending_upload_bytes",
+		metric.WithDescription("The number of bytes of dirty files held in local temp files and not yet uploaded to GCS"),
+		metric.WithUnit("By"))
+	errs = errors.Join(errs, err)
+	syncCount, err = meter.Int64Counter("gcs/sync_count",
+		metric.WithDescription("The cumulative number of files written back to GCS along with the way they were written - compose/full"))
+	errs = errors.Join(errs, err)
+	garbageCollectionCount, err = meter.Int64Counter("gcs/garbage_collection_delete_count",
+		metric.WithDescription("The cumulative number of stale temporary objects deleted by the garbage collector"))
+	errs = errors.Join(errs, err)
+	return
+}
+
+func CaptureStatCacheLookupMetrics(ctx context.Context, cacheHit bool) {
+	statCacheLookupCount.Add(ctx, 1, metric.WithAttributes(tags.CacheHit.String(strconv.FormatBool(cacheHit))))
+}
+
+func CaptureTypeCacheLookupMetrics(ctx context.Context, cacheHit bool) {
+	typeCacheLookupCount.Add(ctx, 1, metric.WithAttributes(tags.CacheHit.String(strconv.FormatBool(cacheHit))))
+}
+
+// CaptureLRUCacheMetrics records the change of the size of the named LRU cache
+// and the number of entries evicted by the change.
+func CaptureLRUCacheMetrics(ctx context.Context, cacheName string, sizeDelta int64, evictions int64) {
+	attrs := metric.WithAttributes(tags.CacheName.String(cacheName))
+	if sizeDelta != 0 {
+		lruCacheSize.Add(ctx, sizeDelta, attrs)
+	}
+	if evictions != 0 {
+		lruCacheEvictionCount.Add(ctx, evictions, attrs)
+	}
+}
+
+// CaptureActiveDownloadJobMetrics records the change of the number of download
+// jobs in the given not yet finished state.
+func CaptureActiveDownloadJobMetrics(ctx context.Context, jobState string, delta int64) {
+	activeDownloadJobs.Add(ctx, delta, metric.WithAttributes(tags.JobState.String(jobState)))
+}
+
+// CaptureFinishedDownloadJobMetrics records a download job reaching the given
+// final state.
+func CaptureFinishedDownloadJobMetrics(ctx context.Context, jobState string) {
+	downloadJobCount.Add(ctx, 1, metric.WithAttributes(tags.JobState.String(jobState)))
+}
+
+func CapturePendingUploadBytesMetrics(ctx context.Context, delta int64) {
+	if delta != 0 {
+		pendingUploadBytes.Add(ctx, delta)
+	}
+}
+
+func CaptureSyncMetrics(ctx context.Context, syncMethod string) {
+	syncCount.Add(ctx, 1, metric.WithAttributes(tags.SyncMethod.String(syncMethod)))
+}
+
+func CaptureGarbageCollectionMetrics(ctx context.Context, objectsDeleted uint64) {
+	garbageCollectionCount.Add(ctx, int64(objectsDeleted))
+}
@@ -0,0 +1,139 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor/tags"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+var (
+	setUpCacheMetrics  sync.Once
+	cacheMetricsReader *sdkmetric.ManualReader
+)
+
+// recordCacheMetrics makes the cache metrics recorded by a meter provider
+// read by the returned reader, instead of the global one, for the rest of the
+// tests. As it is shared by the tests, which may run in parallel, they must
+// compare the values of the metrics before and after recording.
+func recordCacheMetrics(t *testing.T) *sdkmetric.ManualReader {
+	t.Helper()
+	setUpCacheMetrics.Do(func() {
+		cacheMetricsReader = sdkmetric.NewManualReader()
+		provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(cacheMetricsReader))
+		require.NoError(t, registerCacheMetrics(provider.Meter(meterName)))
+	})
+	return cacheMetricsReader
+}
+
+// readMetric returns the sum of the data points of the named metric carrying
+// the given attribute.
+func readMetric(t *testing.T, reader *sdkmetric.ManualReader, name string, attr attribute.KeyValue) int64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	var sum int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			data, ok := m.Data.(metricdata.Sum[int64])
+			require.True(t, ok, "metric %s is not an int64 sum", name)
+			for _, dp := range data.DataPoints {
+				if v, ok := dp.Attributes.Value(attr.Key); ok && v == attr.Value {
+					sum += dp.Value
+				}
+			}
+		}
+	}
+	return sum
+}
+
+func TestCaptureLRUCacheMetrics(t *testing.T) {
+	t.Parallel()
+	reader := recordCacheMetrics(t)
+	ctx := context.Background()
+	attr := tags.CacheName.String("test_cache")
+	size := readMetric(t, reader, "lru_cache/size", attr)
+	evictions := readMetric(t, reader, "lru_cache/eviction_count", attr)
+
+	CaptureLRUCacheMetrics(ctx, "test_cache", 10, 0)
+	CaptureLRUCacheMetrics(ctx, "test_cache", -4, 2)
+
+	assert.Equal(t, size+6, readMetric(t, reader, "lru_cache/size", attr))
+	assert.Equal(t, evictions+2, readMetric(t, reader, "lru_cache/eviction_count", attr))
+}
+
+func TestCaptureDownloadJobMetrics(t *testing.T) {
+	t.Parallel()
+	reader := recordCacheMetrics(t)
+	ctx := context.Background()
+	downloading := tags.JobState.String("Downloading")
+	completed := tags.JobState.String("Completed")
+	active := readMetric(t, reader, "file_cache/active_download_jobs", downloading)
+	finished := readMetric(t, reader, "file_cache/download_job_count", completed)
+
+	CaptureActiveDownloadJobMetrics(ctx, "Downloading", 1)
+	CaptureActiveDownloadJobMetrics(ctx, "Downloading", 1)
+	CaptureActiveDownloadJobMetrics(ctx, "Downloading", -1)
+	CaptureFinishedDownloadJobMetrics(ctx, "Completed")
+
+	assert.Equal(t, active+1, readMetric(t, reader, "file_cache/active_download_jobs", downloading))
+	assert.Equal(t, finished+1, readMetric(t, reader, "file_cache/download_job_count", completed))
+}
+
+func TestCaptureSyncMetrics(t *testing.T) {
+	t.Parallel()
+	reader := recordCacheMetrics(t)
+	ctx := context.Background()
+	compose := tags.SyncMethod.String(SyncMethodCompose)
+	full := tags.SyncMethod.String(SyncMethodFull)
+	composed := readMetric(t, reader, "gcs/sync_count", compose)
+	fullySynced := readMetric(t, reader, "gcs/sync_count", full)
+
+	CaptureSyncMetrics(ctx, SyncMethodCompose)
+	CaptureSyncMetrics(ctx, SyncMethodFull)
+	CaptureSyncMetrics(ctx, SyncMethodFull)
+
+	assert.Equal(t, composed+1, readMetric(t, reader, "gcs/sync_count", compose))
+	assert.Equal(t, fullySynced+2, readMetric(t, reader, "gcs/sync_count", full))
+}
+
+func TestCaptureCacheLookupMetrics(t *testing.T) {
+	t.Parallel()
+	reader := recordCacheMetrics(t)
+	ctx := context.Background()
+	hit := tags.CacheHit.String("true")
+	miss := tags.CacheHit.String("false")
+	statHits := readMetric(t, reader, "stat_cache/lookup_count", hit)
+	typeMisses := readMetric(t, reader, "type_cache/lookup_count", miss)
+
+	CaptureStatCacheLookupMetrics(ctx, true)
+	CaptureTypeCacheLookupMetrics(ctx, false)
+	CaptureTypeCacheLookupMetrics(ctx, true)
+
+	assert.Equal(t, statHits+1, readMetric(t, reader, "stat_cache/lookup_count", hit))
+	assert.Equal(t, typeMisses+1, readMetric(t, reader, "type_cache/lookup_count", miss))
+}
@@ -15,94 +15,260 @@
 package monitor
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
-	"contrib.go.opencensus.io/exporter/ocagent"
-	"contrib.go.opencensus.io/exporter/stackdriver"
+	"cloud.google.com/go/monitoring/apiv3/v2/monitoringpb"
+	cloudmetric "github.com/GoogleCloudPlatform/opentelemetry-operations-go/exporter/metric"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
-	"go.opencensus.io/stats/view"
+	promclient "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
 )
 
-var stackdriverExporter *stackdriver.Exporter
+const (
+	// OTLPProtocolGRPC and OTLPProtocolHTTP are the protocols supported to
+	// export metrics to an OpenTelemetry collector.
+	OTLPProtocolGRPC = "grpc"
+	OTLPProtocolHTTP = "http"
 
-// EnableStackdriverExporter starts to collect monitoring metrics and exports
-// them to Stackdriver iff the given interval is positive.
-func EnableStackdriverExporter(interval time.Duration) error {
-	if interval <= 0 {
-		return nil
+	// The interval at which metrics are exported to an OpenTelemetry collector.
+	otlpExportInterval = 10 * time.Second
+
+	meterName = "github.com/googlecloudplatform/gcsfuse"
+)
+
+// DefaultLatencyDistribution has the same bucket boundaries, in milliseconds,
+// as the latency distribution of OpenCensus, which gcsfuse used to report
+// latencies with.
+var DefaultLatencyDistribution = metric.WithExplicitBucketBoundaries(
+	1, 2, 3, 4, 5, 6, 8, 10, 13, 16, 20, 25, 30, 40, 50, 65, 80, 100, 130, 160,
+	200, 250, 300, 400, 500, 650, 800, 1000, 2000, 5000, 10000, 20000, 50000,
+	100000)
+
+// Meter returns the meter through which gcsfuse records its metrics. The
+// instruments it creates report to the exporters once they are enabled.
+func Meter() metric.Meter {
+	return otel.Meter(meterName)
+}
+
+// MetricsConfig says where to export monitoring metrics to. The zero value
+// exports nothing.
+type MetricsConfig struct {
+	// Export metrics to Stackdriver with this interval, if positive.
+	StackdriverExportInterval time.Duration
+
+	// Export metrics to the OpenTelemetry collector at this address (host:port),
+	// if non-empty, with OTLPProtocolGRPC (the default) or OTLPProtocolHTTP.
+	OtelCollectorAddress  string
+	OtelCollectorProtocol string
+
+	// Serve metrics in the Prometheus exposition format at /metrics on this
+	// port, if positive, of this address. An empty address means all
+	// interfaces.
+	PrometheusPort    int
+	PrometheusAddress string
+}
+
+var meterProvider *sdkmetric.MeterProvider
+var prometheusServer *http.Server
+
+// EnableMetricsExporters starts to export the monitoring metrics as
+// configured. An exporter failing to start doesn't prevent the others from
+// starting; the returned error reports all failures.
+func EnableMetricsExporters(cfg MetricsConfig) error {
+	provider, server, err := newMeterProvider(context.Background(), cfg)
+	if provider == nil {
+		return err
 	}
 
-	var err error
-	if stackdriverExporter, err = stackdriver.NewExporter(stackdriver.Options{
-		ReportingInterval: interval,
-		OnError: func(err error) {
-			logger.Errorf("Fail to send metric: %v", err)
-		},
+	meterProvider = provider
+	prometheusServer = server
+	otel.SetMeterProvider(provider)
+	return err
+}
+
+// CloseMetricsExporters ensures all collected metrics are exported and stops
+// the exporters.
+func CloseMetricsExporters() {
+	if prometheusServer != nil {
+		_ = prometheusServer.Shutdown(context.Background())
+	}
+	prometheusServer = nil
+
+	if meterProvider != nil {
+		if err := meterProvider.Shutdown(context.Background()); err != nil {
+			logger.Errorf("Fail to flush metrics: %v", err)
+		}
+	}
+	meterProvider = nil
+}
+
+// newMeterProvider creates a meter provider with a reader per configured
+// exporter, returning nil if there are none.
+func newMeterProvider(ctx context.Context, cfg MetricsConfig) (*sdkmetric.MeterProvider, *http.Server, error) {
+	var opts []sdkmetric.Option
+	var errs error
 
+	if cfg.StackdriverExportInterval > 0 {
+		if reader, err := newStackdriverReader(cfg.StackdriverExportInterval); err != nil {
+			errs = errors.Join(errs, err)
+		} else {
+			opts = append(opts, sdkmetric.WithReader(reader))
+			logger.Info("Stackdriver exporter started")
+		}
+	}
+
+	if cfg.OtelCollectorAddress != "" {
+		if reader, err := newOTLPReader(ctx, cfg.OtelCollectorAddress, cfg.OtelCollectorProtocol); err != nil {
+			errs = errors.Join(errs, err)
+		} else {
+			opts = append(opts, sdkmetric.WithReader(reader))
+			logger.Info("OpenTelemetry collector exporter started")
+		}
+	}
+
+	var server *http.Server
+	if cfg.PrometheusPort > 0 {
+		reader, s, err := newPrometheusReader(cfg.PrometheusAddress, cfg.PrometheusPort)
+		if err != nil {
+			errs = errors.Join(errs, err)
+		} else {
+			opts = append(opts, sdkmetric.WithReader(reader))
+			server = s
+		}
+	}
+
+	if len(opts) == 0 {
+		return nil, nil, errs
+	}
+
+	opts = append(opts, sdkmetric.WithResource(resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName("gcsfuse"),
+	)))
+	return sdkmetric.NewMeterProvider(opts...), server, errs
+}
+
+func newStackdriverReader(interval time.Duration) (sdkmetric.Reader, error) {
+	exporter, err := cloudmetric.New(
 		// For a local metric "http_sent_bytes", the Stackdriver metric type
 		// would be "custom.googleapis.com/gcsfuse/http_sent_bytes", display
 		// name would be "Http sent bytes".
-		MetricPrefix: "custom.googleapis.com/gcsfuse/",
-		GetMetricDisplayName: func(view *view.View) string {
-			name := strings.ReplaceAll(view.Name, "_", " ")
-			if len(name) > 0 {
-				name = strings.ToUpper(name[:1]) + name[1:]
-			}
-			return name
-		},
-	}); err != nil {
-		return fmt.Errorf("create stackdriver exporter: %w", err)
-	}
-	if err = stackdriverExporter.StartMetricsExporter(); err != nil {
-		return fmt.Errorf("start stackdriver exporter: %w", err)
+		cloudmetric.WithMetricDescriptorTypeFormatter(func(m metricdata.Metrics) string {
+			return "custom.googleapis.com/gcsfuse/" + m.Name
+		}),
+		// The exporter has no option for display names, so they are set on the
+		// requests creating the metric descriptors.
+		cloudmetric.WithMonitoringClientOptions(option.WithGRPCDialOption(
+			grpc.WithChainUnaryInterceptor(setStackdriverDisplayName))),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create stackdriver exporter: %w", err)
 	}
 
-	logger.Info("Stackdriver exporter started")
-	return nil
+	return sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(interval)), nil
 }
 
-// CloseStackdriverExporter ensures all collected metrics are sent to
-// Stackdriver and closes the stackdriverExporter.
-func CloseStackdriverExporter() {
-	if stackdriverExporter != nil {
-		stackdriverExporter.StopMetricsExporter()
-		stackdriverExporter.Flush()
+// setStackdriverDisplayName sets the display name of the metric descriptors
+// being created.
+func setStackdriverDisplayName(
+	ctx context.Context,
+	method string,
+	req, reply any,
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption) error {
+	if r, ok := req.(*monitoringpb.CreateMetricDescriptorRequest); ok && r.MetricDescriptor != nil {
+		r.MetricDescriptor.DisplayName = stackdriverDisplayName(r.MetricDescriptor.Name)
 	}
-	stackdriverExporter = nil
+	return invoker(ctx, method, req, reply, cc, opts...)
 }
 
-var ocExporter *ocagent.Exporter
-
-// EnableOpenTelemetryCollectorExporter starts exporting monitoring metrics to
-// the OpenTelemetry Collector at the given address.
-// Details: https://opentelemetry.io/docs/collector/
-func EnableOpenTelemetryCollectorExporter(address string) error {
-	if address == "" {
-		return nil
+func stackdriverDisplayName(name string) string {
+	name = strings.ReplaceAll(name, "_", " ")
+	if len(name) > 0 {
+		name = strings.ToUpper(name[:1]) + name[1:]
 	}
+	return name
+}
 
+// newOTLPReader exports to the collector at the given address, without TLS,
+// as collectors are expected to run next to gcsfuse.
+func newOTLPReader(ctx context.Context, address string, protocol string) (sdkmetric.Reader, error) {
+	var exporter sdkmetric.Exporter
 	var err error
-	if ocExporter, err = ocagent.NewExporter(
-		ocagent.WithAddress(address),
-		ocagent.WithServiceName("gcsfuse"),
-		ocagent.WithReconnectionPeriod(5*time.Second),
-	); err != nil {
-		return fmt.Errorf("create opentelementry collector exporter: %w", err)
+	switch protocol {
+	case "", OTLPProtocolGRPC:
+		exporter, err = otlpmetricgrpc.New(ctx,
+			otlpmetricgrpc.WithEndpoint(address),
+			otlpmetricgrpc.WithInsecure())
+	case OTLPProtocolHTTP:
+		exporter, err = otlpmetrichttp.New(ctx,
+			otlpmetrichttp.WithEndpoint(address),
+			otlpmetrichttp.WithInsecure())
+	default:
+		err = fmt.Errorf("unsupported protocol %q", protocol)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create opentelemetry collector exporter: %w", err)
 	}
 
-	view.RegisterExporter(ocExporter)
-	logger.Info("OpenTelemetry collector exporter started")
-	return nil
+	return sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(otlpExportInterval)), nil
 }
 
-// CloseOpenTelemetryCollectorExporter ensures all collected metrics are sent to
-// the OpenTelemetry Collect and closes the exporter.
-func CloseOpenTelemetryCollectorExporter() {
-	if ocExporter != nil {
-		ocExporter.Stop()
-		ocExporter.Flush()
+// newPrometheusReader serves the metrics at /metrics on the given address and
+// port, with the names they had with the OpenCensus exporter, e.g.
+// fs/ops_count as gcsfuse_fs_ops_count.
+func newPrometheusReader(address string, port int) (sdkmetric.Reader, *http.Server, error) {
+	registry := promclient.NewRegistry()
+	exporter, err := prometheus.New(
+		prometheus.WithRegisterer(registry),
+		prometheus.WithNamespace("gcsfuse"),
+		prometheus.WithoutUnits(),
+		prometheus.WithoutCounterSuffixes(),
+		prometheus.WithoutScopeInfo(),
+		prometheus.WithoutTargetInfo(),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create prometheus exporter: %w", err)
 	}
-	ocExporter = nil
+
+	l, err := net.Listen("tcp", net.JoinHostPort(address, strconv.Itoa(port)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("listen for prometheus exporter: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+		ErrorLog: logger.NewLegacyLogger(logger.LevelError, "prometheus: "),
+	}))
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+			logger.Errorf("Prometheus exporter stopped: %v", err)
+		}
+	}()
+
+	logger.Infof("Prometheus exporter started on %s", l.Addr())
+	return exporter, server, nil
 }
@@ -0,0 +1,189 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/monitoring/apiv3/v2/monitoringpb"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor/tags"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/metric"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricpb "google.golang.org/genproto/googleapis/api/metric"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// collector is an in-process OpenTelemetry collector, recording the names
+// of the metrics it receives.
+type collector struct {
+	colmetricpb.UnimplementedMetricsServiceServer
+
+	mu    sync.Mutex
+	names map[string]bool
+}
+
+func newCollector() *collector {
+	return &collector{names: make(map[string]bool)}
+}
+
+func (c *collector) Export(ctx context.Context, req *colmetricpb.ExportMetricsServiceRequest) (*colmetricpb.ExportMetricsServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, rm := range req.ResourceMetrics {
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				c.names[m.Name] = true
+			}
+		}
+	}
+	return &colmetricpb.ExportMetricsServiceResponse{}, nil
+}
+
+func (c *collector) received(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.names[name]
+}
+
+// ServeHTTP implements the OTLP/HTTP protocol with protobuf encoding.
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/metrics" {
+		http.NotFound(w, r)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req := &colmetricpb.ExportMetricsServiceRequest{}
+	if err = proto.Unmarshal(body, req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp, _ := c.Export(r.Context(), req)
+	out, _ := proto.Marshal(resp)
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	_, _ = w.Write(out)
+}
+
+// recordAndFlush records a value of a counter through a meter provider
+// configured by cfg, and shuts the provider down so that it is exported.
+func recordAndFlush(t *testing.T, cfg MetricsConfig) {
+	t.Helper()
+	ctx := context.Background()
+	provider, _, err := newMeterProvider(ctx, cfg)
+	require.NoError(t, err)
+	require.NotNil(t, provider)
+	counter, err := provider.Meter(meterName).Int64Counter("fs/ops_count")
+	require.NoError(t, err)
+
+	counter.Add(ctx, 1, metric.WithAttributes(tags.FSOp.String("LookUpInode")))
+
+	require.NoError(t, provider.Shutdown(ctx))
+}
+
+func TestOTLPGRPCExport(t *testing.T) {
+	c := newCollector()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	colmetricpb.RegisterMetricsServiceServer(server, c)
+	go func() { _ = server.Serve(l) }()
+	defer server.Stop()
+
+	recordAndFlush(t, MetricsConfig{
+		OtelCollectorAddress:  l.Addr().String(),
+		OtelCollectorProtocol: OTLPProtocolGRPC,
+	})
+
+	assert.True(t, c.received("fs/ops_count"))
+}
+
+func TestOTLPHTTPExport(t *testing.T) {
+	c := newCollector()
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	recordAndFlush(t, MetricsConfig{
+		OtelCollectorAddress:  strings.TrimPrefix(server.URL, "http://"),
+		OtelCollectorProtocol: OTLPProtocolHTTP,
+	})
+
+	assert.True(t, c.received("fs/ops_count"))
+}
+
+func TestUnsupportedOTLPProtocol(t *testing.T) {
+	provider, _, err := newMeterProvider(context.Background(), MetricsConfig{
+		OtelCollectorAddress:  "localhost:4317",
+		OtelCollectorProtocol: "udp",
+	})
+
+	assert.ErrorContains(t, err, "unsupported protocol")
+	assert.Nil(t, provider)
+}
+
+func TestSetStackdriverDisplayName(t *testing.T) {
+	req := &monitoringpb.CreateMetricDescriptorRequest{
+		MetricDescriptor: &metricpb.MetricDescriptor{
+			Name:        "gcs/read_bytes_count",
+			DisplayName: "gcsfuse/gcs/read_bytes_count",
+		},
+	}
+	var invoked bool
+	invoker := func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+		invoked = true
+		return nil
+	}
+
+	err := setStackdriverDisplayName(context.Background(), "CreateMetricDescriptor", req, nil, nil, invoker)
+
+	require.NoError(t, err)
+	assert.True(t, invoked)
+	assert.Equal(t, "Gcs/read bytes count", req.MetricDescriptor.DisplayName)
+}
+
+// TestPrometheusExport goes through the global meter provider, which can only
+// be set once per process, so it is the only test to do so.
+func TestPrometheusExport(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	require.NoError(t, l.Close())
+	require.NoError(t, EnableMetricsExporters(MetricsConfig{PrometheusPort: port, PrometheusAddress: "127.0.0.1"}))
+	defer CloseMetricsExporters()
+
+	recordRequest(context.Background(), "StatObject", time.Now())
+
+	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/metrics", port))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `gcsfuse_gcs_request_count{gcs_method="StatObject"} 1`)
+	assert.Contains(t, string(body), `gcsfuse_gcs_request_latencies_bucket{gcs_method="StatObject",le="1"}`)
+}
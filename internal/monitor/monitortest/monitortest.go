@@ -0,0 +1,83 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package monitortest lets tests read the metrics recorded by the monitor
+// package.
+//
+// The metrics are read through the global meter provider, which is set on
+// first use, so it must not be used along with monitor.EnableMetricsExporters
+// in the same test binary, and metrics recorded before are not seen. As the
+// metrics are shared by all the tests of the binary, tests running in
+// parallel must tell theirs apart by their attributes, or compare values
+// before and after recording.
+package monitortest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+var (
+	setUp  sync.Once
+	reader *sdkmetric.ManualReader
+)
+
+// Int64 returns the sum of the data points of the named int64 metric carrying
+// all the given attributes.
+func Int64(t testing.TB, name string, attrs ...attribute.KeyValue) int64 {
+	t.Helper()
+	setUp.Do(func() {
+		reader = sdkmetric.NewManualReader()
+		otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	})
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+
+	var sum int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			data, ok := m.Data.(metricdata.Sum[int64])
+			if !ok {
+				t.Fatalf("Metric %s is not an int64 sum", name)
+			}
+			for _, dp := range data.DataPoints {
+				if hasAll(dp.Attributes, attrs) {
+					sum += dp.Value
+				}
+			}
+		}
+	}
+	return sum
+}
+
+func hasAll(set attribute.Set, attrs []attribute.KeyValue) bool {
+	for _, attr := range attrs {
+		if v, ok := set.Value(attr.Key); !ok || v != attr.Value {
+			return false
+		}
+	}
+	return true
+}
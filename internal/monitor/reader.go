@@ -15,15 +15,12 @@
 package monitor
 
 import (
-	"log"
+	"errors"
 	"strconv"
 
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor/tags"
-	"go.opencensus.io/plugin/ochttp"
-	"go.opencensus.io/stats"
-	"go.opencensus.io/stats/view"
-	"go.opencensus.io/tag"
+	"go.opentelemetry.io/otel/metric"
 	"golang.org/x/net/context"
 )
 
@@ -32,127 +29,57 @@ var (
 	// Now depending on the pagesize multiple read calls will be issued by user to read the entire file. These
 	// requests will be served from the downloaded data.
 	// This metric captures only the requests made to GCS, not the subsequent page calls.
-	gcsReadCount = stats.Int64("gcs/read_count",
-		"Specifies the number of gcs reads made along with type - Sequential/Random",
-		stats.UnitDimensionless)
-	downloadBytesCount = stats.Int64("gcs/download_bytes_count",
-		"The cumulative number of bytes downloaded from GCS along with type - Sequential/Random",
-		stats.UnitBytes)
-	fileCacheReadCount = stats.Int64("file_cache/read_count",
-		"Specifies the number of read requests made via file cache along with type - Sequential/Random and cache hit - true/false",
-		stats.UnitDimensionless)
-	fileCacheReadBytesCount = stats.Int64("file_cache/read_bytes_count",
-		"The cumulative number of bytes read from file cache along with read type - Sequential/Random",
-		stats.UnitBytes)
-	fileCacheReadLatency = stats.Float64("file_cache/read_latency",
-		"Latency of read from file cache along with cache hit - true/false",
-		stats.UnitMilliseconds)
+	gcsReadCount            metric.Int64Counter
+	downloadBytesCount      metric.Int64Counter
+	fileCacheReadCount      metric.Int64Counter
+	fileCacheReadBytesCount metric.Int64Counter
+	fileCacheReadLatency    metric.Float64Histogram
 )
 
 const NanosecondsInOneMillisecond = 1000000
 
 // Initialize the metrics.
 func init() {
+	meter := Meter()
+	var err, errs error
 	// GCS related metrics
-	if err := view.Register(
-		&view.View{
-			Name:        "gcs/read_count",
-			Measure:     gcsReadCount,
-			Description: "Specifies the number of gcs reads made along with type - Sequential/Random",
-			Aggregation: view.Sum(),
-			TagKeys:     []tag.Key{tags.ReadType},
-		},
-		&view.View{
-			Name:        "gcs/download_bytes_count",
-			Measure:     downloadBytesCount,
-			Description: "The cumulative number of bytes downloaded from GCS along with type - Sequential/Random",
-			Aggregation: view.Sum(),
-			TagKeys:     []tag.Key{tags.ReadType},
-		},
-		// File cache related metrics
-		&view.View{
-			Name:        "file_cache/read_count",
-			Measure:     fileCacheReadCount,
-			Description: "Specifies the number of read requests made via file cache along with type - Sequential/Random and cache hit - true/false",
-			Aggregation: view.Sum(),
-			TagKeys:     []tag.Key{tags.ReadType, tags.CacheHit},
-		},
-		&view.View{
-			Name:        "file_cache/read_bytes_count",
-			Measure:     fileCacheReadBytesCount,
-			Description: "The cumulative number of bytes read from file cache along with read type - Sequential/Random",
-			Aggregation: view.Sum(),
-			TagKeys:     []tag.Key{tags.ReadType},
-		},
-		&view.View{
-			Name:        "file_cache/read_latencies",
-			Measure:     fileCacheReadLatency,
-			Description: "The cumulative distribution of the file cache read latencies along with cache hit - true/false",
-			Aggregation: ochttp.DefaultLatencyDistribution,
-			TagKeys:     []tag.Key{tags.CacheHit},
-		},
-	); err != nil {
-		log.Fatalf("Failed to register the reader view: %v", err)
+	gcsReadCount, err = meter.Int64Counter("gcs/read_count",
+		metric.WithDescription("Specifies the number of gcs reads made along with type - Sequential/Random"))
+	errs = errors.Join(errs, err)
+	downloadBytesCount, err = meter.Int64Counter("gcs/download_bytes_count",
+		metric.WithDescription("The cumulative number of bytes downloaded from GCS along with type - Sequential/Random"),
+		metric.WithUnit("By"))
+	errs = errors.Join(errs, err)
+	// File cache related metrics
+	fileCacheReadCount, err = meter.Int64Counter("file_cache/read_count",
+		metric.WithDescription("Specifies the number of read requests made via file cache along with type - Sequential/Random and cache hit - true/false"))
+	errs = errors.Join(errs, err)
+	fileCacheReadBytesCount, err = meter.Int64Counter("file_cache/read_bytes_count",
+		metric.WithDescription("The cumulative number of bytes read from file cache along with read type - Sequential/Random"),
+		metric.WithUnit("By"))
+	errs = errors.Join(errs, err)
+	fileCacheReadLatency, err = meter.Float64Histogram("file_cache/read_latencies",
+		metric.WithDescription("The cumulative distribution of the file cache read latencies along with cache hit - true/false"),
+		metric.WithUnit("ms"),
+		DefaultLatencyDistribution)
+	errs = errors.Join(errs, err)
+	if errs != nil {
+		logger.Errorf("Failed to register the reader metrics: %v", errs)
 	}
 }
 
 func CaptureGCSReadMetrics(ctx context.Context, readType string, requestedDataSize int64) {
-	if err := stats.RecordWithTags(
-		ctx,
-		[]tag.Mutator{
-			tag.Upsert(tags.ReadType, readType),
-		},
-		gcsReadCount.M(1),
-	); err != nil {
-		// Error in recording gcsReadCount.
-		logger.Errorf("Cannot record gcsReadCount %v", err)
-	}
-
-	if err := stats.RecordWithTags(
-		ctx,
-		[]tag.Mutator{
-			tag.Upsert(tags.ReadType, readType),
-		},
-		downloadBytesCount.M(requestedDataSize),
-	); err != nil {
-		// Error in recording downloadBytesCount.
-		logger.Errorf("Cannot record downloadBytesCount %v", err)
-	}
+	attrs := metric.WithAttributes(tags.ReadType.String(readType))
+	gcsReadCount.Add(ctx, 1, attrs)
+	downloadBytesCount.Add(ctx, requestedDataSize, attrs)
 }
 
 func CaptureFileCacheMetrics(ctx context.Context, readType string, readDataSize int, cacheHit bool, readLatencyNs int64) {
-	if err := stats.RecordWithTags(
-		ctx,
-		[]tag.Mutator{
-			tag.Upsert(tags.ReadType, readType),
-			tag.Upsert(tags.CacheHit, strconv.FormatBool(cacheHit)),
-		},
-		fileCacheReadCount.M(1),
-	); err != nil {
-		// Error in recording fileCacheReadCount.
-		logger.Errorf("Cannot record fileCacheReadCount %v", err)
-	}
-
-	if err := stats.RecordWithTags(
-		ctx,
-		[]tag.Mutator{
-			tag.Upsert(tags.ReadType, readType),
-		},
-		fileCacheReadBytesCount.M(int64(readDataSize)),
-	); err != nil {
-		// Error in recording fileCacheReadBytesCount.
-		logger.Errorf("Cannot record fileCacheReadBytesCount %v", err)
-	}
+	fileCacheReadCount.Add(ctx, 1, metric.WithAttributes(
+		tags.ReadType.String(readType),
+		tags.CacheHit.String(strconv.FormatBool(cacheHit))))
+	fileCacheReadBytesCount.Add(ctx, int64(readDataSize), metric.WithAttributes(tags.ReadType.String(readType)))
 
 	readLatencyMs := float64(readLatencyNs) / float64(NanosecondsInOneMillisecond)
-	if err := stats.RecordWithTags(
-		ctx,
-		[]tag.Mutator{
-			tag.Upsert(tags.CacheHit, strconv.FormatBool(cacheHit)),
-		},
-		fileCacheReadLatency.M(readLatencyMs),
-	); err != nil {
-		// Error in recording fileCacheReadLatency.
-		logger.Errorf("Cannot record fileCacheReadLatency %v", err)
-	}
+	fileCacheReadLatency.Record(ctx, readLatencyMs, metric.WithAttributes(tags.CacheHit.String(strconv.FormatBool(cacheHit))))
 }
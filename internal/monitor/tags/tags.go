@@ -16,25 +16,61 @@
 package tags
 
 import (
-	"go.opencensus.io/tag"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-var (
+const (
 	// IOMethod annotates the event that opens or closes a connection or file.
-	IOMethod = tag.MustNewKey("io_method")
+	IOMethod = attribute.Key("io_method")
 
 	// GCSMethod annotates the method called in the GCS client library.
-	GCSMethod = tag.MustNewKey("gcs_method")
+	GCSMethod = attribute.Key("gcs_method")
 
 	// FSOp annotates the file system op processed.
-	FSOp = tag.MustNewKey("fs_op")
+	FSOp = attribute.Key("fs_op")
 
 	// FSError annotates the file system failed operations with the error type
-	FSError = tag.MustNewKey("fs_error")
+	FSError = attribute.Key("fs_error")
 
 	// ReadType annotates the read operation with the type - Sequential/Random
-	ReadType = tag.MustNewKey("read_type")
+	ReadType = attribute.Key("read_type")
 
 	// CacheHit annotates the read operation from file cache with true or false.
-	CacheHit = tag.MustNewKey("cache_hit")
+	CacheHit = attribute.Key("cache_hit")
+
+	// GCSBucket and GCSObject annotate the spans of GCS requests with the
+	// bucket and object they are about.
+	GCSBucket = attribute.Key("gcs_bucket")
+	GCSObject = attribute.Key("gcs_object")
+
+	// Offset and Size annotate the spans of reads and writes with the range of
+	// bytes they are about.
+	Offset = attribute.Key("offset")
+	Size   = attribute.Key("size")
+
+	// CacheName annotates the metrics of an in-memory cache with the cache
+	// they are about, e.g. stat_cache.
+	CacheName = attribute.Key("cache_name")
+
+	// JobState annotates the file cache download jobs with their state.
+	JobState = attribute.Key("job_state")
+
+	// SyncMethod annotates the upload of a file with the way the object was
+	// written - compose/full.
+	SyncMethod = attribute.Key("sync_method")
+
+	// RetryReason annotates the retries of GCS requests with the reason they
+	// were retried, or not - error/deadline/budget_exceeded.
+	RetryReason = attribute.Key("retry_reason")
+
+	// HedgeWon annotates the duplicates of slow GCS requests with whether they
+	// answered first.
+	HedgeWon = attribute.Key("hedge_won")
+
+	// TokenSource annotates the fetches of access tokens with the source they
+	// are fetched from - key_file/token_url/default/impersonation.
+	TokenSource = attribute.Key("token_source")
+
+	// Succeeded annotates an operation with whether it succeeded.
+	Succeeded = attribute.Key("succeeded")
 )
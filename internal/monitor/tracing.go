@@ -0,0 +1,183 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// TracingExporterOTLP and TracingExporterFile are the supported exporters
+	// of traces.
+	TracingExporterOTLP = "otlp"
+	TracingExporterFile = "file"
+)
+
+// Tracer returns the tracer through which gcsfuse records its spans. The
+// spans are dropped unless tracing is enabled.
+func Tracer() trace.Tracer {
+	return otel.Tracer(meterName)
+}
+
+// StartSpan starts a span with the given name, as a child of the span in ctx
+// if any, and returns a context holding it.
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan ends the span, marking it as failed if err is non-nil.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TracingConfig says where to export traces to. The zero value exports
+// nothing.
+type TracingConfig struct {
+	// Exporter is TracingExporterOTLP, to export to the OpenTelemetry collector
+	// at OtelCollectorAddress with OtelCollectorProtocol, or
+	// TracingExporterFile, to write the spans as JSON to FilePath.
+	Exporter              string
+	OtelCollectorAddress  string
+	OtelCollectorProtocol string
+	FilePath              string
+
+	// The fraction of traces to sample, in [0, 1]. A trace is sampled as a
+	// whole, starting with its root span.
+	SampleRatio float64
+}
+
+var tracerProvider *sdktrace.TracerProvider
+
+// EnableTracing starts to export traces as configured.
+func EnableTracing(cfg TracingConfig) error {
+	provider, err := newTracerProvider(context.Background(), cfg)
+	if err != nil || provider == nil {
+		return err
+	}
+
+	tracerProvider = provider
+	otel.SetTracerProvider(provider)
+	return nil
+}
+
+// CloseTracing ensures all ended spans are exported and stops the exporter.
+func CloseTracing() {
+	if tracerProvider != nil {
+		if err := tracerProvider.Shutdown(context.Background()); err != nil {
+			logger.Errorf("Fail to flush traces: %v", err)
+		}
+	}
+	tracerProvider = nil
+}
+
+// newTracerProvider creates a tracer provider batching the spans to the
+// configured exporter, returning nil if there is none.
+func newTracerProvider(ctx context.Context, cfg TracingConfig) (*sdktrace.TracerProvider, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case "":
+		return nil, nil
+	case TracingExporterOTLP:
+		exporter, err = newOTLPSpanExporter(ctx, cfg.OtelCollectorAddress, cfg.OtelCollectorProtocol)
+	case TracingExporterFile:
+		exporter, err = newFileSpanExporter(cfg.FilePath)
+	default:
+		err = fmt.Errorf("unsupported exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create trace exporter: %w", err)
+	}
+
+	logger.Infof("Tracing with the %s exporter started", cfg.Exporter)
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName("gcsfuse"),
+		)),
+	), nil
+}
+
+// newOTLPSpanExporter exports to the collector at the given address, without
+// TLS, like newOTLPReader.
+func newOTLPSpanExporter(ctx context.Context, address string, protocol string) (sdktrace.SpanExporter, error) {
+	if address == "" {
+		return nil, fmt.Errorf("no opentelemetry collector address")
+	}
+	switch protocol {
+	case "", OTLPProtocolGRPC:
+		return otlptracegrpc.New(ctx,
+			otlptracegrpc.WithEndpoint(address),
+			otlptracegrpc.WithInsecure())
+	case OTLPProtocolHTTP:
+		return otlptracehttp.New(ctx,
+			otlptracehttp.WithEndpoint(address),
+			otlptracehttp.WithInsecure())
+	default:
+		return nil, fmt.Errorf("unsupported protocol %q", protocol)
+	}
+}
+
+// newFileSpanExporter appends the spans to the file at the given path, one
+// JSON object per span.
+func newFileSpanExporter(path string) (sdktrace.SpanExporter, error) {
+	if path == "" {
+		return nil, fmt.Errorf("no trace file")
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open trace file: %w", err)
+	}
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &fileSpanExporter{SpanExporter: exporter, f: f}, nil
+}
+
+// fileSpanExporter closes the file it writes to on shutdown.
+type fileSpanExporter struct {
+	sdktrace.SpanExporter
+	f *os.File
+}
+
+func (e *fileSpanExporter) Shutdown(ctx context.Context) error {
+	err := e.SpanExporter.Shutdown(ctx)
+	if closeErr := e.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,152 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor/tags"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewTracingBucket returns a bucket recording a span per GCS request, named
+// after the method, e.g. gcs/StatObject. The span of NewReader lasts until the
+// reader is closed.
+func NewTracingBucket(b gcs.Bucket) gcs.Bucket {
+	return &tracingBucket{
+		wrapped: b,
+	}
+}
+
+type tracingBucket struct {
+	wrapped gcs.Bucket
+}
+
+func (tb *tracingBucket) startSpan(ctx context.Context, method string, object string) (context.Context, trace.Span) {
+	return StartSpan(ctx, "gcs/"+method,
+		tags.GCSMethod.String(method),
+		tags.GCSBucket.String(tb.wrapped.Name()),
+		tags.GCSObject.String(object))
+}
+
+func (tb *tracingBucket) Name() string {
+	return tb.wrapped.Name()
+}
+
+func (tb *tracingBucket) BucketType() gcs.BucketType {
+	return tb.wrapped.BucketType()
+}
+
+func (tb *tracingBucket) NewReader(
+	ctx context.Context,
+	req *gcs.ReadObjectRequest) (io.ReadCloser, error) {
+	ctx, span := tb.startSpan(ctx, "NewReader", req.Name)
+	if req.Range != nil {
+		span.SetAttributes(
+			tags.Offset.Int64(int64(req.Range.Start)),
+			tags.Size.Int64(int64(req.Range.Limit-req.Range.Start)))
+	}
+
+	rc, err := tb.wrapped.NewReader(ctx, req)
+	if err != nil {
+		EndSpan(span, err)
+		return nil, err
+	}
+	return &tracingReadCloser{wrapped: rc, span: span}, nil
+}
+
+func (tb *tracingBucket) CreateObject(
+	ctx context.Context,
+	req *gcs.CreateObjectRequest) (*gcs.Object, error) {
+	ctx, span := tb.startSpan(ctx, "CreateObject", req.Name)
+	o, err := tb.wrapped.CreateObject(ctx, req)
+	EndSpan(span, err)
+	return o, err
+}
+
+func (tb *tracingBucket) CopyObject(
+	ctx context.Context,
+	req *gcs.CopyObjectRequest) (*gcs.Object, error) {
+	ctx, span := tb.startSpan(ctx, "CopyObject", req.DstName)
+	o, err := tb.wrapped.CopyObject(ctx, req)
+	EndSpan(span, err)
+	return o, err
+}
+
+func (tb *tracingBucket) ComposeObjects(
+	ctx context.Context,
+	req *gcs.ComposeObjectsRequest) (*gcs.Object, error) {
+	ctx, span := tb.startSpan(ctx, "ComposeObjects", req.DstName)
+	o, err := tb.wrapped.ComposeObjects(ctx, req)
+	EndSpan(span, err)
+	return o, err
+}
+
+func (tb *tracingBucket) StatObject(
+	ctx context.Context,
+	req *gcs.StatObjectRequest) (*gcs.MinObject, *gcs.ExtendedObjectAttributes, error) {
+	ctx, span := tb.startSpan(ctx, "StatObject", req.Name)
+	m, e, err := tb.wrapped.StatObject(ctx, req)
+	EndSpan(span, err)
+	return m, e, err
+}
+
+func (tb *tracingBucket) ListObjects(
+	ctx context.Context,
+	req *gcs.ListObjectsRequest) (*gcs.Listing, error) {
+	ctx, span := tb.startSpan(ctx, "ListObjects", req.Prefix)
+	listing, err := tb.wrapped.ListObjects(ctx, req)
+	EndSpan(span, err)
+	return listing, err
+}
+
+func (tb *tracingBucket) UpdateObject(
+	ctx context.Context,
+	req *gcs.UpdateObjectRequest) (*gcs.Object, error) {
+	ctx, span := tb.startSpan(ctx, "UpdateObject", req.Name)
+	o, err := tb.wrapped.UpdateObject(ctx, req)
+	EndSpan(span, err)
+	return o, err
+}
+
+func (tb *tracingBucket) DeleteObject(
+	ctx context.Context,
+	req *gcs.DeleteObjectRequest) error {
+	ctx, span := tb.startSpan(ctx, "DeleteObject", req.Name)
+	err := tb.wrapped.DeleteObject(ctx, req)
+	EndSpan(span, err)
+	return err
+}
+
+// tracingReadCloser ends the span of the NewReader request once closed, so
+// that the span covers the download of the contents.
+type tracingReadCloser struct {
+	wrapped io.ReadCloser
+	span    trace.Span
+	once    sync.Once
+}
+
+func (trc *tracingReadCloser) Read(p []byte) (int, error) {
+	return trc.wrapped.Read(p)
+}
+
+func (trc *tracingReadCloser) Close() error {
+	err := trc.wrapped.Close()
+	trc.once.Do(func() { EndSpan(trc.span, err) })
+	return err
+}
@@ -0,0 +1,88 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor/tags"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/fake"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"github.com/jacobsa/timeutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans makes the global tracer provider record the ended spans.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	return recorder
+}
+
+func TestTracingBucket(t *testing.T) {
+	recorder := recordSpans(t)
+	ctx := context.Background()
+	clock := timeutil.SimulatedClock{}
+	clock.SetTime(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+	bucket := monitor.NewTracingBucket(fake.NewFakeBucket(&clock, "some_bucket"))
+	_, err := bucket.CreateObject(ctx, &gcs.CreateObjectRequest{
+		Name:     "foo",
+		Contents: strings.NewReader("taco"),
+	})
+	require.NoError(t, err)
+	ctx, parent := monitor.StartSpan(ctx, "fs/ReadFile")
+
+	rc, err := bucket.NewReader(ctx, &gcs.ReadObjectRequest{
+		Name:  "foo",
+		Range: &gcs.ByteRange{Start: 1, Limit: 3},
+	})
+	require.NoError(t, err)
+	assert.Len(t, recorder.Ended(), 1)
+	contents, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	_, _, statErr := bucket.StatObject(ctx, &gcs.StatObjectRequest{Name: "bar"})
+	parent.End()
+
+	assert.Equal(t, "ac", string(contents))
+	spans := recorder.Ended()
+	require.Len(t, spans, 4)
+	read := spans[1]
+	assert.Equal(t, "gcs/NewReader", read.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), read.Parent().SpanID())
+	assert.Contains(t, read.Attributes(), tags.GCSBucket.String("some_bucket"))
+	assert.Contains(t, read.Attributes(), tags.GCSObject.String("foo"))
+	assert.Contains(t, read.Attributes(), tags.Offset.Int64(1))
+	assert.Contains(t, read.Attributes(), tags.Size.Int64(2))
+	assert.Equal(t, codes.Unset, read.Status().Code)
+	stat := spans[2]
+	assert.Equal(t, "gcs/StatObject", stat.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), stat.Parent().SpanID())
+	assert.Error(t, statErr)
+	assert.Equal(t, codes.Error, stat.Status().Code)
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileTracing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.json")
+	provider, err := newTracerProvider(context.Background(), TracingConfig{
+		Exporter:    TracingExporterFile,
+		FilePath:    path,
+		SampleRatio: 1,
+	})
+	require.NoError(t, err)
+
+	_, span := provider.Tracer(meterName).Start(context.Background(), "fs/LookUpInode")
+	span.End()
+	require.NoError(t, provider.Shutdown(context.Background()))
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	var exported struct{ Name string }
+	require.NoError(t, json.NewDecoder(f).Decode(&exported))
+	assert.Equal(t, "fs/LookUpInode", exported.Name)
+}
+
+func TestTracingSampleRatio(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.json")
+	provider, err := newTracerProvider(context.Background(), TracingConfig{
+		Exporter:    TracingExporterFile,
+		FilePath:    path,
+		SampleRatio: 0,
+	})
+	require.NoError(t, err)
+
+	_, span := provider.Tracer(meterName).Start(context.Background(), "fs/LookUpInode")
+	span.End()
+	require.NoError(t, provider.Shutdown(context.Background()))
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Empty(t, contents)
+}
+
+func TestNoTracing(t *testing.T) {
+	provider, err := newTracerProvider(context.Background(), TracingConfig{})
+
+	assert.NoError(t, err)
+	assert.Nil(t, provider)
+}
+
+func TestUnsupportedTracingExporter(t *testing.T) {
+	provider, err := newTracerProvider(context.Background(), TracingConfig{Exporter: "zipkin"})
+
+	assert.ErrorContains(t, err, "unsupported exporter")
+	assert.Nil(t, provider)
+}
@@ -61,6 +61,23 @@ func (cp ClientProtocol) IsValid() bool {
 	return false
 }
 
+// Backend is the API the buckets are accessed with.
+type Backend string
+
+const (
+	GCS   Backend = "gcs"
+	S3    Backend = "s3"
+	Local Backend = "local"
+)
+
+func (b Backend) IsValid() bool {
+	switch b {
+	case GCS, S3, Local:
+		return true
+	}
+	return false
+}
+
 // ParseOptions parse an option string in the format accepted by mount(8) and
 // generated for its external mount helpers.
 //
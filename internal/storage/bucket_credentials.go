@@ -0,0 +1,132 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	mountpkg "github.com/googlecloudplatform/gcsfuse/v2/internal/mount"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/storageutil"
+	"gopkg.in/yaml.v3"
+)
+
+// BucketCredentials are the credentials, billing project and client protocol
+// of the buckets whose names match a pattern, e.g. in a mount of all the
+// buckets owned by different projects. The unset fields are those of the
+// mount.
+type BucketCredentials struct {
+	// The name of the buckets, or a pattern matching them, in the syntax of
+	// path.Match, e.g. team-a-*.
+	Bucket string `yaml:"bucket"`
+
+	// The credentials, replacing all those of the mount if any is set.
+	KeyFile                   string `yaml:"key-file"`
+	TokenUrl                  string `yaml:"token-url"`
+	ImpersonateServiceAccount string `yaml:"impersonate-service-account"`
+
+	BillingProject string                  `yaml:"billing-project"`
+	ClientProtocol mountpkg.ClientProtocol `yaml:"client-protocol"`
+}
+
+// The content of a bucket credentials file.
+type bucketCredentialsFile struct {
+	Buckets []BucketCredentials `yaml:"buckets"`
+}
+
+func (c *BucketCredentials) validate() error {
+	if c.Bucket == "" {
+		return fmt.Errorf("bucket must be set")
+	}
+
+	if _, err := path.Match(c.Bucket, ""); err != nil {
+		return fmt.Errorf("invalid bucket pattern %q: %w", c.Bucket, err)
+	}
+
+	if c.KeyFile != "" && c.TokenUrl != "" {
+		return fmt.Errorf("only one of key-file and token-url can be set")
+	}
+
+	if c.ClientProtocol != "" && !c.ClientProtocol.IsValid() {
+		return fmt.Errorf("invalid client-protocol %q", c.ClientProtocol)
+	}
+
+	return nil
+}
+
+// LoadBucketCredentials reads the bucket credentials file at the given path,
+// in YAML or JSON. Relative key-file paths are resolved relative to the
+// directory of the file.
+func LoadBucketCredentials(p string) (creds []BucketCredentials, err error) {
+	buf, err := os.ReadFile(p)
+	if err != nil {
+		return
+	}
+
+	var f bucketCredentialsFile
+	decoder := yaml.NewDecoder(bytes.NewReader(buf))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(&f); err != nil && err != io.EOF {
+		err = fmt.Errorf("invalid bucket credentials file %s: %w", p, err)
+		return
+	}
+
+	for i := range f.Buckets {
+		if err = f.Buckets[i].validate(); err != nil {
+			err = fmt.Errorf("invalid bucket credentials file %s: entry %d: %w", p, i, err)
+			return
+		}
+
+		if keyFile := f.Buckets[i].KeyFile; keyFile != "" && !filepath.IsAbs(keyFile) {
+			f.Buckets[i].KeyFile = filepath.Join(filepath.Dir(p), keyFile)
+		}
+	}
+
+	creds = f.Buckets
+	err = nil
+	return
+}
+
+// MatchBucketCredentials returns the index of the first of the given bucket
+// credentials matching the named bucket, or -1 if none matches.
+func MatchBucketCredentials(creds []BucketCredentials, bucketName string) int {
+	for i := range creds {
+		if ok, _ := path.Match(creds[i].Bucket, bucketName); ok {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// ClientConfig returns the config of the client of the buckets, i.e. the given
+// config of the mount with the fields set by the bucket credentials.
+func (c *BucketCredentials) ClientConfig(config storageutil.StorageClientConfig) storageutil.StorageClientConfig {
+	if c.KeyFile != "" || c.TokenUrl != "" || c.ImpersonateServiceAccount != "" {
+		config.KeyFile = c.KeyFile
+		config.TokenUrl = c.TokenUrl
+		config.ImpersonateServiceAccount = c.ImpersonateServiceAccount
+	}
+
+	if c.ClientProtocol != "" {
+		config.ClientProtocol = c.ClientProtocol
+	}
+
+	return config
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	mountpkg "github.com/googlecloudplatform/gcsfuse/v2/internal/mount"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/storageutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeBucketCredentials(t *testing.T, contents string) string {
+	p := path.Join(t.TempDir(), "buckets.yaml")
+	require.NoError(t, os.WriteFile(p, []byte(contents), 0600))
+	return p
+}
+
+func TestLoadBucketCredentials(t *testing.T) {
+	p := writeBucketCredentials(t, `
+buckets:
+  - bucket: team-a-*
+    key-file: /etc/team-a.json
+    billing-project: team-a
+  - bucket: shared
+    impersonate-service-account: reader@p.iam.gserviceaccount.com
+    client-protocol: grpc
+`)
+
+	creds, err := LoadBucketCredentials(p)
+
+	require.NoError(t, err)
+	assert.Equal(t, []BucketCredentials{
+		{Bucket: "team-a-*", KeyFile: "/etc/team-a.json", BillingProject: "team-a"},
+		{Bucket: "shared", ImpersonateServiceAccount: "reader@p.iam.gserviceaccount.com", ClientProtocol: mountpkg.GRPC},
+	}, creds)
+}
+
+func TestLoadBucketCredentials_RelativeKeyFile(t *testing.T) {
+	p := writeBucketCredentials(t, "buckets:\n  - bucket: a\n    key-file: keys/a.json\n")
+
+	creds, err := LoadBucketCredentials(p)
+
+	require.NoError(t, err)
+	require.Len(t, creds, 1)
+	assert.Equal(t, path.Join(path.Dir(p), "keys/a.json"), creds[0].KeyFile)
+}
+
+func TestLoadBucketCredentials_Empty(t *testing.T) {
+	creds, err := LoadBucketCredentials(writeBucketCredentials(t, ""))
+
+	assert.NoError(t, err)
+	assert.Empty(t, creds)
+}
+
+func TestLoadBucketCredentials_Invalid(t *testing.T) {
+	testCases := []struct {
+		name     string
+		contents string
+		err      string
+	}{
+		{"unknown field", "buckets:\n  - bucket: a\n    keyfile: b\n", "field keyfile not found"},
+		{"no bucket", "buckets:\n  - key-file: b\n", "entry 0: bucket must be set"},
+		{"bad pattern", "buckets:\n  - bucket: a[\n", "invalid bucket pattern"},
+		{"key file and token url", "buckets:\n  - bucket: a\n    key-file: b\n    token-url: http://c\n", "only one of key-file and token-url"},
+		{"bad protocol", "buckets:\n  - bucket: a\n    client-protocol: http3\n", "invalid client-protocol"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := LoadBucketCredentials(writeBucketCredentials(t, tc.contents))
+
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestMatchBucketCredentials(t *testing.T) {
+	creds := []BucketCredentials{{Bucket: "team-a-*"}, {Bucket: "team-a-shared"}, {Bucket: "*"}}
+
+	assert.Equal(t, 0, MatchBucketCredentials(creds, "team-a-shared"))
+	assert.Equal(t, 2, MatchBucketCredentials(creds, "team-b"))
+	assert.Equal(t, -1, MatchBucketCredentials(creds[:2], "team-b"))
+}
+
+func TestBucketCredentialsClientConfig(t *testing.T) {
+	base := storageutil.StorageClientConfig{
+		KeyFile:        "/etc/mount.json",
+		ClientProtocol: mountpkg.HTTP1,
+		UserAgent:      "gcsfuse",
+	}
+
+	// The credentials of the entry replace all those of the mount.
+	c := (&BucketCredentials{Bucket: "a", TokenUrl: "http://token"}).ClientConfig(base)
+	assert.Equal(t, "", c.KeyFile)
+	assert.Equal(t, "http://token", c.TokenUrl)
+	assert.Equal(t, mountpkg.HTTP1, c.ClientProtocol)
+	assert.Equal(t, "gcsfuse", c.UserAgent)
+
+	c = (&BucketCredentials{Bucket: "a", ClientProtocol: mountpkg.GRPC}).ClientConfig(base)
+	assert.Equal(t, "/etc/mount.json", c.KeyFile)
+	assert.Equal(t, mountpkg.GRPC, c.ClientProtocol)
+}
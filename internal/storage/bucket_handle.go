@@ -46,6 +46,16 @@ type bucketHandle struct {
 	controlClient StorageControlClient
 }
 
+// Return the handle of the bucket to use for a request, without the retries of
+// the client library if the request is retried by the caller.
+func (bh *bucketHandle) handle(ctx context.Context) *storage.BucketHandle {
+	if noRetries, _ := ctx.Value(gcs.NoClientRetriesField).(bool); noRetries {
+		return bh.bucket.Retryer(storage.WithPolicy(storage.RetryNever))
+	}
+
+	return bh.bucket
+}
+
 func (bh *bucketHandle) Name() string {
 	return bh.bucketName
 }
@@ -62,7 +72,7 @@ func (bh *bucketHandle) BucketType() gcs.BucketType {
 			return bh.bucketType
 		}
 
-		storageLayout, err := bh.getStorageLayout()
+		storageLayout, err := bh.getStorageLayout(context.Background())
 
 		// In case bucket does not exist, set type unknown instead of panic.
 		if err != nil {
@@ -97,7 +107,7 @@ func (bh *bucketHandle) NewReader(
 		length = end - start
 	}
 
-	obj := bh.bucket.Object(req.Name)
+	obj := bh.handle(ctx).Object(req.Name)
 
 	// Switching to the requested generation of object.
 	if req.Generation != 0 {
@@ -112,7 +122,7 @@ func (bh *bucketHandle) NewReader(
 	return obj.NewRangeReader(ctx, start, length)
 }
 func (b *bucketHandle) DeleteObject(ctx context.Context, req *gcs.DeleteObjectRequest) error {
-	obj := b.bucket.Object(req.Name)
+	obj := b.handle(ctx).Object(req.Name)
 
 	// Switching to the requested generation of the object. By default, generation
 	// is 0 which signifies the latest generation. Note: GCS will delete the
@@ -150,7 +160,7 @@ func (b *bucketHandle) StatObject(ctx context.Context,
 	req *gcs.StatObjectRequest) (m *gcs.MinObject, e *gcs.ExtendedObjectAttributes, err error) {
 	var attrs *storage.ObjectAttrs
 	// Retrieving object attrs through Go Storage Client.
-	attrs, err = b.bucket.Object(req.Name).Attrs(ctx)
+	attrs, err = b.handle(ctx).Object(req.Name).Attrs(ctx)
 
 	// If error is of type storage.ErrObjectNotExist
 	if err == storage.ErrObjectNotExist {
@@ -173,7 +183,7 @@ func (b *bucketHandle) StatObject(ctx context.Context,
 }
 
 func (bh *bucketHandle) CreateObject(ctx context.Context, req *gcs.CreateObjectRequest) (o *gcs.Object, err error) {
-	obj := bh.bucket.Object(req.Name)
+	obj := bh.handle(ctx).Object(req.Name)
 
 	// GenerationPrecondition - If non-nil, the object will be created/overwritten
 	// only if the current generation for the object name is equal to the given value.
@@ -236,8 +246,9 @@ func (bh *bucketHandle) CreateObject(ctx context.Context, req *gcs.CreateObjectR
 }
 
 func (b *bucketHandle) CopyObject(ctx context.Context, req *gcs.CopyObjectRequest) (o *gcs.Object, err error) {
-	srcObj := b.bucket.Object(req.SrcName)
-	dstObj := b.bucket.Object(req.DstName)
+	bucket := b.handle(ctx)
+	srcObj := bucket.Object(req.SrcName)
+	dstObj := bucket.Object(req.DstName)
 
 	// Switching to the requested generation of source object.
 	if req.SrcGeneration != 0 {
@@ -297,7 +308,7 @@ func (b *bucketHandle) ListObjects(ctx context.Context, req *gcs.ListObjectsRequ
 		IncludeFoldersAsPrefixes: req.IncludeFoldersAsPrefixes,
 		//MaxResults: , (Field not present in storage.Query of Go Storage Library but present in ListObjectsQuery in Jacobsa code.)
 	}
-	itr := b.bucket.Objects(ctx, query) // Returning iterator to the list of objects.
+	itr := b.handle(ctx).Objects(ctx, query) // Returning iterator to the list of objects.
 	pi := itr.PageInfo()
 	pi.MaxSize = req.MaxResults
 	pi.Token = req.ContinuationToken
@@ -346,7 +357,7 @@ func (b *bucketHandle) ListObjects(ctx context.Context, req *gcs.ListObjectsRequ
 }
 
 func (b *bucketHandle) UpdateObject(ctx context.Context, req *gcs.UpdateObjectRequest) (o *gcs.Object, err error) {
-	obj := b.bucket.Object(req.Name)
+	obj := b.handle(ctx).Object(req.Name)
 
 	if req.Generation != 0 {
 		obj = obj.Generation(req.Generation)
@@ -410,7 +421,8 @@ func (b *bucketHandle) UpdateObject(ctx context.Context, req *gcs.UpdateObjectRe
 }
 
 func (b *bucketHandle) ComposeObjects(ctx context.Context, req *gcs.ComposeObjectsRequest) (o *gcs.Object, err error) {
-	dstObj := b.bucket.Object(req.DstName)
+	bucket := b.handle(ctx)
+	dstObj := bucket.Object(req.DstName)
 
 	dstObjConds := storage.Conditions{}
 	if req.DstMetaGenerationPrecondition != nil {
@@ -436,7 +448,7 @@ func (b *bucketHandle) ComposeObjects(ctx context.Context, req *gcs.ComposeObjec
 	// Converting the req.Sources list to a list of storage.ObjectHandle as expected by the Go Storage Client.
 	var srcObjList []*storage.ObjectHandle
 	for _, src := range req.Sources {
-		currSrcObj := b.bucket.Object(src.Name)
+		currSrcObj := bucket.Object(src.Name)
 		// Switching to requested Generation of the object.
 		// Zero src generation is the latest generation, we are skipping it because by default it will take the latest one
 		if src.Generation != 0 {
@@ -468,11 +480,22 @@ func (b *bucketHandle) ComposeObjects(ctx context.Context, req *gcs.ComposeObjec
 	return
 }
 
+// StorageLayout returns the storage layout of the bucket, failing if the bucket
+// doesn't exist or can't be accessed. It requires the storage control client,
+// created when HNS is enabled.
+func (bh *bucketHandle) StorageLayout(ctx context.Context) (*controlpb.StorageLayout, error) {
+	var nilControlClient *control.StorageControlClient = nil
+	if bh.controlClient == nil || bh.controlClient == nilControlClient {
+		return nil, errors.New("the storage control client is not created, see enable-hns")
+	}
+	return bh.getStorageLayout(ctx)
+}
+
 // TODO: Consider adding this method to the bucket interface if additional
 // layout options are needed in the future.
-func (b *bucketHandle) getStorageLayout() (*controlpb.StorageLayout, error) {
+func (b *bucketHandle) getStorageLayout(ctx context.Context) (*controlpb.StorageLayout, error) {
 	var callOptions []gax.CallOption
-	stoargeLayout, err := b.controlClient.GetStorageLayout(context.Background(), &controlpb.GetStorageLayoutRequest{
+	stoargeLayout, err := b.controlClient.GetStorageLayout(ctx, &controlpb.GetStorageLayoutRequest{
 		Name:      "projects/_/buckets/" + b.bucketName + "/storageLayout",
 		Prefix:    "",
 		RequestId: "",
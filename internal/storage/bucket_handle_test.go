@@ -59,7 +59,7 @@ func TestBucketHandleTestSuite(testSuite *testing.T) {
 func (testSuite *BucketHandleTest) SetupTest() {
 	testSuite.fakeStorage = NewFakeStorage()
 	testSuite.storageHandle = testSuite.fakeStorage.CreateStorageHandle()
-	testSuite.bucketHandle = testSuite.storageHandle.BucketHandle(TestBucketName, "")
+	testSuite.bucketHandle = testSuite.storageHandle.BucketHandle(TestBucketName, "").(*bucketHandle)
 
 	assert.NotNil(testSuite.T(), testSuite.bucketHandle)
 }
@@ -263,6 +263,20 @@ func (testSuite *BucketHandleTest) TestStatObjectMethodWithValidObject() {
 	assert.Nil(testSuite.T(), err)
 }
 
+func (testSuite *BucketHandleTest) TestStatObjectMethodWithoutClientRetries() {
+	ctx := context.WithValue(context.Background(), gcs.NoClientRetriesField, true)
+
+	m, _, err := testSuite.bucketHandle.StatObject(ctx,
+		&gcs.StatObjectRequest{
+			Name: TestObjectName,
+		})
+
+	assert.Nil(testSuite.T(), err)
+	assert.Equal(testSuite.T(), TestObjectName, m.Name)
+	assert.NotSame(testSuite.T(), testSuite.bucketHandle.bucket, testSuite.bucketHandle.handle(ctx))
+	assert.Same(testSuite.T(), testSuite.bucketHandle.bucket, testSuite.bucketHandle.handle(context.Background()))
+}
+
 func (testSuite *BucketHandleTest) TestStatObjectMethodWithReturnExtendedObjectAttributesTrue() {
 	m, e, err := testSuite.bucketHandle.StatObject(context.Background(),
 		&gcs.StatObjectRequest{
@@ -1249,3 +1263,38 @@ func (testSuite *BucketHandleTest) TestDefaultBucketTypeWithControlClientNil() {
 
 	assert.Equal(testSuite.T(), gcs.NonHierarchical, testSuite.bucketHandle.bucketType, "Expected Hierarchical bucket type")
 }
+
+func (testSuite *BucketHandleTest) TestStorageLayout() {
+	mockClient := new(MockStorageControlClient)
+	mockClient.On("GetStorageLayout", mock.Anything, mock.Anything, mock.Anything).
+		Return(&controlpb.StorageLayout{
+			HierarchicalNamespace: &controlpb.StorageLayout_HierarchicalNamespace{Enabled: true},
+		}, nil)
+	testSuite.bucketHandle.controlClient = mockClient
+
+	storageLayout, err := testSuite.bucketHandle.StorageLayout(context.Background())
+
+	assert.NoError(testSuite.T(), err)
+	assert.True(testSuite.T(), storageLayout.GetHierarchicalNamespace().Enabled)
+}
+
+func (testSuite *BucketHandleTest) TestStorageLayoutWithError() {
+	var x *controlpb.StorageLayout
+	mockClient := new(MockStorageControlClient)
+	mockClient.On("GetStorageLayout", mock.Anything, mock.Anything, mock.Anything).
+		Return(x, errors.New("mocked error"))
+	testSuite.bucketHandle.controlClient = mockClient
+
+	_, err := testSuite.bucketHandle.StorageLayout(context.Background())
+
+	assert.ErrorContains(testSuite.T(), err, "mocked error")
+}
+
+func (testSuite *BucketHandleTest) TestStorageLayoutWithoutControlClient() {
+	var nilControlClient *control.StorageControlClient = nil
+	testSuite.bucketHandle.controlClient = nilControlClient
+
+	_, err := testSuite.bucketHandle.StorageLayout(context.Background())
+
+	assert.ErrorContains(testSuite.T(), err, "enable-hns")
+}
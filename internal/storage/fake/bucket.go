@@ -24,7 +24,6 @@ import (
 	"hash/crc32"
 	"io"
 	"sort"
-	"strings"
 	"unicode/utf8"
 
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
@@ -87,38 +86,6 @@ func (s fakeObjectSlice) find(name string) int {
 	return len(s)
 }
 
-// Return the smallest string that is lexicographically larger than prefix and
-// does not have prefix as a prefix. For the sole case where this is not
-// possible (all strings consisting solely of 0xff bytes, including the empty
-// string), return the empty string.
-func prefixSuccessor(prefix string) string {
-	// Attempt to increment the last byte. If that is a 0xff byte, erase it and
-	// recurse. If we hit an empty string, then we know our task is impossible.
-	limit := []byte(prefix)
-	for len(limit) > 0 {
-		b := limit[len(limit)-1]
-		if b != 0xff {
-			limit[len(limit)-1]++
-			break
-		}
-
-		limit = limit[:len(limit)-1]
-	}
-
-	return string(limit)
-}
-
-// Return the smallest i such that prefix < s[i].metadata.Name and
-// !strings.HasPrefix(s[i].metadata.Name, prefix).
-func (s fakeObjectSlice) prefixUpperBound(prefix string) int {
-	successor := prefixSuccessor(prefix)
-	if successor == "" {
-		return len(s)
-	}
-
-	return s.lowerBound(successor)
-}
-
 ////////////////////////////////////////////////////////////////////////
 // Helpers
 ////////////////////////////////////////////////////////////////////////
@@ -225,9 +192,13 @@ func (b *bucket) mintObject(
 	return
 }
 
+// Create an object from the given request. The touchup function, if non-nil,
+// adjusts the attributes of the object before they are saved.
+//
 // LOCKS_REQUIRED(b.mu)
 func (b *bucket) createObjectLocked(
-	req *gcs.CreateObjectRequest) (o *gcs.Object, err error) {
+	req *gcs.CreateObjectRequest,
+	touchup func(o *gcs.Object)) (o *gcs.Object, err error) {
 	// Check that the name is legal.
 	err = checkName(req.Name)
 	if err != nil {
@@ -244,9 +215,9 @@ func (b *bucket) createObjectLocked(
 	// Find any existing record for this name.
 	existingIndex := b.objects.find(req.Name)
 
-	var existingRecord *fakeObject
+	var existing *gcs.Object
 	if existingIndex < len(b.objects) {
-		existingRecord = &b.objects[existingIndex]
+		existing = &b.objects[existingIndex].metadata
 	}
 
 	// Check the provided checksum, if any.
@@ -276,60 +247,16 @@ func (b *bucket) createObjectLocked(
 	}
 
 	// Check preconditions.
-	if req.GenerationPrecondition != nil {
-		if *req.GenerationPrecondition == 0 && existingRecord != nil {
-			err = &gcs.PreconditionError{
-				Err: errors.New("Precondition failed: object exists"),
-			}
-
-			return
-		}
-
-		if *req.GenerationPrecondition > 0 {
-			if existingRecord == nil {
-				err = &gcs.PreconditionError{
-					Err: errors.New("Precondition failed: object doesn't exist"),
-				}
-
-				return
-			}
-
-			existingGen := existingRecord.metadata.Generation
-			if existingGen != *req.GenerationPrecondition {
-				err = &gcs.PreconditionError{
-					Err: fmt.Errorf(
-						"Precondition failed: object has generation %v",
-						existingGen),
-				}
-
-				return
-			}
-		}
-	}
-
-	if req.MetaGenerationPrecondition != nil {
-		if existingRecord == nil {
-			err = &gcs.PreconditionError{
-				Err: errors.New("Precondition failed: object doesn't exist"),
-			}
-
-			return
-		}
-
-		existingMetaGen := existingRecord.metadata.MetaGeneration
-		if existingMetaGen != *req.MetaGenerationPrecondition {
-			err = &gcs.PreconditionError{
-				Err: fmt.Errorf(
-					"Precondition failed: object has meta-generation %v",
-					existingMetaGen),
-			}
-
-			return
-		}
+	err = storageutil.CheckWritePreconditions(existing, req.GenerationPrecondition, req.MetaGenerationPrecondition)
+	if err != nil {
+		return
 	}
 
 	// Create an object record from the given attributes.
 	var fo fakeObject = b.mintObject(req, contents)
+	if touchup != nil {
+		touchup(&fo.metadata)
+	}
 	o = copyObject(&fo.metadata)
 
 	// Replace an entry in or add an entry to our list of objects.
@@ -401,14 +328,6 @@ func (b *bucket) newReaderLocked(
 	return
 }
 
-func minInt(a, b int) int {
-	if a < b {
-		return a
-	}
-
-	return b
-}
-
 func copyMetadata(in map[string]string) (out map[string]string) {
 	if in == nil {
 		return
@@ -447,93 +366,14 @@ func (b *bucket) ListObjects(
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	// Set up the result object.
-	listing = new(gcs.Listing)
-
-	// Handle defaults.
-	maxResults := req.MaxResults
-	if maxResults == 0 {
-		maxResults = 1000
-	}
-
-	// Find where in the space of object names to start.
-	nameStart := req.Prefix
-	if req.ContinuationToken != "" && req.ContinuationToken > nameStart {
-		nameStart = req.ContinuationToken
-	}
-
-	// Find the range of indexes within the array to scan.
-	indexStart := b.objects.lowerBound(nameStart)
-	prefixLimit := b.objects.prefixUpperBound(req.Prefix)
-	indexLimit := minInt(indexStart+maxResults, prefixLimit)
-
-	// Scan the array.
-	var lastResultWasPrefix bool
-	for i := indexStart; i < indexLimit; i++ {
-		var o fakeObject = b.objects[i]
-		name := o.metadata.Name
-
-		// Search for a delimiter if necessary.
-		if req.Delimiter != "" {
-			// Search only in the part after the prefix.
-			nameMinusQueryPrefix := name[len(req.Prefix):]
-
-			delimiterIndex := strings.Index(nameMinusQueryPrefix, req.Delimiter)
-			if delimiterIndex >= 0 {
-				resultPrefixLimit := delimiterIndex
-
-				// Transform to an index within name.
-				resultPrefixLimit += len(req.Prefix)
-
-				// Include the delimiter in the result.
-				resultPrefixLimit += len(req.Delimiter)
-
-				// Save the result, but only if it's not a duplicate.
-				resultPrefix := name[:resultPrefixLimit]
-				if len(listing.CollapsedRuns) == 0 ||
-					listing.CollapsedRuns[len(listing.CollapsedRuns)-1] != resultPrefix {
-					listing.CollapsedRuns = append(listing.CollapsedRuns, resultPrefix)
-				}
-
-				isTrailingDelimiter := (delimiterIndex == len(nameMinusQueryPrefix)-1)
-				if !isTrailingDelimiter || !req.IncludeTrailingDelimiter {
-					lastResultWasPrefix = true
-					continue
-				}
-			}
-		}
-
-		lastResultWasPrefix = false
-
-		// Otherwise, return as an object result. Make a copy to avoid handing back
-		// internal state.
-		listing.Objects = append(listing.Objects, copyObject(&o.metadata))
-	}
-
-	// Set up a cursor for where to start the next scan if we didn't exhaust the
-	// results.
-	if indexLimit < prefixLimit {
-		// If the final object we visited was returned as an element in
-		// listing.CollapsedRuns, we want to skip all other objects that would
-		// result in the same so we don't return duplicate elements in
-		// listing.CollapsedRuns across requests.
-		if lastResultWasPrefix {
-			lastResultPrefix := listing.CollapsedRuns[len(listing.CollapsedRuns)-1]
-			listing.ContinuationToken = prefixSuccessor(lastResultPrefix)
-
-			// Check an assumption: prefixSuccessor cannot result in the empty string
-			// above because object names must be non-empty UTF-8 strings, and there
-			// is no valid non-empty UTF-8 string that consists of entirely 0xff
-			// bytes.
-			if listing.ContinuationToken == "" {
-				err = errors.New("Unexpected empty string from prefixSuccessor")
-				return
-			}
-		} else {
-			// Otherwise, we'll start scanning at the next object.
-			listing.ContinuationToken = b.objects[indexLimit].metadata.Name
-		}
-	}
+	listing, err = storageutil.ListObjects(
+		req,
+		len(b.objects),
+		func(i int) string { return b.objects[i].metadata.Name },
+		func(i int) (*gcs.Object, error) {
+			// Make a copy to avoid handing back internal state.
+			return copyObject(&b.objects[i].metadata), nil
+		})
 
 	return
 }
@@ -561,7 +401,7 @@ func (b *bucket) CreateObject(
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	o, err = b.createObjectLocked(req)
+	o, err = b.createObjectLocked(req, nil)
 	return
 }
 
@@ -600,18 +440,9 @@ func (b *bucket) CopyObject(
 	}
 
 	// Does it have the correct meta-generation?
-	if req.SrcMetaGenerationPrecondition != nil {
-		p := *req.SrcMetaGenerationPrecondition
-		if b.objects[srcIndex].metadata.MetaGeneration != p {
-			err = &gcs.PreconditionError{
-				Err: fmt.Errorf(
-					"Object %q has meta-generation %d",
-					req.SrcName,
-					b.objects[srcIndex].metadata.MetaGeneration),
-			}
-
-			return
-		}
+	err = storageutil.CheckMetaGenerationPrecondition(&b.objects[srcIndex].metadata, req.SrcMetaGenerationPrecondition)
+	if err != nil {
+		return
 	}
 
 	// Copy it and assign a new generation number, to ensure that the generation
@@ -643,71 +474,21 @@ func (b *bucket) ComposeObjects(
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	// GCS doesn't like too few or too many sources.
-	if len(req.Sources) < 1 {
-		err = errors.New("You must provide at least one source component")
-		return
-	}
-
-	if len(req.Sources) > gcs.MaxSourcesPerComposeRequest {
-		err = errors.New("You have provided too many source components")
-		return
-	}
-
-	// Find readers for all of the source objects, also computing the sum of
-	// their component counts.
-	var srcReaders []io.Reader
-	var dstComponentCount int64
-
-	for _, src := range req.Sources {
-		var r io.Reader
-		var srcIndex int
-
-		r, srcIndex, err = b.newReaderLocked(&gcs.ReadObjectRequest{
-			Name:       src.Name,
-			Generation: src.Generation,
-		})
-
-		if err != nil {
-			return
-		}
-
-		srcReaders = append(srcReaders, r)
-		dstComponentCount += b.objects[srcIndex].metadata.ComponentCount
-	}
-
-	// GCS doesn't like the component count to go too high.
-	if dstComponentCount > gcs.MaxComponentCount {
-		err = errors.New("Result would have too many components")
-		return
-	}
-
-	// Create the new object.
-	createReq := &gcs.CreateObjectRequest{
-		Name:                       req.DstName,
-		GenerationPrecondition:     req.DstGenerationPrecondition,
-		MetaGenerationPrecondition: req.DstMetaGenerationPrecondition,
-		Contents:                   io.MultiReader(srcReaders...),
-		ContentType:                req.ContentType,
-		Metadata:                   req.Metadata,
-	}
-
-	_, err = b.createObjectLocked(createReq)
-	if err != nil {
-		return
-	}
-
-	dstIndex := b.objects.find(req.DstName)
-	metadata := &b.objects[dstIndex].metadata
-
-	// Touchup: fix the component count.
-	metadata.ComponentCount = dstComponentCount
+	o, err = storageutil.ComposeObjects(
+		req,
+		func(name string, generation int64) (*gcs.Object, io.ReadCloser, error) {
+			r, index, err := b.newReaderLocked(&gcs.ReadObjectRequest{
+				Name:       name,
+				Generation: generation,
+			})
+			if err != nil {
+				return nil, nil, err
+			}
 
-	// Touchup: emulate the real GCS behavior of not exporting an MD5 hash for
-	// composite objects.
-	metadata.MD5 = nil
+			return &b.objects[index].metadata, io.NopCloser(r), nil
+		},
+		b.createObjectLocked)
 
-	o = copyObject(metadata)
 	return
 }
 
@@ -758,69 +539,10 @@ func (b *bucket) UpdateObject(
 	}
 
 	var obj *gcs.Object = &b.objects[index].metadata
-
-	// Does the generation number match the request?
-	if req.Generation != 0 && obj.Generation != req.Generation {
-		err = &gcs.NotFoundError{
-			Err: fmt.Errorf(
-				"Object %q generation %d not found",
-				req.Name,
-				req.Generation),
-		}
-
-		return
-	}
-
-	// Does the meta-generation precondition check out?
-	if req.MetaGenerationPrecondition != nil &&
-		obj.MetaGeneration != *req.MetaGenerationPrecondition {
-		err = &gcs.PreconditionError{
-			Err: fmt.Errorf(
-				"Object %q has meta-generation %d",
-				obj.Name,
-				obj.MetaGeneration),
-		}
-
+	if err = storageutil.UpdateObject(obj, req, b.clock.Now()); err != nil {
 		return
 	}
 
-	// Update the entry's basic fields according to the request.
-	if req.ContentType != nil {
-		obj.ContentType = *req.ContentType
-	}
-
-	if req.ContentEncoding != nil {
-		obj.ContentEncoding = *req.ContentEncoding
-	}
-
-	if req.ContentLanguage != nil {
-		obj.ContentLanguage = *req.ContentLanguage
-	}
-
-	if req.CacheControl != nil {
-		obj.CacheControl = *req.CacheControl
-	}
-
-	// Update the user metadata if necessary.
-	if len(req.Metadata) > 0 {
-		if obj.Metadata == nil {
-			obj.Metadata = make(map[string]string)
-		}
-
-		for k, v := range req.Metadata {
-			if v == nil {
-				delete(obj.Metadata, k)
-				continue
-			}
-
-			obj.Metadata[k] = *v
-		}
-	}
-
-	// Bump up the entry generation number and the update time.
-	obj.MetaGeneration++
-	obj.Updated = b.clock.Now()
-
 	// Make a copy to avoid handing back internal state.
 	o = copyObject(obj)
 
@@ -847,18 +569,9 @@ func (b *bucket) DeleteObject(
 	}
 
 	// Check the meta-generation if requested.
-	if req.MetaGenerationPrecondition != nil {
-		p := *req.MetaGenerationPrecondition
-		if b.objects[index].metadata.MetaGeneration != p {
-			err = &gcs.PreconditionError{
-				Err: fmt.Errorf(
-					"Object %q has meta-generation %d",
-					req.Name,
-					b.objects[index].metadata.MetaGeneration),
-			}
-
-			return
-		}
+	err = storageutil.CheckMetaGenerationPrecondition(&b.objects[index].metadata, req.MetaGenerationPrecondition)
+	if err != nil {
+		return
 	}
 
 	// Remove the object.
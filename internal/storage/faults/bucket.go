@@ -0,0 +1,390 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package faults implements a gcs.Bucket injecting latency, errors and
+// truncated reads in the calls to another bucket, to reproduce the behavior of
+// a flaky network and test the recovery from it.
+package faults
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"golang.org/x/net/context"
+	"google.golang.org/api/googleapi"
+	"gopkg.in/yaml.v3"
+)
+
+// The methods of gcs.Bucket that rules can apply to.
+var methods = []string{
+	"NewReader",
+	"CreateObject",
+	"CopyObject",
+	"ComposeObjects",
+	"StatObject",
+	"ListObjects",
+	"UpdateObject",
+	"DeleteObject",
+}
+
+// Rule injects faults in the calls to a method of the bucket.
+type Rule struct {
+	// The name of the method, e.g. StatObject.
+	Method string `yaml:"method"`
+
+	// A regular expression matching the names of the objects the rule applies
+	// to: the source or destination objects of copies and compositions, and the
+	// prefix of listings. Empty matches all.
+	Object string `yaml:"object"`
+
+	// The probability, in (0, 1], that the rule applies to a matching call.
+	// Zero means always.
+	Probability float64 `yaml:"probability"`
+
+	// The number of calls the rule applies to at most. Zero means no limit.
+	Count int `yaml:"count"`
+
+	// How long the call is delayed.
+	Latency time.Duration `yaml:"latency"`
+
+	// The HTTP status to fail the call with, without reaching the wrapped
+	// bucket. 404 and 412 are returned as gcs.NotFoundError and
+	// gcs.PreconditionError, the others as the googleapi.Error of the status.
+	ErrorCode int `yaml:"error-code"`
+
+	// For NewReader, the number of bytes after which the reader fails, as if
+	// the connection dropped.
+	TruncateAfter *int64 `yaml:"truncate-after"`
+}
+
+// Config is the content of a rules file.
+type Config struct {
+	Rules []Rule `yaml:"rules"`
+
+	// The seed of the random choice of the calls the rules apply to, to
+	// reproduce a run. Zero means a random seed.
+	RandomSeed int64 `yaml:"random-seed"`
+}
+
+// ValidateRules returns an error if a rule is invalid.
+func ValidateRules(rules []Rule) error {
+	for i, r := range rules {
+		if !slices.Contains(methods, r.Method) {
+			return fmt.Errorf("rule %d: unknown method %q, must be one of %v", i, r.Method, methods)
+		}
+
+		if _, err := regexp.Compile(r.Object); err != nil {
+			return fmt.Errorf("rule %d: invalid object: %w", i, err)
+		}
+
+		if r.Probability < 0 || r.Probability > 1 {
+			return fmt.Errorf("rule %d: probability must be in (0, 1]", i)
+		}
+
+		if r.Count < 0 {
+			return fmt.Errorf("rule %d: count can't be negative", i)
+		}
+
+		if r.Latency < 0 {
+			return fmt.Errorf("rule %d: latency can't be negative", i)
+		}
+
+		if r.ErrorCode != 0 && (r.ErrorCode < 400 || r.ErrorCode > 599) {
+			return fmt.Errorf("rule %d: error-code must be an HTTP error status", i)
+		}
+
+		if r.TruncateAfter != nil && (r.Method != "NewReader" || *r.TruncateAfter < 0) {
+			return fmt.Errorf("rule %d: truncate-after must be a number of bytes, for NewReader", i)
+		}
+
+		if r.Latency == 0 && r.ErrorCode == 0 && r.TruncateAfter == nil {
+			return fmt.Errorf("rule %d: one of latency, error-code or truncate-after must be set", i)
+		}
+	}
+
+	return nil
+}
+
+// LoadConfig reads the rules file at the given path, in YAML or JSON.
+func LoadConfig(p string) (c *Config, err error) {
+	buf, err := os.ReadFile(p)
+	if err != nil {
+		return
+	}
+
+	c = new(Config)
+	decoder := yaml.NewDecoder(bytes.NewReader(buf))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(c); err != nil && err != io.EOF {
+		err = fmt.Errorf("invalid rules file %s: %w", p, err)
+		return
+	}
+
+	if err = ValidateRules(c.Rules); err != nil {
+		err = fmt.Errorf("invalid rules file %s: %w", p, err)
+		return
+	}
+
+	return
+}
+
+func (r *Rule) error(method string) error {
+	err := &googleapi.Error{
+		Code:    r.ErrorCode,
+		Message: fmt.Sprintf("fault injected in %s", method),
+	}
+
+	switch r.ErrorCode {
+	case http.StatusNotFound:
+		return &gcs.NotFoundError{Err: err}
+	case http.StatusPreconditionFailed:
+		return &gcs.PreconditionError{Err: err}
+	}
+
+	return err
+}
+
+// NewBucket returns a bucket calling the wrapped one, after injecting the
+// faults of the given valid rules. The calls the rules apply to are drawn from
+// the given seed, or from a random one if zero.
+func NewBucket(rules []Rule, seed int64, wrapped gcs.Bucket) gcs.Bucket {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	b := &bucket{
+		rules:   rules,
+		wrapped: wrapped,
+		rand:    rand.New(rand.NewSource(seed)),
+		applied: make([]int, len(rules)),
+	}
+	for _, r := range rules {
+		b.objects = append(b.objects, regexp.MustCompile(r.Object))
+	}
+
+	return b
+}
+
+type bucket struct {
+	rules   []Rule
+	objects []*regexp.Regexp
+	wrapped gcs.Bucket
+
+	mu sync.Mutex
+
+	rand *rand.Rand // GUARDED_BY(mu)
+
+	// The number of calls each rule applied to.
+	applied []int // GUARDED_BY(mu)
+}
+
+// Return whether the rule at the given index applies to a call about the given
+// object names.
+//
+// LOCKS_EXCLUDED(b.mu)
+func (b *bucket) applies(i int, method string, names []string) bool {
+	r := &b.rules[i]
+	if r.Method != method || !slices.ContainsFunc(names, b.objects[i].MatchString) {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if r.Count > 0 && b.applied[i] >= r.Count {
+		return false
+	}
+
+	if r.Probability > 0 && b.rand.Float64() >= r.Probability {
+		return false
+	}
+
+	b.applied[i]++
+	return true
+}
+
+// inject applies the rules of the given method to a call about the given
+// object names, returning the error to fail it with, if any, and the number of
+// bytes to truncate its reader to, if any.
+func (b *bucket) inject(ctx context.Context, method string, names ...string) (truncateAfter *int64, err error) {
+	for i := range b.rules {
+		if !b.applies(i, method, names) {
+			continue
+		}
+
+		r := &b.rules[i]
+		if r.Latency > 0 {
+			timer := time.NewTimer(r.Latency)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				err = ctx.Err()
+				return
+			}
+		}
+
+		if r.ErrorCode != 0 {
+			err = r.error(method)
+			return
+		}
+
+		if r.TruncateAfter != nil && (truncateAfter == nil || *r.TruncateAfter < *truncateAfter) {
+			truncateAfter = r.TruncateAfter
+		}
+	}
+
+	return
+}
+
+// truncatedReader fails after reading a number of bytes.
+type truncatedReader struct {
+	io.ReadCloser
+	read  int64
+	limit int64
+}
+
+func (r *truncatedReader) Read(p []byte) (n int, err error) {
+	if r.read >= r.limit {
+		err = fmt.Errorf("fault injected in NewReader: stream truncated after %d bytes: %w", r.limit, io.ErrUnexpectedEOF)
+		return
+	}
+
+	if int64(len(p)) > r.limit-r.read {
+		p = p[:r.limit-r.read]
+	}
+
+	n, err = r.ReadCloser.Read(p)
+	r.read += int64(n)
+	return
+}
+
+func (b *bucket) Name() string {
+	return b.wrapped.Name()
+}
+
+func (b *bucket) BucketType() gcs.BucketType {
+	return b.wrapped.BucketType()
+}
+
+func (b *bucket) NewReader(
+	ctx context.Context,
+	req *gcs.ReadObjectRequest) (rc io.ReadCloser, err error) {
+	truncateAfter, err := b.inject(ctx, "NewReader", req.Name)
+	if err != nil {
+		return
+	}
+
+	rc, err = b.wrapped.NewReader(ctx, req)
+	if err != nil {
+		return
+	}
+
+	if truncateAfter != nil {
+		rc = &truncatedReader{ReadCloser: rc, limit: *truncateAfter}
+	}
+
+	return
+}
+
+func (b *bucket) CreateObject(
+	ctx context.Context,
+	req *gcs.CreateObjectRequest) (o *gcs.Object, err error) {
+	if _, err = b.inject(ctx, "CreateObject", req.Name); err != nil {
+		return
+	}
+
+	o, err = b.wrapped.CreateObject(ctx, req)
+	return
+}
+
+func (b *bucket) CopyObject(
+	ctx context.Context,
+	req *gcs.CopyObjectRequest) (o *gcs.Object, err error) {
+	if _, err = b.inject(ctx, "CopyObject", req.SrcName, req.DstName); err != nil {
+		return
+	}
+
+	o, err = b.wrapped.CopyObject(ctx, req)
+	return
+}
+
+func (b *bucket) ComposeObjects(
+	ctx context.Context,
+	req *gcs.ComposeObjectsRequest) (o *gcs.Object, err error) {
+	names := []string{req.DstName}
+	for _, src := range req.Sources {
+		names = append(names, src.Name)
+	}
+
+	if _, err = b.inject(ctx, "ComposeObjects", names...); err != nil {
+		return
+	}
+
+	o, err = b.wrapped.ComposeObjects(ctx, req)
+	return
+}
+
+func (b *bucket) StatObject(
+	ctx context.Context,
+	req *gcs.StatObjectRequest) (m *gcs.MinObject, e *gcs.ExtendedObjectAttributes, err error) {
+	if _, err = b.inject(ctx, "StatObject", req.Name); err != nil {
+		return
+	}
+
+	m, e, err = b.wrapped.StatObject(ctx, req)
+	return
+}
+
+func (b *bucket) ListObjects(
+	ctx context.Context,
+	req *gcs.ListObjectsRequest) (listing *gcs.Listing, err error) {
+	if _, err = b.inject(ctx, "ListObjects", req.Prefix); err != nil {
+		return
+	}
+
+	listing, err = b.wrapped.ListObjects(ctx, req)
+	return
+}
+
+func (b *bucket) UpdateObject(
+	ctx context.Context,
+	req *gcs.UpdateObjectRequest) (o *gcs.Object, err error) {
+	if _, err = b.inject(ctx, "UpdateObject", req.Name); err != nil {
+		return
+	}
+
+	o, err = b.wrapped.UpdateObject(ctx, req)
+	return
+}
+
+func (b *bucket) DeleteObject(
+	ctx context.Context,
+	req *gcs.DeleteObjectRequest) (err error) {
+	if _, err = b.inject(ctx, "DeleteObject", req.Name); err != nil {
+		return
+	}
+
+	err = b.wrapped.DeleteObject(ctx, req)
+	return
+}
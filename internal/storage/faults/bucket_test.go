@@ -0,0 +1,190 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faults
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/fake"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"github.com/jacobsa/timeutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/api/googleapi"
+)
+
+func newFakeBucket(t *testing.T) gcs.Bucket {
+	b := fake.NewFakeBucket(timeutil.RealClock(), "b")
+	_, err := b.CreateObject(context.Background(), &gcs.CreateObjectRequest{Name: "a", Contents: strings.NewReader("taco")})
+	require.NoError(t, err)
+	return b
+}
+
+func stat(b gcs.Bucket) error {
+	_, _, err := b.StatObject(context.Background(), &gcs.StatObjectRequest{Name: "a"})
+	return err
+}
+
+func TestValidateRules(t *testing.T) {
+	n := int64(3)
+	assert.NoError(t, ValidateRules([]Rule{{Method: "StatObject", Object: "^foo/", Probability: 0.5, Count: 2, ErrorCode: 500, Latency: time.Second}}))
+	assert.NoError(t, ValidateRules([]Rule{{Method: "NewReader", TruncateAfter: &n}}))
+	assert.ErrorContains(t, ValidateRules([]Rule{{Method: "Stat", ErrorCode: 500}}), "unknown method \"Stat\"")
+	assert.ErrorContains(t, ValidateRules([]Rule{{Method: "StatObject", Object: "(", ErrorCode: 500}}), "invalid object")
+	assert.ErrorContains(t, ValidateRules([]Rule{{Method: "StatObject", Probability: 2, ErrorCode: 500}}), "probability must be in (0, 1]")
+	assert.ErrorContains(t, ValidateRules([]Rule{{Method: "StatObject", Count: -1, ErrorCode: 500}}), "count can't be negative")
+	assert.ErrorContains(t, ValidateRules([]Rule{{Method: "StatObject", Latency: -time.Second}}), "latency can't be negative")
+	assert.ErrorContains(t, ValidateRules([]Rule{{Method: "StatObject", ErrorCode: 200}}), "error-code must be an HTTP error status")
+	assert.ErrorContains(t, ValidateRules([]Rule{{Method: "StatObject", TruncateAfter: &n}}), "truncate-after must be a number of bytes, for NewReader")
+	assert.ErrorContains(t, ValidateRules([]Rule{{Method: "StatObject"}}), "one of latency, error-code or truncate-after must be set")
+}
+
+func TestNoRules(t *testing.T) {
+	b := NewBucket(nil, 1, newFakeBucket(t))
+
+	assert.Equal(t, "b", b.Name())
+	assert.NoError(t, stat(b))
+}
+
+func TestErrors(t *testing.T) {
+	b := NewBucket([]Rule{{Method: "StatObject", ErrorCode: 503}}, 1, newFakeBucket(t))
+
+	err := stat(b)
+
+	var apiErr *googleapi.Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, 503, apiErr.Code)
+	// Other methods aren't affected.
+	_, err = b.ListObjects(context.Background(), &gcs.ListObjectsRequest{})
+	assert.NoError(t, err)
+}
+
+func TestErrorCodes(t *testing.T) {
+	b := NewBucket([]Rule{{Method: "StatObject", ErrorCode: 404}}, 1, newFakeBucket(t))
+	assert.IsType(t, &gcs.NotFoundError{}, stat(b))
+
+	b = NewBucket([]Rule{{Method: "StatObject", ErrorCode: 412}}, 1, newFakeBucket(t))
+	assert.IsType(t, &gcs.PreconditionError{}, stat(b))
+}
+
+func TestProbabilityIsReproducible(t *testing.T) {
+	failures := func() (n int) {
+		b := NewBucket([]Rule{{Method: "StatObject", Probability: 0.3, ErrorCode: 503}}, 42, newFakeBucket(t))
+		for i := 0; i < 1000; i++ {
+			if stat(b) != nil {
+				n++
+			}
+		}
+		return
+	}
+
+	n := failures()
+
+	assert.InDelta(t, 300, n, 60)
+	assert.Equal(t, n, failures())
+}
+
+func TestLatency(t *testing.T) {
+	b := NewBucket([]Rule{{Method: "StatObject", Latency: 50 * time.Millisecond}}, 1, newFakeBucket(t))
+
+	start := time.Now()
+	err := stat(b)
+
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestLatencyIsCancelled(t *testing.T) {
+	b := NewBucket([]Rule{{Method: "NewReader", Latency: time.Hour}}, 1, newFakeBucket(t))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := b.NewReader(ctx, &gcs.ReadObjectRequest{Name: "a"})
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestObjectPattern(t *testing.T) {
+	b := NewBucket([]Rule{{Method: "StatObject", Object: "^b", ErrorCode: 503}}, 1, newFakeBucket(t))
+
+	assert.NoError(t, stat(b))
+	_, _, err := b.StatObject(context.Background(), &gcs.StatObjectRequest{Name: "bar"})
+	assert.Error(t, err)
+}
+
+func TestCount(t *testing.T) {
+	b := NewBucket([]Rule{{Method: "StatObject", Count: 2, ErrorCode: 503}}, 1, newFakeBucket(t))
+
+	assert.Error(t, stat(b))
+	assert.Error(t, stat(b))
+	assert.NoError(t, stat(b))
+}
+
+func TestTruncateAfter(t *testing.T) {
+	n := int64(2)
+	b := NewBucket([]Rule{{Method: "NewReader", Count: 1, TruncateAfter: &n}}, 1, newFakeBucket(t))
+
+	rc, err := b.NewReader(context.Background(), &gcs.ReadObjectRequest{Name: "a"})
+	require.NoError(t, err)
+	buf, err := io.ReadAll(rc)
+	rc.Close()
+
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, "ta", string(buf))
+	// The next reader isn't truncated.
+	rc, err = b.NewReader(context.Background(), &gcs.ReadObjectRequest{Name: "a"})
+	require.NoError(t, err)
+	buf, err = io.ReadAll(rc)
+	rc.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, "taco", string(buf))
+}
+
+func TestLoadConfig(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "faults.yaml")
+	require.NoError(t, os.WriteFile(p, []byte(`
+rules:
+  - method: NewReader
+    object: "^a$"
+    probability: 0.5
+    count: 3
+    latency: 10ms
+    truncate-after: 1024
+  - method: CreateObject
+    error-code: 412
+random-seed: 7
+`), 0644))
+
+	c, err := LoadConfig(p)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), c.RandomSeed)
+	require.Len(t, c.Rules, 2)
+	assert.Equal(t, "^a$", c.Rules[0].Object)
+	assert.Equal(t, 10*time.Millisecond, c.Rules[0].Latency)
+	assert.Equal(t, int64(1024), *c.Rules[0].TruncateAfter)
+	assert.Equal(t, 412, c.Rules[1].ErrorCode)
+
+	require.NoError(t, os.WriteFile(p, []byte("rules: [{method: StatObject}]\n"), 0644))
+	_, err = LoadConfig(p)
+	assert.ErrorContains(t, err, "one of latency, error-code or truncate-after must be set")
+}
@@ -41,6 +41,11 @@ const (
 	// for passing down Request ID
 	// into the underlying bucket implementation.
 	ReqIdField string = "GcsReqId"
+
+	// NoClientRetriesField is the key of a true value in the context of the
+	// requests that are retried by a layer of gcsfuse, so that the underlying
+	// bucket implementation doesn't retry them too.
+	NoClientRetriesField string = "GcsNoClientRetries"
 )
 
 // Bucket represents a GCS bucket, pre-bound with a bucket name and necessary
@@ -0,0 +1,250 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package hedging implements a gcs.Bucket hedging the small reads and the
+// stats of objects: when a request to another bucket is slower than most of
+// the recent requests of its kind, a duplicate is sent, and the first to
+// answer is used. This cuts the tail latency due to occasional slow responses,
+// at the cost of a few more requests.
+package hedging
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/config"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/storageutil"
+)
+
+// NewBucket returns a bucket hedging the stats, and the reads of small ranges,
+// of the objects of the wrapped one, following the given config.
+func NewBucket(c config.HedgingConfig, wrapped gcs.Bucket) gcs.Bucket {
+	minDelay := time.Duration(c.MinDelayMs) * time.Millisecond
+	return &bucket{
+		wrapped:     wrapped,
+		maxReadSize: uint64(c.MaxReadSizeKB) * 1024,
+		stats:       newLatencies(c.Percentile, minDelay),
+		reads:       newLatencies(c.Percentile, minDelay),
+	}
+}
+
+type bucket struct {
+	wrapped gcs.Bucket
+
+	// The size of the largest ranges whose reads are hedged.
+	maxReadSize uint64
+
+	// The latencies of the stats, and of the opening of the hedged reads.
+	stats *latencies
+	reads *latencies
+}
+
+// The result of a call to the wrapped bucket.
+type result[T any] struct {
+	v     T
+	err   error
+	hedge bool
+}
+
+// hedge calls f, and calls it again if it hasn't returned after the delay of
+// l, returning the result of the first call to succeed, or the error of the
+// last call to fail. The other call is cancelled, and its result, if any,
+// passed to discard.
+//
+// f is called with a context of its own. When hedge succeeds, the caller must
+// call the returned cancel func once done with the result, e.g. the reader it
+// opened.
+func hedge[T any](
+	ctx context.Context,
+	l *latencies,
+	method string,
+	name string,
+	f func(context.Context) (T, error),
+	discard func(T)) (v T, cancel context.CancelFunc, err error) {
+	start := time.Now()
+	delay, ok := l.hedgingDelay()
+	if !ok {
+		ctx, cancel = context.WithCancel(ctx)
+		v, err = f(ctx)
+		if err != nil {
+			cancel()
+			return
+		}
+
+		l.record(time.Since(start))
+		return
+	}
+
+	results := make(chan result[T], 2)
+	callCtx, cancelCall := context.WithCancel(ctx)
+	hedgeCtx, cancelHedge := context.WithCancel(ctx)
+	call := func(ctx context.Context, hedge bool) {
+		go func() {
+			v, err := f(ctx)
+			results <- result[T]{v: v, err: err, hedge: hedge}
+		}()
+	}
+
+	call(callCtx, false)
+	pending := 1
+	hedged := false
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var r result[T]
+	for {
+		select {
+		case <-timer.C:
+			logger.Tracef("Hedging %s(%q), unanswered after %v", method, name, delay)
+			call(hedgeCtx, true)
+			pending++
+			hedged = true
+			continue
+		case r = <-results:
+			pending--
+		}
+
+		// Wait for the other call if this one failed.
+		if r.err != nil && pending > 0 {
+			continue
+		}
+
+		break
+	}
+
+	// Cancel the other call, and release its result once it returns.
+	cancel, cancelOther := cancelCall, cancelHedge
+	if r.hedge {
+		cancel, cancelOther = cancelHedge, cancelCall
+	}
+	cancelOther()
+	go func(pending int) {
+		for ; pending > 0; pending-- {
+			other := <-results
+			if other.err == nil && discard != nil {
+				discard(other.v)
+			}
+		}
+	}(pending)
+
+	if hedged {
+		monitor.CaptureHedgeMetrics(ctx, method, r.err == nil && r.hedge)
+	}
+
+	if r.err != nil {
+		cancel()
+		err = r.err
+		return
+	}
+
+	// When the hedge answered first, the latency of the first call is unknown,
+	// but at least the elapsed time, which keeps the slow calls in the window.
+	l.record(time.Since(start))
+	v = r.v
+	return
+}
+
+func (b *bucket) Name() string {
+	return b.wrapped.Name()
+}
+
+func (b *bucket) BucketType() gcs.BucketType {
+	return b.wrapped.BucketType()
+}
+
+func (b *bucket) NewReader(
+	ctx context.Context,
+	req *gcs.ReadObjectRequest) (rc io.ReadCloser, err error) {
+	if req.Range == nil || req.Range.Limit-req.Range.Start > b.maxReadSize {
+		return b.wrapped.NewReader(ctx, req)
+	}
+
+	rc, cancel, err := hedge(ctx, b.reads, "NewReader", req.Name,
+		func(ctx context.Context) (io.ReadCloser, error) {
+			return b.wrapped.NewReader(ctx, req)
+		},
+		func(rc io.ReadCloser) {
+			rc.Close()
+		})
+	if err != nil {
+		return
+	}
+
+	rc = &storageutil.CancellingReadCloser{ReadCloser: rc, Cancel: cancel}
+	return
+}
+
+func (b *bucket) CreateObject(
+	ctx context.Context,
+	req *gcs.CreateObjectRequest) (*gcs.Object, error) {
+	return b.wrapped.CreateObject(ctx, req)
+}
+
+func (b *bucket) CopyObject(
+	ctx context.Context,
+	req *gcs.CopyObjectRequest) (*gcs.Object, error) {
+	return b.wrapped.CopyObject(ctx, req)
+}
+
+func (b *bucket) ComposeObjects(
+	ctx context.Context,
+	req *gcs.ComposeObjectsRequest) (*gcs.Object, error) {
+	return b.wrapped.ComposeObjects(ctx, req)
+}
+
+// The attributes returned by a stat.
+type statResult struct {
+	m *gcs.MinObject
+	e *gcs.ExtendedObjectAttributes
+}
+
+func (b *bucket) StatObject(
+	ctx context.Context,
+	req *gcs.StatObjectRequest) (m *gcs.MinObject, e *gcs.ExtendedObjectAttributes, err error) {
+	r, cancel, err := hedge(ctx, b.stats, "StatObject", req.Name,
+		func(ctx context.Context) (r statResult, err error) {
+			r.m, r.e, err = b.wrapped.StatObject(ctx, req)
+			return
+		},
+		nil)
+	if err != nil {
+		return
+	}
+
+	cancel()
+	m, e = r.m, r.e
+	return
+}
+
+func (b *bucket) ListObjects(
+	ctx context.Context,
+	req *gcs.ListObjectsRequest) (*gcs.Listing, error) {
+	return b.wrapped.ListObjects(ctx, req)
+}
+
+func (b *bucket) UpdateObject(
+	ctx context.Context,
+	req *gcs.UpdateObjectRequest) (*gcs.Object, error) {
+	return b.wrapped.UpdateObject(ctx, req)
+}
+
+func (b *bucket) DeleteObject(
+	ctx context.Context,
+	req *gcs.DeleteObjectRequest) error {
+	return b.wrapped.DeleteObject(ctx, req)
+}
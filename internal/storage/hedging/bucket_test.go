@@ -0,0 +1,151 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hedging
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/config"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/fake"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/faults"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/storageutil"
+	"github.com/jacobsa/timeutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testConfig = config.HedgingConfig{
+	Enabled:       true,
+	Percentile:    95,
+	MinDelayMs:    1,
+	MaxReadSizeKB: 1,
+}
+
+// countingBucket counts the readers opened and closed.
+type countingBucket struct {
+	gcs.Bucket
+	opened atomic.Int64
+	closed atomic.Int64
+}
+
+func (b *countingBucket) NewReader(ctx context.Context, req *gcs.ReadObjectRequest) (io.ReadCloser, error) {
+	rc, err := b.Bucket.NewReader(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	b.opened.Add(1)
+	return &countingReadCloser{ReadCloser: rc, closed: &b.closed}, nil
+}
+
+type countingReadCloser struct {
+	io.ReadCloser
+	closed *atomic.Int64
+}
+
+func (rc *countingReadCloser) Close() error {
+	rc.closed.Add(1)
+	return rc.ReadCloser.Close()
+}
+
+// Return a bucket with the objects "a" and "slow", whose first stat and read
+// take an hour.
+func newFakeBucket(t *testing.T) *countingBucket {
+	b := fake.NewFakeBucket(timeutil.RealClock(), "b")
+	for _, name := range []string{"a", "slow"} {
+		_, err := storageutil.CreateObject(context.Background(), b, name, []byte("taco"))
+		require.NoError(t, err)
+	}
+
+	return &countingBucket{Bucket: faults.NewBucket([]faults.Rule{
+		{Method: "StatObject", Object: "^slow$", Count: 1, Latency: time.Hour},
+		{Method: "NewReader", Object: "^slow$", Count: 1, Latency: time.Hour},
+	}, 1, b)}
+}
+
+func read(b gcs.Bucket, name string) (string, error) {
+	rc, err := b.NewReader(context.Background(), &gcs.ReadObjectRequest{
+		Name:  name,
+		Range: &gcs.ByteRange{Start: 0, Limit: 4},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	defer rc.Close()
+	buf, err := io.ReadAll(rc)
+	return string(buf), err
+}
+
+func TestStatIsHedged(t *testing.T) {
+	b := NewBucket(testConfig, newFakeBucket(t))
+	for i := 0; i < minSamples; i++ {
+		_, _, err := b.StatObject(context.Background(), &gcs.StatObjectRequest{Name: "a"})
+		require.NoError(t, err)
+	}
+
+	m, _, err := b.StatObject(context.Background(), &gcs.StatObjectRequest{Name: "slow"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "slow", m.Name)
+}
+
+func TestSlowStatIsntHedgedBeforeMinSamples(t *testing.T) {
+	b := NewBucket(testConfig, newFakeBucket(t))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, _, err := b.StatObject(ctx, &gcs.StatObjectRequest{Name: "slow"})
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestReadIsHedged(t *testing.T) {
+	wrapped := newFakeBucket(t)
+	b := NewBucket(testConfig, wrapped)
+	for i := 0; i < minSamples; i++ {
+		_, err := read(b, "a")
+		require.NoError(t, err)
+	}
+
+	contents, err := read(b, "slow")
+
+	require.NoError(t, err)
+	assert.Equal(t, "taco", contents)
+	// The reader of the slow read, cancelled before it opened, isn't leaked.
+	assert.Equal(t, wrapped.opened.Load(), wrapped.closed.Load())
+}
+
+func TestLargeReadIsntHedged(t *testing.T) {
+	b := NewBucket(testConfig, newFakeBucket(t))
+	for i := 0; i < minSamples; i++ {
+		_, err := read(b, "a")
+		require.NoError(t, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := b.NewReader(ctx, &gcs.ReadObjectRequest{
+		Name:  "slow",
+		Range: &gcs.ByteRange{Start: 0, Limit: 2048},
+	})
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,89 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hedging
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// The number of latencies of the most recent requests the delay is
+	// computed from.
+	windowSize = 1000
+
+	// The number of latencies observed before the requests are hedged.
+	minSamples = 100
+
+	// The number of latencies observed between two computations of the delay.
+	recomputeEvery = 10
+)
+
+// latencies tracks the latencies of the recent requests of a kind, to hedge
+// the requests slower than a percentile of them.
+type latencies struct {
+	percentile float64
+	minDelay   time.Duration
+
+	mu sync.Mutex
+
+	// A ring of the latencies of the last windowSize requests, the latest at
+	// index (count-1)%windowSize.
+	samples [windowSize]time.Duration // GUARDED_BY(mu)
+	count   int                       // GUARDED_BY(mu)
+
+	// The delay computed from the samples, valid once count >= minSamples.
+	delay time.Duration // GUARDED_BY(mu)
+}
+
+func newLatencies(percentile float64, minDelay time.Duration) *latencies {
+	return &latencies{
+		percentile: percentile,
+		minDelay:   minDelay,
+	}
+}
+
+// Record the latency of a request.
+//
+// LOCKS_EXCLUDED(l.mu)
+func (l *latencies) record(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.samples[l.count%windowSize] = d
+	l.count++
+	if l.count < minSamples || l.count%recomputeEvery != 0 {
+		return
+	}
+
+	n := min(l.count, windowSize)
+	sorted := slices.Clone(l.samples[:n])
+	slices.Sort(sorted)
+	i := int(math.Ceil(l.percentile/100*float64(n))) - 1
+	l.delay = max(sorted[max(i, 0)], l.minDelay)
+}
+
+// Return the delay after which a request is hedged, or false if too few
+// latencies were observed to hedge.
+//
+// LOCKS_EXCLUDED(l.mu)
+func (l *latencies) hedgingDelay() (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.delay, l.count >= minSamples
+}
@@ -0,0 +1,70 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hedging
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoDelayBeforeMinSamples(t *testing.T) {
+	l := newLatencies(95, 0)
+
+	for i := 0; i < minSamples-1; i++ {
+		l.record(time.Millisecond)
+	}
+
+	_, ok := l.hedgingDelay()
+	assert.False(t, ok)
+}
+
+func TestDelayIsPercentile(t *testing.T) {
+	l := newLatencies(95, 0)
+
+	for i := 1; i <= minSamples; i++ {
+		l.record(time.Duration(i) * time.Millisecond)
+	}
+
+	delay, ok := l.hedgingDelay()
+	assert.True(t, ok)
+	assert.Equal(t, 95*time.Millisecond, delay)
+}
+
+func TestDelayAtLeastMinDelay(t *testing.T) {
+	l := newLatencies(95, 10*time.Millisecond)
+
+	for i := 0; i < minSamples; i++ {
+		l.record(time.Millisecond)
+	}
+
+	delay, _ := l.hedgingDelay()
+	assert.Equal(t, 10*time.Millisecond, delay)
+}
+
+func TestDelayAdaptsToRecentLatencies(t *testing.T) {
+	l := newLatencies(95, 0)
+
+	for i := 0; i < windowSize; i++ {
+		l.record(time.Second)
+	}
+	for i := 0; i < windowSize; i++ {
+		l.record(time.Millisecond)
+	}
+
+	delay, _ := l.hedgingDelay()
+	assert.Equal(t, time.Millisecond, delay)
+}
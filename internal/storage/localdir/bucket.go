@@ -0,0 +1,989 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package localdir implements gcs.Bucket on top of a local directory, to
+// mount reproducible fixtures without network access.
+//
+// The contents of an object are kept in the file at the path of its name under
+// the directory of the bucket, and its other attributes in a JSON sidecar file
+// in a .gcsfuse-meta directory next to it: the ones of "a/b" in
+// a/.gcsfuse-meta/b.json. Objects whose name ends with a slash, like "a/", keep
+// their contents in a/.gcsfuse-meta/.data and their attributes in
+// a/.gcsfuse-meta/.json.
+//
+// A file without a sidecar, or which changed since its sidecar was written, is
+// an object of metageneration 1 whose generation is its modification time in
+// microseconds, so fixtures can be made with any tool.
+//
+// Names must be valid relative paths: they can't have empty, "." or ".."
+// components, nor components named .gcsfuse-meta. An object can't have the name
+// of a directory either, e.g. "a" can't be created next to "a/b".
+package localdir
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/storageutil"
+	"github.com/jacobsa/timeutil"
+	"golang.org/x/net/context"
+)
+
+const (
+	// The directories keeping the sidecar files of the objects next to them.
+	metaDirName = ".gcsfuse-meta"
+
+	sidecarExt = ".json"
+
+	// The file keeping the contents of an object whose name ends with a slash,
+	// in the metadata directory of the directory of the same name.
+	dirObjectData = ".data"
+
+	// The contents of the objects being created are written to temporary files
+	// in this directory of the bucket's metadata directory, then renamed into
+	// place once complete.
+	tmpDirName = "tmp"
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// sidecar is the content of a sidecar file: the attributes of an object that
+// its file doesn't have.
+type sidecar struct {
+	Generation         int64
+	MetaGeneration     int64
+	ContentType        string            `json:",omitempty"`
+	ContentLanguage    string            `json:",omitempty"`
+	ContentEncoding    string            `json:",omitempty"`
+	CacheControl       string            `json:",omitempty"`
+	ContentDisposition string            `json:",omitempty"`
+	CustomTime         string            `json:",omitempty"`
+	StorageClass       string            `json:",omitempty"`
+	Metadata           map[string]string `json:",omitempty"`
+	ComponentCount     int64
+	MD5                string  `json:",omitempty"`
+	CRC32C             *uint32 `json:",omitempty"`
+	Updated            time.Time
+
+	// The size and modification time of the file when the sidecar was written,
+	// to ignore the sidecar once the file is changed by other means.
+	Size    int64
+	ModTime time.Time
+}
+
+type bucket struct {
+	clock timeutil.Clock
+	dir   string
+	name  string
+
+	// Serializes the changes to the objects, and the lookups of objects against
+	// them.
+	mu sync.Mutex
+
+	// The generation of the last object created, to keep generations increasing
+	// when the clock doesn't move.
+	prevGeneration int64 // GUARDED_BY(mu)
+}
+
+// NewBucket returns the bucket with the given name, whose objects are kept
+// under the given directory.
+func NewBucket(clock timeutil.Clock, dir string, name string) gcs.Bucket {
+	return &bucket{clock: clock, dir: dir, name: name}
+}
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+func checkName(name string) (err error) {
+	if len(name) == 0 || len(name) > 1024 {
+		err = errors.New("Invalid object name: length must be in [1, 1024]")
+		return
+	}
+
+	if !utf8.ValidString(name) {
+		err = errors.New("Invalid object name: not valid UTF-8")
+		return
+	}
+
+	for _, r := range name {
+		if r == 0x0a || r == 0x0d {
+			err = errors.New("Invalid object name: must not contain CR or LF")
+			return
+		}
+	}
+
+	if !validPath(strings.TrimSuffix(name, "/")) {
+		err = fmt.Errorf("Invalid object name %q: not a path in a local directory", name)
+		return
+	}
+
+	return
+}
+
+// validPath returns true if the given slash-separated path has no empty, "."
+// or ".." components, nor metadata directories.
+func validPath(p string) bool {
+	for _, c := range strings.Split(p, "/") {
+		if c == "" || c == "." || c == ".." || c == metaDirName {
+			return false
+		}
+	}
+
+	return true
+}
+
+func notFound(name string) error {
+	return &gcs.NotFoundError{
+		Err: fmt.Errorf("Object %s not found", name),
+	}
+}
+
+func isNotExist(err error) bool {
+	return errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR)
+}
+
+func copyMetadata(in map[string]string) (out map[string]string) {
+	if in == nil {
+		return
+	}
+
+	out = make(map[string]string)
+	for k, v := range in {
+		out[k] = v
+	}
+
+	return
+}
+
+// paths returns the paths of the contents and of the sidecar file of the
+// object with the given valid name.
+func (b *bucket) paths(name string) (data string, meta string) {
+	dir, base := path.Split(name)
+	metaDir := filepath.Join(b.dir, filepath.FromSlash(dir), metaDirName)
+	if base == "" {
+		return filepath.Join(metaDir, dirObjectData), filepath.Join(metaDir, sidecarExt)
+	}
+
+	return filepath.Join(b.dir, filepath.FromSlash(name)), filepath.Join(metaDir, base+sidecarExt)
+}
+
+// object returns the object with the given name, whose file has the given
+// info, and whose sidecar file is at the given path.
+func (b *bucket) object(name string, fi os.FileInfo, meta string) (o *gcs.Object, err error) {
+	o = &gcs.Object{
+		Name:           name,
+		Size:           uint64(fi.Size()),
+		Generation:     fi.ModTime().UnixMicro(),
+		MetaGeneration: 1,
+		ComponentCount: 1,
+		StorageClass:   "STANDARD",
+		Updated:        fi.ModTime(),
+	}
+
+	buf, err := os.ReadFile(meta)
+	if isNotExist(err) {
+		err = nil
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf("ReadFile: %w", err)
+		return
+	}
+
+	var sc sidecar
+	if err = json.Unmarshal(buf, &sc); err != nil {
+		err = fmt.Errorf("invalid sidecar file %s: %w", meta, err)
+		return
+	}
+
+	// Ignore the sidecar of a file changed since.
+	if sc.Size != fi.Size() || !sc.ModTime.Equal(fi.ModTime()) {
+		return
+	}
+
+	o.Generation = sc.Generation
+	o.MetaGeneration = sc.MetaGeneration
+	o.ContentType = sc.ContentType
+	o.ContentLanguage = sc.ContentLanguage
+	o.ContentEncoding = sc.ContentEncoding
+	o.CacheControl = sc.CacheControl
+	o.ContentDisposition = sc.ContentDisposition
+	o.CustomTime = sc.CustomTime
+	if sc.StorageClass != "" {
+		o.StorageClass = sc.StorageClass
+	}
+	o.Metadata = sc.Metadata
+	o.ComponentCount = sc.ComponentCount
+	o.CRC32C = sc.CRC32C
+	o.Updated = sc.Updated
+	if sc.MD5 != "" {
+		var md5Sum [md5.Size]byte
+		if _, err = hex.Decode(md5Sum[:], []byte(sc.MD5)); err != nil {
+			err = fmt.Errorf("invalid sidecar file %s: %w", meta, err)
+			return
+		}
+		o.MD5 = &md5Sum
+	}
+
+	return
+}
+
+// Return the object with the given name, or a NotFoundError.
+//
+// LOCKS_REQUIRED(b.mu)
+func (b *bucket) lookupLocked(name string) (o *gcs.Object, err error) {
+	if checkName(name) != nil {
+		err = notFound(name)
+		return
+	}
+
+	data, meta := b.paths(name)
+	fi, err := os.Stat(data)
+	if isNotExist(err) || (err == nil && !fi.Mode().IsRegular()) {
+		err = notFound(name)
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf("Stat: %w", err)
+		return
+	}
+
+	o, err = b.object(name, fi, meta)
+	return
+}
+
+// Open the file of the object with the given name, at the given generation if
+// non-zero.
+//
+// LOCKS_REQUIRED(b.mu)
+func (b *bucket) openLocked(name string, generation int64) (o *gcs.Object, f *os.File, err error) {
+	if checkName(name) != nil {
+		err = notFound(name)
+		return
+	}
+
+	data, meta := b.paths(name)
+	f, err = os.Open(data)
+	if isNotExist(err) {
+		err = notFound(name)
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf("Open: %w", err)
+		return
+	}
+
+	// Stat the opened file rather than the path, so that the object matches the
+	// contents read.
+	fi, err := f.Stat()
+	if err == nil && !fi.Mode().IsRegular() {
+		err = notFound(name)
+	} else if err == nil {
+		o, err = b.object(name, fi, meta)
+	}
+
+	if err == nil && generation != 0 && o.Generation != generation {
+		err = &gcs.NotFoundError{
+			Err: fmt.Errorf("Object %s generation %v not found", name, generation),
+		}
+	}
+
+	if err != nil {
+		f.Close()
+		o, f = nil, nil
+	}
+
+	return
+}
+
+// Return a new generation, larger than all the previous ones.
+//
+// LOCKS_REQUIRED(b.mu)
+func (b *bucket) mintGenerationLocked() int64 {
+	b.prevGeneration = max(b.prevGeneration+1, b.clock.Now().UnixMicro())
+	return b.prevGeneration
+}
+
+// Create a temporary file in the bucket, to be renamed into place once
+// written.
+func (b *bucket) createTemp() (f *os.File, err error) {
+	tmpDir := filepath.Join(b.dir, metaDirName, tmpDirName)
+	if err = os.MkdirAll(tmpDir, 0755); err != nil {
+		err = fmt.Errorf("MkdirAll: %w", err)
+		return
+	}
+
+	f, err = os.CreateTemp(tmpDir, "")
+	if err != nil {
+		err = fmt.Errorf("CreateTemp: %w", err)
+		return
+	}
+
+	return
+}
+
+// Atomically replace the file at the given path with the given temporary file,
+// creating its directory if needed.
+func rename(tmp string, p string) (err error) {
+	if err = os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		err = fmt.Errorf("MkdirAll: %w", err)
+		return
+	}
+
+	if err = os.Rename(tmp, p); err != nil {
+		err = fmt.Errorf("Rename: %w", err)
+		return
+	}
+
+	return
+}
+
+// Write the sidecar file of the given object, whose file has been written.
+//
+// LOCKS_REQUIRED(b.mu)
+func (b *bucket) writeSidecarLocked(o *gcs.Object) (err error) {
+	data, meta := b.paths(o.Name)
+	fi, err := os.Stat(data)
+	if err != nil {
+		err = fmt.Errorf("Stat: %w", err)
+		return
+	}
+
+	sc := sidecar{
+		Generation:         o.Generation,
+		MetaGeneration:     o.MetaGeneration,
+		ContentType:        o.ContentType,
+		ContentLanguage:    o.ContentLanguage,
+		ContentEncoding:    o.ContentEncoding,
+		CacheControl:       o.CacheControl,
+		ContentDisposition: o.ContentDisposition,
+		CustomTime:         o.CustomTime,
+		StorageClass:       o.StorageClass,
+		Metadata:           o.Metadata,
+		ComponentCount:     o.ComponentCount,
+		CRC32C:             o.CRC32C,
+		Updated:            o.Updated,
+		Size:               fi.Size(),
+		ModTime:            fi.ModTime(),
+	}
+	if o.MD5 != nil {
+		sc.MD5 = hex.EncodeToString(o.MD5[:])
+	}
+
+	buf, err := json.MarshalIndent(&sc, "", "  ")
+	if err != nil {
+		err = fmt.Errorf("Marshal: %w", err)
+		return
+	}
+
+	f, err := b.createTemp()
+	if err != nil {
+		return
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(buf)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		err = fmt.Errorf("Write: %w", err)
+		return
+	}
+
+	err = rename(f.Name(), meta)
+	return
+}
+
+// Create an object from the given request. The touchup function, if non-nil,
+// adjusts the attributes of the object before they are saved.
+//
+// LOCKS_EXCLUDED(b.mu)
+func (b *bucket) createObject(
+	req *gcs.CreateObjectRequest,
+	touchup func(o *gcs.Object)) (o *gcs.Object, err error) {
+	// Check that the name is legal.
+	err = checkName(req.Name)
+	if err != nil {
+		return
+	}
+
+	// Write the contents aside, without holding the lock.
+	f, err := b.createTemp()
+	if err != nil {
+		return
+	}
+	defer os.Remove(f.Name())
+
+	md5Hash := md5.New()
+	crc32cHash := crc32.New(crc32cTable)
+	size, err := io.Copy(io.MultiWriter(f, md5Hash, crc32cHash), req.Contents)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		err = fmt.Errorf("Copy: %w", err)
+		return
+	}
+
+	var md5Sum [md5.Size]byte
+	copy(md5Sum[:], md5Hash.Sum(nil))
+	crc32c := crc32cHash.Sum32()
+
+	// Check the provided checksum, if any.
+	if req.CRC32C != nil && crc32c != *req.CRC32C {
+		err = fmt.Errorf(
+			"CRC32C mismatch: got 0x%08x, expected 0x%08x",
+			crc32c,
+			*req.CRC32C)
+
+		return
+	}
+
+	// Check the provided hash, if any.
+	if req.MD5 != nil && md5Sum != *req.MD5 {
+		err = fmt.Errorf(
+			"MD5 mismatch: got %s, expected %s",
+			hex.EncodeToString(md5Sum[:]),
+			hex.EncodeToString(req.MD5[:]))
+
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Find any existing object with this name.
+	existing, err := b.lookupLocked(req.Name)
+	var notFoundErr *gcs.NotFoundError
+	if errors.As(err, &notFoundErr) {
+		existing, err = nil, nil
+	}
+	if err != nil {
+		return
+	}
+
+	// Check preconditions.
+	err = storageutil.CheckWritePreconditions(existing, req.GenerationPrecondition, req.MetaGenerationPrecondition)
+	if err != nil {
+		return
+	}
+
+	o = &gcs.Object{
+		Name:               req.Name,
+		Size:               uint64(size),
+		ContentType:        req.ContentType,
+		ContentLanguage:    req.ContentLanguage,
+		ContentEncoding:    req.ContentEncoding,
+		CacheControl:       req.CacheControl,
+		ContentDisposition: req.ContentDisposition,
+		CustomTime:         req.CustomTime,
+		StorageClass:       req.StorageClass,
+		Metadata:           copyMetadata(req.Metadata),
+		ComponentCount:     1,
+		MD5:                &md5Sum,
+		CRC32C:             &crc32c,
+		Generation:         b.mintGenerationLocked(),
+		MetaGeneration:     1,
+		Updated:            b.clock.Now(),
+	}
+	if o.StorageClass == "" {
+		o.StorageClass = "STANDARD"
+	}
+	if touchup != nil {
+		touchup(o)
+	}
+
+	// Move the contents into place, then save the attributes. A crash in
+	// between leaves a sidecar file which doesn't match the file, and is
+	// ignored.
+	data, _ := b.paths(req.Name)
+	if err = rename(f.Name(), data); err != nil {
+		o = nil
+		return
+	}
+
+	if err = b.writeSidecarLocked(o); err != nil {
+		o = nil
+		return
+	}
+
+	return
+}
+
+// Remove the directories of the given object's name left empty, up to the
+// directory of the bucket.
+//
+// LOCKS_REQUIRED(b.mu)
+func (b *bucket) pruneLocked(name string) {
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		p := filepath.Join(b.dir, filepath.FromSlash(dir))
+		os.Remove(filepath.Join(p, metaDirName))
+		if os.Remove(p) != nil {
+			return
+		}
+	}
+}
+
+// Return the sorted names of the objects starting with the given prefix. With
+// a slash as the delimiter, only the directory of the prefix is read, and each
+// of its subdirectories holding objects is represented by a single name, as
+// the names under a subdirectory collapse into the same run.
+//
+// LOCKS_REQUIRED(b.mu)
+func (b *bucket) namesLocked(prefix string, delimiter string) (names []string, err error) {
+	// Only the directory of the prefix can have matching objects.
+	dir := prefix[:strings.LastIndex(prefix, "/")+1]
+	if dir != "" && !validPath(strings.TrimSuffix(dir, "/")) {
+		return
+	}
+	root := filepath.Join(b.dir, filepath.FromSlash(dir))
+
+	add := func(name string) {
+		if strings.HasPrefix(name, prefix) && checkName(name) == nil {
+			names = append(names, name)
+		}
+	}
+
+	if delimiter == "/" {
+		names, err = b.dirNames(root, dir, prefix)
+		return
+	}
+
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == root && isNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		rel, err := filepath.Rel(b.dir, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		switch {
+		case d.IsDir() && d.Name() == metaDirName:
+			// The object of the directory, if any. The metadata directory of the
+			// bucket has none.
+			if parent := path.Dir(name); parent != "." {
+				if _, err := os.Stat(filepath.Join(p, dirObjectData)); err == nil {
+					add(parent + "/")
+				}
+			}
+			return fs.SkipDir
+
+		case d.IsDir():
+			// Skip the directories whose objects can't match the prefix.
+			if p != root && !strings.HasPrefix(name+"/", prefix) && !strings.HasPrefix(prefix, name+"/") {
+				return fs.SkipDir
+			}
+
+		case d.Type().IsRegular():
+			add(name)
+		}
+
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("WalkDir: %w", err)
+		return
+	}
+
+	sort.Strings(names)
+	return
+}
+
+// Return the sorted names of the objects starting with the given prefix in the
+// directory at the given path, of the given name, and a name of an object in
+// each subdirectory starting with the prefix: its object, if any, or else the
+// first one found.
+//
+// LOCKS_REQUIRED(b.mu)
+func (b *bucket) dirNames(root string, dir string, prefix string) (names []string, err error) {
+	entries, err := os.ReadDir(root)
+	if isNotExist(err) {
+		err = nil
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf("ReadDir: %w", err)
+		return
+	}
+
+	add := func(name string) {
+		if strings.HasPrefix(name, prefix) && checkName(name) == nil {
+			names = append(names, name)
+		}
+	}
+
+	for _, e := range entries {
+		name := dir + e.Name()
+		switch {
+		case e.IsDir() && e.Name() == metaDirName:
+			// The object of the directory, if any. The metadata directory of the
+			// bucket has none.
+			if dir != "" {
+				if _, err := os.Stat(filepath.Join(root, metaDirName, dirObjectData)); err == nil {
+					add(dir)
+				}
+			}
+
+		case e.IsDir():
+			if !strings.HasPrefix(name+"/", prefix) || !validPath(name) {
+				continue
+			}
+
+			var first string
+			if first, err = b.firstName(filepath.Join(root, e.Name()), name+"/"); err != nil {
+				return
+			}
+			if first != "" {
+				add(first)
+			}
+
+		case e.Type().IsRegular():
+			add(name)
+		}
+	}
+
+	sort.Strings(names)
+	return
+}
+
+// Return the name of an object in the directory at the given path, of the
+// given name: its object, if any, or else the first one found, or the empty
+// string if there is none.
+//
+// LOCKS_REQUIRED(b.mu)
+func (b *bucket) firstName(root string, dir string) (name string, err error) {
+	if _, err = os.Stat(filepath.Join(root, metaDirName, dirObjectData)); err == nil {
+		name = dir
+		return
+	}
+
+	errFound := errors.New("found")
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if isNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		rel, err := filepath.Rel(b.dir, p)
+		if err != nil {
+			return err
+		}
+		n := filepath.ToSlash(rel)
+
+		switch {
+		case d.IsDir() && d.Name() == metaDirName:
+			if p != filepath.Join(root, metaDirName) {
+				if _, err := os.Stat(filepath.Join(p, dirObjectData)); err == nil && checkName(path.Dir(n)+"/") == nil {
+					name = path.Dir(n) + "/"
+					return errFound
+				}
+			}
+			return fs.SkipDir
+
+		case d.Type().IsRegular() && checkName(n) == nil:
+			name = n
+			return errFound
+		}
+
+		return nil
+	})
+	if errors.Is(err, errFound) {
+		err = nil
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf("WalkDir: %w", err)
+		return
+	}
+
+	return
+}
+
+////////////////////////////////////////////////////////////////////////
+// Public interface
+////////////////////////////////////////////////////////////////////////
+
+func (b *bucket) Name() string {
+	return b.name
+}
+
+// Directories are only prefixes of the object names, as in a flat bucket.
+func (b *bucket) BucketType() gcs.BucketType {
+	return gcs.NonHierarchical
+}
+
+// LOCKS_EXCLUDED(b.mu)
+func (b *bucket) NewReader(
+	ctx context.Context,
+	req *gcs.ReadObjectRequest) (rc io.ReadCloser, err error) {
+	b.mu.Lock()
+	o, f, err := b.openLocked(req.Name, req.Generation)
+	b.mu.Unlock()
+	if err != nil {
+		return
+	}
+
+	// Extract the requested range.
+	start := uint64(0)
+	limit := o.Size
+	if req.Range != nil {
+		start = req.Range.Start
+		limit = min(req.Range.Limit, o.Size)
+		if start > limit {
+			start = 0
+			limit = 0
+		}
+	}
+
+	rc = &objectReader{
+		Reader: io.NewSectionReader(f, int64(start), int64(limit-start)),
+		Closer: f,
+	}
+	return
+}
+
+// objectReader reads a range of the file of an object, closing the file once
+// done.
+type objectReader struct {
+	io.Reader
+	io.Closer
+}
+
+// LOCKS_EXCLUDED(b.mu)
+func (b *bucket) CreateObject(
+	ctx context.Context,
+	req *gcs.CreateObjectRequest) (o *gcs.Object, err error) {
+	o, err = b.createObject(req, nil)
+	return
+}
+
+// LOCKS_EXCLUDED(b.mu)
+func (b *bucket) CopyObject(
+	ctx context.Context,
+	req *gcs.CopyObjectRequest) (o *gcs.Object, err error) {
+	// Check that the destination name is legal.
+	err = checkName(req.DstName)
+	if err != nil {
+		return
+	}
+
+	b.mu.Lock()
+	src, f, err := b.openLocked(req.SrcName, req.SrcGeneration)
+	b.mu.Unlock()
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	// Does it have the correct meta-generation?
+	err = storageutil.CheckMetaGenerationPrecondition(src, req.SrcMetaGenerationPrecondition)
+	if err != nil {
+		return
+	}
+
+	// Copy it with a new generation number, preserving all metadata.
+	o, err = b.createObject(
+		&gcs.CreateObjectRequest{
+			Name:                   req.DstName,
+			ContentType:            src.ContentType,
+			ContentLanguage:        src.ContentLanguage,
+			ContentEncoding:        src.ContentEncoding,
+			CacheControl:           src.CacheControl,
+			Metadata:               src.Metadata,
+			ContentDisposition:     src.ContentDisposition,
+			CustomTime:             src.CustomTime,
+			StorageClass:           src.StorageClass,
+			Contents:               f,
+			GenerationPrecondition: req.DstGenerationPrecondition,
+		},
+		func(o *gcs.Object) {
+			o.ComponentCount = src.ComponentCount
+			o.MD5 = src.MD5
+		})
+
+	return
+}
+
+// LOCKS_EXCLUDED(b.mu)
+func (b *bucket) ComposeObjects(
+	ctx context.Context,
+	req *gcs.ComposeObjectsRequest) (o *gcs.Object, err error) {
+	o, err = storageutil.ComposeObjects(
+		req,
+		func(name string, generation int64) (*gcs.Object, io.ReadCloser, error) {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+
+			o, f, err := b.openLocked(name, generation)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			return o, f, nil
+		},
+		b.createObject)
+
+	return
+}
+
+// LOCKS_EXCLUDED(b.mu)
+func (b *bucket) StatObject(ctx context.Context,
+	req *gcs.StatObjectRequest) (m *gcs.MinObject, e *gcs.ExtendedObjectAttributes, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	o, err := b.lookupLocked(req.Name)
+	if err != nil {
+		return
+	}
+
+	m = storageutil.ConvertObjToMinObject(o)
+	if req.ReturnExtendedObjectAttributes {
+		e = storageutil.ConvertObjToExtendedObjectAttributes(o)
+	}
+	return
+}
+
+// LOCKS_EXCLUDED(b.mu)
+func (b *bucket) ListObjects(
+	ctx context.Context,
+	req *gcs.ListObjectsRequest) (listing *gcs.Listing, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Unlike the directories of prefixes, the one of the bucket must exist.
+	fi, err := os.Stat(b.dir)
+	if isNotExist(err) || (err == nil && !fi.IsDir()) {
+		err = &gcs.NotFoundError{
+			Err: fmt.Errorf("Bucket %s not found in %s", b.name, b.dir),
+		}
+
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf("Stat: %w", err)
+		return
+	}
+
+	names, err := b.namesLocked(req.Prefix, req.Delimiter)
+	if err != nil {
+		return
+	}
+
+	listing, err = storageutil.ListObjects(
+		req,
+		len(names),
+		func(i int) string { return names[i] },
+		func(i int) (*gcs.Object, error) { return b.lookupLocked(names[i]) })
+
+	return
+}
+
+// LOCKS_EXCLUDED(b.mu)
+func (b *bucket) UpdateObject(
+	ctx context.Context,
+	req *gcs.UpdateObjectRequest) (o *gcs.Object, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	o, err = b.lookupLocked(req.Name)
+	if err != nil {
+		return
+	}
+
+	if err = storageutil.UpdateObject(o, req, b.clock.Now()); err != nil {
+		o = nil
+		return
+	}
+
+	if err = b.writeSidecarLocked(o); err != nil {
+		o = nil
+		return
+	}
+
+	return
+}
+
+// LOCKS_EXCLUDED(b.mu)
+func (b *bucket) DeleteObject(
+	ctx context.Context,
+	req *gcs.DeleteObjectRequest) (err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Do we possess the object with the given name?
+	o, err := b.lookupLocked(req.Name)
+	var notFoundErr *gcs.NotFoundError
+	if errors.As(err, &notFoundErr) {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+
+	// Don't do anything if the generation is wrong.
+	if req.Generation != 0 && o.Generation != req.Generation {
+		return
+	}
+
+	// Check the meta-generation if requested.
+	err = storageutil.CheckMetaGenerationPrecondition(o, req.MetaGenerationPrecondition)
+	if err != nil {
+		return
+	}
+
+	// Remove the object, then its sidecar file, and the directories left
+	// empty.
+	data, meta := b.paths(req.Name)
+	if err = os.Remove(data); err != nil {
+		err = fmt.Errorf("Remove: %w", err)
+		return
+	}
+
+	if err = os.Remove(meta); err != nil && !isNotExist(err) {
+		err = fmt.Errorf("Remove: %w", err)
+		return
+	}
+	err = nil
+
+	b.pruneLocked(req.Name)
+	return
+}
@@ -0,0 +1,411 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package localdir
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"github.com/jacobsa/timeutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/context"
+)
+
+type BucketTest struct {
+	suite.Suite
+	ctx    context.Context
+	clock  *timeutil.SimulatedClock
+	dir    string
+	bucket gcs.Bucket
+}
+
+func TestBucketSuite(t *testing.T) {
+	suite.Run(t, new(BucketTest))
+}
+
+func (t *BucketTest) SetupTest() {
+	t.ctx = context.Background()
+	t.clock = &timeutil.SimulatedClock{}
+	t.clock.SetTime(time.Date(2012, 8, 15, 22, 56, 0, 0, time.UTC))
+	t.dir = filepath.Join(t.T().TempDir(), "b")
+	require.NoError(t.T(), os.Mkdir(t.dir, 0755))
+	t.bucket = NewBucket(t.clock, t.dir, "b")
+}
+
+func (t *BucketTest) create(name string, contents string) *gcs.Object {
+	o, err := t.bucket.CreateObject(t.ctx, &gcs.CreateObjectRequest{
+		Name:     name,
+		Contents: strings.NewReader(contents),
+	})
+	require.NoError(t.T(), err)
+	return o
+}
+
+func (t *BucketTest) read(req *gcs.ReadObjectRequest) (string, error) {
+	rc, err := t.bucket.NewReader(t.ctx, req)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	buf, err := io.ReadAll(rc)
+	return string(buf), err
+}
+
+func (t *BucketTest) stat(name string) (*gcs.MinObject, error) {
+	m, _, err := t.bucket.StatObject(t.ctx, &gcs.StatObjectRequest{Name: name})
+	return m, err
+}
+
+func (t *BucketTest) list(req *gcs.ListObjectsRequest) (names []string, runs []string, token string) {
+	listing, err := t.bucket.ListObjects(t.ctx, req)
+	require.NoError(t.T(), err)
+	for _, o := range listing.Objects {
+		names = append(names, o.Name)
+	}
+	return names, listing.CollapsedRuns, listing.ContinuationToken
+}
+
+func (t *BucketTest) TestCreateAndRead() {
+	o := t.create("a/b", "taco")
+
+	assert.Equal(t.T(), "a/b", o.Name)
+	assert.EqualValues(t.T(), 4, o.Size)
+	assert.Equal(t.T(), t.clock.Now().UnixMicro(), o.Generation)
+	assert.EqualValues(t.T(), 1, o.MetaGeneration)
+	assert.NotNil(t.T(), o.MD5)
+	assert.NotNil(t.T(), o.CRC32C)
+	buf, err := os.ReadFile(filepath.Join(t.dir, "a", "b"))
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), "taco", string(buf))
+	assert.FileExists(t.T(), filepath.Join(t.dir, "a", ".gcsfuse-meta", "b.json"))
+	contents, err := t.read(&gcs.ReadObjectRequest{Name: "a/b", Generation: o.Generation})
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), "taco", contents)
+}
+
+func (t *BucketTest) TestCreateDirObject() {
+	o := t.create("a/", "")
+
+	assert.FileExists(t.T(), filepath.Join(t.dir, "a", ".gcsfuse-meta", ".data"))
+	m, err := t.stat("a/")
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), o.Generation, m.Generation)
+}
+
+func (t *BucketTest) TestCreateIllegalNames() {
+	for _, name := range []string{"", "a//b", "/a", "./a", "a/../b", ".gcsfuse-meta/x", "a\nb"} {
+		_, err := t.bucket.CreateObject(t.ctx, &gcs.CreateObjectRequest{
+			Name:     name,
+			Contents: strings.NewReader(""),
+		})
+		assert.Error(t.T(), err, name)
+	}
+}
+
+func (t *BucketTest) TestCreateIncorrectCRC32C() {
+	crc32c := uint32(17)
+
+	_, err := t.bucket.CreateObject(t.ctx, &gcs.CreateObjectRequest{
+		Name:     "a",
+		Contents: strings.NewReader("taco"),
+		CRC32C:   &crc32c,
+	})
+
+	assert.ErrorContains(t.T(), err, "CRC32C mismatch")
+	_, err = t.stat("a")
+	assert.IsType(t.T(), &gcs.NotFoundError{}, err)
+	entries, err := os.ReadDir(filepath.Join(t.dir, ".gcsfuse-meta", "tmp"))
+	require.NoError(t.T(), err)
+	assert.Empty(t.T(), entries)
+}
+
+func (t *BucketTest) TestCreatePreconditions() {
+	o := t.create("a", "taco")
+	zero := int64(0)
+	wrong := o.Generation + 1
+	wrongMeta := int64(2)
+
+	for _, req := range []*gcs.CreateObjectRequest{
+		{Name: "a", GenerationPrecondition: &zero},
+		{Name: "a", GenerationPrecondition: &wrong},
+		{Name: "a", GenerationPrecondition: &o.Generation, MetaGenerationPrecondition: &wrongMeta},
+		{Name: "b", GenerationPrecondition: &wrong},
+	} {
+		req.Contents = strings.NewReader("burrito")
+		_, err := t.bucket.CreateObject(t.ctx, req)
+		assert.IsType(t.T(), &gcs.PreconditionError{}, err)
+	}
+
+	o2, err := t.bucket.CreateObject(t.ctx, &gcs.CreateObjectRequest{
+		Name:                   "a",
+		Contents:               strings.NewReader("burrito"),
+		GenerationPrecondition: &o.Generation,
+	})
+	require.NoError(t.T(), err)
+	assert.Greater(t.T(), o2.Generation, o.Generation)
+}
+
+func (t *BucketTest) TestObjectsPersist() {
+	o := t.create("a", "taco")
+	_, err := t.bucket.UpdateObject(t.ctx, &gcs.UpdateObjectRequest{
+		Name:        "a",
+		ContentType: &[]string{"text/plain"}[0],
+	})
+	require.NoError(t.T(), err)
+
+	bucket := NewBucket(timeutil.RealClock(), t.dir, "b")
+	m, e, err := bucket.StatObject(t.ctx, &gcs.StatObjectRequest{Name: "a", ForceFetchFromGcs: true, ReturnExtendedObjectAttributes: true})
+
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), o.Generation, m.Generation)
+	assert.EqualValues(t.T(), 2, m.MetaGeneration)
+	assert.Equal(t.T(), "text/plain", e.ContentType)
+	assert.Equal(t.T(), o.MD5, e.MD5)
+	assert.Equal(t.T(), o.CRC32C, e.CRC32C)
+}
+
+func (t *BucketTest) TestFileWithoutSidecar() {
+	p := filepath.Join(t.dir, "a", "b")
+	require.NoError(t.T(), os.MkdirAll(filepath.Dir(p), 0755))
+	require.NoError(t.T(), os.WriteFile(p, []byte("taco"), 0644))
+	fi, err := os.Stat(p)
+	require.NoError(t.T(), err)
+
+	m, err := t.stat("a/b")
+
+	require.NoError(t.T(), err)
+	assert.EqualValues(t.T(), 4, m.Size)
+	assert.Equal(t.T(), fi.ModTime().UnixMicro(), m.Generation)
+	assert.EqualValues(t.T(), 1, m.MetaGeneration)
+}
+
+func (t *BucketTest) TestSidecarOfChangedFileIsIgnored() {
+	o := t.create("a", "taco")
+	_, err := t.bucket.UpdateObject(t.ctx, &gcs.UpdateObjectRequest{Name: "a", Metadata: map[string]*string{"k": &[]string{"v"}[0]}})
+	require.NoError(t.T(), err)
+	p := filepath.Join(t.dir, "a")
+	require.NoError(t.T(), os.WriteFile(p, []byte("burrito"), 0644))
+	fi, err := os.Stat(p)
+	require.NoError(t.T(), err)
+
+	m, err := t.stat("a")
+
+	require.NoError(t.T(), err)
+	assert.NotEqual(t.T(), o.Generation, m.Generation)
+	assert.Equal(t.T(), fi.ModTime().UnixMicro(), m.Generation)
+	assert.EqualValues(t.T(), 1, m.MetaGeneration)
+	assert.EqualValues(t.T(), 7, m.Size)
+	assert.Nil(t.T(), m.Metadata)
+}
+
+func (t *BucketTest) TestReadRangesAndGenerations() {
+	o := t.create("a", "taco")
+
+	for _, tc := range []struct {
+		r    gcs.ByteRange
+		want string
+	}{
+		{gcs.ByteRange{Start: 1, Limit: 3}, "ac"},
+		{gcs.ByteRange{Start: 2, Limit: 10}, "co"},
+		{gcs.ByteRange{Start: 3, Limit: 1}, ""},
+		{gcs.ByteRange{Start: 6, Limit: 10}, ""},
+	} {
+		contents, err := t.read(&gcs.ReadObjectRequest{Name: "a", Range: &tc.r})
+		require.NoError(t.T(), err)
+		assert.Equal(t.T(), tc.want, contents, tc.r)
+	}
+
+	_, err := t.read(&gcs.ReadObjectRequest{Name: "a", Generation: o.Generation + 1})
+	assert.IsType(t.T(), &gcs.NotFoundError{}, err)
+	_, err = t.read(&gcs.ReadObjectRequest{Name: "../b/a"})
+	assert.IsType(t.T(), &gcs.NotFoundError{}, err)
+}
+
+func (t *BucketTest) TestCopyObject() {
+	src := t.create("a", "taco")
+	wrongMeta := int64(2)
+
+	_, err := t.bucket.CopyObject(t.ctx, &gcs.CopyObjectRequest{SrcName: "a", DstName: "b", SrcGeneration: src.Generation + 1})
+	assert.IsType(t.T(), &gcs.NotFoundError{}, err)
+	_, err = t.bucket.CopyObject(t.ctx, &gcs.CopyObjectRequest{SrcName: "a", DstName: "b", SrcMetaGenerationPrecondition: &wrongMeta})
+	assert.IsType(t.T(), &gcs.PreconditionError{}, err)
+
+	dst, err := t.bucket.CopyObject(t.ctx, &gcs.CopyObjectRequest{SrcName: "a", DstName: "c/d", SrcGeneration: src.Generation})
+
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), "c/d", dst.Name)
+	assert.Greater(t.T(), dst.Generation, src.Generation)
+	assert.Equal(t.T(), src.MD5, dst.MD5)
+	contents, err := t.read(&gcs.ReadObjectRequest{Name: "c/d"})
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), "taco", contents)
+}
+
+func (t *BucketTest) TestComposeObjects() {
+	a := t.create("a", "taco")
+	t.create("b", "burrito")
+
+	o, err := t.bucket.ComposeObjects(t.ctx, &gcs.ComposeObjectsRequest{
+		DstName: "c",
+		Sources: []gcs.ComposeSource{{Name: "a", Generation: a.Generation}, {Name: "b"}, {Name: "a"}},
+	})
+
+	require.NoError(t.T(), err)
+	assert.EqualValues(t.T(), 3, o.ComponentCount)
+	assert.Nil(t.T(), o.MD5)
+	contents, err := t.read(&gcs.ReadObjectRequest{Name: "c"})
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), "tacoburritotaco", contents)
+
+	_, err = t.bucket.ComposeObjects(t.ctx, &gcs.ComposeObjectsRequest{
+		DstName: "c",
+		Sources: []gcs.ComposeSource{{Name: "a", Generation: a.Generation + 1}},
+	})
+	assert.IsType(t.T(), &gcs.NotFoundError{}, err)
+}
+
+func (t *BucketTest) TestUpdateObject() {
+	o := t.create("a", "taco")
+	v := "v"
+	wrongMeta := int64(2)
+
+	_, err := t.bucket.UpdateObject(t.ctx, &gcs.UpdateObjectRequest{Name: "a", MetaGenerationPrecondition: &wrongMeta})
+	assert.IsType(t.T(), &gcs.PreconditionError{}, err)
+	_, err = t.bucket.UpdateObject(t.ctx, &gcs.UpdateObjectRequest{Name: "a", Generation: o.Generation + 1})
+	assert.IsType(t.T(), &gcs.NotFoundError{}, err)
+
+	t.clock.AdvanceTime(time.Minute)
+	updated, err := t.bucket.UpdateObject(t.ctx, &gcs.UpdateObjectRequest{
+		Name:     "a",
+		Metadata: map[string]*string{"k": &v, "l": &v},
+	})
+	require.NoError(t.T(), err)
+	updated, err = t.bucket.UpdateObject(t.ctx, &gcs.UpdateObjectRequest{
+		Name:     "a",
+		Metadata: map[string]*string{"l": nil},
+	})
+
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), o.Generation, updated.Generation)
+	assert.EqualValues(t.T(), 3, updated.MetaGeneration)
+	assert.Equal(t.T(), map[string]string{"k": "v"}, updated.Metadata)
+	assert.True(t.T(), t.clock.Now().Equal(updated.Updated))
+	m, err := t.stat("a")
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), map[string]string{"k": "v"}, m.Metadata)
+}
+
+func (t *BucketTest) TestDeleteObject() {
+	o := t.create("a/b/c", "taco")
+	t.create("a/", "")
+	wrongMeta := int64(2)
+
+	err := t.bucket.DeleteObject(t.ctx, &gcs.DeleteObjectRequest{Name: "a/b/c", MetaGenerationPrecondition: &wrongMeta})
+	assert.IsType(t.T(), &gcs.PreconditionError{}, err)
+	// A wrong generation is silently ignored.
+	require.NoError(t.T(), t.bucket.DeleteObject(t.ctx, &gcs.DeleteObjectRequest{Name: "a/b/c", Generation: o.Generation + 1}))
+	_, err = t.stat("a/b/c")
+	require.NoError(t.T(), err)
+
+	require.NoError(t.T(), t.bucket.DeleteObject(t.ctx, &gcs.DeleteObjectRequest{Name: "a/b/c", Generation: o.Generation}))
+
+	_, err = t.stat("a/b/c")
+	assert.IsType(t.T(), &gcs.NotFoundError{}, err)
+	// The directories left empty are removed, but not the ones of other objects.
+	assert.NoDirExists(t.T(), filepath.Join(t.dir, "a", "b"))
+	assert.DirExists(t.T(), filepath.Join(t.dir, "a"))
+	require.NoError(t.T(), t.bucket.DeleteObject(t.ctx, &gcs.DeleteObjectRequest{Name: "a/"}))
+	assert.NoDirExists(t.T(), filepath.Join(t.dir, "a"))
+	// Deleting a missing object succeeds.
+	assert.NoError(t.T(), t.bucket.DeleteObject(t.ctx, &gcs.DeleteObjectRequest{Name: "a/"}))
+}
+
+func (t *BucketTest) TestListObjects() {
+	for _, name := range []string{"a", "b/", "b/c", "b/d/e", "b/f", "c/g"} {
+		t.create(name, "")
+	}
+
+	names, runs, token := t.list(&gcs.ListObjectsRequest{})
+	assert.Equal(t.T(), []string{"a", "b/", "b/c", "b/d/e", "b/f", "c/g"}, names)
+	assert.Empty(t.T(), runs)
+	assert.Empty(t.T(), token)
+
+	names, runs, _ = t.list(&gcs.ListObjectsRequest{Delimiter: "/"})
+	assert.Equal(t.T(), []string{"a"}, names)
+	assert.Equal(t.T(), []string{"b/", "c/"}, runs)
+
+	names, runs, _ = t.list(&gcs.ListObjectsRequest{Prefix: "b/", Delimiter: "/"})
+	assert.Equal(t.T(), []string{"b/", "b/c", "b/f"}, names)
+	assert.Equal(t.T(), []string{"b/d/"}, runs)
+
+	names, runs, _ = t.list(&gcs.ListObjectsRequest{Delimiter: "/", IncludeTrailingDelimiter: true})
+	assert.Equal(t.T(), []string{"a", "b/"}, names)
+	assert.Equal(t.T(), []string{"b/", "c/"}, runs)
+
+	names, _, _ = t.list(&gcs.ListObjectsRequest{Prefix: "b/d"})
+	assert.Equal(t.T(), []string{"b/d/e"}, names)
+}
+
+func (t *BucketTest) TestListObjectsWithDelimiterReadsOneDirectory() {
+	for _, name := range []string{"a/b/c/d", "a/e/", "a/e/f", "a/g"} {
+		t.create(name, "")
+	}
+	require.NoError(t.T(), os.MkdirAll(filepath.Join(t.dir, "a", "empty", "sub"), 0755))
+
+	names, runs, token := t.list(&gcs.ListObjectsRequest{Prefix: "a/", Delimiter: "/"})
+	assert.Equal(t.T(), []string{"a/g"}, names)
+	assert.Equal(t.T(), []string{"a/b/", "a/e/"}, runs)
+	assert.Empty(t.T(), token)
+
+	names, runs, _ = t.list(&gcs.ListObjectsRequest{Prefix: "a/", Delimiter: "/", IncludeTrailingDelimiter: true})
+	assert.Equal(t.T(), []string{"a/e/", "a/g"}, names)
+	assert.Equal(t.T(), []string{"a/b/", "a/e/"}, runs)
+
+	names, runs, _ = t.list(&gcs.ListObjectsRequest{Prefix: "a/e", Delimiter: "/"})
+	assert.Empty(t.T(), names)
+	assert.Equal(t.T(), []string{"a/e/"}, runs)
+}
+
+func (t *BucketTest) TestListObjectsPagination() {
+	for _, name := range []string{"a", "b/c", "b/d", "e"} {
+		t.create(name, "")
+	}
+
+	names, runs, token := t.list(&gcs.ListObjectsRequest{Delimiter: "/", MaxResults: 2})
+	assert.Equal(t.T(), []string{"a"}, names)
+	assert.Equal(t.T(), []string{"b/"}, runs)
+	require.NotEmpty(t.T(), token)
+
+	names, runs, token = t.list(&gcs.ListObjectsRequest{Delimiter: "/", MaxResults: 2, ContinuationToken: token})
+	assert.Equal(t.T(), []string{"e"}, names)
+	assert.Empty(t.T(), runs)
+	assert.Empty(t.T(), token)
+}
+
+func (t *BucketTest) TestListObjectsMissingBucket() {
+	bucket := NewBucket(t.clock, filepath.Join(t.dir, "missing"), "missing")
+
+	_, err := bucket.ListObjects(t.ctx, &gcs.ListObjectsRequest{})
+
+	assert.IsType(t.T(), &gcs.NotFoundError{}, err)
+}
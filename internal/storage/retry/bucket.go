@@ -0,0 +1,352 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package retry implements a gcs.Bucket retrying the requests to another
+// bucket that failed with a transient error or missed their deadline,
+// following a policy per class of requests.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/config"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/storageutil"
+	"github.com/jacobsa/timeutil"
+)
+
+// The cause of the cancellation of an attempt that missed its deadline.
+var errAttemptTimeout = errors.New("attempt timed out")
+
+type policy struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	multiplier     float64
+	attemptTimeout time.Duration
+}
+
+func newPolicy(c config.RetryPolicyConfig) policy {
+	return policy{
+		maxAttempts:    c.MaxAttempts,
+		initialBackoff: time.Duration(c.InitialBackoffMs) * time.Millisecond,
+		maxBackoff:     time.Duration(c.MaxBackoffMs) * time.Millisecond,
+		multiplier:     c.Multiplier,
+		attemptTimeout: time.Duration(c.AttemptTimeoutMs) * time.Millisecond,
+	}
+}
+
+// Return the wait before the given retry, starting at 1, drawn uniformly at
+// random up to the exponential backoff so that clients failing together don't
+// retry together.
+func (p *policy) backoff(retry int) time.Duration {
+	d := float64(p.initialBackoff) * math.Pow(p.multiplier, float64(retry-1))
+	d = min(d, float64(p.maxBackoff))
+	return time.Duration(rand.Float64() * d)
+}
+
+// NewBucket returns a bucket retrying the requests to the wrapped one
+// following the policies of the given config. The requests of a class without
+// a policy are sent once, and retried by the wrapped bucket as before.
+func NewBucket(c config.RetryConfig, clock timeutil.Clock, wrapped gcs.Bucket) gcs.Bucket {
+	return &bucket{
+		wrapped:  wrapped,
+		reads:    newPolicy(c.Reads),
+		metadata: newPolicy(c.Metadata),
+		uploads:  newPolicy(c.Uploads),
+		budget:   newBudget(clock, c.BudgetRatio, float64(c.BudgetMinRetriesPerSec)),
+	}
+}
+
+type bucket struct {
+	wrapped gcs.Bucket
+
+	reads    policy
+	metadata policy
+	uploads  policy
+
+	budget *budget
+}
+
+// retry calls f until it succeeds, fails with an error that isn't transient,
+// or the attempts of the policy or the retry budget are exhausted, and returns
+// the error of the last attempt.
+//
+// f is called with the context of the attempt, which is cancelled when the
+// attempt misses its deadline. When f succeeds, the caller must call the
+// returned cancel func once done with its results, e.g. the reader it opened.
+//
+// The results of f outliving its context, like a reader, may be dead when the
+// attempt succeeds just as it misses its deadline: discard, if not nil,
+// releases them, and the attempt is then retried as timed out. Otherwise the
+// success is final.
+func (b *bucket) retry(
+	ctx context.Context,
+	p *policy,
+	method string,
+	name string,
+	f func(context.Context) error,
+	discard func()) (cancel context.CancelFunc, err error) {
+	b.budget.request()
+
+	// The wrapped bucket mustn't retry the attempts itself.
+	ctx = context.WithValue(ctx, gcs.NoClientRetriesField, true)
+
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancelAttempt := context.WithCancelCause(ctx)
+		var timer *time.Timer
+		fired := make(chan struct{})
+		if p.attemptTimeout > 0 {
+			timer = time.AfterFunc(p.attemptTimeout, func() {
+				cancelAttempt(errAttemptTimeout)
+				close(fired)
+			})
+		}
+
+		err = f(attemptCtx)
+
+		// Stop the deadline, or wait for it to be applied if it's being applied.
+		if timer != nil && !timer.Stop() {
+			<-fired
+		}
+		timedOut := context.Cause(attemptCtx) == errAttemptTimeout
+
+		if err == nil && timedOut && discard != nil {
+			discard()
+			err = attemptCtx.Err()
+		}
+
+		if err == nil {
+			cancel = func() { cancelAttempt(context.Canceled) }
+			return
+		}
+
+		cancelAttempt(context.Canceled)
+		if timedOut {
+			err = fmt.Errorf("attempt timed out after %v: %w", p.attemptTimeout, err)
+		}
+
+		var reason string
+		switch {
+		case ctx.Err() != nil:
+			return
+		case timedOut:
+			reason = monitor.RetryReasonDeadline
+		case storageutil.IsRetryable(err):
+			reason = monitor.RetryReasonError
+		default:
+			return
+		}
+
+		if attempt >= p.maxAttempts {
+			return
+		}
+
+		if !b.budget.withdraw() {
+			logger.Warnf("Not retrying %s(%q), the retry budget is exhausted: %v", method, name, err)
+			monitor.CaptureRetryMetrics(ctx, method, monitor.RetryReasonBudgetExceeded)
+			return
+		}
+
+		wait := p.backoff(attempt)
+		logger.Infof("Retrying %s(%q) in %v, after attempt %d of %d failed: %v", method, name, wait, attempt, p.maxAttempts, err)
+		monitor.CaptureRetryMetrics(ctx, method, reason)
+
+		waitTimer := time.NewTimer(wait)
+		select {
+		case <-waitTimer.C:
+		case <-ctx.Done():
+			waitTimer.Stop()
+			return
+		}
+	}
+}
+
+// do is retry for the requests whose results don't depend on their context
+// once they returned.
+func (b *bucket) do(
+	ctx context.Context,
+	p *policy,
+	method string,
+	name string,
+	f func(context.Context) error) (err error) {
+	cancel, err := b.retry(ctx, p, method, name, f, nil)
+	if err == nil {
+		cancel()
+	}
+
+	return
+}
+
+func (b *bucket) Name() string {
+	return b.wrapped.Name()
+}
+
+func (b *bucket) BucketType() gcs.BucketType {
+	return b.wrapped.BucketType()
+}
+
+func (b *bucket) NewReader(
+	ctx context.Context,
+	req *gcs.ReadObjectRequest) (rc io.ReadCloser, err error) {
+	if b.reads.maxAttempts == 0 {
+		return b.wrapped.NewReader(ctx, req)
+	}
+
+	// The deadline of the attempts is the deadline to open the object: the
+	// reader must outlive them.
+	cancel, err := b.retry(ctx, &b.reads, "NewReader", req.Name,
+		func(ctx context.Context) (err error) {
+			rc, err = b.wrapped.NewReader(ctx, req)
+			return
+		},
+		func() {
+			rc.Close()
+		})
+	if err != nil {
+		return
+	}
+
+	rc = &storageutil.CancellingReadCloser{ReadCloser: rc, Cancel: cancel}
+	return
+}
+
+func (b *bucket) CreateObject(
+	ctx context.Context,
+	req *gcs.CreateObjectRequest) (o *gcs.Object, err error) {
+	// The upload can only be retried if it's idempotent, and if its contents can
+	// be read again.
+	seeker, ok := req.Contents.(io.Seeker)
+	if b.uploads.maxAttempts == 0 || req.GenerationPrecondition == nil || !ok {
+		return b.wrapped.CreateObject(ctx, req)
+	}
+
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return b.wrapped.CreateObject(ctx, req)
+	}
+
+	attempt := 0
+	err = b.do(ctx, &b.uploads, "CreateObject", req.Name, func(ctx context.Context) (err error) {
+		attempt++
+		if attempt > 1 {
+			if _, err = seeker.Seek(start, io.SeekStart); err != nil {
+				err = fmt.Errorf("Seek: %w", err)
+				return
+			}
+		}
+
+		o, err = b.wrapped.CreateObject(ctx, req)
+		return
+	})
+
+	return
+}
+
+func (b *bucket) CopyObject(
+	ctx context.Context,
+	req *gcs.CopyObjectRequest) (o *gcs.Object, err error) {
+	if b.uploads.maxAttempts == 0 || req.DstGenerationPrecondition == nil {
+		return b.wrapped.CopyObject(ctx, req)
+	}
+
+	err = b.do(ctx, &b.uploads, "CopyObject", req.DstName, func(ctx context.Context) (err error) {
+		o, err = b.wrapped.CopyObject(ctx, req)
+		return
+	})
+
+	return
+}
+
+func (b *bucket) ComposeObjects(
+	ctx context.Context,
+	req *gcs.ComposeObjectsRequest) (o *gcs.Object, err error) {
+	if b.uploads.maxAttempts == 0 || req.DstGenerationPrecondition == nil {
+		return b.wrapped.ComposeObjects(ctx, req)
+	}
+
+	err = b.do(ctx, &b.uploads, "ComposeObjects", req.DstName, func(ctx context.Context) (err error) {
+		o, err = b.wrapped.ComposeObjects(ctx, req)
+		return
+	})
+
+	return
+}
+
+func (b *bucket) StatObject(
+	ctx context.Context,
+	req *gcs.StatObjectRequest) (m *gcs.MinObject, e *gcs.ExtendedObjectAttributes, err error) {
+	if b.metadata.maxAttempts == 0 {
+		return b.wrapped.StatObject(ctx, req)
+	}
+
+	err = b.do(ctx, &b.metadata, "StatObject", req.Name, func(ctx context.Context) (err error) {
+		m, e, err = b.wrapped.StatObject(ctx, req)
+		return
+	})
+
+	return
+}
+
+func (b *bucket) ListObjects(
+	ctx context.Context,
+	req *gcs.ListObjectsRequest) (listing *gcs.Listing, err error) {
+	if b.metadata.maxAttempts == 0 {
+		return b.wrapped.ListObjects(ctx, req)
+	}
+
+	err = b.do(ctx, &b.metadata, "ListObjects", req.Prefix, func(ctx context.Context) (err error) {
+		listing, err = b.wrapped.ListObjects(ctx, req)
+		return
+	})
+
+	return
+}
+
+func (b *bucket) UpdateObject(
+	ctx context.Context,
+	req *gcs.UpdateObjectRequest) (o *gcs.Object, err error) {
+	if b.metadata.maxAttempts == 0 || req.MetaGenerationPrecondition == nil {
+		return b.wrapped.UpdateObject(ctx, req)
+	}
+
+	err = b.do(ctx, &b.metadata, "UpdateObject", req.Name, func(ctx context.Context) (err error) {
+		o, err = b.wrapped.UpdateObject(ctx, req)
+		return
+	})
+
+	return
+}
+
+func (b *bucket) DeleteObject(
+	ctx context.Context,
+	req *gcs.DeleteObjectRequest) (err error) {
+	if b.metadata.maxAttempts == 0 || (req.Generation == 0 && req.MetaGenerationPrecondition == nil) {
+		return b.wrapped.DeleteObject(ctx, req)
+	}
+
+	err = b.do(ctx, &b.metadata, "DeleteObject", req.Name, func(ctx context.Context) error {
+		return b.wrapped.DeleteObject(ctx, req)
+	})
+
+	return
+}
@@ -0,0 +1,247 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/config"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/fake"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/faults"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/storageutil"
+	"github.com/jacobsa/timeutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/googleapi"
+)
+
+func testConfig(maxAttempts int) config.RetryConfig {
+	policy := config.RetryPolicyConfig{
+		MaxAttempts:      maxAttempts,
+		InitialBackoffMs: 1,
+		MaxBackoffMs:     10,
+		Multiplier:       2,
+	}
+	return config.RetryConfig{
+		Reads:                  policy,
+		Metadata:               policy,
+		Uploads:                policy,
+		BudgetRatio:            config.DefaultRetryBudgetRatio,
+		BudgetMinRetriesPerSec: config.DefaultRetryBudgetMinRetriesPerSec,
+	}
+}
+
+func newFakeBucket(t *testing.T, rules ...faults.Rule) gcs.Bucket {
+	b := fake.NewFakeBucket(timeutil.RealClock(), "b")
+	_, err := storageutil.CreateObject(context.Background(), b, "a", []byte("taco"))
+	require.NoError(t, err)
+	return faults.NewBucket(rules, 1, b)
+}
+
+func stat(b gcs.Bucket) error {
+	_, _, err := b.StatObject(context.Background(), &gcs.StatObjectRequest{Name: "a"})
+	return err
+}
+
+func TestRetriesTransientErrors(t *testing.T) {
+	b := NewBucket(testConfig(3), timeutil.RealClock(), newFakeBucket(t,
+		faults.Rule{Method: "StatObject", Count: 2, ErrorCode: 503}))
+
+	assert.NoError(t, stat(b))
+}
+
+func TestGivesUpAfterMaxAttempts(t *testing.T) {
+	b := NewBucket(testConfig(3), timeutil.RealClock(), newFakeBucket(t,
+		faults.Rule{Method: "StatObject", Count: 3, ErrorCode: 503}))
+
+	err := stat(b)
+
+	var apiErr *googleapi.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, 503, apiErr.Code)
+	assert.NoError(t, stat(b))
+}
+
+func TestDoesntRetryPermanentErrors(t *testing.T) {
+	b := NewBucket(testConfig(3), timeutil.RealClock(), newFakeBucket(t,
+		faults.Rule{Method: "StatObject", Count: 2, ErrorCode: 404}))
+
+	assert.IsType(t, &gcs.NotFoundError{}, stat(b))
+	// The second fault wasn't consumed by a retry.
+	assert.IsType(t, &gcs.NotFoundError{}, stat(b))
+	assert.NoError(t, stat(b))
+}
+
+func TestNoPolicy(t *testing.T) {
+	b := NewBucket(testConfig(0), timeutil.RealClock(), newFakeBucket(t,
+		faults.Rule{Method: "StatObject", Count: 1, ErrorCode: 503}))
+
+	assert.Error(t, stat(b))
+}
+
+func TestBudgetExceeded(t *testing.T) {
+	c := testConfig(3)
+	c.BudgetRatio = 0
+	c.BudgetMinRetriesPerSec = 0
+	b := NewBucket(c, timeutil.RealClock(), newFakeBucket(t,
+		faults.Rule{Method: "StatObject", Count: 1, ErrorCode: 503}))
+
+	assert.Error(t, stat(b))
+}
+
+func TestAttemptTimeout(t *testing.T) {
+	c := testConfig(2)
+	c.Metadata.AttemptTimeoutMs = 10
+	b := NewBucket(c, timeutil.RealClock(), newFakeBucket(t,
+		faults.Rule{Method: "StatObject", Count: 1, Latency: time.Hour}))
+
+	assert.NoError(t, stat(b))
+}
+
+func TestAttemptTimeoutExhausted(t *testing.T) {
+	c := testConfig(2)
+	c.Metadata.AttemptTimeoutMs = 10
+	b := NewBucket(c, timeutil.RealClock(), newFakeBucket(t,
+		faults.Rule{Method: "StatObject", Latency: time.Hour}))
+
+	err := stat(b)
+
+	assert.ErrorContains(t, err, "attempt timed out after 10ms")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// ctxBucket records the context of the last call to NewReader.
+type ctxBucket struct {
+	gcs.Bucket
+	ctx context.Context
+}
+
+func (b *ctxBucket) NewReader(ctx context.Context, req *gcs.ReadObjectRequest) (io.ReadCloser, error) {
+	b.ctx = ctx
+	return b.Bucket.NewReader(ctx, req)
+}
+
+func TestReaderOutlivesAttemptTimeout(t *testing.T) {
+	c := testConfig(2)
+	c.Reads.AttemptTimeoutMs = 10
+	wrapped := &ctxBucket{Bucket: newFakeBucket(t)}
+	b := NewBucket(c, timeutil.RealClock(), wrapped)
+
+	rc, err := b.NewReader(context.Background(), &gcs.ReadObjectRequest{Name: "a"})
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+
+	assert.NoError(t, wrapped.ctx.Err())
+	assert.Equal(t, true, wrapped.ctx.Value(gcs.NoClientRetriesField))
+	buf, err := io.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.Equal(t, "taco", string(buf))
+	assert.NoError(t, rc.Close())
+	assert.Error(t, wrapped.ctx.Err())
+}
+
+// lateReaderBucket opens readers once the context of the request is done,
+// and counts those opened and closed.
+type lateReaderBucket struct {
+	gcs.Bucket
+	opened, closed int
+}
+
+type countingReadCloser struct {
+	io.ReadCloser
+	b *lateReaderBucket
+}
+
+func (rc *countingReadCloser) Close() error {
+	rc.b.closed++
+	return rc.ReadCloser.Close()
+}
+
+func (b *lateReaderBucket) NewReader(ctx context.Context, req *gcs.ReadObjectRequest) (io.ReadCloser, error) {
+	<-ctx.Done()
+	b.opened++
+	return &countingReadCloser{ReadCloser: io.NopCloser(strings.NewReader("taco")), b: b}, nil
+}
+
+func TestReaderOpenedAsAttemptTimesOut(t *testing.T) {
+	wrapped := &lateReaderBucket{Bucket: newFakeBucket(t)}
+	b := NewBucket(testConfig(2), timeutil.RealClock(), wrapped)
+	b.(*bucket).reads.attemptTimeout = time.Nanosecond
+
+	_, err := b.NewReader(context.Background(), &gcs.ReadObjectRequest{Name: "a"})
+
+	assert.ErrorContains(t, err, "attempt timed out after 1ns")
+	assert.Equal(t, 2, wrapped.opened)
+	assert.Equal(t, 2, wrapped.closed)
+}
+
+// failingUploadBucket reads the contents of the first upload, and fails it.
+type failingUploadBucket struct {
+	gcs.Bucket
+	failed bool
+}
+
+func (b *failingUploadBucket) CreateObject(ctx context.Context, req *gcs.CreateObjectRequest) (*gcs.Object, error) {
+	if !b.failed {
+		b.failed = true
+		_, _ = io.ReadAll(req.Contents)
+		return nil, &googleapi.Error{Code: 503}
+	}
+	return b.Bucket.CreateObject(ctx, req)
+}
+
+func TestUploadWithPrecondition(t *testing.T) {
+	wrapped := &failingUploadBucket{Bucket: newFakeBucket(t)}
+	b := NewBucket(testConfig(2), timeutil.RealClock(), wrapped)
+	var zero int64
+
+	_, err := b.CreateObject(context.Background(), &gcs.CreateObjectRequest{
+		Name:                   "b",
+		Contents:               strings.NewReader("burrito"),
+		GenerationPrecondition: &zero,
+	})
+
+	require.NoError(t, err)
+	contents, err := storageutil.ReadObject(context.Background(), b, "b")
+	require.NoError(t, err)
+	assert.Equal(t, "burrito", string(contents))
+}
+
+func TestUploadWithoutPrecondition(t *testing.T) {
+	wrapped := &failingUploadBucket{Bucket: newFakeBucket(t)}
+	b := NewBucket(testConfig(2), timeutil.RealClock(), wrapped)
+
+	_, err := b.CreateObject(context.Background(), &gcs.CreateObjectRequest{
+		Name:     "b",
+		Contents: strings.NewReader("burrito"),
+	})
+
+	assert.Error(t, err)
+}
+
+func TestDeleteWithoutPrecondition(t *testing.T) {
+	b := NewBucket(testConfig(2), timeutil.RealClock(), newFakeBucket(t,
+		faults.Rule{Method: "DeleteObject", Count: 1, ErrorCode: 503}))
+
+	err := b.DeleteObject(context.Background(), &gcs.DeleteObjectRequest{Name: "a"})
+
+	assert.Error(t, err)
+	assert.NoError(t, b.DeleteObject(context.Background(), &gcs.DeleteObjectRequest{Name: "a"}))
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"sync"
+
+	"github.com/jacobsa/timeutil"
+)
+
+// The number of seconds the retry budget accounts the requests and retries of.
+const budgetWindowSecs = 10
+
+// The requests and retries of a second of the window.
+type budgetSlot struct {
+	sec      int64
+	requests int64
+	retries  int64
+}
+
+// budget limits the retries of the last budgetWindowSecs seconds to a ratio of
+// the requests of the same seconds, plus a minimum number of retries per
+// second, so that an outage doesn't turn into a retry storm.
+type budget struct {
+	clock     timeutil.Clock
+	ratio     float64
+	minPerSec float64
+
+	mu sync.Mutex
+
+	// The slots of the seconds of the window, indexed by the Unix time of the
+	// second modulo budgetWindowSecs. A slot of an older second is stale.
+	slots [budgetWindowSecs]budgetSlot // GUARDED_BY(mu)
+}
+
+func newBudget(clock timeutil.Clock, ratio float64, minPerSec float64) *budget {
+	return &budget{
+		clock:     clock,
+		ratio:     ratio,
+		minPerSec: minPerSec,
+	}
+}
+
+// LOCKS_REQUIRED(b.mu)
+func (b *budget) currentSlot() *budgetSlot {
+	sec := b.clock.Now().Unix()
+	s := &b.slots[sec%budgetWindowSecs]
+	if s.sec != sec {
+		*s = budgetSlot{sec: sec}
+	}
+
+	return s
+}
+
+// Record a request, which earns the budget a fraction of a retry.
+//
+// LOCKS_EXCLUDED(b.mu)
+func (b *budget) request() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.currentSlot().requests++
+}
+
+// Withdraw a retry from the budget, returning false if it's exhausted.
+//
+// LOCKS_EXCLUDED(b.mu)
+func (b *budget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	current := b.currentSlot()
+	var requests, retries int64
+	for _, s := range b.slots {
+		if s.sec > current.sec-budgetWindowSecs {
+			requests += s.requests
+			retries += s.retries
+		}
+	}
+
+	if float64(retries+1) > b.ratio*float64(requests)+b.minPerSec*budgetWindowSecs {
+		return false
+	}
+
+	current.retries++
+	return true
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jacobsa/timeutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudgetRatio(t *testing.T) {
+	var clock timeutil.SimulatedClock
+	clock.SetTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := newBudget(&clock, 0.5, 0)
+	assert.False(t, b.withdraw())
+
+	for i := 0; i < 4; i++ {
+		b.request()
+	}
+	clock.AdvanceTime(5 * time.Second)
+
+	assert.True(t, b.withdraw())
+	assert.True(t, b.withdraw())
+	assert.False(t, b.withdraw())
+}
+
+func TestBudgetWindow(t *testing.T) {
+	var clock timeutil.SimulatedClock
+	clock.SetTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := newBudget(&clock, 1, 0)
+	b.request()
+
+	// The requests older than the window earn nothing.
+	clock.AdvanceTime(budgetWindowSecs * time.Second)
+
+	assert.False(t, b.withdraw())
+}
+
+func TestBudgetMinRetriesPerSec(t *testing.T) {
+	var clock timeutil.SimulatedClock
+	clock.SetTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := newBudget(&clock, 0, 1)
+
+	for i := 0; i < budgetWindowSecs; i++ {
+		assert.True(t, b.withdraw())
+	}
+	assert.False(t, b.withdraw())
+
+	// The retries older than the window are forgotten.
+	clock.AdvanceTime(budgetWindowSecs * time.Second)
+	assert.True(t, b.withdraw())
+}
@@ -0,0 +1,601 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package s3 implements gcs.Bucket on top of the S3 API, to mount buckets of
+// S3-compatible object stores like MinIO.
+//
+// S3 has no generations: the generation of an object is derived from its
+// modification time, which S3 keeps to the second, and its ETag. It changes
+// whenever the object is overwritten, and the metageneration is always 1.
+// Preconditions are checked with a HEAD before the request and, for writes,
+// also sent as If-Match for the servers supporting it. Writes requiring the
+// object not to exist are only sent with If-None-Match.
+package s3
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/storageutil"
+	"github.com/minio/minio-go/v7"
+	"golang.org/x/net/context"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// The size of the parts of multipart uploads. Objects smaller than this
+	// are uploaded with a single PUT.
+	partSize = 16 << 20
+
+	// All the parts of a multipart upload but the last one must be at least
+	// this large.
+	minPartSize = 5 << 20
+
+	// The low bits of a generation hash the ETag, to tell apart the objects
+	// written within a second.
+	generationHashBits = 20
+
+	metaGeneration = 1
+
+	// The number of directory placeholders stat'ed at once when listing.
+	maxConcurrentPlaceholderStats = 16
+
+	userMetadataPrefix = "X-Amz-Meta-"
+)
+
+type bucket struct {
+	client *minio.Client
+	name   string
+}
+
+// NewBucket returns the bucket with the given name, accessed with the given
+// client.
+func NewBucket(client *minio.Client, name string) gcs.Bucket {
+	return &bucket{client: client, name: name}
+}
+
+func (b *bucket) Name() string {
+	return b.name
+}
+
+// S3 has no directories, only prefixes.
+func (b *bucket) BucketType() gcs.BucketType {
+	return gcs.NonHierarchical
+}
+
+func (b *bucket) NewReader(ctx context.Context, req *gcs.ReadObjectRequest) (io.ReadCloser, error) {
+	var opts minio.GetObjectOptions
+	if req.Range != nil {
+		if req.Range.Limit <= req.Range.Start {
+			return io.NopCloser(strings.NewReader("")), nil
+		}
+		if err := opts.SetRange(int64(req.Range.Start), int64(req.Range.Limit)-1); err != nil {
+			return nil, err
+		}
+	}
+
+	rc, info, _, err := minio.Core{Client: b.client}.GetObject(ctx, b.name, req.Name, opts)
+	if err != nil {
+		// Like GCS, return nothing when reading past the end of the object.
+		if minio.ToErrorResponse(err).StatusCode == http.StatusRequestedRangeNotSatisfiable {
+			return io.NopCloser(strings.NewReader("")), nil
+		}
+		return nil, toError(err)
+	}
+
+	if req.Generation != 0 && generation(info) != req.Generation {
+		rc.Close()
+		return nil, &gcs.NotFoundError{Err: fmt.Errorf("object %s with generation %d not found", req.Name, req.Generation)}
+	}
+	return rc, nil
+}
+
+func (b *bucket) CreateObject(ctx context.Context, req *gcs.CreateObjectRequest) (*gcs.Object, error) {
+	opts := minio.PutObjectOptions{
+		UserMetadata:         req.Metadata,
+		ContentType:          req.ContentType,
+		ContentEncoding:      req.ContentEncoding,
+		ContentDisposition:   req.ContentDisposition,
+		ContentLanguage:      req.ContentLanguage,
+		CacheControl:         req.CacheControl,
+		PartSize:             partSize,
+		DisableContentSha256: true,
+	}
+	if p := req.GenerationPrecondition; p != nil && *p == 0 {
+		// Whatever the metageneration precondition, the object must not
+		// exist. minio-go quotes the *, as MinIO accepts it.
+		opts.SetMatchETagExcept("*")
+	} else {
+		etag, err := b.checkPreconditions(ctx, req.Name, req.GenerationPrecondition, req.MetaGenerationPrecondition)
+		if err != nil {
+			return nil, err
+		}
+		if etag != "" {
+			opts.SetMatchETag(etag)
+		}
+	}
+
+	return b.putObject(ctx, req.Name, req.Contents, opts)
+}
+
+// putObject uploads contents of unknown size, and returns the written object.
+// Small objects, the most common, are buffered to be uploaded with a single
+// PUT rather than a multipart upload.
+func (b *bucket) putObject(ctx context.Context, name string, contents io.Reader, opts minio.PutObjectOptions) (*gcs.Object, error) {
+	started := time.Now()
+	var upload minio.UploadInfo
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, contents, partSize)
+	if err == io.EOF {
+		upload, err = minio.Core{Client: b.client}.PutObject(ctx, b.name, name, &buf, n, "", "", opts)
+	} else if err == nil {
+		upload, err = b.client.PutObject(ctx, b.name, name, io.MultiReader(&buf, contents), -1, opts)
+	}
+	if err != nil {
+		return nil, toError(err)
+	}
+	return b.writtenObject(ctx, name, upload, opts, started)
+}
+
+// writtenObject returns the object written by a PUT started at the given
+// time. It is built from the request and the response rather than stat'ed,
+// as the object may have been overwritten since. S3 doesn't return the
+// modification time the generation is derived from, so it is read with a HEAD
+// matching the ETag of the written object, or else, if the object was
+// overwritten meanwhile, taken as the time the PUT started.
+func (b *bucket) writtenObject(ctx context.Context, name string, upload minio.UploadInfo, opts minio.PutObjectOptions, started time.Time) (*gcs.Object, error) {
+	header := opts.Header()
+	info := minio.ObjectInfo{
+		Key:          name,
+		ETag:         upload.ETag,
+		Size:         upload.Size,
+		LastModified: started.UTC(),
+		ContentType:  header.Get("Content-Type"),
+		Metadata:     header,
+	}
+
+	var statOpts minio.StatObjectOptions
+	if err := statOpts.SetMatchETag(upload.ETag); err != nil {
+		return nil, err
+	}
+	stat, err := b.client.StatObject(ctx, b.name, name, statOpts)
+	var notFoundErr *gcs.NotFoundError
+	var preconditionErr *gcs.PreconditionError
+	if err = toError(err); err == nil {
+		info.LastModified = stat.LastModified
+	} else if !errors.As(err, &notFoundErr) && !errors.As(err, &preconditionErr) {
+		return nil, err
+	}
+	return toObject(info), nil
+}
+
+func (b *bucket) CopyObject(ctx context.Context, req *gcs.CopyObjectRequest) (*gcs.Object, error) {
+	src := minio.CopySrcOptions{Bucket: b.name, Object: req.SrcName}
+	if req.SrcGeneration != 0 || req.SrcMetaGenerationPrecondition != nil {
+		info, err := b.client.StatObject(ctx, b.name, req.SrcName, minio.StatObjectOptions{})
+		if err != nil {
+			return nil, toError(err)
+		}
+		if req.SrcGeneration != 0 && generation(info) != req.SrcGeneration {
+			return nil, &gcs.NotFoundError{Err: fmt.Errorf("object %s with generation %d not found", req.SrcName, req.SrcGeneration)}
+		}
+		if p := req.SrcMetaGenerationPrecondition; p != nil && *p != metaGeneration {
+			return nil, &gcs.PreconditionError{Err: fmt.Errorf("object %s has metageneration %d, not %d", req.SrcName, metaGeneration, *p)}
+		}
+		src.MatchETag = info.ETag
+	}
+
+	if _, err := b.checkPreconditions(ctx, req.DstName, req.DstGenerationPrecondition, nil); err != nil {
+		return nil, err
+	}
+
+	if _, err := b.client.CopyObject(ctx, minio.CopyDestOptions{Bucket: b.name, Object: req.DstName}, src); err != nil {
+		return nil, toError(err)
+	}
+	return b.statObject(ctx, req.DstName)
+}
+
+// ComposeObjects copies the sources as the parts of a multipart upload when
+// they are large enough, e.g. when appending to a large object. Otherwise, the
+// sources are read and uploaded again.
+func (b *bucket) ComposeObjects(ctx context.Context, req *gcs.ComposeObjectsRequest) (*gcs.Object, error) {
+	if len(req.Sources) == 0 {
+		return nil, errors.New("compose: no sources")
+	}
+
+	etag, err := b.checkPreconditions(ctx, req.DstName, req.DstGenerationPrecondition, req.DstMetaGenerationPrecondition)
+	if err != nil {
+		return nil, err
+	}
+
+	srcs := make([]minio.CopySrcOptions, len(req.Sources))
+	copyParts := true
+	for i, source := range req.Sources {
+		info, err := b.client.StatObject(ctx, b.name, source.Name, minio.StatObjectOptions{})
+		if err != nil {
+			return nil, toError(err)
+		}
+		if source.Generation != 0 && generation(info) != source.Generation {
+			return nil, &gcs.NotFoundError{Err: fmt.Errorf("object %s with generation %d not found", source.Name, source.Generation)}
+		}
+		if info.Size < minPartSize && i < len(req.Sources)-1 {
+			copyParts = false
+		}
+		srcs[i] = minio.CopySrcOptions{Bucket: b.name, Object: source.Name, MatchETag: info.ETag}
+	}
+
+	if copyParts {
+		dst := minio.CopyDestOptions{
+			Bucket:          b.name,
+			Object:          req.DstName,
+			UserMetadata:    make(map[string]string),
+			ReplaceMetadata: true,
+		}
+		for k, v := range req.Metadata {
+			dst.UserMetadata[k] = v
+		}
+		if req.ContentType != "" {
+			dst.UserMetadata["Content-Type"] = req.ContentType
+		}
+		if _, err = b.client.ComposeObject(ctx, dst, srcs...); err != nil {
+			return nil, toError(err)
+		}
+		return b.statObject(ctx, req.DstName)
+	}
+
+	opts := minio.PutObjectOptions{
+		UserMetadata:         req.Metadata,
+		ContentType:          req.ContentType,
+		PartSize:             partSize,
+		DisableContentSha256: true,
+	}
+	if etag != "" {
+		opts.SetMatchETag(etag)
+	}
+	r := &concatReader{ctx: ctx, client: b.client, bucket: b.name, srcs: srcs}
+	defer r.Close()
+	return b.putObject(ctx, req.DstName, r, opts)
+}
+
+func (b *bucket) StatObject(ctx context.Context, req *gcs.StatObjectRequest) (*gcs.MinObject, *gcs.ExtendedObjectAttributes, error) {
+	info, err := b.client.StatObject(ctx, b.name, req.Name, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, nil, toError(err)
+	}
+
+	o := toObject(info)
+	var extendedAttrs *gcs.ExtendedObjectAttributes
+	if req.ReturnExtendedObjectAttributes {
+		extendedAttrs = storageutil.ConvertObjToExtendedObjectAttributes(o)
+	}
+	return storageutil.ConvertObjToMinObject(o), extendedAttrs, nil
+}
+
+func (b *bucket) ListObjects(ctx context.Context, req *gcs.ListObjectsRequest) (*gcs.Listing, error) {
+	maxKeys := req.MaxResults
+	if maxKeys == 0 {
+		maxKeys = 1000
+	}
+
+	result, err := b.listObjectsV2(ctx, req.Prefix, req.ContinuationToken, req.Delimiter, maxKeys)
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	listing := &gcs.Listing{}
+	for _, info := range result.Contents {
+		listing.Objects = append(listing.Objects, toObject(info))
+	}
+	for _, prefix := range result.CommonPrefixes {
+		listing.CollapsedRuns = append(listing.CollapsedRuns, prefix.Prefix)
+	}
+	if result.IsTruncated {
+		listing.ContinuationToken = result.NextContinuationToken
+	}
+
+	if req.IncludeTrailingDelimiter && req.Delimiter != "" && len(listing.CollapsedRuns) > 0 {
+		placeholders, err := b.statPlaceholders(ctx, listing.CollapsedRuns)
+		if err != nil {
+			return nil, err
+		}
+		listing.Objects = append(listing.Objects, placeholders...)
+		sort.Slice(listing.Objects, func(i, j int) bool {
+			return listing.Objects[i].Name < listing.Objects[j].Name
+		})
+	}
+	return listing, nil
+}
+
+// listObjectsV2 lists a page of objects. minio.Core.ListObjectsV2 takes no
+// context, so the request is left to finish in the background when the
+// context is done first.
+func (b *bucket) listObjectsV2(ctx context.Context, prefix, continuationToken, delimiter string, maxKeys int) (minio.ListBucketV2Result, error) {
+	type result struct {
+		listing minio.ListBucketV2Result
+		err     error
+	}
+	results := make(chan result, 1)
+	go func() {
+		listing, err := minio.Core{Client: b.client}.ListObjectsV2(b.name, prefix, "", continuationToken, delimiter, maxKeys)
+		results <- result{listing, err}
+	}()
+
+	select {
+	case r := <-results:
+		return r.listing, r.err
+	case <-ctx.Done():
+		return minio.ListBucketV2Result{}, ctx.Err()
+	}
+}
+
+// statPlaceholders returns the objects named like the prefixes, i.e. the
+// directory placeholders, which S3 doesn't list along with the prefixes. As a
+// page may have many prefixes, they are stat'ed concurrently.
+func (b *bucket) statPlaceholders(ctx context.Context, prefixes []string) ([]*gcs.Object, error) {
+	found := make([]*gcs.Object, len(prefixes))
+	group, ctx := errgroup.WithContext(ctx)
+	group.SetLimit(maxConcurrentPlaceholderStats)
+	for i, prefix := range prefixes {
+		i, prefix := i, prefix
+		group.Go(func() error {
+			info, err := b.client.StatObject(ctx, b.name, prefix, minio.StatObjectOptions{})
+			var notFoundErr *gcs.NotFoundError
+			if err = toError(err); errors.As(err, &notFoundErr) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			found[i] = toObject(info)
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	var placeholders []*gcs.Object
+	for _, o := range found {
+		if o != nil {
+			placeholders = append(placeholders, o)
+		}
+	}
+	return placeholders, nil
+}
+
+// UpdateObject copies the object onto itself with the new metadata, which
+// changes its generation.
+func (b *bucket) UpdateObject(ctx context.Context, req *gcs.UpdateObjectRequest) (*gcs.Object, error) {
+	info, err := b.client.StatObject(ctx, b.name, req.Name, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, toError(err)
+	}
+	if req.Generation != 0 && generation(info) != req.Generation {
+		return nil, &gcs.NotFoundError{Err: fmt.Errorf("object %s with generation %d not found", req.Name, req.Generation)}
+	}
+	if p := req.MetaGenerationPrecondition; p != nil && *p != metaGeneration {
+		return nil, &gcs.PreconditionError{Err: fmt.Errorf("object %s has metageneration %d, not %d", req.Name, metaGeneration, *p)}
+	}
+
+	o := toObject(info)
+	metadata := make(map[string]string)
+	for k, v := range o.Metadata {
+		metadata[userMetadataPrefix+k] = v
+	}
+	for k, v := range req.Metadata {
+		delete(metadata, userMetadataPrefix+k)
+		if v != nil {
+			metadata[userMetadataPrefix+k] = *v
+		}
+	}
+	for header, value := range map[string]*string{
+		"Content-Type":     req.ContentType,
+		"Content-Encoding": req.ContentEncoding,
+		"Content-Language": req.ContentLanguage,
+		"Cache-Control":    req.CacheControl,
+	} {
+		if value == nil {
+			value = stringOrNil(info.Metadata.Get(header))
+		}
+		if value != nil && *value != "" {
+			metadata[header] = *value
+		}
+	}
+	metadata["X-Amz-Metadata-Directive"] = "REPLACE"
+
+	src := minio.CopySrcOptions{Bucket: b.name, Object: req.Name, MatchETag: info.ETag}
+	_, err = minio.Core{Client: b.client}.CopyObject(ctx, b.name, req.Name, b.name, req.Name, metadata, src, minio.PutObjectOptions{})
+	if err != nil {
+		return nil, toError(err)
+	}
+	return b.statObject(ctx, req.Name)
+}
+
+func (b *bucket) DeleteObject(ctx context.Context, req *gcs.DeleteObjectRequest) error {
+	if req.Generation != 0 || req.MetaGenerationPrecondition != nil {
+		info, err := b.client.StatObject(ctx, b.name, req.Name, minio.StatObjectOptions{})
+		if err != nil {
+			return toError(err)
+		}
+		if req.Generation != 0 && generation(info) != req.Generation {
+			return &gcs.NotFoundError{Err: fmt.Errorf("object %s with generation %d not found", req.Name, req.Generation)}
+		}
+		if p := req.MetaGenerationPrecondition; p != nil && *p != metaGeneration {
+			return &gcs.PreconditionError{Err: fmt.Errorf("object %s has metageneration %d, not %d", req.Name, metaGeneration, *p)}
+		}
+	}
+
+	return toError(b.client.RemoveObject(ctx, b.name, req.Name, minio.RemoveObjectOptions{}))
+}
+
+// checkPreconditions checks the preconditions on the object to be written,
+// and returns its ETag, if it exists and must not change before the write.
+func (b *bucket) checkPreconditions(ctx context.Context, name string, generationPrecondition, metaGenerationPrecondition *int64) (etag string, err error) {
+	if generationPrecondition == nil && metaGenerationPrecondition == nil {
+		return "", nil
+	}
+
+	info, err := b.client.StatObject(ctx, b.name, name, minio.StatObjectOptions{})
+	var notFoundErr *gcs.NotFoundError
+	if err = toError(err); errors.As(err, &notFoundErr) {
+		if generationPrecondition != nil && *generationPrecondition == 0 {
+			return "", nil
+		}
+		return "", &gcs.PreconditionError{Err: err}
+	} else if err != nil {
+		return "", err
+	}
+
+	if p := generationPrecondition; p != nil && *p != generation(info) {
+		return "", &gcs.PreconditionError{Err: fmt.Errorf("object %s has generation %d, not %d", name, generation(info), *p)}
+	}
+	if p := metaGenerationPrecondition; p != nil && *p != metaGeneration {
+		return "", &gcs.PreconditionError{Err: fmt.Errorf("object %s has metageneration %d, not %d", name, metaGeneration, *p)}
+	}
+	return info.ETag, nil
+}
+
+func (b *bucket) statObject(ctx context.Context, name string) (*gcs.Object, error) {
+	info, err := b.client.StatObject(ctx, b.name, name, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, toError(err)
+	}
+	return toObject(info), nil
+}
+
+// concatReader reads the given objects one after the other.
+type concatReader struct {
+	ctx    context.Context
+	client *minio.Client
+	bucket string
+	srcs   []minio.CopySrcOptions
+	rc     io.ReadCloser
+}
+
+func (r *concatReader) Read(p []byte) (n int, err error) {
+	for {
+		if r.rc == nil {
+			if len(r.srcs) == 0 {
+				return 0, io.EOF
+			}
+			var opts minio.GetObjectOptions
+			if err = opts.SetMatchETag(r.srcs[0].MatchETag); err != nil {
+				return
+			}
+			r.rc, _, _, err = minio.Core{Client: r.client}.GetObject(r.ctx, r.bucket, r.srcs[0].Object, opts)
+			if err != nil {
+				return 0, toError(err)
+			}
+			r.srcs = r.srcs[1:]
+		}
+
+		n, err = r.rc.Read(p)
+		if err == io.EOF {
+			r.rc.Close()
+			r.rc = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return
+	}
+}
+
+func (r *concatReader) Close() error {
+	if r.rc != nil {
+		return r.rc.Close()
+	}
+	return nil
+}
+
+// unquotedETag returns the ETag of an object, unquoted as HEAD returns it but
+// not listings.
+func unquotedETag(info minio.ObjectInfo) string {
+	return strings.Trim(info.ETag, `"`)
+}
+
+// generation derives a generation from the modification time and the ETag of
+// an object. Listings return the time to the millisecond, HEAD to the second.
+func generation(info minio.ObjectInfo) int64 {
+	h := fnv.New32a()
+	h.Write([]byte(unquotedETag(info)))
+	return info.LastModified.Unix()<<generationHashBits | int64(h.Sum32()&(1<<generationHashBits-1))
+}
+
+func toObject(info minio.ObjectInfo) *gcs.Object {
+	o := &gcs.Object{
+		Name:               info.Key,
+		ContentType:        info.ContentType,
+		ContentLanguage:    info.Metadata.Get("Content-Language"),
+		CacheControl:       info.Metadata.Get("Cache-Control"),
+		Size:               uint64(info.Size),
+		ContentEncoding:    info.Metadata.Get("Content-Encoding"),
+		ContentDisposition: info.Metadata.Get("Content-Disposition"),
+		Generation:         generation(info),
+		MetaGeneration:     metaGeneration,
+		StorageClass:       info.StorageClass,
+		Updated:            info.LastModified.Truncate(time.Second),
+		ComponentCount:     1,
+	}
+
+	// The ETag of objects uploaded with a single PUT is their MD5.
+	if md5, err := hex.DecodeString(unquotedETag(info)); err == nil && len(md5) == 16 {
+		o.MD5 = new([16]byte)
+		copy(o.MD5[:], md5)
+	}
+
+	// S3 lowercases the keys of user metadata, which are canonicalized as
+	// headers.
+	for k, v := range info.Metadata {
+		if strings.HasPrefix(k, userMetadataPrefix) && len(v) > 0 {
+			if o.Metadata == nil {
+				o.Metadata = make(map[string]string)
+			}
+			o.Metadata[strings.ToLower(strings.TrimPrefix(k, userMetadataPrefix))] = v[0]
+		}
+	}
+	return o
+}
+
+func stringOrNil(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// toError converts the S3 errors to the gcs ones.
+func toError(err error) error {
+	var resp minio.ErrorResponse
+	if err == nil || !errors.As(err, &resp) {
+		return err
+	}
+
+	switch {
+	case resp.Code == "NoSuchKey" || resp.StatusCode == http.StatusNotFound:
+		return &gcs.NotFoundError{Err: err}
+	case resp.Code == "PreconditionFailed" || resp.StatusCode == http.StatusPreconditionFailed:
+		return &gcs.PreconditionError{Err: err}
+	}
+	return err
+}
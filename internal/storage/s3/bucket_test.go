@@ -0,0 +1,494 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/s3/fakes3"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/context"
+)
+
+const testBucketName = "bucket"
+
+type BucketTest struct {
+	suite.Suite
+	server     *fakes3.Server
+	httpServer *httptest.Server
+	bucket     gcs.Bucket
+	ctx        context.Context
+
+	// beforeRequest, if set, is called before the server serves a request.
+	beforeRequest func(r *http.Request)
+}
+
+func TestBucketSuite(t *testing.T) {
+	suite.Run(t, new(BucketTest))
+}
+
+func (t *BucketTest) SetupTest() {
+	t.server = fakes3.NewServer()
+	t.server.CreateBucket(testBucketName)
+	t.beforeRequest = nil
+	t.httpServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if t.beforeRequest != nil {
+			t.beforeRequest(r)
+		}
+		t.server.ServeHTTP(w, r)
+	}))
+	u, err := url.Parse(t.httpServer.URL)
+	require.NoError(t.T(), err)
+
+	client, err := minio.New(u.Host, &minio.Options{
+		Creds:        credentials.NewStaticV4("", "", ""),
+		Region:       "us-east-1",
+		BucketLookup: minio.BucketLookupPath,
+	})
+	require.NoError(t.T(), err)
+	t.bucket = NewBucket(client, testBucketName)
+	t.ctx = context.Background()
+}
+
+func (t *BucketTest) TearDownTest() {
+	t.httpServer.Close()
+}
+
+func (t *BucketTest) create(name, contents string) *gcs.Object {
+	o, err := t.bucket.CreateObject(t.ctx, &gcs.CreateObjectRequest{Name: name, Contents: strings.NewReader(contents)})
+	require.NoError(t.T(), err)
+	return o
+}
+
+func (t *BucketTest) read(req *gcs.ReadObjectRequest) (string, error) {
+	rc, err := t.bucket.NewReader(t.ctx, req)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	return string(b), err
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+	return b
+}
+
+func (t *BucketTest) TestNameAndBucketType() {
+	assert.Equal(t.T(), testBucketName, t.bucket.Name())
+	assert.Equal(t.T(), gcs.NonHierarchical, t.bucket.BucketType())
+}
+
+func (t *BucketTest) TestCreateAndStatObject() {
+	o, err := t.bucket.CreateObject(t.ctx, &gcs.CreateObjectRequest{
+		Name:            "a/b.txt",
+		ContentType:     "text/plain",
+		ContentEncoding: "gzip",
+		Metadata:        map[string]string{"gcsfuse_mtime": "2024-01-02T03:04:05Z"},
+		Contents:        strings.NewReader("taco"),
+	})
+
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), "a/b.txt", o.Name)
+	assert.Equal(t.T(), uint64(4), o.Size)
+	assert.Equal(t.T(), "text/plain", o.ContentType)
+	assert.Equal(t.T(), "gzip", o.ContentEncoding)
+	assert.Equal(t.T(), map[string]string{"gcsfuse_mtime": "2024-01-02T03:04:05Z"}, o.Metadata)
+	assert.NotZero(t.T(), o.Generation)
+	assert.Equal(t.T(), int64(1), o.MetaGeneration)
+	require.NotNil(t.T(), o.MD5)
+
+	m, attrs, err := t.bucket.StatObject(t.ctx, &gcs.StatObjectRequest{Name: "a/b.txt", ReturnExtendedObjectAttributes: true})
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), o.Generation, m.Generation)
+	assert.Equal(t.T(), o.Size, m.Size)
+	assert.Equal(t.T(), o.Updated, m.Updated)
+	assert.Equal(t.T(), o.Metadata, m.Metadata)
+	assert.Equal(t.T(), "gzip", m.ContentEncoding)
+	require.NotNil(t.T(), attrs)
+	assert.Equal(t.T(), "text/plain", attrs.ContentType)
+	assert.Equal(t.T(), o.MD5, attrs.MD5)
+}
+
+func (t *BucketTest) TestStatMissingObject() {
+	_, _, err := t.bucket.StatObject(t.ctx, &gcs.StatObjectRequest{Name: "missing"})
+
+	var notFoundErr *gcs.NotFoundError
+	assert.True(t.T(), errors.As(err, &notFoundErr))
+}
+
+func (t *BucketTest) TestCreateLargeObject() {
+	contents := randomBytes(t.T(), partSize+1)
+
+	o, err := t.bucket.CreateObject(t.ctx, &gcs.CreateObjectRequest{Name: "large", Contents: bytes.NewReader(contents)})
+
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), uint64(len(contents)), o.Size)
+	assert.Nil(t.T(), o.MD5)
+	read, err := t.read(&gcs.ReadObjectRequest{Name: "large"})
+	require.NoError(t.T(), err)
+	assert.True(t.T(), bytes.Equal(contents, []byte(read)))
+	assert.Equal(t.T(), 0, t.server.Uploads())
+}
+
+func (t *BucketTest) TestCreateWithGenerationPreconditionZero() {
+	var zero int64 = 0
+	t.create("foo", "taco")
+
+	_, err := t.bucket.CreateObject(t.ctx, &gcs.CreateObjectRequest{
+		Name:                   "foo",
+		Contents:               strings.NewReader("burrito"),
+		GenerationPrecondition: &zero,
+	})
+
+	var preconditionErr *gcs.PreconditionError
+	assert.True(t.T(), errors.As(err, &preconditionErr))
+	_, err = t.bucket.CreateObject(t.ctx, &gcs.CreateObjectRequest{
+		Name:                   "bar",
+		Contents:               strings.NewReader("burrito"),
+		GenerationPrecondition: &zero,
+	})
+	assert.NoError(t.T(), err)
+}
+
+func (t *BucketTest) TestCreateWithGenerationPreconditionZeroChecksNothingBefore() {
+	var zero int64 = 0
+	var methods []string
+	t.beforeRequest = func(r *http.Request) { methods = append(methods, r.Method) }
+
+	_, err := t.bucket.CreateObject(t.ctx, &gcs.CreateObjectRequest{
+		Name:                   "foo",
+		Contents:               strings.NewReader("taco"),
+		GenerationPrecondition: &zero,
+	})
+
+	require.NoError(t.T(), err)
+	require.NotEmpty(t.T(), methods)
+	assert.Equal(t.T(), http.MethodPut, methods[0])
+}
+
+func (t *BucketTest) TestCreateReturnsTheWrittenObjectWhenOverwritten() {
+	t.beforeRequest = func(r *http.Request) {
+		if r.Method == http.MethodHead {
+			t.beforeRequest = nil
+			t.server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/"+testBucketName+"/foo", strings.NewReader("burrito")))
+		}
+	}
+
+	o := t.create("foo", "taco")
+
+	assert.Equal(t.T(), uint64(4), o.Size)
+	m, _, err := t.bucket.StatObject(t.ctx, &gcs.StatObjectRequest{Name: "foo"})
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), uint64(7), m.Size)
+	assert.NotEqual(t.T(), o.Generation, m.Generation)
+}
+
+func (t *BucketTest) TestCreateWithGenerationPrecondition() {
+	o := t.create("foo", "taco")
+	wrongGeneration := o.Generation + 1
+
+	_, err := t.bucket.CreateObject(t.ctx, &gcs.CreateObjectRequest{
+		Name:                   "foo",
+		Contents:               strings.NewReader("burrito"),
+		GenerationPrecondition: &wrongGeneration,
+	})
+	var preconditionErr *gcs.PreconditionError
+	assert.True(t.T(), errors.As(err, &preconditionErr))
+
+	newObject, err := t.bucket.CreateObject(t.ctx, &gcs.CreateObjectRequest{
+		Name:                   "foo",
+		Contents:               strings.NewReader("burrito"),
+		GenerationPrecondition: &o.Generation,
+	})
+	require.NoError(t.T(), err)
+	assert.NotEqual(t.T(), o.Generation, newObject.Generation)
+}
+
+func (t *BucketTest) TestReadRange() {
+	t.create("foo", "0123456789")
+
+	contents, err := t.read(&gcs.ReadObjectRequest{Name: "foo", Range: &gcs.ByteRange{Start: 2, Limit: 5}})
+
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), "234", contents)
+}
+
+func (t *BucketTest) TestReadRangePastTheEnd() {
+	t.create("foo", "0123456789")
+
+	contents, err := t.read(&gcs.ReadObjectRequest{Name: "foo", Range: &gcs.ByteRange{Start: 8, Limit: 100}})
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), "89", contents)
+
+	contents, err = t.read(&gcs.ReadObjectRequest{Name: "foo", Range: &gcs.ByteRange{Start: 10, Limit: 100}})
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), "", contents)
+}
+
+func (t *BucketTest) TestReadGeneration() {
+	o := t.create("foo", "taco")
+
+	contents, err := t.read(&gcs.ReadObjectRequest{Name: "foo", Generation: o.Generation})
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), "taco", contents)
+
+	_, err = t.read(&gcs.ReadObjectRequest{Name: "foo", Generation: o.Generation + 1})
+	var notFoundErr *gcs.NotFoundError
+	assert.True(t.T(), errors.As(err, &notFoundErr))
+}
+
+func (t *BucketTest) TestReadMissingObject() {
+	_, err := t.read(&gcs.ReadObjectRequest{Name: "missing"})
+
+	var notFoundErr *gcs.NotFoundError
+	assert.True(t.T(), errors.As(err, &notFoundErr))
+}
+
+func (t *BucketTest) TestCopyObject() {
+	src, err := t.bucket.CreateObject(t.ctx, &gcs.CreateObjectRequest{
+		Name:        "src",
+		ContentType: "text/plain",
+		Metadata:    map[string]string{"foo": "bar"},
+		Contents:    strings.NewReader("taco"),
+	})
+	require.NoError(t.T(), err)
+
+	dst, err := t.bucket.CopyObject(t.ctx, &gcs.CopyObjectRequest{SrcName: "src", DstName: "dst", SrcGeneration: src.Generation})
+
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), "dst", dst.Name)
+	assert.Equal(t.T(), "text/plain", dst.ContentType)
+	assert.Equal(t.T(), map[string]string{"foo": "bar"}, dst.Metadata)
+	contents, err := t.read(&gcs.ReadObjectRequest{Name: "dst"})
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), "taco", contents)
+}
+
+func (t *BucketTest) TestCopyObjectWrongGeneration() {
+	src := t.create("src", "taco")
+
+	_, err := t.bucket.CopyObject(t.ctx, &gcs.CopyObjectRequest{SrcName: "src", DstName: "dst", SrcGeneration: src.Generation + 1})
+
+	var notFoundErr *gcs.NotFoundError
+	assert.True(t.T(), errors.As(err, &notFoundErr))
+}
+
+func (t *BucketTest) TestComposeSmallObjects() {
+	dst := t.create("dst", "taco")
+	t.create("tmp", "burrito")
+
+	o, err := t.bucket.ComposeObjects(t.ctx, &gcs.ComposeObjectsRequest{
+		DstName:                   "dst",
+		DstGenerationPrecondition: &dst.Generation,
+		Sources:                   []gcs.ComposeSource{{Name: "dst", Generation: dst.Generation}, {Name: "tmp"}},
+		Metadata:                  map[string]string{"foo": "bar"},
+	})
+
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), uint64(11), o.Size)
+	assert.Equal(t.T(), map[string]string{"foo": "bar"}, o.Metadata)
+	contents, err := t.read(&gcs.ReadObjectRequest{Name: "dst"})
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), "tacoburrito", contents)
+}
+
+// Appending to an object larger than a part copies it on the server.
+func (t *BucketTest) TestComposeLargeObjects() {
+	large := randomBytes(t.T(), fakes3.MinPartSize)
+	dst, err := t.bucket.CreateObject(t.ctx, &gcs.CreateObjectRequest{Name: "dst", Contents: bytes.NewReader(large)})
+	require.NoError(t.T(), err)
+	t.create("tmp", "burrito")
+
+	o, err := t.bucket.ComposeObjects(t.ctx, &gcs.ComposeObjectsRequest{
+		DstName:                   "dst",
+		DstGenerationPrecondition: &dst.Generation,
+		Sources:                   []gcs.ComposeSource{{Name: "dst", Generation: dst.Generation}, {Name: "tmp"}},
+	})
+
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), uint64(len(large)+7), o.Size)
+	assert.NotEqual(t.T(), dst.Generation, o.Generation)
+	contents, err := t.read(&gcs.ReadObjectRequest{Name: "dst"})
+	require.NoError(t.T(), err)
+	assert.True(t.T(), bytes.Equal(append(large, "burrito"...), []byte(contents)))
+}
+
+func (t *BucketTest) TestComposeWithFailedPrecondition() {
+	dst := t.create("dst", "taco")
+	t.create("tmp", "burrito")
+	wrongGeneration := dst.Generation + 1
+
+	_, err := t.bucket.ComposeObjects(t.ctx, &gcs.ComposeObjectsRequest{
+		DstName:                   "dst",
+		DstGenerationPrecondition: &wrongGeneration,
+		Sources:                   []gcs.ComposeSource{{Name: "dst"}, {Name: "tmp"}},
+	})
+
+	var preconditionErr *gcs.PreconditionError
+	assert.True(t.T(), errors.As(err, &preconditionErr))
+	contents, err := t.read(&gcs.ReadObjectRequest{Name: "dst"})
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), "taco", contents)
+}
+
+func (t *BucketTest) TestListObjectsWithDelimiter() {
+	for _, name := range []string{"a", "b/", "b/c", "d/e", "f"} {
+		t.create(name, "")
+	}
+
+	listing, err := t.bucket.ListObjects(t.ctx, &gcs.ListObjectsRequest{Delimiter: "/"})
+
+	require.NoError(t.T(), err)
+	var names []string
+	for _, o := range listing.Objects {
+		names = append(names, o.Name)
+	}
+	assert.Equal(t.T(), []string{"a", "f"}, names)
+	assert.Equal(t.T(), []string{"b/", "d/"}, listing.CollapsedRuns)
+	assert.Equal(t.T(), "", listing.ContinuationToken)
+}
+
+func (t *BucketTest) TestListObjectsIncludeTrailingDelimiter() {
+	for _, name := range []string{"a", "b/", "b/c", "d/e"} {
+		t.create(name, "")
+	}
+
+	listing, err := t.bucket.ListObjects(t.ctx, &gcs.ListObjectsRequest{Delimiter: "/", IncludeTrailingDelimiter: true})
+
+	require.NoError(t.T(), err)
+	var names []string
+	for _, o := range listing.Objects {
+		names = append(names, o.Name)
+	}
+	assert.Equal(t.T(), []string{"a", "b/"}, names)
+	assert.Equal(t.T(), []string{"b/", "d/"}, listing.CollapsedRuns)
+}
+
+func (t *BucketTest) TestListObjectsPages() {
+	for _, name := range []string{"a", "b/c", "b/d", "e", "f/g"} {
+		t.create(name, "")
+	}
+
+	var names, runs []string
+	req := &gcs.ListObjectsRequest{Delimiter: "/", MaxResults: 2}
+	for pages := 1; ; pages++ {
+		listing, err := t.bucket.ListObjects(t.ctx, req)
+		require.NoError(t.T(), err)
+		for _, o := range listing.Objects {
+			names = append(names, o.Name)
+		}
+		runs = append(runs, listing.CollapsedRuns...)
+		if listing.ContinuationToken == "" {
+			assert.Equal(t.T(), 2, pages)
+			break
+		}
+		req.ContinuationToken = listing.ContinuationToken
+	}
+
+	assert.Equal(t.T(), []string{"a", "e"}, names)
+	assert.Equal(t.T(), []string{"b/", "f/"}, runs)
+}
+
+func (t *BucketTest) TestListObjectsIsCancellable() {
+	t.create("foo", "taco")
+	unblock := make(chan struct{})
+	defer close(unblock)
+	t.beforeRequest = func(r *http.Request) {
+		if r.URL.Query().Get("list-type") == "2" {
+			<-unblock
+		}
+	}
+	ctx, cancel := context.WithCancel(t.ctx)
+	cancel()
+
+	_, err := t.bucket.ListObjects(ctx, &gcs.ListObjectsRequest{})
+
+	assert.ErrorIs(t.T(), err, context.Canceled)
+}
+
+func (t *BucketTest) TestListObjectsGenerationMatchesStat() {
+	o := t.create("foo", "taco")
+
+	listing, err := t.bucket.ListObjects(t.ctx, &gcs.ListObjectsRequest{})
+
+	require.NoError(t.T(), err)
+	require.Len(t.T(), listing.Objects, 1)
+	assert.Equal(t.T(), o.Generation, listing.Objects[0].Generation)
+	assert.Equal(t.T(), o.Updated, listing.Objects[0].Updated)
+}
+
+func (t *BucketTest) TestUpdateObject() {
+	o, err := t.bucket.CreateObject(t.ctx, &gcs.CreateObjectRequest{
+		Name:        "foo",
+		ContentType: "text/plain",
+		Metadata:    map[string]string{"keep": "1", "remove": "2"},
+		Contents:    strings.NewReader("taco"),
+	})
+	require.NoError(t.T(), err)
+	value := "3"
+
+	updated, err := t.bucket.UpdateObject(t.ctx, &gcs.UpdateObjectRequest{
+		Name:       "foo",
+		Generation: o.Generation,
+		Metadata:   map[string]*string{"remove": nil, "add": &value},
+	})
+
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), map[string]string{"keep": "1", "add": "3"}, updated.Metadata)
+	assert.Equal(t.T(), "text/plain", updated.ContentType)
+	contents, err := t.read(&gcs.ReadObjectRequest{Name: "foo"})
+	require.NoError(t.T(), err)
+	assert.Equal(t.T(), "taco", contents)
+}
+
+func (t *BucketTest) TestUpdateMissingObject() {
+	_, err := t.bucket.UpdateObject(t.ctx, &gcs.UpdateObjectRequest{Name: "missing"})
+
+	var notFoundErr *gcs.NotFoundError
+	assert.True(t.T(), errors.As(err, &notFoundErr))
+}
+
+func (t *BucketTest) TestDeleteObject() {
+	o := t.create("foo", "taco")
+
+	err := t.bucket.DeleteObject(t.ctx, &gcs.DeleteObjectRequest{Name: "foo", Generation: o.Generation + 1})
+	var notFoundErr *gcs.NotFoundError
+	assert.True(t.T(), errors.As(err, &notFoundErr))
+
+	err = t.bucket.DeleteObject(t.ctx, &gcs.DeleteObjectRequest{Name: "foo", Generation: o.Generation})
+	require.NoError(t.T(), err)
+	_, _, err = t.bucket.StatObject(t.ctx, &gcs.StatObjectRequest{Name: "foo"})
+	assert.True(t.T(), errors.As(err, &notFoundErr))
+
+	// Deleting a missing object is not an error.
+	assert.NoError(t.T(), t.bucket.DeleteObject(t.ctx, &gcs.DeleteObjectRequest{Name: "foo"}))
+}
@@ -0,0 +1,608 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fakes3 is an in-memory S3 server, serving the subset of the S3 API
+// used by the s3 package, to test it without an object store. Requests are
+// not authenticated, and buckets are addressed by path, e.g.
+// http://127.0.0.1:9000/bucket/object.
+package fakes3
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MinPartSize is the minimum size of all the parts of a multipart upload but
+// the last one, like in S3.
+const MinPartSize = 5 << 20
+
+const region = "us-east-1"
+
+// The headers stored with objects, other than the user metadata.
+var objectHeaders = []string{"Content-Type", "Content-Encoding", "Content-Language", "Content-Disposition", "Cache-Control"}
+
+type object struct {
+	data    []byte
+	etag    string
+	modTime time.Time
+	header  http.Header
+}
+
+type upload struct {
+	bucket, key string
+	header      http.Header
+	parts       map[int][]byte
+}
+
+// Server is an http.Handler serving buckets from memory.
+type Server struct {
+	mu sync.Mutex
+
+	// GUARDED_BY(mu)
+	buckets map[string]map[string]*object
+
+	// GUARDED_BY(mu)
+	uploads map[string]*upload
+
+	// GUARDED_BY(mu)
+	nextUploadID int
+}
+
+// NewServer returns a server without buckets.
+func NewServer() *Server {
+	return &Server{
+		buckets: make(map[string]map[string]*object),
+		uploads: make(map[string]*upload),
+	}
+}
+
+// CreateBucket creates an empty bucket, if it doesn't exist.
+func (s *Server) CreateBucket(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.buckets[name] == nil {
+		s.buckets[name] = make(map[string]*object)
+	}
+}
+
+// Uploads returns the number of multipart uploads in progress.
+func (s *Server) Uploads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.uploads)
+}
+
+type s3Error struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string
+	Message string
+	status  int
+}
+
+var (
+	errNoSuchBucket       = &s3Error{Code: "NoSuchBucket", Message: "The specified bucket does not exist.", status: http.StatusNotFound}
+	errNoSuchKey          = &s3Error{Code: "NoSuchKey", Message: "The specified key does not exist.", status: http.StatusNotFound}
+	errNoSuchUpload       = &s3Error{Code: "NoSuchUpload", Message: "The specified upload does not exist.", status: http.StatusNotFound}
+	errPrecondition       = &s3Error{Code: "PreconditionFailed", Message: "At least one of the preconditions you specified did not hold.", status: http.StatusPreconditionFailed}
+	errInvalidRange       = &s3Error{Code: "InvalidRange", Message: "The requested range is not satisfiable.", status: http.StatusRequestedRangeNotSatisfiable}
+	errEntityTooSmall     = &s3Error{Code: "EntityTooSmall", Message: "Your proposed upload is smaller than the minimum allowed object size.", status: http.StatusBadRequest}
+	errInvalidPart        = &s3Error{Code: "InvalidPart", Message: "One or more of the specified parts could not be found.", status: http.StatusBadRequest}
+	errNotImplemented     = &s3Error{Code: "NotImplemented", Message: "A header or query you provided implies functionality that is not implemented.", status: http.StatusNotImplemented}
+	errMalformedXML       = &s3Error{Code: "MalformedXML", Message: "The XML you provided was not well-formed.", status: http.StatusBadRequest}
+	errInvalidCopySource  = &s3Error{Code: "InvalidArgument", Message: "Copy Source must mention the source bucket and key.", status: http.StatusBadRequest}
+	errMethodNotSupported = &s3Error{Code: "MethodNotAllowed", Message: "The specified method is not allowed against this resource.", status: http.StatusMethodNotAllowed}
+)
+
+func writeError(w http.ResponseWriter, r *http.Request, e *s3Error) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(e.status)
+	// HEAD responses have no body.
+	if r.Method != http.MethodHead {
+		xml.NewEncoder(w).Encode(e)
+	}
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.Contains(r.Header.Get("Content-Encoding"), "aws-chunked") {
+		writeError(w, r, errNotImplemented)
+		return
+	}
+
+	bucketName, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	q := r.URL.Query()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := s.buckets[bucketName]
+	if b == nil {
+		writeError(w, r, errNoSuchBucket)
+		return
+	}
+
+	var e *s3Error
+	switch {
+	case key == "" && r.Method == http.MethodGet && q.Has("location"):
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"LocationConstraint"`
+			Region  string   `xml:",chardata"`
+		}{Region: region})
+	case key == "" && r.Method == http.MethodGet && q.Get("list-type") == "2":
+		e = s.list(w, bucketName, b, q)
+	case key == "" && r.Method == http.MethodHead:
+	case key == "":
+		e = errMethodNotSupported
+
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		e = s.get(w, r, b, key)
+	case r.Method == http.MethodPut && q.Has("uploadId"):
+		e = s.putPart(w, r, q)
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		e = s.copy(w, r, b, key)
+	case r.Method == http.MethodPut:
+		e = s.put(w, r, b, key)
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		e = s.createUpload(w, r, b, bucketName, key)
+	case r.Method == http.MethodPost && q.Has("uploadId"):
+		e = s.completeUpload(w, r, b, q)
+	case r.Method == http.MethodDelete && q.Has("uploadId"):
+		if s.uploads[q.Get("uploadId")] == nil {
+			e = errNoSuchUpload
+		} else {
+			delete(s.uploads, q.Get("uploadId"))
+			w.WriteHeader(http.StatusNoContent)
+		}
+	case r.Method == http.MethodDelete:
+		delete(b, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		e = errMethodNotSupported
+	}
+
+	if e != nil {
+		writeError(w, r, e)
+	}
+}
+
+type listedObject struct {
+	Key          string
+	LastModified string
+	ETag         string
+	Size         int
+	StorageClass string
+}
+
+type commonPrefix struct {
+	Prefix string
+}
+
+type listBucketResult struct {
+	XMLName               xml.Name `xml:"ListBucketResult"`
+	Name                  string
+	Prefix                string
+	Delimiter             string `xml:",omitempty"`
+	MaxKeys               int
+	KeyCount              int
+	IsTruncated           bool
+	ContinuationToken     string `xml:",omitempty"`
+	NextContinuationToken string `xml:",omitempty"`
+	Contents              []listedObject
+	CommonPrefixes        []commonPrefix
+}
+
+// list lists the objects like ListObjectsV2. The continuation token encodes the
+// last key or common prefix returned.
+// LOCKS_REQUIRED(s.mu)
+func (s *Server) list(w http.ResponseWriter, bucketName string, b map[string]*object, q url.Values) *s3Error {
+	prefix, delimiter := q.Get("prefix"), q.Get("delimiter")
+	maxKeys := 1000
+	if v := q.Get("max-keys"); v != "" {
+		var err error
+		if maxKeys, err = strconv.Atoi(v); err != nil || maxKeys < 0 {
+			return &s3Error{Code: "InvalidArgument", Message: "Invalid max-keys.", status: http.StatusBadRequest}
+		}
+	}
+	after, afterPrefix := q.Get("start-after"), ""
+	if token := q.Get("continuation-token"); token != "" {
+		decoded, err := base64.URLEncoding.DecodeString(token)
+		if err != nil || len(decoded) == 0 {
+			return &s3Error{Code: "InvalidArgument", Message: "The continuation token provided is incorrect.", status: http.StatusBadRequest}
+		}
+		after = string(decoded[1:])
+		if decoded[0] == 'p' {
+			afterPrefix = after
+		}
+	}
+
+	var keys []string
+	for key := range b {
+		if strings.HasPrefix(key, prefix) && key > after && (afterPrefix == "" || !strings.HasPrefix(key, afterPrefix)) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	result := listBucketResult{
+		Name:              bucketName,
+		Prefix:            prefix,
+		Delimiter:         delimiter,
+		MaxKeys:           maxKeys,
+		ContinuationToken: q.Get("continuation-token"),
+	}
+	var last, lastKind string
+	for _, key := range keys {
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				p := key[:len(prefix)+i+len(delimiter)]
+				if p == last {
+					continue
+				}
+				if result.KeyCount == maxKeys {
+					result.IsTruncated = true
+					break
+				}
+				result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: p})
+				result.KeyCount++
+				last, lastKind = p, "p"
+				continue
+			}
+		}
+
+		if result.KeyCount == maxKeys {
+			result.IsTruncated = true
+			break
+		}
+		o := b[key]
+		result.Contents = append(result.Contents, listedObject{
+			Key:          key,
+			LastModified: o.modTime.Format("2006-01-02T15:04:05.000Z"),
+			ETag:         `"` + o.etag + `"`,
+			Size:         len(o.data),
+			StorageClass: "STANDARD",
+		})
+		result.KeyCount++
+		last, lastKind = key, "k"
+	}
+
+	// After a common prefix, the keys it collapses are skipped.
+	if result.IsTruncated {
+		result.NextContinuationToken = base64.URLEncoding.EncodeToString([]byte(lastKind + last))
+	}
+	writeXML(w, result)
+	return nil
+}
+
+// checkConditions checks the If-Match and If-None-Match headers of a request
+// against the object, which is nil if it doesn't exist.
+func checkConditions(r *http.Request, o *object) *s3Error {
+	if match := r.Header.Get("If-Match"); match != "" {
+		if o == nil {
+			return errNoSuchKey
+		}
+		if match != "*" && strings.Trim(match, `"`) != o.etag {
+			return errPrecondition
+		}
+	}
+	if noneMatch := r.Header.Get("If-None-Match"); noneMatch != "" && o != nil {
+		if noneMatch = strings.Trim(noneMatch, `"`); noneMatch == "*" || noneMatch == o.etag {
+			return errPrecondition
+		}
+	}
+	return nil
+}
+
+func writeObjectHeaders(w http.ResponseWriter, o *object) {
+	for k, v := range o.header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("ETag", `"`+o.etag+`"`)
+	w.Header().Set("Last-Modified", o.modTime.Format(http.TimeFormat))
+	w.Header().Set("Accept-Ranges", "bytes")
+}
+
+// LOCKS_REQUIRED(s.mu)
+func (s *Server) get(w http.ResponseWriter, r *http.Request, b map[string]*object, key string) *s3Error {
+	o := b[key]
+	if o == nil {
+		return errNoSuchKey
+	}
+	if e := checkConditions(r, o); e != nil {
+		return e
+	}
+
+	data := o.data
+	status := http.StatusOK
+	if rng := r.Header.Get("Range"); rng != "" {
+		start, end, ok := parseRange(rng, len(data))
+		if !ok {
+			return errInvalidRange
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end-1, len(data)))
+		data = data[start:end]
+		status = http.StatusPartialContent
+	}
+
+	writeObjectHeaders(w, o)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(status)
+	if r.Method == http.MethodGet {
+		w.Write(data)
+	}
+	return nil
+}
+
+// parseRange parses a range like bytes=10-19, bytes=10- or bytes=-10 into
+// offsets of the object, the end being excluded.
+func parseRange(rng string, size int) (start, end int, ok bool) {
+	first, last, found := strings.Cut(strings.TrimPrefix(rng, "bytes="), "-")
+	if !found {
+		return
+	}
+
+	var err error
+	if first == "" {
+		n, convErr := strconv.Atoi(last)
+		if convErr != nil || n <= 0 {
+			return 0, 0, false
+		}
+		return max(size-n, 0), size, size > 0
+	}
+	if start, err = strconv.Atoi(first); err != nil || start >= size {
+		return 0, 0, false
+	}
+	end = size
+	if last != "" {
+		if end, err = strconv.Atoi(last); err != nil || end < start {
+			return 0, 0, false
+		}
+		end = min(end+1, size)
+	}
+	return start, end, true
+}
+
+// storedHeader returns the headers of a request stored with the object.
+func storedHeader(h http.Header) http.Header {
+	stored := make(http.Header)
+	for k, v := range h {
+		if strings.HasPrefix(k, "X-Amz-Meta-") {
+			stored[k] = v
+		}
+	}
+	for _, k := range objectHeaders {
+		if v := h.Get(k); v != "" {
+			stored.Set(k, v)
+		}
+	}
+	if stored.Get("Content-Type") == "" {
+		stored.Set("Content-Type", "binary/octet-stream")
+	}
+	return stored
+}
+
+func newObject(data []byte, etag string, header http.Header) *object {
+	if etag == "" {
+		sum := md5.Sum(data)
+		etag = hex.EncodeToString(sum[:])
+	}
+	return &object{data: data, etag: etag, modTime: time.Now().UTC(), header: header}
+}
+
+// LOCKS_REQUIRED(s.mu)
+func (s *Server) put(w http.ResponseWriter, r *http.Request, b map[string]*object, key string) *s3Error {
+	if e := checkConditions(r, b[key]); e != nil {
+		return e
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return &s3Error{Code: "IncompleteBody", Message: err.Error(), status: http.StatusBadRequest}
+	}
+
+	o := newObject(data, "", storedHeader(r.Header))
+	b[key] = o
+	w.Header().Set("ETag", `"`+o.etag+`"`)
+	return nil
+}
+
+// copySource returns the object named by the X-Amz-Copy-Source header,
+// checking its conditions.
+// LOCKS_REQUIRED(s.mu)
+func (s *Server) copySource(r *http.Request) (*object, *s3Error) {
+	source, err := url.PathUnescape(strings.TrimPrefix(r.Header.Get("X-Amz-Copy-Source"), "/"))
+	if err != nil {
+		return nil, errInvalidCopySource
+	}
+	bucketName, key, found := strings.Cut(source, "/")
+	if !found {
+		return nil, errInvalidCopySource
+	}
+
+	b := s.buckets[bucketName]
+	if b == nil {
+		return nil, errNoSuchBucket
+	}
+	o := b[key]
+	if o == nil {
+		return nil, errNoSuchKey
+	}
+	if match := r.Header.Get("X-Amz-Copy-Source-If-Match"); match != "" && strings.Trim(match, `"`) != o.etag {
+		return nil, errPrecondition
+	}
+	return o, nil
+}
+
+type copyObjectResult struct {
+	XMLName      xml.Name `xml:"CopyObjectResult"`
+	LastModified string
+	ETag         string
+}
+
+// LOCKS_REQUIRED(s.mu)
+func (s *Server) copy(w http.ResponseWriter, r *http.Request, b map[string]*object, key string) *s3Error {
+	src, e := s.copySource(r)
+	if e != nil {
+		return e
+	}
+	if e = checkConditions(r, b[key]); e != nil {
+		return e
+	}
+
+	header := src.header
+	if r.Header.Get("X-Amz-Metadata-Directive") == "REPLACE" {
+		header = storedHeader(r.Header)
+	}
+	o := newObject(src.data, src.etag, header)
+	b[key] = o
+	writeXML(w, copyObjectResult{LastModified: o.modTime.Format("2006-01-02T15:04:05.000Z"), ETag: `"` + o.etag + `"`})
+	return nil
+}
+
+type initiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	Bucket   string
+	Key      string
+	UploadID string `xml:"UploadId"`
+}
+
+// LOCKS_REQUIRED(s.mu)
+func (s *Server) createUpload(w http.ResponseWriter, r *http.Request, b map[string]*object, bucketName, key string) *s3Error {
+	if e := checkConditions(r, b[key]); e != nil {
+		return e
+	}
+
+	s.nextUploadID++
+	id := strconv.Itoa(s.nextUploadID)
+	s.uploads[id] = &upload{bucket: bucketName, key: key, header: storedHeader(r.Header), parts: make(map[int][]byte)}
+	writeXML(w, initiateMultipartUploadResult{Bucket: bucketName, Key: key, UploadID: id})
+	return nil
+}
+
+func partETag(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+type copyPartResult struct {
+	XMLName      xml.Name `xml:"CopyPartResult"`
+	LastModified string
+	ETag         string
+}
+
+// putPart uploads a part, or copies it from an object.
+// LOCKS_REQUIRED(s.mu)
+func (s *Server) putPart(w http.ResponseWriter, r *http.Request, q url.Values) *s3Error {
+	u := s.uploads[q.Get("uploadId")]
+	if u == nil {
+		return errNoSuchUpload
+	}
+	partNumber, err := strconv.Atoi(q.Get("partNumber"))
+	if err != nil || partNumber < 1 || partNumber > 10000 {
+		return &s3Error{Code: "InvalidArgument", Message: "Part number must be an integer between 1 and 10000.", status: http.StatusBadRequest}
+	}
+
+	if r.Header.Get("X-Amz-Copy-Source") == "" {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return &s3Error{Code: "IncompleteBody", Message: err.Error(), status: http.StatusBadRequest}
+		}
+		u.parts[partNumber] = data
+		w.Header().Set("ETag", `"`+partETag(data)+`"`)
+		return nil
+	}
+
+	src, e := s.copySource(r)
+	if e != nil {
+		return e
+	}
+	data := src.data
+	if rng := r.Header.Get("X-Amz-Copy-Source-Range"); rng != "" {
+		start, end, ok := parseRange(rng, len(data))
+		if !ok {
+			return errInvalidRange
+		}
+		data = data[start:end]
+	}
+	u.parts[partNumber] = append([]byte(nil), data...)
+	writeXML(w, copyPartResult{LastModified: time.Now().UTC().Format("2006-01-02T15:04:05.000Z"), ETag: `"` + partETag(data) + `"`})
+	return nil
+}
+
+type completeMultipartUpload struct {
+	Parts []struct {
+		PartNumber int
+		ETag       string
+	} `xml:"Part"`
+}
+
+type completeMultipartUploadResult struct {
+	XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+	Bucket  string
+	Key     string
+	ETag    string
+}
+
+// completeUpload concatenates the parts. Like S3, the ETag of the object is
+// the MD5 of the MD5s of the parts, followed by their number.
+// LOCKS_REQUIRED(s.mu)
+func (s *Server) completeUpload(w http.ResponseWriter, r *http.Request, b map[string]*object, q url.Values) *s3Error {
+	u := s.uploads[q.Get("uploadId")]
+	if u == nil {
+		return errNoSuchUpload
+	}
+
+	var req completeMultipartUpload
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Parts) == 0 {
+		return errMalformedXML
+	}
+
+	var data, sums []byte
+	for i, p := range req.Parts {
+		part, ok := u.parts[p.PartNumber]
+		if !ok || strings.Trim(p.ETag, `"`) != partETag(part) {
+			return errInvalidPart
+		}
+		if i < len(req.Parts)-1 && len(part) < MinPartSize {
+			return errEntityTooSmall
+		}
+		data = append(data, part...)
+		sum := md5.Sum(part)
+		sums = append(sums, sum[:]...)
+	}
+	sum := md5.Sum(sums)
+
+	o := newObject(data, fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), len(req.Parts)), u.header)
+	b[u.key] = o
+	delete(s.uploads, q.Get("uploadId"))
+	writeXML(w, completeMultipartUploadResult{Bucket: u.bucket, Key: u.key, ETag: `"` + o.etag + `"`})
+	return nil
+}
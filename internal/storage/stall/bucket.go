@@ -0,0 +1,123 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package stall implements a gcs.Bucket whose readers detect the streams of
+// objects that stall, and reopen them from where they stalled, transparently
+// to their callers, instead of waiting for the HTTP timeout.
+package stall
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/config"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+)
+
+// NewBucket returns a bucket whose readers of ranges of a generation of an
+// object, opened from the wrapped bucket, are reopened when they stall
+// following the given config, whose timeout must be positive.
+func NewBucket(c config.ReadStallConfig, wrapped gcs.Bucket) gcs.Bucket {
+	timeout := time.Duration(c.StallTimeoutSecs) * time.Second
+	return &bucket{
+		wrapped:    wrapped,
+		timeout:    timeout,
+		minBytes:   c.StallMinThroughputKBPerSec * 1024 * c.StallTimeoutSecs,
+		maxReopens: c.StallMaxReopens,
+	}
+}
+
+type bucket struct {
+	wrapped    gcs.Bucket
+	timeout    time.Duration
+	minBytes   int64
+	maxReopens int
+}
+
+func (b *bucket) Name() string {
+	return b.wrapped.Name()
+}
+
+func (b *bucket) BucketType() gcs.BucketType {
+	return b.wrapped.BucketType()
+}
+
+func (b *bucket) NewReader(
+	ctx context.Context,
+	req *gcs.ReadObjectRequest) (rc io.ReadCloser, err error) {
+	// A stream can only be reopened where it stalled if its range and
+	// generation are known.
+	if req.Range == nil || req.Generation == 0 {
+		return b.wrapped.NewReader(ctx, req)
+	}
+
+	r := &reader{
+		ctx:        ctx,
+		bucket:     b.wrapped,
+		req:        *req,
+		timeout:    b.timeout,
+		minBytes:   b.minBytes,
+		maxReopens: b.maxReopens,
+		offset:     req.Range.Start,
+	}
+	if err = r.open(); err != nil {
+		return
+	}
+
+	rc = r
+	return
+}
+
+func (b *bucket) CreateObject(
+	ctx context.Context,
+	req *gcs.CreateObjectRequest) (*gcs.Object, error) {
+	return b.wrapped.CreateObject(ctx, req)
+}
+
+func (b *bucket) CopyObject(
+	ctx context.Context,
+	req *gcs.CopyObjectRequest) (*gcs.Object, error) {
+	return b.wrapped.CopyObject(ctx, req)
+}
+
+func (b *bucket) ComposeObjects(
+	ctx context.Context,
+	req *gcs.ComposeObjectsRequest) (*gcs.Object, error) {
+	return b.wrapped.ComposeObjects(ctx, req)
+}
+
+func (b *bucket) StatObject(
+	ctx context.Context,
+	req *gcs.StatObjectRequest) (*gcs.MinObject, *gcs.ExtendedObjectAttributes, error) {
+	return b.wrapped.StatObject(ctx, req)
+}
+
+func (b *bucket) ListObjects(
+	ctx context.Context,
+	req *gcs.ListObjectsRequest) (*gcs.Listing, error) {
+	return b.wrapped.ListObjects(ctx, req)
+}
+
+func (b *bucket) UpdateObject(
+	ctx context.Context,
+	req *gcs.UpdateObjectRequest) (*gcs.Object, error) {
+	return b.wrapped.UpdateObject(ctx, req)
+}
+
+func (b *bucket) DeleteObject(
+	ctx context.Context,
+	req *gcs.DeleteObjectRequest) error {
+	return b.wrapped.DeleteObject(ctx, req)
+}
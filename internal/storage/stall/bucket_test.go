@@ -0,0 +1,179 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stall
+
+import (
+	"context"
+	"io"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/fake"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/storageutil"
+	"github.com/jacobsa/timeutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const contents = "tacoburrito"
+
+// stallingBucket opens readers of ranges that stall after a number of bytes,
+// until the given number of readers were opened.
+type stallingBucket struct {
+	gcs.Bucket
+	stallAfter int64
+	stalls     int
+
+	// The ranges of the readers opened.
+	ranges []gcs.ByteRange
+
+	// Whether the stalled readers hang, or trickle a byte at a time.
+	trickle bool
+}
+
+func (b *stallingBucket) NewReader(ctx context.Context, req *gcs.ReadObjectRequest) (io.ReadCloser, error) {
+	if req.Range == nil {
+		return b.Bucket.NewReader(ctx, req)
+	}
+
+	b.ranges = append(b.ranges, *req.Range)
+	rc, err := b.Bucket.NewReader(ctx, req)
+	if err != nil || len(b.ranges) > b.stalls {
+		return rc, err
+	}
+
+	return &stallingReader{ReadCloser: rc, ctx: ctx, left: b.stallAfter, trickle: b.trickle}, nil
+}
+
+type stallingReader struct {
+	io.ReadCloser
+	ctx     context.Context
+	left    int64
+	trickle bool
+}
+
+func (r *stallingReader) Read(p []byte) (int, error) {
+	if r.left > 0 {
+		n, err := r.ReadCloser.Read(p[:min(int64(len(p)), r.left)])
+		r.left -= int64(n)
+		return n, err
+	}
+
+	if r.trickle {
+		time.Sleep(5 * time.Millisecond)
+		return iotest.OneByteReader(r.ReadCloser).Read(p)
+	}
+
+	<-r.ctx.Done()
+	return 0, r.ctx.Err()
+}
+
+func newBucket(t *testing.T, wrapped *stallingBucket, maxReopens int) *bucket {
+	f := fake.NewFakeBucket(timeutil.RealClock(), "b")
+	_, err := storageutil.CreateObject(context.Background(), f, "a", []byte(contents))
+	require.NoError(t, err)
+	wrapped.Bucket = f
+
+	return &bucket{
+		wrapped:    wrapped,
+		timeout:    20 * time.Millisecond,
+		minBytes:   1024,
+		maxReopens: maxReopens,
+	}
+}
+
+func newReader(t *testing.T, b gcs.Bucket) io.ReadCloser {
+	m, _, err := b.StatObject(context.Background(), &gcs.StatObjectRequest{Name: "a"})
+	require.NoError(t, err)
+
+	rc, err := b.NewReader(context.Background(), &gcs.ReadObjectRequest{
+		Name:       "a",
+		Generation: m.Generation,
+		Range:      &gcs.ByteRange{Start: 2, Limit: m.Size},
+	})
+	require.NoError(t, err)
+	return rc
+}
+
+func TestHangingStreamIsReopened(t *testing.T) {
+	wrapped := &stallingBucket{stallAfter: 3, stalls: 2}
+	b := newBucket(t, wrapped, 2)
+	rc := newReader(t, b)
+
+	buf, err := io.ReadAll(rc)
+
+	require.NoError(t, err)
+	assert.Equal(t, contents[2:], string(buf))
+	assert.Equal(t, []gcs.ByteRange{{Start: 2, Limit: 11}, {Start: 5, Limit: 11}, {Start: 8, Limit: 11}}, wrapped.ranges)
+	assert.NoError(t, rc.Close())
+}
+
+func TestTricklingStreamIsReopened(t *testing.T) {
+	wrapped := &stallingBucket{stallAfter: 3, stalls: 1, trickle: true}
+	b := newBucket(t, wrapped, 1)
+	rc := newReader(t, b)
+
+	buf, err := io.ReadAll(rc)
+
+	require.NoError(t, err)
+	assert.Equal(t, contents[2:], string(buf))
+	assert.Len(t, wrapped.ranges, 2)
+	assert.Equal(t, gcs.ByteRange{Start: 2, Limit: 11}, wrapped.ranges[0])
+	assert.Less(t, wrapped.ranges[1].Start, uint64(11))
+}
+
+func TestGivesUpAfterMaxReopens(t *testing.T) {
+	wrapped := &stallingBucket{stallAfter: 3, stalls: 2}
+	b := newBucket(t, wrapped, 1)
+	rc := newReader(t, b)
+
+	_, err := io.ReadAll(rc)
+
+	assert.ErrorContains(t, err, "stalled at offset 8, and was reopened 1 times")
+}
+
+func TestSlowCallerDoesntStall(t *testing.T) {
+	wrapped := &stallingBucket{}
+	b := newBucket(t, wrapped, 0)
+	rc := newReader(t, b)
+	p := make([]byte, 1)
+
+	var buf []byte
+	for {
+		n, err := rc.Read(p)
+		buf = append(buf, p[:n]...)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	assert.Equal(t, contents[2:], string(buf))
+	assert.Len(t, wrapped.ranges, 1)
+}
+
+func TestWholeObjectReadIsntWrapped(t *testing.T) {
+	b := newBucket(t, &stallingBucket{}, 0)
+
+	rc, err := b.NewReader(context.Background(), &gcs.ReadObjectRequest{Name: "a"})
+
+	require.NoError(t, err)
+	_, ok := rc.(*reader)
+	assert.False(t, ok)
+	assert.NoError(t, rc.Close())
+}
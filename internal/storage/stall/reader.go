@@ -0,0 +1,188 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stall
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/monitor"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+)
+
+// reader reads a range of an object from a stream, which it reopens from the
+// current offset when it stalls: when the time spent waiting for it reaches
+// the timeout, and it delivered less than the minimum throughput meanwhile.
+//
+// The time the caller spends between reads doesn't count, so that a slow
+// caller doesn't stall the stream.
+type reader struct {
+	ctx    context.Context
+	bucket gcs.Bucket
+	req    gcs.ReadObjectRequest
+
+	timeout    time.Duration
+	minBytes   int64
+	maxReopens int
+
+	// The stream, reading from offset, and the func cancelling its request.
+	rc      io.ReadCloser
+	cancel  context.CancelFunc
+	offset  uint64
+	reopens int
+
+	mu sync.Mutex
+
+	// The time spent waiting for the stream, and the bytes it delivered, since
+	// the throughput was last checked.
+	waited time.Duration // GUARDED_BY(mu)
+	bytes  int64         // GUARDED_BY(mu)
+
+	// Whether the stream stalled.
+	stalled bool // GUARDED_BY(mu)
+}
+
+// Open the stream from the current offset.
+func (r *reader) open() (err error) {
+	req := r.req
+	req.Range = &gcs.ByteRange{Start: r.offset, Limit: r.req.Range.Limit}
+
+	ctx, cancel := context.WithCancel(r.ctx)
+	rc, err := r.bucket.NewReader(ctx, &req)
+	if err != nil {
+		cancel()
+		return
+	}
+
+	r.rc, r.cancel = rc, cancel
+	r.waited, r.bytes, r.stalled = 0, 0, false
+	return
+}
+
+// Close the stream.
+func (r *reader) close() (err error) {
+	if r.rc == nil {
+		return
+	}
+
+	err = r.rc.Close()
+	r.cancel()
+	r.rc, r.cancel = nil, nil
+	return
+}
+
+// Account the given time spent waiting for the stream and bytes it delivered,
+// and check its throughput once the time reaches the timeout.
+//
+// LOCKS_REQUIRED(r.mu)
+func (r *reader) account(waited time.Duration, n int) {
+	r.waited += waited
+	r.bytes += int64(n)
+	if r.waited < r.timeout {
+		return
+	}
+
+	if r.bytes < r.minBytes {
+		r.stalled = true
+		return
+	}
+
+	r.waited, r.bytes = 0, 0
+}
+
+// Read from the stream, cancelling it if it stalls meanwhile, and return
+// whether it stalled.
+func (r *reader) read(p []byte) (n int, stalled bool, err error) {
+	r.mu.Lock()
+	start := time.Now()
+	reading := true
+	var timer *time.Timer
+	timer = time.AfterFunc(r.timeout-r.waited, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if !reading {
+			return
+		}
+
+		now := time.Now()
+		r.account(now.Sub(start), 0)
+		start = now
+		if r.stalled {
+			r.cancel()
+			return
+		}
+
+		timer.Reset(r.timeout)
+	})
+	r.mu.Unlock()
+
+	n, err = r.rc.Read(p)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	reading = false
+	timer.Stop()
+	r.account(time.Since(start), n)
+	stalled = r.stalled
+	return
+}
+
+func (r *reader) Read(p []byte) (n int, err error) {
+	for {
+		if r.offset >= r.req.Range.Limit {
+			err = io.EOF
+			return
+		}
+
+		if r.rc == nil {
+			if err = r.open(); err != nil {
+				err = fmt.Errorf("NewReader: %w", err)
+				return
+			}
+		}
+
+		var stalled bool
+		n, stalled, err = r.read(p)
+		r.offset += uint64(n)
+		if !stalled || err == io.EOF || r.ctx.Err() != nil {
+			return
+		}
+
+		r.close()
+		if r.reopens >= r.maxReopens {
+			err = fmt.Errorf("the stream of %q stalled at offset %d, and was reopened %d times", r.req.Name, r.offset, r.reopens)
+			return
+		}
+
+		r.reopens++
+		logger.Warnf("The stream of %q stalled at offset %d, reopening it", r.req.Name, r.offset)
+		monitor.CaptureReadStallMetrics(r.ctx)
+
+		// Return what was read before the stall, and reopen the stream on the
+		// next read.
+		if n > 0 {
+			err = nil
+			return
+		}
+	}
+}
+
+func (r *reader) Close() error {
+	return r.close()
+}
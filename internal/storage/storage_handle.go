@@ -17,15 +17,27 @@ package storage
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
 
 	"cloud.google.com/go/storage"
 	control "cloud.google.com/go/storage/control/apiv2"
 	"github.com/googleapis/gax-go/v2"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/logger"
 	mountpkg "github.com/googlecloudplatform/gcsfuse/v2/internal/mount"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/localdir"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/s3"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/storageutil"
+	"github.com/jacobsa/timeutil"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/metric/noop"
 	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
 	option "google.golang.org/api/option"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -41,7 +53,12 @@ type StorageHandle interface {
 	// to that project rather than to the bucket's owning project.
 	//
 	// A user-project is required for all operations on Requester Pays buckets.
-	BucketHandle(bucketName string, billingProject string) (bh *bucketHandle)
+	BucketHandle(bucketName string, billingProject string) (bh gcs.Bucket)
+
+	// ListBuckets returns the names of the buckets of the given project that
+	// the client is allowed to list. The project is ignored by the backends
+	// other than GCS.
+	ListBuckets(ctx context.Context, projectID string) (names []string, err error)
 }
 
 type storageClient struct {
@@ -49,6 +66,18 @@ type storageClient struct {
 	storageControlClient *control.StorageControlClient
 }
 
+// s3StorageClient accesses the buckets of an S3-compatible object store, e.g.
+// MinIO. Billing projects are specific to GCS, and ignored.
+type s3StorageClient struct {
+	client *minio.Client
+}
+
+// localStorageClient accesses the buckets kept as subdirectories of a local
+// directory. Billing projects are ignored.
+type localStorageClient struct {
+	dir string
+}
+
 // Return clientOpts for both gRPC client and control client.
 func createClientOptionForGRPCClient(clientConfig *storageutil.StorageClientConfig) (clientOpts []option.ClientOption, err error) {
 	// Add Custom endpoint option.
@@ -78,6 +107,10 @@ func createClientOptionForGRPCClient(clientConfig *storageutil.StorageClientConf
 
 	clientOpts = append(clientOpts, option.WithGRPCConnectionPool(clientConfig.GrpcConnPoolSize))
 	clientOpts = append(clientOpts, option.WithUserAgent(clientConfig.UserAgent))
+	if clientConfig.EnableTracing {
+		clientOpts = append(clientOpts, option.WithGRPCDialOption(grpc.WithStatsHandler(
+			otelgrpc.NewClientHandler(otelgrpc.WithMeterProvider(noop.NewMeterProvider())))))
+	}
 
 	return
 }
@@ -145,11 +178,95 @@ func createHTTPClientHandle(ctx context.Context, clientConfig *storageutil.Stora
 	return storage.NewClient(ctx, clientOpts...)
 }
 
+// IsS3Endpoint returns true if the custom endpoint is the one of an S3 API,
+// e.g. s3://minio.example.com:9000, or s3+http://localhost:9000 without TLS.
+func IsS3Endpoint(endpoint *url.URL) bool {
+	return endpoint != nil && (endpoint.Scheme == "s3" || endpoint.Scheme == "s3+http")
+}
+
+// IsLocalEndpoint returns true if the custom endpoint is a local directory of
+// buckets, e.g. file:///var/lib/buckets.
+func IsLocalEndpoint(endpoint *url.URL) bool {
+	return endpoint != nil && endpoint.Scheme == "file"
+}
+
+// s3Credentials returns the credentials of the S3 API, read from the AWS
+// shared credentials file, if given, or else from the environment variables
+// of AWS or MinIO, or else from ~/.aws/credentials.
+func s3Credentials(clientConfig *storageutil.StorageClientConfig) *credentials.Credentials {
+	if clientConfig.AnonymousAccess {
+		return credentials.NewStaticV4("", "", "")
+	} else if clientConfig.AwsCredentialsFile != "" {
+		return credentials.NewFileAWSCredentials(clientConfig.AwsCredentialsFile, "")
+	}
+	return credentials.NewChainCredentials([]credentials.Provider{
+		&credentials.EnvAWS{},
+		&credentials.EnvMinio{},
+		&credentials.FileAWSCredentials{},
+	})
+}
+
+// createS3Client returns a client of the S3 API at the custom endpoint, or of
+// AWS by default.
+func createS3Client(clientConfig *storageutil.StorageClientConfig) (*minio.Client, error) {
+	if clientConfig.ClientProtocol == mountpkg.GRPC {
+		return nil, fmt.Errorf("client-protocol requested is not supported by the S3 API: %s", clientConfig.ClientProtocol)
+	}
+
+	endpoint, secure := "s3.amazonaws.com", true
+	if u := clientConfig.CustomEndpoint; u != nil {
+		endpoint = u.Host
+		secure = u.Scheme == "https" || u.Scheme == "s3"
+	}
+
+	transport, err := minio.DefaultTransport(secure)
+	if err != nil {
+		return nil, err
+	}
+	transport.MaxConnsPerHost = clientConfig.MaxConnsPerHost
+	transport.MaxIdleConnsPerHost = clientConfig.MaxIdleConnsPerHost
+	var roundTripper http.RoundTripper = transport
+	if clientConfig.EnableTracing {
+		roundTripper = otelhttp.NewTransport(roundTripper, otelhttp.WithMeterProvider(noop.NewMeterProvider()))
+	}
+
+	// The region of the buckets is looked up when not set.
+	return minio.New(endpoint, &minio.Options{
+		Creds:        s3Credentials(clientConfig),
+		Secure:       secure,
+		Transport:    roundTripper,
+		Region:       os.Getenv("AWS_REGION"),
+		BucketLookup: minio.BucketLookupAuto,
+	})
+}
+
 // NewStorageHandle returns the handle of http or grpc Go storage client based on the
-// provided StorageClientConfig.ClientProtocol.
+// provided StorageClientConfig.ClientProtocol, or the handle of an S3 client
+// when the backend is S3, or of a local directory when it is local.
 // Please check out the StorageClientConfig to know about the parameters used in
 // http and gRPC client.
 func NewStorageHandle(ctx context.Context, clientConfig storageutil.StorageClientConfig) (sh StorageHandle, err error) {
+	if clientConfig.Backend == mountpkg.S3 || IsS3Endpoint(clientConfig.CustomEndpoint) {
+		var client *minio.Client
+		client, err = createS3Client(&clientConfig)
+		if err != nil {
+			err = fmt.Errorf("S3 client creation failed: %w", err)
+			return
+		}
+		sh = &s3StorageClient{client: client}
+		return
+	} else if clientConfig.Backend == mountpkg.Local || IsLocalEndpoint(clientConfig.CustomEndpoint) {
+		if !IsLocalEndpoint(clientConfig.CustomEndpoint) || clientConfig.CustomEndpoint.Path == "" {
+			err = fmt.Errorf("the local backend needs a custom-endpoint like file:///path/to/buckets")
+			return
+		}
+		sh = &localStorageClient{dir: clientConfig.CustomEndpoint.Path}
+		return
+	} else if clientConfig.Backend != "" && clientConfig.Backend != mountpkg.GCS {
+		err = fmt.Errorf("invalid backend requested: %s", clientConfig.Backend)
+		return
+	}
+
 	var sc *storage.Client
 	// The default protocol for the Go Storage control client's folders API is gRPC.
 	// gcsfuse will initially mirror this behavior due to the client's lack of HTTP support.
@@ -198,7 +315,7 @@ func NewStorageHandle(ctx context.Context, clientConfig storageutil.StorageClien
 	return
 }
 
-func (sh *storageClient) BucketHandle(bucketName string, billingProject string) (bh *bucketHandle) {
+func (sh *storageClient) BucketHandle(bucketName string, billingProject string) (bh gcs.Bucket) {
 	storageBucketHandle := sh.client.Bucket(bucketName)
 
 	if billingProject != "" {
@@ -212,3 +329,57 @@ func (sh *storageClient) BucketHandle(bucketName string, billingProject string)
 	}
 	return
 }
+
+func (sh *storageClient) ListBuckets(ctx context.Context, projectID string) (names []string, err error) {
+	it := sh.client.Buckets(ctx, projectID)
+	for {
+		var attrs *storage.BucketAttrs
+		attrs, err = it.Next()
+		if err == iterator.Done {
+			err = nil
+			return
+		}
+		if err != nil {
+			err = fmt.Errorf("listing the buckets of %q: %w", projectID, err)
+			return
+		}
+
+		names = append(names, attrs.Name)
+	}
+}
+
+func (sh *s3StorageClient) BucketHandle(bucketName string, billingProject string) (bh gcs.Bucket) {
+	return s3.NewBucket(sh.client, bucketName)
+}
+
+func (sh *s3StorageClient) ListBuckets(ctx context.Context, projectID string) (names []string, err error) {
+	buckets, err := sh.client.ListBuckets(ctx)
+	if err != nil {
+		err = fmt.Errorf("listing the buckets: %w", err)
+		return
+	}
+
+	for _, b := range buckets {
+		names = append(names, b.Name)
+	}
+	return
+}
+
+func (sh *localStorageClient) BucketHandle(bucketName string, billingProject string) (bh gcs.Bucket) {
+	return localdir.NewBucket(timeutil.RealClock(), filepath.Join(sh.dir, bucketName), bucketName)
+}
+
+func (sh *localStorageClient) ListBuckets(ctx context.Context, projectID string) (names []string, err error) {
+	entries, err := os.ReadDir(sh.dir)
+	if err != nil {
+		err = fmt.Errorf("listing the buckets: %w", err)
+		return
+	}
+
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	return
+}
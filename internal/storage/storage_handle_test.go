@@ -18,6 +18,8 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"os"
+	"path"
 	"testing"
 
 	mountpkg "github.com/googlecloudplatform/gcsfuse/v2/internal/mount"
@@ -51,7 +53,7 @@ func (testSuite *StorageHandleTest) TearDownTest() {
 
 func (testSuite *StorageHandleTest) TestBucketHandleWhenBucketExistsWithEmptyBillingProject() {
 	storageHandle := testSuite.fakeStorage.CreateStorageHandle()
-	bucketHandle := storageHandle.BucketHandle(TestBucketName, "")
+	bucketHandle := storageHandle.BucketHandle(TestBucketName, "").(*bucketHandle)
 
 	assert.NotNil(testSuite.T(), bucketHandle)
 	assert.Equal(testSuite.T(), TestBucketName, bucketHandle.bucketName)
@@ -60,14 +62,14 @@ func (testSuite *StorageHandleTest) TestBucketHandleWhenBucketExistsWithEmptyBil
 
 func (testSuite *StorageHandleTest) TestBucketHandleWhenBucketDoesNotExistWithEmptyBillingProject() {
 	storageHandle := testSuite.fakeStorage.CreateStorageHandle()
-	bucketHandle := storageHandle.BucketHandle(invalidBucketName, "")
+	bucketHandle := storageHandle.BucketHandle(invalidBucketName, "").(*bucketHandle)
 
 	assert.Nil(testSuite.T(), bucketHandle.Bucket)
 }
 
 func (testSuite *StorageHandleTest) TestBucketHandleWhenBucketExistsWithNonEmptyBillingProject() {
 	storageHandle := testSuite.fakeStorage.CreateStorageHandle()
-	bucketHandle := storageHandle.BucketHandle(TestBucketName, projectID)
+	bucketHandle := storageHandle.BucketHandle(TestBucketName, projectID).(*bucketHandle)
 
 	assert.NotNil(testSuite.T(), bucketHandle)
 	assert.Equal(testSuite.T(), TestBucketName, bucketHandle.bucketName)
@@ -76,7 +78,7 @@ func (testSuite *StorageHandleTest) TestBucketHandleWhenBucketExistsWithNonEmpty
 
 func (testSuite *StorageHandleTest) TestBucketHandleWhenBucketDoesNotExistWithNonEmptyBillingProject() {
 	storageHandle := testSuite.fakeStorage.CreateStorageHandle()
-	bucketHandle := storageHandle.BucketHandle(invalidBucketName, projectID)
+	bucketHandle := storageHandle.BucketHandle(invalidBucketName, projectID).(*bucketHandle)
 
 	assert.Nil(testSuite.T(), bucketHandle.Bucket)
 }
@@ -201,6 +203,116 @@ func (testSuite *StorageHandleTest) TestNewStorageHandleWithInvalidClientProtoco
 	assert.Contains(testSuite.T(), err.Error(), "invalid client-protocol requested: test-protocol")
 }
 
+func (testSuite *StorageHandleTest) TestNewStorageHandleWithS3Backend() {
+	sc := storageutil.GetDefaultStorageClientConfig()
+	sc.Backend = mountpkg.S3
+	sc.CustomEndpoint = &url.URL{Scheme: "http", Host: "localhost:9000"}
+
+	handleCreated, err := NewStorageHandle(context.Background(), sc)
+
+	assert.Nil(testSuite.T(), err)
+	assert.IsType(testSuite.T(), &s3StorageClient{}, handleCreated)
+	assert.Equal(testSuite.T(), TestBucketName, handleCreated.BucketHandle(TestBucketName, projectID).Name())
+}
+
+func (testSuite *StorageHandleTest) TestNewStorageHandleWithS3Endpoint() {
+	for _, endpoint := range []string{"s3://minio.example.com:9000", "s3+http://localhost:9000"} {
+		sc := storageutil.GetDefaultStorageClientConfig()
+		var err error
+		sc.CustomEndpoint, err = url.Parse(endpoint)
+		assert.Nil(testSuite.T(), err)
+
+		handleCreated, err := NewStorageHandle(context.Background(), sc)
+
+		assert.Nil(testSuite.T(), err)
+		assert.IsType(testSuite.T(), &s3StorageClient{}, handleCreated, endpoint)
+	}
+}
+
+func (testSuite *StorageHandleTest) TestS3CredentialsFromAwsCredentialsFile() {
+	sc := storageutil.GetDefaultStorageClientConfig()
+	sc.AnonymousAccess = false
+	sc.AwsCredentialsFile = path.Join(testSuite.T().TempDir(), "credentials")
+	err := os.WriteFile(sc.AwsCredentialsFile, []byte("[default]\naws_access_key_id = id\naws_secret_access_key = secret\n"), 0600)
+	assert.Nil(testSuite.T(), err)
+
+	value, err := s3Credentials(&sc).Get()
+
+	assert.Nil(testSuite.T(), err)
+	assert.Equal(testSuite.T(), "id", value.AccessKeyID)
+	assert.Equal(testSuite.T(), "secret", value.SecretAccessKey)
+}
+
+func (testSuite *StorageHandleTest) TestNewStorageHandleWithS3BackendAndGRPC() {
+	sc := storageutil.GetDefaultStorageClientConfig()
+	sc.Backend = mountpkg.S3
+	sc.ClientProtocol = mountpkg.GRPC
+
+	handleCreated, err := NewStorageHandle(context.Background(), sc)
+
+	assert.NotNil(testSuite.T(), err)
+	assert.Nil(testSuite.T(), handleCreated)
+	assert.Contains(testSuite.T(), err.Error(), "client-protocol requested is not supported by the S3 API: grpc")
+}
+
+func (testSuite *StorageHandleTest) TestNewStorageHandleWithLocalEndpoint() {
+	sc := storageutil.GetDefaultStorageClientConfig()
+	sc.CustomEndpoint = &url.URL{Scheme: "file", Path: "/var/lib/buckets"}
+
+	handleCreated, err := NewStorageHandle(context.Background(), sc)
+
+	assert.Nil(testSuite.T(), err)
+	assert.Equal(testSuite.T(), &localStorageClient{dir: "/var/lib/buckets"}, handleCreated)
+	assert.Equal(testSuite.T(), TestBucketName, handleCreated.BucketHandle(TestBucketName, projectID).Name())
+}
+
+func (testSuite *StorageHandleTest) TestListBuckets() {
+	storageHandle := testSuite.fakeStorage.CreateStorageHandle()
+
+	names, err := storageHandle.ListBuckets(context.Background(), projectID)
+
+	assert.Nil(testSuite.T(), err)
+	assert.Equal(testSuite.T(), []string{TestBucketName}, names)
+}
+
+func (testSuite *StorageHandleTest) TestListBucketsWithLocalEndpoint() {
+	dir := testSuite.T().TempDir()
+	assert.Nil(testSuite.T(), os.Mkdir(path.Join(dir, "a"), 0700))
+	assert.Nil(testSuite.T(), os.Mkdir(path.Join(dir, "b"), 0700))
+	assert.Nil(testSuite.T(), os.WriteFile(path.Join(dir, "c"), nil, 0600))
+	sc := storageutil.GetDefaultStorageClientConfig()
+	sc.CustomEndpoint = &url.URL{Scheme: "file", Path: dir}
+	storageHandle, err := NewStorageHandle(context.Background(), sc)
+	assert.Nil(testSuite.T(), err)
+
+	names, err := storageHandle.ListBuckets(context.Background(), projectID)
+
+	assert.Nil(testSuite.T(), err)
+	assert.Equal(testSuite.T(), []string{"a", "b"}, names)
+}
+
+func (testSuite *StorageHandleTest) TestNewStorageHandleWithLocalBackendWithoutDirectory() {
+	sc := storageutil.GetDefaultStorageClientConfig()
+	sc.Backend = mountpkg.Local
+
+	handleCreated, err := NewStorageHandle(context.Background(), sc)
+
+	assert.NotNil(testSuite.T(), err)
+	assert.Nil(testSuite.T(), handleCreated)
+	assert.Contains(testSuite.T(), err.Error(), "the local backend needs a custom-endpoint like file:///path/to/buckets")
+}
+
+func (testSuite *StorageHandleTest) TestNewStorageHandleWithInvalidBackend() {
+	sc := storageutil.GetDefaultStorageClientConfig()
+	sc.Backend = "test-backend"
+
+	handleCreated, err := NewStorageHandle(context.Background(), sc)
+
+	assert.NotNil(testSuite.T(), err)
+	assert.Nil(testSuite.T(), handleCreated)
+	assert.Contains(testSuite.T(), err.Error(), "invalid backend requested: test-backend")
+}
+
 func (testSuite *StorageHandleTest) TestCreateGRPCClientHandle() {
 	sc := storageutil.GetDefaultStorageClientConfig()
 	sc.ClientProtocol = mountpkg.GRPC
@@ -0,0 +1,32 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageutil
+
+import (
+	"context"
+	"io"
+)
+
+// CancellingReadCloser cancels the context of the request that opened it when
+// closed.
+type CancellingReadCloser struct {
+	io.ReadCloser
+	Cancel context.CancelFunc
+}
+
+func (rc *CancellingReadCloser) Close() error {
+	defer rc.Cancel()
+	return rc.ReadCloser.Close()
+}
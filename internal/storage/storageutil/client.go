@@ -25,6 +25,8 @@ import (
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/auth"
 	"github.com/googlecloudplatform/gcsfuse/v2/internal/config"
 	mountpkg "github.com/googlecloudplatform/gcsfuse/v2/internal/mount"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/metric/noop"
 	"golang.org/x/net/context"
 	"golang.org/x/oauth2"
 )
@@ -34,6 +36,10 @@ const urlSchemeSeparator = "://"
 type StorageClientConfig struct {
 	/** Common client parameters. */
 
+	// Backend is the API of the object store, GCS or S3. A custom endpoint
+	// with the s3 or s3+http scheme also selects S3.
+	Backend mountpkg.Backend
+
 	// ClientProtocol decides the go-sdk client to create.
 	ClientProtocol    mountpkg.ClientProtocol
 	UserAgent         string
@@ -44,6 +50,19 @@ type StorageClientConfig struct {
 	MaxRetrySleep     time.Duration
 	RetryMultiplier   float64
 
+	// ImpersonateServiceAccount, if set, is the service account impersonated
+	// with the credentials, or a comma-separated delegation chain ending with
+	// it.
+	ImpersonateServiceAccount string
+
+	// AllowCredentialExecutables allows the external account credentials of
+	// the key file to run the executable they are sourced from.
+	AllowCredentialExecutables bool
+
+	// AwsCredentialsFile, if set, is the AWS shared credentials file holding
+	// the credentials of the S3 backend.
+	AwsCredentialsFile string
+
 	/** HTTP client parameters. */
 	MaxConnsPerHost            int
 	MaxIdleConnsPerHost        int
@@ -54,6 +73,10 @@ type StorageClientConfig struct {
 	/** Grpc client parameters. */
 	GrpcConnPoolSize int
 
+	// EnableTracing records a span per HTTP request or gRPC call, i.e. per
+	// attempt when requests are retried.
+	EnableTracing bool
+
 	// Enabling new API flow for HNS bucket.
 	EnableHNS config.EnableHNS
 }
@@ -114,13 +137,19 @@ func CreateHttpClient(storageClientConfig *StorageClientConfig) (httpClient *htt
 			UserAgent: storageClientConfig.UserAgent,
 		}
 	}
+
+	if storageClientConfig.EnableTracing {
+		httpClient.Transport = otelhttp.NewTransport(httpClient.Transport,
+			otelhttp.WithMeterProvider(noop.NewMeterProvider()))
+	}
 	return httpClient, err
 }
 
 // It creates the token-source from the provided
 // key-file or using ADC search order (https://cloud.google.com/docs/authentication/application-default-credentials#order).
 func CreateTokenSource(storageClientConfig *StorageClientConfig) (tokenSrc oauth2.TokenSource, err error) {
-	return auth.GetTokenSource(context.Background(), storageClientConfig.KeyFile, storageClientConfig.TokenUrl, storageClientConfig.ReuseTokenFromUrl)
+	return auth.GetTokenSource(context.Background(), storageClientConfig.KeyFile, storageClientConfig.TokenUrl, storageClientConfig.ReuseTokenFromUrl,
+		storageClientConfig.ImpersonateServiceAccount, storageClientConfig.AllowCredentialExecutables)
 }
 
 // StripScheme strips the scheme part of given url.
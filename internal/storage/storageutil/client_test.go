@@ -20,6 +20,7 @@ import (
 
 	"github.com/jacobsa/oglematchers"
 	. "github.com/jacobsa/ogletest"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"golang.org/x/oauth2"
 )
 
@@ -130,3 +131,15 @@ func (t *clientTest) TestStripScheme() {
 		AssertEq(tc.expectedOutput, output)
 	}
 }
+
+func (t *clientTest) TestCreateHttpClientWithTracing() {
+	sc := GetDefaultStorageClientConfig()
+	sc.EnableTracing = true
+
+	httpClient, err := CreateHttpClient(&sc)
+
+	ExpectEq(nil, err)
+	AssertNe(nil, httpClient)
+	_, ok := httpClient.Transport.(*otelhttp.Transport)
+	ExpectTrue(ok)
+}
@@ -21,10 +21,18 @@ import (
 )
 
 func ShouldRetry(err error) (b bool) {
-	b = storage.ShouldRetry(err)
+	b = IsRetryable(err)
 	if b {
 		logger.Infof("Retrying for the error: %v", err)
-		return
+	}
+	return
+}
+
+// IsRetryable returns whether a request that failed with the given error may
+// succeed if retried.
+func IsRetryable(err error) bool {
+	if storage.ShouldRetry(err) {
+		return true
 	}
 
 	// HTTP 401 errors - Invalid Credentials
@@ -36,10 +44,8 @@ func ShouldRetry(err error) (b bool) {
 	// TODO: Please incorporate the correct fix post resolution of the above issue.
 	if typed, ok := err.(*googleapi.Error); ok {
 		if typed.Code == 401 {
-			b = true
-			logger.Infof("Retrying for error-code 401: %v", err)
-			return
+			return true
 		}
 	}
-	return
+	return false
 }
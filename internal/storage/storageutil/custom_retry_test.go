@@ -16,6 +16,7 @@ package storageutil
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"net/url"
@@ -118,3 +119,10 @@ func TestShouldRetryReturnsTrueForConnectionRefusedAndResetErrors(t *testing.T)
 		})
 	}
 }
+
+func TestIsRetryableWithWrappedError(t *testing.T) {
+	err := fmt.Errorf("Error in fetching object attributes: %w", &googleapi.Error{Code: 503})
+
+	assert.True(t, IsRetryable(err))
+	assert.False(t, IsRetryable(fmt.Errorf("Error in fetching object attributes: %w", &googleapi.Error{Code: 404})))
+}
@@ -0,0 +1,361 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageutil
+
+// The semantics of GCS shared by the buckets emulating it, like the fake and
+// the local directory ones.
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+)
+
+// Return the smallest string that is lexicographically larger than prefix and
+// does not have prefix as a prefix. For the sole case where this is not
+// possible (all strings consisting solely of 0xff bytes, including the empty
+// string), return the empty string.
+func PrefixSuccessor(prefix string) string {
+	// Attempt to increment the last byte. If that is a 0xff byte, erase it and
+	// recurse. If we hit an empty string, then we know our task is impossible.
+	limit := []byte(prefix)
+	for len(limit) > 0 {
+		b := limit[len(limit)-1]
+		if b != 0xff {
+			limit[len(limit)-1]++
+			break
+		}
+
+		limit = limit[:len(limit)-1]
+	}
+
+	return string(limit)
+}
+
+// ListObjects lists the objects of a bucket like GCS does, given its n objects
+// sorted by name. name returns the name of the i-th object, and object the
+// object itself, only called for the objects listed.
+func ListObjects(
+	req *gcs.ListObjectsRequest,
+	n int,
+	name func(i int) string,
+	object func(i int) (*gcs.Object, error)) (listing *gcs.Listing, err error) {
+	lowerBound := func(s string) int {
+		return sort.Search(n, func(i int) bool { return name(i) >= s })
+	}
+
+	// Set up the result object.
+	listing = new(gcs.Listing)
+
+	// Handle defaults.
+	maxResults := req.MaxResults
+	if maxResults == 0 {
+		maxResults = 1000
+	}
+
+	// Find where in the space of object names to start.
+	nameStart := req.Prefix
+	if req.ContinuationToken != "" && req.ContinuationToken > nameStart {
+		nameStart = req.ContinuationToken
+	}
+
+	// Find the range of indexes to scan.
+	indexStart := lowerBound(nameStart)
+	prefixLimit := n
+	if successor := PrefixSuccessor(req.Prefix); successor != "" {
+		prefixLimit = lowerBound(successor)
+	}
+	indexLimit := min(indexStart+maxResults, prefixLimit)
+
+	// Scan the objects.
+	var lastResultWasPrefix bool
+	for i := indexStart; i < indexLimit; i++ {
+		name := name(i)
+
+		// Search for a delimiter if necessary.
+		if req.Delimiter != "" {
+			// Search only in the part after the prefix.
+			nameMinusQueryPrefix := name[len(req.Prefix):]
+
+			delimiterIndex := strings.Index(nameMinusQueryPrefix, req.Delimiter)
+			if delimiterIndex >= 0 {
+				resultPrefixLimit := len(req.Prefix) + delimiterIndex + len(req.Delimiter)
+
+				// Save the result, but only if it's not a duplicate.
+				resultPrefix := name[:resultPrefixLimit]
+				if len(listing.CollapsedRuns) == 0 ||
+					listing.CollapsedRuns[len(listing.CollapsedRuns)-1] != resultPrefix {
+					listing.CollapsedRuns = append(listing.CollapsedRuns, resultPrefix)
+				}
+
+				isTrailingDelimiter := (delimiterIndex == len(nameMinusQueryPrefix)-1)
+				if !isTrailingDelimiter || !req.IncludeTrailingDelimiter {
+					lastResultWasPrefix = true
+					continue
+				}
+			}
+		}
+
+		lastResultWasPrefix = false
+
+		// Otherwise, return as an object result.
+		var o *gcs.Object
+		o, err = object(i)
+		if err != nil {
+			listing = nil
+			return
+		}
+
+		listing.Objects = append(listing.Objects, o)
+	}
+
+	// Set up a cursor for where to start the next scan if we didn't exhaust the
+	// results.
+	if indexLimit < prefixLimit {
+		// If the final object we visited was returned as an element in
+		// listing.CollapsedRuns, we want to skip all other objects that would
+		// result in the same so we don't return duplicate elements in
+		// listing.CollapsedRuns across requests.
+		if lastResultWasPrefix {
+			lastResultPrefix := listing.CollapsedRuns[len(listing.CollapsedRuns)-1]
+			listing.ContinuationToken = PrefixSuccessor(lastResultPrefix)
+
+			// Check an assumption: PrefixSuccessor cannot result in the empty string
+			// above because object names must be non-empty UTF-8 strings, and there
+			// is no valid non-empty UTF-8 string that consists of entirely 0xff
+			// bytes.
+			if listing.ContinuationToken == "" {
+				listing = nil
+				err = errors.New("Unexpected empty string from PrefixSuccessor")
+				return
+			}
+		} else {
+			// Otherwise, we'll start scanning at the next object.
+			listing.ContinuationToken = name(indexLimit)
+		}
+	}
+
+	return
+}
+
+// CheckWritePreconditions checks the preconditions of a request writing an
+// object against the existing one, which is nil if there is none.
+func CheckWritePreconditions(
+	existing *gcs.Object,
+	generationPrecondition *int64,
+	metaGenerationPrecondition *int64) (err error) {
+	if generationPrecondition != nil {
+		if *generationPrecondition == 0 && existing != nil {
+			err = &gcs.PreconditionError{
+				Err: errors.New("Precondition failed: object exists"),
+			}
+
+			return
+		}
+
+		if *generationPrecondition > 0 {
+			if existing == nil {
+				err = &gcs.PreconditionError{
+					Err: errors.New("Precondition failed: object doesn't exist"),
+				}
+
+				return
+			}
+
+			if existing.Generation != *generationPrecondition {
+				err = &gcs.PreconditionError{
+					Err: fmt.Errorf(
+						"Precondition failed: object has generation %v",
+						existing.Generation),
+				}
+
+				return
+			}
+		}
+	}
+
+	if metaGenerationPrecondition != nil {
+		if existing == nil {
+			err = &gcs.PreconditionError{
+				Err: errors.New("Precondition failed: object doesn't exist"),
+			}
+
+			return
+		}
+
+		if existing.MetaGeneration != *metaGenerationPrecondition {
+			err = &gcs.PreconditionError{
+				Err: fmt.Errorf(
+					"Precondition failed: object has meta-generation %v",
+					existing.MetaGeneration),
+			}
+
+			return
+		}
+	}
+
+	return
+}
+
+// CheckMetaGenerationPrecondition checks the meta-generation precondition, if
+// any, of a request on an existing object.
+func CheckMetaGenerationPrecondition(o *gcs.Object, precondition *int64) (err error) {
+	if precondition != nil && o.MetaGeneration != *precondition {
+		err = &gcs.PreconditionError{
+			Err: fmt.Errorf(
+				"Object %q has meta-generation %d",
+				o.Name,
+				o.MetaGeneration),
+		}
+	}
+
+	return
+}
+
+// UpdateObject applies the request to the given object, updated at the given
+// time, once checked that its generation and meta-generation match the
+// request. The object is left untouched when they don't.
+func UpdateObject(o *gcs.Object, req *gcs.UpdateObjectRequest, now time.Time) (err error) {
+	// Does the generation number match the request?
+	if req.Generation != 0 && o.Generation != req.Generation {
+		err = &gcs.NotFoundError{
+			Err: fmt.Errorf(
+				"Object %q generation %d not found",
+				req.Name,
+				req.Generation),
+		}
+
+		return
+	}
+
+	// Does the meta-generation precondition check out?
+	if err = CheckMetaGenerationPrecondition(o, req.MetaGenerationPrecondition); err != nil {
+		return
+	}
+
+	// Update the basic fields according to the request.
+	if req.ContentType != nil {
+		o.ContentType = *req.ContentType
+	}
+
+	if req.ContentEncoding != nil {
+		o.ContentEncoding = *req.ContentEncoding
+	}
+
+	if req.ContentLanguage != nil {
+		o.ContentLanguage = *req.ContentLanguage
+	}
+
+	if req.CacheControl != nil {
+		o.CacheControl = *req.CacheControl
+	}
+
+	// Update the user metadata if necessary.
+	if len(req.Metadata) > 0 {
+		if o.Metadata == nil {
+			o.Metadata = make(map[string]string)
+		}
+
+		for k, v := range req.Metadata {
+			if v == nil {
+				delete(o.Metadata, k)
+				continue
+			}
+
+			o.Metadata[k] = *v
+		}
+	}
+
+	// Bump up the meta-generation number and the update time.
+	o.MetaGeneration++
+	o.Updated = now
+
+	return
+}
+
+// ComposeObjects composes the sources of the request, opened with open, into
+// the object created with create. The touchup function passed to create
+// adjusts the attributes of the object before they are saved.
+func ComposeObjects(
+	req *gcs.ComposeObjectsRequest,
+	open func(name string, generation int64) (*gcs.Object, io.ReadCloser, error),
+	create func(req *gcs.CreateObjectRequest, touchup func(o *gcs.Object)) (*gcs.Object, error)) (o *gcs.Object, err error) {
+	// GCS doesn't like too few or too many sources.
+	if len(req.Sources) < 1 {
+		err = errors.New("You must provide at least one source component")
+		return
+	}
+
+	if len(req.Sources) > gcs.MaxSourcesPerComposeRequest {
+		err = errors.New("You have provided too many source components")
+		return
+	}
+
+	// Open all of the source objects, also computing the sum of their component
+	// counts.
+	var srcReaders []io.Reader
+	var dstComponentCount int64
+
+	for _, src := range req.Sources {
+		var srcObj *gcs.Object
+		var rc io.ReadCloser
+
+		srcObj, rc, err = open(src.Name, src.Generation)
+		if err != nil {
+			return
+		}
+		defer rc.Close()
+
+		srcReaders = append(srcReaders, rc)
+		dstComponentCount += srcObj.ComponentCount
+	}
+
+	// GCS doesn't like the component count to go too high.
+	if dstComponentCount > gcs.MaxComponentCount {
+		err = errors.New("Result would have too many components")
+		return
+	}
+
+	// Create the new object.
+	o, err = create(
+		&gcs.CreateObjectRequest{
+			Name:                       req.DstName,
+			GenerationPrecondition:     req.DstGenerationPrecondition,
+			MetaGenerationPrecondition: req.DstMetaGenerationPrecondition,
+			Contents:                   io.MultiReader(srcReaders...),
+			ContentType:                req.ContentType,
+			Metadata:                   req.Metadata,
+			ContentLanguage:            req.ContentLanguage,
+			ContentEncoding:            req.ContentEncoding,
+			CacheControl:               req.CacheControl,
+			ContentDisposition:         req.ContentDisposition,
+			CustomTime:                 req.CustomTime,
+			StorageClass:               req.StorageClass,
+		},
+		func(o *gcs.Object) {
+			o.ComponentCount = dstComponentCount
+
+			// Emulate the real GCS behavior of not exporting an MD5 hash for
+			// composite objects.
+			o.MD5 = nil
+		})
+
+	return
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageutil
+
+import (
+	"testing"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/storage/gcs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefixSuccessor(t *testing.T) {
+	assert.Equal(t, "b", PrefixSuccessor("a"))
+	assert.Equal(t, "a0", PrefixSuccessor("a/"))
+	assert.Equal(t, "b", PrefixSuccessor("a\xff"))
+	assert.Equal(t, "", PrefixSuccessor("\xff\xff"))
+	assert.Equal(t, "", PrefixSuccessor(""))
+}
+
+func TestListObjectsCollapsesRunsAcrossPages(t *testing.T) {
+	names := []string{"a", "b/c", "b/d", "b/e/f", "g"}
+	list := func(req *gcs.ListObjectsRequest) *gcs.Listing {
+		listing, err := ListObjects(
+			req,
+			len(names),
+			func(i int) string { return names[i] },
+			func(i int) (*gcs.Object, error) { return &gcs.Object{Name: names[i]}, nil })
+		require.NoError(t, err)
+		return listing
+	}
+
+	listing := list(&gcs.ListObjectsRequest{Delimiter: "/", MaxResults: 2})
+	assert.Equal(t, []*gcs.Object{{Name: "a"}}, listing.Objects)
+	assert.Equal(t, []string{"b/"}, listing.CollapsedRuns)
+	assert.Equal(t, "b0", listing.ContinuationToken)
+
+	listing = list(&gcs.ListObjectsRequest{Delimiter: "/", MaxResults: 2, ContinuationToken: listing.ContinuationToken})
+	assert.Equal(t, []*gcs.Object{{Name: "g"}}, listing.Objects)
+	assert.Empty(t, listing.CollapsedRuns)
+	assert.Empty(t, listing.ContinuationToken)
+
+	listing = list(&gcs.ListObjectsRequest{Prefix: "b/", Delimiter: "/"})
+	assert.Equal(t, []*gcs.Object{{Name: "b/c"}, {Name: "b/d"}}, listing.Objects)
+	assert.Equal(t, []string{"b/e/"}, listing.CollapsedRuns)
+}
+
+func TestCheckWritePreconditions(t *testing.T) {
+	var zero, one, two int64 = 0, 1, 2
+	existing := &gcs.Object{Name: "a", Generation: 1, MetaGeneration: 2}
+
+	assert.NoError(t, CheckWritePreconditions(nil, nil, nil))
+	assert.NoError(t, CheckWritePreconditions(nil, &zero, nil))
+	assert.NoError(t, CheckWritePreconditions(existing, &one, &two))
+	assert.IsType(t, &gcs.PreconditionError{}, CheckWritePreconditions(existing, &zero, nil))
+	assert.IsType(t, &gcs.PreconditionError{}, CheckWritePreconditions(nil, &one, nil))
+	assert.IsType(t, &gcs.PreconditionError{}, CheckWritePreconditions(existing, &two, nil))
+	assert.IsType(t, &gcs.PreconditionError{}, CheckWritePreconditions(nil, nil, &one))
+	assert.IsType(t, &gcs.PreconditionError{}, CheckWritePreconditions(existing, nil, &one))
+}
+
+func TestUpdateObjectLeavesObjectUntouchedOnMismatch(t *testing.T) {
+	var one int64 = 1
+	contentType := "text/plain"
+	o := &gcs.Object{Name: "a", Generation: 1, MetaGeneration: 2}
+
+	err := UpdateObject(o, &gcs.UpdateObjectRequest{Name: "a", Generation: 2, ContentType: &contentType}, o.Updated)
+	assert.IsType(t, &gcs.NotFoundError{}, err)
+	err = UpdateObject(o, &gcs.UpdateObjectRequest{Name: "a", MetaGenerationPrecondition: &one, ContentType: &contentType}, o.Updated)
+	assert.IsType(t, &gcs.PreconditionError{}, err)
+
+	assert.Equal(t, &gcs.Object{Name: "a", Generation: 1, MetaGeneration: 2}, o)
+}
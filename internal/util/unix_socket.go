@@ -0,0 +1,98 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// How long to wait for a socket found at the path to listen on to answer,
+// before deciding that its process exited.
+const liveSocketDialTimeout = time.Second
+
+// ListenUnixSocket listens on a unix socket at the given path, with the given
+// permissions, which are set before the socket can be reached at the path. A
+// socket left at the path by a process that exited is replaced, but not one
+// that still accepts connections, nor a file created at the path meanwhile.
+//
+// The processes listening on the path check it and install their socket in
+// turn, holding a lock on the file at the path with the ".lock" suffix, which
+// is left in place.
+//
+// Closing the listener doesn't remove the socket: the caller removes it once
+// done.
+func ListenUnixSocket(p string, mode os.FileMode) (l *net.UnixListener, err error) {
+	lock, err := os.OpenFile(p+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("opening the lock file: %w", err)
+	}
+	defer lock.Close()
+
+	if err = syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return nil, fmt.Errorf("locking %s: %w", lock.Name(), err)
+	}
+
+	stale := false
+	if fi, statErr := os.Lstat(p); statErr == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and isn't a socket", p)
+		}
+
+		if c, dialErr := net.DialTimeout("unix", p, liveSocketDialTimeout); dialErr == nil {
+			c.Close()
+			return nil, fmt.Errorf("%s is in use by another process", p)
+		}
+		stale = true
+	}
+
+	// Create the socket in a directory only the owner can access, and move it
+	// to the path once its permissions are set.
+	dir, err := os.MkdirTemp(filepath.Dir(p), ".socket")
+	if err != nil {
+		return nil, fmt.Errorf("creating a private directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tmp := filepath.Join(dir, "s")
+	l, err = net.ListenUnix("unix", &net.UnixAddr{Name: tmp, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	l.SetUnlinkOnClose(false)
+
+	if err = os.Chmod(tmp, mode); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("setting socket permissions: %w", err)
+	}
+
+	// Only replace the stale socket: unlike rename, link fails if a file was
+	// created at the path by a process not taking the lock.
+	if stale {
+		err = os.Rename(tmp, p)
+	} else {
+		err = os.Link(tmp, p)
+	}
+	if err != nil {
+		l.Close()
+		return nil, fmt.Errorf("moving socket in place: %w", err)
+	}
+
+	return l, nil
+}
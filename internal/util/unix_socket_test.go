@@ -0,0 +1,121 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Unix socket paths are limited in length, so don't use t.TempDir().
+func socketPath(t *testing.T) string {
+	dir, err := os.MkdirTemp("", "sock")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "s.sock")
+}
+
+func TestListenUnixSocket(t *testing.T) {
+	p := socketPath(t)
+
+	l, err := ListenUnixSocket(p, 0600)
+
+	require.NoError(t, err)
+	defer l.Close()
+	fi, err := os.Lstat(p)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+	c, err := net.Dial("unix", p)
+	require.NoError(t, err)
+	c.Close()
+	// The private directory is removed, and the lock file left.
+	entries, err := os.ReadDir(filepath.Dir(p))
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.Equal(t, []string{"s.sock", "s.sock.lock"}, names)
+}
+
+func TestListenUnixSocket_ReplacesStaleSocket(t *testing.T) {
+	p := socketPath(t)
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: p, Net: "unix"})
+	require.NoError(t, err)
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	l, err := ListenUnixSocket(p, 0600)
+
+	require.NoError(t, err)
+	l.Close()
+}
+
+func TestListenUnixSocket_RefusesLiveSocket(t *testing.T) {
+	p := socketPath(t)
+	live, err := net.Listen("unix", p)
+	require.NoError(t, err)
+	defer live.Close()
+
+	_, err = ListenUnixSocket(p, 0600)
+
+	assert.ErrorContains(t, err, "in use by another process")
+}
+
+func TestListenUnixSocket_RefusesOtherFiles(t *testing.T) {
+	p := socketPath(t)
+	require.NoError(t, os.WriteFile(p, nil, 0600))
+
+	_, err := ListenUnixSocket(p, 0600)
+
+	assert.ErrorContains(t, err, "isn't a socket")
+}
+
+func TestListenUnixSocket_ConcurrentListenersDontReplaceEachOther(t *testing.T) {
+	p := socketPath(t)
+	const n = 8
+	listeners := make(chan *net.UnixListener, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if l, err := ListenUnixSocket(p, 0600); err == nil {
+				listeners <- l
+			}
+		}()
+	}
+	wg.Wait()
+	close(listeners)
+
+	// Exactly one listener got the path, and still serves it.
+	require.Len(t, listeners, 1)
+	l := <-listeners
+	defer l.Close()
+	c, err := net.Dial("unix", p)
+	require.NoError(t, err)
+	defer c.Close()
+	require.NoError(t, l.SetDeadline(time.Now().Add(time.Second)))
+	accepted, err := l.Accept()
+	require.NoError(t, err)
+	accepted.Close()
+}
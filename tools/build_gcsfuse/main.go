@@ -25,12 +25,14 @@
 // For Linux, writes the following to dst_dir:
 //
 //	bin/gcsfuse
+//	bin/gcsfuse-log
 //	sbin/mount.fuse.gcsfuse
 //	sbin/mount.gcsfuse
 //
 // For OS X:
 //
 //	bin/gcsfuse
+//	bin/gcsfuse-log
 //	sbin/mount_gcsfuse
 package main
 
@@ -121,6 +123,10 @@ func buildBinaries(dstDir, srcDir, version string, buildArgs []string) (err erro
 			"github.com/googlecloudplatform/gcsfuse/v2",
 			"bin/gcsfuse",
 		},
+		{
+			"github.com/googlecloudplatform/gcsfuse/v2/tools/gcsfuse_log",
+			"bin/gcsfuse-log",
+		},
 		{
 			"github.com/googlecloudplatform/gcsfuse/v2/tools/mount_gcsfuse",
 			path.Join("sbin", mountHelperName),
@@ -0,0 +1,175 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Summarizes the requests found in gcsfuse logs.
+//
+// Usage:
+//
+//	gcsfuse-log [flags] (latency|slowest|timeline) [log_file...]
+//
+// The logs must be written with --log-format json and trace severity, with
+// --debug_fuse and --debug_gcs for the FUSE ops and GCS requests to be logged.
+// Log files are read in the order given, so pass rotated files oldest first;
+// standard input is read if none is given.
+//
+// The commands print:
+//
+//	latency   the latency histogram of each FUSE op, GCS method and of the
+//	          reads via the file cache;
+//	slowest   the requests which took the longest;
+//	timeline  the requests about each file, in order.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/logparser"
+)
+
+var fCount = flag.Int("n", 20, "Number of requests printed by slowest.")
+var fPath = flag.String("path", "", "Path of the file or directory whose timeline is printed, instead of all of them.")
+var fJSON = flag.Bool("json", false, "Print JSON instead of text.")
+
+func parseLogs(files []string) ([]*logparser.Request, error) {
+	if len(files) == 0 {
+		return logparser.Parse(os.Stdin)
+	}
+
+	readers := make([]io.Reader, 0, len(files))
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+	return logparser.Parse(io.MultiReader(readers...))
+}
+
+func describe(r *logparser.Request) string {
+	desc := r.Name() + " " + r.Desc
+	if r.Err != "" {
+		desc += ": " + r.Err
+	}
+	return desc
+}
+
+func latency(w io.Writer, histograms []*logparser.Histogram) {
+	for _, h := range histograms {
+		fmt.Fprintf(w, "%s: count %d, errors %d, p50 %v, p90 %v, p99 %v, max %v\n",
+			h.Name, h.Count, h.Errors, h.P50, h.P90, h.P99, h.Max)
+		tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', tabwriter.AlignRight)
+		for i, count := range h.Counts {
+			if count == 0 {
+				continue
+			}
+			bucket := fmt.Sprintf("> %v", logparser.HistogramBounds[len(logparser.HistogramBounds)-1])
+			if i < len(logparser.HistogramBounds) {
+				bucket = fmt.Sprintf("<= %v", logparser.HistogramBounds[i])
+			}
+			fmt.Fprintf(tw, "  %s\t %d\t\n", bucket, count)
+		}
+		tw.Flush()
+	}
+}
+
+func slowest(w io.Writer, requests []*logparser.Request) {
+	for _, r := range requests {
+		fmt.Fprintf(w, "%s %12v %s\n", r.Start.Format(time.RFC3339Nano), r.Duration(), describe(r))
+	}
+}
+
+func timelines(w io.Writer, timelines map[string][]*logparser.Request) {
+	var paths []string
+	for p := range timelines {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		fmt.Fprintf(w, "%s:\n", p)
+		for _, r := range timelines[p] {
+			duration := "unfinished"
+			if r.Finished() {
+				duration = r.Duration().String()
+			}
+			fmt.Fprintf(w, "  %s %12s %s\n", r.Start.Format(time.RFC3339Nano), duration, describe(r))
+		}
+	}
+}
+
+// run runs the command given by args, printing its report to w.
+func run(w io.Writer, args []string) (err error) {
+	if len(args) == 0 {
+		err = fmt.Errorf("Usage: %s [flags] (latency|slowest|timeline) [log_file...]", os.Args[0])
+		return
+	}
+	cmd := args[0]
+	if cmd != "latency" && cmd != "slowest" && cmd != "timeline" {
+		err = fmt.Errorf("unknown command %q", cmd)
+		return
+	}
+
+	requests, err := parseLogs(args[1:])
+	if err != nil {
+		return
+	}
+
+	var report any
+	var printText func(io.Writer)
+	switch cmd {
+	case "latency":
+		histograms := logparser.LatencyHistograms(requests)
+		report = histograms
+		printText = func(w io.Writer) { latency(w, histograms) }
+
+	case "slowest":
+		slow := logparser.Slowest(requests, *fCount)
+		report = slow
+		printText = func(w io.Writer) { slowest(w, slow) }
+
+	default:
+		all := logparser.Timelines(requests)
+		if *fPath != "" {
+			all = map[string][]*logparser.Request{*fPath: all[*fPath]}
+		}
+		report = all
+		printText = func(w io.Writer) { timelines(w, all) }
+	}
+
+	if *fJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	printText(w)
+	return
+}
+
+func main() {
+	flag.Parse()
+
+	if err := run(os.Stdout, flag.Args()); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/logparser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const logs = `{"timestamp":{"seconds":1704458059,"nanos":100000000},"severity":"TRACE","message":"fuse_debug: Op 0x00000002        connection.go:415] <- LookUpInode (parent 1, name \"foo\", PID 42)"}
+{"timestamp":{"seconds":1704458059,"nanos":101000000},"severity":"TRACE","message":"gcs: Req              0x0: <- StatObject(\"foo\")"}
+{"timestamp":{"seconds":1704458059,"nanos":104000000},"severity":"TRACE","message":"gcs: Req              0x0: -> StatObject(\"foo\") (3ms): OK"}
+{"timestamp":{"seconds":1704458059,"nanos":110000000},"severity":"TRACE","message":"fuse_debug: Op 0x00000002        connection.go:497] -> OK (inode 2)"}
+{"timestamp":{"seconds":1704458059,"nanos":200000000},"severity":"TRACE","message":"fuse_debug: Op 0x00000004        connection.go:415] <- OpenFile (inode 2, PID 42)"}
+`
+
+// writeLogs writes the logs to a file, returning its path.
+func writeLogs(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "gcsfuse.log")
+	require.NoError(t, os.WriteFile(path, []byte(logs), 0600))
+	return path
+}
+
+// runWithFlags runs the command with the supplied flags, returning its output.
+func runWithFlags(t *testing.T, asJSON bool, path string, args ...string) string {
+	t.Helper()
+	defer func(prevJSON bool, prevPath string) { *fJSON, *fPath = prevJSON, prevPath }(*fJSON, *fPath)
+	*fJSON, *fPath = asJSON, path
+
+	var out bytes.Buffer
+	require.NoError(t, run(&out, args))
+	return out.String()
+}
+
+func TestRunWithoutCommand(t *testing.T) {
+	assert.ErrorContains(t, run(&bytes.Buffer{}, nil), "Usage")
+	assert.ErrorContains(t, run(&bytes.Buffer{}, []string{"slow"}), `unknown command "slow"`)
+}
+
+func TestRunWithMissingLogFile(t *testing.T) {
+	err := run(&bytes.Buffer{}, []string{"latency", filepath.Join(t.TempDir(), "missing.log")})
+
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestLatency(t *testing.T) {
+	out := runWithFlags(t, false, "", "latency", writeLogs(t))
+
+	assert.Equal(t, `fuse/LookUpInode: count 1, errors 0, p50 10ms, p90 10ms, p99 10ms, max 10ms
+   <= 10ms  1
+gcs/StatObject: count 1, errors 0, p50 3ms, p90 3ms, p99 3ms, max 3ms
+   <= 5ms  1
+`, out)
+}
+
+func TestLatencyAsJSON(t *testing.T) {
+	out := runWithFlags(t, true, "", "latency", writeLogs(t))
+
+	var histograms []*logparser.Histogram
+	require.NoError(t, json.Unmarshal([]byte(out), &histograms))
+	require.Len(t, histograms, 2)
+	assert.Equal(t, "fuse/LookUpInode", histograms[0].Name)
+	assert.Equal(t, "gcs/StatObject", histograms[1].Name)
+}
+
+func TestSlowest(t *testing.T) {
+	*fCount = 1
+	defer func() { *fCount = 20 }()
+
+	out := runWithFlags(t, false, "", "slowest", writeLogs(t))
+
+	assert.Equal(t, `2024-01-05T12:34:19.1Z         10ms fuse/LookUpInode LookUpInode (parent 1, name "foo", PID 42)
+`, out)
+}
+
+func TestTimeline(t *testing.T) {
+	out := runWithFlags(t, false, "foo", "timeline", writeLogs(t))
+
+	assert.Equal(t, `foo:
+  2024-01-05T12:34:19.1Z         10ms fuse/LookUpInode LookUpInode (parent 1, name "foo", PID 42)
+  2024-01-05T12:34:19.101Z          3ms gcs/StatObject StatObject("foo")
+  2024-01-05T12:34:19.2Z   unfinished fuse/OpenFile OpenFile (inode 2, PID 42)
+`, out)
+}
@@ -23,7 +23,6 @@ import (
 
 	"cloud.google.com/go/storage"
 	"github.com/googlecloudplatform/gcsfuse/v2/tools/integration_tests/util/client"
-	"github.com/googlecloudplatform/gcsfuse/v2/tools/integration_tests/util/log_parser/json_parser/read_logs"
 	"github.com/googlecloudplatform/gcsfuse/v2/tools/integration_tests/util/operations"
 	"github.com/googlecloudplatform/gcsfuse/v2/tools/integration_tests/util/setup"
 	"github.com/googlecloudplatform/gcsfuse/v2/tools/integration_tests/util/test_setup"
@@ -74,7 +73,7 @@ func (s *cacheFileForRangeReadFalseTest) TestRangeReadsWithCacheMiss(t *testing.
 	// Read file again from offset 1000 and validate from gcs.
 	expectedOutcome2 := readChunkAndValidateObjectContentsFromGCS(s.ctx, s.storageClient, testFileName, offsetForSecondRangeRead, t)
 
-	structuredReadLogs := read_logs.GetStructuredLogsSortedByTimestamp(setup.LogFile(), t)
+	structuredReadLogs := parseReadLogs(t)
 	validate(expectedOutcome1, structuredReadLogs[0], false, false, 1, t)
 	validate(expectedOutcome2, structuredReadLogs[1], false, false, 1, t)
 	validateFileIsNotCached(testFileName, t)
@@ -93,22 +92,22 @@ func (s *cacheFileForRangeReadFalseTest) TestConcurrentReads_ReadIsTreatedNonSeq
 	}
 	wg.Wait()
 
-	structuredReadLogs := read_logs.GetStructuredLogsSortedByTimestamp(setup.LogFile(), t)
+	structuredReadLogs := parseReadLogs(t)
 	// Goroutine execution order isn't guaranteed.
 	// If the object name in expected outcome doesn't align with the logs, swap
 	// the expected outcome objects and file names at positions 0 and 1.
-	if expectedOutcome[0].ObjectName != structuredReadLogs[0].ObjectName {
+	if expectedOutcome[0].ObjectName != structuredReadLogs[0].Object {
 		expectedOutcome[0], expectedOutcome[1] = expectedOutcome[1], expectedOutcome[0]
 		testFileNames[0], testFileNames[1] = testFileNames[1], testFileNames[0]
 	}
 	validate(expectedOutcome[0], structuredReadLogs[0], true, false, randomReadChunkCount, t)
 	validate(expectedOutcome[1], structuredReadLogs[1], true, false, randomReadChunkCount, t)
 	// Validate last chunk was considered non-sequential and cache hit false for first read.
-	ogletest.ExpectEq(false, structuredReadLogs[0].Chunks[randomReadChunkCount-1].IsSequential)
-	ogletest.ExpectEq(false, structuredReadLogs[0].Chunks[randomReadChunkCount-1].CacheHit)
+	ogletest.ExpectEq(false, structuredReadLogs[0].Reads[randomReadChunkCount-1].Sequential)
+	ogletest.ExpectEq(false, structuredReadLogs[0].Reads[randomReadChunkCount-1].CacheHit)
 	// Validate last chunk was considered sequential and cache hit true for second read.
-	ogletest.ExpectEq(true, structuredReadLogs[1].Chunks[randomReadChunkCount-1].IsSequential)
-	ogletest.ExpectEq(true, structuredReadLogs[1].Chunks[randomReadChunkCount-1].CacheHit)
+	ogletest.ExpectEq(true, structuredReadLogs[1].Reads[randomReadChunkCount-1].Sequential)
+	ogletest.ExpectEq(true, structuredReadLogs[1].Reads[randomReadChunkCount-1].CacheHit)
 
 	validateFileIsNotCached(testFileNames[0], t)
 	validateFileInCacheDirectory(testFileNames[1], fileSizeForRangeRead, s.ctx, s.storageClient, t)
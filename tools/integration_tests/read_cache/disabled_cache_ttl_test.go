@@ -24,7 +24,6 @@ import (
 	"github.com/googlecloudplatform/gcsfuse/v2/tools/integration_tests/util/operations"
 
 	"cloud.google.com/go/storage"
-	"github.com/googlecloudplatform/gcsfuse/v2/tools/integration_tests/util/log_parser/json_parser/read_logs"
 	"github.com/googlecloudplatform/gcsfuse/v2/tools/integration_tests/util/setup"
 	"github.com/googlecloudplatform/gcsfuse/v2/tools/integration_tests/util/test_setup"
 )
@@ -67,7 +66,7 @@ func (s *disabledCacheTTLTest) TestReadAfterObjectUpdateIsCacheMiss(t *testing.T
 	expectedOutcome3 := readFileAndValidateCacheWithGCS(s.ctx, s.storageClient, testFileName, smallContentSize, true, t)
 
 	// Parse the log file and validate cache hit or miss from the structured logs.
-	structuredReadLogs := read_logs.GetStructuredLogsSortedByTimestamp(setup.LogFile(), t)
+	structuredReadLogs := parseReadLogs(t)
 	validate(expectedOutcome1, structuredReadLogs[0], true, false, chunksRead, t)
 	validate(expectedOutcome2, structuredReadLogs[1], true, false, chunksReadAfterUpdate, t)
 	validate(expectedOutcome3, structuredReadLogs[2], true, true, chunksReadAfterUpdate, t)
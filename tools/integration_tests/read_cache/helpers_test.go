@@ -25,8 +25,8 @@ import (
 	"time"
 
 	"cloud.google.com/go/storage"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/logparser"
 	"github.com/googlecloudplatform/gcsfuse/v2/tools/integration_tests/util/client"
-	"github.com/googlecloudplatform/gcsfuse/v2/tools/integration_tests/util/log_parser/json_parser/read_logs"
 	"github.com/googlecloudplatform/gcsfuse/v2/tools/integration_tests/util/operations"
 	"github.com/googlecloudplatform/gcsfuse/v2/tools/integration_tests/util/setup"
 )
@@ -70,31 +70,46 @@ func readFileAndGetExpectedOutcome(testDirPath, fileName string, readFullFile bo
 	return expected
 }
 
-func validate(expected *Expected, logEntry *read_logs.StructuredReadLogEntry,
+// parseReadLogs returns the reads via the file cache found in the gcsfuse
+// logs, grouped by file handle in the order of the first read on each.
+func parseReadLogs(t *testing.T) []*logparser.FileReads {
+	file, err := os.Open(setup.LogFile())
+	if err != nil {
+		t.Fatalf("Failed to open log file: %v", err)
+	}
+	defer file.Close()
+	requests, err := logparser.Parse(file)
+	if err != nil {
+		t.Fatalf("Failed to parse logs %s correctly: %v", setup.LogFile(), err)
+	}
+	return logparser.ReadsByHandle(requests)
+}
+
+func validate(expected *Expected, logEntry *logparser.FileReads,
 	isSeq, cacheHit bool, chunkCount int, t *testing.T) {
-	if logEntry.StartTimeSeconds < expected.StartTimeStampSeconds {
-		t.Errorf("start time in logs %d less than actual start time %d.", logEntry.StartTimeSeconds, expected.StartTimeStampSeconds)
+	if logEntry.Start.Unix() < expected.StartTimeStampSeconds {
+		t.Errorf("start time in logs %d less than actual start time %d.", logEntry.Start.Unix(), expected.StartTimeStampSeconds)
 	}
-	if logEntry.BucketName != expected.BucketName {
+	if logEntry.Bucket != expected.BucketName {
 		t.Errorf("Bucket names don't match! Expected: %s, Got from logs: %s",
-			expected.BucketName, logEntry.BucketName)
+			expected.BucketName, logEntry.Bucket)
 	}
-	if logEntry.ObjectName != expected.ObjectName {
+	if logEntry.Object != expected.ObjectName {
 		t.Errorf("Object names don't match! Expected: %s, Got from logs: %s",
-			expected.ObjectName, logEntry.ObjectName)
+			expected.ObjectName, logEntry.Object)
 	}
-	if len(logEntry.Chunks) != chunkCount {
+	if len(logEntry.Reads) != chunkCount {
 		t.Errorf("chunks read don't match! Expected: %d, Got from logs: %d",
-			chunkCount, len(logEntry.Chunks))
+			chunkCount, len(logEntry.Reads))
 	}
-	if logEntry.Chunks[len(logEntry.Chunks)-1].StartTimeSeconds > expected.EndTimeStampSeconds {
+	if logEntry.Reads[len(logEntry.Reads)-1].Start.Unix() > expected.EndTimeStampSeconds {
 		t.Errorf("end time in logs more than actual end time.")
 	}
-	if cacheHit != logEntry.Chunks[0].CacheHit {
-		t.Errorf("Expected Cache Hit: %t, Got from logs: %t", cacheHit, logEntry.Chunks[0].CacheHit)
+	if cacheHit != logEntry.Reads[0].CacheHit {
+		t.Errorf("Expected Cache Hit: %t, Got from logs: %t", cacheHit, logEntry.Reads[0].CacheHit)
 	}
-	if isSeq != logEntry.Chunks[0].IsSequential {
-		t.Errorf("Expected Is Sequential: %t, Got from logs: %t", isSeq, logEntry.Chunks[0].IsSequential)
+	if isSeq != logEntry.Reads[0].Sequential {
+		t.Errorf("Expected Is Sequential: %t, Got from logs: %t", isSeq, logEntry.Reads[0].Sequential)
 	}
 }
 
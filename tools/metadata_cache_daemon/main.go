@@ -0,0 +1,99 @@
+// Copyright 2024 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Serves a metadata cache shared by the gcsfuse mounts on a host.
+//
+// Usage:
+//
+//	metadata_cache_daemon [flags] socket_path
+//
+// Mounts use the daemon when 'metadata-cache: shared-cache-socket' is set to
+// socket_path in their config-file. Any mount able to connect to the socket
+// can read the cached metadata of all buckets, so restrict its permissions
+// (see --socket-mode) to the users running trusted mounts.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/cache/metadata/shared"
+	"github.com/googlecloudplatform/gcsfuse/v2/internal/util"
+)
+
+var fStatCacheMaxSizeMB = flag.Uint64("stat-cache-max-size-mb", 256, "Maximum size of the stat cache, in MiB.")
+var fTypeCacheMaxSizeMB = flag.Uint64("type-cache-max-size-mb", 64, "Maximum size of the type cache shared by all directories, in MiB.")
+var fSocketMode = flag.String("socket-mode", "0600", "Permission bits of the socket, in octal.")
+
+func run(args []string) (err error) {
+	if len(args) != 1 {
+		err = fmt.Errorf("Usage: %s [flags] socket_path", os.Args[0])
+		return
+	}
+	socketPath := args[0]
+
+	socketMode, err := strconv.ParseUint(*fSocketMode, 8, 32)
+	if err != nil {
+		err = fmt.Errorf("parsing socket-mode: %w", err)
+		return
+	}
+
+	server, err := shared.NewServer(*fStatCacheMaxSizeMB, *fTypeCacheMaxSizeMB)
+	if err != nil {
+		return
+	}
+
+	// The socket is only reachable once its permissions are set, so that no
+	// other user can read or poison the cache meanwhile.
+	l, err := util.ListenUnixSocket(socketPath, os.FileMode(socketMode))
+	if err != nil {
+		err = fmt.Errorf("listening: %w", err)
+		return
+	}
+	defer os.Remove(socketPath)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Printf("Received %v, shutting down", sig)
+		server.Close()
+	}()
+
+	log.Printf("Serving shared metadata cache on %s (stat-cache: %d MiB, type-cache: %d MiB)",
+		socketPath, *fStatCacheMaxSizeMB, *fTypeCacheMaxSizeMB)
+	if err = server.Serve(l); errors.Is(err, net.ErrClosed) {
+		err = nil
+	}
+
+	return
+}
+
+func main() {
+	log.SetFlags(log.Lmicroseconds)
+	flag.Parse()
+
+	err := run(flag.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}